@@ -0,0 +1,57 @@
+// Package api holds the OpenAPI description of the HTTP API. Tests check the
+// handlers against it and the frontend client is generated from it.
+package api
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"github.com/muneerlalji/Luma/apierror"
+)
+
+//go:generate go run ./cmd/tsclient ../../frontend/src/services/api/generated.ts
+
+//go:embed openapi.yaml
+var specYAML []byte
+
+var loadSpec = sync.OnceValues(func() (*openapi3.T, error) {
+	loader := openapi3.NewLoader()
+	spec, err := loader.LoadFromData(specYAML)
+	if err != nil {
+		return nil, fmt.Errorf("parse OpenAPI spec: %w", err)
+	}
+	if err := spec.Validate(loader.Context); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI spec: %w", err)
+	}
+	return spec, nil
+})
+
+var specJSON = sync.OnceValues(func() ([]byte, error) {
+	spec, err := Spec()
+	if err != nil {
+		return nil, err
+	}
+	return spec.MarshalJSON()
+})
+
+// Spec returns the parsed and validated specification. Callers must not
+// modify it.
+func Spec() (*openapi3.T, error) {
+	return loadSpec()
+}
+
+// Handler serves the specification as JSON
+func Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := specJSON()
+		if err != nil {
+			apierror.Abort(c, apierror.Internal("Failed to load API specification", err))
+			return
+		}
+		c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+	}
+}
@@ -0,0 +1,54 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muneerlalji/Luma/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// generatedClient is the checked-in output of go generate
+const generatedClient = "../../frontend/src/services/api/generated.ts"
+
+func TestSpecIsValid(t *testing.T) {
+	spec, err := api.Spec()
+	require.NoError(t, err)
+	assert.Equal(t, "Luma API", spec.Info.Title)
+}
+
+func TestHandler_ServesJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/openapi.json", api.Handler())
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/openapi.json", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "3.0.3", body["openapi"])
+	assert.Contains(t, body["paths"], "/memories")
+}
+
+func TestTypeScriptClientIsCurrent(t *testing.T) {
+	checkedIn, err := os.ReadFile(generatedClient)
+	if os.IsNotExist(err) {
+		t.Skip("frontend is not checked out")
+	}
+	require.NoError(t, err)
+
+	spec, err := api.Spec()
+	require.NoError(t, err)
+	generated, err := api.TypeScript(spec)
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(checkedIn, generated), "%s is stale; run go generate ./api/", generatedClient)
+}
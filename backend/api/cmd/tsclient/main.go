@@ -0,0 +1,33 @@
+// Command tsclient writes the TypeScript client for the API spec to the file
+// named by its argument
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/muneerlalji/Luma/api"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: tsclient <output.ts>")
+		os.Exit(2)
+	}
+	if err := run(os.Args[1]); err != nil {
+		fmt.Fprintln(os.Stderr, "tsclient:", err)
+		os.Exit(1)
+	}
+}
+
+func run(path string) error {
+	spec, err := api.Spec()
+	if err != nil {
+		return err
+	}
+	code, err := api.TypeScript(spec)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, code, 0o644)
+}
@@ -0,0 +1,271 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// methodOrder lists operations of a path in the order they are generated
+var methodOrder = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+var pathTemplateParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+const typeScriptHeader = `// Code generated by backend/api/cmd/tsclient from backend/api/openapi.yaml. DO NOT EDIT.
+
+import axios, { AxiosRequestConfig } from 'axios';
+
+export interface RequestOptions extends AxiosRequestConfig {
+  /** Session token, sent as a bearer token */
+  token?: string;
+}
+
+const request = async <T>(config: AxiosRequestConfig, options: RequestOptions = {}): Promise<T> => {
+  const { token, headers, ...rest } = options;
+  const response = await axios.request<T>({
+    ...rest,
+    ...config,
+    baseURL: process.env.NEXT_PUBLIC_API_URL,
+    headers: token ? { ...headers, Authorization: ` + "`Bearer ${token}`" + ` } : headers,
+  });
+  return response.data;
+};
+`
+
+// TypeScript renders the frontend client for spec: a type for each schema and
+// an axios call for each operation, named by its operationId
+func TypeScript(spec *openapi3.T) ([]byte, error) {
+	var out strings.Builder
+	out.WriteString(typeScriptHeader)
+
+	names := make([]string, 0, len(spec.Components.Schemas))
+	for name := range spec.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		schema := spec.Components.Schemas[name].Value
+		out.WriteString("\n")
+		writeDoc(&out, "", schema.Description)
+		if schema.Type.Is(openapi3.TypeObject) && len(schema.Properties) > 0 {
+			fmt.Fprintf(&out, "export interface %s %s\n", name, objectType(schema, ""))
+		} else {
+			fmt.Fprintf(&out, "export type %s = %s;\n", name, tsType(spec.Components.Schemas[name], ""))
+		}
+	}
+
+	paths := spec.Paths.Map()
+	templates := make([]string, 0, len(paths))
+	for template := range paths {
+		templates = append(templates, template)
+	}
+	sort.Strings(templates)
+	for _, template := range templates {
+		item := paths[template]
+		for _, method := range methodOrder {
+			operation := item.GetOperation(method)
+			if operation == nil {
+				continue
+			}
+			if operation.OperationID == "" {
+				return nil, fmt.Errorf("%s %s has no operationId", method, template)
+			}
+			code, err := operationFunc(serverPath(spec, item)+template, method, item, operation)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, template, err)
+			}
+			out.WriteString("\n")
+			out.WriteString(code)
+		}
+	}
+	return []byte(out.String()), nil
+}
+
+// serverPath is the path of the first server of item, which is the current
+// version unless the path overrides its servers
+func serverPath(spec *openapi3.T, item *openapi3.PathItem) string {
+	servers := spec.Servers
+	if len(item.Servers) > 0 {
+		servers = item.Servers
+	}
+	if len(servers) == 0 {
+		return ""
+	}
+	return strings.TrimRight(servers[0].URL, "/")
+}
+
+// operationFunc renders the client function for one operation. Its arguments
+// are the path parameters, the request body, the query parameters and the
+// request options, in that order.
+func operationFunc(template, method string, item *openapi3.PathItem, operation *openapi3.Operation) (string, error) {
+	var args, config []string
+
+	url := template
+	var query []string
+	declared := make(map[string]bool)
+	for _, ref := range append(item.Parameters, operation.Parameters...) {
+		param := ref.Value
+		switch param.In {
+		case openapi3.ParameterInPath:
+			declared[param.Name] = true
+			args = append(args, fmt.Sprintf("%s: %s", param.Name, tsType(param.Schema, "")))
+			url = strings.ReplaceAll(url, "{"+param.Name+"}", "${encodeURIComponent("+param.Name+")}")
+		case openapi3.ParameterInQuery:
+			query = append(query, fmt.Sprintf("%s?: %s", quoteKey(param.Name), tsType(param.Schema, "")))
+		default:
+			return "", fmt.Errorf("unsupported %s parameter %q", param.In, param.Name)
+		}
+	}
+	for _, match := range pathTemplateParam.FindAllStringSubmatch(template, -1) {
+		if !declared[match[1]] {
+			return "", fmt.Errorf("path parameter %s is not declared", match[0])
+		}
+	}
+	config = append(config, "method: '"+method+"'", "url: `"+url+"`")
+
+	if body := operation.RequestBody; body != nil {
+		bodyType := "FormData"
+		if media := body.Value.Content.Get("application/json"); media != nil {
+			bodyType = tsType(media.Schema, "")
+		} else if body.Value.Content.Get("multipart/form-data") == nil {
+			return "", fmt.Errorf("unsupported request body")
+		}
+		name := "body"
+		if !body.Value.Required {
+			name += "?"
+		}
+		args = append(args, name+": "+bodyType)
+		config = append(config, "data: body")
+	}
+	if len(query) > 0 {
+		args = append(args, "query?: { "+strings.Join(query, "; ")+" }")
+		config = append(config, "params: query")
+	}
+	args = append(args, "options?: RequestOptions")
+
+	var out strings.Builder
+	writeDoc(&out, "", operation.Summary)
+	fmt.Fprintf(&out, "export const %s = (%s) =>\n", operation.OperationID, strings.Join(args, ", "))
+	fmt.Fprintf(&out, "  request<%s>({ %s }, options);\n", responseType(operation), strings.Join(config, ", "))
+	return out.String(), nil
+}
+
+// responseType is the JSON body type of the first successful response
+func responseType(operation *openapi3.Operation) string {
+	codes := make([]string, 0)
+	for code := range operation.Responses.Map() {
+		if status, err := strconv.Atoi(code); err == nil && status >= 200 && status < 300 {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		response := operation.Responses.Value(code).Value
+		if media := response.Content.Get("application/json"); media != nil {
+			return tsType(media.Schema, "")
+		}
+	}
+	return "void"
+}
+
+func tsType(ref *openapi3.SchemaRef, indent string) string {
+	if ref == nil {
+		return "unknown"
+	}
+	if ref.Ref != "" {
+		return ref.Ref[strings.LastIndex(ref.Ref, "/")+1:]
+	}
+	schema := ref.Value
+
+	if len(schema.OneOf) > 0 {
+		types := make([]string, 0, len(schema.OneOf))
+		for _, option := range schema.OneOf {
+			types = append(types, tsType(option, indent))
+		}
+		return strings.Join(types, " | ")
+	}
+	if len(schema.Enum) > 0 {
+		values := make([]string, 0, len(schema.Enum))
+		for _, value := range schema.Enum {
+			values = append(values, fmt.Sprintf("'%v'", value))
+		}
+		return strings.Join(values, " | ")
+	}
+
+	switch {
+	case schema.Type.Is(openapi3.TypeString):
+		if schema.Format == "binary" {
+			return "Blob"
+		}
+		return "string"
+	case schema.Type.Is(openapi3.TypeInteger), schema.Type.Is(openapi3.TypeNumber):
+		return "number"
+	case schema.Type.Is(openapi3.TypeBoolean):
+		return "boolean"
+	case schema.Type.Is(openapi3.TypeArray):
+		items := tsType(schema.Items, indent)
+		if strings.Contains(items, " | ") {
+			items = "(" + items + ")"
+		}
+		return items + "[]"
+	case schema.Type.Is(openapi3.TypeObject):
+		if len(schema.Properties) > 0 {
+			return objectType(schema, indent)
+		}
+		if additional := schema.AdditionalProperties.Schema; additional != nil {
+			return "Record<string, " + tsType(additional, indent) + ">"
+		}
+		return "Record<string, unknown>"
+	default:
+		return "unknown"
+	}
+}
+
+func objectType(schema *openapi3.Schema, indent string) string {
+	required := make(map[string]bool, len(schema.Required))
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out strings.Builder
+	out.WriteString("{\n")
+	for _, name := range names {
+		property := schema.Properties[name]
+		if property.Ref == "" {
+			writeDoc(&out, indent+"  ", property.Value.Description)
+		}
+		optional := "?"
+		if required[name] {
+			optional = ""
+		}
+		fmt.Fprintf(&out, "%s  %s%s: %s;\n", indent, quoteKey(name), optional, tsType(property, indent+"  "))
+	}
+	out.WriteString(indent + "}")
+	return out.String()
+}
+
+func writeDoc(out *strings.Builder, indent, text string) {
+	text = strings.Join(strings.Fields(text), " ")
+	if text != "" {
+		fmt.Fprintf(out, "%s/** %s */\n", indent, text)
+	}
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+func quoteKey(name string) string {
+	if identifier.MatchString(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
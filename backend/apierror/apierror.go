@@ -0,0 +1,113 @@
+// Package apierror defines the error envelope every API response uses:
+//
+//	{"error": {"code": "memory_not_found", "message": "...", "fields": {...}, "request_id": "..."}}
+//
+// Codes are stable so the frontend can localize messages; messages are
+// English fallbacks.
+package apierror
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muneerlalji/Luma/logging"
+)
+
+// Error is an API error with a stable machine-readable code
+type Error struct {
+	Status  int                     `json:"-"`
+	Code    string                  `json:"code"`
+	Message string                  `json:"message"`
+	Fields  map[string][]FieldError `json:"fields,omitempty"`
+	// RequestID is filled in when the error is rendered
+	RequestID string `json:"request_id,omitempty"`
+	// Cause is logged but never sent to the client
+	Cause error `json:"-"`
+}
+
+// FieldError is one problem with a request field. Like error codes, field
+// codes such as "required" are stable.
+type FieldError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// New creates an error answered with status
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// Internal is a 500 whose cause is logged instead of shown
+func Internal(message string, cause error) *Error {
+	return New(http.StatusInternalServerError, CodeInternal, message).WithCause(cause)
+}
+
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return e.Code + ": " + e.Message + ": " + e.Cause.Error()
+	}
+	return e.Code + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// WithCause returns a copy of e that records cause for the logs
+func (e *Error) WithCause(cause error) *Error {
+	copy := *e
+	copy.Cause = cause
+	return &copy
+}
+
+// WithField returns a copy of e with problems about one request field
+func (e *Error) WithField(field string, problems ...FieldError) *Error {
+	copy := *e
+	copy.Fields = make(map[string][]FieldError, len(e.Fields)+1)
+	for name, existing := range e.Fields {
+		copy.Fields[name] = existing
+	}
+	copy.Fields[field] = append(append([]FieldError(nil), copy.Fields[field]...), problems...)
+	return &copy
+}
+
+// Abort stops the handler chain with err, which Middleware renders. Errors
+// that are not *Error are answered with a generic 500.
+func Abort(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// Middleware renders the last error recorded with Abort, unless a response
+// has already been written, and logs server errors with their cause
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		last := c.Errors.Last()
+		if last == nil {
+			return
+		}
+
+		var apiErr *Error
+		if !errors.As(last.Err, &apiErr) {
+			apiErr = Internal("Something went wrong", last.Err)
+		}
+		if apiErr.Status >= http.StatusInternalServerError {
+			logging.FromContext(c).Error("request failed", "code", apiErr.Code, "error", apiErr)
+		}
+		if c.Writer.Written() {
+			return
+		}
+		Write(c, apiErr)
+	}
+}
+
+// Write responds with err in the envelope straight away, for code that runs
+// outside Middleware
+func Write(c *gin.Context, err *Error) {
+	body := *err
+	body.RequestID = c.GetString("request_id")
+	c.AbortWithStatusJSON(err.Status, gin.H{"error": body})
+}
@@ -0,0 +1,146 @@
+package apierror_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/logging"
+	"github.com/muneerlalji/Luma/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errMemoryNotFound = apierror.New(http.StatusNotFound, "memory_not_found", "Memory not found")
+
+type createMemoryRequest struct {
+	Title string `json:"title" binding:"required,max=5"`
+	Type  string `json:"type" binding:"required,oneof=text photo"`
+	Count int    `json:"count"`
+}
+
+func newRouter(out *bytes.Buffer) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.ContextWithFallback = true
+	router.Use(
+		middleware.RequestID(logging.New(out, logging.FormatJSON, slog.LevelInfo)),
+		apierror.Middleware(),
+	)
+	router.GET("/memories/missing", func(c *gin.Context) {
+		apierror.Abort(c, errMemoryNotFound)
+	})
+	router.POST("/memories", func(c *gin.Context) {
+		var req createMemoryRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.Abort(c, apierror.Bind(err))
+			return
+		}
+		c.Status(http.StatusCreated)
+	})
+	router.GET("/upload", func(c *gin.Context) {
+		apierror.Abort(c, apierror.Internal("Failed to upload photo", errors.New("s3: access denied for bucket luma-prod")))
+	})
+	router.GET("/plain", func(c *gin.Context) {
+		apierror.Abort(c, errors.New("pq: connection refused"))
+	})
+	router.GET("/written", func(c *gin.Context) {
+		c.String(http.StatusOK, "partial")
+		apierror.Abort(c, apierror.Internal("Streaming failed", errors.New("stream closed")))
+	})
+	return router
+}
+
+type envelope struct {
+	Error apierror.Error `json:"error"`
+}
+
+func do(t *testing.T, router *gin.Engine, method, path, body string) (*httptest.ResponseRecorder, envelope) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.RequestIDHeader, "req-1")
+	router.ServeHTTP(w, req)
+
+	var response envelope
+	if strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	}
+	return w, response
+}
+
+func TestMiddleware_RendersEnvelope(t *testing.T) {
+	var out bytes.Buffer
+	w, response := do(t, newRouter(&out), "GET", "/memories/missing", "")
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "memory_not_found", response.Error.Code)
+	assert.Equal(t, "Memory not found", response.Error.Message)
+	assert.Equal(t, "req-1", response.Error.RequestID)
+	assert.Empty(t, response.Error.Fields)
+	assert.Empty(t, out.String(), "client errors are not logged")
+}
+
+func TestMiddleware_HidesInternalCause(t *testing.T) {
+	var out bytes.Buffer
+	router := newRouter(&out)
+
+	w, response := do(t, router, "GET", "/upload", "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, apierror.CodeInternal, response.Error.Code)
+	assert.Equal(t, "Failed to upload photo", response.Error.Message)
+	assert.NotContains(t, w.Body.String(), "luma-prod")
+	assert.Contains(t, out.String(), "luma-prod")
+
+	w, response = do(t, router, "GET", "/plain", "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, apierror.CodeInternal, response.Error.Code)
+	assert.NotContains(t, w.Body.String(), "connection refused")
+}
+
+func TestMiddleware_KeepsWrittenResponse(t *testing.T) {
+	var out bytes.Buffer
+	w, _ := do(t, newRouter(&out), "GET", "/written", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "partial", w.Body.String())
+	assert.Contains(t, out.String(), "stream closed")
+}
+
+func TestBind_FieldErrors(t *testing.T) {
+	var out bytes.Buffer
+	router := newRouter(&out)
+
+	w, response := do(t, router, "POST", "/memories", `{"title": "Summer at the lake", "type": "video"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, apierror.CodeValidationFailed, response.Error.Code)
+	assert.Equal(t, []apierror.FieldError{{Code: "max", Message: "must be at most 5 characters"}}, response.Error.Fields["title"])
+	assert.Equal(t, []apierror.FieldError{{Code: "oneof", Message: "must be one of text, photo"}}, response.Error.Fields["type"])
+
+	w, response = do(t, router, "POST", "/memories", `{"title": "Lake", "type": "text", "count": "three"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "type", response.Error.Fields["count"][0].Code)
+
+	w, response = do(t, router, "POST", "/memories", `{"title": `)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, apierror.CodeInvalidRequest, response.Error.Code)
+	assert.Empty(t, response.Error.Fields)
+}
+
+func TestWithField_DoesNotModifyOriginal(t *testing.T) {
+	base := apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Some fields are invalid")
+	withTitle := base.WithField("title", apierror.FieldError{Code: "required", Message: "is required"})
+	withBoth := withTitle.WithField("type", apierror.FieldError{Code: "required", Message: "is required"})
+
+	assert.Empty(t, base.Fields)
+	assert.Len(t, withTitle.Fields, 1)
+	assert.Len(t, withBoth.Fields, 2)
+}
@@ -0,0 +1,87 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// Report fields by their JSON names rather than Go struct field names
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			for _, tag := range []string{"json", "form"} {
+				name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+				if name == "-" {
+					return ""
+				}
+				if name != "" {
+					return name
+				}
+			}
+			return field.Name
+		})
+	}
+}
+
+// Bind describes an error from binding a request, with a message for each
+// invalid field instead of the raw validator output
+func Bind(err error) *Error {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		apiErr := New(http.StatusBadRequest, CodeValidationFailed, "Some fields are invalid")
+		for _, fieldErr := range validationErrs {
+			apiErr = apiErr.WithField(fieldErr.Field(), FieldError{Code: fieldErr.Tag(), Message: fieldMessage(fieldErr)})
+		}
+		return apiErr
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return New(http.StatusBadRequest, CodeValidationFailed, "Some fields are invalid").
+			WithField(typeErr.Field, FieldError{Code: "type", Message: "has the wrong type"})
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return New(http.StatusBadRequest, CodeInvalidRequest, "Request body is not valid JSON")
+	}
+	return New(http.StatusBadRequest, CodeInvalidRequest, "Invalid request").WithCause(err)
+}
+
+func fieldMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		if fieldErr.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters", fieldErr.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fieldErr.Param())
+	case "max":
+		if fieldErr.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters", fieldErr.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fieldErr.Param())
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fieldErr.Param()), ", ")
+	case "datetime":
+		if fieldErr.Param() == "15:04" {
+			return "must be a time written as HH:MM"
+		}
+		return "must be a date written as YYYY-MM-DD"
+	case "uuid", "uuid4":
+		return "must be a valid ID"
+	default:
+		return "is invalid"
+	}
+}
@@ -0,0 +1,22 @@
+package apierror
+
+import "net/http"
+
+// Codes shared across handlers. Resource-specific codes, such as
+// "memory_not_found", are declared next to the handlers that use them.
+const (
+	CodeInternal         = "internal_error"
+	CodeInvalidRequest   = "invalid_request"
+	CodeValidationFailed = "validation_failed"
+	CodeUnauthenticated  = "unauthenticated"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeRateLimited      = "rate_limited"
+)
+
+// Errors used across handlers and middleware
+var (
+	ErrUnauthenticated  = New(http.StatusUnauthorized, CodeUnauthenticated, "User not authenticated")
+	ErrNotFound         = New(http.StatusNotFound, CodeNotFound, "Not found")
+	ErrMethodNotAllowed = New(http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+)
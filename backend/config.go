@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/muneerlalji/Luma/config"
+	"github.com/muneerlalji/Luma/webpush"
+)
+
+const configUsage = `Usage: luma config <command>
+
+Commands:
+  check             validate the configuration and print it with secrets redacted
+  vapid-keys        print a new key pair for VAPID_PUBLIC_KEY and VAPID_PRIVATE_KEY
+
+Flags:
+  -file <path>      YAML or TOML config file to read instead of CONFIG_FILE
+`
+
+// runConfig implements the config subcommand
+func runConfig(args []string) {
+	flags := flag.NewFlagSet("config", flag.ExitOnError)
+	file := flags.String("file", "", "config file to read instead of CONFIG_FILE")
+	flags.Usage = func() { fmt.Fprint(os.Stderr, configUsage) }
+	flags.Parse(args)
+
+	if flags.NArg() == 1 && flags.Arg(0) == "vapid-keys" {
+		public, private, err := webpush.GenerateKeys()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("VAPID_PUBLIC_KEY=%s\nVAPID_PRIVATE_KEY=%s\n", public, private)
+		return
+	}
+	if flags.NArg() != 1 || flags.Arg(0) != "check" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load(config.Options{ConfigFile: *file})
+	if cfg == nil {
+		log.Fatal(err)
+	}
+	cfg.WriteRedacted(os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\n%v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("\nConfiguration is valid")
+}
+
+// loadConfig reads the configuration and exits on any problem with the
+// given settings, or with any setting at all when none are given
+func loadConfig(keys ...string) *config.Config {
+	cfg, err := config.Load(config.Options{})
+	if errs, ok := err.(config.Errors); ok && len(keys) > 0 {
+		err = errs.Only(keys...)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	return cfg
+}
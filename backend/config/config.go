@@ -0,0 +1,601 @@
+// Package config loads the backend's settings once at startup, validates
+// them together and hands typed values to the rest of the program.
+package config
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/muneerlalji/Luma/handlers"
+	"github.com/muneerlalji/Luma/jobs"
+	"github.com/muneerlalji/Luma/logging"
+	"github.com/muneerlalji/Luma/middleware"
+	"github.com/muneerlalji/Luma/oidc"
+	"github.com/muneerlalji/Luma/tracing"
+	"github.com/muneerlalji/Luma/webpush"
+)
+
+// DefaultAnthropicAPIURL is the Messages API endpoint used for chat
+const DefaultAnthropicAPIURL = "https://api.anthropic.com/v1/messages"
+
+// DefaultLegacyAPISunset is when the unversioned API aliases are removed
+var DefaultLegacyAPISunset = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+
+// minJWTSecretLength is the shortest HS256 secret accepted
+const minJWTSecretLength = 32
+
+// Config is the validated configuration of the backend
+type Config struct {
+	Port    string
+	GinMode string
+	Server  Server
+	Log     Log
+	Metrics Metrics
+	Tracing tracing.Config
+
+	// LegacyAPISunset is announced in the Sunset header of the unversioned
+	// API aliases
+	LegacyAPISunset time.Time
+
+	DatabaseDSN    string
+	MigrateOnStart bool
+
+	AWSRegion string
+	S3Bucket  string
+	// AWSAccessKeyID and AWSSecretAccessKey override the default AWS
+	// credential chain when both are set
+	AWSAccessKeyID     string
+	AWSSecretAccessKey string
+
+	SMTP SMTP
+
+	ClaudeAPIKey    string
+	AnthropicAPIURL string
+
+	// WebPush holds the VAPID keys notifications are pushed with; push is
+	// off without them
+	WebPush webpush.Config
+
+	// UnconfirmedAccountTTL is how long never-confirmed accounts are kept;
+	// zero disables the purge
+	UnconfirmedAccountTTL time.Duration
+	// ReminderInterval is how often the jobs that open reminders, send
+	// digests, notify routines, escalate check-ins, choose memory prompts
+	// and push notifications run; zero disables them on this instance's
+	// worker
+	ReminderInterval time.Duration
+	// EmailInterval is how often the email outbox is checked for emails due
+	// a retry; queued emails are sent straight away. Zero disables sending
+	// on this instance's worker.
+	EmailInterval time.Duration
+
+	// EmbeddedWorker runs the job worker inside the server; without it the
+	// worker command runs the jobs
+	EmbeddedWorker bool
+	// Jobs holds the job worker settings
+	Jobs jobs.Config
+
+	// RateLimitStore is "memory" or "postgres"
+	RateLimitStore string
+	// RateLimits holds per-route overrides keyed by lowercase route name
+	RateLimits map[string]RateLimit
+
+	OIDC []oidc.Config
+
+	// Handlers is passed to handlers.New
+	Handlers handlers.Config
+
+	settings []Setting
+}
+
+// Server holds the HTTP server timeouts; zero disables a timeout
+type Server struct {
+	ReadHeaderTimeout time.Duration
+	// ReadTimeout covers the whole request body, so it bounds uploads
+	ReadTimeout time.Duration
+	// WriteTimeout also cuts off streaming chat responses; off by default
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// ShutdownTimeout is how long in-flight requests may drain on SIGTERM
+	ShutdownTimeout time.Duration
+}
+
+// Log holds the logging settings
+type Log struct {
+	// Format is "json" or "text"
+	Format string
+	Level  slog.Level
+}
+
+// Metrics controls where /metrics is served. It is not served at all unless
+// Addr or Token is set.
+type Metrics struct {
+	// Addr is a separate listener such as ":9090" kept off the public port
+	Addr string
+	// Token, when set, must be sent as a bearer token to read metrics
+	Token string
+}
+
+// SMTP holds the mail server settings. Email is disabled when Host is empty.
+type SMTP struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	From     string
+}
+
+// RateLimit overrides the default limits of a route; nil keeps the default
+type RateLimit struct {
+	PerIP    *middleware.Limit
+	PerEmail *middleware.Limit
+}
+
+// RateLimit returns defaults with the overrides for name applied
+func (c *Config) RateLimit(name string, defaults middleware.RateLimitConfig) middleware.RateLimitConfig {
+	config := defaults
+	config.Name = strings.ToLower(name)
+	if override, ok := c.RateLimits[config.Name]; ok {
+		if override.PerIP != nil {
+			config.PerIP = *override.PerIP
+		}
+		if override.PerEmail != nil {
+			config.PerEmail = *override.PerEmail
+		}
+	}
+	return config
+}
+
+// Setting is one resolved setting and where its value came from
+type Setting struct {
+	Key    string
+	Value  string
+	Origin string
+	Secret bool
+}
+
+// Settings returns every setting read, in the order it was read
+func (c *Config) Settings() []Setting {
+	return c.settings
+}
+
+// WriteRedacted prints the effective configuration with secrets hidden
+func (c *Config) WriteRedacted(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, setting := range c.settings {
+		value := setting.Value
+		if setting.Secret && value != "" {
+			value = "[redacted]"
+		}
+		if value == "" {
+			value = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t(%s)\n", setting.Key, value, setting.Origin)
+	}
+	return tw.Flush()
+}
+
+// Options controls where Load reads settings from
+type Options struct {
+	// Environ defaults to os.Environ()
+	Environ []string
+	// EnvFile defaults to ".env"; a missing file is ignored
+	EnvFile string
+	// ConfigFile defaults to the CONFIG_FILE setting
+	ConfigFile string
+}
+
+// Load reads settings from, highest precedence first, the environment, the
+// dotenv file and the YAML or TOML config file, then validates them. Any
+// setting can instead be read from a file named by <KEY>_FILE. Problems with
+// individual settings are returned together as Errors along with the config
+// so they can be reported; other errors leave the config nil.
+func Load(opts Options) (*Config, error) {
+	if opts.Environ == nil {
+		opts.Environ = os.Environ()
+	}
+	if opts.EnvFile == "" {
+		opts.EnvFile = ".env"
+	}
+
+	src, err := newSource(opts.Environ, opts.EnvFile, opts.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &loader{src: src}
+	c := &Config{}
+
+	c.Port = l.string("PORT", "", false)
+	if c.Port == "" {
+		l.fail("PORT", "is required")
+	} else if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		l.fail("PORT", "must be a port number")
+	}
+	c.GinMode = l.oneOf("GIN_MODE", "debug", "debug", "release", "test")
+	c.Server = Server{
+		ReadHeaderTimeout: l.duration("HTTP_READ_HEADER_TIMEOUT", 10*time.Second, true),
+		ReadTimeout:       l.duration("HTTP_READ_TIMEOUT", 5*time.Minute, true),
+		WriteTimeout:      l.duration("HTTP_WRITE_TIMEOUT", 0, true),
+		IdleTimeout:       l.duration("HTTP_IDLE_TIMEOUT", 2*time.Minute, true),
+		ShutdownTimeout:   l.duration("SHUTDOWN_TIMEOUT", 30*time.Second, false),
+	}
+
+	// JSON suits log collectors in production, text is easier to read locally
+	defaultLogFormat := logging.FormatText
+	if c.GinMode == "release" {
+		defaultLogFormat = logging.FormatJSON
+	}
+	c.Log.Format = l.oneOf("LOG_FORMAT", defaultLogFormat, logging.FormatJSON, logging.FormatText)
+	if err := c.Log.Level.UnmarshalText([]byte(l.oneOf("LOG_LEVEL", "info", "debug", "info", "warn", "error"))); err != nil {
+		c.Log.Level = slog.LevelInfo
+	}
+
+	c.Metrics = Metrics{
+		Addr:  l.string("METRICS_ADDR", "", false),
+		Token: l.string("METRICS_TOKEN", "", true),
+	}
+	if c.Metrics.Addr != "" {
+		if _, _, err := net.SplitHostPort(c.Metrics.Addr); err != nil {
+			l.fail("METRICS_ADDR", "must be a listen address such as :9090")
+		}
+	}
+
+	c.Tracing = tracing.Config{
+		Exporter:     l.oneOf("TRACING_EXPORTER", tracing.ExporterNone, tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP),
+		OTLPEndpoint: l.url("TRACING_OTLP_ENDPOINT", ""),
+		SampleRatio:  l.float("TRACING_SAMPLE_RATIO", 1),
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		l.fail("TRACING_SAMPLE_RATIO", "must be between 0 and 1")
+	}
+
+	c.LegacyAPISunset = l.date("API_LEGACY_SUNSET", DefaultLegacyAPISunset)
+
+	c.DatabaseDSN = l.string("POSTGRES_DSN", "", true)
+	l.require("POSTGRES_DSN", c.DatabaseDSN)
+	c.MigrateOnStart = l.bool("MIGRATE_ON_START", false)
+
+	c.AWSRegion = l.string("AWS_REGION", "", false)
+	l.require("AWS_REGION", c.AWSRegion)
+	c.S3Bucket = l.string("S3_BUCKET", "", false)
+	l.require("S3_BUCKET", c.S3Bucket)
+	c.AWSAccessKeyID = l.string("AWS_ACCESS_KEY_ID", "", false)
+	c.AWSSecretAccessKey = l.string("AWS_SECRET_ACCESS_KEY", "", true)
+	if (c.AWSAccessKeyID == "") != (c.AWSSecretAccessKey == "") {
+		l.fail("AWS_ACCESS_KEY_ID", "and AWS_SECRET_ACCESS_KEY must be set together")
+	}
+
+	c.SMTP = SMTP{
+		Host:     l.string("SMTP_HOST", "", false),
+		Port:     l.int("SMTP_PORT", 465),
+		User:     l.string("SMTP_USER", "", false),
+		Password: l.string("SMTP_PASS", "", true),
+		From:     l.string("SMTP_FROM", "", false),
+	}
+	if c.SMTP.Host != "" {
+		l.require("SMTP_USER", c.SMTP.User)
+		l.require("SMTP_PASS", c.SMTP.Password)
+		l.require("SMTP_FROM", c.SMTP.From)
+	}
+
+	c.ClaudeAPIKey = l.string("CLAUDE_API_KEY", "", true)
+	c.AnthropicAPIURL = l.url("ANTHROPIC_API_URL", DefaultAnthropicAPIURL)
+
+	c.WebPush = webpush.Config{
+		PublicKey:  l.string("VAPID_PUBLIC_KEY", "", false),
+		PrivateKey: l.string("VAPID_PRIVATE_KEY", "", true),
+		Subject:    l.string("VAPID_SUBJECT", "", false),
+	}
+	if c.WebPush.PublicKey != "" || c.WebPush.PrivateKey != "" {
+		l.require("VAPID_PUBLIC_KEY", c.WebPush.PublicKey)
+		l.require("VAPID_PRIVATE_KEY", c.WebPush.PrivateKey)
+		l.require("VAPID_SUBJECT", c.WebPush.Subject)
+		if !l.failed("VAPID_PUBLIC_KEY") && !l.failed("VAPID_PRIVATE_KEY") && !l.failed("VAPID_SUBJECT") {
+			if _, err := webpush.New(c.WebPush); err != nil {
+				l.fail("VAPID_PRIVATE_KEY", "%v", err)
+			}
+		}
+	}
+
+	c.UnconfirmedAccountTTL = l.duration("UNCONFIRMED_ACCOUNT_TTL", 7*24*time.Hour, true)
+	c.ReminderInterval = l.duration("REMINDER_INTERVAL", 5*time.Minute, true)
+	c.EmailInterval = l.duration("EMAIL_OUTBOX_INTERVAL", 30*time.Second, true)
+
+	c.EmbeddedWorker = l.bool("WORKER_EMBEDDED", true)
+	c.Jobs = jobs.Config{
+		Concurrency:     l.int("WORKER_CONCURRENCY", jobs.DefaultConfig.Concurrency),
+		PollInterval:    l.duration("WORKER_POLL_INTERVAL", jobs.DefaultConfig.PollInterval, false),
+		Lease:           l.duration("JOB_LEASE", jobs.DefaultConfig.Lease, false),
+		RetryDelay:      l.duration("JOB_RETRY_DELAY", jobs.DefaultConfig.RetryDelay, false),
+		Retention:       l.duration("JOB_RETENTION", 72*time.Hour, true),
+		ShutdownTimeout: c.Server.ShutdownTimeout,
+	}
+	if c.Jobs.Concurrency < 1 {
+		l.fail("WORKER_CONCURRENCY", "must be at least 1")
+	}
+
+	c.RateLimitStore = l.oneOf("RATE_LIMIT_STORE", "memory", "memory", "postgres")
+	c.RateLimits = l.rateLimits()
+
+	c.OIDC = l.oidcProviders()
+
+	c.Handlers = l.handlerConfig()
+
+	c.settings = l.settings
+	if len(l.errs) > 0 {
+		return c, l.errs
+	}
+	return c, nil
+}
+
+// handlerConfig reads the settings handlers use at request time
+func (l *loader) handlerConfig() handlers.Config {
+	config := handlers.DefaultConfig()
+
+	config.JWTSecret = l.string("JWT_SECRET", "", true)
+	if len(config.JWTSecret) < minJWTSecretLength && !l.failed("JWT_SECRET") {
+		l.fail("JWT_SECRET", "must be at least %d characters", minJWTSecretLength)
+	}
+
+	config.FrontendURL = strings.TrimRight(l.url("FRONTEND_URL", ""), "/")
+	l.require("FRONTEND_URL", config.FrontendURL)
+	config.APIBaseURL = strings.TrimRight(l.url("API_BASE_URL", ""), "/")
+	l.require("API_BASE_URL", config.APIBaseURL)
+
+	config.ConfirmationTokenTTL = l.duration("CONFIRMATION_TOKEN_TTL", config.ConfirmationTokenTTL, false)
+	config.ResetTokenTTL = l.duration("RESET_TOKEN_TTL", config.ResetTokenTTL, false)
+	config.EmailChangeCancelTTL = l.duration("EMAIL_CHANGE_CANCEL_TTL", config.EmailChangeCancelTTL, false)
+	config.LockoutThreshold = l.int("LOGIN_LOCKOUT_THRESHOLD", config.LockoutThreshold)
+	if config.LockoutThreshold < 1 {
+		l.fail("LOGIN_LOCKOUT_THRESHOLD", "must be at least 1")
+	}
+	config.LockoutDuration = l.duration("LOGIN_LOCKOUT_DURATION", config.LockoutDuration, false)
+	config.NotificationPollInterval = l.duration("NOTIFICATION_POLL_INTERVAL", config.NotificationPollInterval, false)
+
+	for _, email := range strings.Split(l.string("ADMIN_EMAILS", "", false), ",") {
+		if email = strings.TrimSpace(email); email != "" {
+			config.AdminEmails = append(config.AdminEmails, strings.ToLower(email))
+		}
+	}
+
+	policy := &config.PasswordPolicy
+	policy.MinLength = l.int("PASSWORD_MIN_LENGTH", policy.MinLength)
+	if policy.MinLength < 1 || policy.MinLength > policy.MaxLength {
+		l.fail("PASSWORD_MIN_LENGTH", "must be between 1 and %d", policy.MaxLength)
+	}
+	policy.RequireUpper = l.bool("PASSWORD_REQUIRE_UPPER", policy.RequireUpper)
+	policy.RequireLower = l.bool("PASSWORD_REQUIRE_LOWER", policy.RequireLower)
+	policy.RequireDigit = l.bool("PASSWORD_REQUIRE_DIGIT", policy.RequireDigit)
+	policy.RequireSymbol = l.bool("PASSWORD_REQUIRE_SYMBOL", policy.RequireSymbol)
+	policy.RejectCommon = l.bool("PASSWORD_REJECT_COMMON", policy.RejectCommon)
+
+	return config
+}
+
+// rateLimits reads RATE_LIMIT_<NAME>_IP and RATE_LIMIT_<NAME>_EMAIL, both
+// written as "<requests>/<window>". A value of "0/1s" disables that limit.
+func (l *loader) rateLimits() map[string]RateLimit {
+	limits := map[string]RateLimit{}
+	for _, key := range l.src.keys("RATE_LIMIT_") {
+		key = strings.TrimSuffix(key, "_FILE")
+		var name string
+		var field func(*RateLimit) **middleware.Limit
+		switch {
+		case strings.HasSuffix(key, "_IP"):
+			name = strings.TrimSuffix(strings.TrimPrefix(key, "RATE_LIMIT_"), "_IP")
+			field = func(r *RateLimit) **middleware.Limit { return &r.PerIP }
+		case strings.HasSuffix(key, "_EMAIL"):
+			name = strings.TrimSuffix(strings.TrimPrefix(key, "RATE_LIMIT_"), "_EMAIL")
+			field = func(r *RateLimit) **middleware.Limit { return &r.PerEmail }
+		default:
+			continue
+		}
+
+		name = strings.ToLower(name)
+		override := limits[name]
+		if *field(&override) != nil {
+			continue
+		}
+		value := l.string(key, "", false)
+		if value == "" {
+			continue
+		}
+		limit, err := middleware.ParseLimit(value)
+		if err != nil {
+			l.fail(key, "%v", err)
+			continue
+		}
+		*field(&override) = &limit
+		limits[name] = override
+	}
+	return limits
+}
+
+// oidcProviders reads OIDC_PROVIDERS, a comma separated list of provider
+// names, and for each name OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID,
+// OIDC_<NAME>_CLIENT_SECRET, OIDC_<NAME>_REDIRECT_URL and optionally
+// OIDC_<NAME>_SCOPES (space separated) and OIDC_<NAME>_DISPLAY_NAME
+func (l *loader) oidcProviders() []oidc.Config {
+	var configs []oidc.Config
+
+	for _, name := range strings.Split(l.string("OIDC_PROVIDERS", "", false), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		config := oidc.Config{
+			Name:         name,
+			DisplayName:  l.string(prefix+"DISPLAY_NAME", name, false),
+			Issuer:       l.url(prefix+"ISSUER", ""),
+			ClientID:     l.string(prefix+"CLIENT_ID", "", false),
+			ClientSecret: l.string(prefix+"CLIENT_SECRET", "", true),
+			RedirectURL:  l.url(prefix+"REDIRECT_URL", ""),
+			Scopes: strings.FieldsFunc(l.string(prefix+"SCOPES", "", false), func(r rune) bool {
+				return r == ' ' || r == ','
+			}),
+		}
+		l.require(prefix+"ISSUER", config.Issuer)
+		l.require(prefix+"CLIENT_ID", config.ClientID)
+		l.require(prefix+"REDIRECT_URL", config.RedirectURL)
+
+		configs = append(configs, config)
+	}
+
+	return configs
+}
+
+// loader reads typed settings from a source, recording each one and
+// collecting problems instead of stopping at the first
+type loader struct {
+	src      *source
+	settings []Setting
+	errs     Errors
+}
+
+func (l *loader) fail(key, format string, args ...interface{}) {
+	l.errs = append(l.errs, &Error{Key: key, Message: fmt.Sprintf(format, args...)})
+}
+
+func (l *loader) require(key, value string) {
+	if value == "" && !l.failed(key) {
+		l.fail(key, "is required")
+	}
+}
+
+func (l *loader) failed(key string) bool {
+	for _, err := range l.errs {
+		if err.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns the raw value of key and records it; set is false when
+// the key is unset or empty
+func (l *loader) lookup(key, fallback string, secret bool) (string, bool) {
+	value, origin, ok, err := l.src.lookup(key)
+	if err != nil {
+		l.fail(key, "%v", err)
+	}
+	value = strings.TrimSpace(value)
+	set := ok && err == nil && value != ""
+	if !set {
+		value, origin = fallback, "default"
+	}
+	l.settings = append(l.settings, Setting{Key: key, Value: value, Origin: origin, Secret: secret})
+	return value, set
+}
+
+func (l *loader) string(key, fallback string, secret bool) string {
+	value, _ := l.lookup(key, fallback, secret)
+	return value
+}
+
+func (l *loader) int(key string, fallback int) int {
+	value, set := l.lookup(key, strconv.Itoa(fallback), false)
+	if !set {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		l.fail(key, "must be a whole number, got %q", value)
+		return fallback
+	}
+	return parsed
+}
+
+func (l *loader) float(key string, fallback float64) float64 {
+	value, set := l.lookup(key, strconv.FormatFloat(fallback, 'g', -1, 64), false)
+	if !set {
+		return fallback
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		l.fail(key, "must be a number, got %q", value)
+		return fallback
+	}
+	return parsed
+}
+
+func (l *loader) bool(key string, fallback bool) bool {
+	value, set := l.lookup(key, strconv.FormatBool(fallback), false)
+	if !set {
+		return fallback
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		l.fail(key, "must be true or false, got %q", value)
+		return fallback
+	}
+	return parsed
+}
+
+// duration parses a Go duration such as "15m"; zero is only accepted when
+// allowZero is set
+func (l *loader) duration(key string, fallback time.Duration, allowZero bool) time.Duration {
+	value, set := l.lookup(key, fallback.String(), false)
+	if !set {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		l.fail(key, "must be a duration such as 15m or 24h, got %q", value)
+		return fallback
+	}
+	if parsed < 0 || parsed == 0 && !allowZero {
+		l.fail(key, "must be positive")
+		return fallback
+	}
+	return parsed
+}
+
+// date parses a calendar date such as "2027-04-30" as midnight UTC
+func (l *loader) date(key string, fallback time.Time) time.Time {
+	value, set := l.lookup(key, fallback.Format(time.DateOnly), false)
+	if !set {
+		return fallback
+	}
+	parsed, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		l.fail(key, "must be a date such as 2027-04-30, got %q", value)
+		return fallback
+	}
+	return parsed
+}
+
+// url accepts absolute http and https URLs
+func (l *loader) url(key, fallback string) string {
+	value, _ := l.lookup(key, fallback, false)
+	if value == "" {
+		return ""
+	}
+	parsed, err := url.Parse(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		l.fail(key, "must be an absolute http(s) URL, got %q", value)
+		return ""
+	}
+	return value
+}
+
+func (l *loader) oneOf(key, fallback string, allowed ...string) string {
+	value, _ := l.lookup(key, fallback, false)
+	for _, option := range allowed {
+		if value == option {
+			return value
+		}
+	}
+	l.fail(key, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+	return fallback
+}
@@ -0,0 +1,43 @@
+package config
+
+import "strings"
+
+// Error is a problem with a single setting
+type Error struct {
+	Key     string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Key + " " + e.Message
+}
+
+// Errors lists every problem found while loading the configuration
+type Errors []*Error
+
+func (e Errors) Error() string {
+	var b strings.Builder
+	b.WriteString("invalid configuration:")
+	for _, err := range e {
+		b.WriteString("\n  - ")
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+// Only returns the problems with the given settings, or nil when there are
+// none, for commands that need just part of the configuration
+func (e Errors) Only(keys ...string) error {
+	var only Errors
+	for _, err := range e {
+		for _, key := range keys {
+			if err.Key == key {
+				only = append(only, err)
+			}
+		}
+	}
+	if len(only) == 0 {
+		return nil
+	}
+	return only
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// layer is one place settings are read from
+type layer struct {
+	name   string
+	values map[string]string
+}
+
+// source resolves settings from layers in order of precedence
+type source struct {
+	layers []layer
+}
+
+// newSource reads the process environment, the optional dotenv file and the
+// optional config file named by CONFIG_FILE (or configFile when not empty)
+func newSource(environ []string, envFile, configFile string) (*source, error) {
+	env := layer{name: "env", values: map[string]string{}}
+	for _, pair := range environ {
+		if key, value, ok := strings.Cut(pair, "="); ok {
+			env.values[key] = value
+		}
+	}
+	src := &source{layers: []layer{env}}
+
+	if envFile != "" {
+		values, err := godotenv.Read(envFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("reading %s: %w", envFile, err)
+		}
+		if err == nil {
+			src.layers = append(src.layers, layer{name: filepath.Base(envFile), values: values})
+		}
+	}
+
+	if configFile == "" {
+		configFile, _, _ = src.raw("CONFIG_FILE")
+	}
+	if configFile != "" {
+		values, err := readConfigFile(configFile)
+		if err != nil {
+			return nil, err
+		}
+		src.layers = append(src.layers, layer{name: filepath.Base(configFile), values: values})
+	}
+
+	return src, nil
+}
+
+// raw returns the value of key from the first layer that sets it
+func (s *source) raw(key string) (value, origin string, ok bool) {
+	for _, l := range s.layers {
+		if value, ok := l.values[key]; ok {
+			return value, l.name, true
+		}
+	}
+	return "", "", false
+}
+
+// lookup resolves key, reading the value from the file named by key_FILE
+// when that is set instead so secrets can be mounted rather than exported
+func (s *source) lookup(key string) (value, origin string, ok bool, err error) {
+	value, origin, ok = s.raw(key)
+	path, fileOrigin, fromFile := s.raw(key + "_FILE")
+	if !fromFile || path == "" {
+		return value, origin, ok, nil
+	}
+	if ok {
+		return "", "", false, fmt.Errorf("cannot be set together with %s_FILE", key)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", false, fmt.Errorf("could not be read from %s_FILE: %w", key, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), fileOrigin + ", " + key + "_FILE", true, nil
+}
+
+// keys returns every key set in any layer that starts with prefix
+func (s *source) keys(prefix string) []string {
+	seen := map[string]bool{}
+	for _, l := range s.layers {
+		for key := range l.values {
+			if strings.HasPrefix(key, prefix) {
+				seen[key] = true
+			}
+		}
+	}
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// readConfigFile parses a YAML or TOML file into settings. Keys are the
+// environment variable names; nested tables join with underscores, so
+// smtp.host and SMTP_HOST are the same setting. Lists become comma
+// separated values.
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	var tree map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	default:
+		return nil, fmt.Errorf("config file %s must end in .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	flatten(values, "", tree)
+	return values, nil
+}
+
+func flatten(values map[string]string, prefix string, tree map[string]interface{}) {
+	for key, value := range tree {
+		key = strings.ToUpper(prefix + key)
+		switch value := value.(type) {
+		case map[string]interface{}:
+			flatten(values, key+"_", value)
+		case []interface{}:
+			items := make([]string, len(value))
+			for i, item := range value {
+				items[i] = fmt.Sprint(item)
+			}
+			values[key] = strings.Join(items, ",")
+		case nil:
+			values[key] = ""
+		default:
+			values[key] = fmt.Sprint(value)
+		}
+	}
+}
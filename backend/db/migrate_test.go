@@ -0,0 +1,222 @@
+package db_test
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/muneerlalji/Luma/db"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_index.up.sql":      {Data: []byte("CREATE INDEX a ON t (c);")},
+		"0002_add_index.down.sql":    {Data: []byte("DROP INDEX a;")},
+		"0001_create_table.up.sql":   {Data: []byte("CREATE TABLE t (c int);")},
+		"0001_create_table.down.sql": {Data: []byte("DROP TABLE t;")},
+		"README.md":                  {Data: []byte("ignored")},
+	}
+
+	migrations, err := db.LoadMigrations(fsys)
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, int64(1), migrations[0].Version)
+	assert.Equal(t, "create_table", migrations[0].Name)
+	assert.Equal(t, "CREATE TABLE t (c int);", migrations[0].Up)
+	assert.Equal(t, "DROP TABLE t;", migrations[0].Down)
+	assert.Equal(t, int64(2), migrations[1].Version)
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"missing down": {
+			"0001_create_table.up.sql": {Data: []byte("CREATE TABLE t (c int);")},
+		},
+		"bad filename": {
+			"create_table.sql": {Data: []byte("CREATE TABLE t (c int);")},
+		},
+		"conflicting names": {
+			"0001_create_table.up.sql": {Data: []byte("CREATE TABLE t (c int);")},
+			"0001_other.down.sql":      {Data: []byte("DROP TABLE t;")},
+		},
+	}
+
+	for name, fsys := range cases {
+		_, err := db.LoadMigrations(fsys)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := db.LoadMigrations(os.DirFS("migrations"))
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, migration := range migrations {
+		assert.Equal(t, int64(i+1), migration.Version, "migration versions should be sequential")
+	}
+}
+
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0001_initial.up.sql"), []byte("SELECT 1;"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0001_initial.down.sql"), []byte("SELECT 1;"), 0o644))
+
+	up, down, err := db.CreateMigration(dir, "Add Reminders")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "0002_add_reminders.up.sql"), up)
+	assert.Equal(t, filepath.Join(dir, "0002_add_reminders.down.sql"), down)
+
+	migrations, err := db.LoadMigrations(os.DirFS(dir))
+	require.NoError(t, err)
+	assert.Len(t, migrations, 2)
+
+	_, _, err = db.CreateMigration(dir, "drop; table")
+	assert.Error(t, err)
+}
+
+// The models as they were when AutoMigrate managed the schema
+type baselineUser struct {
+	ID                uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	Email             string    `gorm:"uniqueIndex;not null"`
+	Password          string    `gorm:"not null"`
+	DisplayName       string
+	CreatedAt         time.Time `gorm:"autoCreateTime"`
+	UpdatedAt         time.Time `gorm:"autoUpdateTime"`
+	EmailConfirmed    bool      `gorm:"default:false"`
+	ConfirmationToken string    `gorm:"size:64"`
+	ResetToken        string    `gorm:"size:64"`
+	ResetTokenExpiry  *time.Time
+}
+
+func (baselineUser) TableName() string { return "users" }
+
+type baselineMemory struct {
+	ID        uuid.UUID        `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID    uuid.UUID        `gorm:"type:uuid;not null"`
+	User      baselineUser     `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Title     string           `gorm:"not null"`
+	Type      string           `gorm:"not null"`
+	Content   string           `gorm:"type:text;not null"`
+	People    []baselinePerson `gorm:"many2many:memory_people;joinForeignKey:MemoryID;joinReferences:PersonID"`
+	CreatedAt time.Time        `gorm:"autoCreateTime"`
+}
+
+func (baselineMemory) TableName() string { return "memories" }
+
+type baselinePerson struct {
+	ID           uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID       uuid.UUID      `gorm:"type:uuid;not null"`
+	User         baselineUser   `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	FirstName    string         `gorm:"not null"`
+	LastName     string         `gorm:"not null"`
+	Email        string         `gorm:"not null"`
+	Phone        string         `gorm:"not null"`
+	Relationship string         `gorm:"not null"`
+	Notes        string         `gorm:"not null"`
+	PhotoID      *uuid.UUID     `gorm:"type:uuid"`
+	Photo        *baselinePhoto `gorm:"foreignKey:PhotoID"`
+	CreatedAt    time.Time      `gorm:"autoCreateTime"`
+	UpdatedAt    time.Time      `gorm:"autoUpdateTime"`
+}
+
+func (baselinePerson) TableName() string { return "people" }
+
+type baselinePhoto struct {
+	ID         uuid.UUID       `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID     uuid.UUID       `gorm:"type:uuid;not null"`
+	User       baselineUser    `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	MemoryID   *uuid.UUID      `gorm:"type:uuid"`
+	Memory     *baselineMemory `gorm:"foreignKey:MemoryID"`
+	S3Key      string          `gorm:"not null"`
+	Filename   string
+	Filetype   string
+	UploadedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (baselinePhoto) TableName() string { return "photos" }
+
+type baselineChatMessage struct {
+	ID        uuid.UUID    `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID    uuid.UUID    `gorm:"type:uuid;not null"`
+	User      baselineUser `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Role      string       `gorm:"not null"`
+	Content   string       `gorm:"type:text;not null"`
+	CreatedAt time.Time    `gorm:"autoCreateTime"`
+}
+
+func (baselineChatMessage) TableName() string { return "chat_messages" }
+
+// withSearchPath points dsn at a schema of its own
+func withSearchPath(dsn, schema string) string {
+	if !strings.Contains(dsn, "://") {
+		return dsn + " search_path=" + schema
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return dsn
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func TestMigrator_AdoptsAutoMigrateSchema(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("no test database: TEST_POSTGRES_DSN is not set")
+	}
+	ctx := context.Background()
+
+	// Migrate a schema of its own, so the shared test schema is left alone
+	const schema = "adopt_automigrate"
+	admin, err := db.Open(dsn)
+	require.NoError(t, err)
+	require.NoError(t, admin.Exec("DROP SCHEMA IF EXISTS "+schema+" CASCADE").Error)
+	require.NoError(t, admin.Exec("CREATE SCHEMA "+schema).Error)
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA IF EXISTS " + schema + " CASCADE") })
+
+	gormDB, err := db.Open(withSearchPath(dsn, schema))
+	require.NoError(t, err)
+	require.NoError(t, gormDB.AutoMigrate(
+		&baselineUser{}, &baselinePhoto{}, &baselineMemory{}, &baselinePerson{}, &baselineChatMessage{},
+	))
+	existing := baselineUser{Email: "existing@example.com", Password: "hash", EmailConfirmed: true}
+	require.NoError(t, gormDB.Create(&existing).Error)
+
+	migrator, err := db.NewMigrator(gormDB)
+	require.NoError(t, err)
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+
+	// Every column of today's models exists on the adopted tables
+	for _, model := range []interface{}{
+		&models.User{}, &models.Memory{}, &models.Person{}, &models.Photo{}, &models.ChatMessage{},
+	} {
+		stmt := gormDB.Model(model).Statement
+		require.NoError(t, stmt.Parse(model))
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			assert.True(t, gormDB.Migrator().HasColumn(model, field.DBName),
+				"%s.%s is missing", stmt.Schema.Table, field.DBName)
+		}
+	}
+
+	// Existing users can still sign in and be locked out
+	var user models.User
+	require.NoError(t, gormDB.First(&user, "id = ?", existing.ID).Error)
+	assert.Equal(t, 0, user.FailedLoginAttempts)
+	assert.NoError(t, gormDB.Model(&user).Update("failed_login_attempts", 1).Error)
+}
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS oidc_login_states;
+DROP TABLE IF EXISTS user_identities;
+DROP TABLE IF EXISTS rate_limit_buckets;
+DROP TABLE IF EXISTS chat_messages;
+DROP TABLE IF EXISTS memory_people;
+DROP TABLE IF EXISTS people;
+DROP TABLE IF EXISTS photos;
+DROP TABLE IF EXISTS memories;
+DROP TABLE IF EXISTS users;
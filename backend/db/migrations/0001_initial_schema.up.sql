@@ -0,0 +1,131 @@
+-- Baseline schema. Statements use IF NOT EXISTS so databases created by the
+-- old AutoMigrate setup are adopted, and the columns added since then are
+-- added to tables it already created.
+
+CREATE TABLE IF NOT EXISTS users (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    email text NOT NULL,
+    password text NOT NULL,
+    display_name text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    email_confirmed boolean DEFAULT false,
+    confirmation_token varchar(64),
+    confirmation_token_expiry timestamptz,
+    reset_token varchar(64),
+    reset_token_expiry timestamptz,
+    pending_email text,
+    email_change_token varchar(64),
+    email_change_expiry timestamptz,
+    email_change_cancel_token varchar(64),
+    email_change_cancel_expiry timestamptz,
+    previous_email text,
+    failed_login_attempts bigint NOT NULL DEFAULT 0,
+    lockout_count bigint NOT NULL DEFAULT 0,
+    locked_until timestamptz,
+    unlock_token varchar(64)
+);
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS confirmation_token_expiry timestamptz,
+    ADD COLUMN IF NOT EXISTS pending_email text,
+    ADD COLUMN IF NOT EXISTS email_change_token varchar(64),
+    ADD COLUMN IF NOT EXISTS email_change_expiry timestamptz,
+    ADD COLUMN IF NOT EXISTS email_change_cancel_token varchar(64),
+    ADD COLUMN IF NOT EXISTS email_change_cancel_expiry timestamptz,
+    ADD COLUMN IF NOT EXISTS previous_email text,
+    ADD COLUMN IF NOT EXISTS failed_login_attempts bigint NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS lockout_count bigint NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS locked_until timestamptz,
+    ADD COLUMN IF NOT EXISTS unlock_token varchar(64);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+CREATE INDEX IF NOT EXISTS idx_users_confirmation_token ON users (confirmation_token);
+CREATE INDEX IF NOT EXISTS idx_users_reset_token ON users (reset_token);
+CREATE INDEX IF NOT EXISTS idx_users_email_change_token ON users (email_change_token);
+CREATE INDEX IF NOT EXISTS idx_users_email_change_cancel_token ON users (email_change_cancel_token);
+CREATE INDEX IF NOT EXISTS idx_users_unlock_token ON users (unlock_token);
+
+CREATE TABLE IF NOT EXISTS memories (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL,
+    title text NOT NULL,
+    type text NOT NULL,
+    content text NOT NULL,
+    created_at timestamptz,
+    CONSTRAINT fk_memories_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS photos (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL,
+    memory_id uuid,
+    s3_key text NOT NULL,
+    filename text,
+    filetype text,
+    uploaded_at timestamptz,
+    CONSTRAINT fk_photos_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_photos_memory FOREIGN KEY (memory_id) REFERENCES memories (id)
+);
+
+CREATE TABLE IF NOT EXISTS people (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL,
+    first_name text NOT NULL,
+    last_name text NOT NULL,
+    email text NOT NULL,
+    phone text NOT NULL,
+    relationship text NOT NULL,
+    notes text NOT NULL,
+    photo_id uuid,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_people_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_people_photo FOREIGN KEY (photo_id) REFERENCES photos (id)
+);
+
+CREATE TABLE IF NOT EXISTS memory_people (
+    memory_id uuid NOT NULL,
+    person_id uuid NOT NULL,
+    PRIMARY KEY (memory_id, person_id),
+    CONSTRAINT fk_memory_people_memory FOREIGN KEY (memory_id) REFERENCES memories (id),
+    CONSTRAINT fk_memory_people_person FOREIGN KEY (person_id) REFERENCES people (id)
+);
+
+CREATE TABLE IF NOT EXISTS chat_messages (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL,
+    role text NOT NULL,
+    content text NOT NULL,
+    created_at timestamptz,
+    CONSTRAINT fk_chat_messages_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    key varchar(255) PRIMARY KEY,
+    tokens double precision NOT NULL,
+    updated_at timestamptz NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS user_identities (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL,
+    provider text NOT NULL,
+    subject text NOT NULL,
+    email text,
+    created_at timestamptz,
+    CONSTRAINT fk_user_identities_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_identities_provider_subject ON user_identities (provider, subject);
+
+CREATE TABLE IF NOT EXISTS oidc_login_states (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    state_hash varchar(64) NOT NULL,
+    provider text NOT NULL,
+    code_verifier text NOT NULL,
+    nonce text NOT NULL,
+    link_user_id uuid,
+    expires_at timestamptz NOT NULL,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_oidc_login_states_state_hash ON oidc_login_states (state_hash);
+CREATE INDEX IF NOT EXISTS idx_oidc_login_states_expires_at ON oidc_login_states (expires_at);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS reminders;
+DROP INDEX IF EXISTS idx_people_next_reminder_at;
+ALTER TABLE people
+    DROP COLUMN IF EXISTS contact_every_days,
+    DROP COLUMN IF EXISTS next_reminder_at,
+    DROP COLUMN IF EXISTS last_contacted_at;
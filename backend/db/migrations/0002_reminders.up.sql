@@ -0,0 +1,25 @@
+-- Reach-out reminders: a contact cadence per person and the reminders the
+-- scheduler opens from it
+
+ALTER TABLE people
+    ADD COLUMN contact_every_days bigint NOT NULL DEFAULT 0,
+    ADD COLUMN next_reminder_at timestamptz,
+    ADD COLUMN last_contacted_at timestamptz;
+CREATE INDEX idx_people_next_reminder_at ON people (next_reminder_at) WHERE contact_every_days > 0;
+
+CREATE TABLE reminders (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL,
+    person_id uuid NOT NULL,
+    due_at timestamptz NOT NULL,
+    status text NOT NULL,
+    snoozed_until timestamptz,
+    emailed_at timestamptz,
+    resolved_at timestamptz,
+    created_at timestamptz,
+    CONSTRAINT fk_reminders_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_reminders_person FOREIGN KEY (person_id) REFERENCES people (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_reminders_user_id ON reminders (user_id);
+-- At most one open reminder per person, even with several schedulers
+CREATE UNIQUE INDEX idx_reminders_open_person ON reminders (person_id) WHERE status = 'open';
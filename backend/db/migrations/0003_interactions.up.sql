@@ -0,0 +1,17 @@
+-- Log of calls, visits and messages with the people in a user's life
+
+CREATE TABLE interactions (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL,
+    person_id uuid NOT NULL,
+    type text NOT NULL,
+    occurred_at timestamptz NOT NULL,
+    notes text NOT NULL,
+    memory_id uuid,
+    created_at timestamptz,
+    CONSTRAINT fk_interactions_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_interactions_person FOREIGN KEY (person_id) REFERENCES people (id) ON DELETE CASCADE,
+    CONSTRAINT fk_interactions_memory FOREIGN KEY (memory_id) REFERENCES memories (id) ON DELETE SET NULL
+);
+CREATE INDEX idx_interactions_person_occurred_at ON interactions (person_id, occurred_at DESC);
+CREATE INDEX idx_interactions_user_occurred_at ON interactions (user_id, occurred_at);
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_users_digest;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS last_digest_at,
+    DROP COLUMN IF EXISTS caregiver_email;
+
+ALTER TABLE memories DROP COLUMN IF EXISTS occurred_on;
+
+ALTER TABLE people
+    DROP COLUMN IF EXISTS died_on,
+    DROP COLUMN IF EXISTS anniversary,
+    DROP COLUMN IF EXISTS birthday;
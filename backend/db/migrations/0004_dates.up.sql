@@ -0,0 +1,14 @@
+-- Birthdays, anniversaries and dates of death for people, dates for
+-- memories, and the caregiver who gets the daily digest of upcoming dates
+
+ALTER TABLE people
+    ADD COLUMN birthday date,
+    ADD COLUMN anniversary date,
+    ADD COLUMN died_on date;
+
+ALTER TABLE memories ADD COLUMN occurred_on date;
+
+ALTER TABLE users
+    ADD COLUMN caregiver_email text NOT NULL DEFAULT '',
+    ADD COLUMN last_digest_at timestamptz;
+CREATE INDEX idx_users_digest ON users (last_digest_at) WHERE caregiver_email <> '';
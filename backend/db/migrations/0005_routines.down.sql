@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS check_ins;
+DROP TABLE IF EXISTS routines;
+
+ALTER TABLE users DROP COLUMN IF EXISTS timezone;
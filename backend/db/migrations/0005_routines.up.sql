@@ -0,0 +1,40 @@
+-- Daily routines and medication schedules, the check-ins confirming them and
+-- the user's timezone their times are in
+
+ALTER TABLE users ADD COLUMN timezone text NOT NULL DEFAULT 'UTC';
+
+CREATE TABLE routines (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL,
+    kind text NOT NULL,
+    title text NOT NULL,
+    instructions text NOT NULL,
+    recurrence text NOT NULL,
+    times jsonb NOT NULL,
+    starts_on date NOT NULL,
+    grace_minutes bigint NOT NULL,
+    next_due_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_routines_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_routines_user_id ON routines (user_id);
+CREATE INDEX idx_routines_next_due_at ON routines (next_due_at) WHERE next_due_at IS NOT NULL;
+
+CREATE TABLE check_ins (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL,
+    routine_id uuid NOT NULL,
+    scheduled_at timestamptz NOT NULL,
+    status text NOT NULL,
+    note text NOT NULL,
+    checked_in_at timestamptz,
+    escalated_at timestamptz,
+    created_at timestamptz,
+    CONSTRAINT fk_check_ins_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_check_ins_routine FOREIGN KEY (routine_id) REFERENCES routines (id) ON DELETE CASCADE
+);
+-- One check-in per occurrence, even when the scheduler races the user
+CREATE UNIQUE INDEX idx_check_ins_occurrence ON check_ins (routine_id, scheduled_at);
+CREATE INDEX idx_check_ins_user_scheduled_at ON check_ins (user_id, scheduled_at);
+CREATE INDEX idx_check_ins_unescalated ON check_ins (scheduled_at) WHERE status = 'missed' AND escalated_at IS NULL;
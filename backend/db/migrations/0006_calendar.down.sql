@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_people_birthday_uid;
+ALTER TABLE people DROP COLUMN IF EXISTS birthday_uid;
+
+DROP INDEX IF EXISTS idx_memories_calendar_uid;
+ALTER TABLE memories DROP COLUMN IF EXISTS calendar_uid;
+
+DROP INDEX IF EXISTS idx_users_calendar_token;
+ALTER TABLE users DROP COLUMN IF EXISTS calendar_token;
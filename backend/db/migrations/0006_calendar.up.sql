@@ -0,0 +1,12 @@
+-- The secret of each user's calendar feed, and the UIDs of calendar events
+-- imported as memories and birthdays so importing again does not duplicate
+-- them
+
+ALTER TABLE users ADD COLUMN calendar_token varchar(64);
+CREATE INDEX idx_users_calendar_token ON users (calendar_token);
+
+ALTER TABLE memories ADD COLUMN calendar_uid text NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX idx_memories_calendar_uid ON memories (user_id, calendar_uid) WHERE calendar_uid <> '';
+
+ALTER TABLE people ADD COLUMN birthday_uid text NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX idx_people_birthday_uid ON people (user_id, birthday_uid) WHERE birthday_uid <> '';
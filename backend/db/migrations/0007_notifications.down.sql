@@ -0,0 +1,5 @@
+ALTER TABLE routines DROP COLUMN IF EXISTS notify_at;
+
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS push_subscriptions;
+DROP TABLE IF EXISTS notifications;
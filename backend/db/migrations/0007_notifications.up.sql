@@ -0,0 +1,49 @@
+-- In-app notifications, the browsers they are pushed to and which channels
+-- each user wants for each type of notification
+
+CREATE TABLE notifications (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL,
+    type text NOT NULL,
+    title text NOT NULL,
+    body text NOT NULL,
+    url text NOT NULL,
+    data jsonb NOT NULL,
+    in_app boolean NOT NULL,
+    push_pending boolean NOT NULL,
+    read_at timestamptz,
+    created_at timestamptz,
+    CONSTRAINT fk_notifications_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_notifications_user_created_at ON notifications (user_id, created_at) WHERE in_app;
+CREATE INDEX idx_notifications_unread ON notifications (user_id) WHERE in_app AND read_at IS NULL;
+CREATE INDEX idx_notifications_push_pending ON notifications (created_at) WHERE push_pending;
+
+CREATE TABLE push_subscriptions (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL,
+    endpoint text NOT NULL,
+    p256dh text NOT NULL,
+    auth text NOT NULL,
+    user_agent text NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_push_subscriptions_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX idx_push_subscriptions_endpoint ON push_subscriptions (endpoint);
+CREATE INDEX idx_push_subscriptions_user_id ON push_subscriptions (user_id);
+
+CREATE TABLE notification_preferences (
+    user_id uuid NOT NULL,
+    type text NOT NULL,
+    channel text NOT NULL,
+    enabled boolean NOT NULL,
+    updated_at timestamptz,
+    PRIMARY KEY (user_id, type, channel),
+    CONSTRAINT fk_notification_preferences_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- Routines are notified from their next occurrence on
+ALTER TABLE routines ADD COLUMN notify_at timestamptz;
+UPDATE routines SET notify_at = next_due_at;
+CREATE INDEX idx_routines_notify_at ON routines (notify_at) WHERE notify_at IS NOT NULL;
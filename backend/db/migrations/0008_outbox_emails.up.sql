@@ -0,0 +1,26 @@
+-- Emails are rendered into an outbox and sent by a background worker, in the
+-- recipient's locale
+
+CREATE TABLE outbox_emails (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL,
+    template text NOT NULL,
+    locale text NOT NULL,
+    recipient text NOT NULL,
+    subject text NOT NULL,
+    text_body text NOT NULL,
+    html_body text NOT NULL,
+    status text NOT NULL,
+    attempts bigint NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL,
+    last_error text NOT NULL DEFAULT '',
+    sent_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_outbox_emails_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_outbox_emails_due ON outbox_emails (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_outbox_emails_status_created_at ON outbox_emails (status, created_at);
+CREATE INDEX idx_outbox_emails_user_id ON outbox_emails (user_id);
+
+ALTER TABLE users ADD COLUMN locale text NOT NULL DEFAULT 'en';
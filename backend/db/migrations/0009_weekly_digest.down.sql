@@ -0,0 +1,3 @@
+ALTER TABLE chat_messages DROP COLUMN IF EXISTS confused;
+ALTER TABLE users DROP COLUMN IF EXISTS last_weekly_digest_at;
+ALTER TABLE users DROP COLUMN IF EXISTS weekly_digest_opt_out;
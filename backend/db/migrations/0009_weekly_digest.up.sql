@@ -0,0 +1,6 @@
+-- Caregivers get a weekly digest of how the user is doing, which they can
+-- unsubscribe from, including chat messages that suggest confusion
+
+ALTER TABLE users ADD COLUMN weekly_digest_opt_out boolean NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN last_weekly_digest_at timestamptz;
+ALTER TABLE chat_messages ADD COLUMN confused boolean NOT NULL DEFAULT false;
@@ -0,0 +1,23 @@
+-- Background jobs run by workers embedded in the server or started with the
+-- worker command
+
+CREATE TABLE jobs (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    kind text NOT NULL,
+    payload jsonb NOT NULL,
+    status text NOT NULL,
+    attempts bigint NOT NULL DEFAULT 0,
+    max_attempts bigint NOT NULL,
+    run_at timestamptz NOT NULL,
+    key text,
+    lock_token text NOT NULL DEFAULT '',
+    locked_until timestamptz,
+    last_error text NOT NULL DEFAULT '',
+    finished_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX idx_jobs_key ON jobs (key);
+CREATE INDEX idx_jobs_due ON jobs (run_at) WHERE status = 'pending';
+CREATE INDEX idx_jobs_lease ON jobs (locked_until) WHERE status = 'running';
+CREATE INDEX idx_jobs_status_created_at ON jobs (status, created_at);
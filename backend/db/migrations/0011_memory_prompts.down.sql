@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS memory_prompts;
+
+DROP INDEX IF EXISTS idx_users_next_prompt_at;
+ALTER TABLE memories DROP COLUMN IF EXISTS next_resurface_on;
+ALTER TABLE memories DROP COLUMN IF EXISTS resurface_interval;
+ALTER TABLE memories DROP COLUMN IF EXISTS favorite;
+ALTER TABLE users DROP COLUMN IF EXISTS next_prompt_at;
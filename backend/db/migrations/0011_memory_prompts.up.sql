@@ -0,0 +1,26 @@
+-- Daily reminiscence prompts that resurface memories on a spaced schedule,
+-- and favourite memories that come back more often
+
+ALTER TABLE users ADD COLUMN next_prompt_at timestamptz;
+ALTER TABLE memories ADD COLUMN favorite boolean NOT NULL DEFAULT false;
+ALTER TABLE memories ADD COLUMN resurface_interval bigint NOT NULL DEFAULT 0;
+ALTER TABLE memories ADD COLUMN next_resurface_on date;
+
+CREATE TABLE memory_prompts (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL,
+    memory_id uuid NOT NULL,
+    day date NOT NULL,
+    reason text NOT NULL,
+    person_id uuid,
+    question text NOT NULL,
+    response text NOT NULL,
+    responded_at timestamptz,
+    created_at timestamptz,
+    CONSTRAINT fk_memory_prompts_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_memory_prompts_memory FOREIGN KEY (memory_id) REFERENCES memories (id) ON DELETE CASCADE,
+    CONSTRAINT fk_memory_prompts_person FOREIGN KEY (person_id) REFERENCES people (id) ON DELETE SET NULL
+);
+-- One prompt a day, even when the job races the user opening the app
+CREATE UNIQUE INDEX idx_memory_prompts_user_day ON memory_prompts (user_id, day);
+CREATE INDEX idx_users_next_prompt_at ON users (next_prompt_at);
@@ -0,0 +1,13 @@
+-- Everyone in a user's care circle gets the weekly digest, not only the
+-- caregiver, and each can unsubscribe on their own
+
+CREATE TABLE care_circle_members (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL,
+    name text NOT NULL DEFAULT '',
+    email text NOT NULL,
+    weekly_digest_opt_out boolean NOT NULL DEFAULT false,
+    created_at timestamptz,
+    CONSTRAINT fk_care_circle_members_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX idx_care_circle_members_user_email ON care_circle_members (user_id, lower(email));
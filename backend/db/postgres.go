@@ -1,42 +1,76 @@
 package db
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
-	"os"
-
-	"github.com/muneerlalji/Luma/models"
+	"log/slog"
+	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
 
-// Initialize the database connection
-func Init() {
-	dsn := os.Getenv("POSTGRES_DSN")
+// Open connects to the database at dsn
+func Open(dsn string) (*gorm.DB, error) {
 	if dsn == "" {
-		log.Fatal("Missing POSTGRES_DSN environment variable")
+		return nil, errors.New("missing database DSN")
 	}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	// TranslateError maps unique violations to gorm.ErrDuplicatedKey
+	return gorm.Open(postgres.Open(dsn), &gorm.Config{
+		TranslateError: true,
+		// Logged queries show placeholders, not values such as emails and
+		// password hashes
+		Logger: logger.New(slogWriter{}, logger.Config{
+			SlowThreshold:             200 * time.Millisecond,
+			LogLevel:                  logger.Warn,
+			IgnoreRecordNotFoundError: true,
+			ParameterizedQueries:      true,
+		}),
+	})
+}
+
+// slogWriter sends GORM's warnings to the default slog logger
+type slogWriter struct{}
+
+func (slogWriter) Printf(format string, args ...interface{}) {
+	slog.Warn(fmt.Sprintf(format, args...), "component", "gorm")
+}
+
+// Initialize the database connection. The schema is managed by migrations
+// (see migrate.go); the backend refuses to start while any are pending
+// unless migrateOnStart is set, in which case it applies them first.
+func Init(dsn string, migrateOnStart bool) error {
+	db, err := Open(dsn)
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	// Migrate tables in dependency order
-	err = db.AutoMigrate(
-		&models.User{},
-		&models.Photo{},
-		&models.Person{},
-		&models.Memory{},
-		&models.ChatMessage{},
-	)
+	migrator, err := NewMigrator(db)
 	if err != nil {
-		log.Fatal("AutoMigrate error:", err)
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	ctx := context.Background()
+	if migrateOnStart {
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return fmt.Errorf("migration error: %w", err)
+		}
+		for _, migration := range applied {
+			slog.Info("applied migration", "version", migration.Version, "name", migration.Name)
+		}
+	}
+
+	if err := migrator.RequireCurrent(ctx); err != nil {
+		return fmt.Errorf("%w (run `go run . migrate up`)", err)
 	}
 
-	fmt.Println("Connected to PostgreSQL & migrated schema")
+	slog.Info("connected to PostgreSQL")
 	DB = db
+	return nil
 }
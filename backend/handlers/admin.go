@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/mailer"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/repository"
+)
+
+const (
+	defaultOutboxEmailLimit = 50
+	maxOutboxEmailLimit     = 200
+	defaultJobLimit         = 50
+	maxJobLimit             = 200
+)
+
+// OutboxEmailResponse is the delivery status of an email, without its bodies
+type OutboxEmailResponse struct {
+	ID            uuid.UUID  `json:"id"`
+	UserID        uuid.UUID  `json:"userId"`
+	Template      string     `json:"template"`
+	Locale        string     `json:"locale"`
+	Recipient     string     `json:"recipient"`
+	Subject       string     `json:"subject"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"`
+	LastError     string     `json:"lastError,omitempty"`
+	SentAt        *time.Time `json:"sentAt,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+// JobResponse is the state of a background job
+type JobResponse struct {
+	ID          uuid.UUID       `json:"id"`
+	Kind        string          `json:"kind"`
+	Payload     json.RawMessage `json:"payload"`
+	Key         string          `json:"key,omitempty"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"maxAttempts"`
+	RunAt       time.Time       `json:"runAt"`
+	LockedUntil *time.Time      `json:"lockedUntil,omitempty"`
+	LastError   string          `json:"lastError,omitempty"`
+	FinishedAt  *time.Time      `json:"finishedAt,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+}
+
+// EmailTemplatesResponse lists the email templates and their languages
+type EmailTemplatesResponse struct {
+	Templates []string `json:"templates"`
+	Locales   []string `json:"locales"`
+}
+
+// EmailPreviewResponse is an email template rendered with sample data
+type EmailPreviewResponse struct {
+	Template string `json:"template"`
+	Locale   string `json:"locale"`
+	Subject  string `json:"subject"`
+	Text     string `json:"text"`
+	HTML     string `json:"html"`
+}
+
+func newOutboxEmailResponse(email *models.OutboxEmail) OutboxEmailResponse {
+	response := OutboxEmailResponse{
+		ID:        email.ID,
+		UserID:    email.UserID,
+		Template:  email.Template,
+		Locale:    email.Locale,
+		Recipient: email.Recipient,
+		Subject:   email.Subject,
+		Status:    email.Status,
+		Attempts:  email.Attempts,
+		LastError: email.LastError,
+		SentAt:    email.SentAt,
+		CreatedAt: email.CreatedAt,
+	}
+	if email.Status == models.EmailPending {
+		response.NextAttemptAt = &email.NextAttemptAt
+	}
+	return response
+}
+
+func newJobResponse(job *models.Job) JobResponse {
+	response := JobResponse{
+		ID:          job.ID,
+		Kind:        job.Kind,
+		Payload:     job.Payload,
+		Status:      job.Status,
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		RunAt:       job.RunAt,
+		LockedUntil: job.LockedUntil,
+		LastError:   job.LastError,
+		FinishedAt:  job.FinishedAt,
+		CreatedAt:   job.CreatedAt,
+	}
+	if job.Key != nil {
+		response.Key = *job.Key
+	}
+	return response
+}
+
+// RequireAdmin lets through only users whose email is in AdminEmails. It
+// reads the address from the account rather than the token, so a changed
+// email takes effect straight away.
+func (h *Handler) RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("user_id")
+		if !exists {
+			apierror.Abort(c, apierror.ErrUnauthenticated)
+			return
+		}
+
+		userUUID, ok := userID.(uuid.UUID)
+		if !ok {
+			apierror.Abort(c, errInvalidUserID)
+			return
+		}
+
+		user, err := h.store.Users.Get(c, userUUID)
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Abort(c, errAdminOnly)
+			return
+		}
+		if err != nil {
+			apierror.Abort(c, apierror.Internal("Failed to get user", err))
+			return
+		}
+		if !slices.Contains(h.config.AdminEmails, strings.ToLower(user.Email)) {
+			apierror.Abort(c, errAdminOnly)
+			return
+		}
+		c.Next()
+	}
+}
+
+// GetOutboxEmails lists emails newest first, optionally only those with the
+// given status
+func (h *Handler) GetOutboxEmails(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", models.EmailPending, models.EmailSent, models.EmailFailed:
+	default:
+		apierror.Abort(c, errInvalidEmailStatus)
+		return
+	}
+
+	limit := defaultOutboxEmailLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxOutboxEmailLimit {
+			apierror.Abort(c, errInvalidOutboxEmailLimit)
+			return
+		}
+		limit = parsed
+	}
+
+	emails, err := h.store.OutboxEmails.List(c, status, limit)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to get emails", err))
+		return
+	}
+	responses := make([]OutboxEmailResponse, 0, len(emails))
+	for i := range emails {
+		responses = append(responses, newOutboxEmailResponse(&emails[i]))
+	}
+	c.JSON(http.StatusOK, responses)
+}
+
+// RetryOutboxEmail puts a failed email back in the outbox with fresh attempts
+func (h *Handler) RetryOutboxEmail(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Abort(c, errInvalidOutboxEmailID)
+		return
+	}
+
+	email, err := h.store.OutboxEmails.Get(c, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		apierror.Abort(c, errOutboxEmailNotFound)
+		return
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to get email", err))
+		return
+	}
+	if email.Status != models.EmailFailed {
+		apierror.Abort(c, errOutboxEmailNotFailed)
+		return
+	}
+
+	email.Status = models.EmailPending
+	email.Attempts = 0
+	email.NextAttemptAt = h.now()
+	if err := h.store.OutboxEmails.Update(c, email); err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to retry email", err))
+		return
+	}
+	h.wakeOutbox()
+	c.JSON(http.StatusOK, newOutboxEmailResponse(email))
+}
+
+// GetEmailTemplates lists the email templates and the languages they are
+// written in
+func (h *Handler) GetEmailTemplates(c *gin.Context) {
+	c.JSON(http.StatusOK, EmailTemplatesResponse{Templates: mailer.Names(), Locales: mailer.Locales()})
+}
+
+// PreviewEmailTemplate renders an email template with sample data, in the
+// language of the locale query parameter or the default one
+func (h *Handler) PreviewEmailTemplate(c *gin.Context) {
+	name := c.Param("name")
+	locale := c.DefaultQuery("locale", mailer.DefaultLocale)
+	if !mailer.Supported(locale) {
+		apierror.Abort(c, errUnsupportedLocale)
+		return
+	}
+
+	data, err := mailer.Sample(name)
+	if errors.Is(err, mailer.ErrUnknownTemplate) {
+		apierror.Abort(c, errEmailTemplateNotFound)
+		return
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to preview email", err))
+		return
+	}
+	message, err := mailer.Render(name, locale, data)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to preview email", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, EmailPreviewResponse{
+		Template: name,
+		Locale:   locale,
+		Subject:  message.Subject,
+		Text:     message.Text,
+		HTML:     message.HTML,
+	})
+}
+
+// GetJobs lists background jobs newest first, optionally only those with the
+// given status or kind
+func (h *Handler) GetJobs(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", models.JobPending, models.JobRunning, models.JobSucceeded, models.JobDead:
+	default:
+		apierror.Abort(c, errInvalidJobStatus)
+		return
+	}
+
+	limit := defaultJobLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxJobLimit {
+			apierror.Abort(c, errInvalidJobLimit)
+			return
+		}
+		limit = parsed
+	}
+
+	jobs, err := h.store.Jobs.List(c, status, c.Query("kind"), limit)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to get jobs", err))
+		return
+	}
+	responses := make([]JobResponse, 0, len(jobs))
+	for i := range jobs {
+		responses = append(responses, newJobResponse(&jobs[i]))
+	}
+	c.JSON(http.StatusOK, responses)
+}
+
+// getJob loads the job named by the id path parameter, aborting if there is
+// none
+func (h *Handler) getJob(c *gin.Context) (*models.Job, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Abort(c, errInvalidJobID)
+		return nil, false
+	}
+
+	job, err := h.store.Jobs.Get(c, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		apierror.Abort(c, errJobNotFound)
+		return nil, false
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to get job", err))
+		return nil, false
+	}
+	return job, true
+}
+
+// GetJob returns a background job with its payload and last error
+func (h *Handler) GetJob(c *gin.Context) {
+	job, ok := h.getJob(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, newJobResponse(job))
+}
+
+// RetryJob puts a dead job back in the queue with fresh attempts
+func (h *Handler) RetryJob(c *gin.Context) {
+	job, ok := h.getJob(c)
+	if !ok {
+		return
+	}
+	if job.Status != models.JobDead {
+		apierror.Abort(c, errJobNotDead)
+		return
+	}
+
+	job.Status = models.JobPending
+	job.Attempts = 0
+	job.RunAt = h.now()
+	job.FinishedAt = nil
+	if err := h.store.Jobs.Update(c, job); err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to retry job", err))
+		return
+	}
+	c.JSON(http.StatusOK, newJobResponse(job))
+}
@@ -1,9 +1,10 @@
 package handlers
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"net/http"
-	"os"
+	"net/mail"
 	"strings"
 
 	"crypto/rand"
@@ -12,11 +13,13 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/muneerlalji/Luma/db"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/logging"
+	"github.com/muneerlalji/Luma/mailer"
 	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/repository"
 	"github.com/muneerlalji/Luma/utils"
 	"golang.org/x/crypto/bcrypt"
-	"gorm.io/gorm"
 )
 
 func generateRandomToken(n int) (string, error) {
@@ -28,58 +31,111 @@ func generateRandomToken(n int) (string, error) {
 	return hex.EncodeToString(b), nil
 }
 
+// checkPasswordPolicy validates password against the configured policy. On
+// failure it responds with each violation under field and returns false.
+func (h *Handler) checkPasswordPolicy(c *gin.Context, field, password string, personal ...string) bool {
+	violations := h.config.PasswordPolicy.Validate(password, personal...)
+	if len(violations) == 0 {
+		return true
+	}
+
+	problems := make([]apierror.FieldError, len(violations))
+	for i, violation := range violations {
+		problems[i] = apierror.FieldError{Code: violation.Code, Message: violation.Message}
+	}
+	apierror.Abort(c, errWeakPassword.WithField(field, problems...))
+	return false
+}
+
+// issueConfirmationToken stores a fresh confirmation token digest on the user
+// and returns the plaintext token for the email link
+func (h *Handler) issueConfirmationToken(user *models.User) (string, error) {
+	token, err := generateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	expiry := h.now().Add(h.config.ConfirmationTokenTTL)
+	user.ConfirmationToken = utils.HashToken(token)
+	user.ConfirmationTokenExpiry = &expiry
+	return token, nil
+}
+
+// queueConfirmationEmail queues an email with a link for the user to confirm
+// their address
+func (h *Handler) queueConfirmationEmail(ctx context.Context, store *repository.Store, user *models.User, token string) error {
+	return h.queueEmail(ctx, store, user, user.Email, mailer.ConfirmEmail, mailer.LinkData{
+		Name: user.DisplayName,
+		URL:  h.config.FrontendURL + "/confirm?token=" + token,
+	})
+}
+
 // Handles user registration
-func Register(c *gin.Context) {
+func (h *Handler) Register(c *gin.Context) {
 	var req models.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		fmt.Println(req)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Bind(err))
 		return
 	}
 
-	var existingUser models.User
-	if err := db.DB.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "User with this email already exists"})
+	if !h.checkPasswordPolicy(c, "password", req.Password, req.Email, req.DisplayName) {
 		return
 	}
-
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+	locale := req.Locale
+	if locale == "" {
+		locale = mailer.MatchLocale(c.GetHeader("Accept-Language"))
+	} else if !mailer.Supported(locale) {
+		apierror.Abort(c, errInvalidLocale)
 		return
 	}
 
-	confirmationToken, err := generateRandomToken(32)
+	if existingUser, err := h.store.Users.GetByEmail(c, req.Email); err == nil {
+		// An unconfirmed account whose link has expired no longer holds the address
+		expired := !existingUser.EmailConfirmed && existingUser.ConfirmationTokenExpiry != nil &&
+			existingUser.ConfirmationTokenExpiry.Before(h.now())
+		if !expired {
+			apierror.Abort(c, errEmailRegistered)
+			return
+		}
+		if err := h.store.Users.Delete(c, existingUser.ID); err != nil {
+			apierror.Abort(c, apierror.Internal("Failed to create user", err))
+			return
+		}
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate confirmation token"})
+		apierror.Abort(c, apierror.Internal("Failed to hash password", err))
 		return
 	}
 
 	user := models.User{
-		Email:             req.Email,
-		Password:          string(hashedPassword),
-		DisplayName:       req.DisplayName,
-		ConfirmationToken: confirmationToken,
-		EmailConfirmed:    false,
+		Email:          req.Email,
+		Password:       string(hashedPassword),
+		DisplayName:    req.DisplayName,
+		EmailConfirmed: false,
+		Locale:         locale,
 	}
 
-	if err := db.DB.Create(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+	confirmationToken, err := h.issueConfirmationToken(&user)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to generate confirmation token", err))
 		return
 	}
 
-	// Send confirmation email
-	frontendURL := os.Getenv("FRONTEND_URL")
-	if frontendURL == "" {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Missing FRONTEND_URL environment variable"})
+	// The confirmation email is only queued with the account, so an address
+	// is never left taken by an account nobody was sent a link for
+	err = h.store.Transaction(c, func(tx *repository.Store) error {
+		if err := tx.Users.Create(c, &user); err != nil {
+			return err
+		}
+		return h.queueConfirmationEmail(c, tx, &user, confirmationToken)
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		apierror.Abort(c, errEmailRegistered)
 		return
 	}
-	confirmURL := frontendURL + "/confirm?token=" + confirmationToken
-	subject := "Confirm your email"
-	body := "Please confirm your email by clicking the following link: " + confirmURL
-	if err := utils.SendEmail(user.Email, subject, body); err != nil {
-		fmt.Printf("Failed to send confirmation email: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send confirmation email"})
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to create user", err))
 		return
 	}
 
@@ -87,6 +143,7 @@ func Register(c *gin.Context) {
 		ID:          user.ID,
 		Email:       user.Email,
 		DisplayName: user.DisplayName,
+		Locale:      user.Locale,
 		CreatedAt:   user.CreatedAt,
 	}
 
@@ -96,37 +153,101 @@ func Register(c *gin.Context) {
 	})
 }
 
+// ResendConfirmation issues a new confirmation link for an unconfirmed account.
+// The response does not reveal whether such an account exists.
+func (h *Handler) ResendConfirmation(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, apierror.Bind(err))
+		return
+	}
+
+	const message = "If an unconfirmed account exists for this email, a new confirmation link has been sent."
+
+	user, err := h.store.Users.GetByEmail(c, req.Email)
+	if err != nil || user.EmailConfirmed {
+		c.JSON(http.StatusOK, gin.H{"message": message})
+		return
+	}
+
+	confirmationToken, err := h.issueConfirmationToken(user)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to generate confirmation token", err))
+		return
+	}
+
+	// The old link only stops working once the new one is queued
+	err = h.store.Transaction(c, func(tx *repository.Store) error {
+		set, err := tx.Users.SetConfirmationToken(c, user.ID, user.ConfirmationToken, *user.ConfirmationTokenExpiry)
+		if err != nil || !set {
+			// Confirmed in the meantime, so there is nothing to send
+			return err
+		}
+		return h.queueConfirmationEmail(c, tx, user, confirmationToken)
+	})
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to send confirmation email", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// PurgeUnconfirmedUsers deletes accounts that were never confirmed within
+// olderThan of registering and returns how many were removed
+func (h *Handler) PurgeUnconfirmedUsers(ctx context.Context, olderThan time.Duration) (int64, error) {
+	return h.store.Users.DeleteUnconfirmedBefore(ctx, h.now().Add(-olderThan))
+}
+
 // Handles user authentication
-func Login(c *gin.Context) {
+func (h *Handler) Login(c *gin.Context) {
 	var req models.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Bind(err))
 		return
 	}
 
-	var user models.User
-	if err := db.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+	user, err := h.store.Users.GetByEmail(c, req.Email)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Abort(c, errInvalidCredentials)
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		apierror.Abort(c, apierror.Internal("Database error", nil))
+		return
+	}
+
+	if locked, retryAfter := isLocked(user, h.now()); locked {
+		abortLocked(c, retryAfter)
 		return
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+		if err := h.recordFailedLogin(c, user); err != nil {
+			logging.FromContext(c).Error("failed to record failed login", "error", err)
+		}
+		if locked, retryAfter := isLocked(user, h.now()); locked {
+			abortLocked(c, retryAfter)
+			return
+		}
+		apierror.Abort(c, errInvalidCredentials)
 		return
 	}
 
+	if err := h.clearFailedLogins(c, user); err != nil {
+		logging.FromContext(c).Error("failed to clear failed logins", "error", err)
+	}
+
 	if !user.EmailConfirmed {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Please confirm your email before logging in."})
+		apierror.Abort(c, errEmailNotConfirmed)
 		return
 	}
 
-	token, err := utils.GenerateToken(user.ID, user.Email)
+	token, err := utils.GenerateToken(h.config.JWTSecret, user.ID, user.Email)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		apierror.Abort(c, apierror.Internal("Failed to generate token", err))
 		return
 	}
 
@@ -144,129 +265,184 @@ func Login(c *gin.Context) {
 }
 
 // GetCurrentUser returns the current authenticated user's information
-func GetCurrentUser(c *gin.Context) {
+func (h *Handler) GetCurrentUser(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		apierror.Abort(c, apierror.ErrUnauthenticated)
 		return
 	}
 
 	userUUID, ok := userID.(uuid.UUID)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		apierror.Abort(c, errInvalidUserID)
 		return
 	}
 
-	var user models.User
-	if err := db.DB.Where("id = ?", userUUID).First(&user).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	user, err := h.store.Users.Get(c, userUUID)
+	if err != nil {
+		apierror.Abort(c, errUserNotFound)
 		return
 	}
 
 	userResponse := models.UserResponse{
-		ID:          user.ID,
-		Email:       user.Email,
-		DisplayName: user.DisplayName,
-		CreatedAt:   user.CreatedAt,
+		ID:             user.ID,
+		Email:          user.Email,
+		DisplayName:    user.DisplayName,
+		PendingEmail:   user.PendingEmail,
+		CaregiverEmail: user.CaregiverEmail,
+		Timezone:       user.Timezone,
+		Locale:         user.Locale,
+		WeeklyDigest:   weeklyDigestOn(user),
+		CreatedAt:      user.CreatedAt,
 	}
 
 	c.JSON(http.StatusOK, userResponse)
 }
 
 // UpdateProfile handles profile information updates
-func UpdateProfile(c *gin.Context) {
+func (h *Handler) UpdateProfile(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		apierror.Abort(c, apierror.ErrUnauthenticated)
 		return
 	}
 
 	userUUID, ok := userID.(uuid.UUID)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		apierror.Abort(c, errInvalidUserID)
 		return
 	}
 
 	var req struct {
 		DisplayName string `json:"displayName"`
+		// CaregiverEmail is left alone when missing and removed when empty
+		CaregiverEmail *string `json:"caregiverEmail"`
+		// Timezone is left alone when missing
+		Timezone *string `json:"timezone"`
+		// Locale is left alone when missing
+		Locale *string `json:"locale"`
+		// WeeklyDigest is left alone when missing
+		WeeklyDigest *bool `json:"weeklyDigest"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Bind(err))
+		return
+	}
+
+	if req.CaregiverEmail != nil && *req.CaregiverEmail != "" {
+		if address, err := mail.ParseAddress(*req.CaregiverEmail); err != nil || address.Address != *req.CaregiverEmail {
+			apierror.Abort(c, errInvalidCaregiverEmail)
+			return
+		}
+	}
+	if req.Timezone != nil {
+		// LoadLocation reads "" and "Local" as the server's own zone
+		if _, err := time.LoadLocation(*req.Timezone); err != nil || *req.Timezone == "" || *req.Timezone == "Local" {
+			apierror.Abort(c, errInvalidTimezone)
+			return
+		}
+	}
+	if req.Locale != nil && !mailer.Supported(*req.Locale) {
+		apierror.Abort(c, errInvalidLocale)
 		return
 	}
 
-	var user models.User
-	if err := db.DB.Where("id = ?", userUUID).First(&user).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	user, err := h.store.Users.Get(c, userUUID)
+	if err != nil {
+		apierror.Abort(c, errUserNotFound)
 		return
 	}
 
 	// Update user fields
 	user.DisplayName = req.DisplayName
+	if req.CaregiverEmail != nil {
+		// A new caregiver has not unsubscribed from anything
+		if !strings.EqualFold(*req.CaregiverEmail, user.CaregiverEmail) {
+			user.WeeklyDigestOptOut = false
+		}
+		user.CaregiverEmail = *req.CaregiverEmail
+	}
+	if req.WeeklyDigest != nil {
+		user.WeeklyDigestOptOut = !*req.WeeklyDigest
+	}
+	if req.Timezone != nil {
+		user.Timezone = *req.Timezone
+	}
+	if req.Locale != nil {
+		user.Locale = *req.Locale
+	}
 
-	if err := db.DB.Save(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+	if err := h.store.Users.Update(c, user); err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to update profile", err))
 		return
 	}
 
 	userResponse := models.UserResponse{
-		ID:          user.ID,
-		Email:       user.Email,
-		DisplayName: user.DisplayName,
-		CreatedAt:   user.CreatedAt,
+		ID:             user.ID,
+		Email:          user.Email,
+		DisplayName:    user.DisplayName,
+		CaregiverEmail: user.CaregiverEmail,
+		Timezone:       user.Timezone,
+		Locale:         user.Locale,
+		WeeklyDigest:   weeklyDigestOn(user),
+		CreatedAt:      user.CreatedAt,
 	}
 
 	c.JSON(http.StatusOK, gin.H{"user": userResponse, "message": "Profile updated successfully"})
 }
 
 // ChangePassword handles password changes
-func ChangePassword(c *gin.Context) {
+func (h *Handler) ChangePassword(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		apierror.Abort(c, apierror.ErrUnauthenticated)
 		return
 	}
 
 	userUUID, ok := userID.(uuid.UUID)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		apierror.Abort(c, errInvalidUserID)
 		return
 	}
 
 	var req struct {
-		CurrentPassword string `json:"currentPassword"`
-		NewPassword     string `json:"newPassword"`
+		CurrentPassword string `json:"currentPassword" binding:"required"`
+		NewPassword     string `json:"newPassword" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Bind(err))
 		return
 	}
 
-	var user models.User
-	if err := db.DB.Where("id = ?", userUUID).First(&user).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	user, err := h.store.Users.Get(c, userUUID)
+	if err != nil {
+		apierror.Abort(c, errUserNotFound)
 		return
 	}
 
 	// Verify current password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Current password is incorrect"})
+		apierror.Abort(c, errWrongPassword)
+		return
+	}
+
+	if !h.checkPasswordPolicy(c, "newPassword", req.NewPassword, user.Email, user.DisplayName) {
 		return
 	}
 
 	// Hash new password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		apierror.Abort(c, apierror.Internal("Failed to hash password", err))
 		return
 	}
 
 	// Update password
 	user.Password = string(hashedPassword)
-	if err := db.DB.Save(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+	if err := h.store.Users.Update(c, user); err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to update password", err))
 		return
 	}
 
@@ -274,28 +450,43 @@ func ChangePassword(c *gin.Context) {
 }
 
 // DeleteAccount handles account deletion
-func DeleteAccount(c *gin.Context) {
+func (h *Handler) DeleteAccount(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		apierror.Abort(c, apierror.ErrUnauthenticated)
 		return
 	}
 
 	userUUID, ok := userID.(uuid.UUID)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		apierror.Abort(c, errInvalidUserID)
 		return
 	}
 
-	var user models.User
-	if err := db.DB.Where("id = ?", userUUID).First(&user).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	user, err := h.store.Users.Get(c, userUUID)
+	if err != nil {
+		apierror.Abort(c, errUserNotFound)
 		return
 	}
 
-	// Delete user (this will cascade to related records)
-	if err := db.DB.Delete(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+	// Delete user (this will cascade to related records), and queue the
+	// removal of their photo files
+	err = h.store.Transaction(c, func(tx *repository.Store) error {
+		keys, err := tx.Photos.KeysByUser(c, user.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Users.Delete(c, user.ID); err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+		_, err = DeletePhotoFilesJob.Enqueue(c, tx, DeletePhotoFilesPayload{Keys: keys})
+		return err
+	})
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to delete account", err))
 		return
 	}
 
@@ -303,38 +494,39 @@ func DeleteAccount(c *gin.Context) {
 }
 
 // Validates JWT token and sets user context
-func AuthMiddleware() gin.HandlerFunc {
+func (h *Handler) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+			apierror.Abort(c, errAuthHeaderRequired)
 			c.Abort()
 			return
 		}
 
 		tokenParts := strings.Split(authHeader, " ")
 		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
+			apierror.Abort(c, errInvalidAuthHeader)
 			c.Abort()
 			return
 		}
 
 		tokenString := tokenParts[1]
-		claims, err := utils.ValidateToken(tokenString)
+		claims, err := utils.ValidateToken(h.config.JWTSecret, tokenString)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			apierror.Abort(c, errInvalidAuthToken)
 			c.Abort()
 			return
 		}
 
 		c.Set("user_id", claims.UserID)
 		c.Set("user_email", claims.Email)
+		c.Request = c.Request.WithContext(logging.With(c.Request.Context(), "user_id", claims.UserID))
 		c.Next()
 	}
 }
 
 // ConfirmEmail handles email confirmation
-func ConfirmEmail(c *gin.Context) {
+func (h *Handler) ConfirmEmail(c *gin.Context) {
 	var token string
 
 	// First try to get token from query parameter
@@ -346,22 +538,30 @@ func ConfirmEmail(c *gin.Context) {
 			Token string `json:"token" binding:"required"`
 		}
 		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Token is required"})
+			apierror.Abort(c, errTokenRequired)
 			return
 		}
 		token = req.Token
 	}
 
-	var user models.User
-	if err := db.DB.Where("confirmation_token = ?", token).First(&user).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+	digest := utils.HashToken(token)
+	user, err := h.store.Users.GetByToken(c, repository.ConfirmationToken, digest)
+	if err != nil || user.ConfirmationTokenExpiry == nil || !user.ConfirmationTokenExpiry.After(h.now()) {
+		apierror.Abort(c, errInvalidToken)
 		return
 	}
 
+	// Clearing the token in the same conditional update makes it single-use
 	user.EmailConfirmed = true
 	user.ConfirmationToken = ""
-	if err := db.DB.Save(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm email"})
+	user.ConfirmationTokenExpiry = nil
+	updated, err := h.store.Users.UpdateIfToken(c, user, repository.ConfirmationToken, digest)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to confirm email", err))
+		return
+	}
+	if !updated {
+		apierror.Abort(c, errInvalidToken)
 		return
 	}
 
@@ -369,71 +569,91 @@ func ConfirmEmail(c *gin.Context) {
 }
 
 // ForgotPassword handles password reset requests
-func ForgotPassword(c *gin.Context) {
+func (h *Handler) ForgotPassword(c *gin.Context) {
 	var req struct {
 		Email string `json:"email" binding:"required,email"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Bind(err))
 		return
 	}
 
-	var user models.User
-	if err := db.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
+	user, err := h.store.Users.GetByEmail(c, req.Email)
+	if err != nil {
 		c.JSON(http.StatusOK, gin.H{"message": "If the email exists, a reset link has been sent."})
 		return
 	}
 
 	resetToken, err := generateRandomToken(32)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate reset token"})
+		apierror.Abort(c, apierror.Internal("Failed to generate reset token", err))
 		return
 	}
-	expiry := time.Now().Add(1 * time.Hour)
-	user.ResetToken = resetToken
+	expiry := h.now().Add(h.config.ResetTokenTTL)
+	user.ResetToken = utils.HashToken(resetToken)
 	user.ResetTokenExpiry = &expiry
-	if err := db.DB.Save(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save reset token"})
+	if err := h.store.Users.Update(c, user); err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to save reset token", err))
+		return
+	}
+	err = h.queueEmail(c, h.store, user, user.Email, mailer.ResetPassword, mailer.LinkData{
+		Name: user.DisplayName,
+		URL:  h.config.FrontendURL + "/reset-password?token=" + resetToken,
+	})
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to queue reset email", err))
 		return
 	}
-	frontendURL := os.Getenv("FRONTEND_URL")
-	resetURL := frontendURL + "/reset-password?token=" + resetToken
-	subject := "Reset your password"
-	body := "Click the following link to reset your password: " + resetURL
-	utils.SendEmail(user.Email, subject, body)
 	c.JSON(http.StatusOK, gin.H{"message": "If the email exists, a reset link has been sent."})
 }
 
 // ResetPassword handles password reset
-func ResetPassword(c *gin.Context) {
+func (h *Handler) ResetPassword(c *gin.Context) {
 	var req struct {
 		Token    string `json:"token" binding:"required"`
-		Password string `json:"password" binding:"required,min=6"`
+		Password string `json:"password" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Bind(err))
 		return
 	}
 
-	var user models.User
-	if err := db.DB.Where("reset_token = ?", req.Token).First(&user).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+	digest := utils.HashToken(req.Token)
+	user, err := h.store.Users.GetByToken(c, repository.ResetToken, digest)
+	if err != nil {
+		apierror.Abort(c, errInvalidToken)
 		return
 	}
-	if user.ResetTokenExpiry == nil || user.ResetTokenExpiry.Before(time.Now()) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Token expired"})
+	if user.ResetTokenExpiry == nil || user.ResetTokenExpiry.Before(h.now()) {
+		apierror.Abort(c, errTokenExpired)
+		return
+	}
+	if !h.checkPasswordPolicy(c, "password", req.Password, user.Email, user.DisplayName) {
 		return
 	}
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		apierror.Abort(c, apierror.Internal("Failed to hash password", err))
 		return
 	}
+
 	user.Password = string(hashedPassword)
 	user.ResetToken = ""
 	user.ResetTokenExpiry = nil
-	if err := db.DB.Save(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+	// Proving control of the inbox also lifts any login lockout
+	user.FailedLoginAttempts = 0
+	user.LockedUntil = nil
+	user.UnlockToken = ""
+
+	// Matching on the token digest again keeps the token single-use when two
+	// resets race
+	updated, err := h.store.Users.UpdateIfToken(c, user, repository.ResetToken, digest)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to reset password", err))
+		return
+	}
+	if !updated {
+		apierror.Abort(c, errInvalidToken)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Password reset successful. You can now log in."})
@@ -2,71 +2,71 @@ package handlers_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/muneerlalji/Luma/apierror"
 	"github.com/muneerlalji/Luma/handlers"
+	"github.com/muneerlalji/Luma/jobs"
+	"github.com/muneerlalji/Luma/mailer"
 	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/repository"
 	"github.com/muneerlalji/Luma/testutils"
+	"github.com/muneerlalji/Luma/utils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"golang.org/x/crypto/bcrypt"
-	"gorm.io/gorm"
 )
 
 type AuthTestSuite struct {
 	suite.Suite
 	router    *gin.Engine
-	db        *gorm.DB
+	env       *testutils.TestEnv
+	store     *repository.Store
 	emailMock *testutils.EmailMock
 }
 
 func (suite *AuthTestSuite) SetupSuite() {
 	// Set Gin to test mode
 	gin.SetMode(gin.TestMode)
-
-	suite.db = testutils.SetupTestDB()
-	suite.db.AutoMigrate(&models.User{})
 }
 
 func (suite *AuthTestSuite) SetupTest() {
-	// Clear database before each test
-	suite.db.Exec("DELETE FROM users")
-
-	// Setup email mock
-	suite.emailMock = testutils.SetupEmailMock()
+	// Start each test with an empty store and email mock
+	suite.env = testutils.NewTestEnv()
+	suite.store = suite.env.Store
+	suite.emailMock = suite.env.Email
+	h := suite.env.Handler
 
 	// Setup router
 	suite.router = gin.Default()
+	suite.router.Use(testutils.OpenAPIValidator(suite.T()), apierror.Middleware())
 
 	// Setup routes
 	auth := suite.router.Group("/auth")
 	{
-		auth.POST("/register", handlers.Register)
-		auth.POST("/login", handlers.Login)
-		auth.POST("/confirm", handlers.ConfirmEmail)
-		auth.POST("/forgot-password", handlers.ForgotPassword)
-		auth.POST("/reset-password", handlers.ResetPassword)
+		auth.POST("/register", h.Register)
+		auth.POST("/login", h.Login)
+		auth.POST("/confirm", h.ConfirmEmail)
+		auth.POST("/forgot-password", h.ForgotPassword)
+		auth.POST("/resend-confirmation", h.ResendConfirmation)
+		auth.POST("/reset-password", h.ResetPassword)
+		auth.POST("/unlock", h.UnlockAccount)
 	}
 }
 
-func (suite *AuthTestSuite) TearDownSuite() {
-	suite.db.Exec("DROP TABLE IF EXISTS chat_messages CASCADE")
-	suite.db.Exec("DROP TABLE IF EXISTS memory_people CASCADE")
-	suite.db.Exec("DROP TABLE IF EXISTS memories CASCADE")
-	suite.db.Exec("DROP TABLE IF EXISTS people CASCADE")
-	suite.db.Exec("DROP TABLE IF EXISTS photos CASCADE")
-	suite.db.Exec("DROP TABLE IF EXISTS users CASCADE")
-}
-
 func (suite *AuthTestSuite) TestRegister_Success() {
 	// Test data
 	registerData := models.RegisterRequest{
 		Email:       "test@example.com",
-		Password:    "password123",
+		Password:    "violet-Harbor-42",
 		DisplayName: "Test User",
 	}
 
@@ -86,19 +86,130 @@ func (suite *AuthTestSuite) TestRegister_Success() {
 	assert.Contains(suite.T(), response, "user")
 	assert.Contains(suite.T(), response, "message")
 
-	// Verify email was sent
-	assert.Equal(suite.T(), 1, suite.emailMock.GetEmailCount())
+	// The confirmation email is queued, not sent during the request
+	assert.Equal(suite.T(), 0, suite.emailMock.GetEmailCount())
+	sent, err := suite.env.SendQueuedEmails()
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 1, sent)
 	emails := suite.emailMock.FindEmailByRecipient(registerData.Email)
-	assert.Len(suite.T(), emails, 1)
+	suite.Require().Len(emails, 1)
 	assert.Equal(suite.T(), "Confirm your email", emails[0].Subject)
 
-	var user models.User
-	err = suite.db.Where("email = ?", registerData.Email).First(&user).Error
+	user, err := suite.store.Users.GetByEmail(context.Background(), registerData.Email)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), registerData.Email, user.Email)
 	assert.Equal(suite.T(), registerData.DisplayName, user.DisplayName)
 	assert.False(suite.T(), user.EmailConfirmed)
 	assert.NotEmpty(suite.T(), user.ConfirmationToken)
+	assert.NotNil(suite.T(), user.ConfirmationTokenExpiry)
+	assert.Equal(suite.T(), "en", user.Locale)
+
+	// Only the digest of the emailed token is stored
+	assert.NotContains(suite.T(), emails[0].Body, user.ConfirmationToken)
+	suite.emailMock.AssertRendered(suite.T(), emails[0], mailer.ConfirmEmail, "en", mailer.LinkData{
+		Name: registerData.DisplayName,
+		URL:  "http://localhost:3000/confirm?token=" + tokenFromEmail(emails[0].Body),
+	})
+}
+
+func (suite *AuthTestSuite) TestRegister_LocaleFromAcceptLanguage() {
+	registerData := models.RegisterRequest{
+		Email:       "test@example.com",
+		Password:    "violet-Harbor-42",
+		DisplayName: "Test User",
+	}
+
+	jsonData, _ := json.Marshal(registerData)
+	req, _ := http.NewRequest("POST", "/auth/register", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", "es-MX,es;q=0.9,en;q=0.5")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	suite.Require().Equal(http.StatusCreated, w.Code)
+
+	user, err := suite.store.Users.GetByEmail(context.Background(), registerData.Email)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "es", user.Locale)
+	_, err = suite.env.SendQueuedEmails()
+	suite.Require().NoError(err)
+	emails := suite.emailMock.FindEmailByTemplate(mailer.ConfirmEmail)
+	suite.Require().Len(emails, 1)
+	assert.Equal(suite.T(), "Confirma tu correo", emails[0].Subject)
+
+	// An unsupported locale is rejected rather than silently replaced
+	registerData.Email = "other@example.com"
+	registerData.Locale = "fr"
+	jsonData, _ = json.Marshal(registerData)
+	req, _ = http.NewRequest("POST", "/auth/register", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+func (suite *AuthTestSuite) TestRegister_EmailFailureIsRetried() {
+	suite.emailMock.SetSendError(errors.New("smtp unavailable"))
+
+	registerData := models.RegisterRequest{
+		Email:       "test@example.com",
+		Password:    "violet-Harbor-42",
+		DisplayName: "Test User",
+	}
+
+	jsonData, _ := json.Marshal(registerData)
+	req, _ := http.NewRequest("POST", "/auth/register", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	// Registration no longer waits on the mail server
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+	_, err := suite.env.SendQueuedEmails()
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), 0, suite.emailMock.GetEmailCount())
+
+	// The email stays in the outbox and goes out once delivery recovers and
+	// its retry is due
+	suite.emailMock.SetSendError(nil)
+	pending, err := suite.store.OutboxEmails.List(context.Background(), models.EmailPending, 10)
+	suite.Require().NoError(err)
+	suite.Require().Len(pending, 1)
+	assert.Equal(suite.T(), 1, pending[0].Attempts)
+	pending[0].NextAttemptAt = time.Now()
+	suite.Require().NoError(suite.store.OutboxEmails.Update(context.Background(), &pending[0]))
+	sent, err := suite.env.SendQueuedEmails()
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 1, sent)
+	assert.Len(suite.T(), suite.emailMock.FindEmailByRecipient(registerData.Email), 1)
+}
+
+func (suite *AuthTestSuite) TestRegister_ReplacesExpiredUnconfirmedAccount() {
+	expiry := time.Now().Add(-time.Hour)
+	user := models.User{
+		Email:                   "test@example.com",
+		Password:                "hashedpassword",
+		DisplayName:             "Old User",
+		ConfirmationToken:       utils.HashToken("stale-token"),
+		ConfirmationTokenExpiry: &expiry,
+	}
+	suite.store.Users.Create(context.Background(), &user)
+
+	jsonData, _ := json.Marshal(models.RegisterRequest{
+		Email:       "test@example.com",
+		Password:    "violet-Harbor-42",
+		DisplayName: "New User",
+	})
+	req, _ := http.NewRequest("POST", "/auth/register", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusCreated, w.Code)
+
+	newUser, err := suite.store.Users.GetByEmail(context.Background(), "test@example.com")
+	assert.NoError(suite.T(), err)
+	assert.NotEqual(suite.T(), user.ID, newUser.ID)
+	assert.Equal(suite.T(), "New User", newUser.DisplayName)
 }
 
 func (suite *AuthTestSuite) TestRegister_DuplicateEmail() {
@@ -108,12 +219,12 @@ func (suite *AuthTestSuite) TestRegister_DuplicateEmail() {
 		Password:    "hashedpassword",
 		DisplayName: "Test User",
 	}
-	suite.db.Create(&user)
+	suite.store.Users.Create(context.Background(), &user)
 
 	// Try to register with same email
 	registerData := models.RegisterRequest{
 		Email:       "test@example.com",
-		Password:    "password123",
+		Password:    "violet-Harbor-42",
 		DisplayName: "Another User",
 	}
 
@@ -126,17 +237,20 @@ func (suite *AuthTestSuite) TestRegister_DuplicateEmail() {
 
 	assert.Equal(suite.T(), http.StatusConflict, w.Code)
 
-	var response map[string]interface{}
+	var response struct {
+		Error apierror.Error `json:"error"`
+	}
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(suite.T(), err)
-	assert.Contains(suite.T(), response["error"], "already exists")
+	assert.Equal(suite.T(), "email_in_use", response.Error.Code)
+	assert.Contains(suite.T(), response.Error.Message, "already exists")
 }
 
 func (suite *AuthTestSuite) TestRegister_InvalidData() {
 	// Test with invalid email
 	registerData := models.RegisterRequest{
 		Email:       "invalid-email",
-		Password:    "password123",
+		Password:    "violet-Harbor-42",
 		DisplayName: "Test User",
 	}
 
@@ -150,6 +264,34 @@ func (suite *AuthTestSuite) TestRegister_InvalidData() {
 	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
 }
 
+func (suite *AuthTestSuite) TestRegister_WeakPassword() {
+	registerData := models.RegisterRequest{
+		Email:       "margaret@example.com",
+		Password:    "margaret",
+		DisplayName: "Margaret",
+	}
+
+	jsonData, _ := json.Marshal(registerData)
+	req, _ := http.NewRequest("POST", "/auth/register", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	var response struct {
+		Error apierror.Error `json:"error"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "weak_password", response.Error.Code)
+	assert.Len(suite.T(), response.Error.Fields["password"], 2)
+	assert.Equal(suite.T(), utils.PasswordContainsPersonal, response.Error.Fields["password"][0].Code)
+	assert.Equal(suite.T(), utils.PasswordTooCommon, response.Error.Fields["password"][1].Code)
+	assert.Equal(suite.T(), 0, suite.emailMock.GetEmailCount())
+}
+
 func (suite *AuthTestSuite) TestLogin_Success() {
 	// Create a user first with hashed password
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
@@ -160,7 +302,7 @@ func (suite *AuthTestSuite) TestLogin_Success() {
 		EmailConfirmed:    true,
 		ConfirmationToken: "",
 	}
-	suite.db.Create(&user)
+	suite.store.Users.Create(context.Background(), &user)
 
 	// Test login
 	loginData := models.LoginRequest{
@@ -194,17 +336,96 @@ func (suite *AuthTestSuite) TestLogin_InvalidCredentials() {
 	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
 }
 
+func (suite *AuthTestSuite) TestLogin_LocksAfterRepeatedFailures() {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	user := models.User{
+		Email:          "test@example.com",
+		Password:       string(hashedPassword),
+		DisplayName:    "Test User",
+		EmailConfirmed: true,
+	}
+	suite.store.Users.Create(context.Background(), &user)
+
+	login := func(password string) *httptest.ResponseRecorder {
+		jsonData, _ := json.Marshal(models.LoginRequest{Email: user.Email, Password: password})
+		req, _ := http.NewRequest("POST", "/auth/login", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 4; i++ {
+		assert.Equal(suite.T(), http.StatusUnauthorized, login("wrongpassword").Code)
+	}
+
+	w := login("wrongpassword")
+	assert.Equal(suite.T(), http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(suite.T(), w.Header().Get("Retry-After"))
+
+	// The correct password is rejected while the account is locked
+	assert.Equal(suite.T(), http.StatusTooManyRequests, login("password123").Code)
+
+	_, err := suite.env.SendQueuedEmails()
+	suite.Require().NoError(err)
+	emails := suite.emailMock.FindEmailBySubject("Your account has been locked")
+	assert.Len(suite.T(), emails, 1)
+	assert.Contains(suite.T(), emails[0].Body, "/unlock?token=")
+
+	lockedUser, _ := suite.store.Users.Get(context.Background(), user.ID)
+	assert.NotNil(suite.T(), lockedUser.LockedUntil)
+	assert.Equal(suite.T(), 1, lockedUser.LockoutCount)
+	assert.NotEmpty(suite.T(), lockedUser.UnlockToken)
+}
+
+func (suite *AuthTestSuite) TestUnlockAccount_Success() {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	lockedUntil := time.Now().Add(time.Hour)
+	user := models.User{
+		Email:          "test@example.com",
+		Password:       string(hashedPassword),
+		DisplayName:    "Test User",
+		EmailConfirmed: true,
+		LockoutCount:   1,
+		LockedUntil:    &lockedUntil,
+		UnlockToken:    utils.HashToken("test-unlock-token"),
+	}
+	suite.store.Users.Create(context.Background(), &user)
+
+	jsonData, _ := json.Marshal(map[string]string{"token": "test-unlock-token"})
+	req, _ := http.NewRequest("POST", "/auth/unlock", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	unlockedUser, _ := suite.store.Users.Get(context.Background(), user.ID)
+	assert.Nil(suite.T(), unlockedUser.LockedUntil)
+	assert.Empty(suite.T(), unlockedUser.UnlockToken)
+
+	jsonData, _ = json.Marshal(models.LoginRequest{Email: user.Email, Password: "password123"})
+	req, _ = http.NewRequest("POST", "/auth/login", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+}
+
 func (suite *AuthTestSuite) TestConfirmEmail_Success() {
 	// Create a user with confirmation token
 	token := "test-confirmation-token"
+	expiry := time.Now().Add(time.Hour)
 	user := models.User{
-		Email:             "test@example.com",
-		Password:          "hashedpassword",
-		DisplayName:       "Test User",
-		EmailConfirmed:    false,
-		ConfirmationToken: token,
+		Email:                   "test@example.com",
+		Password:                "hashedpassword",
+		DisplayName:             "Test User",
+		EmailConfirmed:          false,
+		ConfirmationToken:       utils.HashToken(token),
+		ConfirmationTokenExpiry: &expiry,
 	}
-	suite.db.Create(&user)
+	suite.store.Users.Create(context.Background(), &user)
 
 	confirmData := map[string]string{"token": token}
 	jsonData, _ := json.Marshal(confirmData)
@@ -217,13 +438,167 @@ func (suite *AuthTestSuite) TestConfirmEmail_Success() {
 	assert.Equal(suite.T(), http.StatusOK, w.Code)
 
 	// Verify user is confirmed
-	var updatedUser models.User
-	err := suite.db.Where("email = ?", user.Email).First(&updatedUser).Error
+	updatedUser, err := suite.store.Users.GetByEmail(context.Background(), user.Email)
 	assert.NoError(suite.T(), err)
 	assert.True(suite.T(), updatedUser.EmailConfirmed)
 	assert.Empty(suite.T(), updatedUser.ConfirmationToken)
 }
 
+func (suite *AuthTestSuite) TestConfirmEmail_ExpiredToken() {
+	token := "test-confirmation-token"
+	expiry := time.Now().Add(-time.Minute)
+	user := models.User{
+		Email:                   "test@example.com",
+		Password:                "hashedpassword",
+		DisplayName:             "Test User",
+		ConfirmationToken:       utils.HashToken(token),
+		ConfirmationTokenExpiry: &expiry,
+	}
+	suite.store.Users.Create(context.Background(), &user)
+
+	jsonData, _ := json.Marshal(map[string]string{"token": token})
+	req, _ := http.NewRequest("POST", "/auth/confirm", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	unconfirmedUser, _ := suite.store.Users.Get(context.Background(), user.ID)
+	assert.False(suite.T(), unconfirmedUser.EmailConfirmed)
+}
+
+func (suite *AuthTestSuite) TestConfirmEmail_TokenIsSingleUse() {
+	token := "test-confirmation-token"
+	expiry := time.Now().Add(time.Hour)
+	user := models.User{
+		Email:                   "test@example.com",
+		Password:                "hashedpassword",
+		DisplayName:             "Test User",
+		ConfirmationToken:       utils.HashToken(token),
+		ConfirmationTokenExpiry: &expiry,
+	}
+	suite.store.Users.Create(context.Background(), &user)
+
+	jsonData, _ := json.Marshal(map[string]string{"token": token})
+	for _, expected := range []int{http.StatusOK, http.StatusBadRequest} {
+		req, _ := http.NewRequest("POST", "/auth/confirm", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, req)
+		assert.Equal(suite.T(), expected, w.Code)
+	}
+}
+
+func (suite *AuthTestSuite) TestResendConfirmation() {
+	expiry := time.Now().Add(-time.Minute)
+	user := models.User{
+		Email:                   "test@example.com",
+		Password:                "hashedpassword",
+		DisplayName:             "Test User",
+		ConfirmationToken:       utils.HashToken("old-token"),
+		ConfirmationTokenExpiry: &expiry,
+	}
+	suite.store.Users.Create(context.Background(), &user)
+
+	jsonData, _ := json.Marshal(map[string]string{"email": user.Email})
+	req, _ := http.NewRequest("POST", "/auth/resend-confirmation", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	_, err := suite.env.SendQueuedEmails()
+	suite.Require().NoError(err)
+	emails := suite.emailMock.FindEmailBySubject("Confirm your email")
+	assert.Len(suite.T(), emails, 1)
+
+	updatedUser, _ := suite.store.Users.Get(context.Background(), user.ID)
+	assert.NotEqual(suite.T(), utils.HashToken("old-token"), updatedUser.ConfirmationToken)
+	assert.True(suite.T(), updatedUser.ConfirmationTokenExpiry.After(time.Now()))
+
+	// Unknown addresses get the same response without an email
+	jsonData, _ = json.Marshal(map[string]string{"email": "nobody@example.com"})
+	req, _ = http.NewRequest("POST", "/auth/resend-confirmation", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	_, err = suite.env.SendQueuedEmails()
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 1, suite.emailMock.GetEmailCount())
+}
+
+func (suite *AuthTestSuite) TestPurgeUnconfirmedUsers() {
+	ctx := context.Background()
+	monthAgo := time.Now().Add(-30 * 24 * time.Hour)
+	confirmed := models.User{Email: "confirmed@example.com", Password: "x", EmailConfirmed: true, CreatedAt: monthAgo}
+	stale := models.User{Email: "stale@example.com", Password: "x", CreatedAt: monthAgo}
+	fresh := models.User{Email: "fresh@example.com", Password: "x"}
+	suite.store.Users.Create(ctx, &confirmed)
+	suite.store.Users.Create(ctx, &stale)
+	suite.store.Users.Create(ctx, &fresh)
+
+	purged, err := suite.env.Handler.PurgeUnconfirmedUsers(ctx, 7*24*time.Hour)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), purged)
+
+	for _, user := range []models.User{confirmed, stale, fresh} {
+		_, err := suite.store.Users.Get(ctx, user.ID)
+		if user.ID == stale.ID {
+			assert.ErrorIs(suite.T(), err, repository.ErrNotFound)
+		} else {
+			assert.NoError(suite.T(), err)
+		}
+	}
+}
+
+func (suite *AuthTestSuite) TestDeleteAccount_DeletesPhotoFiles() {
+	ctx := context.Background()
+	user := models.User{Email: "margaret@example.com", Password: "x", EmailConfirmed: true}
+	other := models.User{Email: "tom@example.com", Password: "x", EmailConfirmed: true}
+	suite.store.Users.Create(ctx, &user)
+	suite.store.Users.Create(ctx, &other)
+	for _, photo := range []models.Photo{
+		{UserID: user.ID, S3Key: "photos/margaret-1.jpg"},
+		{UserID: user.ID, S3Key: "photos/margaret-2.jpg"},
+		{UserID: other.ID, S3Key: "photos/tom.jpg"},
+	} {
+		suite.Require().NoError(suite.env.Storage.Put(ctx, photo.S3Key, strings.NewReader("jpeg"), "image/jpeg"))
+		suite.Require().NoError(suite.store.Photos.Create(ctx, &photo))
+	}
+
+	suite.router.DELETE("/profile", func(c *gin.Context) {
+		c.Set("user_id", user.ID)
+		c.Next()
+	}, suite.env.Handler.DeleteAccount)
+	req, _ := http.NewRequest("DELETE", "/profile", nil)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	suite.Require().Equal(http.StatusOK, w.Code)
+
+	// The files outlive the account until the job runs
+	_, ok := suite.env.Storage.Get("photos/margaret-1.jpg")
+	assert.True(suite.T(), ok)
+
+	worker := jobs.New(suite.store, jobs.Config{})
+	jobs.Handle(worker, handlers.DeletePhotoFilesJob, suite.env.Handler.DeletePhotoFiles)
+	ran, err := worker.RunDue(ctx)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 1, ran)
+
+	for key, kept := range map[string]bool{
+		"photos/margaret-1.jpg": false,
+		"photos/margaret-2.jpg": false,
+		"photos/tom.jpg":        true,
+	} {
+		_, ok := suite.env.Storage.Get(key)
+		assert.Equal(suite.T(), kept, ok, key)
+	}
+}
+
 func (suite *AuthTestSuite) TestConfirmEmail_InvalidToken() {
 	confirmData := map[string]string{"token": "invalid-token"}
 	jsonData, _ := json.Marshal(confirmData)
@@ -0,0 +1,605 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/ical"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/recurrence"
+	"github.com/muneerlalji/Luma/repository"
+	"github.com/muneerlalji/Luma/utils"
+)
+
+const (
+	// calendarProductID identifies Luma as the producer of calendar feeds
+	calendarProductID = "-//Luma//Calendar Feed//EN"
+	// calendarUIDDomain ends the UID of every feed event, so a feed imported
+	// back into Luma is recognised and skipped
+	calendarUIDDomain = "@luma"
+	// calendarRefresh is how often subscribed calendars are asked to refresh
+	calendarRefresh = time.Hour
+	// calendarTimezoneYears is how far ahead the feed lists changes of the
+	// user's UTC offset
+	calendarTimezoneYears = 10
+	// routineEventDuration is how long routine events last in the feed
+	routineEventDuration = 15 * time.Minute
+	// maxCalendarImportBytes bounds the size of an imported calendar file
+	maxCalendarImportBytes = 1 << 20
+	// importedMemoryType is the type of memories imported from calendars
+	importedMemoryType = "event"
+)
+
+// Reasons an imported calendar event was skipped
+const (
+	SkipCancelled   = "cancelled"
+	SkipNoUID       = "no_uid"
+	SkipNoDate      = "no_date"
+	SkipFromLuma    = "from_luma"
+	SkipRecurring   = "recurring"
+	SkipFuture      = "future"
+	SkipBirthdaySet = "birthday_set"
+)
+
+// CalendarFeedResponse holds the secret URL calendars subscribe to
+type CalendarFeedResponse struct {
+	URL string `json:"url"`
+}
+
+// SkippedEvent is a calendar event that was not imported and why
+type SkippedEvent struct {
+	UID     string `json:"uid,omitempty"`
+	Summary string `json:"summary"`
+	Reason  string `json:"reason"`
+}
+
+// CalendarImportResponse counts what an import changed. Events imported
+// before are matched on their UID and updated rather than added again.
+type CalendarImportResponse struct {
+	MemoriesCreated int `json:"memoriesCreated"`
+	MemoriesUpdated int `json:"memoriesUpdated"`
+	BirthdaysSet    int `json:"birthdaysSet"`
+	PeopleCreated   int `json:"peopleCreated"`
+	// Unchanged counts events imported before that have not changed
+	Unchanged int            `json:"unchanged"`
+	Skipped   []SkippedEvent `json:"skipped"`
+}
+
+func (h *Handler) calendarFeedURL(token string) string {
+	return fmt.Sprintf("%s/api/v1/calendar/feed/%s.ics", h.config.APIBaseURL, token)
+}
+
+// CreateCalendarFeed issues a new secret calendar feed URL. Any earlier URL
+// stops working.
+func (h *Handler) CreateCalendarFeed(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	user, err := h.store.Users.Get(c, userUUID)
+	if err != nil {
+		apierror.Abort(c, errUserNotFound)
+		return
+	}
+
+	token, err := generateRandomToken(32)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to generate calendar token", err))
+		return
+	}
+	user.CalendarToken = utils.HashToken(token)
+	if err := h.store.Users.Update(c, user); err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to save calendar token", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, CalendarFeedResponse{URL: h.calendarFeedURL(token)})
+}
+
+// DeleteCalendarFeed turns the calendar feed off
+func (h *Handler) DeleteCalendarFeed(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	user, err := h.store.Users.Get(c, userUUID)
+	if err != nil {
+		apierror.Abort(c, errUserNotFound)
+		return
+	}
+
+	user.CalendarToken = ""
+	if err := h.store.Users.Update(c, user); err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to turn off calendar feed", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Calendar feed turned off"})
+}
+
+// GetCalendarFeed serves the iCalendar feed named by the secret in the URL.
+// Calendar apps cannot send a bearer token, so the secret authenticates the
+// request.
+func (h *Handler) GetCalendarFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("file"), ".ics")
+	user, err := h.store.Users.GetByToken(c, repository.CalendarToken, utils.HashToken(token))
+	if errors.Is(err, repository.ErrNotFound) {
+		apierror.Abort(c, errCalendarFeedNotFound)
+		return
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to find calendar feed", err))
+		return
+	}
+
+	calendar, err := h.calendarFeed(c, user)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to build calendar feed", err))
+		return
+	}
+	var body bytes.Buffer
+	if err := calendar.Encode(&body); err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to write calendar feed", err))
+		return
+	}
+
+	c.Header("Cache-Control", "private, no-cache")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", body.Bytes())
+}
+
+// calendarFeed lists the user's birthdays, anniversaries, reach-out
+// reminders and routines as a VCALENDAR
+func (h *Handler) calendarFeed(ctx context.Context, user *models.User) (*ical.Component, error) {
+	now := h.now()
+	loc := location(user)
+
+	people, err := h.store.People.ListByUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	reminders, err := h.store.Reminders.ListActive(ctx, user.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	routines, err := h.store.Routines.ListByUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	calendar := ical.NewCalendar(calendarProductID)
+	calendar.AddText("NAME", "Luma")
+	calendar.AddText("X-WR-CALNAME", "Luma")
+	calendar.Add("REFRESH-INTERVAL", ical.FormatDuration(calendarRefresh), ical.Params{"VALUE": {"DURATION"}})
+	calendar.Add("X-PUBLISHED-TTL", ical.FormatDuration(calendarRefresh))
+
+	// Routine times are local, so the feed describes the user's timezone
+	if len(routines) > 0 && loc != time.UTC {
+		from := now
+		for i := range routines {
+			starts := time.Date(routines[i].StartsOn.Year(), routines[i].StartsOn.Month(), routines[i].StartsOn.Day(), 0, 0, 0, 0, loc)
+			if starts.Before(from) {
+				from = starts
+			}
+		}
+		calendar.AddComponent(ical.Timezone(loc, from, now.AddDate(calendarTimezoneYears, 0, 0)))
+	}
+
+	for i := range people {
+		person := &people[i]
+		name := personName(person)
+		if person.Birthday != nil {
+			calendar.AddComponent(annualEvent("birthday-"+person.ID.String(), *person.Birthday, name+"'s birthday", now))
+		}
+		if person.Anniversary != nil {
+			calendar.AddComponent(annualEvent("anniversary-"+person.ID.String(), *person.Anniversary, "Anniversary with "+name, now))
+		}
+		if person.DiedOn != nil {
+			calendar.AddComponent(annualEvent("died-"+person.ID.String(), *person.DiedOn, "Remembering "+name, now))
+		}
+		if person.NextReminderAt != nil {
+			event := feedEvent("next-reminder-"+person.ID.String(), "Get in touch with "+name, now)
+			event.AddDate("DTSTART", localDay(*person.NextReminderAt, loc))
+			event.AddDate("DTEND", localDay(*person.NextReminderAt, loc).AddDate(0, 0, 1))
+			calendar.AddComponent(event)
+		}
+	}
+
+	for i := range reminders {
+		reminder := &reminders[i]
+		event := feedEvent("reminder-"+reminder.ID.String(), "Get in touch with "+personName(&reminder.Person), now)
+		if reminder.Person.Phone != "" {
+			event.AddText("DESCRIPTION", "Phone "+reminder.Person.Phone)
+		}
+		event.AddDate("DTSTART", localDay(reminder.DueAt, loc))
+		event.AddDate("DTEND", localDay(reminder.DueAt, loc).AddDate(0, 0, 1))
+		calendar.AddComponent(event)
+	}
+
+	for i := range routines {
+		for _, event := range routineEvents(&routines[i], loc, now) {
+			calendar.AddComponent(event)
+		}
+	}
+	return calendar, nil
+}
+
+// feedEvent starts a VEVENT with the properties every event needs
+func feedEvent(uid, summary string, stamp time.Time) *ical.Component {
+	event := ical.NewComponent("VEVENT")
+	event.Add("UID", uid+calendarUIDDomain)
+	event.AddTime("DTSTAMP", stamp.UTC())
+	event.AddText("SUMMARY", summary)
+	return event
+}
+
+// annualEvent is an all-day event on date every year. Dates on 29 February
+// fall on the 28th in other years, as in the upcoming feed.
+func annualEvent(uid string, date time.Time, summary string, stamp time.Time) *ical.Component {
+	event := feedEvent(uid, summary, stamp)
+	event.AddDate("DTSTART", date)
+	event.AddDate("DTEND", date.AddDate(0, 0, 1))
+	rule := "FREQ=YEARLY"
+	if date.Month() == time.February && date.Day() == 29 {
+		rule = "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=-1"
+	}
+	event.Add("RRULE", rule)
+	event.Add("TRANSP", "TRANSPARENT")
+	return event
+}
+
+// routineEvents is a recurring event for each time of day of the routine,
+// with an alarm when it is due
+func routineEvents(routine *models.Routine, loc *time.Location, stamp time.Time) []*ical.Component {
+	rule, err := recurrence.Parse(routine.Recurrence)
+	if err != nil {
+		return nil
+	}
+
+	// DTSTART always counts as an occurrence, so it must be the first day
+	// the rule matches rather than the day the routine starts
+	first := routine.StartsOn
+	for !rule.Matches(routine.StartsOn, first) {
+		first = first.AddDate(0, 0, 1)
+		if first.Sub(routine.StartsOn) > occurrenceSearchDays*24*time.Hour {
+			return nil
+		}
+	}
+
+	// UNTIL must be a UTC time when DTSTART is a local one
+	until := rule.Until
+	rule.Until = nil
+	rrule := rule.String()
+	if until != nil {
+		end := time.Date(until.Year(), until.Month(), until.Day(), 23, 59, 59, 0, loc)
+		rrule += ";UNTIL=" + ical.FormatUTC(end)
+	}
+
+	events := []*ical.Component{}
+	for _, at := range routine.Times {
+		clock, err := time.Parse(timeLayout, at)
+		if err != nil {
+			continue
+		}
+		uid := fmt.Sprintf("routine-%s-%s", routine.ID, clock.Format("1504"))
+		event := feedEvent(uid, routine.Title, stamp)
+		if routine.Instructions != "" {
+			event.AddText("DESCRIPTION", routine.Instructions)
+		}
+		event.AddText("CATEGORIES", strings.ToUpper(routine.Kind))
+		event.AddTime("DTSTART", time.Date(first.Year(), first.Month(), first.Day(), clock.Hour(), clock.Minute(), 0, 0, loc))
+		event.Add("DURATION", ical.FormatDuration(routineEventDuration))
+		event.Add("RRULE", rrule)
+
+		alarm := ical.NewComponent("VALARM")
+		alarm.Add("ACTION", "DISPLAY")
+		alarm.Add("TRIGGER", ical.FormatDuration(0))
+		alarm.AddText("DESCRIPTION", routine.Title)
+		event.AddComponent(alarm)
+		events = append(events, event)
+	}
+	return events
+}
+
+// ImportCalendar turns the events of an uploaded .ics file into dated
+// memories, and birthday events into people's birthdays
+func (h *Handler) ImportCalendar(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		apierror.Abort(c, errFileRequired)
+		return
+	}
+	if fileHeader.Size > maxCalendarImportBytes {
+		apierror.Abort(c, errCalendarTooLarge)
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to open file", err))
+		return
+	}
+	defer file.Close()
+
+	calendar, err := ical.Parse(io.LimitReader(file, maxCalendarImportBytes))
+	if err != nil {
+		apierror.Abort(c, errInvalidCalendar.WithField("file", apierror.FieldError{Code: "ical", Message: err.Error()}))
+		return
+	}
+
+	loc, err := h.userLocation(c, userUUID)
+	if err != nil {
+		apierror.Abort(c, errUserNotFound)
+		return
+	}
+
+	var result CalendarImportResponse
+	err = h.store.Transaction(c, func(tx *repository.Store) error {
+		result = CalendarImportResponse{Skipped: []SkippedEvent{}}
+		people, err := tx.People.ListByUser(c, userUUID)
+		if err != nil {
+			return err
+		}
+		importer := calendarImporter{tx: tx, userID: userUUID, loc: loc, today: localDay(h.now(), loc), people: people, result: &result}
+		for _, event := range calendar.Children("VEVENT") {
+			if err := importer.importEvent(c, event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to import calendar", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// calendarImporter imports the events of one calendar inside a transaction
+type calendarImporter struct {
+	tx     *repository.Store
+	userID uuid.UUID
+	loc    *time.Location
+	today  time.Time
+	// people grows as birthdays of new people are imported
+	people []models.Person
+	result *CalendarImportResponse
+}
+
+func (i *calendarImporter) skip(uid, summary, reason string) {
+	i.result.Skipped = append(i.result.Skipped, SkippedEvent{UID: uid, Summary: summary, Reason: reason})
+}
+
+func (i *calendarImporter) importEvent(ctx context.Context, event *ical.Component) error {
+	uid := strings.TrimSpace(event.Text("UID"))
+	summary := strings.TrimSpace(event.Text("SUMMARY"))
+
+	switch {
+	case strings.EqualFold(event.Text("STATUS"), "CANCELLED"):
+		i.skip(uid, summary, SkipCancelled)
+		return nil
+	case uid == "":
+		i.skip(uid, summary, SkipNoUID)
+		return nil
+	case strings.HasSuffix(uid, calendarUIDDomain):
+		i.skip(uid, summary, SkipFromLuma)
+		return nil
+	}
+
+	start, allDay, err := event.Time("DTSTART", i.loc)
+	if err != nil {
+		i.skip(uid, summary, SkipNoDate)
+		return nil
+	}
+	date := start
+	if !allDay {
+		date = localDay(start, i.loc)
+	}
+
+	yearly := false
+	if rule := event.Get("RRULE"); rule != nil {
+		yearly = strings.Contains(strings.ToUpper(rule.Value), "FREQ=YEARLY")
+		if !yearly {
+			i.skip(uid, summary, SkipRecurring)
+			return nil
+		}
+	}
+
+	if name := birthdayName(summary); name != "" {
+		return i.importBirthday(ctx, uid, summary, name, date)
+	}
+	if date.After(i.today) {
+		i.skip(uid, summary, SkipFuture)
+		return nil
+	}
+	return i.importMemory(ctx, uid, summary, event, date)
+}
+
+// importBirthday sets the birthday of the person named, adding the person
+// when there is no one by that name
+func (i *calendarImporter) importBirthday(ctx context.Context, uid, summary, name string, date time.Time) error {
+	person, err := i.tx.People.GetByBirthdayUID(ctx, i.userID, uid)
+	if errors.Is(err, repository.ErrNotFound) {
+		person, err = nil, nil
+		if match := findPersonByName(i.people, name); match != nil {
+			person = match
+			if person.Birthday != nil && !person.Birthday.Equal(date) && person.BirthdayUID == "" {
+				// Keep a birthday the user entered themselves
+				i.skip(uid, summary, SkipBirthdaySet)
+				return nil
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if person == nil {
+		first, last := splitName(name)
+		person = &models.Person{
+			UserID:      i.userID,
+			FirstName:   first,
+			LastName:    last,
+			Notes:       "Added from an imported calendar",
+			Birthday:    &date,
+			BirthdayUID: uid,
+		}
+		if err := i.tx.People.Create(ctx, person); err != nil {
+			return err
+		}
+		i.people = append(i.people, *person)
+		i.result.PeopleCreated++
+		i.result.BirthdaysSet++
+		return nil
+	}
+
+	if person.Birthday != nil && person.Birthday.Equal(date) && person.BirthdayUID == uid {
+		i.result.Unchanged++
+		return nil
+	}
+	person.Birthday, person.BirthdayUID = &date, uid
+	if err := i.tx.People.Update(ctx, person); err != nil {
+		return err
+	}
+	i.result.BirthdaysSet++
+	return nil
+}
+
+// importMemory adds the event as a memory, or updates the memory imported
+// from it before
+func (i *calendarImporter) importMemory(ctx context.Context, uid, summary string, event *ical.Component, date time.Time) error {
+	if summary == "" {
+		summary = "Calendar event"
+	}
+	content := strings.TrimSpace(event.Text("DESCRIPTION"))
+	if location := strings.TrimSpace(event.Text("LOCATION")); location != "" {
+		content = strings.TrimSpace(content + "\n\nAt " + location)
+	}
+
+	memory, err := i.tx.Memories.GetByCalendarUID(ctx, i.userID, uid)
+	if errors.Is(err, repository.ErrNotFound) {
+		memory = &models.Memory{
+			UserID:      i.userID,
+			Title:       summary,
+			Type:        importedMemoryType,
+			Content:     content,
+			OccurredOn:  &date,
+			CalendarUID: uid,
+		}
+		if err := i.tx.Memories.Create(ctx, memory); err != nil {
+			return err
+		}
+		i.result.MemoriesCreated++
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if memory.Title == summary && memory.Content == content && memory.OccurredOn != nil && memory.OccurredOn.Equal(date) {
+		i.result.Unchanged++
+		return nil
+	}
+	memory.Title, memory.Content, memory.OccurredOn = summary, content, &date
+	if err := i.tx.Memories.Update(ctx, memory); err != nil {
+		return err
+	}
+	i.result.MemoriesUpdated++
+	return nil
+}
+
+// Patterns birthdayName picks names out of event summaries with. They match
+// the summary itself rather than a lower-cased copy, whose byte offsets can
+// differ from the original's.
+var (
+	birthdayWord   = regexp.MustCompile(`(?i)birthday`)
+	happyPrefix    = regexp.MustCompile(`(?i)^happy\s+`)
+	possessiveMark = regexp.MustCompile(`(?i)['’]s?$`)
+	ofPrefix       = regexp.MustCompile(`(?i)^of\s+`)
+)
+
+// birthdayName is the name in an event summary such as "Tom's birthday" or
+// "Birthday: Tom Hughes", or "" when the summary is not about a birthday
+func birthdayName(summary string) string {
+	match := birthdayWord.FindStringIndex(summary)
+	if match == nil {
+		return ""
+	}
+	trim := func(s string) string {
+		return strings.Trim(s, " \t:-–—!.,🎂🎉")
+	}
+
+	before := trim(happyPrefix.ReplaceAllString(trim(summary[:match[0]]), ""))
+	before = trim(possessiveMark.ReplaceAllString(before, ""))
+	if before != "" && !strings.EqualFold(before, "happy") {
+		return before
+	}
+
+	return trim(ofPrefix.ReplaceAllString(trim(summary[match[1]:]), ""))
+}
+
+// findPersonByName matches a full name, or a first name only one person has
+func findPersonByName(people []models.Person, name string) *models.Person {
+	var byFirstName *models.Person
+	firstNames := 0
+	for i := range people {
+		if strings.EqualFold(personName(&people[i]), name) {
+			return &people[i]
+		}
+		if strings.EqualFold(people[i].FirstName, name) {
+			byFirstName = &people[i]
+			firstNames++
+		}
+	}
+	if firstNames == 1 {
+		return byFirstName
+	}
+	return nil
+}
+
+// splitName splits a full name at its last space into first and last names
+func splitName(name string) (string, string) {
+	name = strings.Join(strings.Fields(name), " ")
+	index := strings.LastIndex(name, " ")
+	if index < 0 {
+		return name, ""
+	}
+	return name[:index], name[index+1:]
+}
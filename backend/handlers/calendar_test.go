@@ -0,0 +1,350 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/handlers"
+	"github.com/muneerlalji/Luma/ical"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type CalendarTestSuite struct {
+	suite.Suite
+	env    *testutils.TestEnv
+	router *gin.Engine
+	user   models.User
+	now    time.Time
+}
+
+func (suite *CalendarTestSuite) SetupSuite() {
+	gin.SetMode(gin.TestMode)
+}
+
+func (suite *CalendarTestSuite) SetupTest() {
+	loc, err := time.LoadLocation("Europe/London")
+	suite.Require().NoError(err)
+	suite.now = time.Date(2026, time.March, 2, 10, 0, 0, 0, loc)
+	suite.env = testutils.NewTestEnv(func(deps *handlers.Deps) {
+		deps.Clock = func() time.Time { return suite.now }
+	})
+	h := suite.env.Handler
+
+	suite.user = models.User{Email: "margaret@example.com", Password: "x", DisplayName: "Margaret", EmailConfirmed: true, Timezone: "Europe/London"}
+	suite.env.Store.Users.Create(context.Background(), &suite.user)
+
+	suite.router = gin.New()
+	suite.router.Use(testutils.OpenAPIValidator(suite.T()), apierror.Middleware())
+	suite.router.GET("/calendar/feed/:file", h.GetCalendarFeed)
+	protected := suite.router.Group("/")
+	protected.Use(func(c *gin.Context) {
+		c.Set("user_id", suite.user.ID)
+		c.Next()
+	})
+	protected.POST("/calendar/feed", h.CreateCalendarFeed)
+	protected.DELETE("/calendar/feed", h.DeleteCalendarFeed)
+	protected.POST("/calendar/import", h.ImportCalendar)
+	protected.POST("/routines", h.CreateRoutine)
+}
+
+func (suite *CalendarTestSuite) request(method, path string, body any) *httptest.ResponseRecorder {
+	var reader bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&reader).Encode(body)
+	}
+	req, _ := http.NewRequest(method, path, &reader)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+// feedPath turns on the feed and returns the path of its URL
+func (suite *CalendarTestSuite) feedPath() string {
+	w := suite.request("POST", "/calendar/feed", nil)
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	var feed handlers.CalendarFeedResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &feed))
+	feedURL, err := url.Parse(feed.URL)
+	suite.Require().NoError(err)
+	suite.Require().True(strings.HasPrefix(feedURL.Path, "/api/v1/calendar/feed/"))
+	return strings.TrimPrefix(feedURL.Path, "/api/v1")
+}
+
+func (suite *CalendarTestSuite) feed(path string) *ical.Component {
+	w := suite.request("GET", path, nil)
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(suite.T(), "text/calendar; charset=utf-8", w.Header().Get("Content-Type"))
+	calendar, err := ical.Parse(w.Body)
+	suite.Require().NoError(err)
+	return calendar
+}
+
+func (suite *CalendarTestSuite) importCalendar(events ...string) (*httptest.ResponseRecorder, handlers.CalendarImportResponse) {
+	content := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Example//EN\r\n" + strings.Join(events, "") + "END:VCALENDAR\r\n"
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", "calendar.ics")
+	part.Write([]byte(content))
+	form.Close()
+
+	req, _ := http.NewRequest("POST", "/calendar/import", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	var result handlers.CalendarImportResponse
+	if w.Code == http.StatusOK {
+		suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &result))
+	}
+	return w, result
+}
+
+func calendarEvent(lines ...string) string {
+	return "BEGIN:VEVENT\r\n" + strings.Join(lines, "\r\n") + "\r\nEND:VEVENT\r\n"
+}
+
+func (suite *CalendarTestSuite) addPerson(first, last string, birthday *time.Time) *models.Person {
+	person := &models.Person{UserID: suite.user.ID, FirstName: first, LastName: last, Birthday: birthday}
+	suite.Require().NoError(suite.env.Store.People.Create(context.Background(), person))
+	return person
+}
+
+func calendarDate(year int, month time.Month, day int) *time.Time {
+	t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return &t
+}
+
+func (suite *CalendarTestSuite) TestFeed() {
+	tom := suite.addPerson("Tom", "Hughes", calendarDate(1990, time.April, 12))
+	ann := suite.addPerson("Ann", "Lee", calendarDate(1944, time.February, 29))
+	w := suite.request("POST", "/routines", handlers.RoutineRequest{
+		Kind: models.RoutineMedication, Title: "Blood pressure tablets", Instructions: "Two with water",
+		Recurrence: "FREQ=WEEKLY;BYDAY=WE;UNTIL=20260630", Times: []string{"09:00"},
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+	var routine handlers.RoutineResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &routine))
+
+	calendar := suite.feed(suite.feedPath())
+	assert.Equal(suite.T(), "2.0", calendar.Text("VERSION"))
+	assert.Equal(suite.T(), "Luma", calendar.Text("X-WR-CALNAME"))
+	timezones := calendar.Children("VTIMEZONE")
+	suite.Require().Len(timezones, 1)
+	assert.Equal(suite.T(), "Europe/London", timezones[0].Text("TZID"))
+
+	events := map[string]*ical.Component{}
+	for _, event := range calendar.Children("VEVENT") {
+		events[event.Text("UID")] = event
+	}
+	suite.Require().Len(events, 3)
+
+	birthday := events["birthday-"+tom.ID.String()+"@luma"]
+	suite.Require().NotNil(birthday)
+	assert.Equal(suite.T(), "Tom Hughes's birthday", birthday.Text("SUMMARY"))
+	assert.Equal(suite.T(), "19900412", birthday.Get("DTSTART").Value)
+	assert.Equal(suite.T(), "DATE", birthday.Get("DTSTART").Params.Get("VALUE"))
+	assert.Equal(suite.T(), "FREQ=YEARLY", birthday.Text("RRULE"))
+
+	leap := events["birthday-"+ann.ID.String()+"@luma"]
+	suite.Require().NotNil(leap)
+	assert.Equal(suite.T(), "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=-1", leap.Get("RRULE").Value)
+
+	tablets := events["routine-"+routine.ID.String()+"-0900@luma"]
+	suite.Require().NotNil(tablets)
+	assert.Equal(suite.T(), "Blood pressure tablets", tablets.Text("SUMMARY"))
+	assert.Equal(suite.T(), "Two with water", tablets.Text("DESCRIPTION"))
+	// The routine starts on a Monday but first happens on the Wednesday
+	assert.Equal(suite.T(), "20260304T090000", tablets.Get("DTSTART").Value)
+	assert.Equal(suite.T(), "Europe/London", tablets.Get("DTSTART").Params.Get("TZID"))
+	// 23:59:59 on 30 June in London is 22:59:59 UTC
+	assert.Equal(suite.T(), "FREQ=WEEKLY;BYDAY=WE;UNTIL=20260630T225959Z", tablets.Get("RRULE").Value)
+	suite.Require().Len(tablets.Children("VALARM"), 1)
+}
+
+func (suite *CalendarTestSuite) TestFeedIncludesReminders() {
+	tom := suite.addPerson("Tom", "Hughes", nil)
+	next := suite.now.AddDate(0, 0, 3)
+	tom.NextReminderAt = &next
+	suite.Require().NoError(suite.env.Store.People.Update(context.Background(), tom))
+
+	calendar := suite.feed(suite.feedPath())
+	events := calendar.Children("VEVENT")
+	suite.Require().Len(events, 1)
+	assert.Equal(suite.T(), "Get in touch with Tom Hughes", events[0].Text("SUMMARY"))
+	assert.Equal(suite.T(), "20260305", events[0].Get("DTSTART").Value)
+	// Without routines the feed has no times to describe a timezone for
+	assert.Empty(suite.T(), calendar.Children("VTIMEZONE"))
+}
+
+func (suite *CalendarTestSuite) TestFeedURLIsSecret() {
+	first := suite.feedPath()
+	second := suite.feedPath()
+	assert.NotEqual(suite.T(), first, second)
+
+	w := suite.request("GET", first, nil)
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "calendar_feed_not_found")
+	assert.Equal(suite.T(), http.StatusOK, suite.request("GET", second, nil).Code)
+
+	assert.Equal(suite.T(), http.StatusOK, suite.request("DELETE", "/calendar/feed", nil).Code)
+	assert.Equal(suite.T(), http.StatusNotFound, suite.request("GET", second, nil).Code)
+	assert.Equal(suite.T(), http.StatusNotFound, suite.request("GET", "/calendar/feed/.ics", nil).Code)
+}
+
+func (suite *CalendarTestSuite) TestImport() {
+	tom := suite.addPerson("Tom", "Hughes", nil)
+	suite.addPerson("Ann", "Lee", calendarDate(1950, time.May, 1))
+
+	events := []string{
+		calendarEvent("UID:trip@example.com", "SUMMARY:Trip to Whitby", "DESCRIPTION:Fish and chips", "LOCATION:Whitby", "DTSTART:20250812T090000Z"),
+		calendarEvent("UID:late@example.com", "SUMMARY:Late dinner", "DTSTART;TZID=America/New_York:20250101T220000"),
+		calendarEvent("UID:tom@example.com", "SUMMARY:Tom's birthday", "DTSTART;VALUE=DATE:19900412", "RRULE:FREQ=YEARLY"),
+		calendarEvent("UID:jo@example.com", "SUMMARY:Birthday: Jo Smith", "DTSTART;VALUE=DATE:19880704", "RRULE:FREQ=YEARLY"),
+		calendarEvent("UID:ann@example.com", "SUMMARY:Ann's birthday", "DTSTART;VALUE=DATE:19500502"),
+		calendarEvent("UID:future@example.com", "SUMMARY:Dentist", "DTSTART;VALUE=DATE:20260401"),
+		calendarEvent("UID:club@example.com", "SUMMARY:Book club", "DTSTART:20250101T190000Z", "RRULE:FREQ=WEEKLY"),
+		calendarEvent("UID:off@example.com", "SUMMARY:Concert", "STATUS:CANCELLED", "DTSTART:20250101T190000Z"),
+		calendarEvent("SUMMARY:No UID", "DTSTART:20250101T190000Z"),
+		calendarEvent("UID:undated@example.com", "SUMMARY:Someday"),
+		calendarEvent("UID:routine-1-0900@luma", "SUMMARY:Tablets", "DTSTART:20250101T090000Z"),
+	}
+	w, result := suite.importCalendar(events...)
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(suite.T(), 2, result.MemoriesCreated)
+	assert.Equal(suite.T(), 2, result.BirthdaysSet)
+	assert.Equal(suite.T(), 1, result.PeopleCreated)
+	reasons := map[string]string{}
+	for _, skipped := range result.Skipped {
+		reasons[skipped.Summary] = skipped.Reason
+	}
+	assert.Equal(suite.T(), map[string]string{
+		"Ann's birthday": handlers.SkipBirthdaySet,
+		"Dentist":        handlers.SkipFuture,
+		"Book club":      handlers.SkipRecurring,
+		"Concert":        handlers.SkipCancelled,
+		"No UID":         handlers.SkipNoUID,
+		"Someday":        handlers.SkipNoDate,
+		"Tablets":        handlers.SkipFromLuma,
+	}, reasons)
+
+	memories, err := suite.env.Store.Memories.ListByUser(context.Background(), suite.user.ID)
+	suite.Require().NoError(err)
+	suite.Require().Len(memories, 2)
+	byTitle := map[string]models.Memory{}
+	for _, memory := range memories {
+		byTitle[memory.Title] = memory
+	}
+	trip := byTitle["Trip to Whitby"]
+	assert.Equal(suite.T(), "event", trip.Type)
+	assert.Equal(suite.T(), "Fish and chips\n\nAt Whitby", trip.Content)
+	assert.Equal(suite.T(), "2025-08-12", trip.OccurredOn.Format(time.DateOnly))
+	// 22:00 in New York is already the next day in London
+	assert.Equal(suite.T(), "2025-01-02", byTitle["Late dinner"].OccurredOn.Format(time.DateOnly))
+
+	updated, err := suite.env.Store.People.GetForUser(context.Background(), tom.ID, suite.user.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "1990-04-12", updated.Birthday.Format(time.DateOnly))
+
+	people, err := suite.env.Store.People.ListByUser(context.Background(), suite.user.ID)
+	suite.Require().NoError(err)
+	suite.Require().Len(people, 3)
+	var jo *models.Person
+	for i := range people {
+		if people[i].FirstName == "Jo" {
+			jo = &people[i]
+		}
+	}
+	suite.Require().NotNil(jo)
+	assert.Equal(suite.T(), "Smith", jo.LastName)
+	assert.Equal(suite.T(), "1988-07-04", jo.Birthday.Format(time.DateOnly))
+}
+
+func (suite *CalendarTestSuite) TestImportNonASCIIBirthdays() {
+	// Lower-casing changes the byte length of these names, so their offsets
+	// in a lower-cased summary do not line up with the summary itself
+	long := strings.Repeat("Ⱥ", 9)
+	w, result := suite.importCalendar(
+		calendarEvent("UID:long@example.com", "SUMMARY:"+long+" birthday", "DTSTART;VALUE=DATE:19700101"),
+		calendarEvent("UID:ilker@example.com", "SUMMARY:Happy İlker Şahin’S BIRTHDAY", "DTSTART;VALUE=DATE:19800202"),
+		calendarEvent("UID:zoe@example.com", "SUMMARY:Birthday of ZOË İNCE 🎂", "DTSTART;VALUE=DATE:19900303"),
+	)
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(suite.T(), 3, result.BirthdaysSet)
+
+	people, err := suite.env.Store.People.ListByUser(context.Background(), suite.user.ID)
+	suite.Require().NoError(err)
+	names := map[string]string{}
+	for _, person := range people {
+		names[person.Birthday.Format(time.DateOnly)] = strings.TrimSpace(person.FirstName + " " + person.LastName)
+	}
+	assert.Equal(suite.T(), map[string]string{
+		"1970-01-01": long,
+		"1980-02-02": "İlker Şahin",
+		"1990-03-03": "ZOË İNCE",
+	}, names)
+}
+
+func (suite *CalendarTestSuite) TestReimportUpdatesInsteadOfDuplicating() {
+	trip := calendarEvent("UID:trip@example.com", "SUMMARY:Trip to Whitby", "DTSTART;VALUE=DATE:20250812")
+	birthday := calendarEvent("UID:jo@example.com", "SUMMARY:Jo's birthday", "DTSTART;VALUE=DATE:19880704", "RRULE:FREQ=YEARLY")
+	_, result := suite.importCalendar(trip, birthday)
+	assert.Equal(suite.T(), 1, result.MemoriesCreated)
+	assert.Equal(suite.T(), 1, result.PeopleCreated)
+
+	_, result = suite.importCalendar(trip, birthday)
+	assert.Equal(suite.T(), handlers.CalendarImportResponse{Unchanged: 2, Skipped: []handlers.SkippedEvent{}}, result)
+
+	moved := calendarEvent("UID:trip@example.com", "SUMMARY:Trip to Scarborough", "DTSTART;VALUE=DATE:20250813")
+	movedBirthday := calendarEvent("UID:jo@example.com", "SUMMARY:Jo's birthday", "DTSTART;VALUE=DATE:19880705", "RRULE:FREQ=YEARLY")
+	_, result = suite.importCalendar(moved, movedBirthday)
+	assert.Equal(suite.T(), 1, result.MemoriesUpdated)
+	assert.Equal(suite.T(), 1, result.BirthdaysSet)
+	assert.Zero(suite.T(), result.PeopleCreated)
+
+	memories, err := suite.env.Store.Memories.ListByUser(context.Background(), suite.user.ID)
+	suite.Require().NoError(err)
+	suite.Require().Len(memories, 1)
+	assert.Equal(suite.T(), "Trip to Scarborough", memories[0].Title)
+	people, err := suite.env.Store.People.ListByUser(context.Background(), suite.user.ID)
+	suite.Require().NoError(err)
+	suite.Require().Len(people, 1)
+	assert.Equal(suite.T(), "1988-07-05", people[0].Birthday.Format(time.DateOnly))
+}
+
+func (suite *CalendarTestSuite) TestImportErrors() {
+	req, _ := http.NewRequest("POST", "/calendar/import", nil)
+	req.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w, _ = suite.importCalendar("BEGIN:VEVENT\r\nUID:1\r\n")
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	var response struct {
+		Error apierror.Error `json:"error"`
+	}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	suite.Require().Len(response.Error.Fields["file"], 1)
+	assert.Equal(suite.T(), "ical", response.Error.Fields["file"][0].Code)
+
+	w, _ = suite.importCalendar(calendarEvent("UID:big", "DESCRIPTION:"+strings.Repeat("x", 1<<20)))
+	assert.Equal(suite.T(), http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "calendar_too_large")
+}
+
+func TestCalendarTestSuite(t *testing.T) {
+	suite.Run(t, new(CalendarTestSuite))
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/repository"
+)
+
+// AddCareCircleMemberRequest adds someone to the weekly digest
+type AddCareCircleMemberRequest struct {
+	Name  string `json:"name"`
+	Email string `json:"email" binding:"required,email"`
+}
+
+type CareCircleMemberResponse struct {
+	ID    uuid.UUID `json:"id"`
+	Name  string    `json:"name"`
+	Email string    `json:"email"`
+	// WeeklyDigest is false once the member unsubscribes
+	WeeklyDigest bool      `json:"weeklyDigest"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+func newCareCircleMemberResponse(member *models.CareCircleMember) CareCircleMemberResponse {
+	return CareCircleMemberResponse{
+		ID:           member.ID,
+		Name:         member.Name,
+		Email:        member.Email,
+		WeeklyDigest: !member.WeeklyDigestOptOut,
+		CreatedAt:    member.CreatedAt,
+	}
+}
+
+// GetCareCircle lists the people who get the user's weekly digest besides
+// their caregiver
+func (h *Handler) GetCareCircle(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	members, err := h.store.CareCircle.ListByUser(c, userUUID)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to fetch care circle", err))
+		return
+	}
+
+	responses := make([]CareCircleMemberResponse, 0, len(members))
+	for i := range members {
+		responses = append(responses, newCareCircleMemberResponse(&members[i]))
+	}
+	c.JSON(http.StatusOK, responses)
+}
+
+// AddCareCircleMember adds someone to the user's weekly digest. Members are
+// sent it from the next digest on, until they unsubscribe or are removed.
+func (h *Handler) AddCareCircleMember(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	var req AddCareCircleMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, apierror.Bind(err))
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	member := models.CareCircleMember{
+		UserID: userUUID,
+		Name:   strings.TrimSpace(req.Name),
+		Email:  req.Email,
+	}
+	err := h.store.CareCircle.Create(c, &member)
+	switch {
+	case errors.Is(err, repository.ErrDuplicate):
+		apierror.Abort(c, errCareCircleMemberExists)
+	case err != nil:
+		apierror.Abort(c, apierror.Internal("Failed to add care circle member", err))
+	default:
+		c.JSON(http.StatusCreated, newCareCircleMemberResponse(&member))
+	}
+}
+
+// DeleteCareCircleMember removes someone from the user's weekly digest
+func (h *Handler) DeleteCareCircleMember(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	memberID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Abort(c, errInvalidCareCircleMemberID)
+		return
+	}
+
+	err = h.store.CareCircle.Delete(c, memberID, userUUID)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		apierror.Abort(c, errCareCircleMemberNotFound)
+	case err != nil:
+		apierror.Abort(c, apierror.Internal("Failed to remove care circle member", err))
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Care circle member removed successfully"})
+	}
+}
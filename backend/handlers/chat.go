@@ -1,72 +1,105 @@
 package handlers
 
 import (
-	"bufio"
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
-	"os"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/muneerlalji/Luma/db"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/llm"
+	"github.com/muneerlalji/Luma/logging"
 	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/repository"
+	"github.com/muneerlalji/Luma/tracing"
 )
 
-// Claude API request/response structures
-type ClaudeMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
+// chatSystemPrompt frames the assistant; the user's memories and people
+// are appended to it
+const chatSystemPrompt = `You are a compassionate AI assistant designed to help people with memory loss and dementia. 
+Your role is to help them remember important information about their life, people, and events.
 
-type ClaudeRequest struct {
-	Model       string          `json:"model"`
-	MaxTokens   int             `json:"max_tokens"`
-	Messages    []ClaudeMessage `json:"messages"`
-	Temperature float64         `json:"temperature"`
-	Stream      bool            `json:"stream"`
-}
+IMPORTANT GUIDELINES:
+- Be patient, kind, and understanding
+- Use simple, clear language
+- If you don't have information about something, say so gently
+- Focus on positive memories and helpful information
+- Be encouraging and supportive
+- If someone seems confused, help clarify gently
+- Always be respectful and dignified
+- Speak about people who have passed away in the past tense, and gently
+- When asked what to do today, go through today's schedule in order and say what is still to do
+- If there is a memory for today, gently invite them to talk about it, but never insist
 
-type ClaudeResponse struct {
-	Content []struct {
-		Text string `json:"text"`
-	} `json:"content"`
+User's Personal Information:
+`
+
+// confusionPhrases in a user's message suggest they were disoriented, which
+// their caregiver's weekly digest points out. They are matched in lower case,
+// in every language emails are written in.
+var confusionPhrases = []string{
+	"where am i", "what day is it", "what year is it", "who are you", "where is everyone",
+	"i don't know where", "i can't remember", "i'm confused", "i am confused", "i'm lost", "i am lost",
+	"dónde estoy", "qué día es", "qué año es", "quién eres", "no me acuerdo", "no recuerdo",
+	"estoy confundid", "estoy perdid",
 }
 
-type ClaudeStreamingResponse struct {
-	Type  string `json:"type"`
-	Index int    `json:"index"`
-	Delta struct {
-		Type string `json:"type"`
-		Text string `json:"text"`
-	} `json:"delta"`
+// seemsConfused reports whether message contains one of confusionPhrases
+func seemsConfused(message string) bool {
+	message = strings.ToLower(strings.ReplaceAll(message, "’", "'"))
+	for _, phrase := range confusionPhrases {
+		if strings.Contains(message, phrase) {
+			return true
+		}
+	}
+	return false
 }
 
 // Chat handles chat requests and provides AI-powered responses
-func Chat(c *gin.Context) {
+func (h *Handler) Chat(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		apierror.Abort(c, apierror.ErrUnauthenticated)
 		return
 	}
 
 	var req models.ChatRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		fmt.Printf("Error binding JSON: %v\n", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Bind(err))
 		return
 	}
 
-	// Get user's memories and people for context
-	memories, people, err := getUserContext(userID.(uuid.UUID))
+	// Opening with a prompt's question records that the user engaged with it
+	var opener string
+	if req.PromptID != nil {
+		err := h.store.Transaction(c, func(tx *repository.Store) error {
+			prompt, err := h.respondToPrompt(c, tx, *req.PromptID, userID.(uuid.UUID), models.PromptEngaged)
+			if err == nil {
+				opener = prompt.Question
+			}
+			return err
+		})
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			apierror.Abort(c, errPromptNotFound)
+			return
+		case err != nil && !errors.Is(err, errPromptAnswered):
+			apierror.Abort(c, apierror.Internal("Failed to respond to memory prompt", err))
+			return
+		}
+	}
+
+	// Get user's memories, people and recent contact for context
+	userContext, err := h.getUserContext(c, userID.(uuid.UUID))
 	if err != nil {
-		fmt.Printf("Error getting user context: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user context"})
+		apierror.Abort(c, apierror.Internal("Failed to get user context", err))
 		return
 	}
+	userContext.opener = opener
 
 	// Check if streaming is requested
 	if c.Query("stream") == "true" {
@@ -78,25 +111,26 @@ func Chat(c *gin.Context) {
 		c.Header("Access-Control-Allow-Headers", "Cache-Control")
 
 		// Handle streaming chat
-		if err := generateStreamingAIResponse(c, userID.(uuid.UUID), req.Message, memories, people); err != nil {
-			fmt.Printf("Streaming chat error: %v\n", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Streaming failed"})
+		if err := h.generateStreamingAIResponse(c, userID.(uuid.UUID), req.Message, userContext); err != nil {
+			if errors.Is(err, llm.ErrNotConfigured) {
+				apierror.Abort(c, errChatNotConfigured)
+				return
+			}
+			apierror.Abort(c, apierror.Internal("Streaming failed", err))
 		}
 		return
 	}
 
 	// Generate AI response (non-streaming)
-	response, err := generateAIResponse(req.Message, memories, people)
+	response, err := h.generateAIResponse(c, req.Message, userContext)
 	if err != nil {
-		fmt.Printf("Error generating AI response: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate response"})
+		apierror.Abort(c, apierror.Internal("Failed to generate response", err))
 		return
 	}
 
 	// Save both user message and AI response to database
-	if err := saveChatMessages(userID.(uuid.UUID), req.Message, response); err != nil {
-		fmt.Printf("Error saving chat messages: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save chat messages"})
+	if err := h.saveChatMessages(c, userID.(uuid.UUID), opener, req.Message, response); err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to save chat messages", err))
 		return
 	}
 
@@ -104,10 +138,10 @@ func Chat(c *gin.Context) {
 }
 
 // GetChatHistory retrieves the user's chat history
-func GetChatHistory(c *gin.Context) {
+func (h *Handler) GetChatHistory(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		apierror.Abort(c, apierror.ErrUnauthenticated)
 		return
 	}
 
@@ -116,300 +150,201 @@ func GetChatHistory(c *gin.Context) {
 	var limit int
 	if limitStr != "" {
 		if _, err := fmt.Sscanf(limitStr, "%d", &limit); err != nil || limit <= 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+			apierror.Abort(c, errInvalidLimit)
 			return
 		}
 	}
 
-	var messages []models.ChatMessage
-	query := db.DB.Where("user_id = ?", userID).Order("created_at asc")
-
-	if limit > 0 {
-		query = query.Limit(limit)
-	}
-
-	if err := query.Find(&messages).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve chat history"})
+	messages, err := h.store.ChatMessages.ListByUser(c, userID.(uuid.UUID), limit)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to retrieve chat history", err))
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"messages": messages})
 }
 
-// getUserContext retrieves user's memories and people for AI context
-func getUserContext(userID uuid.UUID) ([]models.Memory, []models.Person, error) {
-	var memories []models.Memory
-	var people []models.Person
-
-	// Get memories with people relationships
-	if err := db.DB.Preload("People").Where("user_id = ?", userID).Find(&memories).Error; err != nil {
-		return nil, nil, err
-	}
-
-	// Get people
-	if err := db.DB.Where("user_id = ?", userID).Find(&people).Error; err != nil {
-		return nil, nil, err
-	}
-
-	return memories, people, nil
+// chatContext is what the assistant knows about the user's life
+type chatContext struct {
+	memories []models.Memory
+	people   []models.Person
+	// lastContacts holds the latest interaction with each person
+	lastContacts []models.Interaction
+	// upcoming holds the birthdays and anniversaries of the next two weeks
+	upcoming []UpcomingEvent
+	// schedule holds today's routines in the user's timezone
+	schedule []ScheduledCheckIn
+	// prompt is today's memory prompt, if the user has not skipped it
+	prompt *models.MemoryPrompt
+	// opener is the assistant's question the user's message answers
+	opener string
+	now    time.Time
+	loc    *time.Location
 }
 
-// generateAIResponse creates a response using Claude API with user context
-func generateAIResponse(userMessage string, memories []models.Memory, people []models.Person) (string, error) {
-	apiKey := os.Getenv("CLAUDE_API_KEY")
-	if apiKey == "" {
-		fmt.Printf("CLAUDE_API_KEY is not set\n")
-		return "I'm sorry, but I'm not configured to respond right now. Please contact support.", nil
-	}
-
-	apiURL := os.Getenv("ANTHROPIC_API_URL")
-	if apiURL == "" {
-		fmt.Printf("ANTHROPIC_API_URL is not set\n")
-		return "I'm sorry, but I'm not configured to respond right now. Please contact support.", nil
-	}
+// getUserContext retrieves user's memories, people, latest interactions and
+// today's schedule for AI context
+func (h *Handler) getUserContext(ctx context.Context, userID uuid.UUID) (_ chatContext, err error) {
+	ctx, span := tracing.Start(ctx, "chat.get_user_context")
+	defer func() { tracing.End(span, err) }()
 
-	// Build context from user's data
-	context := buildContext(memories, people)
+	userContext := chatContext{now: h.now()}
 
-	// Create system prompt
-	systemPrompt := `You are a compassionate AI assistant designed to help people with memory loss and dementia. 
-Your role is to help them remember important information about their life, people, and events.
-
-IMPORTANT GUIDELINES:
-- Be patient, kind, and understanding
-- Use simple, clear language
-- If you don't have information about something, say so gently
-- Focus on positive memories and helpful information
-- Be encouraging and supportive
-- If someone seems confused, help clarify gently
-- Always be respectful and dignified
-
-User's Personal Information:
-` + context
-
-	// Prepare Claude API request
-	claudeReq := ClaudeRequest{
-		Model:       "claude-3-5-sonnet-20241022",
-		MaxTokens:   1000,
-		Temperature: 0.7,
-		Messages: []ClaudeMessage{
-			{Role: "user", Content: systemPrompt + "\n\nUser: " + userMessage},
-		},
-		Stream: false,
-	}
-
-	// Make API request
-	jsonData, err := json.Marshal(claudeReq)
+	// Get memories with people relationships
+	userContext.memories, err = h.store.Memories.ListByUser(ctx, userID)
 	if err != nil {
-		fmt.Printf("Error marshaling request: %v\n", err)
-		return "", err
+		return chatContext{}, err
 	}
 
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(jsonData))
+	// Get people
+	userContext.people, err = h.store.People.ListByUser(ctx, userID)
 	if err != nil {
-		fmt.Printf("Error creating request: %v\n", err)
-		return "", err
+		return chatContext{}, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", apiKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	userContext.lastContacts, err = h.store.Interactions.LatestByPerson(ctx, userID)
 	if err != nil {
-		fmt.Printf("Error making HTTP request: %v\n", err)
-		return "", err
+		return chatContext{}, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	userContext.loc, err = h.userLocation(ctx, userID)
 	if err != nil {
-		fmt.Printf("Error reading response body: %v\n", err)
-		return "", err
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("Claude API request failed with status %d: %s\n", resp.StatusCode, string(body))
-		return "", fmt.Errorf("API request failed: %s", string(body))
-	}
-
-	var claudeResp ClaudeResponse
-	if err := json.Unmarshal(body, &claudeResp); err != nil {
-		fmt.Printf("Error unmarshaling response: %v\n", err)
-		return "", err
-	}
-
-	return claudeResp.Content[0].Text, nil
-}
-
-// generateStreamingAIResponse handles streaming responses from Claude API
-func generateStreamingAIResponse(c *gin.Context, userID uuid.UUID, userMessage string, memories []models.Memory, people []models.Person) error {
-	apiKey := os.Getenv("CLAUDE_API_KEY")
-	if apiKey == "" {
-		fmt.Printf("CLAUDE_API_KEY is not set for streaming\n")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Streaming not configured"})
-		return fmt.Errorf("streaming not configured")
-	}
-
-	apiURL := os.Getenv("ANTHROPIC_API_URL")
-	if apiURL == "" {
-		fmt.Printf("ANTHROPIC_API_URL is not set for streaming\n")
-		return fmt.Errorf("streaming not configured")
-	}
-
-	// Build context for streaming
-	context := buildContext(memories, people)
-
-	// Create system prompt for streaming
-	systemPrompt := `You are a compassionate AI assistant designed to help people with memory loss and dementia. 
-Your role is to help them remember important information about their life, people, and events.
-
-IMPORTANT GUIDELINES:
-- Be patient, kind, and understanding
-- Use simple, clear language
-- If you don't have information about something, say so gently
-- Focus on positive memories and helpful information
-- Be encouraging and supportive
-- If someone seems confused, help clarify gently
-- Always be respectful and dignified
-
-User's Personal Information:
-` + context
-
-	// Prepare Claude API request for streaming
-	claudeReq := ClaudeRequest{
-		Model:       "claude-3-5-sonnet-20241022",
-		MaxTokens:   2000,
-		Temperature: 0.7,
-		Messages: []ClaudeMessage{
-			{Role: "user", Content: systemPrompt + "\n\nUser: " + userMessage},
-		},
-		Stream: true,
+		return chatContext{}, err
 	}
 
-	// Make API request
-	jsonData, err := json.Marshal(claudeReq)
+	today := localDay(userContext.now, userContext.loc)
+	userContext.upcoming = upcomingEvents(userContext.people, userContext.memories, today, chatUpcomingDays)
+	userContext.schedule, err = h.daySchedule(ctx, userID, userContext.loc, today, userContext.now)
 	if err != nil {
-		fmt.Printf("Error marshaling streaming request: %v\n", err)
-		return err
+		return chatContext{}, err
 	}
 
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		fmt.Printf("Error creating streaming request: %v\n", err)
-		return err
+	prompt, err := h.store.MemoryPrompts.GetForDay(ctx, userID, today)
+	switch {
+	case err == nil && prompt.Response != models.PromptSkipped:
+		userContext.prompt = prompt
+	case err != nil && !errors.Is(err, repository.ErrNotFound):
+		return chatContext{}, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", apiKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
+	return userContext, nil
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Printf("Error making HTTP streaming request: %v\n", err)
-		return err
+// chatPrompt combines the system prompt, the user's context, the question
+// that opened the conversation and their message
+func chatPrompt(userMessage string, userContext chatContext) string {
+	prompt := chatSystemPrompt + buildContext(userContext)
+	if userContext.opener != "" {
+		prompt += "\n\nAssistant: " + userContext.opener
 	}
-	defer resp.Body.Close()
+	return prompt + "\n\nUser: " + userMessage
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		fmt.Printf("Claude API streaming request failed with status %d: %s\n", resp.StatusCode, string(body))
-		return fmt.Errorf("API streaming request failed: %s", string(body))
+// generateAIResponse creates a response using the language model with user context
+func (h *Handler) generateAIResponse(c *gin.Context, userMessage string, userContext chatContext) (string, error) {
+	// The client may still watch its context after the handler returns, when
+	// gin has reused c for another request, so it gets the request's context
+	response, err := h.llm.Complete(c.Request.Context(), chatPrompt(userMessage, userContext), 1000)
+	if errors.Is(err, llm.ErrNotConfigured) {
+		logging.FromContext(c).Warn("language model is not configured")
+		return "I'm sorry, but I'm not configured to respond right now. Please contact support.", nil
 	}
+	return response, err
+}
 
-	// Read and stream the response
-	scanner := bufio.NewScanner(resp.Body)
-	buf := make([]byte, 0, 1024*1024)
-	scanner.Buffer(buf, 1024*1024)
-
+// generateStreamingAIResponse streams the language model's response to the
+// client as Server-Sent Events
+func (h *Handler) generateStreamingAIResponse(c *gin.Context, userID uuid.UUID, userMessage string, userContext chatContext) error {
 	var fullResponse strings.Builder
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		// Handle Server-Sent Events format
-		if strings.HasPrefix(line, "data: ") {
-			data := strings.TrimPrefix(line, "data: ")
+	err := h.llm.Stream(c.Request.Context(), chatPrompt(userMessage, userContext), 2000, func(text string) {
+		// Escape newlines for SSE format
+		escapedText := strings.ReplaceAll(text, "\n", "\\n")
 
-			// Check for end of stream
-			if data == "[DONE]" {
-				break
-			}
-
-			// Parse JSON data
-			var claudeStreamResp ClaudeStreamingResponse
-			if err := json.Unmarshal([]byte(data), &claudeStreamResp); err != nil {
-				fmt.Printf("Error parsing streaming data: %v, data: %s\n", err, data)
-				continue
-			}
-
-			// Extract text content
-			if claudeStreamResp.Type == "content_block_delta" &&
-				(claudeStreamResp.Delta.Type == "text" || claudeStreamResp.Delta.Type == "text_delta") {
-
-				// Escape newlines for SSE format
-				escapedText := strings.ReplaceAll(claudeStreamResp.Delta.Text, "\n", "\\n")
-
-				fmt.Fprintf(c.Writer, "data: %s\n\n", escapedText)
-				c.Writer.Flush()
-				fullResponse.WriteString(claudeStreamResp.Delta.Text)
-			}
-		} else {
-			var claudeStreamResp ClaudeStreamingResponse
-			if err := json.Unmarshal([]byte(line), &claudeStreamResp); err == nil {
-				if claudeStreamResp.Type == "content_block_delta" &&
-					(claudeStreamResp.Delta.Type == "text" || claudeStreamResp.Delta.Type == "text_delta") {
-
-					// Escape newlines for SSE format
-					escapedText := strings.ReplaceAll(claudeStreamResp.Delta.Text, "\n", "\\n")
-
-					fmt.Fprintf(c.Writer, "data: %s\n\n", escapedText)
-					c.Writer.Flush()
-					fullResponse.WriteString(claudeStreamResp.Delta.Text)
-				}
-			}
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		fmt.Printf("Error reading streaming response: %v\n", err)
+		fmt.Fprintf(c.Writer, "data: %s\n\n", escapedText)
+		c.Writer.Flush()
+		fullResponse.WriteString(text)
+	})
+	if err != nil {
 		return err
 	}
 
-	// Check if we have any remaining buffer content
-	if buffer := scanner.Bytes(); len(buffer) > 0 {
-		fmt.Printf("Warning: Unprocessed buffer content: %s\n", string(buffer))
-	}
-
 	// Save the messages to database after streaming is complete
-	if err := saveChatMessages(userID, userMessage, fullResponse.String()); err != nil {
-		fmt.Printf("Error saving streaming chat messages: %v\n", err)
+	if err := h.saveChatMessages(c, userID, userContext.opener, userMessage, fullResponse.String()); err != nil {
+		logging.FromContext(c).Error("failed to save streamed chat messages", "error", err)
 	}
 
 	return nil
 }
 
-// buildContext creates a context string from user's memories and people
-func buildContext(memories []models.Memory, people []models.Person) string {
+// buildContext creates a context string from user's memories, people and
+// when they were last in touch
+func buildContext(userContext chatContext) string {
 	var context strings.Builder
+	memories, people := userContext.memories, userContext.people
 
 	// Add people information
 	if len(people) > 0 {
 		context.WriteString("Important People in Your Life:\n")
 		for _, person := range people {
+			about := person.Relationship
+			if person.DiedOn != nil {
+				about += ", passed away on " + person.DiedOn.Format("2 January 2006")
+			}
 			context.WriteString(fmt.Sprintf("- %s %s (%s): %s\n",
-				person.FirstName, person.LastName, person.Relationship, person.Notes))
+				person.FirstName, person.LastName, about, person.Notes))
 		}
 		context.WriteString("\n")
 	}
 
+	// Add when the user last saw or spoke to each person
+	if facts := lastContactFacts(userContext); len(facts) > 0 {
+		context.WriteString("When You Were Last in Touch:\n")
+		for _, fact := range facts {
+			context.WriteString("- " + fact + "\n")
+		}
+		context.WriteString("\n")
+	}
+
+	// Add birthdays and anniversaries coming up
+	if len(userContext.upcoming) > 0 {
+		context.WriteString("Coming Up:\n")
+		for i := range userContext.upcoming {
+			event := &userContext.upcoming[i]
+			context.WriteString(fmt.Sprintf("- %s: %s\n", eventWhen(event), event.Title))
+		}
+		context.WriteString("\n")
+	}
+
+	// Add today's routines and whether they are done
+	if len(userContext.schedule) > 0 {
+		context.WriteString(fmt.Sprintf("Today's Schedule (%s):\n", userContext.now.In(userContext.loc).Format("Monday, 2 January")))
+		for _, item := range userContext.schedule {
+			line := fmt.Sprintf("- %s %s (%s, %s)", item.Time, item.Title, item.Kind, scheduleStatusText(item.Status))
+			if item.Instructions != "" {
+				line += ": " + item.Instructions
+			}
+			context.WriteString(line + "\n")
+		}
+		context.WriteString("\n")
+	}
+
+	// Add the memory the user is invited to reminisce about today
+	if prompt := userContext.prompt; prompt != nil {
+		context.WriteString("Today's Memory:\n")
+		context.WriteString(fmt.Sprintf("- %s. Ask about it with: %s\n\n", prompt.Memory.Title, prompt.Question))
+	}
+
 	// Add memories information
 	if len(memories) > 0 {
 		context.WriteString("Your Memories and Events:\n")
 		for _, memory := range memories {
+			title := memory.Title
+			if memory.OccurredOn != nil {
+				title += ", " + memory.OccurredOn.Format("2 January 2006")
+			}
 			context.WriteString(fmt.Sprintf("- %s (%s): %s\n",
-				memory.Title, memory.Type, memory.Content))
+				title, memory.Type, memory.Content))
 
 			// Add associated people
 			if len(memory.People) > 0 {
@@ -425,23 +360,84 @@ func buildContext(memories []models.Memory, people []models.Person) string {
 	return context.String()
 }
 
-// saveChatMessages saves both user and assistant messages to the database
-func saveChatMessages(userID uuid.UUID, userMessage, assistantMessage string) error {
-	// Save user message
+// saveChatMessages saves both user and assistant messages to the database,
+// after the assistant's opening question if there was one
+func (h *Handler) saveChatMessages(ctx context.Context, userID uuid.UUID, opener, userMessage, assistantMessage string) (err error) {
+	ctx, span := tracing.Start(ctx, "chat.save_messages")
+	defer func() { tracing.End(span, err) }()
+
 	userMsg := models.ChatMessage{
-		UserID:  userID,
-		Role:    "user",
-		Content: userMessage,
-	}
-	if err := db.DB.Create(&userMsg).Error; err != nil {
-		return err
+		UserID:   userID,
+		Role:     "user",
+		Content:  userMessage,
+		Confused: seemsConfused(userMessage),
 	}
-
-	// Save assistant message
 	assistantMsg := models.ChatMessage{
 		UserID:  userID,
 		Role:    "assistant",
 		Content: assistantMessage,
 	}
-	return db.DB.Create(&assistantMsg).Error
+	if opener == "" {
+		return h.store.ChatMessages.Create(ctx, &userMsg, &assistantMsg)
+	}
+	openerMsg := models.ChatMessage{
+		UserID:  userID,
+		Role:    "assistant",
+		Content: opener,
+	}
+	return h.store.ChatMessages.Create(ctx, &openerMsg, &userMsg, &assistantMsg)
+}
+
+// scheduleStatusText describes a check-in status to the assistant
+func scheduleStatusText(status string) string {
+	switch status {
+	case models.CheckInDone:
+		return "done"
+	case models.CheckInSkipped:
+		return "skipped"
+	case models.CheckInMissed:
+		return "missed"
+	default:
+		return "still to do"
+	}
+}
+
+// lastContactFacts describes the latest interaction with each person, such as
+// "Last in touch with Tom Hughes: visit on Monday, 2 March 2026 (3 days ago)"
+func lastContactFacts(userContext chatContext) []string {
+	names := make(map[uuid.UUID]string, len(userContext.people))
+	for i := range userContext.people {
+		names[userContext.people[i].ID] = personName(&userContext.people[i])
+	}
+
+	var facts []string
+	for _, interaction := range userContext.lastContacts {
+		name, ok := names[interaction.PersonID]
+		if !ok {
+			continue
+		}
+		fact := fmt.Sprintf("Last in touch with %s: %s on %s (%s)", name, interaction.Type,
+			interaction.OccurredAt.Format("Monday, 2 January 2006"), daysAgo(interaction.OccurredAt, userContext.now))
+		if interaction.Notes != "" {
+			fact += ". " + interaction.Notes
+		}
+		facts = append(facts, fact)
+	}
+	return facts
+}
+
+// daysAgo says how many calendar days before now then was, in words
+func daysAgo(then, now time.Time) string {
+	date := func(t time.Time) time.Time {
+		year, month, day := t.In(now.Location()).Date()
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	switch days := daysBetween(date(then), date(now)); days {
+	case 0:
+		return "today"
+	case 1:
+		return "yesterday"
+	default:
+		return fmt.Sprintf("%d days ago", days)
+	}
 }
@@ -2,25 +2,29 @@ package handlers_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/muneerlalji/Luma/apierror"
 	"github.com/muneerlalji/Luma/handlers"
+	"github.com/muneerlalji/Luma/llm"
 	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/repository"
 	"github.com/muneerlalji/Luma/testutils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
-	"gorm.io/gorm"
 )
 
 type ChatTestSuite struct {
 	suite.Suite
 	router        *gin.Engine
-	db            *gorm.DB
+	store         *repository.Store
 	user          models.User
 	token         string
 	anthropicMock *testutils.AnthropicMock
@@ -31,17 +35,15 @@ func (suite *ChatTestSuite) SetupSuite() {
 
 	// Setup Anthropic mock
 	suite.anthropicMock = testutils.SetupAnthropicMock()
-
-	// Setup test database with Anthropic mock URL
-	suite.db = testutils.SetupTestDBWithAnthropicMock(suite.anthropicMock.GetBaseURL())
-
-	// Auto-migrate test database
-	suite.db.AutoMigrate(&models.User{}, &models.ChatMessage{})
 }
 
 func (suite *ChatTestSuite) SetupTest() {
-	// Clear database before each test
-	testutils.CleanupTestDB(suite.db)
+	// Start each test from an empty store pointed at the Anthropic mock
+	env := testutils.NewTestEnv(func(d *handlers.Deps) {
+		d.LLM = llm.NewClaude("test-key", suite.anthropicMock.GetBaseURL())
+	})
+	suite.store = env.Store
+	h := env.Handler
 
 	// Clear Anthropic mock requests
 	suite.anthropicMock.ClearRequests()
@@ -53,13 +55,14 @@ func (suite *ChatTestSuite) SetupTest() {
 		DisplayName:    "Test User",
 		EmailConfirmed: true,
 	}
-	suite.db.Create(&suite.user)
+	suite.store.Users.Create(context.Background(), &suite.user)
 
 	// Generate a test token
 	suite.token = "test-token"
 
 	// Setup router
 	suite.router = gin.Default()
+	suite.router.Use(testutils.OpenAPIValidator(suite.T()), apierror.Middleware())
 
 	// Setup protected routes
 	protected := suite.router.Group("/")
@@ -69,16 +72,12 @@ func (suite *ChatTestSuite) SetupTest() {
 		c.Next()
 	})
 	{
-		protected.POST("/chat", handlers.Chat)
-		protected.GET("/chat/history", handlers.GetChatHistory)
+		protected.POST("/chat", h.Chat)
+		protected.GET("/chat/history", h.GetChatHistory)
 	}
 }
 
 func (suite *ChatTestSuite) TearDownSuite() {
-	// Clean up test database
-	suite.db.Exec("DROP TABLE IF EXISTS chat_messages CASCADE")
-	suite.db.Exec("DROP TABLE IF EXISTS users CASCADE")
-
 	// Close Anthropic mock
 	suite.anthropicMock.Close()
 }
@@ -105,14 +104,40 @@ func (suite *ChatTestSuite) TestChat_Success() {
 	assert.Contains(suite.T(), response, "message")
 
 	// Verify chat message was created in database
-	var chat models.ChatMessage
-	err = suite.db.Where("content = ?", chatData.Message).First(&chat).Error
+	chats, err := suite.store.ChatMessages.ListByUser(context.Background(), suite.user.ID, 0)
 	assert.NoError(suite.T(), err)
+	suite.Require().NotEmpty(chats)
+	chat := chats[0]
 	assert.Equal(suite.T(), chatData.Message, chat.Content)
 	assert.Equal(suite.T(), "user", chat.Role)
 	assert.Equal(suite.T(), suite.user.ID, chat.UserID)
 }
 
+func (suite *ChatTestSuite) TestChat_FlagsConfusion() {
+	for _, message := range []string{"Who is Tom?", "Sorry, where am I?", "¿Qué día es hoy?", "I’m lost"} {
+		jsonData, _ := json.Marshal(models.ChatRequest{Message: message})
+		req, _ := http.NewRequest("POST", "/chat", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, req)
+		suite.Require().Equal(http.StatusOK, w.Code)
+	}
+
+	chats, err := suite.store.ChatMessages.ListByUser(context.Background(), suite.user.ID, 0)
+	suite.Require().NoError(err)
+	confused := map[string]bool{}
+	for _, chat := range chats {
+		if chat.Role == "user" {
+			confused[chat.Content] = chat.Confused
+		} else {
+			assert.False(suite.T(), chat.Confused, "only the user's messages are flagged")
+		}
+	}
+	assert.Equal(suite.T(), map[string]bool{
+		"Who is Tom?": false, "Sorry, where am I?": true, "¿Qué día es hoy?": true, "I’m lost": true,
+	}, confused)
+}
+
 func (suite *ChatTestSuite) TestChat_EmptyMessage() {
 	chatData := models.ChatRequest{
 		Message: "",
@@ -150,7 +175,7 @@ func (suite *ChatTestSuite) TestGetChatHistory_Success() {
 	}
 
 	for _, chat := range chats {
-		suite.db.Create(&chat)
+		suite.store.ChatMessages.Create(context.Background(), &chat)
 	}
 
 	req, _ := http.NewRequest("GET", "/chat/history", nil)
@@ -200,7 +225,7 @@ func (suite *ChatTestSuite) TestGetChatHistory_UserIsolation() {
 		DisplayName:    "Other User",
 		EmailConfirmed: true,
 	}
-	suite.db.Create(&otherUser)
+	suite.store.Users.Create(context.Background(), &otherUser)
 
 	// Create chat messages for both users
 	chat1 := models.ChatMessage{
@@ -214,8 +239,7 @@ func (suite *ChatTestSuite) TestGetChatHistory_UserIsolation() {
 		UserID:  otherUser.ID,
 	}
 
-	suite.db.Create(&chat1)
-	suite.db.Create(&chat2)
+	suite.store.ChatMessages.Create(context.Background(), &chat1, &chat2)
 
 	// Get chat history for the first user
 	req, _ := http.NewRequest("GET", "/chat/history", nil)
@@ -243,7 +267,7 @@ func (suite *ChatTestSuite) TestGetChatHistory_Limit() {
 			Content: fmt.Sprintf("Message %d", i),
 			UserID:  suite.user.ID,
 		}
-		suite.db.Create(&chat)
+		suite.store.ChatMessages.Create(context.Background(), &chat)
 	}
 
 	req, _ := http.NewRequest("GET", "/chat/history?limit=10", nil)
@@ -263,6 +287,132 @@ func (suite *ChatTestSuite) TestGetChatHistory_Limit() {
 	assert.Len(suite.T(), chatsArray, 10)
 }
 
+func (suite *ChatTestSuite) TestChat_IncludesLastContact() {
+	person := models.Person{UserID: suite.user.ID, FirstName: "Tom", LastName: "Hughes", Relationship: "Son"}
+	suite.Require().NoError(suite.store.People.Create(context.Background(), &person))
+	visited := time.Now().AddDate(0, 0, -3)
+	for _, interaction := range []models.Interaction{
+		{UserID: suite.user.ID, PersonID: person.ID, Type: models.InteractionCall, OccurredAt: visited.AddDate(0, 0, -7)},
+		{UserID: suite.user.ID, PersonID: person.ID, Type: models.InteractionVisit, OccurredAt: visited, Notes: "Walked by the lake"},
+	} {
+		suite.Require().NoError(suite.store.Interactions.Create(context.Background(), &interaction))
+	}
+
+	jsonData, _ := json.Marshal(models.ChatRequest{Message: "When did I last see Tom?"})
+	req, _ := http.NewRequest("POST", "/chat", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	suite.Require().Equal(http.StatusOK, w.Code)
+
+	requests := suite.anthropicMock.GetRequests()
+	suite.Require().Len(requests, 1)
+	prompt := requests[0].Messages[0].Content
+	assert.Contains(suite.T(), prompt, "Last in touch with Tom Hughes: visit on "+visited.Format("Monday, 2 January 2006")+" (3 days ago). Walked by the lake")
+	assert.NotContains(suite.T(), prompt, "Last in touch with Tom Hughes: call")
+}
+
+func (suite *ChatTestSuite) TestChat_DeceasedAndUpcoming() {
+	today := time.Now().UTC()
+	birthday := today.AddDate(-12, 0, 2)
+	diedOn := time.Date(2021, time.May, 3, 0, 0, 0, 0, time.UTC)
+	for _, person := range []models.Person{
+		{UserID: suite.user.ID, FirstName: "Tom", LastName: "Hughes", Relationship: "Grandson", Birthday: &birthday},
+		{UserID: suite.user.ID, FirstName: "Frank", LastName: "Hughes", Relationship: "Husband", DiedOn: &diedOn},
+	} {
+		suite.Require().NoError(suite.store.People.Create(context.Background(), &person))
+	}
+
+	jsonData, _ := json.Marshal(models.ChatRequest{Message: "What's coming up?"})
+	req, _ := http.NewRequest("POST", "/chat", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	suite.Require().Equal(http.StatusOK, w.Code)
+
+	requests := suite.anthropicMock.GetRequests()
+	suite.Require().Len(requests, 1)
+	prompt := requests[0].Messages[0].Content
+	assert.Contains(suite.T(), prompt, "past tense")
+	assert.Contains(suite.T(), prompt, "Frank Hughes (Husband, passed away on 3 May 2021)")
+	assert.Contains(suite.T(), prompt, today.AddDate(0, 0, 2).Format("Monday, 2 January")+" (in 2 days): Tom Hughes turns 12")
+}
+
+func (suite *ChatTestSuite) TestChat_IncludesTodaysSchedule() {
+	now := time.Now().UTC()
+	yesterday := now.AddDate(0, 0, -1)
+	routine := models.Routine{
+		UserID: suite.user.ID, Kind: models.RoutineMedication, Title: "Blood pressure tablets", Instructions: "Two with water",
+		Recurrence: "FREQ=DAILY", Times: []string{"00:00", "23:59"}, StartsOn: yesterday, GraceMinutes: 60, CreatedAt: yesterday,
+	}
+	suite.Require().NoError(suite.store.Routines.Create(context.Background(), &routine))
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	checkIn := models.CheckIn{UserID: suite.user.ID, RoutineID: routine.ID, ScheduledAt: midnight, Status: models.CheckInDone, CheckedInAt: &midnight}
+	suite.Require().NoError(suite.store.CheckIns.Create(context.Background(), &checkIn))
+
+	jsonData, _ := json.Marshal(models.ChatRequest{Message: "What do I need to do today?"})
+	req, _ := http.NewRequest("POST", "/chat", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	suite.Require().Equal(http.StatusOK, w.Code)
+
+	requests := suite.anthropicMock.GetRequests()
+	suite.Require().Len(requests, 1)
+	prompt := requests[0].Messages[0].Content
+	assert.Contains(suite.T(), prompt, "Today's Schedule ("+now.Format("Monday, 2 January")+"):")
+	assert.Contains(suite.T(), prompt, "- 00:00 Blood pressure tablets (medication, done): Two with water")
+	assert.Contains(suite.T(), prompt, "- 23:59 Blood pressure tablets (medication, still to do): Two with water")
+}
+
+func (suite *ChatTestSuite) TestChat_OpensWithMemoryPrompt() {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	memory := models.Memory{UserID: suite.user.ID, Title: "Lake trip", Type: "story", Content: "Swimming at dawn", CreatedAt: now.AddDate(-1, 0, 0)}
+	suite.Require().NoError(suite.store.Memories.Create(ctx, &memory))
+	prompt := models.MemoryPrompt{
+		UserID: suite.user.ID, MemoryID: memory.ID, Day: time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC),
+		Reason: models.PromptRevisit, Question: "Do you remember Lake trip? Tell me about it.", Response: models.PromptPending,
+	}
+	suite.Require().NoError(suite.store.MemoryPrompts.Create(ctx, &prompt))
+
+	send := func(req models.ChatRequest) *httptest.ResponseRecorder {
+		jsonData, _ := json.Marshal(req)
+		httpReq, _ := http.NewRequest("POST", "/chat", bytes.NewBuffer(jsonData))
+		httpReq.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, httpReq)
+		return w
+	}
+	suite.Require().Equal(http.StatusOK, send(models.ChatRequest{Message: "We swam every morning", PromptID: &prompt.ID}).Code)
+
+	requests := suite.anthropicMock.GetRequests()
+	suite.Require().Len(requests, 1)
+	content := requests[0].Messages[0].Content
+	assert.Contains(suite.T(), content, "Today's Memory:\n- Lake trip. Ask about it with: "+prompt.Question)
+	assert.Contains(suite.T(), content, "Assistant: "+prompt.Question+"\n\nUser: We swam every morning")
+
+	chats, err := suite.store.ChatMessages.ListByUser(ctx, suite.user.ID, 0)
+	suite.Require().NoError(err)
+	suite.Require().Len(chats, 3)
+	assert.Equal(suite.T(), "assistant", chats[0].Role)
+	assert.Equal(suite.T(), prompt.Question, chats[0].Content)
+	assert.Equal(suite.T(), "We swam every morning", chats[1].Content)
+
+	answered, err := suite.store.MemoryPrompts.GetForUser(ctx, prompt.ID, suite.user.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), models.PromptEngaged, answered.Response)
+
+	// Later messages about the same prompt are not opened again
+	suite.Require().Equal(http.StatusOK, send(models.ChatRequest{Message: "And had a picnic", PromptID: &prompt.ID}).Code)
+	chats, err = suite.store.ChatMessages.ListByUser(ctx, suite.user.ID, 0)
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), chats, 5)
+
+	unknown := memory.ID
+	assert.Equal(suite.T(), http.StatusNotFound, send(models.ChatRequest{Message: "Hello", PromptID: &unknown}).Code)
+}
+
 func TestChatTestSuite(t *testing.T) {
 	suite.Run(t, new(ChatTestSuite))
 }
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/muneerlalji/Luma/mailer"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/repository"
+	"github.com/muneerlalji/Luma/utils"
+)
+
+const (
+	// outboxBatchSize is how many emails ProcessOutbox claims per transaction
+	outboxBatchSize = 50
+	// outboxMaxAttempts is how many times an email is tried before it is
+	// marked failed
+	outboxMaxAttempts = 8
+	// outboxRetryDelay is the wait after the first failed attempt, doubling
+	// with each further one
+	outboxRetryDelay = time.Minute
+)
+
+// ErrNotDelivered is returned by ProcessOutbox when emails failed to send.
+// They stay in the outbox to be retried, so the run itself did its work.
+var ErrNotDelivered = errors.New("emails not delivered")
+
+// queueEmail renders the named template in the user's locale and puts it in
+// the outbox of store for ProcessOutbox to send. Emails about a user go out
+// in their locale even when addressed to someone else, such as a caregiver.
+// Queue with the transaction's store so the email is only sent if the
+// changes it announces are committed.
+func (h *Handler) queueEmail(ctx context.Context, store *repository.Store, user *models.User, to, name string, data any) error {
+	message, err := mailer.Render(name, user.Locale, data)
+	if err != nil {
+		return err
+	}
+	locale := user.Locale
+	if !mailer.Supported(locale) {
+		locale = mailer.DefaultLocale
+	}
+	email := models.OutboxEmail{
+		UserID:        user.ID,
+		Template:      name,
+		Locale:        locale,
+		Recipient:     to,
+		Subject:       message.Subject,
+		TextBody:      message.Text,
+		HTMLBody:      message.HTML,
+		Status:        models.EmailPending,
+		NextAttemptAt: h.now(),
+	}
+	if err := store.OutboxEmails.Create(ctx, &email); err != nil {
+		return fmt.Errorf("queue %s email: %w", name, err)
+	}
+	h.wakeOutbox()
+	return nil
+}
+
+// wakeOutbox tells the email worker there is something to send without
+// waiting for it
+func (h *Handler) wakeOutbox() {
+	select {
+	case h.outbox <- struct{}{}:
+	default:
+	}
+}
+
+// OutboxWake receives when emails were queued. An email queued in a
+// transaction that had not committed yet when the worker ran is sent on the
+// worker's next run.
+func (h *Handler) OutboxWake() <-chan struct{} {
+	return h.outbox
+}
+
+// ProcessOutbox sends the emails in the outbox that are due. A failed email
+// is retried with exponential backoff until it has been tried
+// outboxMaxAttempts times, then marked failed. Sent emails keep their record
+// but lose their bodies, which may carry one-time links. Rows are claimed
+// with row locks, so several instances may run it at once. It returns how
+// many emails were sent.
+func (h *Handler) ProcessOutbox(ctx context.Context) (int, error) {
+	var sent, failed int
+	var deliveryErr error
+	for {
+		var claimed int
+		err := h.store.Transaction(ctx, func(tx *repository.Store) error {
+			now := h.now()
+			emails, err := tx.OutboxEmails.ClaimDue(ctx, now, outboxBatchSize)
+			if err != nil {
+				return err
+			}
+			claimed = len(emails)
+			for i := range emails {
+				email := &emails[i]
+				email.Attempts++
+				sendErr := h.email.SendEmail(ctx, &utils.Email{
+					To:       email.Recipient,
+					Subject:  email.Subject,
+					Text:     email.TextBody,
+					HTML:     email.HTMLBody,
+					Template: email.Template,
+				})
+				if sendErr != nil {
+					failed++
+					deliveryErr = sendErr
+					email.LastError = sendErr.Error()
+					if email.Attempts >= outboxMaxAttempts {
+						email.Status = models.EmailFailed
+					} else {
+						email.NextAttemptAt = now.Add(outboxRetryDelay << (email.Attempts - 1))
+					}
+				} else {
+					sent++
+					email.Status = models.EmailSent
+					email.SentAt = &now
+					email.LastError = ""
+					email.TextBody = ""
+					email.HTMLBody = ""
+				}
+				if err := tx.OutboxEmails.Update(ctx, email); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return sent, fmt.Errorf("send outbox: %w", err)
+		}
+		// Every claimed email is settled or pushed back, so a short batch
+		// was the last one
+		if claimed < outboxBatchSize {
+			break
+		}
+	}
+	if failed > 0 {
+		return sent, fmt.Errorf("%w: %d failed: %w", ErrNotDelivered, failed, deliveryErr)
+	}
+	return sent, nil
+}
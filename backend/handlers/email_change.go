@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/mailer"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/repository"
+	"github.com/muneerlalji/Luma/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// clearEmailChange resets all email change state on the user
+func clearEmailChange(user *models.User) {
+	user.PendingEmail = ""
+	user.EmailChangeToken = ""
+	user.EmailChangeExpiry = nil
+	user.EmailChangeCancelToken = ""
+	user.EmailChangeCancelExpiry = nil
+	user.PreviousEmail = ""
+}
+
+// RequestEmailChange starts changing the login email. The new address must be
+// verified before it replaces the current one, and the current address is
+// told about the change with a link to cancel it.
+func (h *Handler) RequestEmailChange(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	var req struct {
+		NewEmail        string `json:"newEmail" binding:"required,email"`
+		CurrentPassword string `json:"currentPassword" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, apierror.Bind(err))
+		return
+	}
+	newEmail := strings.TrimSpace(req.NewEmail)
+
+	user, err := h.store.Users.Get(c, userUUID)
+	if err != nil {
+		apierror.Abort(c, errUserNotFound)
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+		apierror.Abort(c, errWrongPassword)
+		return
+	}
+
+	if strings.EqualFold(newEmail, user.Email) {
+		apierror.Abort(c, errEmailUnchanged)
+		return
+	}
+
+	inUse, err := h.store.Users.EmailInUse(c, newEmail, user.ID)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Database error", err))
+		return
+	}
+	if inUse {
+		apierror.Abort(c, errEmailInUse)
+		return
+	}
+
+	verifyToken, err := generateRandomToken(32)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to generate verification token", err))
+		return
+	}
+	cancelToken, err := generateRandomToken(32)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to generate verification token", err))
+		return
+	}
+
+	now := h.now()
+	verifyExpiry := now.Add(h.config.ConfirmationTokenTTL)
+	cancelExpiry := now.Add(h.config.EmailChangeCancelTTL)
+
+	clearEmailChange(user)
+	user.PendingEmail = newEmail
+	user.EmailChangeToken = utils.HashToken(verifyToken)
+	user.EmailChangeExpiry = &verifyExpiry
+	user.EmailChangeCancelToken = utils.HashToken(cancelToken)
+	user.EmailChangeCancelExpiry = &cancelExpiry
+
+	// The new address is sent a link to verify it and the old one a link to
+	// cancel the change
+	err = h.store.Transaction(c, func(tx *repository.Store) error {
+		if err := tx.Users.Update(c, user); err != nil {
+			return err
+		}
+		err := h.queueEmail(c, tx, user, newEmail, mailer.VerifyEmailChange, mailer.LinkData{
+			Name: user.DisplayName,
+			URL:  h.config.FrontendURL + "/confirm-email-change?token=" + verifyToken,
+		})
+		if err != nil {
+			return err
+		}
+		return h.queueEmail(c, tx, user, user.Email, mailer.EmailChangeNotice, mailer.EmailChangeNoticeData{
+			Name:     user.DisplayName,
+			NewEmail: newEmail,
+			URL:      h.config.FrontendURL + "/cancel-email-change?token=" + cancelToken,
+		})
+	})
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to start email change", err))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":      "Check your new email address for a link to confirm the change.",
+		"pendingEmail": newEmail,
+	})
+}
+
+// ConfirmEmailChange swaps the login email once the new address is verified
+func (h *Handler) ConfirmEmailChange(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, errTokenRequired)
+		return
+	}
+
+	digest := utils.HashToken(req.Token)
+	user, err := h.store.Users.GetByToken(c, repository.EmailChangeToken, digest)
+	if err != nil || user.EmailChangeExpiry == nil || !user.EmailChangeExpiry.After(h.now()) {
+		apierror.Abort(c, errInvalidToken)
+		return
+	}
+
+	inUse, err := h.store.Users.EmailInUse(c, user.PendingEmail, user.ID)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Database error", err))
+		return
+	}
+	if inUse {
+		apierror.Abort(c, errEmailInUse)
+		return
+	}
+
+	// Keep the cancel token and the old address so the change can still be
+	// reverted from the notice sent to the old address
+	user.PreviousEmail = user.Email
+	user.Email = user.PendingEmail
+	user.EmailConfirmed = true
+	user.PendingEmail = ""
+	user.EmailChangeToken = ""
+	user.EmailChangeExpiry = nil
+
+	updated, err := h.store.Users.UpdateIfToken(c, user, repository.EmailChangeToken, digest)
+	if errors.Is(err, repository.ErrDuplicate) {
+		apierror.Abort(c, errEmailInUse)
+		return
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to change email", err))
+		return
+	}
+	if !updated {
+		apierror.Abort(c, errInvalidToken)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email changed successfully. Use your new email to log in."})
+}
+
+// CancelEmailChange cancels a pending email change, or reverts a completed
+// one, using the link sent to the old address
+func (h *Handler) CancelEmailChange(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, errTokenRequired)
+		return
+	}
+
+	digest := utils.HashToken(req.Token)
+	user, err := h.store.Users.GetByToken(c, repository.EmailChangeCancelToken, digest)
+	if err != nil || user.EmailChangeCancelExpiry == nil || !user.EmailChangeCancelExpiry.After(h.now()) {
+		apierror.Abort(c, errInvalidToken)
+		return
+	}
+
+	message := "Email change cancelled."
+	if user.PreviousEmail != "" {
+		inUse, err := h.store.Users.EmailInUse(c, user.PreviousEmail, user.ID)
+		if err != nil {
+			apierror.Abort(c, apierror.Internal("Database error", err))
+			return
+		}
+		if inUse {
+			apierror.Abort(c, errPreviousEmailInUse)
+			return
+		}
+		user.Email = user.PreviousEmail
+		message = "Email change reverted. Use your previous email to log in."
+	}
+
+	clearEmailChange(user)
+	updated, err := h.store.Users.UpdateIfToken(c, user, repository.EmailChangeCancelToken, digest)
+	if errors.Is(err, repository.ErrDuplicate) {
+		apierror.Abort(c, errPreviousEmailInUse)
+		return
+	}
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to cancel email change", err))
+		return
+	}
+	if !updated {
+		apierror.Abort(c, errInvalidToken)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
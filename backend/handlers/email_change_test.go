@@ -0,0 +1,217 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/mailer"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/repository"
+	"github.com/muneerlalji/Luma/testutils"
+	"github.com/muneerlalji/Luma/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type EmailChangeTestSuite struct {
+	suite.Suite
+	router    *gin.Engine
+	env       *testutils.TestEnv
+	store     *repository.Store
+	user      models.User
+	emailMock *testutils.EmailMock
+}
+
+func (suite *EmailChangeTestSuite) SetupSuite() {
+	gin.SetMode(gin.TestMode)
+}
+
+func (suite *EmailChangeTestSuite) SetupTest() {
+	suite.env = testutils.NewTestEnv()
+	suite.store = suite.env.Store
+	suite.emailMock = suite.env.Email
+	h := suite.env.Handler
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	suite.user = models.User{
+		Email:          "old@example.com",
+		Password:       string(hashedPassword),
+		DisplayName:    "Test User",
+		EmailConfirmed: true,
+	}
+	suite.store.Users.Create(context.Background(), &suite.user)
+
+	suite.router = gin.Default()
+	suite.router.Use(testutils.OpenAPIValidator(suite.T()), apierror.Middleware())
+
+	auth := suite.router.Group("/auth")
+	{
+		auth.POST("/confirm-email-change", h.ConfirmEmailChange)
+		auth.POST("/cancel-email-change", h.CancelEmailChange)
+	}
+
+	protected := suite.router.Group("/")
+	protected.Use(func(c *gin.Context) {
+		c.Set("user_id", suite.user.ID)
+		c.Next()
+	})
+	{
+		protected.POST("/profile/email", h.RequestEmailChange)
+	}
+}
+
+func (suite *EmailChangeTestSuite) post(path string, data interface{}) *httptest.ResponseRecorder {
+	jsonData, _ := json.Marshal(data)
+	req, _ := http.NewRequest("POST", path, bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+// tokenFromEmail extracts the token query parameter from the link in an email
+func tokenFromEmail(body string) string {
+	index := strings.Index(body, "token=")
+	if index == -1 {
+		return ""
+	}
+	return strings.Fields(body[index+len("token="):])[0]
+}
+
+func (suite *EmailChangeTestSuite) requestChange(newEmail string) {
+	w := suite.post("/profile/email", map[string]string{
+		"newEmail":        newEmail,
+		"currentPassword": "password123",
+	})
+	assert.Equal(suite.T(), http.StatusAccepted, w.Code)
+	_, err := suite.env.SendQueuedEmails()
+	suite.Require().NoError(err)
+}
+
+func (suite *EmailChangeTestSuite) TestRequestEmailChange_SendsVerificationAndNotice() {
+	suite.requestChange("new@example.com")
+
+	verification := suite.emailMock.FindEmailByRecipient("new@example.com")
+	assert.Len(suite.T(), verification, 1)
+	assert.Contains(suite.T(), verification[0].Body, "/confirm-email-change?token=")
+
+	notice := suite.emailMock.FindEmailByRecipient("old@example.com")
+	suite.Require().Len(notice, 1)
+	suite.emailMock.AssertRendered(suite.T(), notice[0], mailer.EmailChangeNotice, "en", mailer.EmailChangeNoticeData{
+		Name:     suite.user.DisplayName,
+		NewEmail: "new@example.com",
+		URL:      "http://localhost:3000/cancel-email-change?token=" + tokenFromEmail(notice[0].Body),
+	})
+
+	// The login email does not change until the new address is verified
+	user, _ := suite.store.Users.Get(context.Background(), suite.user.ID)
+	assert.Equal(suite.T(), "old@example.com", user.Email)
+	assert.Equal(suite.T(), "new@example.com", user.PendingEmail)
+}
+
+func (suite *EmailChangeTestSuite) TestRequestEmailChange_WrongPassword() {
+	w := suite.post("/profile/email", map[string]string{
+		"newEmail":        "new@example.com",
+		"currentPassword": "wrongpassword",
+	})
+
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Equal(suite.T(), 0, suite.emailMock.GetEmailCount())
+}
+
+func (suite *EmailChangeTestSuite) TestRequestEmailChange_EmailTaken() {
+	suite.store.Users.Create(context.Background(), &models.User{Email: "taken@example.com", Password: "x", EmailConfirmed: true})
+
+	w := suite.post("/profile/email", map[string]string{
+		"newEmail":        "taken@example.com",
+		"currentPassword": "password123",
+	})
+
+	assert.Equal(suite.T(), http.StatusConflict, w.Code)
+}
+
+func (suite *EmailChangeTestSuite) TestConfirmEmailChange_SwapsEmail() {
+	suite.requestChange("new@example.com")
+	token := tokenFromEmail(suite.emailMock.FindEmailByRecipient("new@example.com")[0].Body)
+
+	w := suite.post("/auth/confirm-email-change", map[string]string{"token": token})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	user, _ := suite.store.Users.Get(context.Background(), suite.user.ID)
+	assert.Equal(suite.T(), "new@example.com", user.Email)
+	assert.Empty(suite.T(), user.PendingEmail)
+
+	// The verification link is single-use
+	w = suite.post("/auth/confirm-email-change", map[string]string{"token": token})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+func (suite *EmailChangeTestSuite) TestConfirmEmailChange_TakenInTheMeantime() {
+	suite.requestChange("new@example.com")
+	token := tokenFromEmail(suite.emailMock.FindEmailByRecipient("new@example.com")[0].Body)
+
+	suite.store.Users.Create(context.Background(), &models.User{Email: "new@example.com", Password: "x", EmailConfirmed: true})
+
+	w := suite.post("/auth/confirm-email-change", map[string]string{"token": token})
+	assert.Equal(suite.T(), http.StatusConflict, w.Code)
+
+	user, _ := suite.store.Users.Get(context.Background(), suite.user.ID)
+	assert.Equal(suite.T(), "old@example.com", user.Email)
+}
+
+func (suite *EmailChangeTestSuite) TestConfirmEmailChange_Expired() {
+	suite.requestChange("new@example.com")
+	token := tokenFromEmail(suite.emailMock.FindEmailByRecipient("new@example.com")[0].Body)
+
+	user, _ := suite.store.Users.Get(context.Background(), suite.user.ID)
+	expired := time.Now().Add(-time.Minute)
+	user.EmailChangeExpiry = &expired
+	suite.store.Users.Update(context.Background(), user)
+
+	w := suite.post("/auth/confirm-email-change", map[string]string{"token": token})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+func (suite *EmailChangeTestSuite) TestCancelEmailChange_BeforeConfirmation() {
+	suite.requestChange("new@example.com")
+	verifyToken := tokenFromEmail(suite.emailMock.FindEmailByRecipient("new@example.com")[0].Body)
+	cancelToken := tokenFromEmail(suite.emailMock.FindEmailByRecipient("old@example.com")[0].Body)
+
+	w := suite.post("/auth/cancel-email-change", map[string]string{"token": cancelToken})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	// The verification link no longer works once cancelled
+	w = suite.post("/auth/confirm-email-change", map[string]string{"token": verifyToken})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	user, _ := suite.store.Users.Get(context.Background(), suite.user.ID)
+	assert.Equal(suite.T(), "old@example.com", user.Email)
+	assert.Empty(suite.T(), user.PendingEmail)
+}
+
+func (suite *EmailChangeTestSuite) TestCancelEmailChange_RevertsAfterConfirmation() {
+	suite.requestChange("new@example.com")
+	verifyToken := tokenFromEmail(suite.emailMock.FindEmailByRecipient("new@example.com")[0].Body)
+	cancelToken := tokenFromEmail(suite.emailMock.FindEmailByRecipient("old@example.com")[0].Body)
+
+	assert.Equal(suite.T(), http.StatusOK, suite.post("/auth/confirm-email-change", map[string]string{"token": verifyToken}).Code)
+	assert.Equal(suite.T(), http.StatusOK, suite.post("/auth/cancel-email-change", map[string]string{"token": cancelToken}).Code)
+
+	user, _ := suite.store.Users.Get(context.Background(), suite.user.ID)
+	assert.Equal(suite.T(), "old@example.com", user.Email)
+	assert.NotEqual(suite.T(), utils.HashToken(cancelToken), user.EmailChangeCancelToken)
+}
+
+func TestEmailChangeTestSuite(t *testing.T) {
+	suite.Run(t, new(EmailChangeTestSuite))
+}
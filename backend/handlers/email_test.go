@@ -0,0 +1,241 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/handlers"
+	"github.com/muneerlalji/Luma/mailer"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type EmailTestSuite struct {
+	suite.Suite
+	env    *testutils.TestEnv
+	router *gin.Engine
+	admin  models.User
+	user   models.User
+	// caller is the user requests are made as
+	caller *models.User
+	now    time.Time
+}
+
+func (suite *EmailTestSuite) SetupSuite() {
+	gin.SetMode(gin.TestMode)
+}
+
+func (suite *EmailTestSuite) SetupTest() {
+	suite.now = time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC)
+	suite.env = testutils.NewTestEnv(func(deps *handlers.Deps) {
+		deps.Clock = func() time.Time { return suite.now }
+		deps.Config.AdminEmails = []string{"admin@example.com"}
+	})
+	h := suite.env.Handler
+
+	suite.admin = models.User{Email: "Admin@example.com", Password: "x", DisplayName: "Admin", EmailConfirmed: true}
+	suite.env.Store.Users.Create(context.Background(), &suite.admin)
+	suite.user = models.User{Email: "margarita@example.com", Password: "x", DisplayName: "Margarita", EmailConfirmed: true, Locale: "es"}
+	suite.env.Store.Users.Create(context.Background(), &suite.user)
+	suite.caller = &suite.admin
+
+	suite.router = gin.New()
+	suite.router.Use(testutils.OpenAPIValidator(suite.T()), apierror.Middleware())
+	suite.router.POST("/auth/forgot-password", h.ForgotPassword)
+	admin := suite.router.Group("/admin")
+	admin.Use(func(c *gin.Context) {
+		c.Set("user_id", suite.caller.ID)
+		c.Next()
+	}, h.RequireAdmin())
+	admin.GET("/emails", h.GetOutboxEmails)
+	admin.POST("/emails/:id/retry", h.RetryOutboxEmail)
+	admin.GET("/email-templates", h.GetEmailTemplates)
+	admin.GET("/email-templates/:name/preview", h.PreviewEmailTemplate)
+}
+
+func (suite *EmailTestSuite) request(method, path string, body any) *httptest.ResponseRecorder {
+	var reader bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&reader).Encode(body)
+	}
+	req, _ := http.NewRequest(method, path, &reader)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+// queueReset queues a password reset email for the user
+func (suite *EmailTestSuite) queueReset() {
+	w := suite.request("POST", "/auth/forgot-password", map[string]string{"email": suite.user.Email})
+	suite.Require().Equal(http.StatusOK, w.Code)
+}
+
+func (suite *EmailTestSuite) outbox(query string) []handlers.OutboxEmailResponse {
+	w := suite.request("GET", "/admin/emails"+query, nil)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var emails []handlers.OutboxEmailResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &emails))
+	return emails
+}
+
+func (suite *EmailTestSuite) TestQueuedEmailIsSentInUserLocale() {
+	suite.queueReset()
+	assert.Zero(suite.T(), suite.env.Email.GetEmailCount(), "emails wait for the worker")
+
+	sent, err := suite.env.SendQueuedEmails()
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 1, sent)
+
+	emails := suite.env.Email.FindEmailByTemplate(mailer.ResetPassword)
+	suite.Require().Len(emails, 1)
+	assert.Equal(suite.T(), "Restablece tu contraseña", emails[0].Subject)
+	assert.Contains(suite.T(), emails[0].HTML, `lang="es"`)
+
+	// The record stays, without the one-time link
+	stored, err := suite.env.Store.OutboxEmails.List(context.Background(), models.EmailSent, 10)
+	suite.Require().NoError(err)
+	suite.Require().Len(stored, 1)
+	assert.Equal(suite.T(), "es", stored[0].Locale)
+	assert.Empty(suite.T(), stored[0].TextBody)
+	assert.Empty(suite.T(), stored[0].HTMLBody)
+	suite.Require().NotNil(stored[0].SentAt)
+	assert.Equal(suite.T(), suite.now, stored[0].SentAt.UTC())
+
+	sent, err = suite.env.SendQueuedEmails()
+	suite.Require().NoError(err)
+	assert.Zero(suite.T(), sent, "sent emails are not sent again")
+}
+
+func (suite *EmailTestSuite) TestFailedEmailBacksOffThenFails() {
+	suite.queueReset()
+	suite.env.Email.SetSendError(errors.New("smtp down"))
+
+	_, err := suite.env.SendQueuedEmails()
+	suite.Require().Error(err)
+
+	// Not due again until a minute later, then two
+	suite.now = suite.now.Add(59 * time.Second)
+	_, err = suite.env.SendQueuedEmails()
+	suite.Require().NoError(err)
+	suite.now = suite.now.Add(time.Second)
+	_, err = suite.env.SendQueuedEmails()
+	suite.Require().Error(err)
+
+	emails := suite.outbox("?status=pending")
+	suite.Require().Len(emails, 1)
+	assert.Equal(suite.T(), 2, emails[0].Attempts)
+	assert.Equal(suite.T(), "smtp down", emails[0].LastError)
+	suite.Require().NotNil(emails[0].NextAttemptAt)
+	assert.Equal(suite.T(), suite.now.Add(2*time.Minute), emails[0].NextAttemptAt.UTC())
+
+	for range 6 {
+		suite.now = suite.now.Add(24 * time.Hour)
+		_, err = suite.env.SendQueuedEmails()
+		suite.Require().Error(err)
+	}
+	assert.Empty(suite.T(), suite.outbox("?status=pending"))
+	failed := suite.outbox("?status=failed")
+	suite.Require().Len(failed, 1)
+	assert.Equal(suite.T(), 8, failed[0].Attempts)
+	assert.Nil(suite.T(), failed[0].NextAttemptAt)
+
+	// Failed emails wait for an administrator
+	suite.now = suite.now.Add(24 * time.Hour)
+	_, err = suite.env.SendQueuedEmails()
+	suite.Require().NoError(err)
+
+	suite.env.Email.SetSendError(nil)
+	w := suite.request("POST", "/admin/emails/"+failed[0].ID.String()+"/retry", nil)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var retried handlers.OutboxEmailResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &retried))
+	assert.Equal(suite.T(), models.EmailPending, retried.Status)
+	assert.Zero(suite.T(), retried.Attempts)
+
+	sent, err := suite.env.SendQueuedEmails()
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 1, sent)
+	assert.Len(suite.T(), suite.env.Email.FindEmailByTemplate(mailer.ResetPassword), 1)
+
+	w = suite.request("POST", "/admin/emails/"+failed[0].ID.String()+"/retry", nil)
+	assert.Equal(suite.T(), http.StatusConflict, w.Code)
+}
+
+func (suite *EmailTestSuite) TestListEmails() {
+	suite.queueReset()
+	_, err := suite.env.SendQueuedEmails()
+	suite.Require().NoError(err)
+	suite.now = suite.now.Add(time.Minute)
+	suite.queueReset()
+
+	emails := suite.outbox("")
+	suite.Require().Len(emails, 2)
+	assert.Equal(suite.T(), models.EmailPending, emails[0].Status, "newest first")
+	assert.Equal(suite.T(), models.EmailSent, emails[1].Status)
+	assert.Equal(suite.T(), suite.user.Email, emails[0].Recipient)
+	assert.Equal(suite.T(), mailer.ResetPassword, emails[0].Template)
+
+	assert.Len(suite.T(), suite.outbox("?status=sent"), 1)
+	assert.Len(suite.T(), suite.outbox("?limit=1"), 1)
+	assert.Equal(suite.T(), http.StatusBadRequest, suite.request("GET", "/admin/emails?status=bounced", nil).Code)
+	assert.Equal(suite.T(), http.StatusBadRequest, suite.request("GET", "/admin/emails?limit=0", nil).Code)
+}
+
+func (suite *EmailTestSuite) TestRetryUnknownEmail() {
+	assert.Equal(suite.T(), http.StatusBadRequest, suite.request("POST", "/admin/emails/nope/retry", nil).Code)
+	assert.Equal(suite.T(), http.StatusNotFound, suite.request("POST", "/admin/emails/"+suite.user.ID.String()+"/retry", nil).Code)
+}
+
+func (suite *EmailTestSuite) TestPreviewTemplates() {
+	w := suite.request("GET", "/admin/email-templates", nil)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var templates handlers.EmailTemplatesResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &templates))
+	assert.Contains(suite.T(), templates.Templates, mailer.CaregiverDigest)
+	assert.Equal(suite.T(), []string{"en", "es"}, templates.Locales)
+
+	for _, name := range templates.Templates {
+		for _, locale := range templates.Locales {
+			w := suite.request("GET", "/admin/email-templates/"+name+"/preview?locale="+locale, nil)
+			suite.Require().Equal(http.StatusOK, w.Code, name+" "+locale)
+			var preview handlers.EmailPreviewResponse
+			suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &preview))
+			assert.NotEmpty(suite.T(), preview.Subject)
+			assert.NotEmpty(suite.T(), preview.Text)
+			assert.True(suite.T(), strings.Contains(preview.HTML, `lang="`+locale+`"`), name+" "+locale)
+		}
+	}
+
+	w = suite.request("GET", "/admin/email-templates/"+mailer.CheckInMissed+"/preview", nil)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var preview handlers.EmailPreviewResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &preview))
+	assert.Equal(suite.T(), "en", preview.Locale)
+
+	assert.Equal(suite.T(), http.StatusNotFound, suite.request("GET", "/admin/email-templates/welcome/preview", nil).Code)
+	assert.Equal(suite.T(), http.StatusBadRequest, suite.request("GET", "/admin/email-templates/reminder/preview?locale=fr", nil).Code)
+}
+
+func (suite *EmailTestSuite) TestAdminOnly() {
+	suite.caller = &suite.user
+	for _, path := range []string{"/admin/emails", "/admin/email-templates", "/admin/email-templates/reminder/preview"} {
+		w := suite.request("GET", path, nil)
+		assert.Equal(suite.T(), http.StatusForbidden, w.Code, path)
+	}
+}
+
+func TestEmailTestSuite(t *testing.T) {
+	suite.Run(t, new(EmailTestSuite))
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/muneerlalji/Luma/apierror"
+)
+
+var (
+	fieldRequired  = apierror.FieldError{Code: "required", Message: "is required"}
+	fieldNotFuture = apierror.FieldError{Code: "past", Message: "may not be in the future"}
+
+	fieldNotificationType    = apierror.FieldError{Code: "oneof", Message: "must be reminder, routine_due, check_in_missed or reminiscence"}
+	fieldNotificationChannel = apierror.FieldError{Code: "channel", Message: "is not a channel this type of notification is sent on"}
+)
+
+// Errors returned by the handlers. Codes are part of the API: the frontend
+// localizes messages by code, so change a message freely but never a code.
+var (
+	// Authentication
+	errAuthHeaderRequired = apierror.New(http.StatusUnauthorized, "authorization_required", "Authorization header required")
+	errInvalidAuthHeader  = apierror.New(http.StatusUnauthorized, "invalid_authorization_header", "Invalid authorization header format")
+	errInvalidAuthToken   = apierror.New(http.StatusUnauthorized, "invalid_auth_token", "Invalid token")
+	errInvalidUserID      = apierror.New(http.StatusInternalServerError, apierror.CodeInternal, "Invalid user ID format")
+	errInvalidCredentials = apierror.New(http.StatusUnauthorized, "invalid_credentials", "Invalid email or password")
+	errEmailNotConfirmed  = apierror.New(http.StatusUnauthorized, "email_not_confirmed", "Please confirm your email before logging in.")
+	errWeakPassword       = apierror.New(http.StatusBadRequest, "weak_password", "Password does not meet the requirements")
+	errWrongPassword      = apierror.New(http.StatusBadRequest, "current_password_incorrect", "Current password is incorrect")
+	errTokenRequired      = apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Token is required").WithField("token", fieldRequired)
+	errInvalidToken       = apierror.New(http.StatusBadRequest, "invalid_token", "Invalid or expired token")
+	errTokenExpired       = apierror.New(http.StatusBadRequest, "token_expired", "Token expired")
+
+	// Users and email changes
+	errUserNotFound          = apierror.New(http.StatusNotFound, "user_not_found", "User not found")
+	errEmailRegistered       = apierror.New(http.StatusConflict, "email_in_use", "User with this email already exists")
+	errEmailInUse            = apierror.New(http.StatusConflict, "email_in_use", "Email is already in use")
+	errPreviousEmailInUse    = apierror.New(http.StatusConflict, "previous_email_in_use", "Your previous email is now used by another account")
+	errEmailUnchanged        = apierror.New(http.StatusBadRequest, "email_unchanged", "New email must be different from your current email")
+	errInvalidCaregiverEmail = apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Some fields are invalid").WithField("caregiverEmail", apierror.FieldError{Code: "email", Message: "must be a valid email address"})
+	errInvalidLocale         = apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Some fields are invalid").WithField("locale", apierror.FieldError{Code: "locale", Message: "must be a supported language such as en"})
+
+	// Care circle
+	errCareCircleMemberExists    = apierror.New(http.StatusConflict, "care_circle_member_exists", "This email address is already in the care circle")
+	errCareCircleMemberNotFound  = apierror.New(http.StatusNotFound, "care_circle_member_not_found", "Care circle member not found")
+	errInvalidCareCircleMemberID = apierror.New(http.StatusBadRequest, "invalid_care_circle_member_id", "Invalid care circle member ID format")
+
+	// Identity providers
+	errUnknownProvider         = apierror.New(http.StatusNotFound, "identity_provider_not_found", "Unknown identity provider")
+	errProviderUnavailable     = apierror.New(http.StatusBadGateway, "identity_provider_unavailable", "Identity provider is unavailable")
+	errProviderSignIn          = apierror.New(http.StatusUnauthorized, "identity_provider_sign_in_failed", "Sign-in with identity provider failed")
+	errProviderEmailUnverified = apierror.New(http.StatusForbidden, "identity_email_unverified", "Your identity provider did not share a verified email address")
+	errInvalidSignInState      = apierror.New(http.StatusBadRequest, "invalid_sign_in_state", "Invalid or expired sign-in attempt")
+	errIdentityLinked          = apierror.New(http.StatusConflict, "identity_already_linked", "This account is already linked to another user")
+	errIdentityNotFound        = apierror.New(http.StatusNotFound, "identity_not_found", "Linked account not found")
+	errInvalidIdentityID       = apierror.New(http.StatusBadRequest, "invalid_identity_id", "Invalid identity ID format")
+	errLastSignInMethod        = apierror.New(http.StatusBadRequest, "last_sign_in_method", "Set a password before removing your only sign-in method")
+
+	// Memories, people and photos
+	errPersonNotFound  = apierror.New(http.StatusBadRequest, "person_not_found", "One or more people not found or not owned by user")
+	errPersonMissing   = apierror.New(http.StatusNotFound, "person_not_found", "Person not found or not owned by user")
+	errInvalidPersonID = apierror.New(http.StatusBadRequest, "invalid_person_id", "Invalid person ID format")
+	errPhotoNotFound   = apierror.New(http.StatusNotFound, "photo_not_found", "Photo not found or not owned by user")
+	errPhotoReference  = apierror.New(http.StatusBadRequest, "photo_not_found", "Photo not found or not owned by user")
+	errPhotoIDRequired = apierror.New(http.StatusBadRequest, "invalid_photo_id", "Photo ID is required")
+	errInvalidPhotoID  = apierror.New(http.StatusBadRequest, "invalid_photo_id", "Invalid photo ID format")
+	errFileRequired    = apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "File is required").WithField("file", fieldRequired)
+	errInvalidLimit    = apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Invalid limit parameter").WithField("limit", apierror.FieldError{Code: "min", Message: "must be a positive number"})
+	errMemoryReference = apierror.New(http.StatusBadRequest, "memory_not_found", "Memory not found or not owned by user")
+	errMemoryNotFound  = apierror.New(http.StatusNotFound, "memory_not_found", "Memory not found or not owned by user")
+	errInvalidMemoryID = apierror.New(http.StatusBadRequest, "invalid_memory_id", "Invalid memory ID format")
+
+	// Reminiscence prompts
+	errNoMemoryPrompt  = apierror.New(http.StatusNotFound, "no_memory_prompt", "No memory is due to be resurfaced today")
+	errPromptNotFound  = apierror.New(http.StatusNotFound, "memory_prompt_not_found", "Memory prompt not found")
+	errInvalidPromptID = apierror.New(http.StatusBadRequest, "invalid_memory_prompt_id", "Invalid memory prompt ID format")
+	errPromptAnswered  = apierror.New(http.StatusConflict, "memory_prompt_answered", "Memory prompt was already answered")
+
+	// Dates
+	errDateInFuture = apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Dates may not be in the future")
+
+	// Interactions and insights
+	errInteractionInFuture = apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Interaction time may not be in the future").WithField("occurredAt", fieldNotFuture)
+	errInvalidDays         = apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Invalid days parameter").WithField("days", apierror.FieldError{Code: "range", Message: "must be a number from 1 to 365"})
+
+	// Reminders
+	errReminderNotFound  = apierror.New(http.StatusNotFound, "reminder_not_found", "Reminder not found")
+	errInvalidReminderID = apierror.New(http.StatusBadRequest, "invalid_reminder_id", "Invalid reminder ID format")
+	errReminderResolved  = apierror.New(http.StatusConflict, "reminder_resolved", "Reminder was already completed or dismissed")
+	errSnoozeInPast      = apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Snooze time must be in the future").WithField("until", apierror.FieldError{Code: "future", Message: "must be in the future"})
+
+	// Routines and check-ins
+	errRoutineNotFound   = apierror.New(http.StatusNotFound, "routine_not_found", "Routine not found")
+	errInvalidRoutineID  = apierror.New(http.StatusBadRequest, "invalid_routine_id", "Invalid routine ID format")
+	errInvalidRecurrence = apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Some fields are invalid").WithField("recurrence", apierror.FieldError{Code: "rrule", Message: "must be a supported RRULE such as FREQ=WEEKLY;BYDAY=MO,TH"})
+	errNotScheduled      = apierror.New(http.StatusBadRequest, "not_scheduled", "The routine is not scheduled at that time").WithField("scheduledAt", apierror.FieldError{Code: "occurrence", Message: "must be a time the routine is scheduled"})
+	errCheckInTooEarly   = apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Check-ins may be at most 2 hours early").WithField("scheduledAt", apierror.FieldError{Code: "too_early", Message: "may be at most 2 hours from now"})
+	errCheckInConflict   = apierror.New(http.StatusConflict, "check_in_conflict", "The check-in changed at the same time, please try again")
+	errInvalidDate       = apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Invalid date parameter").WithField("date", apierror.FieldError{Code: "datetime", Message: "must be a date written as YYYY-MM-DD"})
+	errInvalidTimezone   = apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Some fields are invalid").WithField("timezone", apierror.FieldError{Code: "timezone", Message: "must be an IANA timezone such as Europe/London"})
+
+	// Calendar
+	errCalendarFeedNotFound = apierror.New(http.StatusNotFound, "calendar_feed_not_found", "Calendar feed not found")
+	errCalendarTooLarge     = apierror.New(http.StatusRequestEntityTooLarge, "calendar_too_large", "Calendar files may be at most 1 MB")
+	errInvalidCalendar      = apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "The file is not a valid iCalendar file")
+
+	// Notifications and push
+	errNotificationNotFound       = apierror.New(http.StatusNotFound, "notification_not_found", "Notification not found")
+	errInvalidNotificationID      = apierror.New(http.StatusBadRequest, "invalid_notification_id", "Invalid notification ID format")
+	errInvalidNotificationLimit   = apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Invalid limit parameter").WithField("limit", apierror.FieldError{Code: "range", Message: "must be a number from 1 to 100"})
+	errInvalidNotificationType    = apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Unknown notification type")
+	errInvalidNotificationChannel = apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Unsupported notification channel")
+	errPushNotConfigured          = apierror.New(http.StatusInternalServerError, "push_not_configured", "Push notifications are not configured")
+	errInvalidPushEndpoint        = apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Some fields are invalid").WithField("endpoint", apierror.FieldError{Code: "https_url", Message: "must be an https URL"})
+	errInvalidPushKeys            = apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Some fields are invalid").WithField("keys", apierror.FieldError{Code: "push_keys", Message: "must be the keys of a push subscription"})
+	errPushEndpointRequired       = apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Endpoint is required").WithField("endpoint", fieldRequired)
+	errPushSubscriptionNotFound   = apierror.New(http.StatusNotFound, "push_subscription_not_found", "Push subscription not found")
+
+	// Administration
+	errAdminOnly               = apierror.New(http.StatusForbidden, "admin_only", "Only administrators may do this")
+	errOutboxEmailNotFound     = apierror.New(http.StatusNotFound, "email_not_found", "Email not found")
+	errInvalidOutboxEmailID    = apierror.New(http.StatusBadRequest, "invalid_email_id", "Invalid email ID format")
+	errOutboxEmailNotFailed    = apierror.New(http.StatusConflict, "email_not_failed", "Only failed emails can be retried")
+	errInvalidOutboxEmailLimit = apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Invalid limit parameter").WithField("limit", apierror.FieldError{Code: "range", Message: "must be a number from 1 to 200"})
+	errInvalidEmailStatus      = apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Invalid status parameter").WithField("status", apierror.FieldError{Code: "oneof", Message: "must be pending, sent or failed"})
+	errEmailTemplateNotFound   = apierror.New(http.StatusNotFound, "email_template_not_found", "Email template not found")
+	errUnsupportedLocale       = apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Unsupported locale").WithField("locale", apierror.FieldError{Code: "locale", Message: "must be a supported language such as en"})
+	errJobNotFound             = apierror.New(http.StatusNotFound, "job_not_found", "Job not found")
+	errInvalidJobID            = apierror.New(http.StatusBadRequest, "invalid_job_id", "Invalid job ID format")
+	errJobNotDead              = apierror.New(http.StatusConflict, "job_not_dead", "Only dead jobs can be retried")
+	errInvalidJobLimit         = apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Invalid limit parameter").WithField("limit", apierror.FieldError{Code: "range", Message: "must be a number from 1 to 200"})
+	errInvalidJobStatus        = apierror.New(http.StatusBadRequest, apierror.CodeValidationFailed, "Invalid status parameter").WithField("status", apierror.FieldError{Code: "oneof", Message: "must be pending, running, succeeded or dead"})
+
+	// Chat
+	errChatNotConfigured = apierror.New(http.StatusInternalServerError, "chat_not_configured", "Streaming not configured")
+)
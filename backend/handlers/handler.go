@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/muneerlalji/Luma/llm"
+	"github.com/muneerlalji/Luma/oidc"
+	"github.com/muneerlalji/Luma/repository"
+	"github.com/muneerlalji/Luma/storage"
+	"github.com/muneerlalji/Luma/utils"
+	"github.com/muneerlalji/Luma/webpush"
+)
+
+// Config holds the settings handlers read at request time
+type Config struct {
+	// JWTSecret signs session tokens
+	JWTSecret string
+	// FrontendURL is the base of links sent in emails
+	FrontendURL string
+	// APIBaseURL is the base of photo URLs returned to clients
+	APIBaseURL string
+
+	ConfirmationTokenTTL time.Duration
+	ResetTokenTTL        time.Duration
+	EmailChangeCancelTTL time.Duration
+
+	// LockoutThreshold consecutive failed logins lock an account for
+	// LockoutDuration, doubling with each further lockout
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+
+	PasswordPolicy utils.PasswordPolicy
+
+	// NotificationPollInterval is how often an open notification stream
+	// looks for new notifications
+	NotificationPollInterval time.Duration
+
+	// AdminEmails are the lowercase addresses of the users who may use the
+	// admin endpoints
+	AdminEmails []string
+}
+
+// DefaultConfig returns the settings used when nothing is overridden
+func DefaultConfig() Config {
+	return Config{
+		ConfirmationTokenTTL: 24 * time.Hour,
+		ResetTokenTTL:        time.Hour,
+		EmailChangeCancelTTL: 7 * 24 * time.Hour,
+		LockoutThreshold:     5,
+		LockoutDuration:      15 * time.Minute,
+		PasswordPolicy:       utils.DefaultPasswordPolicy(),
+
+		NotificationPollInterval: 5 * time.Second,
+	}
+}
+
+// Deps are the services a Handler is built from
+type Deps struct {
+	Store   *repository.Store
+	Email   utils.EmailService
+	LLM     llm.Client
+	Storage storage.Storage
+	// Push defaults to a sender without VAPID keys, which pushes nothing
+	Push webpush.Sender
+	// OIDC defaults to an empty registry
+	OIDC   *oidc.Registry
+	Config Config
+	// Clock defaults to time.Now
+	Clock func() time.Time
+}
+
+// Handler serves the API using the services it was built with
+type Handler struct {
+	store   *repository.Store
+	email   utils.EmailService
+	llm     llm.Client
+	storage storage.Storage
+	push    webpush.Sender
+	oidc    *oidc.Registry
+	config  Config
+	now     func() time.Time
+
+	// streams is closed to end open notification streams
+	streams      chan struct{}
+	closeStreams sync.Once
+	// outbox wakes the email worker when emails are queued
+	outbox chan struct{}
+}
+
+// New creates a Handler from its dependencies
+func New(deps Deps) *Handler {
+	h := &Handler{
+		store:   deps.Store,
+		email:   deps.Email,
+		llm:     deps.LLM,
+		storage: deps.Storage,
+		push:    deps.Push,
+		oidc:    deps.OIDC,
+		config:  deps.Config,
+		now:     deps.Clock,
+		streams: make(chan struct{}),
+		outbox:  make(chan struct{}, 1),
+	}
+	if h.push == nil {
+		h.push, _ = webpush.New(webpush.Config{})
+	}
+	if h.oidc == nil {
+		h.oidc = oidc.NewRegistry(nil)
+	}
+	if h.now == nil {
+		h.now = time.Now
+	}
+	return h
+}
@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/repository"
+)
+
+// defaultInsightDays is the insights window when the request names none
+const defaultInsightDays = 30
+
+// Trends compare the interactions of the insights window with the window
+// before it
+const (
+	TrendUp     = "up"
+	TrendDown   = "down"
+	TrendSteady = "steady"
+)
+
+// CreateInteractionRequest logs a call, visit or message with a person
+type CreateInteractionRequest struct {
+	Type string `json:"type" binding:"required,oneof=call visit message"`
+	// OccurredAt defaults to now and may not be in the future
+	OccurredAt *time.Time `json:"occurredAt,omitempty"`
+	Notes      string     `json:"notes"`
+	MemoryID   *uuid.UUID `json:"memoryId,omitempty"`
+}
+
+type InteractionResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	PersonID   uuid.UUID  `json:"personId"`
+	Type       string     `json:"type"`
+	OccurredAt time.Time  `json:"occurredAt"`
+	Notes      string     `json:"notes"`
+	MemoryID   *uuid.UUID `json:"memoryId,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// PersonInsight summarizes how often the user is in touch with one person
+type PersonInsight struct {
+	PersonID         uuid.UUID  `json:"personId"`
+	PersonName       string     `json:"personName"`
+	LastContactAt    *time.Time `json:"lastContactAt,omitempty"`
+	DaysSinceContact *int       `json:"daysSinceContact,omitempty"`
+	InteractionCount int        `json:"interactionCount"`
+	// AverageGapDays is the mean time between logged interactions
+	AverageGapDays *float64 `json:"averageGapDays,omitempty"`
+	RecentCount    int      `json:"recentCount"`
+	PreviousCount  int      `json:"previousCount"`
+	Trend          string   `json:"trend"`
+}
+
+// InsightsResponse covers the last Days days. NotContacted lists the people
+// the user has not been in touch with in that time, longest first.
+type InsightsResponse struct {
+	Days          int             `json:"days"`
+	People        []PersonInsight `json:"people"`
+	NotContacted  []uuid.UUID     `json:"notContacted"`
+	RecentCount   int             `json:"recentCount"`
+	PreviousCount int             `json:"previousCount"`
+	Trend         string          `json:"trend"`
+}
+
+func newInteractionResponse(interaction *models.Interaction) InteractionResponse {
+	return InteractionResponse{
+		ID:         interaction.ID,
+		PersonID:   interaction.PersonID,
+		Type:       interaction.Type,
+		OccurredAt: interaction.OccurredAt,
+		Notes:      interaction.Notes,
+		MemoryID:   interaction.MemoryID,
+		CreatedAt:  interaction.CreatedAt,
+	}
+}
+
+func trend(recent, previous int) string {
+	switch {
+	case recent > previous:
+		return TrendUp
+	case recent < previous:
+		return TrendDown
+	default:
+		return TrendSteady
+	}
+}
+
+// daysBetween counts the whole days from then until now
+func daysBetween(then, now time.Time) int {
+	return int(now.Sub(then) / (24 * time.Hour))
+}
+
+// queryDays reads the days query parameter, from 1 to 365, or aborts the
+// request when it is invalid
+func queryDays(c *gin.Context, fallback int) (int, bool) {
+	value := c.Query("days")
+	if value == "" {
+		return fallback, true
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 1 || days > 365 {
+		apierror.Abort(c, errInvalidDays)
+		return 0, false
+	}
+	return days, true
+}
+
+// CreateInteraction logs contact with a person. The newest contact counts as
+// reaching out: it resolves an open reminder and restarts the cadence.
+func (h *Handler) CreateInteraction(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	personID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Abort(c, errInvalidPersonID)
+		return
+	}
+
+	var req CreateInteractionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, apierror.Bind(err))
+		return
+	}
+
+	now := h.now()
+	occurredAt := now
+	if req.OccurredAt != nil {
+		if req.OccurredAt.After(now) {
+			apierror.Abort(c, errInteractionInFuture)
+			return
+		}
+		occurredAt = *req.OccurredAt
+	}
+
+	interaction := models.Interaction{
+		UserID:     userUUID,
+		PersonID:   personID,
+		Type:       req.Type,
+		OccurredAt: occurredAt,
+		Notes:      req.Notes,
+		MemoryID:   req.MemoryID,
+	}
+	err = h.store.Transaction(c, func(tx *repository.Store) error {
+		person, err := tx.People.GetForUser(c, personID, userUUID)
+		if err != nil {
+			return err
+		}
+		if req.MemoryID != nil {
+			if _, err := tx.Memories.GetForUser(c, *req.MemoryID, userUUID); err != nil {
+				if errors.Is(err, repository.ErrNotFound) {
+					return errMemoryReference
+				}
+				return err
+			}
+		}
+		if err := tx.Interactions.Create(c, &interaction); err != nil {
+			return err
+		}
+
+		// Logging an older contact leaves the schedule alone
+		if person.LastContactedAt != nil && !occurredAt.After(*person.LastContactedAt) {
+			return nil
+		}
+		person.LastContactedAt = &occurredAt
+		scheduleNextReminder(person, now)
+		if err := tx.People.Update(c, person); err != nil {
+			return err
+		}
+		return tx.Reminders.ResolveOpen(c, person.ID, models.ReminderCompleted, now)
+	})
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		apierror.Abort(c, errPersonMissing)
+	case errors.Is(err, errMemoryReference):
+		apierror.Abort(c, errMemoryReference)
+	case err != nil:
+		apierror.Abort(c, apierror.Internal("Failed to log interaction", err))
+	default:
+		c.JSON(http.StatusCreated, newInteractionResponse(&interaction))
+	}
+}
+
+// GetInteractions returns the interactions logged with a person, newest first
+func (h *Handler) GetInteractions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	personID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Abort(c, errInvalidPersonID)
+		return
+	}
+
+	if _, err := h.store.People.GetForUser(c, personID, userUUID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Abort(c, errPersonMissing)
+			return
+		}
+		apierror.Abort(c, apierror.Internal("Failed to get person", err))
+		return
+	}
+
+	interactions, err := h.store.Interactions.ListByPerson(c, userUUID, personID)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to get interactions", err))
+		return
+	}
+
+	responses := make([]InteractionResponse, 0, len(interactions))
+	for i := range interactions {
+		responses = append(responses, newInteractionResponse(&interactions[i]))
+	}
+	c.JSON(http.StatusOK, responses)
+}
+
+// GetInsights summarizes how often the user is in touch with each of their
+// people over the last days days, 30 unless the query says otherwise
+func (h *Handler) GetInsights(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	days, ok := queryDays(c, defaultInsightDays)
+	if !ok {
+		return
+	}
+
+	people, err := h.store.People.ListByUser(c, userUUID)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to get people", err))
+		return
+	}
+	interactions, err := h.store.Interactions.ListByUser(c, userUUID)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to get interactions", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, buildInsights(people, interactions, days, h.now()))
+}
+
+// buildInsights summarizes interactions, which are oldest first, per person
+func buildInsights(people []models.Person, interactions []models.Interaction, days int, now time.Time) InsightsResponse {
+	recentFrom := now.AddDate(0, 0, -days)
+	previousFrom := recentFrom.AddDate(0, 0, -days)
+
+	byPerson := make(map[uuid.UUID][]models.Interaction)
+	for _, interaction := range interactions {
+		byPerson[interaction.PersonID] = append(byPerson[interaction.PersonID], interaction)
+	}
+
+	response := InsightsResponse{Days: days, People: []PersonInsight{}, NotContacted: []uuid.UUID{}}
+	for i := range people {
+		person := &people[i]
+		logged := byPerson[person.ID]
+		insight := PersonInsight{
+			PersonID:         person.ID,
+			PersonName:       personName(person),
+			InteractionCount: len(logged),
+		}
+
+		// Completing a reminder records contact without logging an interaction
+		lastContact := person.LastContactedAt
+		if len(logged) > 0 {
+			last := logged[len(logged)-1].OccurredAt
+			if lastContact == nil || last.After(*lastContact) {
+				lastContact = &last
+			}
+		}
+		if lastContact != nil {
+			since := daysBetween(*lastContact, now)
+			insight.LastContactAt = lastContact
+			insight.DaysSinceContact = &since
+		}
+		if len(logged) > 1 {
+			span := logged[len(logged)-1].OccurredAt.Sub(logged[0].OccurredAt)
+			gap := span.Hours() / 24 / float64(len(logged)-1)
+			insight.AverageGapDays = &gap
+		}
+
+		for _, interaction := range logged {
+			switch {
+			case interaction.OccurredAt.After(recentFrom):
+				insight.RecentCount++
+			case interaction.OccurredAt.After(previousFrom):
+				insight.PreviousCount++
+			}
+		}
+		insight.Trend = trend(insight.RecentCount, insight.PreviousCount)
+		response.RecentCount += insight.RecentCount
+		response.PreviousCount += insight.PreviousCount
+		response.People = append(response.People, insight)
+	}
+	response.Trend = trend(response.RecentCount, response.PreviousCount)
+
+	// People never contacted come first, then the longest out of touch
+	sort.SliceStable(response.People, func(i, j int) bool {
+		a, b := response.People[i].LastContactAt, response.People[j].LastContactAt
+		switch {
+		case a == nil || b == nil:
+			return a == nil && b != nil
+		default:
+			return a.Before(*b)
+		}
+	})
+	for _, insight := range response.People {
+		if insight.LastContactAt == nil || !insight.LastContactAt.After(recentFrom) {
+			response.NotContacted = append(response.NotContacted, insight.PersonID)
+		}
+	}
+	return response
+}
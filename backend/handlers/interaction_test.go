@@ -0,0 +1,218 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/handlers"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type InteractionTestSuite struct {
+	suite.Suite
+	env    *testutils.TestEnv
+	router *gin.Engine
+	user   models.User
+	now    time.Time
+}
+
+func (suite *InteractionTestSuite) SetupSuite() {
+	gin.SetMode(gin.TestMode)
+}
+
+func (suite *InteractionTestSuite) SetupTest() {
+	suite.now = time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC)
+	suite.env = testutils.NewTestEnv(func(deps *handlers.Deps) {
+		deps.Clock = func() time.Time { return suite.now }
+	})
+	h := suite.env.Handler
+
+	suite.user = models.User{Email: "margaret@example.com", Password: "x", DisplayName: "Margaret", EmailConfirmed: true}
+	suite.env.Store.Users.Create(context.Background(), &suite.user)
+
+	suite.router = gin.New()
+	suite.router.Use(testutils.OpenAPIValidator(suite.T()), apierror.Middleware())
+	protected := suite.router.Group("/")
+	protected.Use(func(c *gin.Context) {
+		c.Set("user_id", suite.user.ID)
+		c.Next()
+	})
+	protected.POST("/people", h.CreatePerson)
+	protected.POST("/people/:id/interactions", h.CreateInteraction)
+	protected.GET("/people/:id/interactions", h.GetInteractions)
+	protected.GET("/insights", h.GetInsights)
+	protected.GET("/reminders", h.GetReminders)
+}
+
+func (suite *InteractionTestSuite) request(method, path string, body any) *httptest.ResponseRecorder {
+	var reader bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&reader).Encode(body)
+	}
+	req, _ := http.NewRequest(method, path, &reader)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *InteractionTestSuite) createPerson(firstName string, everyDays int) handlers.PersonResponse {
+	w := suite.request("POST", "/people", handlers.CreatePersonRequest{
+		FirstName: firstName, LastName: "Hughes", Email: "tom@example.com", Phone: "555-0100",
+		Relationship: "Son", ContactEveryDays: everyDays,
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+	var person handlers.PersonResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &person))
+	return person
+}
+
+func (suite *InteractionTestSuite) logInteraction(personID uuid.UUID, kind string, daysAgo int) {
+	occurredAt := suite.now.AddDate(0, 0, -daysAgo)
+	w := suite.request("POST", "/people/"+personID.String()+"/interactions", handlers.CreateInteractionRequest{
+		Type: kind, OccurredAt: &occurredAt,
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+}
+
+func (suite *InteractionTestSuite) insights(query string) handlers.InsightsResponse {
+	w := suite.request("GET", "/insights"+query, nil)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var insights handlers.InsightsResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &insights))
+	return insights
+}
+
+func (suite *InteractionTestSuite) TestLogAndList() {
+	person := suite.createPerson("Tom", 0)
+
+	w := suite.request("POST", "/people/"+person.ID.String()+"/interactions", handlers.CreateInteractionRequest{
+		Type: models.InteractionVisit, Notes: "Lunch at the garden centre",
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+	var logged handlers.InteractionResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &logged))
+	assert.Equal(suite.T(), suite.now, logged.OccurredAt)
+	assert.Equal(suite.T(), "Lunch at the garden centre", logged.Notes)
+
+	suite.logInteraction(person.ID, models.InteractionCall, 5)
+
+	w = suite.request("GET", "/people/"+person.ID.String()+"/interactions", nil)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var interactions []handlers.InteractionResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &interactions))
+	suite.Require().Len(interactions, 2)
+	assert.Equal(suite.T(), logged.ID, interactions[0].ID)
+	assert.Equal(suite.T(), models.InteractionCall, interactions[1].Type)
+}
+
+func (suite *InteractionTestSuite) TestLogValidation() {
+	person := suite.createPerson("Tom", 0)
+	path := "/people/" + person.ID.String() + "/interactions"
+
+	w := suite.request("POST", path, map[string]string{"type": "letter"})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	tomorrow := suite.now.AddDate(0, 0, 1)
+	w = suite.request("POST", path, handlers.CreateInteractionRequest{Type: models.InteractionCall, OccurredAt: &tomorrow})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "occurredAt")
+
+	memoryID := uuid.New()
+	w = suite.request("POST", path, handlers.CreateInteractionRequest{Type: models.InteractionCall, MemoryID: &memoryID})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "memory_not_found")
+
+	w = suite.request("POST", "/people/"+suite.user.ID.String()+"/interactions", handlers.CreateInteractionRequest{Type: models.InteractionCall})
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+	w = suite.request("GET", "/people/not-a-uuid/interactions", nil)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+func (suite *InteractionTestSuite) TestLogCompletesReminder() {
+	person := suite.createPerson("Tom", 7)
+	suite.now = suite.now.AddDate(0, 0, 8)
+	_, err := suite.env.Handler.ProcessReminders(context.Background())
+	suite.Require().NoError(err)
+
+	w := suite.request("GET", "/reminders", nil)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), person.ID.String())
+
+	suite.logInteraction(person.ID, models.InteractionCall, 1)
+
+	w = suite.request("GET", "/reminders", nil)
+	assert.Equal(suite.T(), "[]", w.Body.String())
+	stored, err := suite.env.Store.People.GetForUser(context.Background(), person.ID, suite.user.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), suite.now.AddDate(0, 0, -1), *stored.LastContactedAt)
+	assert.Equal(suite.T(), suite.now.AddDate(0, 0, 6), *stored.NextReminderAt)
+
+	// Logging an older contact keeps the latest one
+	suite.logInteraction(person.ID, models.InteractionVisit, 4)
+	stored, err = suite.env.Store.People.GetForUser(context.Background(), person.ID, suite.user.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), suite.now.AddDate(0, 0, -1), *stored.LastContactedAt)
+}
+
+func (suite *InteractionTestSuite) TestInsights() {
+	tom := suite.createPerson("Tom", 0)
+	ann := suite.createPerson("Ann", 0)
+	never := suite.createPerson("Sam", 0)
+
+	// Tom: more often lately. Ann: only before the window.
+	for _, daysAgo := range []int{2, 10, 20} {
+		suite.logInteraction(tom.ID, models.InteractionCall, daysAgo)
+	}
+	suite.logInteraction(tom.ID, models.InteractionVisit, 40)
+	suite.logInteraction(ann.ID, models.InteractionVisit, 45)
+
+	insights := suite.insights("")
+	assert.Equal(suite.T(), 30, insights.Days)
+	assert.Equal(suite.T(), 3, insights.RecentCount)
+	assert.Equal(suite.T(), 2, insights.PreviousCount)
+	assert.Equal(suite.T(), handlers.TrendUp, insights.Trend)
+	assert.Equal(suite.T(), []uuid.UUID{never.ID, ann.ID}, insights.NotContacted)
+
+	suite.Require().Len(insights.People, 3)
+	assert.Equal(suite.T(), never.ID, insights.People[0].PersonID)
+	assert.Nil(suite.T(), insights.People[0].LastContactAt)
+
+	annInsight := insights.People[1]
+	assert.Equal(suite.T(), "Ann Hughes", annInsight.PersonName)
+	assert.Equal(suite.T(), 45, *annInsight.DaysSinceContact)
+	assert.Nil(suite.T(), annInsight.AverageGapDays)
+	assert.Equal(suite.T(), handlers.TrendDown, annInsight.Trend)
+
+	tomInsight := insights.People[2]
+	assert.Equal(suite.T(), 2, *tomInsight.DaysSinceContact)
+	assert.Equal(suite.T(), 4, tomInsight.InteractionCount)
+	assert.InDelta(suite.T(), 38.0/3, *tomInsight.AverageGapDays, 0.001)
+	assert.Equal(suite.T(), 3, tomInsight.RecentCount)
+	assert.Equal(suite.T(), 1, tomInsight.PreviousCount)
+
+	// A longer window takes in Ann's visit
+	insights = suite.insights("?days=60")
+	assert.Equal(suite.T(), []uuid.UUID{never.ID}, insights.NotContacted)
+	assert.Equal(suite.T(), handlers.TrendUp, insights.Trend)
+
+	for _, query := range []string{"?days=0", "?days=366", "?days=week"} {
+		w := suite.request("GET", "/insights"+query, nil)
+		assert.Equal(suite.T(), http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestInteractionTestSuite(t *testing.T) {
+	suite.Run(t, new(InteractionTestSuite))
+}
@@ -0,0 +1,163 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/handlers"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type JobTestSuite struct {
+	suite.Suite
+	env    *testutils.TestEnv
+	router *gin.Engine
+	admin  models.User
+	user   models.User
+	// caller is the user requests are made as
+	caller *models.User
+	now    time.Time
+}
+
+func (suite *JobTestSuite) SetupSuite() {
+	gin.SetMode(gin.TestMode)
+}
+
+func (suite *JobTestSuite) SetupTest() {
+	suite.now = time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC)
+	suite.env = testutils.NewTestEnv(func(deps *handlers.Deps) {
+		deps.Clock = func() time.Time { return suite.now }
+		deps.Config.AdminEmails = []string{"admin@example.com"}
+	})
+	h := suite.env.Handler
+
+	suite.admin = models.User{Email: "admin@example.com", Password: "x", DisplayName: "Admin", EmailConfirmed: true}
+	suite.env.Store.Users.Create(context.Background(), &suite.admin)
+	suite.user = models.User{Email: "margaret@example.com", Password: "x", DisplayName: "Margaret", EmailConfirmed: true}
+	suite.env.Store.Users.Create(context.Background(), &suite.user)
+	suite.caller = &suite.admin
+
+	suite.router = gin.New()
+	suite.router.Use(testutils.OpenAPIValidator(suite.T()), apierror.Middleware())
+	admin := suite.router.Group("/admin")
+	admin.Use(func(c *gin.Context) {
+		c.Set("user_id", suite.caller.ID)
+		c.Next()
+	}, h.RequireAdmin())
+	admin.GET("/jobs", h.GetJobs)
+	admin.GET("/jobs/:id", h.GetJob)
+	admin.POST("/jobs/:id/retry", h.RetryJob)
+}
+
+func (suite *JobTestSuite) request(method, path string, body any) *httptest.ResponseRecorder {
+	var reader bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&reader).Encode(body)
+	}
+	req, _ := http.NewRequest(method, path, &reader)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+// createJob stores a job of kind with status, created minutes after now
+func (suite *JobTestSuite) createJob(kind, status string, minutes int) models.Job {
+	job := models.Job{
+		Kind: kind, Payload: json.RawMessage(`{"userId":"` + suite.user.ID.String() + `"}`),
+		Status: status, MaxAttempts: 3, RunAt: suite.now,
+		CreatedAt: suite.now.Add(time.Duration(minutes) * time.Minute),
+	}
+	if status == models.JobDead {
+		job.Attempts = 3
+		job.LastError = "smtp down"
+		job.FinishedAt = &suite.now
+	}
+	suite.Require().NoError(suite.env.Store.Jobs.Create(context.Background(), &job))
+	return job
+}
+
+func (suite *JobTestSuite) jobs(query string) []handlers.JobResponse {
+	w := suite.request("GET", "/admin/jobs"+query, nil)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var jobs []handlers.JobResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &jobs))
+	return jobs
+}
+
+func (suite *JobTestSuite) TestListJobs() {
+	suite.createJob("send_emails", models.JobSucceeded, 0)
+	suite.createJob("welcome", models.JobDead, 1)
+	suite.createJob("welcome", models.JobPending, 2)
+
+	jobs := suite.jobs("")
+	suite.Require().Len(jobs, 3)
+	assert.Equal(suite.T(), models.JobPending, jobs[0].Status, "newest first")
+	assert.JSONEq(suite.T(), `{"userId":"`+suite.user.ID.String()+`"}`, string(jobs[0].Payload))
+
+	assert.Len(suite.T(), suite.jobs("?kind=welcome"), 2)
+	assert.Len(suite.T(), suite.jobs("?kind=welcome&status=dead"), 1)
+	assert.Len(suite.T(), suite.jobs("?limit=1"), 1)
+	assert.Equal(suite.T(), http.StatusBadRequest, suite.request("GET", "/admin/jobs?status=failed", nil).Code)
+	assert.Equal(suite.T(), http.StatusBadRequest, suite.request("GET", "/admin/jobs?limit=201", nil).Code)
+}
+
+func (suite *JobTestSuite) TestGetJob() {
+	job := suite.createJob("welcome", models.JobDead, 0)
+
+	w := suite.request("GET", "/admin/jobs/"+job.ID.String(), nil)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var found handlers.JobResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &found))
+	assert.Equal(suite.T(), "welcome", found.Kind)
+	assert.Equal(suite.T(), "smtp down", found.LastError)
+	assert.Equal(suite.T(), 3, found.Attempts)
+	suite.Require().NotNil(found.FinishedAt)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, suite.request("GET", "/admin/jobs/nope", nil).Code)
+	assert.Equal(suite.T(), http.StatusNotFound, suite.request("GET", "/admin/jobs/"+suite.user.ID.String(), nil).Code)
+}
+
+func (suite *JobTestSuite) TestRetryDeadJob() {
+	job := suite.createJob("welcome", models.JobDead, 0)
+	suite.now = suite.now.Add(time.Hour)
+
+	w := suite.request("POST", "/admin/jobs/"+job.ID.String()+"/retry", nil)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var retried handlers.JobResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &retried))
+	assert.Equal(suite.T(), models.JobPending, retried.Status)
+	assert.Zero(suite.T(), retried.Attempts)
+	assert.Equal(suite.T(), suite.now, retried.RunAt.UTC())
+	assert.Nil(suite.T(), retried.FinishedAt)
+
+	due, err := suite.env.Store.Jobs.ClaimDue(context.Background(), suite.now, []string{"welcome"}, 10)
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), due, 1)
+
+	w = suite.request("POST", "/admin/jobs/"+job.ID.String()+"/retry", nil)
+	assert.Equal(suite.T(), http.StatusConflict, w.Code)
+	assert.Equal(suite.T(), http.StatusNotFound, suite.request("POST", "/admin/jobs/"+suite.user.ID.String()+"/retry", nil).Code)
+}
+
+func (suite *JobTestSuite) TestAdminOnly() {
+	job := suite.createJob("welcome", models.JobDead, 0)
+	suite.caller = &suite.user
+	assert.Equal(suite.T(), http.StatusForbidden, suite.request("GET", "/admin/jobs", nil).Code)
+	assert.Equal(suite.T(), http.StatusForbidden, suite.request("GET", "/admin/jobs/"+job.ID.String(), nil).Code)
+	assert.Equal(suite.T(), http.StatusForbidden, suite.request("POST", "/admin/jobs/"+job.ID.String()+"/retry", nil).Code)
+}
+
+func TestJobTestSuite(t *testing.T) {
+	suite.Run(t, new(JobTestSuite))
+}
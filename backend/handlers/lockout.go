@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/mailer"
+	"github.com/muneerlalji/Luma/middleware"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/repository"
+	"github.com/muneerlalji/Luma/utils"
+)
+
+const maxLockoutDuration = 24 * time.Hour
+
+// lockoutDuration returns how long the nth lockout of an account lasts. Each
+// further lockout doubles the duration, up to maxLockoutDuration.
+func (h *Handler) lockoutDuration(lockoutCount int) time.Duration {
+	duration := h.config.LockoutDuration
+
+	for i := 1; i < lockoutCount && duration < maxLockoutDuration; i++ {
+		duration *= 2
+	}
+	if duration > maxLockoutDuration {
+		duration = maxLockoutDuration
+	}
+	return duration
+}
+
+// isLocked reports whether the account is locked and for how much longer
+func isLocked(user *models.User, now time.Time) (bool, time.Duration) {
+	if user.LockedUntil == nil || !user.LockedUntil.After(now) {
+		return false, 0
+	}
+	return true, user.LockedUntil.Sub(now)
+}
+
+// recordFailedLogin counts a failed login and locks the account once the
+// threshold is reached, emailing the owner a link to unlock it early. Only
+// the lockout columns are written, so a login racing a password reset or
+// email change cannot undo it.
+func (h *Handler) recordFailedLogin(ctx context.Context, user *models.User) error {
+	attempts, err := h.store.Users.IncrementFailedLogins(ctx, user.ID)
+	if err != nil || attempts < h.config.LockoutThreshold {
+		return err
+	}
+
+	unlockToken, err := generateRandomToken(32)
+	if err != nil {
+		return err
+	}
+
+	return h.store.Transaction(ctx, func(tx *repository.Store) error {
+		lockedUntil, err := tx.Users.LockOut(ctx, user.ID, h.config.LockoutThreshold, utils.HashToken(unlockToken),
+			func(lockoutCount int) time.Time { return h.now().Add(h.lockoutDuration(lockoutCount)) })
+		if err != nil || lockedUntil == nil {
+			// A concurrent failed login locked the account first
+			return err
+		}
+		user.LockedUntil = lockedUntil
+		return h.queueEmail(ctx, tx, user, user.Email, mailer.AccountLocked, mailer.LinkData{
+			Name: user.DisplayName,
+			URL:  h.config.FrontendURL + "/unlock?token=" + unlockToken,
+		})
+	})
+}
+
+// clearFailedLogins resets lockout state after a successful login
+func (h *Handler) clearFailedLogins(ctx context.Context, user *models.User) error {
+	if user.FailedLoginAttempts == 0 && user.LockoutCount == 0 && user.LockedUntil == nil {
+		return nil
+	}
+
+	return h.store.Users.ClearFailedLogins(ctx, user.ID)
+}
+
+// abortLocked responds to a login attempt against a locked account
+func abortLocked(c *gin.Context, retryAfter time.Duration) {
+	middleware.AbortTooManyRequests(c, retryAfter, "account_locked",
+		"Too many failed sign-in attempts. Check your email to unlock your account or try again later.")
+}
+
+// UnlockAccount lifts a login lockout using the token from the unlock email
+func (h *Handler) UnlockAccount(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, errTokenRequired)
+		return
+	}
+
+	// Unlock tokens are only issued alongside a lockout and cleared with it
+	digest := utils.HashToken(req.Token)
+	user, err := h.store.Users.GetByToken(c, repository.UnlockToken, digest)
+	if err != nil {
+		apierror.Abort(c, errInvalidToken)
+		return
+	}
+
+	user.FailedLoginAttempts = 0
+	user.LockedUntil = nil
+	user.UnlockToken = ""
+	updated, err := h.store.Users.UpdateIfToken(c, user, repository.UnlockToken, digest)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to unlock account", err))
+		return
+	}
+	if !updated {
+		apierror.Abort(c, errInvalidToken)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlocked. You can now log in."})
+}
@@ -3,12 +3,11 @@ package handlers
 import (
 	"fmt"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/muneerlalji/Luma/db"
+	"github.com/muneerlalji/Luma/apierror"
 	"github.com/muneerlalji/Luma/models"
 )
 
@@ -19,109 +18,128 @@ type CreateMemoryRequest struct {
 	Content   string      `json:"content" binding:"required"`
 	PhotoID   *uuid.UUID  `json:"photoId,omitempty"`
 	PeopleIDs []uuid.UUID `json:"peopleIds,omitempty"`
+	// OccurredOn is the date the memory is from, as YYYY-MM-DD
+	OccurredOn *string `json:"occurredOn,omitempty" binding:"omitempty,datetime=2006-01-02"`
+	// Favorite memories come back more often in reminiscence prompts
+	Favorite bool `json:"favorite,omitempty"`
 }
 
 // MemoryResponse represents the memory data sent to the client
 type MemoryResponse struct {
-	ID        uuid.UUID        `json:"id"`
-	Title     string           `json:"title"`
-	Type      string           `json:"type"`
-	Content   string           `json:"content"`
-	PhotoID   *uuid.UUID       `json:"photoId,omitempty"`
-	PhotoURL  *string          `json:"photoUrl,omitempty"`
-	People    []PersonResponse `json:"people,omitempty"`
-	CreatedAt string           `json:"createdAt"`
+	ID         uuid.UUID        `json:"id"`
+	Title      string           `json:"title"`
+	Type       string           `json:"type"`
+	Content    string           `json:"content"`
+	PhotoID    *uuid.UUID       `json:"photoId,omitempty"`
+	PhotoURL   *string          `json:"photoUrl,omitempty"`
+	People     []PersonResponse `json:"people,omitempty"`
+	OccurredOn *string          `json:"occurredOn,omitempty"`
+	Favorite   bool             `json:"favorite"`
+	CreatedAt  string           `json:"createdAt"`
+}
+
+// newMemoryResponse describes memory without its photo and people
+func newMemoryResponse(memory *models.Memory) MemoryResponse {
+	return MemoryResponse{
+		ID:         memory.ID,
+		Title:      memory.Title,
+		Type:       memory.Type,
+		Content:    memory.Content,
+		OccurredOn: formatDate(memory.OccurredOn),
+		Favorite:   memory.Favorite,
+		CreatedAt:  memory.CreatedAt.Format(time.RFC3339),
+	}
 }
 
 // CreateMemory handles memory creation for authenticated users
-func CreateMemory(c *gin.Context) {
+func (h *Handler) CreateMemory(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		apierror.Abort(c, apierror.ErrUnauthenticated)
 		return
 	}
 
 	var req CreateMemoryRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Bind(err))
 		return
 	}
 
 	userUUID, ok := userID.(uuid.UUID)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		apierror.Abort(c, errInvalidUserID)
 		return
 	}
 
 	memory := models.Memory{
-		UserID:  userUUID,
-		Title:   req.Title,
-		Type:    req.Type,
-		Content: req.Content,
+		UserID:     userUUID,
+		Title:      req.Title,
+		Type:       req.Type,
+		Content:    req.Content,
+		OccurredOn: parseDate(req.OccurredOn),
+		Favorite:   req.Favorite,
+	}
+	if memory.OccurredOn != nil && memory.OccurredOn.After(calendarDay(h.now())) {
+		apierror.Abort(c, errDateInFuture.WithField("occurredOn", fieldNotFuture))
+		return
 	}
 
-	if err := db.DB.Create(&memory).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create memory"})
+	if err := h.store.Memories.Create(c, &memory); err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to create memory", err))
 		return
 	}
 
 	if req.PhotoID != nil {
-		var photo models.Photo
-		if err := db.DB.Where("id = ? AND user_id = ?", req.PhotoID, userUUID).First(&photo).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Photo not found or not owned by user"})
+		photo, err := h.store.Photos.GetForUser(c, *req.PhotoID, userUUID)
+		if err != nil {
+			apierror.Abort(c, errPhotoReference)
 			return
 		}
 
 		photo.MemoryID = &memory.ID
-		if err := db.DB.Save(&photo).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to associate photo with memory"})
+		if err := h.store.Photos.Update(c, photo); err != nil {
+			apierror.Abort(c, apierror.Internal("Failed to associate photo with memory", err))
 			return
 		}
 	}
 
 	// Associate people with memory if provided
 	if len(req.PeopleIDs) > 0 {
-		var people []models.Person
-		if err := db.DB.Where("id IN ? AND user_id = ?", req.PeopleIDs, userUUID).Find(&people).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "One or more people not found or not owned by user"})
+		people, err := h.store.People.ListByIDs(c, userUUID, req.PeopleIDs)
+		if err != nil {
+			apierror.Abort(c, errPersonNotFound)
 			return
 		}
 
-		if err := db.DB.Model(&memory).Association("People").Append(people); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to associate people with memory"})
+		if err := h.store.Memories.AddPeople(c, memory.ID, people); err != nil {
+			apierror.Abort(c, apierror.Internal("Failed to associate people with memory", err))
 			return
 		}
 	}
 
-	response := MemoryResponse{
-		ID:        memory.ID,
-		Title:     memory.Title,
-		Type:      memory.Type,
-		Content:   memory.Content,
-		PhotoID:   req.PhotoID,
-		CreatedAt: memory.CreatedAt.Format(time.RFC3339),
-	}
+	response := newMemoryResponse(&memory)
+	response.PhotoID = req.PhotoID
 
 	c.JSON(http.StatusCreated, gin.H{"memory": response})
 }
 
 // GetMemories returns all memories for the authenticated user
-func GetMemories(c *gin.Context) {
+func (h *Handler) GetMemories(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		apierror.Abort(c, apierror.ErrUnauthenticated)
 		return
 	}
 
 	userUUID, ok := userID.(uuid.UUID)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		apierror.Abort(c, errInvalidUserID)
 		return
 	}
 
-	var memories []models.Memory
-	if err := db.DB.Preload("People").Where("user_id = ?", userUUID).Order("created_at DESC").Find(&memories).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch memories"})
+	memories, err := h.store.Memories.ListByUser(c, userUUID)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to fetch memories", err))
 		return
 	}
 
@@ -129,13 +147,12 @@ func GetMemories(c *gin.Context) {
 
 	for _, memory := range memories {
 		// Get associated photo if any
-		var photo models.Photo
 		var photoID *uuid.UUID
 		var photoURL *string
-		if err := db.DB.Where("memory_id = ?", memory.ID).First(&photo).Error; err == nil {
+		if photo, err := h.store.Photos.GetByMemory(c, memory.ID); err == nil {
 			photoID = &photo.ID
 			// Generate photo URL
-			url := fmt.Sprintf("%s/photos/%s", os.Getenv("API_BASE_URL"), photo.ID.String())
+			url := fmt.Sprintf("%s/api/v1/photos/%s", h.config.APIBaseURL, photo.ID.String())
 			photoURL = &url
 		}
 
@@ -154,16 +171,10 @@ func GetMemories(c *gin.Context) {
 			})
 		}
 
-		response := MemoryResponse{
-			ID:        memory.ID,
-			Title:     memory.Title,
-			Type:      memory.Type,
-			Content:   memory.Content,
-			PhotoID:   photoID,
-			PhotoURL:  photoURL,
-			People:    personResponses,
-			CreatedAt: memory.CreatedAt.Format(time.RFC3339),
-		}
+		response := newMemoryResponse(&memory)
+		response.PhotoID = photoID
+		response.PhotoURL = photoURL
+		response.People = personResponses
 		responses = append(responses, response)
 	}
 
@@ -2,40 +2,40 @@ package handlers_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/muneerlalji/Luma/apierror"
 	"github.com/muneerlalji/Luma/handlers"
 	"github.com/muneerlalji/Luma/middleware"
 	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/repository"
 	"github.com/muneerlalji/Luma/testutils"
 	"github.com/muneerlalji/Luma/utils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
-	"gorm.io/gorm"
 )
 
 type MemoryTestSuite struct {
 	suite.Suite
 	router *gin.Engine
-	db     *gorm.DB
+	store  *repository.Store
 	user   models.User
 	token  string
 }
 
 func (suite *MemoryTestSuite) SetupSuite() {
 	gin.SetMode(gin.TestMode)
-	suite.db = testutils.SetupTestDB()
-
-	// Auto-migrate test database
-	suite.db.AutoMigrate(&models.User{}, &models.Memory{})
 }
 
 func (suite *MemoryTestSuite) SetupTest() {
-	testutils.CleanupTestDB(suite.db)
+	env := testutils.NewTestEnv()
+	suite.store = env.Store
+	h := env.Handler
 
 	// Create a test user
 	suite.user = models.User{
@@ -44,10 +44,10 @@ func (suite *MemoryTestSuite) SetupTest() {
 		DisplayName:    "Test User",
 		EmailConfirmed: true,
 	}
-	suite.db.Create(&suite.user)
+	suite.store.Users.Create(context.Background(), &suite.user)
 
 	// Generate a proper JWT token for testing
-	token, err := utils.GenerateToken(suite.user.ID, suite.user.Email)
+	token, err := utils.GenerateToken(testutils.JWTSecret, suite.user.ID, suite.user.Email)
 	if err != nil {
 		suite.T().Fatalf("Failed to generate test token: %v", err)
 	}
@@ -55,28 +55,23 @@ func (suite *MemoryTestSuite) SetupTest() {
 
 	// Setup router
 	suite.router = gin.Default()
+	suite.router.Use(testutils.OpenAPIValidator(suite.T()), apierror.Middleware())
 	suite.router.Use(middleware.CORSMiddleware())
 
 	// Protected routes with real auth middleware
 	protected := suite.router.Group("/")
-	protected.Use(handlers.AuthMiddleware())
+	protected.Use(h.AuthMiddleware())
 	{
-		protected.POST("/memories", handlers.CreateMemory)
-		protected.GET("/memories", handlers.GetMemories)
+		protected.POST("/memories", h.CreateMemory)
+		protected.GET("/memories", h.GetMemories)
 	}
 }
 
-func (suite *MemoryTestSuite) TearDownSuite() {
-	// Clean up test database using testutils
-	testutils.CleanupTestDB(suite.db)
-}
-
 func (suite *MemoryTestSuite) TestCreateMemory_Success() {
-	memoryData := models.Memory{
+	memoryData := handlers.CreateMemoryRequest{
 		Title:   "Test Memory",
 		Type:    "story",
 		Content: "This is a test memory content",
-		UserID:  suite.user.ID,
 	}
 
 	jsonData, _ := json.Marshal(memoryData)
@@ -96,9 +91,10 @@ func (suite *MemoryTestSuite) TestCreateMemory_Success() {
 	assert.Contains(suite.T(), response, "memory")
 
 	// Verify memory was created in database
-	var memory models.Memory
-	err = suite.db.Where("title = ?", memoryData.Title).First(&memory).Error
+	memories, err := suite.store.Memories.ListByUser(context.Background(), suite.user.ID)
 	assert.NoError(suite.T(), err)
+	suite.Require().Len(memories, 1)
+	memory := memories[0]
 	assert.Equal(suite.T(), memoryData.Title, memory.Title)
 	assert.Equal(suite.T(), memoryData.Content, memory.Content)
 	assert.Equal(suite.T(), suite.user.ID, memory.UserID)
@@ -140,7 +136,7 @@ func (suite *MemoryTestSuite) TestGetMemories_Success() {
 	}
 
 	for _, memory := range memories {
-		suite.db.Create(&memory)
+		suite.store.Memories.Create(context.Background(), &memory)
 	}
 
 	req, _ := http.NewRequest("GET", "/memories", nil)
@@ -0,0 +1,730 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/logging"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/repository"
+	"github.com/muneerlalji/Luma/webpush"
+)
+
+const (
+	// defaultNotificationLimit is how many notifications are listed when the
+	// request names no limit, and maxNotificationLimit the most it may name
+	defaultNotificationLimit = 50
+	maxNotificationLimit     = 100
+	// pushBatchSize is how many notifications one scheduler transaction
+	// claims for pushing
+	pushBatchSize = 50
+	// pushTTL is how long a push service keeps a message for a device that
+	// is offline
+	pushTTL = time.Hour
+	// pushRetryWindow is how long a notification that could not be pushed is
+	// retried; after that it is only shown in the app
+	pushRetryWindow = time.Hour
+	// streamKeepAlive is how often an idle notification stream sends a
+	// comment so proxies keep the connection open
+	streamKeepAlive = 30 * time.Second
+	// streamOverlap is how far back a notification stream looks on each poll,
+	// so notifications committed late with an earlier creation time are not
+	// missed
+	streamOverlap = time.Minute
+	// streamBatchSize is how many notifications one poll of a stream reads
+	streamBatchSize = 100
+)
+
+// notificationTypes lists the types of notification in the order they are
+// shown in the preferences
+var notificationTypes = []string{
+	models.NotificationReminder,
+	models.NotificationRoutineDue,
+	models.NotificationCheckInMissed,
+	models.NotificationReminiscence,
+}
+
+// notificationChannels lists the channels each type of notification can be
+// sent on. Emails about missed check-ins go to the caregiver.
+var notificationChannels = map[string][]string{
+	models.NotificationReminder:      {models.ChannelInApp, models.ChannelPush, models.ChannelEmail},
+	models.NotificationRoutineDue:    {models.ChannelInApp, models.ChannelPush},
+	models.NotificationCheckInMissed: {models.ChannelInApp, models.ChannelPush, models.ChannelEmail},
+	models.NotificationReminiscence:  {models.ChannelInApp, models.ChannelPush},
+}
+
+type NotificationResponse struct {
+	ID        uuid.UUID         `json:"id"`
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Body      string            `json:"body"`
+	URL       string            `json:"url"`
+	Data      map[string]string `json:"data"`
+	ReadAt    *time.Time        `json:"readAt,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+// NotificationListResponse is a page of notifications with the number still
+// unread
+type NotificationListResponse struct {
+	Notifications []NotificationResponse `json:"notifications"`
+	Unread        int64                  `json:"unread"`
+}
+
+// UnreadCountResponse is the number of unread notifications
+type UnreadCountResponse struct {
+	Unread int64 `json:"unread"`
+}
+
+// PushKeyResponse is the application server key browsers subscribe with
+type PushKeyResponse struct {
+	PublicKey string `json:"publicKey"`
+}
+
+// PushSubscriptionRequest is a browser's PushSubscription.toJSON()
+type PushSubscriptionRequest struct {
+	Endpoint string               `json:"endpoint" binding:"required"`
+	Keys     PushSubscriptionKeys `json:"keys" binding:"required"`
+}
+
+// PushSubscriptionKeys are the browser's keys in base64url
+type PushSubscriptionKeys struct {
+	P256dh string `json:"p256dh" binding:"required"`
+	Auth   string `json:"auth" binding:"required"`
+}
+
+// NotificationPreferenceRequest turns one channel on or off for one type of
+// notification
+type NotificationPreferenceRequest struct {
+	Type    string `json:"type" binding:"required"`
+	Channel string `json:"channel" binding:"required"`
+	Enabled *bool  `json:"enabled" binding:"required"`
+}
+
+// NotificationPreferencesRequest changes some of the user's preferences;
+// the others are kept
+type NotificationPreferencesRequest struct {
+	Preferences []NotificationPreferenceRequest `json:"preferences" binding:"required,dive"`
+}
+
+type NotificationPreferenceResponse struct {
+	Type    string `json:"type"`
+	Channel string `json:"channel"`
+	Enabled bool   `json:"enabled"`
+}
+
+// NotificationPreferencesResponse lists every channel of every type of
+// notification
+type NotificationPreferencesResponse struct {
+	Preferences []NotificationPreferenceResponse `json:"preferences"`
+}
+
+func newNotificationResponse(notification *models.Notification) NotificationResponse {
+	data := notification.Data
+	if data == nil {
+		data = map[string]string{}
+	}
+	return NotificationResponse{
+		ID:        notification.ID,
+		Type:      notification.Type,
+		Title:     notification.Title,
+		Body:      notification.Body,
+		URL:       notification.URL,
+		Data:      data,
+		ReadAt:    notification.ReadAt,
+		CreatedAt: notification.CreatedAt,
+	}
+}
+
+// channelKey names one channel of one type of notification
+type channelKey struct {
+	kind    string
+	channel string
+}
+
+// notificationSettings are the channels a user turned on or off
+type notificationSettings map[channelKey]bool
+
+// enabled reports whether the user wants kind on channel; channels are on
+// until turned off
+func (s notificationSettings) enabled(kind, channel string) bool {
+	on, ok := s[channelKey{kind, channel}]
+	return !ok || on
+}
+
+func loadNotificationSettings(ctx context.Context, store *repository.Store, userID uuid.UUID) (notificationSettings, error) {
+	preferences, err := store.NotificationPreferences.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	settings := make(notificationSettings, len(preferences))
+	for _, preference := range preferences {
+		settings[channelKey{preference.Type, preference.Channel}] = preference.Enabled
+	}
+	return settings, nil
+}
+
+func newNotificationPreferencesResponse(settings notificationSettings) NotificationPreferencesResponse {
+	response := NotificationPreferencesResponse{Preferences: []NotificationPreferenceResponse{}}
+	for _, kind := range notificationTypes {
+		for _, channel := range notificationChannels[kind] {
+			response.Preferences = append(response.Preferences, NotificationPreferenceResponse{
+				Type:    kind,
+				Channel: channel,
+				Enabled: settings.enabled(kind, channel),
+			})
+		}
+	}
+	return response
+}
+
+// notify stores a notification for its user on the channels they want it
+// on: listed in the app, pushed to their browsers by ProcessPush, or both.
+// Nothing is stored when the user turned both off.
+func (h *Handler) notify(ctx context.Context, tx *repository.Store, notification *models.Notification) error {
+	settings, err := loadNotificationSettings(ctx, tx, notification.UserID)
+	if err != nil {
+		return err
+	}
+	notification.InApp = settings.enabled(notification.Type, models.ChannelInApp)
+	notification.PushPending = false
+	if h.push.PublicKey() != "" && settings.enabled(notification.Type, models.ChannelPush) {
+		subscriptions, err := tx.PushSubscriptions.ListByUser(ctx, notification.UserID)
+		if err != nil {
+			return err
+		}
+		notification.PushPending = len(subscriptions) > 0
+	}
+	if !notification.InApp && !notification.PushPending {
+		return nil
+	}
+	if notification.Data == nil {
+		notification.Data = map[string]string{}
+	}
+	if notification.CreatedAt.IsZero() {
+		notification.CreatedAt = h.now()
+	}
+	return tx.Notifications.Create(ctx, notification)
+}
+
+// GetNotifications lists the user's notifications newest first, only the
+// unread ones when unread=true
+func (h *Handler) GetNotifications(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	limit := defaultNotificationLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxNotificationLimit {
+			apierror.Abort(c, errInvalidNotificationLimit)
+			return
+		}
+		limit = parsed
+	}
+
+	notifications, err := h.store.Notifications.List(c, userUUID, c.Query("unread") == "true", limit)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to get notifications", err))
+		return
+	}
+	unread, err := h.store.Notifications.CountUnread(c, userUUID)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to get notifications", err))
+		return
+	}
+
+	response := NotificationListResponse{
+		Notifications: make([]NotificationResponse, 0, len(notifications)),
+		Unread:        unread,
+	}
+	for i := range notifications {
+		response.Notifications = append(response.Notifications, newNotificationResponse(&notifications[i]))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// MarkNotificationRead marks one notification read. Marking it again keeps
+// the time it was first read.
+func (h *Handler) MarkNotificationRead(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	notificationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Abort(c, errInvalidNotificationID)
+		return
+	}
+
+	notification, err := h.store.Notifications.MarkRead(c, notificationID, userUUID, h.now())
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		apierror.Abort(c, errNotificationNotFound)
+	case err != nil:
+		apierror.Abort(c, apierror.Internal("Failed to mark notification read", err))
+	default:
+		c.JSON(http.StatusOK, newNotificationResponse(notification))
+	}
+}
+
+// MarkAllNotificationsRead marks all of the user's notifications read
+func (h *Handler) MarkAllNotificationsRead(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	if _, err := h.store.Notifications.MarkAllRead(c, userUUID, h.now()); err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to mark notifications read", err))
+		return
+	}
+	c.JSON(http.StatusOK, UnreadCountResponse{Unread: 0})
+}
+
+// CloseStreams ends the open notification streams so the server can shut
+// down without waiting for clients to disconnect
+func (h *Handler) CloseStreams() {
+	h.closeStreams.Do(func() { close(h.streams) })
+}
+
+// StreamNotifications sends the user's new notifications as Server-Sent
+// Events while the connection is open: a "notification" event for each,
+// with the notification ID as the event ID, and an "unread" event with the
+// unread count when the stream opens and whenever the count changes. A
+// client reconnecting with Last-Event-ID receives what it missed.
+// Notifications may be sent twice across reconnects, so clients should
+// ignore IDs they already have.
+func (h *Handler) StreamNotifications(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	// Notifications up to the cursor are already known to the client
+	cursor := h.now()
+	var lastID uuid.UUID
+	if value := c.GetHeader("Last-Event-ID"); value != "" {
+		if id, err := uuid.Parse(value); err == nil {
+			if last, err := h.store.Notifications.GetForUser(c, id, userUUID); err == nil {
+				cursor = last.CreatedAt
+				lastID = last.ID
+			}
+		}
+	}
+	known, err := h.store.Notifications.ListSince(c, userUUID, cursor.Add(-streamOverlap), streamBatchSize)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to get notifications", err))
+		return
+	}
+	unread, err := h.store.Notifications.CountUnread(c, userUUID)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to get notifications", err))
+		return
+	}
+	seen := make(map[uuid.UUID]time.Time)
+	for _, notification := range known {
+		if notification.ID == lastID || notification.CreatedAt.Before(cursor) || lastID == uuid.Nil && !notification.CreatedAt.After(cursor) {
+			seen[notification.ID] = notification.CreatedAt
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// Stops proxies such as nginx from buffering events
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	writeUnreadEvent(c, unread)
+	c.Writer.Flush()
+
+	poll := time.NewTicker(h.config.NotificationPollInterval)
+	defer poll.Stop()
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-h.streams:
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(c.Writer, ": keep-alive\n\n")
+		case <-poll.C:
+			notifications, err := h.store.Notifications.ListSince(c, userUUID, cursor.Add(-streamOverlap), streamBatchSize)
+			if err != nil {
+				logging.FromContext(c).Error("failed to poll notifications", "error", err)
+				continue
+			}
+			for i := range notifications {
+				notification := &notifications[i]
+				if _, ok := seen[notification.ID]; ok {
+					continue
+				}
+				seen[notification.ID] = notification.CreatedAt
+				if notification.CreatedAt.After(cursor) {
+					cursor = notification.CreatedAt
+				}
+				data, _ := json.Marshal(newNotificationResponse(notification))
+				fmt.Fprintf(c.Writer, "id: %s\nevent: notification\ndata: %s\n\n", notification.ID, data)
+			}
+			for id, createdAt := range seen {
+				if createdAt.Before(cursor.Add(-streamOverlap)) {
+					delete(seen, id)
+				}
+			}
+
+			count, err := h.store.Notifications.CountUnread(c, userUUID)
+			if err != nil {
+				logging.FromContext(c).Error("failed to count unread notifications", "error", err)
+			} else if count != unread {
+				unread = count
+				writeUnreadEvent(c, unread)
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+func writeUnreadEvent(c *gin.Context, unread int64) {
+	data, _ := json.Marshal(UnreadCountResponse{Unread: unread})
+	fmt.Fprintf(c.Writer, "event: unread\ndata: %s\n\n", data)
+}
+
+// GetPushKey returns the key browsers subscribe to push messages with
+func (h *Handler) GetPushKey(c *gin.Context) {
+	publicKey := h.push.PublicKey()
+	if publicKey == "" {
+		apierror.Abort(c, errPushNotConfigured)
+		return
+	}
+	c.JSON(http.StatusOK, PushKeyResponse{PublicKey: publicKey})
+}
+
+// SubscribePush stores a browser the user allowed to receive push messages.
+// Subscribing a browser again replaces its keys.
+func (h *Handler) SubscribePush(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	if h.push.PublicKey() == "" {
+		apierror.Abort(c, errPushNotConfigured)
+		return
+	}
+
+	var req PushSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, apierror.Bind(err))
+		return
+	}
+	endpoint, err := url.Parse(req.Endpoint)
+	if err != nil || endpoint.Scheme != "https" || endpoint.Host == "" {
+		apierror.Abort(c, errInvalidPushEndpoint)
+		return
+	}
+	subscription := webpush.Subscription{Endpoint: req.Endpoint, P256dh: req.Keys.P256dh, Auth: req.Keys.Auth}
+	// Encrypting an empty message checks the keys are usable
+	if _, err := webpush.Encrypt(nil, subscription); err != nil {
+		apierror.Abort(c, errInvalidPushKeys)
+		return
+	}
+
+	err = h.store.PushSubscriptions.Save(c, &models.PushSubscription{
+		UserID:    userUUID,
+		Endpoint:  req.Endpoint,
+		P256dh:    req.Keys.P256dh,
+		Auth:      req.Keys.Auth,
+		UserAgent: c.Request.UserAgent(),
+	})
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to save push subscription", err))
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Push subscription saved"})
+}
+
+// UnsubscribePush forgets the user's browser with the given endpoint
+func (h *Handler) UnsubscribePush(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	endpoint := c.Query("endpoint")
+	if endpoint == "" {
+		apierror.Abort(c, errPushEndpointRequired)
+		return
+	}
+
+	err := h.store.PushSubscriptions.DeleteByEndpoint(c, userUUID, endpoint)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		apierror.Abort(c, errPushSubscriptionNotFound)
+	case err != nil:
+		apierror.Abort(c, apierror.Internal("Failed to delete push subscription", err))
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Push subscription deleted"})
+	}
+}
+
+// GetNotificationPreferences lists whether each channel of each type of
+// notification is on
+func (h *Handler) GetNotificationPreferences(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	settings, err := loadNotificationSettings(c, h.store, userUUID)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to get notification preferences", err))
+		return
+	}
+	c.JSON(http.StatusOK, newNotificationPreferencesResponse(settings))
+}
+
+// UpdateNotificationPreferences turns channels on or off and returns all of
+// the user's preferences
+func (h *Handler) UpdateNotificationPreferences(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	var req NotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, apierror.Bind(err))
+		return
+	}
+	for i, preference := range req.Preferences {
+		channels, ok := notificationChannels[preference.Type]
+		if !ok {
+			apierror.Abort(c, errInvalidNotificationType.WithField(fmt.Sprintf("preferences[%d].type", i), fieldNotificationType))
+			return
+		}
+		if !slices.Contains(channels, preference.Channel) {
+			apierror.Abort(c, errInvalidNotificationChannel.WithField(fmt.Sprintf("preferences[%d].channel", i), fieldNotificationChannel))
+			return
+		}
+	}
+
+	var settings notificationSettings
+	err := h.store.Transaction(c, func(tx *repository.Store) error {
+		for _, preference := range req.Preferences {
+			err := tx.NotificationPreferences.Set(c, &models.NotificationPreference{
+				UserID:  userUUID,
+				Type:    preference.Type,
+				Channel: preference.Channel,
+				Enabled: *preference.Enabled,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		var err error
+		settings, err = loadNotificationSettings(c, tx, userUUID)
+		return err
+	})
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to update notification preferences", err))
+		return
+	}
+	c.JSON(http.StatusOK, newNotificationPreferencesResponse(settings))
+}
+
+// pushPayload is the JSON the service worker receives
+type pushPayload struct {
+	ID    uuid.UUID `json:"id"`
+	Type  string    `json:"type"`
+	Title string    `json:"title"`
+	Body  string    `json:"body,omitempty"`
+	URL   string    `json:"url"`
+}
+
+// ProcessPush pushes pending notifications to the browsers their users
+// subscribed. Subscriptions the push service no longer knows are deleted.
+// A notification no browser could be sent is tried once per run, and again
+// on later runs until it is pushRetryWindow old. Rows are claimed with row locks, so several
+// instances may run it at once. It returns how many notifications were
+// pushed.
+func (h *Handler) ProcessPush(ctx context.Context) (int, error) {
+	if h.push.PublicKey() == "" {
+		return 0, nil
+	}
+
+	var pushed int
+	var deliveryErr error
+	// retry holds the notifications left pending for a later run
+	var retry []uuid.UUID
+	for {
+		var claimed, pushedInBatch int
+		var retryInBatch []uuid.UUID
+		err := h.store.Transaction(ctx, func(tx *repository.Store) error {
+			notifications, err := tx.Notifications.ClaimPushPending(ctx, retry, pushBatchSize)
+			if err != nil {
+				return err
+			}
+			claimed = len(notifications)
+			subscriptions := make(map[uuid.UUID][]models.PushSubscription)
+			for i := range notifications {
+				notification := &notifications[i]
+				userSubscriptions, ok := subscriptions[notification.UserID]
+				if !ok {
+					if userSubscriptions, err = tx.PushSubscriptions.ListByUser(ctx, notification.UserID); err != nil {
+						return err
+					}
+				}
+
+				var sent int
+				var sendErr error
+				kept := userSubscriptions[:0]
+				for _, subscription := range userSubscriptions {
+					err := h.pushNotification(ctx, notification, &subscription)
+					switch {
+					case errors.Is(err, webpush.ErrGone):
+						if err := tx.PushSubscriptions.Delete(ctx, subscription.ID); err != nil {
+							return err
+						}
+						continue
+					case err != nil:
+						sendErr = err
+					default:
+						sent++
+					}
+					kept = append(kept, subscription)
+				}
+				subscriptions[notification.UserID] = kept
+
+				if sendErr != nil && sent == 0 && h.now().Sub(notification.CreatedAt) < pushRetryWindow {
+					retryInBatch = append(retryInBatch, notification.ID)
+					deliveryErr = sendErr
+					continue
+				}
+				notification.PushPending = false
+				if err := tx.Notifications.Update(ctx, notification); err != nil {
+					return err
+				}
+				if sent > 0 {
+					pushedInBatch++
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return pushed, fmt.Errorf("push notifications: %w", err)
+		}
+		pushed += pushedInBatch
+		retry = append(retry, retryInBatch...)
+		if claimed < pushBatchSize {
+			break
+		}
+	}
+	if len(retry) > 0 {
+		return pushed, fmt.Errorf("push %d notifications: %w", len(retry), deliveryErr)
+	}
+	return pushed, nil
+}
+
+// pushNotification sends notification to one browser. Notifications about
+// routines are urgent enough to wake a device saving battery.
+func (h *Handler) pushNotification(ctx context.Context, notification *models.Notification, subscription *models.PushSubscription) error {
+	payload := pushPayload{
+		ID:    notification.ID,
+		Type:  notification.Type,
+		Title: notification.Title,
+		Body:  notification.Body,
+		URL:   notification.URL,
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	// Long bodies, such as routine instructions, are left for the app
+	if len(data) > webpush.MaxPayloadSize {
+		payload.Body = ""
+		if data, err = json.Marshal(payload); err != nil {
+			return err
+		}
+	}
+
+	urgency := webpush.UrgencyNormal
+	if notification.Type == models.NotificationRoutineDue || notification.Type == models.NotificationCheckInMissed {
+		urgency = webpush.UrgencyHigh
+	}
+	return h.push.Send(ctx, webpush.Subscription{
+		Endpoint: subscription.Endpoint,
+		P256dh:   subscription.P256dh,
+		Auth:     subscription.Auth,
+	}, webpush.Message{Payload: data, TTL: pushTTL, Urgency: urgency})
+}
@@ -0,0 +1,590 @@
+package handlers_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/handlers"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/testutils"
+	"github.com/muneerlalji/Luma/webpush"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type NotificationTestSuite struct {
+	suite.Suite
+	env    *testutils.TestEnv
+	push   *testutils.PushMock
+	router *gin.Engine
+	user   models.User
+	loc    *time.Location
+	now    time.Time
+}
+
+func (suite *NotificationTestSuite) SetupSuite() {
+	gin.SetMode(gin.TestMode)
+}
+
+func (suite *NotificationTestSuite) SetupTest() {
+	// 08:00 on a Monday in New York
+	loc, err := time.LoadLocation("America/New_York")
+	suite.Require().NoError(err)
+	suite.loc = loc
+	suite.now = time.Date(2026, time.March, 2, 8, 0, 0, 0, loc)
+	suite.push = testutils.NewPushMock()
+	suite.env = testutils.NewTestEnv(func(deps *handlers.Deps) {
+		deps.Clock = func() time.Time { return suite.now }
+		deps.Push = suite.push
+		deps.Config.NotificationPollInterval = 10 * time.Millisecond
+	})
+	h := suite.env.Handler
+
+	suite.user = models.User{Email: "margaret@example.com", Password: "x", DisplayName: "Margaret", EmailConfirmed: true, Timezone: "America/New_York"}
+	suite.env.Store.Users.Create(context.Background(), &suite.user)
+
+	suite.router = gin.New()
+	suite.router.Use(testutils.OpenAPIValidator(suite.T()), apierror.Middleware())
+	protected := suite.router.Group("/")
+	protected.Use(func(c *gin.Context) {
+		c.Set("user_id", suite.user.ID)
+		c.Next()
+	})
+	protected.POST("/people", h.CreatePerson)
+	protected.POST("/routines", h.CreateRoutine)
+	protected.GET("/notifications", h.GetNotifications)
+	protected.GET("/notifications/stream", h.StreamNotifications)
+	protected.POST("/notifications/:id/read", h.MarkNotificationRead)
+	protected.POST("/notifications/read-all", h.MarkAllNotificationsRead)
+	protected.GET("/notifications/preferences", h.GetNotificationPreferences)
+	protected.PUT("/notifications/preferences", h.UpdateNotificationPreferences)
+	protected.GET("/notifications/push-key", h.GetPushKey)
+	protected.POST("/notifications/push-subscriptions", h.SubscribePush)
+	protected.DELETE("/notifications/push-subscriptions", h.UnsubscribePush)
+}
+
+func (suite *NotificationTestSuite) request(method, path string, body any) *httptest.ResponseRecorder {
+	var reader bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&reader).Encode(body)
+	}
+	req, _ := http.NewRequest(method, path, &reader)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+// at is the time of day on the suite's current day in New York
+func (suite *NotificationTestSuite) at(hour, minute int) time.Time {
+	year, month, day := suite.now.In(suite.loc).Date()
+	return time.Date(year, month, day, hour, minute, 0, 0, suite.loc)
+}
+
+func (suite *NotificationTestSuite) notifications(query string) handlers.NotificationListResponse {
+	w := suite.request("GET", "/notifications"+query, nil)
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	var list handlers.NotificationListResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &list))
+	return list
+}
+
+// seed stores an in-app notification created at createdAt
+func (suite *NotificationTestSuite) seed(title string, createdAt time.Time) models.Notification {
+	notification := models.Notification{
+		UserID: suite.user.ID, Type: models.NotificationReminder, Title: title, URL: "/people",
+		Data: map[string]string{}, InApp: true, CreatedAt: createdAt,
+	}
+	suite.Require().NoError(suite.env.Store.Notifications.Create(context.Background(), &notification))
+	return notification
+}
+
+// subscription is a push subscription as a browser would send it
+func (suite *NotificationTestSuite) subscription(endpoint string) handlers.PushSubscriptionRequest {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	suite.Require().NoError(err)
+	auth := make([]byte, 16)
+	rand.Read(auth)
+	return handlers.PushSubscriptionRequest{
+		Endpoint: endpoint,
+		Keys: handlers.PushSubscriptionKeys{
+			P256dh: base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()),
+			Auth:   base64.RawURLEncoding.EncodeToString(auth),
+		},
+	}
+}
+
+func (suite *NotificationTestSuite) subscribe(endpoint string) {
+	w := suite.request("POST", "/notifications/push-subscriptions", suite.subscription(endpoint))
+	suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+}
+
+func (suite *NotificationTestSuite) setPreference(kind, channel string, enabled bool) {
+	w := suite.request("PUT", "/notifications/preferences", map[string]any{
+		"preferences": []map[string]any{{"type": kind, "channel": channel, "enabled": enabled}},
+	})
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+}
+
+func (suite *NotificationTestSuite) TestListAndMarkRead() {
+	first := suite.seed("First", suite.now.Add(-2*time.Hour))
+	second := suite.seed("Second", suite.now.Add(-time.Hour))
+
+	list := suite.notifications("")
+	suite.Require().Len(list.Notifications, 2)
+	assert.Equal(suite.T(), "Second", list.Notifications[0].Title)
+	assert.Equal(suite.T(), int64(2), list.Unread)
+	assert.Len(suite.T(), suite.notifications("?limit=1").Notifications, 1)
+
+	w := suite.request("POST", "/notifications/"+first.ID.String()+"/read", nil)
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	var read handlers.NotificationResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &read))
+	suite.Require().NotNil(read.ReadAt)
+	assert.True(suite.T(), suite.now.Equal(*read.ReadAt))
+
+	// Reading it again keeps the first read time
+	suite.now = suite.now.Add(time.Hour)
+	w = suite.request("POST", "/notifications/"+first.ID.String()+"/read", nil)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &read))
+	assert.True(suite.T(), suite.now.Add(-time.Hour).Equal(*read.ReadAt))
+
+	unread := suite.notifications("?unread=true")
+	suite.Require().Len(unread.Notifications, 1)
+	assert.Equal(suite.T(), second.ID, unread.Notifications[0].ID)
+	assert.Equal(suite.T(), int64(1), unread.Unread)
+
+	w = suite.request("POST", "/notifications/read-all", nil)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	assert.Empty(suite.T(), suite.notifications("?unread=true").Notifications)
+	assert.Len(suite.T(), suite.notifications("").Notifications, 2)
+
+	// Other users' notifications are not found
+	other := models.User{Email: "tom@example.com", Password: "x", EmailConfirmed: true}
+	suite.env.Store.Users.Create(context.Background(), &other)
+	theirs := models.Notification{UserID: other.ID, Type: models.NotificationReminder, Title: "Theirs", InApp: true}
+	suite.Require().NoError(suite.env.Store.Notifications.Create(context.Background(), &theirs))
+	assert.Equal(suite.T(), http.StatusNotFound, suite.request("POST", "/notifications/"+theirs.ID.String()+"/read", nil).Code)
+	assert.Equal(suite.T(), http.StatusNotFound, suite.request("POST", "/notifications/"+uuid.NewString()+"/read", nil).Code)
+	assert.Equal(suite.T(), http.StatusBadRequest, suite.request("POST", "/notifications/abc/read", nil).Code)
+	assert.Equal(suite.T(), http.StatusBadRequest, suite.request("GET", "/notifications?limit=0", nil).Code)
+	assert.Equal(suite.T(), http.StatusBadRequest, suite.request("GET", "/notifications?limit=101", nil).Code)
+}
+
+func (suite *NotificationTestSuite) TestSchedulerNotifies() {
+	w := suite.request("POST", "/routines", handlers.RoutineRequest{
+		Kind: models.RoutineMedication, Title: "Blood pressure tablets", Instructions: "Two with water",
+		Recurrence: "FREQ=DAILY", Times: []string{"09:00"}, GraceMinutes: 30,
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+	var routine handlers.RoutineResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &routine))
+
+	process := func() {
+		_, err := suite.env.Handler.ProcessCheckIns(context.Background())
+		suite.Require().NoError(err)
+	}
+	process()
+	assert.Empty(suite.T(), suite.notifications("").Notifications)
+
+	// The user is told when the tablets are due, once
+	suite.now = suite.at(9, 0)
+	process()
+	process()
+	list := suite.notifications("")
+	suite.Require().Len(list.Notifications, 1)
+	due := list.Notifications[0]
+	assert.Equal(suite.T(), models.NotificationRoutineDue, due.Type)
+	assert.Equal(suite.T(), "Time for Blood pressure tablets", due.Title)
+	assert.Equal(suite.T(), "Due at 09:00. Two with water", due.Body)
+	assert.Equal(suite.T(), "/today", due.URL)
+	assert.Equal(suite.T(), routine.ID.String(), due.Data["routineId"])
+
+	// and again when nobody checked in within the grace period
+	suite.now = suite.at(9, 30)
+	process()
+	list = suite.notifications("")
+	suite.Require().Len(list.Notifications, 2)
+	missed := list.Notifications[0]
+	assert.Equal(suite.T(), models.NotificationCheckInMissed, missed.Type)
+	assert.Equal(suite.T(), "Blood pressure tablets was not checked in", missed.Title)
+	assert.Contains(suite.T(), missed.Body, "09:00")
+
+	// Occurrences passed while the scheduler was not running are not
+	// announced as due
+	suite.now = suite.at(9, 30).AddDate(0, 0, 2)
+	process()
+	for _, notification := range suite.notifications("").Notifications {
+		if notification.Type == models.NotificationRoutineDue {
+			assert.Equal(suite.T(), due.ID, notification.ID)
+		}
+	}
+
+	// Reminders notify when they open
+	w = suite.request("POST", "/people", handlers.CreatePersonRequest{
+		FirstName: "Tom", LastName: "Hughes", Email: "tom@example.com", Phone: "555-0100", Relationship: "Son", ContactEveryDays: 7,
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+	suite.now = suite.now.AddDate(0, 0, 7)
+	_, err := suite.env.Handler.ProcessReminders(context.Background())
+	suite.Require().NoError(err)
+	reminder := suite.notifications("").Notifications[0]
+	assert.Equal(suite.T(), models.NotificationReminder, reminder.Type)
+	assert.Equal(suite.T(), "Time to reach out to Tom", reminder.Title)
+	assert.Contains(suite.T(), reminder.Body, "555-0100")
+	assert.NotEmpty(suite.T(), reminder.Data["personId"])
+}
+
+func (suite *NotificationTestSuite) TestPreferences() {
+	w := suite.request("GET", "/notifications/preferences", nil)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var preferences handlers.NotificationPreferencesResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &preferences))
+	suite.Require().Len(preferences.Preferences, 10)
+	for _, preference := range preferences.Preferences {
+		assert.True(suite.T(), preference.Enabled, "%s %s", preference.Type, preference.Channel)
+	}
+
+	// Without in-app or push, reminders are only emailed
+	suite.setPreference(models.NotificationReminder, models.ChannelInApp, false)
+	w = suite.request("POST", "/people", handlers.CreatePersonRequest{
+		FirstName: "Tom", LastName: "Hughes", Email: "tom@example.com", Phone: "555-0100", Relationship: "Son", ContactEveryDays: 7,
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+	suite.now = suite.now.AddDate(0, 0, 7)
+	sent, err := suite.env.Handler.ProcessReminders(context.Background())
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 1, sent)
+	assert.Empty(suite.T(), suite.notifications("").Notifications)
+
+	// Without email they are only listed, and not emailed later either
+	suite.setPreference(models.NotificationReminder, models.ChannelInApp, true)
+	suite.setPreference(models.NotificationReminder, models.ChannelEmail, false)
+	w = suite.request("POST", "/people", handlers.CreatePersonRequest{
+		FirstName: "Ann", LastName: "Hughes", Email: "ann@example.com", Phone: "555-0101", Relationship: "Daughter", ContactEveryDays: 7,
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+	suite.now = suite.now.AddDate(0, 0, 7)
+	sent, err = suite.env.Handler.ProcessReminders(context.Background())
+	suite.Require().NoError(err)
+	assert.Zero(suite.T(), sent)
+	suite.setPreference(models.NotificationReminder, models.ChannelEmail, true)
+	sent, err = suite.env.Handler.ProcessReminders(context.Background())
+	suite.Require().NoError(err)
+	assert.Zero(suite.T(), sent)
+	_, err = suite.env.SendQueuedEmails()
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), suite.env.Email.GetSentEmails(), 1)
+	assert.Len(suite.T(), suite.notifications("").Notifications, 1)
+
+	w = suite.request("GET", "/notifications/preferences", nil)
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &preferences))
+	assert.Contains(suite.T(), preferences.Preferences, handlers.NotificationPreferenceResponse{
+		Type: models.NotificationRoutineDue, Channel: models.ChannelPush, Enabled: true,
+	})
+
+	// Routines coming due are never emailed
+	w = suite.request("PUT", "/notifications/preferences", map[string]any{
+		"preferences": []map[string]any{{"type": models.NotificationRoutineDue, "channel": models.ChannelEmail, "enabled": true}},
+	})
+	suite.Require().Equal(http.StatusBadRequest, w.Code)
+	var response struct {
+		Error apierror.Error `json:"error"`
+	}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(suite.T(), "channel", response.Error.Fields["preferences[0].channel"][0].Code)
+
+	w = suite.request("PUT", "/notifications/preferences", map[string]any{
+		"preferences": []map[string]any{{"type": "birthday", "channel": models.ChannelPush, "enabled": true}},
+	})
+	suite.Require().Equal(http.StatusBadRequest, w.Code)
+	w = suite.request("PUT", "/notifications/preferences", map[string]any{
+		"preferences": []map[string]any{{"type": models.NotificationReminder, "channel": models.ChannelPush}},
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+func (suite *NotificationTestSuite) TestMissedCheckInEmailPreference() {
+	suite.user.CaregiverEmail = "carer@example.com"
+	suite.Require().NoError(suite.env.Store.Users.Update(context.Background(), &suite.user))
+	suite.setPreference(models.NotificationCheckInMissed, models.ChannelEmail, false)
+	w := suite.request("POST", "/routines", handlers.RoutineRequest{
+		Kind: models.RoutineMedication, Title: "Tablets", Recurrence: "FREQ=DAILY", Times: []string{"09:00"}, GraceMinutes: 30,
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+
+	suite.now = suite.at(9, 30)
+	sent, err := suite.env.Handler.ProcessCheckIns(context.Background())
+	suite.Require().NoError(err)
+	assert.Zero(suite.T(), sent)
+
+	// Turning the email back on does not send the settled check-in
+	suite.setPreference(models.NotificationCheckInMissed, models.ChannelEmail, true)
+	sent, err = suite.env.Handler.ProcessCheckIns(context.Background())
+	suite.Require().NoError(err)
+	assert.Zero(suite.T(), sent)
+	_, err = suite.env.SendQueuedEmails()
+	suite.Require().NoError(err)
+	assert.Empty(suite.T(), suite.env.Email.GetSentEmails())
+}
+
+func (suite *NotificationTestSuite) TestPushSubscriptions() {
+	w := suite.request("GET", "/notifications/push-key", nil)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var key handlers.PushKeyResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &key))
+	assert.Equal(suite.T(), testutils.PushMockKey, key.PublicKey)
+
+	suite.subscribe("https://push.example.com/tablet")
+	suite.subscribe("https://push.example.com/phone")
+	// Subscribing again replaces the keys
+	suite.subscribe("https://push.example.com/tablet")
+	subscriptions, err := suite.env.Store.PushSubscriptions.ListByUser(context.Background(), suite.user.ID)
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), subscriptions, 2)
+
+	invalid := suite.subscription("http://push.example.com/tablet")
+	assert.Equal(suite.T(), http.StatusBadRequest, suite.request("POST", "/notifications/push-subscriptions", invalid).Code)
+	invalid = suite.subscription("https://push.example.com/tablet")
+	invalid.Keys.P256dh = "not-a-key"
+	assert.Equal(suite.T(), http.StatusBadRequest, suite.request("POST", "/notifications/push-subscriptions", invalid).Code)
+
+	w = suite.request("DELETE", "/notifications/push-subscriptions?endpoint=https://push.example.com/phone", nil)
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	w = suite.request("DELETE", "/notifications/push-subscriptions?endpoint=https://push.example.com/phone", nil)
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+	assert.Equal(suite.T(), http.StatusBadRequest, suite.request("DELETE", "/notifications/push-subscriptions", nil).Code)
+}
+
+func (suite *NotificationTestSuite) TestProcessPush() {
+	suite.subscribe("https://push.example.com/tablet")
+	suite.subscribe("https://push.example.com/gone")
+	suite.push.SetSendError("https://push.example.com/gone", webpush.ErrGone)
+
+	w := suite.request("POST", "/routines", handlers.RoutineRequest{
+		Kind: models.RoutineMedication, Title: "Tablets", Recurrence: "FREQ=DAILY", Times: []string{"09:00"}, GraceMinutes: 30,
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+	suite.now = suite.at(9, 0)
+	_, err := suite.env.Handler.ProcessCheckIns(context.Background())
+	suite.Require().NoError(err)
+
+	pushed, err := suite.env.Handler.ProcessPush(context.Background())
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 1, pushed)
+	sent := suite.push.GetSent()
+	suite.Require().Len(sent, 1)
+	assert.Equal(suite.T(), "https://push.example.com/tablet", sent[0].Subscription.Endpoint)
+	assert.Equal(suite.T(), webpush.UrgencyHigh, sent[0].Message.Urgency)
+	var payload map[string]string
+	suite.Require().NoError(json.Unmarshal(sent[0].Message.Payload, &payload))
+	assert.Equal(suite.T(), "Time for Tablets", payload["title"])
+	assert.Equal(suite.T(), "/today", payload["url"])
+
+	// The subscription the push service forgot is deleted, and each
+	// notification is pushed once
+	subscriptions, err := suite.env.Store.PushSubscriptions.ListByUser(context.Background(), suite.user.ID)
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), subscriptions, 1)
+	pushed, err = suite.env.Handler.ProcessPush(context.Background())
+	suite.Require().NoError(err)
+	assert.Zero(suite.T(), pushed)
+
+	// Failed pushes are retried for a while, then left to the app
+	suite.push.SetSendError("https://push.example.com/tablet", errors.New("push service unavailable"))
+	suite.now = suite.at(9, 30)
+	_, err = suite.env.Handler.ProcessCheckIns(context.Background())
+	suite.Require().NoError(err)
+	_, err = suite.env.Handler.ProcessPush(context.Background())
+	assert.ErrorContains(suite.T(), err, "push service unavailable")
+	suite.push.SetSendError("https://push.example.com/tablet", nil)
+	pushed, err = suite.env.Handler.ProcessPush(context.Background())
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 1, pushed)
+
+	suite.push.SetSendError("https://push.example.com/tablet", errors.New("push service unavailable"))
+	old := models.Notification{
+		UserID: suite.user.ID, Type: models.NotificationReminder, Title: "Old",
+		InApp: true, PushPending: true, CreatedAt: suite.now.Add(-2 * time.Hour),
+	}
+	suite.Require().NoError(suite.env.Store.Notifications.Create(context.Background(), &old))
+	pushed, err = suite.env.Handler.ProcessPush(context.Background())
+	suite.Require().NoError(err)
+	assert.Zero(suite.T(), pushed)
+
+	// Without push, notifications are not queued for it
+	suite.setPreference(models.NotificationRoutineDue, models.ChannelPush, false)
+	suite.push.SetSendError("https://push.example.com/tablet", nil)
+	suite.now = suite.at(9, 0).AddDate(0, 0, 1)
+	_, err = suite.env.Handler.ProcessCheckIns(context.Background())
+	suite.Require().NoError(err)
+	pushed, err = suite.env.Handler.ProcessPush(context.Background())
+	suite.Require().NoError(err)
+	assert.Zero(suite.T(), pushed)
+}
+
+func (suite *NotificationTestSuite) TestProcessPushTriesFailuresOncePerRun() {
+	ctx := context.Background()
+	suite.subscribe("https://push.example.com/down")
+	suite.push.SetSendError("https://push.example.com/down", errors.New("push service unavailable"))
+	failing := suite.seed("Failing", suite.now.Add(-time.Minute))
+	failing.PushPending = true
+	suite.Require().NoError(suite.env.Store.Notifications.Update(ctx, &failing))
+
+	// Enough newer notifications that the run takes a second batch
+	other := models.User{Email: "other@example.com", Password: "x", EmailConfirmed: true}
+	suite.Require().NoError(suite.env.Store.Users.Create(ctx, &other))
+	suite.Require().NoError(suite.env.Store.PushSubscriptions.Save(ctx, &models.PushSubscription{
+		UserID: other.ID, Endpoint: "https://push.example.com/other", P256dh: "a", Auth: "a",
+	}))
+	for i := range 50 {
+		suite.Require().NoError(suite.env.Store.Notifications.Create(ctx, &models.Notification{
+			UserID: other.ID, Type: models.NotificationReminder, Title: "Reminder", Data: map[string]string{},
+			InApp: true, PushPending: true, CreatedAt: suite.now.Add(time.Duration(i) * time.Second),
+		}))
+	}
+
+	pushed, err := suite.env.Handler.ProcessPush(ctx)
+	assert.ErrorContains(suite.T(), err, "push service unavailable")
+	assert.Equal(suite.T(), 50, pushed)
+	assert.Equal(suite.T(), 1, suite.push.Attempts("https://push.example.com/down"))
+
+	// It is tried again on the next run
+	_, err = suite.env.Handler.ProcessPush(ctx)
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), 2, suite.push.Attempts("https://push.example.com/down"))
+}
+
+func (suite *NotificationTestSuite) TestPushNotConfigured() {
+	env := testutils.NewTestEnv()
+	router := gin.New()
+	router.Use(testutils.OpenAPIValidator(suite.T()), apierror.Middleware())
+	router.GET("/notifications/push-key", env.Handler.GetPushKey)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/notifications/push-key", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(suite.T(), http.StatusInternalServerError, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "push_not_configured")
+
+	pushed, err := env.Handler.ProcessPush(context.Background())
+	suite.Require().NoError(err)
+	assert.Zero(suite.T(), pushed)
+}
+
+// event is one Server-Sent Event
+type event struct {
+	id, name, data string
+}
+
+// readEvents parses events from a stream until it ends
+func readEvents(body *bufio.Reader, events chan<- event) {
+	defer close(events)
+	var current event
+	for {
+		line, err := body.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if current.name != "" {
+				events <- current
+			}
+			current = event{}
+		case strings.HasPrefix(line, "id: "):
+			current.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			current.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			current.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func (suite *NotificationTestSuite) openStream(server *httptest.Server, lastEventID string) <-chan event {
+	req, _ := http.NewRequest("GET", server.URL+"/notifications/stream", nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := server.Client().Do(req)
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal(suite.T(), "text/event-stream", resp.Header.Get("Content-Type"))
+	suite.T().Cleanup(func() { resp.Body.Close() })
+	events := make(chan event)
+	go readEvents(bufio.NewReader(resp.Body), events)
+	return events
+}
+
+func (suite *NotificationTestSuite) next(events <-chan event) event {
+	select {
+	case e, ok := <-events:
+		suite.Require().True(ok, "stream ended")
+		return e
+	case <-time.After(5 * time.Second):
+		suite.FailNow("no event")
+		return event{}
+	}
+}
+
+func (suite *NotificationTestSuite) TestStream() {
+	server := httptest.NewServer(suite.router)
+	defer server.Close()
+
+	earlier := suite.seed("Earlier", suite.now.Add(-time.Minute))
+	events := suite.openStream(server, "")
+	first := suite.next(events)
+	assert.Equal(suite.T(), "unread", first.name)
+	assert.JSONEq(suite.T(), `{"unread":1}`, first.data)
+
+	// Notifications the client already had are not sent again
+	created := suite.seed("Time to reach out to Tom", suite.now.Add(time.Second))
+	notification := suite.next(events)
+	assert.Equal(suite.T(), "notification", notification.name)
+	assert.Equal(suite.T(), created.ID.String(), notification.id)
+	var sent handlers.NotificationResponse
+	suite.Require().NoError(json.Unmarshal([]byte(notification.data), &sent))
+	assert.Equal(suite.T(), "Time to reach out to Tom", sent.Title)
+	unread := suite.next(events)
+	assert.Equal(suite.T(), "unread", unread.name)
+	assert.JSONEq(suite.T(), `{"unread":2}`, unread.data)
+
+	// Reading changes the count
+	suite.Require().Equal(http.StatusOK, suite.request("POST", "/notifications/read-all", nil).Code)
+	unread = suite.next(events)
+	assert.JSONEq(suite.T(), `{"unread":0}`, unread.data)
+
+	// A reconnecting client gets what came after the last event it saw
+	missed := suite.seed("Missed", suite.now.Add(2*time.Second))
+	resumed := suite.openStream(server, earlier.ID.String())
+	suite.next(resumed)
+	assert.Equal(suite.T(), created.ID.String(), suite.next(resumed).id)
+	assert.Equal(suite.T(), missed.ID.String(), suite.next(resumed).id)
+
+	// Closing the streams ends them
+	suite.env.Handler.CloseStreams()
+	for range events {
+	}
+	for range resumed {
+	}
+}
+
+func TestNotificationTestSuite(t *testing.T) {
+	suite.Run(t, new(NotificationTestSuite))
+}
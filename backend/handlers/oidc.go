@@ -0,0 +1,357 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/logging"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/oidc"
+	"github.com/muneerlalji/Luma/repository"
+	"github.com/muneerlalji/Luma/utils"
+)
+
+// oidcStateTTL bounds how long a user may take at the identity provider
+const oidcStateTTL = 10 * time.Minute
+
+// GetOIDCProviders lists the identity providers users can sign in with
+func (h *Handler) GetOIDCProviders(c *gin.Context) {
+	providers := make([]gin.H, 0)
+	for _, config := range h.oidc.Configs() {
+		providers = append(providers, gin.H{"name": config.Name, "displayName": config.DisplayName})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"providers": providers})
+}
+
+// beginOIDC stores the PKCE verifier and nonce for a new sign-in attempt and
+// responds with the provider URL to send the user to
+func (h *Handler) beginOIDC(c *gin.Context, linkUserID *uuid.UUID) {
+	provider, err := h.oidc.Provider(c.Request.Context(), c.Param("provider"))
+	if errors.Is(err, oidc.ErrUnknownProvider) {
+		apierror.Abort(c, errUnknownProvider)
+		return
+	}
+	if err != nil {
+		apierror.Abort(c, errProviderUnavailable.WithCause(err))
+		return
+	}
+
+	state, err := oidc.RandomString(32)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to start sign-in", err))
+		return
+	}
+	nonce, err := oidc.RandomString(32)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to start sign-in", err))
+		return
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to start sign-in", err))
+		return
+	}
+
+	loginState := models.OIDCLoginState{
+		StateHash:    utils.HashToken(state),
+		Provider:     provider.Config().Name,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		LinkUserID:   linkUserID,
+		ExpiresAt:    h.now().Add(oidcStateTTL),
+	}
+	if err := h.store.LoginStates.Create(c, &loginState); err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to start sign-in", err))
+		return
+	}
+
+	// Opportunistically drop abandoned attempts
+	h.store.LoginStates.DeleteExpired(c, h.now())
+
+	c.JSON(http.StatusOK, gin.H{"authorizationUrl": provider.AuthCodeURL(state, nonce, challenge)})
+}
+
+// StartOIDCLogin begins signing in with an identity provider
+func (h *Handler) StartOIDCLogin(c *gin.Context) {
+	h.beginOIDC(c, nil)
+}
+
+// StartOIDCLink begins linking an identity provider account to the signed-in user
+func (h *Handler) StartOIDCLink(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	h.beginOIDC(c, &userUUID)
+}
+
+// OIDCCallback completes a sign-in or link once the provider redirects back
+// to the frontend with an authorization code
+func (h *Handler) OIDCCallback(c *gin.Context) {
+	var req struct {
+		Code  string `json:"code" binding:"required"`
+		State string `json:"state" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, apierror.Bind(err))
+		return
+	}
+
+	providerName := c.Param("provider")
+
+	// Consume the state so each authorization response is used only once
+	loginState, err := h.store.LoginStates.Consume(c, utils.HashToken(req.State), providerName, h.now())
+	if err != nil {
+		apierror.Abort(c, errInvalidSignInState)
+		return
+	}
+
+	provider, err := h.oidc.Provider(c.Request.Context(), providerName)
+	if err != nil {
+		apierror.Abort(c, errProviderUnavailable.WithCause(err))
+		return
+	}
+
+	claims, err := provider.Exchange(c.Request.Context(), req.Code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		logging.FromContext(c).Warn("OIDC code exchange failed", "error", err)
+		apierror.Abort(c, errProviderSignIn)
+		return
+	}
+
+	if loginState.LinkUserID != nil {
+		h.linkIdentity(c, *loginState.LinkUserID, providerName, claims)
+		return
+	}
+
+	user, apiErr := h.userForIdentity(c, providerName, claims)
+	if apiErr != nil {
+		apierror.Abort(c, apiErr)
+		return
+	}
+
+	token, err := utils.GenerateToken(h.config.JWTSecret, user.ID, user.Email)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to generate token", err))
+		return
+	}
+
+	userResponse := models.UserResponse{
+		ID:          user.ID,
+		Email:       user.Email,
+		DisplayName: user.DisplayName,
+		CreatedAt:   user.CreatedAt,
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":  userResponse,
+		"token": token,
+	})
+}
+
+// linkIdentity attaches the provider account to an already signed-in user
+func (h *Handler) linkIdentity(c *gin.Context, userID uuid.UUID, providerName string, claims *oidc.Claims) {
+	existing, err := h.store.Identities.GetByProviderSubject(c, providerName, claims.Subject)
+	if err == nil {
+		if existing.UserID != userID {
+			apierror.Abort(c, errIdentityLinked)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Account already linked"})
+		return
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		apierror.Abort(c, apierror.Internal("Database error", err))
+		return
+	}
+
+	identity := models.UserIdentity{
+		UserID:   userID,
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+	if err := h.store.Identities.Create(c, &identity); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			apierror.Abort(c, errIdentityLinked)
+			return
+		}
+		apierror.Abort(c, apierror.Internal("Failed to link account", nil))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account linked successfully"})
+}
+
+// userForIdentity finds the user for a provider account, linking it to an
+// existing account with the same verified email or creating a new user.
+// When no user can be signed in it returns the error to respond with.
+func (h *Handler) userForIdentity(ctx context.Context, providerName string, claims *oidc.Claims) (*models.User, *apierror.Error) {
+	identity, err := h.store.Identities.GetByProviderSubject(ctx, providerName, claims.Subject)
+	if err == nil {
+		user, err := h.store.Users.Get(ctx, identity.UserID)
+		if err != nil {
+			return nil, apierror.Internal("Database error", err)
+		}
+		return user, nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return nil, apierror.Internal("Database error", err)
+	}
+
+	// Matching on email is only safe when the provider vouches for it
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, errProviderEmailUnverified
+	}
+
+	var user *models.User
+	err = h.store.Transaction(ctx, func(tx *repository.Store) error {
+		var err error
+		user, err = tx.Users.GetByEmail(ctx, claims.Email)
+		switch {
+		case err == nil && !user.EmailConfirmed:
+			// Someone registered this address without confirming it. The provider
+			// has now proven who owns it, so drop the unverified password rather
+			// than let whoever chose it into the account.
+			user.Password = ""
+			user.EmailConfirmed = true
+			user.ConfirmationToken = ""
+			user.ConfirmationTokenExpiry = nil
+			if err := tx.Users.Update(ctx, user); err != nil {
+				return err
+			}
+		case errors.Is(err, repository.ErrNotFound):
+			user = &models.User{
+				Email:          claims.Email,
+				DisplayName:    oidcDisplayName(claims),
+				EmailConfirmed: true,
+			}
+			if err := tx.Users.Create(ctx, user); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		}
+
+		return tx.Identities.Create(ctx, &models.UserIdentity{
+			UserID:   user.ID,
+			Provider: providerName,
+			Subject:  claims.Subject,
+			Email:    claims.Email,
+		})
+	})
+	if err != nil {
+		return nil, apierror.Internal("Failed to sign in", err)
+	}
+
+	return user, nil
+}
+
+// oidcDisplayName picks a display name for users created from a provider account
+func oidcDisplayName(claims *oidc.Claims) string {
+	if claims.Name != "" {
+		return claims.Name
+	}
+	return strings.SplitN(claims.Email, "@", 2)[0]
+}
+
+// GetIdentities lists the identity provider accounts linked to the user
+func (h *Handler) GetIdentities(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	identities, err := h.store.Identities.ListByUser(c, userUUID)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to get linked accounts", err))
+		return
+	}
+
+	responses := make([]models.UserIdentityResponse, 0, len(identities))
+	for _, identity := range identities {
+		responses = append(responses, models.UserIdentityResponse{
+			ID:        identity.ID,
+			Provider:  identity.Provider,
+			Email:     identity.Email,
+			CreatedAt: identity.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"identities": responses})
+}
+
+// DeleteIdentity unlinks an identity provider account, refusing to remove
+// the last way a user without a password can sign in
+func (h *Handler) DeleteIdentity(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	identityID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Abort(c, errInvalidIdentityID)
+		return
+	}
+
+	user, err := h.store.Users.Get(c, userUUID)
+	if err != nil {
+		apierror.Abort(c, errUserNotFound)
+		return
+	}
+
+	identity, err := h.store.Identities.GetForUser(c, identityID, userUUID)
+	if err != nil {
+		apierror.Abort(c, errIdentityNotFound)
+		return
+	}
+
+	if user.Password == "" {
+		count, err := h.store.Identities.CountByUser(c, userUUID)
+		if err != nil {
+			apierror.Abort(c, apierror.Internal("Database error", err))
+			return
+		}
+		if count <= 1 {
+			apierror.Abort(c, errLastSignInMethod)
+			return
+		}
+	}
+
+	if err := h.store.Identities.Delete(c, identity.ID); err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to unlink account", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlinked successfully"})
+}
@@ -0,0 +1,261 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/handlers"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/oidc"
+	"github.com/muneerlalji/Luma/repository"
+	"github.com/muneerlalji/Luma/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type OIDCTestSuite struct {
+	suite.Suite
+	router *gin.Engine
+	store  *repository.Store
+	mock   *testutils.OIDCMock
+	user   models.User
+}
+
+func (suite *OIDCTestSuite) SetupSuite() {
+	gin.SetMode(gin.TestMode)
+
+	suite.mock = testutils.NewOIDCMock()
+}
+
+func (suite *OIDCTestSuite) SetupTest() {
+	env := testutils.NewTestEnv(func(d *handlers.Deps) {
+		d.OIDC = oidc.NewRegistry(nil, suite.mock.Config("mock", "http://localhost:3000/oidc/callback"))
+	})
+	suite.store = env.Store
+	h := env.Handler
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	suite.user = models.User{
+		Email:          "existing@example.com",
+		Password:       string(hashedPassword),
+		DisplayName:    "Existing User",
+		EmailConfirmed: true,
+	}
+	suite.store.Users.Create(context.Background(), &suite.user)
+
+	suite.router = gin.Default()
+	suite.router.Use(testutils.OpenAPIValidator(suite.T()), apierror.Middleware())
+
+	auth := suite.router.Group("/auth")
+	{
+		auth.GET("/oidc/providers", h.GetOIDCProviders)
+		auth.GET("/oidc/:provider/start", h.StartOIDCLogin)
+		auth.POST("/oidc/:provider/callback", h.OIDCCallback)
+	}
+
+	protected := suite.router.Group("/")
+	protected.Use(func(c *gin.Context) {
+		c.Set("user_id", suite.user.ID)
+		c.Next()
+	})
+	{
+		protected.GET("/profile/identities", h.GetIdentities)
+		protected.POST("/profile/identities/:provider", h.StartOIDCLink)
+		protected.DELETE("/profile/identities/:id", h.DeleteIdentity)
+	}
+}
+
+func (suite *OIDCTestSuite) TearDownSuite() {
+	suite.mock.Close()
+}
+
+func (suite *OIDCTestSuite) request(method, path string, data interface{}) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	if data != nil {
+		json.NewEncoder(&body).Encode(data)
+	}
+	req, _ := http.NewRequest(method, path, &body)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+// completeFlow starts a flow at the given endpoint, lets the mock provider
+// approve it and posts the result to the callback
+func (suite *OIDCTestSuite) completeFlow(method, startPath string) *httptest.ResponseRecorder {
+	w := suite.request(method, startPath, nil)
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+
+	var start map[string]string
+	json.Unmarshal(w.Body.Bytes(), &start)
+
+	code, state, err := suite.mock.Authorize(start["authorizationUrl"])
+	suite.Require().NoError(err)
+
+	return suite.request("POST", "/auth/oidc/mock/callback", gin.H{"code": code, "state": state})
+}
+
+func (suite *OIDCTestSuite) TestGetProviders() {
+	w := suite.request("GET", "/auth/oidc/providers", nil)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"name":"mock"`)
+	assert.Contains(suite.T(), w.Body.String(), `"displayName":"Mock Provider"`)
+}
+
+func (suite *OIDCTestSuite) TestStart_UnknownProvider() {
+	w := suite.request("GET", "/auth/oidc/unknown/start", nil)
+
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+}
+
+func (suite *OIDCTestSuite) TestLogin_CreatesUser() {
+	suite.mock.SetUser(testutils.OIDCUser{Subject: "new-subject", Email: "new@example.com", EmailVerified: true, Name: "New Person"})
+
+	w := suite.completeFlow("GET", "/auth/oidc/mock/start")
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(suite.T(), w.Body.String(), `"token"`)
+
+	user, err := suite.store.Users.GetByEmail(context.Background(), "new@example.com")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "New Person", user.DisplayName)
+	assert.Empty(suite.T(), user.Password)
+	assert.True(suite.T(), user.EmailConfirmed)
+
+	_, err = suite.store.Identities.GetByProviderSubject(context.Background(), "mock", "new-subject")
+	assert.NoError(suite.T(), err)
+	count, _ := suite.store.Identities.CountByUser(context.Background(), user.ID)
+	assert.Equal(suite.T(), int64(1), count)
+
+	// Signing in again reuses the identity
+	w = suite.completeFlow("GET", "/auth/oidc/mock/start")
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	again, err := suite.store.Users.GetByEmail(context.Background(), "new@example.com")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), user.ID, again.ID)
+}
+
+func (suite *OIDCTestSuite) TestLogin_LinksExistingUserByVerifiedEmail() {
+	suite.mock.SetUser(testutils.OIDCUser{Subject: "existing-subject", Email: "existing@example.com", EmailVerified: true})
+
+	w := suite.completeFlow("GET", "/auth/oidc/mock/start")
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(suite.T(), w.Body.String(), suite.user.ID.String())
+
+	// The existing password keeps working
+	user, _ := suite.store.Users.Get(context.Background(), suite.user.ID)
+	assert.Equal(suite.T(), suite.user.Password, user.Password)
+}
+
+func (suite *OIDCTestSuite) TestLogin_UnverifiedEmailRejected() {
+	suite.mock.SetUser(testutils.OIDCUser{Subject: "unverified-subject", Email: "existing@example.com", EmailVerified: false})
+
+	w := suite.completeFlow("GET", "/auth/oidc/mock/start")
+
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+	count, _ := suite.store.Identities.CountByUser(context.Background(), suite.user.ID)
+	assert.Equal(suite.T(), int64(0), count)
+}
+
+func (suite *OIDCTestSuite) TestLogin_ClaimsUnconfirmedAccount() {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("squatter-password"), bcrypt.DefaultCost)
+	squatted := models.User{
+		Email:             "claimed@example.com",
+		Password:          string(hashedPassword),
+		DisplayName:       "Squatter",
+		ConfirmationToken: "pending",
+	}
+	suite.store.Users.Create(context.Background(), &squatted)
+
+	suite.mock.SetUser(testutils.OIDCUser{Subject: "owner-subject", Email: "claimed@example.com", EmailVerified: true})
+	w := suite.completeFlow("GET", "/auth/oidc/mock/start")
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+
+	user, _ := suite.store.Users.Get(context.Background(), squatted.ID)
+	assert.Empty(suite.T(), user.Password)
+	assert.True(suite.T(), user.EmailConfirmed)
+	assert.Empty(suite.T(), user.ConfirmationToken)
+}
+
+func (suite *OIDCTestSuite) TestCallback_StateSingleUse() {
+	w := suite.request("GET", "/auth/oidc/mock/start", nil)
+	var start map[string]string
+	json.Unmarshal(w.Body.Bytes(), &start)
+
+	code, state, err := suite.mock.Authorize(start["authorizationUrl"])
+	suite.Require().NoError(err)
+
+	w = suite.request("POST", "/auth/oidc/mock/callback", gin.H{"code": code, "state": state})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	w = suite.request("POST", "/auth/oidc/mock/callback", gin.H{"code": code, "state": state})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+func (suite *OIDCTestSuite) TestCallback_InvalidState() {
+	w := suite.request("POST", "/auth/oidc/mock/callback", gin.H{"code": "code", "state": "forged"})
+
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+func (suite *OIDCTestSuite) TestLink() {
+	suite.mock.SetUser(testutils.OIDCUser{Subject: "link-subject", Email: "other@example.com", EmailVerified: true})
+
+	w := suite.completeFlow("POST", "/profile/identities/mock")
+	assert.Equal(suite.T(), http.StatusOK, w.Code, w.Body.String())
+
+	w = suite.request("GET", "/profile/identities", nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `"provider":"mock"`)
+	assert.Contains(suite.T(), w.Body.String(), "other@example.com")
+}
+
+func (suite *OIDCTestSuite) TestLink_AlreadyLinkedToAnotherUser() {
+	other := models.User{Email: "owner@example.com", DisplayName: "Owner", EmailConfirmed: true}
+	suite.store.Users.Create(context.Background(), &other)
+	suite.store.Identities.Create(context.Background(), &models.UserIdentity{UserID: other.ID, Provider: "mock", Subject: "taken-subject"})
+
+	suite.mock.SetUser(testutils.OIDCUser{Subject: "taken-subject", Email: "owner@example.com", EmailVerified: true})
+	w := suite.completeFlow("POST", "/profile/identities/mock")
+
+	assert.Equal(suite.T(), http.StatusConflict, w.Code)
+}
+
+func (suite *OIDCTestSuite) TestDeleteIdentity() {
+	identity := models.UserIdentity{UserID: suite.user.ID, Provider: "mock", Subject: "delete-subject"}
+	suite.store.Identities.Create(context.Background(), &identity)
+
+	w := suite.request("DELETE", "/profile/identities/"+identity.ID.String(), nil)
+
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	_, err := suite.store.Identities.GetForUser(context.Background(), identity.ID, suite.user.ID)
+	assert.ErrorIs(suite.T(), err, repository.ErrNotFound)
+}
+
+func (suite *OIDCTestSuite) TestDeleteIdentity_LastSignInMethod() {
+	suite.user.Password = ""
+	suite.store.Users.Update(context.Background(), &suite.user)
+	identity := models.UserIdentity{UserID: suite.user.ID, Provider: "mock", Subject: "only-subject"}
+	suite.store.Identities.Create(context.Background(), &identity)
+
+	w := suite.request("DELETE", "/profile/identities/"+identity.ID.String(), nil)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+func TestOIDCTestSuite(t *testing.T) {
+	suite.Run(t, new(OIDCTestSuite))
+}
@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/muneerlalji/Luma/db"
+	"github.com/muneerlalji/Luma/apierror"
 	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/repository"
 )
 
 type CreatePersonRequest struct {
@@ -17,6 +20,9 @@ type CreatePersonRequest struct {
 	Relationship string     `json:"relationship" binding:"required"`
 	Notes        string     `json:"notes"`
 	PhotoID      *uuid.UUID `json:"photoId,omitempty"`
+	// ContactEveryDays asks for a reminder to reach out every so many days
+	ContactEveryDays int `json:"contactEveryDays" binding:"min=0,max=365"`
+	PersonDatesRequest
 }
 
 type PersonResponse struct {
@@ -29,32 +35,115 @@ type PersonResponse struct {
 	Notes        string     `json:"notes"`
 	PhotoID      *uuid.UUID `json:"photoId,omitempty"`
 	PhotoURL     *string    `json:"photoUrl,omitempty"`
+
+	ContactEveryDays int        `json:"contactEveryDays"`
+	NextReminderAt   *time.Time `json:"nextReminderAt,omitempty"`
+	LastContactedAt  *time.Time `json:"lastContactedAt,omitempty"`
+
+	Birthday    *string `json:"birthday,omitempty"`
+	Anniversary *string `json:"anniversary,omitempty"`
+	DiedOn      *string `json:"diedOn,omitempty"`
+}
+
+// PersonDatesRequest holds a person's dates, written as YYYY-MM-DD. Setting
+// a date of death stops reminders to reach out to them.
+type PersonDatesRequest struct {
+	Birthday    *string `json:"birthday,omitempty" binding:"omitempty,datetime=2006-01-02"`
+	Anniversary *string `json:"anniversary,omitempty" binding:"omitempty,datetime=2006-01-02"`
+	DiedOn      *string `json:"diedOn,omitempty" binding:"omitempty,datetime=2006-01-02"`
+}
+
+// apply sets the dates on person or returns an error naming a date after
+// today
+func (req *PersonDatesRequest) apply(person *models.Person, now time.Time) *apierror.Error {
+	dates := []struct {
+		field string
+		value *string
+		date  **time.Time
+	}{
+		{"birthday", req.Birthday, &person.Birthday},
+		{"anniversary", req.Anniversary, &person.Anniversary},
+		{"diedOn", req.DiedOn, &person.DiedOn},
+	}
+	var err *apierror.Error
+	for _, d := range dates {
+		date := parseDate(d.value)
+		if date != nil && date.After(calendarDay(now)) {
+			if err == nil {
+				err = errDateInFuture
+			}
+			err = err.WithField(d.field, fieldNotFuture)
+			continue
+		}
+		*d.date = date
+	}
+	return err
+}
+
+// ContactCadenceRequest sets how often to be reminded to reach out to a
+// person; zero turns reminders off
+type ContactCadenceRequest struct {
+	EveryDays *int `json:"everyDays" binding:"required,min=0,max=365"`
+}
+
+func newPersonResponse(person *models.Person) PersonResponse {
+	return PersonResponse{
+		ID:               person.ID,
+		FirstName:        person.FirstName,
+		LastName:         person.LastName,
+		Email:            person.Email,
+		Phone:            person.Phone,
+		Relationship:     person.Relationship,
+		Notes:            person.Notes,
+		PhotoID:          person.PhotoID,
+		ContactEveryDays: person.ContactEveryDays,
+		NextReminderAt:   person.NextReminderAt,
+		LastContactedAt:  person.LastContactedAt,
+		Birthday:         formatDate(person.Birthday),
+		Anniversary:      formatDate(person.Anniversary),
+		DiedOn:           formatDate(person.DiedOn),
+	}
+}
+
+// scheduleNextReminder sets when the next reminder to reach out to person
+// opens: one cadence after the last contact, or after now if there was none.
+// There are none for people who have passed away.
+func scheduleNextReminder(person *models.Person, now time.Time) {
+	if person.ContactEveryDays <= 0 || person.DiedOn != nil {
+		person.NextReminderAt = nil
+		return
+	}
+	from := now
+	if person.LastContactedAt != nil {
+		from = *person.LastContactedAt
+	}
+	next := from.AddDate(0, 0, person.ContactEveryDays)
+	person.NextReminderAt = &next
 }
 
-func CreatePerson(c *gin.Context) {
+func (h *Handler) CreatePerson(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		apierror.Abort(c, apierror.ErrUnauthenticated)
 		return
 	}
 
 	userUUID, ok := userID.(uuid.UUID)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		apierror.Abort(c, errInvalidUserID)
 		return
 	}
 
 	var req CreatePersonRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Abort(c, apierror.Bind(err))
 		return
 	}
 
 	// Validate photo ownership if provided
 	if req.PhotoID != nil {
-		var photo models.Photo
-		if err := db.DB.Where("id = ? AND user_id = ?", req.PhotoID, userUUID).First(&photo).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Photo not found or not owned by user"})
+		if _, err := h.store.Photos.GetForUser(c, *req.PhotoID, userUUID); err != nil {
+			apierror.Abort(c, errPhotoReference)
 			return
 		}
 	}
@@ -68,60 +157,152 @@ func CreatePerson(c *gin.Context) {
 		Relationship: req.Relationship,
 		Notes:        req.Notes,
 		PhotoID:      req.PhotoID,
+
+		ContactEveryDays: req.ContactEveryDays,
+	}
+	if err := req.PersonDatesRequest.apply(&person, h.now()); err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+	scheduleNextReminder(&person, h.now())
+
+	if err := h.store.People.Create(c, &person); err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to create person", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, newPersonResponse(&person))
+}
+
+func (h *Handler) GetPeople(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
 	}
 
-	if err := db.DB.Create(&person).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create person"})
+	people, err := h.store.People.ListByUser(c, userUUID)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to get people", err))
 		return
 	}
 
-	response := PersonResponse{
-		ID:           person.ID,
-		FirstName:    person.FirstName,
-		LastName:     person.LastName,
-		Email:        person.Email,
-		Phone:        person.Phone,
-		Relationship: person.Relationship,
-		Notes:        person.Notes,
-		PhotoID:      person.PhotoID,
+	responses := make([]PersonResponse, 0, len(people))
+	for i := range people {
+		responses = append(responses, newPersonResponse(&people[i]))
 	}
 
-	c.JSON(http.StatusCreated, response)
+	c.JSON(http.StatusOK, responses)
 }
 
-func GetPeople(c *gin.Context) {
+// SetContactCadence sets how often the user is reminded to reach out to a
+// person and reschedules the next reminder
+func (h *Handler) SetContactCadence(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		apierror.Abort(c, apierror.ErrUnauthenticated)
 		return
 	}
 
 	userUUID, ok := userID.(uuid.UUID)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		apierror.Abort(c, errInvalidUserID)
 		return
 	}
 
-	var people []models.Person
-	if err := db.DB.Preload("Photo").Where("user_id = ?", userUUID).Find(&people).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get people"})
+	personID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Abort(c, errInvalidPersonID)
 		return
 	}
 
-	var responses []PersonResponse
-	for _, person := range people {
-		response := PersonResponse{
-			ID:           person.ID,
-			FirstName:    person.FirstName,
-			LastName:     person.LastName,
-			Email:        person.Email,
-			Phone:        person.Phone,
-			Relationship: person.Relationship,
-			Notes:        person.Notes,
-			PhotoID:      person.PhotoID,
+	var req ContactCadenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, apierror.Bind(err))
+		return
+	}
+
+	person, err := h.store.People.GetForUser(c, personID, userUUID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Abort(c, errPersonMissing)
+			return
 		}
-		responses = append(responses, response)
+		apierror.Abort(c, apierror.Internal("Failed to get person", err))
+		return
 	}
 
-	c.JSON(http.StatusOK, responses)
+	person.ContactEveryDays = *req.EveryDays
+	scheduleNextReminder(person, h.now())
+	if err := h.store.People.Update(c, person); err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to update person", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, newPersonResponse(person))
+}
+
+// SetPersonDates replaces a person's birthday, anniversary and date of death;
+// dates left out are cleared
+func (h *Handler) SetPersonDates(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	personID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Abort(c, errInvalidPersonID)
+		return
+	}
+
+	var req PersonDatesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, apierror.Bind(err))
+		return
+	}
+
+	var person *models.Person
+	err = h.store.Transaction(c, func(tx *repository.Store) error {
+		person, err = tx.People.GetForUser(c, personID, userUUID)
+		if err != nil {
+			return err
+		}
+		now := h.now()
+		if err := req.apply(person, now); err != nil {
+			return err
+		}
+		scheduleNextReminder(person, now)
+		if err := tx.People.Update(c, person); err != nil {
+			return err
+		}
+		if person.DiedOn == nil {
+			return nil
+		}
+		return tx.Reminders.ResolveOpen(c, person.ID, models.ReminderDismissed, now)
+	})
+	var apiErr *apierror.Error
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		apierror.Abort(c, errPersonMissing)
+	case errors.As(err, &apiErr):
+		apierror.Abort(c, apiErr)
+	case err != nil:
+		apierror.Abort(c, apierror.Internal("Failed to update person", err))
+	default:
+		c.JSON(http.StatusOK, newPersonResponse(person))
+	}
 }
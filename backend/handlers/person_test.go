@@ -2,39 +2,39 @@ package handlers_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/muneerlalji/Luma/apierror"
 	"github.com/muneerlalji/Luma/handlers"
 	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/repository"
 	"github.com/muneerlalji/Luma/testutils"
 	"github.com/muneerlalji/Luma/utils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
-	"gorm.io/gorm"
 )
 
 type PersonTestSuite struct {
 	suite.Suite
 	router *gin.Engine
-	db     *gorm.DB
+	store  *repository.Store
 	user   models.User
 	token  string
 }
 
 func (suite *PersonTestSuite) SetupSuite() {
 	gin.SetMode(gin.TestMode)
-	suite.db = testutils.SetupTestDB()
-
-	// Auto-migrate test database
-	suite.db.AutoMigrate(&models.User{}, &models.Person{})
 }
 
 func (suite *PersonTestSuite) SetupTest() {
-	testutils.CleanupTestDB(suite.db)
+	env := testutils.NewTestEnv()
+	suite.store = env.Store
+	h := env.Handler
 
 	// Create a test user
 	suite.user = models.User{
@@ -43,9 +43,9 @@ func (suite *PersonTestSuite) SetupTest() {
 		DisplayName:    "Test User",
 		EmailConfirmed: true,
 	}
-	suite.db.Create(&suite.user)
+	suite.store.Users.Create(context.Background(), &suite.user)
 
-	token, err := utils.GenerateToken(suite.user.ID, suite.user.Email)
+	token, err := utils.GenerateToken(testutils.JWTSecret, suite.user.ID, suite.user.Email)
 	if err != nil {
 		suite.T().Fatalf("Failed to generate test token: %v", err)
 	}
@@ -53,6 +53,7 @@ func (suite *PersonTestSuite) SetupTest() {
 
 	// Setup router
 	suite.router = gin.Default()
+	suite.router.Use(testutils.OpenAPIValidator(suite.T()), apierror.Middleware())
 
 	// Setup protected routes
 	protected := suite.router.Group("/")
@@ -62,16 +63,11 @@ func (suite *PersonTestSuite) SetupTest() {
 		c.Next()
 	})
 	{
-		protected.POST("/people", handlers.CreatePerson)
-		protected.GET("/people", handlers.GetPeople)
+		protected.POST("/people", h.CreatePerson)
+		protected.GET("/people", h.GetPeople)
 	}
 }
 
-func (suite *PersonTestSuite) TearDownSuite() {
-	// Clean up test database using testutils
-	testutils.CleanupTestDB(suite.db)
-}
-
 func (suite *PersonTestSuite) TestCreatePerson_Success() {
 	personData := handlers.CreatePersonRequest{
 		FirstName:    "John",
@@ -101,9 +97,10 @@ func (suite *PersonTestSuite) TestCreatePerson_Success() {
 	assert.Equal(suite.T(), personData.Email, response.Email)
 
 	// Verify person was created in database
-	var person models.Person
-	err = suite.db.Where("email = ?", personData.Email).First(&person).Error
+	people, err := suite.store.People.ListByUser(context.Background(), suite.user.ID)
 	assert.NoError(suite.T(), err)
+	suite.Require().Len(people, 1)
+	person := people[0]
 	assert.Equal(suite.T(), personData.FirstName, person.FirstName)
 	assert.Equal(suite.T(), personData.LastName, person.LastName)
 	assert.Equal(suite.T(), personData.Email, person.Email)
@@ -152,7 +149,7 @@ func (suite *PersonTestSuite) TestGetPeople_Success() {
 	}
 
 	for _, person := range people {
-		suite.db.Create(&person)
+		suite.store.People.Create(context.Background(), &person)
 	}
 
 	req, _ := http.NewRequest("GET", "/people", nil)
@@ -196,7 +193,7 @@ func (suite *PersonTestSuite) TestGetPeople_UserIsolation() {
 		DisplayName:    "Other User",
 		EmailConfirmed: true,
 	}
-	suite.db.Create(&otherUser)
+	suite.store.Users.Create(context.Background(), &otherUser)
 
 	// Create people for both users
 	person1 := models.Person{
@@ -218,8 +215,8 @@ func (suite *PersonTestSuite) TestGetPeople_UserIsolation() {
 		UserID:       otherUser.ID,
 	}
 
-	suite.db.Create(&person1)
-	suite.db.Create(&person2)
+	suite.store.People.Create(context.Background(), &person1)
+	suite.store.People.Create(context.Background(), &person2)
 
 	// Get people for the first user
 	req, _ := http.NewRequest("GET", "/people", nil)
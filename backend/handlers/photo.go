@@ -1,76 +1,82 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
-	"os"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/muneerlalji/Luma/db"
-	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/jobs"
 )
 
+// DeletePhotoFilesJob removes the files of deleted photos from storage. It
+// is queued in the transaction that deletes the photos, so the files go
+// only once nothing refers to them.
+var DeletePhotoFilesJob = jobs.Kind[DeletePhotoFilesPayload]{Name: "delete_photo_files", MaxAttempts: 8}
+
+// DeletePhotoFilesPayload lists the storage keys of the deleted photos
+type DeletePhotoFilesPayload struct {
+	Keys []string `json:"keys"`
+}
+
+// DeletePhotoFiles runs a DeletePhotoFilesJob. Files already deleted by an
+// earlier attempt are deleted again, which does nothing.
+func (h *Handler) DeletePhotoFiles(ctx context.Context, payload DeletePhotoFilesPayload) error {
+	var errs []error
+	for _, key := range payload.Keys {
+		if err := h.storage.Delete(ctx, key); err != nil {
+			errs = append(errs, fmt.Errorf("delete %s: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // GetPhoto serves a photo from S3 with authentication
-func GetPhoto(c *gin.Context) {
+func (h *Handler) GetPhoto(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		apierror.Abort(c, apierror.ErrUnauthenticated)
 		return
 	}
 
 	userUUID, ok := userID.(uuid.UUID)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		apierror.Abort(c, errInvalidUserID)
 		return
 	}
 
 	photoID := c.Param("id")
 	if photoID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Photo ID is required"})
+		apierror.Abort(c, errPhotoIDRequired)
 		return
 	}
 
 	photoUUID, err := uuid.Parse(photoID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid photo ID format"})
-		return
-	}
-
-	var photo models.Photo
-	if err := db.DB.Where("id = ? AND user_id = ?", photoUUID, userUUID).First(&photo).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Photo not found or not owned by user"})
+		apierror.Abort(c, errInvalidPhotoID)
 		return
 	}
 
-	cfg, err := config.LoadDefaultConfig(c, config.WithRegion(os.Getenv("AWS_REGION")))
+	photo, err := h.store.Photos.GetForUser(c, photoUUID, userUUID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "AWS config error"})
+		apierror.Abort(c, errPhotoNotFound)
 		return
 	}
 
-	s3Client := s3.NewFromConfig(cfg)
-	bucket := os.Getenv("S3_BUCKET")
-
-	presignClient := s3.NewPresignClient(s3Client)
-	presignedURL, err := presignClient.PresignGetObject(c, &s3.GetObjectInput{
-		Bucket: &bucket,
-		Key:    &photo.S3Key,
-	}, func(opts *s3.PresignOptions) {
-		opts.Expires = time.Hour
-	})
-
+	presignedURL, err := h.storage.PresignGet(c.Request.Context(), photo.S3Key, time.Hour)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate photo URL"})
+		apierror.Abort(c, apierror.Internal("Failed to generate photo URL", err))
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"url":        presignedURL.URL,
+		"url":        presignedURL,
 		"filename":   photo.Filename,
 		"filetype":   photo.Filetype,
 		"uploadedAt": photo.UploadedAt,
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/mailer"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/repository"
+)
+
+// reminderBatchSize is how many rows one scheduler transaction claims
+const reminderBatchSize = 50
+
+type ReminderResponse struct {
+	ID           uuid.UUID  `json:"id"`
+	PersonID     uuid.UUID  `json:"personId"`
+	PersonName   string     `json:"personName"`
+	Phone        string     `json:"phone"`
+	Email        string     `json:"email"`
+	DueAt        time.Time  `json:"dueAt"`
+	Status       string     `json:"status"`
+	SnoozedUntil *time.Time `json:"snoozedUntil,omitempty"`
+	ResolvedAt   *time.Time `json:"resolvedAt,omitempty"`
+}
+
+// SnoozeReminderRequest hides a reminder until the given time
+type SnoozeReminderRequest struct {
+	Until time.Time `json:"until" binding:"required"`
+}
+
+func newReminderResponse(reminder *models.Reminder) ReminderResponse {
+	return ReminderResponse{
+		ID:           reminder.ID,
+		PersonID:     reminder.PersonID,
+		PersonName:   personName(&reminder.Person),
+		Phone:        reminder.Person.Phone,
+		Email:        reminder.Person.Email,
+		DueAt:        reminder.DueAt,
+		Status:       reminder.Status,
+		SnoozedUntil: reminder.SnoozedUntil,
+		ResolvedAt:   reminder.ResolvedAt,
+	}
+}
+
+func personName(person *models.Person) string {
+	return strings.TrimSpace(person.FirstName + " " + person.LastName)
+}
+
+// ProcessReminders opens a reminder for each person whose cadence is due,
+// notifying the user, and queues emails for the active reminders that have
+// not been sent yet. Rows are claimed with row locks, so several instances
+// may run it at once without opening or sending a reminder twice. It returns
+// how many reminders were queued.
+func (h *Handler) ProcessReminders(ctx context.Context) (int, error) {
+	if err := h.openDueReminders(ctx); err != nil {
+		return 0, fmt.Errorf("open reminders: %w", err)
+	}
+	return h.emailReminders(ctx)
+}
+
+// openDueReminders opens reminders for people whose next reminder is due and
+// schedules the one after it
+func (h *Handler) openDueReminders(ctx context.Context) error {
+	for {
+		var claimed int
+		err := h.store.Transaction(ctx, func(tx *repository.Store) error {
+			now := h.now()
+			people, err := tx.People.ClaimDueForReminder(ctx, now, reminderBatchSize)
+			if err != nil {
+				return err
+			}
+			claimed = len(people)
+			for i := range people {
+				person := &people[i]
+				// A reminder the user has not acted on yet stays the only one
+				open, err := tx.Reminders.HasOpen(ctx, person.ID)
+				if err != nil {
+					return err
+				}
+				if !open {
+					reminder := models.Reminder{
+						UserID:   person.UserID,
+						PersonID: person.ID,
+						DueAt:    *person.NextReminderAt,
+						Status:   models.ReminderOpen,
+					}
+					if err := tx.Reminders.Create(ctx, &reminder); err != nil {
+						return err
+					}
+					if err := h.notify(ctx, tx, reminderNotification(&reminder, person)); err != nil {
+						return err
+					}
+				}
+				next := now.AddDate(0, 0, person.ContactEveryDays)
+				person.NextReminderAt = &next
+				if err := tx.People.Update(ctx, person); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil || claimed < reminderBatchSize {
+			return err
+		}
+	}
+}
+
+// emailReminders queues emails for active reminders that have not been
+// sent, skipping users who turned reminder emails off
+func (h *Handler) emailReminders(ctx context.Context) (int, error) {
+	var queued int
+	for {
+		var claimed, queuedInBatch int
+		err := h.store.Transaction(ctx, func(tx *repository.Store) error {
+			reminders, err := tx.Reminders.ClaimUnsent(ctx, h.now(), reminderBatchSize)
+			if err != nil {
+				return err
+			}
+			claimed = len(reminders)
+			for i := range reminders {
+				reminder := &reminders[i]
+				user, err := tx.Users.Get(ctx, reminder.UserID)
+				if err != nil {
+					return err
+				}
+				settings, err := loadNotificationSettings(ctx, tx, user.ID)
+				if err != nil {
+					return err
+				}
+				// Reminders the user wants no email for are settled unsent
+				emailed := settings.enabled(models.NotificationReminder, models.ChannelEmail)
+				if emailed {
+					if err := h.queueReminderEmail(ctx, tx, user, &reminder.Person); err != nil {
+						return err
+					}
+					queuedInBatch++
+				}
+				now := h.now()
+				reminder.EmailedAt = &now
+				if err := tx.Reminders.Update(ctx, reminder); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return queued, fmt.Errorf("email reminders: %w", err)
+		}
+		queued += queuedInBatch
+		if claimed < reminderBatchSize {
+			return queued, nil
+		}
+	}
+}
+
+// queueReminderEmail queues an email nudging the user to get in touch with
+// person
+func (h *Handler) queueReminderEmail(ctx context.Context, store *repository.Store, user *models.User, person *models.Person) error {
+	data := mailer.ReminderData{
+		FirstName:  person.FirstName,
+		PersonName: personName(person),
+		Phone:      person.Phone,
+	}
+	if h.config.FrontendURL != "" {
+		data.URL = h.config.FrontendURL + "/people"
+	}
+	return h.queueEmail(ctx, store, user, user.Email, mailer.Reminder, data)
+}
+
+// reminderNotification tells the user a reminder to reach out to person is due
+func reminderNotification(reminder *models.Reminder, person *models.Person) *models.Notification {
+	body := "This is your reminder to get in touch with " + personName(person) + "."
+	if person.Phone != "" {
+		body += " You can call them on " + person.Phone + "."
+	}
+	return &models.Notification{
+		UserID: reminder.UserID,
+		Type:   models.NotificationReminder,
+		Title:  "Time to reach out to " + person.FirstName,
+		Body:   body,
+		URL:    "/people",
+		Data:   map[string]string{"reminderId": reminder.ID.String(), "personId": person.ID.String()},
+	}
+}
+
+// GetReminders returns the user's reminders that are due and not snoozed
+func (h *Handler) GetReminders(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	reminders, err := h.store.Reminders.ListActive(c, userUUID, h.now())
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to get reminders", err))
+		return
+	}
+
+	responses := make([]ReminderResponse, 0, len(reminders))
+	for i := range reminders {
+		responses = append(responses, newReminderResponse(&reminders[i]))
+	}
+	c.JSON(http.StatusOK, responses)
+}
+
+// SnoozeReminder hides a reminder until the requested time, when it shows
+// again and is emailed once more
+func (h *Handler) SnoozeReminder(c *gin.Context) {
+	var req SnoozeReminderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, apierror.Bind(err))
+		return
+	}
+	if !req.Until.After(h.now()) {
+		apierror.Abort(c, errSnoozeInPast)
+		return
+	}
+
+	h.updateReminder(c, func(tx *repository.Store, reminder *models.Reminder) error {
+		reminder.SnoozedUntil = &req.Until
+		reminder.EmailedAt = nil
+		return nil
+	})
+}
+
+// CompleteReminder records that the user got in touch with the person and
+// schedules the next reminder a cadence from now
+func (h *Handler) CompleteReminder(c *gin.Context) {
+	h.updateReminder(c, func(tx *repository.Store, reminder *models.Reminder) error {
+		now := h.now()
+		reminder.Status = models.ReminderCompleted
+		reminder.ResolvedAt = &now
+
+		person := &reminder.Person
+		person.LastContactedAt = &now
+		scheduleNextReminder(person, now)
+		return tx.People.Update(c, person)
+	})
+}
+
+// DismissReminder closes a reminder without recording a contact; the next
+// one opens on the existing schedule
+func (h *Handler) DismissReminder(c *gin.Context) {
+	h.updateReminder(c, func(tx *repository.Store, reminder *models.Reminder) error {
+		now := h.now()
+		reminder.Status = models.ReminderDismissed
+		reminder.ResolvedAt = &now
+		return nil
+	})
+}
+
+// updateReminder applies change to the open reminder named in the path and
+// saves it in one transaction
+func (h *Handler) updateReminder(c *gin.Context, change func(tx *repository.Store, reminder *models.Reminder) error) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	reminderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Abort(c, errInvalidReminderID)
+		return
+	}
+
+	var reminder *models.Reminder
+	err = h.store.Transaction(c, func(tx *repository.Store) error {
+		reminder, err = tx.Reminders.GetForUser(c, reminderID, userUUID)
+		if err != nil {
+			return err
+		}
+		if reminder.Status != models.ReminderOpen {
+			return errReminderResolved
+		}
+		if err := change(tx, reminder); err != nil {
+			return err
+		}
+		return tx.Reminders.Update(c, reminder)
+	})
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		apierror.Abort(c, errReminderNotFound)
+	case errors.Is(err, errReminderResolved):
+		apierror.Abort(c, errReminderResolved)
+	case err != nil:
+		apierror.Abort(c, apierror.Internal("Failed to update reminder", err))
+	default:
+		c.JSON(http.StatusOK, newReminderResponse(reminder))
+	}
+}
@@ -0,0 +1,257 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/handlers"
+	"github.com/muneerlalji/Luma/mailer"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ReminderTestSuite struct {
+	suite.Suite
+	env    *testutils.TestEnv
+	router *gin.Engine
+	user   models.User
+	now    time.Time
+}
+
+func (suite *ReminderTestSuite) SetupSuite() {
+	gin.SetMode(gin.TestMode)
+}
+
+func (suite *ReminderTestSuite) SetupTest() {
+	suite.now = time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC)
+	suite.env = testutils.NewTestEnv(func(deps *handlers.Deps) {
+		deps.Clock = func() time.Time { return suite.now }
+	})
+	h := suite.env.Handler
+
+	suite.user = models.User{Email: "margaret@example.com", Password: "x", DisplayName: "Margaret", EmailConfirmed: true}
+	suite.env.Store.Users.Create(context.Background(), &suite.user)
+
+	suite.router = gin.New()
+	suite.router.Use(testutils.OpenAPIValidator(suite.T()), apierror.Middleware())
+	protected := suite.router.Group("/")
+	protected.Use(func(c *gin.Context) {
+		c.Set("user_id", suite.user.ID)
+		c.Next()
+	})
+	protected.POST("/people", h.CreatePerson)
+	protected.PUT("/people/:id/contact-cadence", h.SetContactCadence)
+	protected.GET("/reminders", h.GetReminders)
+	protected.POST("/reminders/:id/snooze", h.SnoozeReminder)
+	protected.POST("/reminders/:id/complete", h.CompleteReminder)
+	protected.POST("/reminders/:id/dismiss", h.DismissReminder)
+}
+
+func (suite *ReminderTestSuite) request(method, path string, body any) *httptest.ResponseRecorder {
+	var reader bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&reader).Encode(body)
+	}
+	req, _ := http.NewRequest(method, path, &reader)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+// createPerson adds a person the user wants to contact every week
+func (suite *ReminderTestSuite) createPerson(firstName string) handlers.PersonResponse {
+	w := suite.request("POST", "/people", handlers.CreatePersonRequest{
+		FirstName: firstName, LastName: "Hughes", Email: "tom@example.com", Phone: "555-0100",
+		Relationship: "Son", ContactEveryDays: 7,
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+	var person handlers.PersonResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &person))
+	return person
+}
+
+// process runs the scheduler and sends what it queued, returning how many
+// reminder emails were queued
+func (suite *ReminderTestSuite) process() int {
+	queued, err := suite.env.Handler.ProcessReminders(context.Background())
+	suite.Require().NoError(err)
+	_, err = suite.env.SendQueuedEmails()
+	suite.Require().NoError(err)
+	return queued
+}
+
+func (suite *ReminderTestSuite) reminders() []handlers.ReminderResponse {
+	w := suite.request("GET", "/reminders", nil)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var reminders []handlers.ReminderResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &reminders))
+	return reminders
+}
+
+func (suite *ReminderTestSuite) TestCadenceOpensAndEmailsReminder() {
+	person := suite.createPerson("Tom")
+	suite.Require().NotNil(person.NextReminderAt)
+	assert.Equal(suite.T(), suite.now.AddDate(0, 0, 7), *person.NextReminderAt)
+
+	assert.Zero(suite.T(), suite.process())
+	assert.Empty(suite.T(), suite.reminders())
+
+	suite.now = suite.now.AddDate(0, 0, 7)
+	assert.Equal(suite.T(), 1, suite.process())
+
+	emails := suite.env.Email.FindEmailByRecipient("margaret@example.com")
+	suite.Require().Len(emails, 1)
+	suite.env.Email.AssertRendered(suite.T(), emails[0], mailer.Reminder, "en", mailer.ReminderData{
+		FirstName:  "Tom",
+		PersonName: "Tom Hughes",
+		Phone:      "555-0100",
+		URL:        "http://localhost:3000/people",
+	})
+
+	reminders := suite.reminders()
+	suite.Require().Len(reminders, 1)
+	assert.Equal(suite.T(), person.ID, reminders[0].PersonID)
+	assert.Equal(suite.T(), "Tom Hughes", reminders[0].PersonName)
+	assert.Equal(suite.T(), models.ReminderOpen, reminders[0].Status)
+
+	// Neither a second run nor the next cadence adds another reminder or email
+	assert.Zero(suite.T(), suite.process())
+	suite.now = suite.now.AddDate(0, 0, 7)
+	assert.Zero(suite.T(), suite.process())
+	assert.Len(suite.T(), suite.reminders(), 1)
+	assert.Equal(suite.T(), 1, suite.env.Email.GetEmailCount())
+}
+
+func (suite *ReminderTestSuite) TestFailedEmailIsRetried() {
+	suite.createPerson("Tom")
+	suite.now = suite.now.AddDate(0, 0, 7)
+
+	suite.env.Email.SetSendError(errors.New("smtp down"))
+	queued, err := suite.env.Handler.ProcessReminders(context.Background())
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 1, queued)
+	_, err = suite.env.SendQueuedEmails()
+	assert.ErrorContains(suite.T(), err, "smtp down")
+	assert.Len(suite.T(), suite.reminders(), 1)
+
+	// The outbox retries the email; the reminder is not queued again
+	suite.env.Email.SetSendError(nil)
+	suite.now = suite.now.Add(time.Minute)
+	assert.Zero(suite.T(), suite.process())
+	assert.Len(suite.T(), suite.env.Email.FindEmailByTemplate(mailer.Reminder), 1)
+}
+
+func (suite *ReminderTestSuite) TestSnooze() {
+	suite.createPerson("Tom")
+	suite.now = suite.now.AddDate(0, 0, 7)
+	suite.process()
+	reminder := suite.reminders()[0]
+
+	w := suite.request("POST", "/reminders/"+reminder.ID.String()+"/snooze", handlers.SnoozeReminderRequest{Until: suite.now.Add(-time.Hour)})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	until := suite.now.Add(24 * time.Hour)
+	w = suite.request("POST", "/reminders/"+reminder.ID.String()+"/snooze", handlers.SnoozeReminderRequest{Until: until})
+	suite.Require().Equal(http.StatusOK, w.Code)
+	assert.Empty(suite.T(), suite.reminders())
+	assert.Zero(suite.T(), suite.process())
+
+	// Once the snooze is over the reminder shows and is emailed again
+	suite.now = until
+	assert.Len(suite.T(), suite.reminders(), 1)
+	assert.Equal(suite.T(), 1, suite.process())
+	assert.Equal(suite.T(), 2, suite.env.Email.GetEmailCount())
+}
+
+func (suite *ReminderTestSuite) TestCompleteSchedulesNextReminder() {
+	person := suite.createPerson("Tom")
+	suite.now = suite.now.AddDate(0, 0, 10)
+	suite.process()
+	reminder := suite.reminders()[0]
+
+	w := suite.request("POST", "/reminders/"+reminder.ID.String()+"/complete", nil)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var completed handlers.ReminderResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &completed))
+	assert.Equal(suite.T(), models.ReminderCompleted, completed.Status)
+	assert.Empty(suite.T(), suite.reminders())
+
+	stored, err := suite.env.Store.People.GetForUser(context.Background(), person.ID, suite.user.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), suite.now, *stored.LastContactedAt)
+	assert.Equal(suite.T(), suite.now.AddDate(0, 0, 7), *stored.NextReminderAt)
+
+	w = suite.request("POST", "/reminders/"+reminder.ID.String()+"/dismiss", nil)
+	assert.Equal(suite.T(), http.StatusConflict, w.Code)
+}
+
+func (suite *ReminderTestSuite) TestDismiss() {
+	suite.createPerson("Tom")
+	suite.now = suite.now.AddDate(0, 0, 7)
+	suite.process()
+	reminder := suite.reminders()[0]
+
+	w := suite.request("POST", "/reminders/"+reminder.ID.String()+"/dismiss", nil)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	assert.Empty(suite.T(), suite.reminders())
+
+	// The next reminder still opens on schedule
+	suite.now = suite.now.AddDate(0, 0, 7)
+	assert.Equal(suite.T(), 1, suite.process())
+	assert.Len(suite.T(), suite.reminders(), 1)
+}
+
+func (suite *ReminderTestSuite) TestReminderOfOtherUser() {
+	other := models.User{Email: "other@example.com", Password: "x", DisplayName: "Other", EmailConfirmed: true}
+	suite.env.Store.Users.Create(context.Background(), &other)
+	person := models.Person{UserID: other.ID, FirstName: "Ann", LastName: "Lee", ContactEveryDays: 1}
+	suite.env.Store.People.Create(context.Background(), &person)
+	reminder := models.Reminder{UserID: other.ID, PersonID: person.ID, DueAt: suite.now, Status: models.ReminderOpen}
+	suite.Require().NoError(suite.env.Store.Reminders.Create(context.Background(), &reminder))
+
+	assert.Empty(suite.T(), suite.reminders())
+	w := suite.request("POST", "/reminders/"+reminder.ID.String()+"/complete", nil)
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+	w = suite.request("POST", "/reminders/not-a-uuid/complete", nil)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+func (suite *ReminderTestSuite) TestSetContactCadence() {
+	person := suite.createPerson("Tom")
+
+	w := suite.request("PUT", "/people/"+person.ID.String()+"/contact-cadence", map[string]int{"everyDays": 3})
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var updated handlers.PersonResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &updated))
+	assert.Equal(suite.T(), 3, updated.ContactEveryDays)
+	assert.Equal(suite.T(), suite.now.AddDate(0, 0, 3), *updated.NextReminderAt)
+
+	w = suite.request("PUT", "/people/"+person.ID.String()+"/contact-cadence", map[string]int{"everyDays": 0})
+	suite.Require().Equal(http.StatusOK, w.Code)
+	updated = handlers.PersonResponse{}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &updated))
+	assert.Nil(suite.T(), updated.NextReminderAt)
+
+	suite.now = suite.now.AddDate(0, 1, 0)
+	assert.Zero(suite.T(), suite.process())
+
+	w = suite.request("PUT", "/people/"+person.ID.String()+"/contact-cadence", map[string]int{"everyDays": 400})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	w = suite.request("PUT", "/people/"+suite.user.ID.String()+"/contact-cadence", map[string]int{"everyDays": 3})
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+}
+
+func TestReminderTestSuite(t *testing.T) {
+	suite.Run(t, new(ReminderTestSuite))
+}
@@ -0,0 +1,528 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/repository"
+)
+
+const (
+	// promptHour is the hour of the day, in the user's timezone, the daily
+	// reminiscence prompt is sent
+	promptHour = 9
+	// promptBatchSize is how many users one scheduler transaction claims
+	promptBatchSize = 50
+	// resurfaceFirstInterval is how many days a memory is put away for after
+	// its first prompt; every prompt doubles it up to resurfaceMaxInterval,
+	// or favoriteMaxInterval for favourite memories
+	resurfaceFirstInterval = 7
+	resurfaceMaxInterval   = 180
+	favoriteMaxInterval    = 30
+	// mentionWindowDays is how far back a person counts as talked about
+	mentionWindowDays = 30
+	// defaultPromptHistoryDays is how many days of prompts are listed when
+	// the request names no days
+	defaultPromptHistoryDays = 30
+)
+
+// MemoryPromptResponse is a day's reminiscence prompt
+type MemoryPromptResponse struct {
+	ID uuid.UUID `json:"id"`
+	// Day is the date the prompt is for, as YYYY-MM-DD
+	Day         string         `json:"day"`
+	Reason      string         `json:"reason"`
+	Question    string         `json:"question"`
+	Response    string         `json:"response"`
+	RespondedAt *time.Time     `json:"respondedAt,omitempty"`
+	PersonID    *uuid.UUID     `json:"personId,omitempty"`
+	Memory      MemoryResponse `json:"memory"`
+}
+
+// MemoryPromptListResponse lists recent prompts with how the user responded
+type MemoryPromptListResponse struct {
+	Prompts    []MemoryPromptResponse `json:"prompts"`
+	Engaged    int                    `json:"engaged"`
+	Skipped    int                    `json:"skipped"`
+	Unanswered int                    `json:"unanswered"`
+}
+
+// RespondToPromptRequest records whether the user took up a prompt
+type RespondToPromptRequest struct {
+	Response string `json:"response" binding:"required,oneof=engaged skipped"`
+}
+
+// FavoriteMemoryRequest marks a memory as a favourite or not
+type FavoriteMemoryRequest struct {
+	Favorite *bool `json:"favorite" binding:"required"`
+}
+
+func newMemoryPromptResponse(prompt *models.MemoryPrompt) MemoryPromptResponse {
+	return MemoryPromptResponse{
+		ID:          prompt.ID,
+		Day:         prompt.Day.Format(dateLayout),
+		Reason:      prompt.Reason,
+		Question:    prompt.Question,
+		Response:    prompt.Response,
+		RespondedAt: prompt.RespondedAt,
+		PersonID:    prompt.PersonID,
+		Memory:      newMemoryResponse(&prompt.Memory),
+	}
+}
+
+// resurfaceOrder sorts memories by when they may come back, those never
+// prompted first, then the oldest
+func resurfaceOrder(memories []models.Memory) []models.Memory {
+	sorted := append([]models.Memory(nil), memories...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].NextResurfaceOn, sorted[j].NextResurfaceOn
+		switch {
+		case a == nil && b != nil:
+			return true
+		case a != nil && b == nil:
+			return false
+		case a != nil && !a.Equal(*b):
+			return a.Before(*b)
+		}
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+	return sorted
+}
+
+// pickMemory chooses the memory to resurface on day: one from this day in an
+// earlier year, else one with someone not in mentioned, else a favourite,
+// else any memory whose turn has come. Memories added on day are left
+// for later. It returns nil when no memory is due.
+func pickMemory(memories []models.Memory, mentioned map[uuid.UUID]bool, day time.Time, loc *time.Location) (*models.Memory, string, *models.Person) {
+	var due []models.Memory
+	for _, memory := range resurfaceOrder(memories) {
+		if !localDay(memory.CreatedAt, loc).Before(day) {
+			continue
+		}
+		if on := memory.OccurredOn; on != nil && on.Year() < day.Year() && nextOccurrence(*on, day).Equal(day) {
+			return &memory, models.PromptOnThisDay, nil
+		}
+		if memory.NextResurfaceOn == nil || !memory.NextResurfaceOn.After(day) {
+			due = append(due, memory)
+		}
+	}
+
+	for i := range due {
+		for j := range due[i].People {
+			if person := &due[i].People[j]; !mentioned[person.ID] {
+				return &due[i], models.PromptPerson, person
+			}
+		}
+	}
+	for i := range due {
+		if due[i].Favorite {
+			return &due[i], models.PromptFavorite, nil
+		}
+	}
+	if len(due) > 0 {
+		return &due[0], models.PromptRevisit, nil
+	}
+	return nil, "", nil
+}
+
+// resurface puts memory away after it was prompted on day, for twice as long
+// as the last time
+func resurface(memory *models.Memory, day time.Time) {
+	limit := resurfaceMaxInterval
+	if memory.Favorite {
+		limit = favoriteMaxInterval
+	}
+	memory.ResurfaceInterval = min(max(memory.ResurfaceInterval*2, resurfaceFirstInterval), limit)
+	next := day.AddDate(0, 0, memory.ResurfaceInterval)
+	memory.NextResurfaceOn = &next
+}
+
+// promptQuestion is what the assistant opens a conversation about memory
+// with
+func promptQuestion(memory *models.Memory, reason string, person *models.Person, day time.Time) string {
+	switch reason {
+	case models.PromptOnThisDay:
+		years := day.Year() - memory.OccurredOn.Year()
+		ago := fmt.Sprintf("%d years ago", years)
+		if years == 1 {
+			ago = "A year ago"
+		}
+		return fmt.Sprintf("%s today: %s. What do you remember about that day?", ago, memory.Title)
+	case models.PromptPerson:
+		return fmt.Sprintf("Here is a memory with %s: %s. What comes to mind when you think of it?", personName(person), memory.Title)
+	case models.PromptFavorite:
+		return fmt.Sprintf("One of your favourite memories: %s. Would you like to tell me about it?", memory.Title)
+	default:
+		return fmt.Sprintf("Do you remember %s? Tell me about it.", memory.Title)
+	}
+}
+
+// mentionedPeople returns the people the user talked about, or was prompted
+// about, since since. A person counts as talked about when a message of the
+// user's names them as a whole word, in any case.
+func mentionedPeople(ctx context.Context, store *repository.Store, userID uuid.UUID, people []models.Person, since time.Time) (map[uuid.UUID]bool, error) {
+	mentioned := make(map[uuid.UUID]bool)
+	messages, err := store.ChatMessages.ListSince(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[uuid.UUID]*regexp.Regexp, len(people))
+	for _, person := range people {
+		if person.FirstName != "" {
+			names[person.ID] = nameWord(person.FirstName)
+		}
+	}
+	for _, message := range messages {
+		if message.Role != "user" {
+			continue
+		}
+		for id, name := range names {
+			if name.MatchString(message.Content) {
+				mentioned[id] = true
+			}
+		}
+	}
+
+	prompts, err := store.MemoryPrompts.ListByUser(ctx, userID, calendarDay(since))
+	if err != nil {
+		return nil, err
+	}
+	for _, prompt := range prompts {
+		if prompt.PersonID != nil {
+			mentioned[*prompt.PersonID] = true
+		}
+	}
+	return mentioned, nil
+}
+
+// nameWord matches name as a whole word in any case, so "Al" is not found
+// in "always". Letters and digits of any script count as part of a word.
+func nameWord(name string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)(?:^|[^\p{L}\p{N}_])` + regexp.QuoteMeta(strings.TrimSpace(name)) + `(?:$|[^\p{L}\p{N}_])`)
+}
+
+// ensurePrompt returns the user's prompt for day, choosing a memory for it
+// when there is none yet, and reports whether it created it. It returns nil
+// when no memory is due.
+func (h *Handler) ensurePrompt(ctx context.Context, store *repository.Store, user *models.User, day time.Time) (*models.MemoryPrompt, bool, error) {
+	prompt, err := store.MemoryPrompts.GetForDay(ctx, user.ID, day)
+	if err == nil || !errors.Is(err, repository.ErrNotFound) {
+		return prompt, false, err
+	}
+
+	memories, err := store.Memories.ListByUser(ctx, user.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	people, err := store.People.ListByUser(ctx, user.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	mentioned, err := mentionedPeople(ctx, store, user.ID, people, day.AddDate(0, 0, -mentionWindowDays))
+	if err != nil {
+		return nil, false, err
+	}
+	memory, reason, person := pickMemory(memories, mentioned, day, location(user))
+	if memory == nil {
+		return nil, false, nil
+	}
+
+	prompt = &models.MemoryPrompt{
+		UserID:   user.ID,
+		MemoryID: memory.ID,
+		Day:      day,
+		Reason:   reason,
+		Question: promptQuestion(memory, reason, person, day),
+		Response: models.PromptPending,
+	}
+	if person != nil {
+		prompt.PersonID = &person.ID
+	}
+	if err := store.MemoryPrompts.Create(ctx, prompt); err != nil {
+		return nil, false, err
+	}
+	resurface(memory, day)
+	if err := store.Memories.Update(ctx, memory); err != nil {
+		return nil, false, err
+	}
+	memory.People = nil
+	prompt.Memory = *memory
+	return prompt, true, nil
+}
+
+// reminiscenceNotification invites the user to talk about the day's prompt
+func reminiscenceNotification(prompt *models.MemoryPrompt) *models.Notification {
+	return &models.Notification{
+		UserID: prompt.UserID,
+		Type:   models.NotificationReminiscence,
+		Title:  "A memory for today",
+		Body:   prompt.Question,
+		URL:    "/chat?prompt=" + prompt.ID.String(),
+		Data:   map[string]string{"promptId": prompt.ID.String(), "memoryId": prompt.MemoryID.String()},
+	}
+}
+
+// ProcessPrompts chooses the day's memory for each user whose prompt hour
+// has come and notifies them of it. Users are claimed with row locks, so
+// several instances may run it at once. It returns how many prompts were
+// created.
+func (h *Handler) ProcessPrompts(ctx context.Context) (int, error) {
+	var created int
+	for {
+		var claimed, createdInBatch int
+		err := h.store.Transaction(ctx, func(tx *repository.Store) error {
+			now := h.now()
+			users, err := tx.Users.ClaimPromptDue(ctx, now, promptBatchSize)
+			if err != nil {
+				return err
+			}
+			claimed = len(users)
+			for i := range users {
+				user := &users[i]
+				loc := location(user)
+				local := now.In(loc)
+				next := time.Date(local.Year(), local.Month(), local.Day(), promptHour, 0, 0, 0, loc)
+				if !now.Before(next) {
+					prompt, isNew, err := h.ensurePrompt(ctx, tx, user, localDay(now, loc))
+					if err != nil {
+						return err
+					}
+					if isNew {
+						if err := h.notify(ctx, tx, reminiscenceNotification(prompt)); err != nil {
+							return err
+						}
+						createdInBatch++
+					}
+					next = next.AddDate(0, 0, 1)
+				}
+				if err := tx.Users.SetNextPromptAt(ctx, user.ID, next); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return created, fmt.Errorf("create memory prompts: %w", err)
+		}
+		created += createdInBatch
+		if claimed < promptBatchSize {
+			return created, nil
+		}
+	}
+}
+
+// GetTodayPrompt returns the memory the user is invited to reminisce about
+// today, choosing it if the daily job has not yet
+func (h *Handler) GetTodayPrompt(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	user, err := h.store.Users.Get(c, userUUID)
+	if err != nil {
+		apierror.Abort(c, errUserNotFound)
+		return
+	}
+	day := localDay(h.now(), location(user))
+
+	var prompt *models.MemoryPrompt
+	err = h.store.Transaction(c, func(tx *repository.Store) error {
+		prompt, _, err = h.ensurePrompt(c, tx, user, day)
+		return err
+	})
+	// The daily job chose one at the same time
+	if errors.Is(err, repository.ErrDuplicate) {
+		prompt, err = h.store.MemoryPrompts.GetForDay(c, userUUID, day)
+	}
+	switch {
+	case err != nil:
+		apierror.Abort(c, apierror.Internal("Failed to get today's memory", err))
+	case prompt == nil:
+		apierror.Abort(c, errNoMemoryPrompt)
+	default:
+		c.JSON(http.StatusOK, newMemoryPromptResponse(prompt))
+	}
+}
+
+// GetPrompts lists the user's prompts of the last days days, newest first,
+// with how many they engaged with, skipped or left unanswered
+func (h *Handler) GetPrompts(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	days, ok := queryDays(c, defaultPromptHistoryDays)
+	if !ok {
+		return
+	}
+
+	loc, err := h.userLocation(c, userUUID)
+	if err != nil {
+		apierror.Abort(c, errUserNotFound)
+		return
+	}
+	since := localDay(h.now(), loc).AddDate(0, 0, 1-days)
+
+	prompts, err := h.store.MemoryPrompts.ListByUser(c, userUUID, since)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to get memory prompts", err))
+		return
+	}
+
+	response := MemoryPromptListResponse{Prompts: make([]MemoryPromptResponse, 0, len(prompts))}
+	for i := range prompts {
+		response.Prompts = append(response.Prompts, newMemoryPromptResponse(&prompts[i]))
+		switch prompts[i].Response {
+		case models.PromptEngaged:
+			response.Engaged++
+		case models.PromptSkipped:
+			response.Skipped++
+		default:
+			response.Unanswered++
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RespondToPrompt records whether the user took up a prompt. A skipped
+// memory is put away for longer before it comes back.
+func (h *Handler) RespondToPrompt(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	promptID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Abort(c, errInvalidPromptID)
+		return
+	}
+
+	var req RespondToPromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, apierror.Bind(err))
+		return
+	}
+
+	var prompt *models.MemoryPrompt
+	err = h.store.Transaction(c, func(tx *repository.Store) error {
+		prompt, err = h.respondToPrompt(c, tx, promptID, userUUID, req.Response)
+		return err
+	})
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		apierror.Abort(c, errPromptNotFound)
+	case errors.Is(err, errPromptAnswered):
+		apierror.Abort(c, errPromptAnswered)
+	case err != nil:
+		apierror.Abort(c, apierror.Internal("Failed to respond to memory prompt", err))
+	default:
+		c.JSON(http.StatusOK, newMemoryPromptResponse(prompt))
+	}
+}
+
+// respondToPrompt records the response to the user's pending prompt
+func (h *Handler) respondToPrompt(ctx context.Context, store *repository.Store, id, userID uuid.UUID, response string) (*models.MemoryPrompt, error) {
+	prompt, err := store.MemoryPrompts.GetForUser(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if prompt.Response != models.PromptPending {
+		return nil, errPromptAnswered
+	}
+	now := h.now()
+	prompt.Response = response
+	prompt.RespondedAt = &now
+	if err := store.MemoryPrompts.Update(ctx, prompt); err != nil {
+		return nil, err
+	}
+	if response == models.PromptSkipped {
+		resurface(&prompt.Memory, prompt.Day)
+		if err := store.Memories.Update(ctx, &prompt.Memory); err != nil {
+			return nil, err
+		}
+	}
+	return prompt, nil
+}
+
+// FavoriteMemory marks a memory as a favourite, which comes back more often
+// in prompts, or unmarks it
+func (h *Handler) FavoriteMemory(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	memoryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Abort(c, errInvalidMemoryID)
+		return
+	}
+
+	var req FavoriteMemoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, apierror.Bind(err))
+		return
+	}
+
+	memory, err := h.store.Memories.GetForUser(c, memoryID, userUUID)
+	if err != nil {
+		apierror.Abort(c, errMemoryNotFound)
+		return
+	}
+	memory.Favorite = *req.Favorite
+	// A memory put away for long comes back within the favourite limit
+	if memory.Favorite && memory.ResurfaceInterval > favoriteMaxInterval {
+		memory.ResurfaceInterval = favoriteMaxInterval
+		if memory.NextResurfaceOn != nil {
+			if latest := calendarDay(h.now()).AddDate(0, 0, favoriteMaxInterval); memory.NextResurfaceOn.After(latest) {
+				memory.NextResurfaceOn = &latest
+			}
+		}
+	}
+	if err := h.store.Memories.Update(c, memory); err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to update memory", err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"memory": newMemoryResponse(memory)})
+}
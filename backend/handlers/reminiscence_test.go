@@ -0,0 +1,299 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/handlers"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ReminiscenceTestSuite struct {
+	suite.Suite
+	env    *testutils.TestEnv
+	router *gin.Engine
+	user   models.User
+	now    time.Time
+}
+
+func (suite *ReminiscenceTestSuite) SetupSuite() {
+	gin.SetMode(gin.TestMode)
+}
+
+func (suite *ReminiscenceTestSuite) SetupTest() {
+	suite.now = time.Date(2026, time.March, 2, 10, 0, 0, 0, time.UTC)
+	suite.env = testutils.NewTestEnv(func(deps *handlers.Deps) {
+		deps.Clock = func() time.Time { return suite.now }
+	})
+	h := suite.env.Handler
+
+	suite.user = models.User{Email: "margaret@example.com", Password: "x", DisplayName: "Margaret", EmailConfirmed: true}
+	suite.env.Store.Users.Create(context.Background(), &suite.user)
+
+	suite.router = gin.New()
+	suite.router.Use(testutils.OpenAPIValidator(suite.T()), apierror.Middleware())
+	protected := suite.router.Group("/")
+	protected.Use(func(c *gin.Context) {
+		c.Set("user_id", suite.user.ID)
+		c.Next()
+	})
+	protected.PUT("/memories/:id/favorite", h.FavoriteMemory)
+	protected.GET("/reminiscence", h.GetPrompts)
+	protected.GET("/reminiscence/today", h.GetTodayPrompt)
+	protected.POST("/reminiscence/:id/respond", h.RespondToPrompt)
+}
+
+func (suite *ReminiscenceTestSuite) request(method, path string, body any) *httptest.ResponseRecorder {
+	var reader bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&reader).Encode(body)
+	}
+	req, _ := http.NewRequest(method, path, &reader)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+// addMemory creates a memory, added a year ago unless it says otherwise
+func (suite *ReminiscenceTestSuite) addMemory(memory models.Memory, people ...models.Person) models.Memory {
+	memory.UserID = suite.user.ID
+	memory.Type = "story"
+	if memory.CreatedAt.IsZero() {
+		memory.CreatedAt = suite.now.AddDate(-1, 0, 0)
+	}
+	suite.Require().NoError(suite.env.Store.Memories.Create(context.Background(), &memory))
+	if len(people) > 0 {
+		suite.Require().NoError(suite.env.Store.Memories.AddPeople(context.Background(), memory.ID, people))
+	}
+	return memory
+}
+
+// today returns the day's prompt, or nil when no memory is due
+func (suite *ReminiscenceTestSuite) today() *handlers.MemoryPromptResponse {
+	w := suite.request("GET", "/reminiscence/today", nil)
+	if w.Code == http.StatusNotFound {
+		return nil
+	}
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	var prompt handlers.MemoryPromptResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &prompt))
+	return &prompt
+}
+
+func (suite *ReminiscenceTestSuite) TestOnThisDayComesFirst() {
+	occurred := time.Date(2016, time.March, 2, 0, 0, 0, 0, time.UTC)
+	suite.addMemory(models.Memory{Title: "Favourite picnic", Favorite: true})
+	suite.addMemory(models.Memory{Title: "Wedding day", OccurredOn: &occurred})
+
+	prompt := suite.today()
+	suite.Require().NotNil(prompt)
+	assert.Equal(suite.T(), models.PromptOnThisDay, prompt.Reason)
+	assert.Equal(suite.T(), "2026-03-02", prompt.Day)
+	assert.Equal(suite.T(), "10 years ago today: Wedding day. What do you remember about that day?", prompt.Question)
+	assert.Equal(suite.T(), models.PromptPending, prompt.Response)
+
+	// The same prompt all day
+	again := suite.today()
+	suite.Require().NotNil(again)
+	assert.Equal(suite.T(), prompt.ID, again.ID)
+}
+
+func (suite *ReminiscenceTestSuite) TestPeopleNotTalkedAboutThenFavourites() {
+	ctx := context.Background()
+	tom := models.Person{UserID: suite.user.ID, FirstName: "Tom", LastName: "Hughes"}
+	suite.Require().NoError(suite.env.Store.People.Create(ctx, &tom))
+	suite.addMemory(models.Memory{Title: "Old house"})
+	suite.addMemory(models.Memory{Title: "Tom's graduation"}, tom)
+	suite.addMemory(models.Memory{Title: "Dancing", Favorite: true})
+	// Added today, so it waits until tomorrow
+	today := models.Memory{UserID: suite.user.ID, Title: "New photo", Type: "photo", CreatedAt: suite.now}
+	suite.Require().NoError(suite.env.Store.Memories.Create(ctx, &today))
+
+	prompt := suite.today()
+	suite.Require().NotNil(prompt)
+	assert.Equal(suite.T(), models.PromptPerson, prompt.Reason)
+	assert.Equal(suite.T(), &tom.ID, prompt.PersonID)
+	assert.Equal(suite.T(), "Here is a memory with Tom Hughes: Tom's graduation. What comes to mind when you think of it?", prompt.Question)
+
+	// Tom was prompted about yesterday, so the favourite comes next
+	suite.now = suite.now.AddDate(0, 0, 1)
+	prompt = suite.today()
+	suite.Require().NotNil(prompt)
+	assert.Equal(suite.T(), models.PromptFavorite, prompt.Reason)
+	assert.Equal(suite.T(), "Dancing", prompt.Memory.Title)
+
+	suite.now = suite.now.AddDate(0, 0, 1)
+	prompt = suite.today()
+	suite.Require().NotNil(prompt)
+	assert.Equal(suite.T(), models.PromptRevisit, prompt.Reason)
+	assert.Equal(suite.T(), "Old house", prompt.Memory.Title)
+
+	suite.now = suite.now.AddDate(0, 0, 1)
+	prompt = suite.today()
+	suite.Require().NotNil(prompt)
+	assert.Equal(suite.T(), "New photo", prompt.Memory.Title)
+}
+
+func (suite *ReminiscenceTestSuite) TestMentionedPeopleAreLeftOut() {
+	ctx := context.Background()
+	tom := models.Person{UserID: suite.user.ID, FirstName: "Tom", LastName: "Hughes"}
+	suite.Require().NoError(suite.env.Store.People.Create(ctx, &tom))
+	suite.addMemory(models.Memory{Title: "Tom's graduation"}, tom)
+	message := models.ChatMessage{UserID: suite.user.ID, Role: "user", Content: "tom visited on Sunday", CreatedAt: suite.now.AddDate(0, 0, -3)}
+	suite.Require().NoError(suite.env.Store.ChatMessages.Create(ctx, &message))
+
+	prompt := suite.today()
+	suite.Require().NotNil(prompt)
+	assert.Equal(suite.T(), models.PromptRevisit, prompt.Reason)
+	assert.Nil(suite.T(), prompt.PersonID)
+}
+
+func (suite *ReminiscenceTestSuite) TestMentionsMatchWholeNames() {
+	ctx := context.Background()
+	al := models.Person{UserID: suite.user.ID, FirstName: "Al", LastName: "Burns"}
+	suite.Require().NoError(suite.env.Store.People.Create(ctx, &al))
+	suite.addMemory(models.Memory{Title: "Fishing trip"}, al)
+	ed := models.Person{UserID: suite.user.ID, FirstName: "Ed", LastName: "Stone"}
+	suite.Require().NoError(suite.env.Store.People.Create(ctx, &ed))
+	suite.addMemory(models.Memory{Title: "Bowling night"}, ed)
+	zoe := models.Person{UserID: suite.user.ID, FirstName: "Zoë", LastName: "Ince"}
+	suite.Require().NoError(suite.env.Store.People.Create(ctx, &zoe))
+	suite.addMemory(models.Memory{Title: "Zoë's wedding"}, zoe)
+	for _, content := range []string{"I always wanted a garden", "ZOË rang, she's well"} {
+		message := models.ChatMessage{UserID: suite.user.ID, Role: "user", Content: content, CreatedAt: suite.now.AddDate(0, 0, -3)}
+		suite.Require().NoError(suite.env.Store.ChatMessages.Create(ctx, &message))
+	}
+
+	// Al and Ed are only part of other words, so one of them comes up
+	prompt := suite.today()
+	suite.Require().NotNil(prompt)
+	assert.Equal(suite.T(), models.PromptPerson, prompt.Reason)
+	suite.Require().NotNil(prompt.PersonID)
+	assert.NotEqual(suite.T(), zoe.ID, *prompt.PersonID)
+}
+
+func (suite *ReminiscenceTestSuite) TestMemoriesAreSpacedOut() {
+	// The memory added first comes back first
+	first := suite.addMemory(models.Memory{Title: "Old house", CreatedAt: suite.now.AddDate(-2, 0, 0)})
+	suite.addMemory(models.Memory{Title: "Lake trip"})
+
+	var titles []string
+	for day := 0; day < 24; day++ {
+		if prompt := suite.today(); prompt != nil {
+			titles = append(titles, suite.now.Format("Jan 2")+" "+prompt.Memory.Title)
+		}
+		suite.now = suite.now.AddDate(0, 0, 1)
+	}
+	// Each is put away for 7 days, then 14
+	assert.Equal(suite.T(), []string{
+		"Mar 2 Old house", "Mar 3 Lake trip",
+		"Mar 9 Old house", "Mar 10 Lake trip",
+		"Mar 23 Old house", "Mar 24 Lake trip",
+	}, titles)
+
+	memory, err := suite.env.Store.Memories.GetForUser(context.Background(), first.ID, suite.user.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 28, memory.ResurfaceInterval)
+	assert.Equal(suite.T(), "2026-04-20", memory.NextResurfaceOn.Format("2006-01-02"))
+}
+
+func (suite *ReminiscenceTestSuite) TestRespond() {
+	memory := suite.addMemory(models.Memory{Title: "Old house"})
+	prompt := suite.today()
+	suite.Require().NotNil(prompt)
+
+	w := suite.request("POST", "/reminiscence/"+prompt.ID.String()+"/respond", handlers.RespondToPromptRequest{Response: models.PromptSkipped})
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var answered handlers.MemoryPromptResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &answered))
+	assert.Equal(suite.T(), models.PromptSkipped, answered.Response)
+	suite.Require().NotNil(answered.RespondedAt)
+
+	// Skipping puts the memory away for longer
+	stored, err := suite.env.Store.Memories.GetForUser(context.Background(), memory.ID, suite.user.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 14, stored.ResurfaceInterval)
+
+	w = suite.request("POST", "/reminiscence/"+prompt.ID.String()+"/respond", handlers.RespondToPromptRequest{Response: models.PromptEngaged})
+	assert.Equal(suite.T(), http.StatusConflict, w.Code)
+	w = suite.request("POST", "/reminiscence/"+uuid.NewString()+"/respond", handlers.RespondToPromptRequest{Response: models.PromptEngaged})
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+	w = suite.request("POST", "/reminiscence/"+prompt.ID.String()+"/respond", map[string]string{"response": "maybe"})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w = suite.request("GET", "/reminiscence?days=7", nil)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var list handlers.MemoryPromptListResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &list))
+	suite.Require().Len(list.Prompts, 1)
+	assert.Equal(suite.T(), 0, list.Engaged)
+	assert.Equal(suite.T(), 1, list.Skipped)
+	assert.Equal(suite.T(), 0, list.Unanswered)
+}
+
+func (suite *ReminiscenceTestSuite) TestNothingToResurface() {
+	assert.Equal(suite.T(), http.StatusNotFound, suite.request("GET", "/reminiscence/today", nil).Code)
+}
+
+func (suite *ReminiscenceTestSuite) TestProcessPromptsAtPromptHour() {
+	ctx := context.Background()
+	suite.user.Timezone = "Europe/Madrid"
+	suite.Require().NoError(suite.env.Store.Users.Update(ctx, &suite.user))
+	suite.addMemory(models.Memory{Title: "Old house"})
+
+	// 08:00 in Madrid
+	suite.now = time.Date(2026, time.March, 2, 7, 0, 0, 0, time.UTC)
+	created, err := suite.env.Handler.ProcessPrompts(ctx)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 0, created)
+	user, err := suite.env.Store.Users.Get(ctx, suite.user.ID)
+	suite.Require().NoError(err)
+	assert.True(suite.T(), user.NextPromptAt.Equal(time.Date(2026, time.March, 2, 8, 0, 0, 0, time.UTC)))
+
+	suite.now = suite.now.Add(time.Hour)
+	created, err = suite.env.Handler.ProcessPrompts(ctx)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 1, created)
+	created, err = suite.env.Handler.ProcessPrompts(ctx)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 0, created)
+
+	notifications, err := suite.env.Store.Notifications.List(ctx, suite.user.ID, false, 10)
+	suite.Require().NoError(err)
+	suite.Require().Len(notifications, 1)
+	prompt := suite.today()
+	suite.Require().NotNil(prompt)
+	assert.Equal(suite.T(), models.NotificationReminiscence, notifications[0].Type)
+	assert.Equal(suite.T(), "/chat?prompt="+prompt.ID.String(), notifications[0].URL)
+	assert.Equal(suite.T(), prompt.Question, notifications[0].Body)
+}
+
+func (suite *ReminiscenceTestSuite) TestFavorite() {
+	memory := suite.addMemory(models.Memory{Title: "Dancing"})
+
+	w := suite.request("PUT", "/memories/"+memory.ID.String()+"/favorite", map[string]bool{"favorite": true})
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var response struct{ Memory handlers.MemoryResponse }
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	assert.True(suite.T(), response.Memory.Favorite)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, suite.request("PUT", "/memories/"+memory.ID.String()+"/favorite", map[string]string{}).Code)
+	assert.Equal(suite.T(), http.StatusBadRequest, suite.request("PUT", "/memories/unknown/favorite", map[string]bool{"favorite": true}).Code)
+	assert.Equal(suite.T(), http.StatusNotFound, suite.request("PUT", "/memories/"+uuid.NewString()+"/favorite", map[string]bool{"favorite": true}).Code)
+}
+
+func TestReminiscenceTestSuite(t *testing.T) {
+	suite.Run(t, new(ReminiscenceTestSuite))
+}
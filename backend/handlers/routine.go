@@ -0,0 +1,952 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/mailer"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/recurrence"
+	"github.com/muneerlalji/Luma/repository"
+)
+
+// timeLayout is how routine times of day are written in the API
+const timeLayout = "15:04"
+
+const (
+	// defaultGraceMinutes is how late a check-in may be when the routine
+	// names no grace period
+	defaultGraceMinutes = 60
+	// earlyCheckIn is how long before an occurrence it may be checked in
+	earlyCheckIn = 2 * time.Hour
+	// escalationWindow is how old a missed check-in may be and still be
+	// escalated, so a caregiver added later is not emailed about old ones
+	escalationWindow = 24 * time.Hour
+	// occurrenceSearchDays bounds the search for a routine's next occurrence
+	occurrenceSearchDays = 2 * 366
+	// defaultAdherenceDays is the adherence window when the request names none
+	defaultAdherenceDays = 30
+	// routineBatchSize is how many rows one scheduler transaction claims
+	routineBatchSize = 50
+)
+
+// CheckInPending is the status of a scheduled check-in that is neither done
+// nor late yet. It is never stored.
+const CheckInPending = "pending"
+
+// RoutineRequest creates or replaces a routine
+type RoutineRequest struct {
+	Kind         string `json:"kind" binding:"required,oneof=medication activity"`
+	Title        string `json:"title" binding:"required"`
+	Instructions string `json:"instructions"`
+	// Recurrence is an RRULE such as FREQ=WEEKLY;BYDAY=MO,TH
+	Recurrence string   `json:"recurrence" binding:"required"`
+	Times      []string `json:"times" binding:"required,min=1,max=12,dive,datetime=15:04"`
+	// StartsOn defaults to today when creating and is kept when replacing
+	StartsOn *string `json:"startsOn,omitempty" binding:"omitempty,datetime=2006-01-02"`
+	// GraceMinutes defaults to 60
+	GraceMinutes int `json:"graceMinutes,omitempty" binding:"omitempty,min=5,max=720"`
+}
+
+type RoutineResponse struct {
+	ID           uuid.UUID  `json:"id"`
+	Kind         string     `json:"kind"`
+	Title        string     `json:"title"`
+	Instructions string     `json:"instructions"`
+	Recurrence   string     `json:"recurrence"`
+	Times        []string   `json:"times"`
+	StartsOn     string     `json:"startsOn"`
+	GraceMinutes int        `json:"graceMinutes"`
+	NextDueAt    *time.Time `json:"nextDueAt,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+}
+
+// CheckInRequest confirms or skips one occurrence of a routine
+type CheckInRequest struct {
+	ScheduledAt time.Time `json:"scheduledAt" binding:"required"`
+	Status      string    `json:"status" binding:"required,oneof=done skipped"`
+	Note        string    `json:"note"`
+}
+
+type CheckInResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	RoutineID   uuid.UUID  `json:"routineId"`
+	ScheduledAt time.Time  `json:"scheduledAt"`
+	Status      string     `json:"status"`
+	Note        string     `json:"note"`
+	CheckedInAt *time.Time `json:"checkedInAt,omitempty"`
+}
+
+// ScheduledCheckIn is one occurrence of a routine on the user's schedule
+type ScheduledCheckIn struct {
+	RoutineID    uuid.UUID `json:"routineId"`
+	Kind         string    `json:"kind"`
+	Title        string    `json:"title"`
+	Instructions string    `json:"instructions"`
+	// Time is the time of day in the user's timezone
+	Time        string     `json:"time"`
+	ScheduledAt time.Time  `json:"scheduledAt"`
+	Status      string     `json:"status"`
+	Note        string     `json:"note,omitempty"`
+	CheckedInAt *time.Time `json:"checkedInAt,omitempty"`
+}
+
+// AdherenceCounts tallies scheduled check-ins. Pending ones are left out.
+type AdherenceCounts struct {
+	Scheduled int `json:"scheduled"`
+	Done      int `json:"done"`
+	Skipped   int `json:"skipped"`
+	Missed    int `json:"missed"`
+	// Rate is the share of scheduled check-ins done, when any were scheduled
+	Rate *float64 `json:"rate,omitempty"`
+}
+
+type RoutineAdherence struct {
+	RoutineID uuid.UUID `json:"routineId"`
+	Kind      string    `json:"kind"`
+	Title     string    `json:"title"`
+	AdherenceCounts
+}
+
+type DailyAdherence struct {
+	Date string `json:"date"`
+	AdherenceCounts
+}
+
+// AdherenceResponse covers the last Days days, today included
+type AdherenceResponse struct {
+	Days int `json:"days"`
+	AdherenceCounts
+	Routines []RoutineAdherence `json:"routines"`
+	Daily    []DailyAdherence   `json:"daily"`
+}
+
+func (a *AdherenceCounts) add(status string) {
+	a.Scheduled++
+	switch status {
+	case models.CheckInDone:
+		a.Done++
+	case models.CheckInSkipped:
+		a.Skipped++
+	default:
+		a.Missed++
+	}
+}
+
+func (a *AdherenceCounts) finish() {
+	if a.Scheduled > 0 {
+		rate := float64(a.Done) / float64(a.Scheduled)
+		a.Rate = &rate
+	}
+}
+
+func newRoutineResponse(routine *models.Routine) RoutineResponse {
+	return RoutineResponse{
+		ID:           routine.ID,
+		Kind:         routine.Kind,
+		Title:        routine.Title,
+		Instructions: routine.Instructions,
+		Recurrence:   routine.Recurrence,
+		Times:        routine.Times,
+		StartsOn:     routine.StartsOn.Format(dateLayout),
+		GraceMinutes: routine.GraceMinutes,
+		NextDueAt:    routine.NextDueAt,
+		CreatedAt:    routine.CreatedAt,
+	}
+}
+
+func newCheckInResponse(checkIn *models.CheckIn) CheckInResponse {
+	return CheckInResponse{
+		ID:          checkIn.ID,
+		RoutineID:   checkIn.RoutineID,
+		ScheduledAt: checkIn.ScheduledAt,
+		Status:      checkIn.Status,
+		Note:        checkIn.Note,
+		CheckedInAt: checkIn.CheckedInAt,
+	}
+}
+
+// apply checks req and copies it onto routine. startsOn is used when the
+// request names no start date.
+func (req *RoutineRequest) apply(routine *models.Routine, startsOn time.Time) *apierror.Error {
+	rule, err := recurrence.Parse(req.Recurrence)
+	if err != nil {
+		return errInvalidRecurrence
+	}
+
+	times := make([]string, 0, len(req.Times))
+	for _, at := range req.Times {
+		if !slices.Contains(times, at) {
+			times = append(times, at)
+		}
+	}
+	sort.Strings(times)
+
+	routine.Kind = req.Kind
+	routine.Title = req.Title
+	routine.Instructions = req.Instructions
+	routine.Recurrence = rule.String()
+	routine.Times = times
+	routine.StartsOn = startsOn
+	if date := parseDate(req.StartsOn); date != nil {
+		routine.StartsOn = *date
+	}
+	routine.GraceMinutes = req.GraceMinutes
+	if routine.GraceMinutes == 0 {
+		routine.GraceMinutes = defaultGraceMinutes
+	}
+	return nil
+}
+
+// location is the user's timezone, UTC when it is unset or unknown
+func location(user *models.User) *time.Location {
+	if user.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(user.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// localDay is the calendar date of t in loc, at midnight UTC
+func localDay(t time.Time, loc *time.Location) time.Time {
+	year, month, day := t.In(loc).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// occurrences lists when routine is scheduled from from until to, soonest
+// first, with its times of day read in loc
+func occurrences(routine *models.Routine, loc *time.Location, from, to time.Time) []time.Time {
+	rule, err := recurrence.Parse(routine.Recurrence)
+	if err != nil {
+		return nil
+	}
+	var scheduled []time.Time
+	for day, last := localDay(from, loc), localDay(to, loc); !day.After(last); day = day.AddDate(0, 0, 1) {
+		if !rule.Matches(routine.StartsOn, day) {
+			continue
+		}
+		for _, at := range routine.Times {
+			clock, err := time.Parse(timeLayout, at)
+			if err != nil {
+				continue
+			}
+			t := time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+			if !t.Before(from) && t.Before(to) {
+				scheduled = append(scheduled, t)
+			}
+		}
+	}
+	return scheduled
+}
+
+// nextOccurrenceAfter is the routine's first occurrence after after, or nil
+// when its recurrence has ended
+func nextOccurrenceAfter(routine *models.Routine, loc *time.Location, after time.Time) *time.Time {
+	from := after.Truncate(time.Minute).Add(time.Minute)
+	end := from.AddDate(0, 0, occurrenceSearchDays)
+	for start := from; start.Before(end); start = start.AddDate(0, 1, 0) {
+		if found := occurrences(routine, loc, start, start.AddDate(0, 1, 0)); len(found) > 0 {
+			return &found[0]
+		}
+	}
+	return nil
+}
+
+// isScheduled reports whether routine has an occurrence at exactly t
+func isScheduled(routine *models.Routine, loc *time.Location, t time.Time) bool {
+	found := occurrences(routine, loc, t, t.Add(time.Minute))
+	return len(found) == 1 && found[0].Equal(t)
+}
+
+// occurrenceStatus is the status of an occurrence at now: the check-in's
+// when there is one, otherwise missed once the grace period has passed
+func occurrenceStatus(routine *models.Routine, scheduledAt time.Time, checkIn *models.CheckIn, now time.Time) string {
+	if checkIn != nil {
+		return checkIn.Status
+	}
+	if now.Before(scheduledAt.Add(time.Duration(routine.GraceMinutes) * time.Minute)) {
+		return CheckInPending
+	}
+	return models.CheckInMissed
+}
+
+// occurrenceKey identifies an occurrence of a routine
+type occurrenceKey struct {
+	routineID uuid.UUID
+	at        int64
+}
+
+func checkInsByOccurrence(checkIns []models.CheckIn) map[occurrenceKey]*models.CheckIn {
+	byOccurrence := make(map[occurrenceKey]*models.CheckIn, len(checkIns))
+	for i := range checkIns {
+		byOccurrence[occurrenceKey{checkIns[i].RoutineID, checkIns[i].ScheduledAt.Unix()}] = &checkIns[i]
+	}
+	return byOccurrence
+}
+
+// userLocation loads the user's timezone
+func (h *Handler) userLocation(ctx context.Context, userID uuid.UUID) (*time.Location, error) {
+	user, err := h.store.Users.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return location(user), nil
+}
+
+// daySchedule lists the user's routine occurrences on day, a date in loc,
+// in order with their check-ins. Occurrences from before a routine was
+// created are left out.
+func (h *Handler) daySchedule(ctx context.Context, userID uuid.UUID, loc *time.Location, day, now time.Time) ([]ScheduledCheckIn, error) {
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+	to := from.AddDate(0, 0, 1)
+
+	routines, err := h.store.Routines.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	checkIns, err := h.store.CheckIns.ListByUser(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	byOccurrence := checkInsByOccurrence(checkIns)
+
+	schedule := []ScheduledCheckIn{}
+	for i := range routines {
+		routine := &routines[i]
+		for _, at := range occurrences(routine, loc, from, to) {
+			checkIn := byOccurrence[occurrenceKey{routine.ID, at.Unix()}]
+			if checkIn == nil && at.Before(routine.CreatedAt) {
+				continue
+			}
+			item := ScheduledCheckIn{
+				RoutineID:    routine.ID,
+				Kind:         routine.Kind,
+				Title:        routine.Title,
+				Instructions: routine.Instructions,
+				Time:         at.Format(timeLayout),
+				ScheduledAt:  at,
+				Status:       occurrenceStatus(routine, at, checkIn, now),
+			}
+			if checkIn != nil {
+				item.Note, item.CheckedInAt = checkIn.Note, checkIn.CheckedInAt
+			}
+			schedule = append(schedule, item)
+		}
+	}
+	sort.SliceStable(schedule, func(i, j int) bool {
+		if !schedule[i].ScheduledAt.Equal(schedule[j].ScheduledAt) {
+			return schedule[i].ScheduledAt.Before(schedule[j].ScheduledAt)
+		}
+		return schedule[i].Title < schedule[j].Title
+	})
+	return schedule, nil
+}
+
+// CreateRoutine adds a routine, such as tablets at 09:00 every day
+func (h *Handler) CreateRoutine(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	var req RoutineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, apierror.Bind(err))
+		return
+	}
+
+	loc, err := h.userLocation(c, userUUID)
+	if err != nil {
+		apierror.Abort(c, errUserNotFound)
+		return
+	}
+
+	// Occurrences from before the routine existed are not expected of the user
+	now := h.now()
+	routine := models.Routine{UserID: userUUID, CreatedAt: now}
+	if apiErr := req.apply(&routine, localDay(now, loc)); apiErr != nil {
+		apierror.Abort(c, apiErr)
+		return
+	}
+	routine.NextDueAt = nextOccurrenceAfter(&routine, loc, now)
+	routine.NotifyAt = routine.NextDueAt
+
+	if err := h.store.Routines.Create(c, &routine); err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to create routine", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, newRoutineResponse(&routine))
+}
+
+// GetRoutines lists the user's routines
+func (h *Handler) GetRoutines(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	routines, err := h.store.Routines.ListByUser(c, userUUID)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to get routines", err))
+		return
+	}
+
+	responses := make([]RoutineResponse, 0, len(routines))
+	for i := range routines {
+		responses = append(responses, newRoutineResponse(&routines[i]))
+	}
+	c.JSON(http.StatusOK, responses)
+}
+
+// UpdateRoutine replaces a routine. Its check-ins are kept, and the next
+// occurrence is worked out again from now.
+func (h *Handler) UpdateRoutine(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	routineID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Abort(c, errInvalidRoutineID)
+		return
+	}
+
+	var req RoutineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, apierror.Bind(err))
+		return
+	}
+
+	var routine *models.Routine
+	err = h.store.Transaction(c, func(tx *repository.Store) error {
+		user, err := tx.Users.Get(c, userUUID)
+		if err != nil {
+			return err
+		}
+		routine, err = tx.Routines.GetForUser(c, routineID, userUUID)
+		if err != nil {
+			return err
+		}
+		if apiErr := req.apply(routine, routine.StartsOn); apiErr != nil {
+			return apiErr
+		}
+		routine.NextDueAt = nextOccurrenceAfter(routine, location(user), h.now())
+		routine.NotifyAt = routine.NextDueAt
+		return tx.Routines.Update(c, routine)
+	})
+	var apiErr *apierror.Error
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		apierror.Abort(c, errRoutineNotFound)
+	case errors.As(err, &apiErr):
+		apierror.Abort(c, apiErr)
+	case err != nil:
+		apierror.Abort(c, apierror.Internal("Failed to update routine", err))
+	default:
+		c.JSON(http.StatusOK, newRoutineResponse(routine))
+	}
+}
+
+// DeleteRoutine removes a routine along with its check-ins
+func (h *Handler) DeleteRoutine(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	routineID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Abort(c, errInvalidRoutineID)
+		return
+	}
+
+	err = h.store.Transaction(c, func(tx *repository.Store) error {
+		if _, err := tx.Routines.GetForUser(c, routineID, userUUID); err != nil {
+			return err
+		}
+		return tx.Routines.Delete(c, routineID)
+	})
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		apierror.Abort(c, errRoutineNotFound)
+	case err != nil:
+		apierror.Abort(c, apierror.Internal("Failed to delete routine", err))
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Routine deleted successfully"})
+	}
+}
+
+// CheckIn records that the user did, or skipped, one occurrence of a
+// routine. An occurrence already recorded as missed can still be confirmed.
+func (h *Handler) CheckIn(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	routineID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Abort(c, errInvalidRoutineID)
+		return
+	}
+
+	var req CheckInRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, apierror.Bind(err))
+		return
+	}
+
+	now := h.now()
+	if req.ScheduledAt.After(now.Add(earlyCheckIn)) {
+		apierror.Abort(c, errCheckInTooEarly)
+		return
+	}
+
+	var checkIn *models.CheckIn
+	err = h.store.Transaction(c, func(tx *repository.Store) error {
+		user, err := tx.Users.Get(c, userUUID)
+		if err != nil {
+			return err
+		}
+		routine, err := tx.Routines.GetForUser(c, routineID, userUUID)
+		if err != nil {
+			return err
+		}
+		if !isScheduled(routine, location(user), req.ScheduledAt) {
+			return errNotScheduled
+		}
+
+		checkIn, err = tx.CheckIns.GetByOccurrence(c, routine.ID, req.ScheduledAt)
+		if errors.Is(err, repository.ErrNotFound) {
+			checkIn = &models.CheckIn{UserID: userUUID, RoutineID: routine.ID, ScheduledAt: req.ScheduledAt}
+		} else if err != nil {
+			return err
+		}
+		checkIn.Status = req.Status
+		checkIn.Note = req.Note
+		checkIn.CheckedInAt = &now
+		if checkIn.ID == uuid.Nil {
+			return tx.CheckIns.Create(c, checkIn)
+		}
+		return tx.CheckIns.Update(c, checkIn)
+	})
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		apierror.Abort(c, errRoutineNotFound)
+	case errors.Is(err, errNotScheduled):
+		apierror.Abort(c, errNotScheduled)
+	case errors.Is(err, repository.ErrDuplicate):
+		apierror.Abort(c, errCheckInConflict)
+	case err != nil:
+		apierror.Abort(c, apierror.Internal("Failed to check in", err))
+	default:
+		c.JSON(http.StatusOK, newCheckInResponse(checkIn))
+	}
+}
+
+// GetSchedule lists the user's routine occurrences on the date in the query,
+// today in their timezone unless it says otherwise
+func (h *Handler) GetSchedule(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	loc, err := h.userLocation(c, userUUID)
+	if err != nil {
+		apierror.Abort(c, errUserNotFound)
+		return
+	}
+
+	now := h.now()
+	day := localDay(now, loc)
+	if value := c.Query("date"); value != "" {
+		if day, err = time.Parse(dateLayout, value); err != nil {
+			apierror.Abort(c, errInvalidDate)
+			return
+		}
+	}
+
+	schedule, err := h.daySchedule(c, userUUID, loc, day, now)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to get schedule", err))
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+// GetAdherence reports how many scheduled check-ins were done, skipped or
+// missed over the last days days, per routine and per day
+func (h *Handler) GetAdherence(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	days, ok := queryDays(c, defaultAdherenceDays)
+	if !ok {
+		return
+	}
+
+	loc, err := h.userLocation(c, userUUID)
+	if err != nil {
+		apierror.Abort(c, errUserNotFound)
+		return
+	}
+
+	routines, err := h.store.Routines.ListByUser(c, userUUID)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to get routines", err))
+		return
+	}
+
+	now := h.now()
+	first := localDay(now, loc).AddDate(0, 0, 1-days)
+	from := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, loc)
+	checkIns, err := h.store.CheckIns.ListByUser(c, userUUID, from, now)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to get check-ins", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, buildAdherence(routines, checkIns, loc, from, now, days))
+}
+
+// buildAdherence tallies each routine's occurrences from from until now
+// against the check-ins recorded for them. Check-ins for times a routine is
+// no longer scheduled at still count.
+func buildAdherence(routines []models.Routine, checkIns []models.CheckIn, loc *time.Location, from, now time.Time, days int) AdherenceResponse {
+	response := AdherenceResponse{Days: days, Routines: []RoutineAdherence{}, Daily: make([]DailyAdherence, days)}
+	dayIndex := make(map[string]int, days)
+	for i := range response.Daily {
+		date := localDay(from, loc).AddDate(0, 0, i).Format(dateLayout)
+		response.Daily[i].Date = date
+		dayIndex[date] = i
+	}
+
+	byOccurrence := checkInsByOccurrence(checkIns)
+	routineIndex := make(map[uuid.UUID]int, len(routines))
+	count := func(routineID uuid.UUID, at time.Time, status string) {
+		if status == CheckInPending {
+			return
+		}
+		response.Routines[routineIndex[routineID]].add(status)
+		response.add(status)
+		if i, ok := dayIndex[at.In(loc).Format(dateLayout)]; ok {
+			response.Daily[i].add(status)
+		}
+	}
+
+	for i := range routines {
+		routine := &routines[i]
+		routineIndex[routine.ID] = len(response.Routines)
+		response.Routines = append(response.Routines, RoutineAdherence{RoutineID: routine.ID, Kind: routine.Kind, Title: routine.Title})
+		start := from
+		if routine.CreatedAt.After(start) {
+			start = routine.CreatedAt
+		}
+		for _, at := range occurrences(routine, loc, start, now) {
+			key := occurrenceKey{routine.ID, at.Unix()}
+			count(routine.ID, at, occurrenceStatus(routine, at, byOccurrence[key], now))
+			delete(byOccurrence, key)
+		}
+	}
+	for i := range checkIns {
+		checkIn := &checkIns[i]
+		if _, left := byOccurrence[occurrenceKey{checkIn.RoutineID, checkIn.ScheduledAt.Unix()}]; left {
+			count(checkIn.RoutineID, checkIn.ScheduledAt, checkIn.Status)
+		}
+	}
+
+	response.finish()
+	for i := range response.Routines {
+		response.Routines[i].finish()
+	}
+	for i := range response.Daily {
+		response.Daily[i].finish()
+	}
+	return response
+}
+
+// ProcessCheckIns notifies the user as each routine occurrence comes due,
+// records a missed check-in for each occurrence nobody confirmed within its
+// grace period and emails the user's caregiver about it. Rows are claimed
+// with row locks, so several instances may run it at once. It returns how
+// many caregiver emails were queued.
+func (h *Handler) ProcessCheckIns(ctx context.Context) (int, error) {
+	if err := h.notifyDueRoutines(ctx); err != nil {
+		return 0, fmt.Errorf("notify due routines: %w", err)
+	}
+	if err := h.recordMissedCheckIns(ctx); err != nil {
+		return 0, fmt.Errorf("record missed check-ins: %w", err)
+	}
+	return h.escalateMissedCheckIns(ctx)
+}
+
+// notifyDueRoutines notifies the user of the routine occurrences that have
+// come due. Occurrences already checked in for, or past their grace period
+// because the scheduler was not running, are passed over.
+func (h *Handler) notifyDueRoutines(ctx context.Context) error {
+	for {
+		var claimed int
+		err := h.store.Transaction(ctx, func(tx *repository.Store) error {
+			now := h.now()
+			routines, err := tx.Routines.ClaimNotifyDue(ctx, now, routineBatchSize)
+			if err != nil {
+				return err
+			}
+			claimed = len(routines)
+			locations := make(map[uuid.UUID]*time.Location)
+			for i := range routines {
+				routine := &routines[i]
+				loc, ok := locations[routine.UserID]
+				if !ok {
+					user, err := tx.Users.Get(ctx, routine.UserID)
+					if err != nil {
+						return err
+					}
+					loc = location(user)
+					locations[routine.UserID] = loc
+				}
+
+				grace := time.Duration(routine.GraceMinutes) * time.Minute
+				for routine.NotifyAt != nil && !routine.NotifyAt.After(now) {
+					due := *routine.NotifyAt
+					routine.NotifyAt = nextOccurrenceAfter(routine, loc, due)
+					if !now.Before(due.Add(grace)) {
+						continue
+					}
+					_, err := tx.CheckIns.GetByOccurrence(ctx, routine.ID, due)
+					if err == nil {
+						continue
+					}
+					if !errors.Is(err, repository.ErrNotFound) {
+						return err
+					}
+					if err := h.notify(ctx, tx, routineDueNotification(routine, loc, due)); err != nil {
+						return err
+					}
+				}
+				if err := tx.Routines.Update(ctx, routine); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil || claimed < routineBatchSize {
+			return err
+		}
+	}
+}
+
+// routineDueNotification tells the user an occurrence of routine is due
+func routineDueNotification(routine *models.Routine, loc *time.Location, due time.Time) *models.Notification {
+	body := "Due at " + due.In(loc).Format(timeLayout) + "."
+	if routine.Instructions != "" {
+		body += " " + routine.Instructions
+	}
+	return &models.Notification{
+		UserID: routine.UserID,
+		Type:   models.NotificationRoutineDue,
+		Title:  "Time for " + routine.Title,
+		Body:   body,
+		URL:    "/today",
+		Data:   map[string]string{"routineId": routine.ID.String(), "scheduledAt": due.UTC().Format(time.RFC3339)},
+	}
+}
+
+// checkInMissedNotification tells the user nobody checked in for an
+// occurrence of routine
+func checkInMissedNotification(routine *models.Routine, loc *time.Location, checkIn *models.CheckIn) *models.Notification {
+	return &models.Notification{
+		UserID: routine.UserID,
+		Type:   models.NotificationCheckInMissed,
+		Title:  routine.Title + " was not checked in",
+		Body:   "It was due at " + checkIn.ScheduledAt.In(loc).Format(timeLayout) + ". You can still check in if you did it.",
+		URL:    "/today",
+		Data: map[string]string{
+			"routineId":   routine.ID.String(),
+			"checkInId":   checkIn.ID.String(),
+			"scheduledAt": checkIn.ScheduledAt.UTC().Format(time.RFC3339),
+		},
+	}
+}
+
+// recordMissedCheckIns settles the occurrences whose grace period has passed
+// and moves each routine on to its next occurrence. The user is notified of
+// missed check-ins of the last day.
+func (h *Handler) recordMissedCheckIns(ctx context.Context) error {
+	for {
+		var claimed int
+		err := h.store.Transaction(ctx, func(tx *repository.Store) error {
+			now := h.now()
+			routines, err := tx.Routines.ClaimDue(ctx, now, routineBatchSize)
+			if err != nil {
+				return err
+			}
+			claimed = len(routines)
+			locations := make(map[uuid.UUID]*time.Location)
+			for i := range routines {
+				routine := &routines[i]
+				loc, ok := locations[routine.UserID]
+				if !ok {
+					user, err := tx.Users.Get(ctx, routine.UserID)
+					if err != nil {
+						return err
+					}
+					loc = location(user)
+					locations[routine.UserID] = loc
+				}
+
+				grace := time.Duration(routine.GraceMinutes) * time.Minute
+				for routine.NextDueAt != nil && !routine.NextDueAt.Add(grace).After(now) {
+					due := *routine.NextDueAt
+					_, err := tx.CheckIns.GetByOccurrence(ctx, routine.ID, due)
+					if errors.Is(err, repository.ErrNotFound) {
+						missed := models.CheckIn{UserID: routine.UserID, RoutineID: routine.ID, ScheduledAt: due, Status: models.CheckInMissed}
+						err = tx.CheckIns.Create(ctx, &missed)
+						if err == nil && now.Sub(due) < escalationWindow {
+							err = h.notify(ctx, tx, checkInMissedNotification(routine, loc, &missed))
+						}
+					}
+					if err != nil {
+						return err
+					}
+					routine.NextDueAt = nextOccurrenceAfter(routine, loc, due)
+				}
+				if err := tx.Routines.Update(ctx, routine); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil || claimed < routineBatchSize {
+			return err
+		}
+	}
+}
+
+// escalateMissedCheckIns queues emails to caregivers about missed check-ins
+// of the last day, skipping users who turned these emails off
+func (h *Handler) escalateMissedCheckIns(ctx context.Context) (int, error) {
+	var queued int
+	for {
+		var claimed, queuedInBatch int
+		err := h.store.Transaction(ctx, func(tx *repository.Store) error {
+			checkIns, err := tx.CheckIns.ClaimUnescalated(ctx, h.now().Add(-escalationWindow), routineBatchSize)
+			if err != nil {
+				return err
+			}
+			claimed = len(checkIns)
+			for i := range checkIns {
+				checkIn := &checkIns[i]
+				user, err := tx.Users.Get(ctx, checkIn.UserID)
+				if err != nil {
+					return err
+				}
+				settings, err := loadNotificationSettings(ctx, tx, user.ID)
+				if err != nil {
+					return err
+				}
+				// Check-ins the user wants no email for are settled unsent
+				emailed := settings.enabled(models.NotificationCheckInMissed, models.ChannelEmail)
+				if emailed {
+					if err := h.queueMissedCheckInEmail(ctx, tx, user, checkIn); err != nil {
+						return err
+					}
+					queuedInBatch++
+				}
+				now := h.now()
+				checkIn.EscalatedAt = &now
+				if err := tx.CheckIns.Update(ctx, checkIn); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return queued, fmt.Errorf("email missed check-ins: %w", err)
+		}
+		queued += queuedInBatch
+		if claimed < routineBatchSize {
+			return queued, nil
+		}
+	}
+}
+
+// queueMissedCheckInEmail queues an email telling user's caregiver that a
+// check-in was missed
+func (h *Handler) queueMissedCheckInEmail(ctx context.Context, store *repository.Store, user *models.User, checkIn *models.CheckIn) error {
+	data := mailer.CheckInMissedData{
+		Name:         userName(user),
+		Routine:      checkIn.Routine.Title,
+		Instructions: checkIn.Routine.Instructions,
+		Due:          checkIn.ScheduledAt.In(location(user)),
+	}
+	if h.config.FrontendURL != "" {
+		data.URL = h.config.FrontendURL + "/today"
+	}
+	return h.queueEmail(ctx, store, user, user.CaregiverEmail, mailer.CheckInMissed, data)
+}
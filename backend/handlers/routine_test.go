@@ -0,0 +1,289 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/handlers"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RoutineTestSuite struct {
+	suite.Suite
+	env    *testutils.TestEnv
+	router *gin.Engine
+	user   models.User
+	now    time.Time
+	loc    *time.Location
+}
+
+func (suite *RoutineTestSuite) SetupSuite() {
+	gin.SetMode(gin.TestMode)
+}
+
+func (suite *RoutineTestSuite) SetupTest() {
+	// 08:00 on a Monday in New York
+	loc, err := time.LoadLocation("America/New_York")
+	suite.Require().NoError(err)
+	suite.loc = loc
+	suite.now = time.Date(2026, time.March, 2, 8, 0, 0, 0, loc)
+	suite.env = testutils.NewTestEnv(func(deps *handlers.Deps) {
+		deps.Clock = func() time.Time { return suite.now }
+	})
+	h := suite.env.Handler
+
+	suite.user = models.User{Email: "margaret@example.com", Password: "x", DisplayName: "Margaret", EmailConfirmed: true}
+	suite.env.Store.Users.Create(context.Background(), &suite.user)
+
+	suite.router = gin.New()
+	suite.router.Use(testutils.OpenAPIValidator(suite.T()), apierror.Middleware())
+	protected := suite.router.Group("/")
+	protected.Use(func(c *gin.Context) {
+		c.Set("user_id", suite.user.ID)
+		c.Next()
+	})
+	protected.PUT("/profile", h.UpdateProfile)
+	protected.POST("/routines", h.CreateRoutine)
+	protected.GET("/routines", h.GetRoutines)
+	protected.PUT("/routines/:id", h.UpdateRoutine)
+	protected.DELETE("/routines/:id", h.DeleteRoutine)
+	protected.POST("/routines/:id/check-ins", h.CheckIn)
+	protected.GET("/schedule", h.GetSchedule)
+	protected.GET("/adherence", h.GetAdherence)
+
+	w := suite.request("PUT", "/profile", map[string]string{"displayName": "Margaret", "timezone": "America/New_York"})
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+}
+
+func (suite *RoutineTestSuite) request(method, path string, body any) *httptest.ResponseRecorder {
+	var reader bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&reader).Encode(body)
+	}
+	req, _ := http.NewRequest(method, path, &reader)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *RoutineTestSuite) createRoutine(req handlers.RoutineRequest) handlers.RoutineResponse {
+	w := suite.request("POST", "/routines", req)
+	suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+	var routine handlers.RoutineResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &routine))
+	return routine
+}
+
+func (suite *RoutineTestSuite) tablets() handlers.RoutineResponse {
+	return suite.createRoutine(handlers.RoutineRequest{
+		Kind: models.RoutineMedication, Title: "Blood pressure tablets", Instructions: "Two with water",
+		Recurrence: "FREQ=DAILY", Times: []string{"21:00", "09:00"}, GraceMinutes: 30,
+	})
+}
+
+// at is the time of day on the suite's current day in New York
+func (suite *RoutineTestSuite) at(hour, minute int) time.Time {
+	year, month, day := suite.now.In(suite.loc).Date()
+	return time.Date(year, month, day, hour, minute, 0, 0, suite.loc)
+}
+
+func (suite *RoutineTestSuite) checkIn(routineID uuid.UUID, scheduledAt time.Time, status string) *httptest.ResponseRecorder {
+	return suite.request("POST", "/routines/"+routineID.String()+"/check-ins", handlers.CheckInRequest{
+		ScheduledAt: scheduledAt, Status: status,
+	})
+}
+
+func (suite *RoutineTestSuite) schedule(query string) []handlers.ScheduledCheckIn {
+	w := suite.request("GET", "/schedule"+query, nil)
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	var schedule []handlers.ScheduledCheckIn
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &schedule))
+	return schedule
+}
+
+func (suite *RoutineTestSuite) TestCreateAndSchedule() {
+	tablets := suite.tablets()
+	assert.Equal(suite.T(), []string{"09:00", "21:00"}, tablets.Times)
+	assert.Equal(suite.T(), "2026-03-02", tablets.StartsOn)
+	assert.True(suite.T(), suite.at(9, 0).Equal(*tablets.NextDueAt))
+	suite.createRoutine(handlers.RoutineRequest{
+		Kind: models.RoutineActivity, Title: "Walk with Tom", Recurrence: "RRULE:FREQ=WEEKLY;BYDAY=TU,FR", Times: []string{"10:30"},
+	})
+
+	schedule := suite.schedule("")
+	suite.Require().Len(schedule, 2)
+	assert.Equal(suite.T(), "09:00", schedule[0].Time)
+	assert.True(suite.T(), suite.at(9, 0).Equal(schedule[0].ScheduledAt))
+	assert.Equal(suite.T(), handlers.CheckInPending, schedule[0].Status)
+	assert.Equal(suite.T(), "Two with water", schedule[0].Instructions)
+	assert.Equal(suite.T(), "21:00", schedule[1].Time)
+
+	tuesday := suite.schedule("?date=2026-03-03")
+	suite.Require().Len(tuesday, 3)
+	assert.Equal(suite.T(), "Walk with Tom", tuesday[1].Title)
+
+	w := suite.request("GET", "/routines", nil)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "FREQ=WEEKLY;BYDAY=TU,FR")
+}
+
+func (suite *RoutineTestSuite) TestRoutineValidation() {
+	valid := handlers.RoutineRequest{Kind: models.RoutineMedication, Title: "Tablets", Recurrence: "FREQ=DAILY", Times: []string{"09:00"}}
+
+	invalid := valid
+	invalid.Recurrence = "every day"
+	w := suite.request("POST", "/routines", invalid)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "recurrence")
+
+	w = suite.request("POST", "/routines", map[string]any{
+		"kind": "medication", "title": "Tablets", "recurrence": "FREQ=DAILY", "times": []string{"9am"},
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "HH:MM")
+
+	w = suite.request("PUT", "/profile", map[string]string{"displayName": "Margaret", "timezone": "Mars/Olympus"})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "timezone")
+
+	w = suite.request("GET", "/schedule?date=tomorrow", nil)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	w = suite.request("PUT", "/routines/"+suite.user.ID.String(), valid)
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+	w = suite.request("DELETE", "/routines/not-a-uuid", nil)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+func (suite *RoutineTestSuite) TestCheckIn() {
+	tablets := suite.tablets()
+
+	// An hour early is fine; 13 hours is not
+	w := suite.checkIn(tablets.ID, suite.at(9, 0), models.CheckInSkipped)
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	var skipped handlers.CheckInResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &skipped))
+	w = suite.checkIn(tablets.ID, suite.at(21, 0), models.CheckInDone)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	// Checking in again replaces the first answer
+	w = suite.checkIn(tablets.ID, suite.at(9, 0), models.CheckInDone)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var done handlers.CheckInResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &done))
+	assert.Equal(suite.T(), skipped.ID, done.ID)
+	assert.Equal(suite.T(), models.CheckInDone, done.Status)
+
+	schedule := suite.schedule("")
+	assert.Equal(suite.T(), models.CheckInDone, schedule[0].Status)
+	assert.True(suite.T(), suite.now.Equal(*schedule[0].CheckedInAt))
+
+	w = suite.checkIn(tablets.ID, suite.at(9, 30), models.CheckInDone)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "not_scheduled")
+	w = suite.checkIn(suite.user.ID, suite.at(9, 0), models.CheckInDone)
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	w = suite.request("DELETE", "/routines/"+tablets.ID.String(), nil)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	assert.Empty(suite.T(), suite.schedule(""))
+}
+
+func (suite *RoutineTestSuite) TestMissedCheckInEscalation() {
+	tablets := suite.tablets()
+
+	// Within the grace period nothing happens
+	suite.now = suite.at(9, 29)
+	sent, err := suite.env.Handler.ProcessCheckIns(context.Background())
+	suite.Require().NoError(err)
+	assert.Zero(suite.T(), sent)
+	assert.Equal(suite.T(), handlers.CheckInPending, suite.schedule("")[0].Status)
+
+	// Without a caregiver the check-in is recorded as missed but nobody is emailed
+	suite.now = suite.at(9, 30)
+	sent, err = suite.env.Handler.ProcessCheckIns(context.Background())
+	suite.Require().NoError(err)
+	assert.Zero(suite.T(), sent)
+	assert.Equal(suite.T(), models.CheckInMissed, suite.schedule("")[0].Status)
+
+	w := suite.request("PUT", "/profile", map[string]string{"displayName": "Margaret", "caregiverEmail": "carer@example.com"})
+	suite.Require().Equal(http.StatusOK, w.Code)
+	sent, err = suite.env.Handler.ProcessCheckIns(context.Background())
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 1, sent)
+	_, err = suite.env.SendQueuedEmails()
+	suite.Require().NoError(err)
+	emails := suite.env.Email.FindEmailByRecipient("carer@example.com")
+	suite.Require().Len(emails, 1)
+	assert.Equal(suite.T(), "Margaret has not checked in for Blood pressure tablets", emails[0].Subject)
+	assert.Contains(suite.T(), emails[0].Body, "due at 09:00 on Monday, 2 March")
+	assert.Contains(suite.T(), emails[0].Body, "Two with water")
+
+	// Each missed check-in is escalated once, and can still be confirmed
+	sent, err = suite.env.Handler.ProcessCheckIns(context.Background())
+	suite.Require().NoError(err)
+	assert.Zero(suite.T(), sent)
+	w = suite.checkIn(tablets.ID, suite.at(9, 0), models.CheckInDone)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	assert.Equal(suite.T(), models.CheckInDone, suite.schedule("")[0].Status)
+
+	// A confirmed occurrence is not escalated
+	suite.now = suite.at(21, 0)
+	suite.Require().Equal(http.StatusOK, suite.checkIn(tablets.ID, suite.at(21, 0), models.CheckInDone).Code)
+	suite.now = suite.at(23, 0)
+	sent, err = suite.env.Handler.ProcessCheckIns(context.Background())
+	suite.Require().NoError(err)
+	assert.Zero(suite.T(), sent)
+	assert.Len(suite.T(), suite.env.Email.FindEmailByRecipient("carer@example.com"), 1)
+}
+
+func (suite *RoutineTestSuite) TestAdherence() {
+	tablets := suite.tablets()
+	suite.createRoutine(handlers.RoutineRequest{
+		Kind: models.RoutineActivity, Title: "Walk", Recurrence: "FREQ=DAILY", Times: []string{"10:00"},
+	})
+
+	// Monday: 09:00 done, 21:00 missed. Tuesday: 09:00 skipped, 21:00 not due.
+	suite.Require().Equal(http.StatusOK, suite.checkIn(tablets.ID, suite.at(9, 0), models.CheckInDone).Code)
+	suite.now = suite.now.AddDate(0, 0, 1)
+	suite.Require().Equal(http.StatusOK, suite.checkIn(tablets.ID, suite.at(9, 0), models.CheckInSkipped).Code)
+	suite.now = suite.at(12, 0)
+
+	w := suite.request("GET", "/adherence?days=3", nil)
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	var adherence handlers.AdherenceResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &adherence))
+
+	assert.Equal(suite.T(), 3, adherence.Days)
+	assert.Equal(suite.T(), 5, adherence.Scheduled)
+	assert.Equal(suite.T(), 1, adherence.Done)
+	assert.Equal(suite.T(), 1, adherence.Skipped)
+	assert.Equal(suite.T(), 3, adherence.Missed)
+	assert.InDelta(suite.T(), 0.2, *adherence.Rate, 0.001)
+
+	suite.Require().Len(adherence.Routines, 2)
+	assert.Equal(suite.T(), 3, adherence.Routines[0].Scheduled)
+	assert.Equal(suite.T(), 2, adherence.Routines[1].Missed)
+
+	suite.Require().Len(adherence.Daily, 3)
+	assert.Equal(suite.T(), "2026-03-01", adherence.Daily[0].Date)
+	assert.Zero(suite.T(), adherence.Daily[0].Scheduled)
+	assert.Nil(suite.T(), adherence.Daily[0].Rate)
+	assert.Equal(suite.T(), 3, adherence.Daily[1].Scheduled)
+	assert.Equal(suite.T(), 2, adherence.Daily[2].Scheduled)
+}
+
+func TestRoutineTestSuite(t *testing.T) {
+	suite.Run(t, new(RoutineTestSuite))
+}
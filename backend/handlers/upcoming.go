@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/mailer"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/repository"
+)
+
+// dateLayout is how calendar dates such as birthdays are written in the API
+const dateLayout = "2006-01-02"
+
+const (
+	// defaultUpcomingDays is the upcoming window when the request names none
+	defaultUpcomingDays = 30
+	// digestDays is how far ahead the caregiver digest looks
+	digestDays = 7
+	// chatUpcomingDays is how far ahead the assistant is told about dates
+	chatUpcomingDays = 14
+	// digestBatchSize is how many users one digest transaction claims
+	digestBatchSize = 50
+)
+
+// Kinds of upcoming events
+const (
+	EventBirthday          = "birthday"
+	EventAnniversary       = "anniversary"
+	EventDeathAnniversary  = "death_anniversary"
+	EventMemoryAnniversary = "memory_anniversary"
+)
+
+// UpcomingEvent is a date coming up for a person or a memory. Years is the
+// age turned or the number of years since the original date.
+type UpcomingEvent struct {
+	Kind       string     `json:"kind"`
+	Date       string     `json:"date"`
+	DaysAway   int        `json:"daysAway"`
+	Years      int        `json:"years"`
+	Title      string     `json:"title"`
+	PersonID   *uuid.UUID `json:"personId,omitempty"`
+	PersonName string     `json:"personName,omitempty"`
+	// Deceased is set for dates of people who have passed away
+	Deceased bool       `json:"deceased"`
+	MemoryID *uuid.UUID `json:"memoryId,omitempty"`
+
+	on time.Time
+	// memoryTitle is the memory's own title, as Title describes the event
+	memoryTitle string
+}
+
+// parseDate reads an optional date the binding already checked against
+// dateLayout. Dates are kept at midnight UTC.
+func parseDate(value *string) *time.Time {
+	if value == nil || *value == "" {
+		return nil
+	}
+	date, err := time.Parse(dateLayout, *value)
+	if err != nil {
+		return nil
+	}
+	return &date
+}
+
+func formatDate(date *time.Time) *string {
+	if date == nil {
+		return nil
+	}
+	formatted := date.Format(dateLayout)
+	return &formatted
+}
+
+// calendarDay is the date of t in UTC, at midnight
+func calendarDay(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// nextOccurrence is the first anniversary of date on or after day. Dates on
+// 29 February fall on the 28th in other years.
+func nextOccurrence(date, day time.Time) time.Time {
+	occurrence := func(year int) time.Time {
+		d := date.Day()
+		if date.Month() == time.February && d == 29 && !isLeap(year) {
+			d = 28
+		}
+		return time.Date(year, date.Month(), d, 0, 0, 0, 0, time.UTC)
+	}
+	next := occurrence(day.Year())
+	if next.Before(day) {
+		next = occurrence(day.Year() + 1)
+	}
+	return next
+}
+
+func isLeap(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+// upcomingEvents lists the anniversaries of people's dates and of memories
+// from day, the user's date today (see localDay), through the following
+// days days, soonest first
+func upcomingEvents(people []models.Person, memories []models.Memory, day time.Time, days int) []UpcomingEvent {
+	events := []UpcomingEvent{}
+	add := func(kind string, date *time.Time, event UpcomingEvent) {
+		if date == nil {
+			return
+		}
+		on := nextOccurrence(*date, day)
+		away := int(on.Sub(day) / (24 * time.Hour))
+		years := on.Year() - date.Year()
+		if away > days || years < 1 {
+			return
+		}
+		event.Kind, event.on = kind, on
+		event.Date = on.Format(dateLayout)
+		event.DaysAway, event.Years = away, years
+		event.Title = eventTitle(&event)
+		events = append(events, event)
+	}
+
+	for i := range people {
+		person := &people[i]
+		// Copied so each event points at its own ID
+		id := person.ID
+		event := UpcomingEvent{PersonID: &id, PersonName: personName(person), Deceased: person.DiedOn != nil}
+		add(EventBirthday, person.Birthday, event)
+		add(EventAnniversary, person.Anniversary, event)
+		add(EventDeathAnniversary, person.DiedOn, event)
+	}
+	for i := range memories {
+		memory := &memories[i]
+		id := memory.ID
+		add(EventMemoryAnniversary, memory.OccurredOn, UpcomingEvent{MemoryID: &id, Title: memory.Title, memoryTitle: memory.Title})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].DaysAway != events[j].DaysAway {
+			return events[i].DaysAway < events[j].DaysAway
+		}
+		return events[i].Title < events[j].Title
+	})
+	return events
+}
+
+// eventTitle describes event, such as "Tom Hughes turns 12"
+func eventTitle(event *UpcomingEvent) string {
+	switch event.Kind {
+	case EventBirthday:
+		if event.Deceased {
+			return fmt.Sprintf("%s would have turned %d", event.PersonName, event.Years)
+		}
+		return fmt.Sprintf("%s turns %d", event.PersonName, event.Years)
+	case EventAnniversary:
+		return fmt.Sprintf("%s anniversary with %s", ordinal(event.Years), event.PersonName)
+	case EventDeathAnniversary:
+		return fmt.Sprintf("%s since %s passed away", yearsText(event.Years), event.PersonName)
+	default:
+		return fmt.Sprintf("%s since %s", yearsText(event.Years), event.Title)
+	}
+}
+
+func yearsText(years int) string {
+	if years == 1 {
+		return "1 year"
+	}
+	return fmt.Sprintf("%d years", years)
+}
+
+func ordinal(n int) string {
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+// eventWhen says when event is, such as "Friday, 23 October (in 5 days)"
+func eventWhen(event *UpcomingEvent) string {
+	date := event.on.Format("Monday, 2 January")
+	switch event.DaysAway {
+	case 0:
+		return "today, " + date
+	case 1:
+		return "tomorrow, " + date
+	default:
+		return fmt.Sprintf("%s (in %d days)", date, event.DaysAway)
+	}
+}
+
+// GetUpcoming lists birthdays, anniversaries and memory anniversaries from
+// today through the next days days, 30 unless the query says otherwise
+func (h *Handler) GetUpcoming(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		apierror.Abort(c, apierror.ErrUnauthenticated)
+		return
+	}
+
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		apierror.Abort(c, errInvalidUserID)
+		return
+	}
+
+	days, ok := queryDays(c, defaultUpcomingDays)
+	if !ok {
+		return
+	}
+
+	people, err := h.store.People.ListByUser(c, userUUID)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to get people", err))
+		return
+	}
+	memories, err := h.store.Memories.ListByUser(c, userUUID)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to get memories", err))
+		return
+	}
+	loc, err := h.userLocation(c, userUUID)
+	if err != nil {
+		apierror.Abort(c, apierror.Internal("Failed to get user", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, upcomingEvents(people, memories, localDay(h.now(), loc), days))
+}
+
+// ProcessDigests queues an email to each caregiver, once a day in the user's
+// timezone, with the dates coming up for the user they look after in the
+// next week. Users are
+// claimed with row locks, so several instances may run it at once. It
+// returns how many digests were queued.
+func (h *Handler) ProcessDigests(ctx context.Context) (int, error) {
+	var queued int
+	for {
+		var claimed, queuedInBatch int
+		err := h.store.Transaction(ctx, func(tx *repository.Store) error {
+			now := h.now()
+			users, err := tx.Users.ClaimDigestDue(ctx, now, digestBatchSize)
+			if err != nil {
+				return err
+			}
+			claimed = len(users)
+			for i := range users {
+				user := &users[i]
+				people, err := tx.People.ListByUser(ctx, user.ID)
+				if err != nil {
+					return err
+				}
+				memories, err := tx.Memories.ListByUser(ctx, user.ID)
+				if err != nil {
+					return err
+				}
+				// A quiet week still counts as today's digest
+				today := localDay(now, location(user))
+				if events := upcomingEvents(people, memories, today, digestDays); len(events) > 0 {
+					if err := h.queueDigest(ctx, tx, user, events); err != nil {
+						return err
+					}
+					queuedInBatch++
+				}
+				if err := tx.Users.MarkDigestSent(ctx, user.ID, now); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return queued, fmt.Errorf("email digests: %w", err)
+		}
+		queued += queuedInBatch
+		if claimed < digestBatchSize {
+			return queued, nil
+		}
+	}
+}
+
+// userName is how emails to the user's caregiver refer to the user
+func userName(user *models.User) string {
+	if user.DisplayName == "" {
+		return user.Email
+	}
+	return user.DisplayName
+}
+
+// queueDigest queues an email to user's caregiver with the dates coming up
+func (h *Handler) queueDigest(ctx context.Context, store *repository.Store, user *models.User, events []UpcomingEvent) error {
+	data := mailer.DigestData{Name: userName(user), Events: make([]mailer.DigestEvent, len(events))}
+	for i, event := range events {
+		data.Events[i] = mailer.DigestEvent{
+			Kind:       event.Kind,
+			PersonName: event.PersonName,
+			Title:      event.memoryTitle,
+			Years:      event.Years,
+			Deceased:   event.Deceased,
+			Date:       event.on,
+			DaysAway:   event.DaysAway,
+		}
+	}
+	if h.config.FrontendURL != "" {
+		data.URL = h.config.FrontendURL + "/people"
+	}
+	return h.queueEmail(ctx, store, user, user.CaregiverEmail, mailer.CaregiverDigest, data)
+}
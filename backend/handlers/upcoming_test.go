@@ -0,0 +1,302 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/handlers"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type UpcomingTestSuite struct {
+	suite.Suite
+	env    *testutils.TestEnv
+	router *gin.Engine
+	user   models.User
+	now    time.Time
+}
+
+func (suite *UpcomingTestSuite) SetupSuite() {
+	gin.SetMode(gin.TestMode)
+}
+
+func (suite *UpcomingTestSuite) SetupTest() {
+	// A Monday
+	suite.now = time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC)
+	suite.env = testutils.NewTestEnv(func(deps *handlers.Deps) {
+		deps.Clock = func() time.Time { return suite.now }
+	})
+	h := suite.env.Handler
+
+	suite.user = models.User{Email: "margaret@example.com", Password: "x", DisplayName: "Margaret", EmailConfirmed: true}
+	suite.env.Store.Users.Create(context.Background(), &suite.user)
+
+	suite.router = gin.New()
+	suite.router.Use(testutils.OpenAPIValidator(suite.T()), apierror.Middleware())
+	protected := suite.router.Group("/")
+	protected.Use(func(c *gin.Context) {
+		c.Set("user_id", suite.user.ID)
+		c.Next()
+	})
+	protected.PUT("/profile", h.UpdateProfile)
+	protected.POST("/people", h.CreatePerson)
+	protected.PUT("/people/:id/dates", h.SetPersonDates)
+	protected.POST("/memories", h.CreateMemory)
+	protected.GET("/upcoming", h.GetUpcoming)
+	protected.GET("/reminders", h.GetReminders)
+}
+
+func (suite *UpcomingTestSuite) request(method, path string, body any) *httptest.ResponseRecorder {
+	var reader bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&reader).Encode(body)
+	}
+	req, _ := http.NewRequest(method, path, &reader)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func date(value string) *string {
+	return &value
+}
+
+func (suite *UpcomingTestSuite) createPerson(firstName string, dates handlers.PersonDatesRequest) handlers.PersonResponse {
+	w := suite.request("POST", "/people", handlers.CreatePersonRequest{
+		FirstName: firstName, LastName: "Hughes", Email: "family@example.com", Phone: "555-0100",
+		Relationship: "Family", PersonDatesRequest: dates,
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code, w.Body.String())
+	var person handlers.PersonResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &person))
+	return person
+}
+
+func (suite *UpcomingTestSuite) upcoming(query string) []handlers.UpcomingEvent {
+	w := suite.request("GET", "/upcoming"+query, nil)
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var events []handlers.UpcomingEvent
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &events))
+	return events
+}
+
+func (suite *UpcomingTestSuite) TestUpcoming() {
+	tom := suite.createPerson("Tom", handlers.PersonDatesRequest{Birthday: date("2014-03-06")})
+	suite.Equal("2014-03-06", *tom.Birthday)
+	suite.createPerson("Ann", handlers.PersonDatesRequest{Birthday: date("1950-06-01"), Anniversary: date("1970-03-02")})
+	suite.createPerson("Frank", handlers.PersonDatesRequest{Birthday: date("1940-03-10"), DiedOn: date("2021-03-20")})
+	w := suite.request("POST", "/memories", handlers.CreateMemoryRequest{
+		Title: "Lake trip", Type: "story", Content: "Swimming at dawn", OccurredOn: date("2016-03-03"),
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+
+	var titles []string
+	for _, event := range suite.upcoming("") {
+		titles = append(titles, event.Date+" "+event.Title)
+	}
+	assert.Equal(suite.T(), []string{
+		"2026-03-02 56th anniversary with Ann Hughes",
+		"2026-03-03 10 years since Lake trip",
+		"2026-03-06 Tom Hughes turns 12",
+		"2026-03-10 Frank Hughes would have turned 86",
+		"2026-03-20 5 years since Frank Hughes passed away",
+	}, titles)
+
+	events := suite.upcoming("?days=7")
+	suite.Require().Len(events, 3)
+	birthday := events[2]
+	assert.Equal(suite.T(), handlers.EventBirthday, birthday.Kind)
+	assert.Equal(suite.T(), 4, birthday.DaysAway)
+	assert.Equal(suite.T(), 12, birthday.Years)
+	assert.Equal(suite.T(), tom.ID, *birthday.PersonID)
+	assert.False(suite.T(), birthday.Deceased)
+
+	w = suite.request("GET", "/upcoming?days=0", nil)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+func (suite *UpcomingTestSuite) TestLeapDayBirthday() {
+	suite.createPerson("Leo", handlers.PersonDatesRequest{Birthday: date("2000-02-29")})
+	suite.now = time.Date(2027, time.February, 27, 9, 0, 0, 0, time.UTC)
+
+	events := suite.upcoming("?days=3")
+	suite.Require().Len(events, 1)
+	assert.Equal(suite.T(), "2027-02-28", events[0].Date)
+	assert.Equal(suite.T(), 27, events[0].Years)
+}
+
+func (suite *UpcomingTestSuite) TestDatesValidation() {
+	w := suite.request("POST", "/people", map[string]any{
+		"firstName": "Tom", "lastName": "Hughes", "email": "tom@example.com", "phone": "555-0100",
+		"relationship": "Son", "birthday": "6 March 2014",
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "birthday")
+
+	person := suite.createPerson("Tom", handlers.PersonDatesRequest{})
+	w = suite.request("PUT", "/people/"+person.ID.String()+"/dates", handlers.PersonDatesRequest{DiedOn: date("2026-03-03")})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "diedOn")
+
+	w = suite.request("POST", "/memories", handlers.CreateMemoryRequest{
+		Title: "Next year", Type: "story", Content: "Not yet", OccurredOn: date("2027-01-01"),
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w = suite.request("PUT", "/people/"+suite.user.ID.String()+"/dates", handlers.PersonDatesRequest{})
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+}
+
+func (suite *UpcomingTestSuite) TestDateOfDeathStopsReminders() {
+	w := suite.request("POST", "/people", handlers.CreatePersonRequest{
+		FirstName: "Frank", LastName: "Hughes", Email: "frank@example.com", Phone: "555-0100",
+		Relationship: "Husband", ContactEveryDays: 7,
+	})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+	var person handlers.PersonResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &person))
+
+	suite.now = suite.now.AddDate(0, 0, 7)
+	_, err := suite.env.Handler.ProcessReminders(context.Background())
+	suite.Require().NoError(err)
+
+	w = suite.request("PUT", "/people/"+person.ID.String()+"/dates", handlers.PersonDatesRequest{
+		Birthday: date("1940-03-10"), DiedOn: date("2026-03-05"),
+	})
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var updated handlers.PersonResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &updated))
+	assert.Equal(suite.T(), "2026-03-05", *updated.DiedOn)
+	assert.Nil(suite.T(), updated.NextReminderAt)
+	assert.Equal(suite.T(), "[]", suite.request("GET", "/reminders", nil).Body.String())
+
+	// Dates left out are cleared
+	w = suite.request("PUT", "/people/"+person.ID.String()+"/dates", handlers.PersonDatesRequest{DiedOn: date("2026-03-05")})
+	suite.Require().Equal(http.StatusOK, w.Code)
+	updated = handlers.PersonResponse{}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &updated))
+	assert.Nil(suite.T(), updated.Birthday)
+}
+
+func (suite *UpcomingTestSuite) TestCaregiverDigest() {
+	suite.createPerson("Tom", handlers.PersonDatesRequest{Birthday: date("2014-03-06")})
+	suite.createPerson("Ann", handlers.PersonDatesRequest{Birthday: date("1950-06-01")})
+
+	// No caregiver, no digest
+	sent, err := suite.env.Handler.ProcessDigests(context.Background())
+	suite.Require().NoError(err)
+	assert.Zero(suite.T(), sent)
+
+	w := suite.request("PUT", "/profile", map[string]string{"displayName": "Margaret", "caregiverEmail": "not an email"})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+	w = suite.request("PUT", "/profile", map[string]string{"displayName": "Margaret", "caregiverEmail": "carer@example.com"})
+	suite.Require().Equal(http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), "carer@example.com")
+
+	sent, err = suite.env.Handler.ProcessDigests(context.Background())
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 1, sent)
+	_, err = suite.env.SendQueuedEmails()
+	suite.Require().NoError(err)
+	emails := suite.env.Email.FindEmailByRecipient("carer@example.com")
+	suite.Require().Len(emails, 1)
+	assert.Equal(suite.T(), "Coming up for Margaret", emails[0].Subject)
+	assert.Contains(suite.T(), emails[0].Body, "Friday, 6 March (in 4 days): Tom Hughes turns 12")
+	assert.NotContains(suite.T(), emails[0].Body, "Ann")
+
+	// Once a day
+	suite.now = suite.now.Add(6 * time.Hour)
+	sent, err = suite.env.Handler.ProcessDigests(context.Background())
+	suite.Require().NoError(err)
+	assert.Zero(suite.T(), sent)
+
+	// A failed delivery is retried by the outbox, not by queueing the
+	// digest again
+	suite.now = suite.now.AddDate(0, 0, 1)
+	suite.env.Email.SetSendError(errors.New("smtp down"))
+	sent, err = suite.env.Handler.ProcessDigests(context.Background())
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 1, sent)
+	_, err = suite.env.SendQueuedEmails()
+	assert.ErrorContains(suite.T(), err, "smtp down")
+	suite.env.Email.SetSendError(nil)
+	suite.now = suite.now.Add(time.Minute)
+	sent, err = suite.env.Handler.ProcessDigests(context.Background())
+	suite.Require().NoError(err)
+	assert.Zero(suite.T(), sent)
+	_, err = suite.env.SendQueuedEmails()
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), suite.env.Email.FindEmailByRecipient("carer@example.com"), 2)
+
+	// Removing the caregiver stops the digest
+	w = suite.request("PUT", "/profile", map[string]string{"displayName": "Margaret", "caregiverEmail": ""})
+	suite.Require().Equal(http.StatusOK, w.Code)
+	suite.now = suite.now.AddDate(0, 0, 1)
+	sent, err = suite.env.Handler.ProcessDigests(context.Background())
+	suite.Require().NoError(err)
+	assert.Zero(suite.T(), sent)
+}
+
+func (suite *UpcomingTestSuite) TestUpcomingInUserTimezone() {
+	suite.createPerson("Tom", handlers.PersonDatesRequest{Birthday: date("2014-03-06")})
+	w := suite.request("PUT", "/profile", map[string]string{
+		"displayName": "Margaret", "caregiverEmail": "carer@example.com", "timezone": "Pacific/Auckland",
+	})
+	suite.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+
+	// Noon on the 5th in UTC is already 01:00 on the 6th in Auckland
+	suite.now = time.Date(2026, time.March, 5, 12, 0, 0, 0, time.UTC)
+	events := suite.upcoming("?days=7")
+	suite.Require().Len(events, 1)
+	assert.Equal(suite.T(), "2026-03-06", events[0].Date)
+	assert.Zero(suite.T(), events[0].DaysAway)
+
+	sent, err := suite.env.Handler.ProcessDigests(context.Background())
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 1, sent)
+	_, err = suite.env.SendQueuedEmails()
+	suite.Require().NoError(err)
+	emails := suite.env.Email.FindEmailByRecipient("carer@example.com")
+	suite.Require().Len(emails, 1)
+	assert.Contains(suite.T(), emails[0].Body, "today, Friday, 6 March: Tom Hughes turns 12")
+
+	// The next digest is due at midnight in Auckland, not in UTC
+	suite.now = time.Date(2026, time.March, 5, 23, 0, 0, 0, time.UTC)
+	sent, err = suite.env.Handler.ProcessDigests(context.Background())
+	suite.Require().NoError(err)
+	assert.Zero(suite.T(), sent, "still the 6th in Auckland")
+	suite.now = time.Date(2026, time.March, 6, 10, 0, 0, 0, time.UTC)
+	assert.Equal(suite.T(), "2026-03-06", suite.upcoming("?days=1")[0].Date, "the 6th until midnight")
+	suite.now = time.Date(2026, time.March, 6, 11, 0, 0, 0, time.UTC)
+	_, err = suite.env.Handler.ProcessDigests(context.Background())
+	suite.Require().NoError(err)
+	user, err := suite.env.Store.Users.Get(context.Background(), suite.user.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), suite.now, user.LastDigestAt.UTC(), "the 7th has begun")
+
+	// Evening on the 5th in Los Angeles is already the 6th in UTC
+	w = suite.request("PUT", "/profile", map[string]string{
+		"displayName": "Margaret", "caregiverEmail": "carer@example.com", "timezone": "America/Los_Angeles",
+	})
+	suite.Require().Equal(http.StatusOK, w.Code)
+	suite.now = time.Date(2026, time.March, 6, 3, 0, 0, 0, time.UTC)
+	events = suite.upcoming("?days=7")
+	suite.Require().Len(events, 1)
+	assert.Equal(suite.T(), 1, events[0].DaysAway)
+}
+
+func TestUpcomingTestSuite(t *testing.T) {
+	suite.Run(t, new(UpcomingTestSuite))
+}
@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/mailer"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/repository"
+	"github.com/muneerlalji/Luma/utils"
+)
+
+const (
+	// weeklyDigestDays is how often the weekly digest goes out and how far
+	// back and ahead it looks
+	weeklyDigestDays = 7
+	// confusedExcerptLength is how many characters of a confused message
+	// the weekly digest quotes
+	confusedExcerptLength = 200
+)
+
+// weeklyDigestOn reports whether user's caregiver gets the weekly digest
+func weeklyDigestOn(user *models.User) bool {
+	return user.CaregiverEmail != "" && !user.WeeklyDigestOptOut
+}
+
+// unsubscribeMessage is what the unsubscribe token of a caregiver signs. It
+// names the caregiver, so links stop working once the caregiver changes.
+func unsubscribeMessage(userID uuid.UUID, caregiverEmail string) string {
+	return "weekly_digest_unsubscribe:" + userID.String() + ":" + strings.ToLower(caregiverEmail)
+}
+
+// memberUnsubscribeMessage is what the unsubscribe token of a care circle
+// member signs
+func memberUnsubscribeMessage(member *models.CareCircleMember) string {
+	return "weekly_digest_unsubscribe:member:" + member.ID.String() + ":" + strings.ToLower(member.Email)
+}
+
+// signedToken joins an ID and the signature of message into the token of an
+// unsubscribe link
+func (h *Handler) signedToken(id uuid.UUID, message string) string {
+	return id.String() + "." + utils.Sign(h.config.JWTSecret, message)
+}
+
+// digestRecipient is someone a weekly digest goes to, with the token of the
+// link that unsubscribes them
+type digestRecipient struct {
+	email string
+	token string
+}
+
+// weeklyDigestRecipients returns the caregiver and the care circle members
+// who still get user's weekly digest, each address once
+func (h *Handler) weeklyDigestRecipients(ctx context.Context, store *repository.Store, user *models.User) ([]digestRecipient, error) {
+	var recipients []digestRecipient
+	seen := make(map[string]bool)
+	if weeklyDigestOn(user) {
+		seen[strings.ToLower(user.CaregiverEmail)] = true
+		recipients = append(recipients, digestRecipient{
+			email: user.CaregiverEmail,
+			token: h.signedToken(user.ID, unsubscribeMessage(user.ID, user.CaregiverEmail)),
+		})
+	}
+
+	members, err := store.CareCircle.ListByUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range members {
+		member := &members[i]
+		if member.WeeklyDigestOptOut || seen[strings.ToLower(member.Email)] {
+			continue
+		}
+		seen[strings.ToLower(member.Email)] = true
+		recipients = append(recipients, digestRecipient{
+			email: member.Email,
+			token: h.signedToken(member.ID, memberUnsubscribeMessage(member)),
+		})
+	}
+	return recipients, nil
+}
+
+// ProcessWeeklyDigests queues an email, once a week, to the caregiver and
+// each member of the care circle of a user, with how the user got on: what
+// they added, how much they talked to the assistant and whether they seemed
+// confused, the routines they missed and the birthdays coming up. Users are
+// claimed with row locks, so several instances may run it at once. It
+// returns how many digests were queued.
+func (h *Handler) ProcessWeeklyDigests(ctx context.Context) (int, error) {
+	var queued int
+	for {
+		var claimed, batch int
+		err := h.store.Transaction(ctx, func(tx *repository.Store) error {
+			batch = 0
+			now := h.now()
+			since := now.AddDate(0, 0, -weeklyDigestDays)
+			users, err := tx.Users.ClaimWeeklyDigestDue(ctx, since, digestBatchSize)
+			if err != nil {
+				return err
+			}
+			claimed = len(users)
+			for i := range users {
+				user := &users[i]
+				recipients, err := h.weeklyDigestRecipients(ctx, tx, user)
+				if err != nil {
+					return err
+				}
+				data, err := h.weeklyDigest(ctx, tx, user, since, now)
+				if err != nil {
+					return err
+				}
+				for _, recipient := range recipients {
+					data.UnsubscribeURL = h.config.FrontendURL + "/unsubscribe?token=" + recipient.token
+					if err := h.queueEmail(ctx, tx, user, recipient.email, mailer.WeeklyDigest, data); err != nil {
+						return err
+					}
+					batch++
+				}
+				if err := tx.Users.MarkWeeklyDigestSent(ctx, user.ID, now); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return queued, fmt.Errorf("email weekly digests: %w", err)
+		}
+		queued += batch
+		if claimed < digestBatchSize {
+			return queued, nil
+		}
+	}
+}
+
+// weeklyDigest gathers what happened to user from since until now, and the
+// birthdays of the week ahead. The unsubscribe link is left to the caller,
+// as it differs for each recipient.
+func (h *Handler) weeklyDigest(ctx context.Context, store *repository.Store, user *models.User, since, now time.Time) (mailer.WeeklyDigestData, error) {
+	loc := location(user)
+	data := mailer.WeeklyDigestData{
+		Name: userName(user),
+		From: since.In(loc),
+		To:   now.In(loc),
+	}
+	if h.config.FrontendURL != "" {
+		data.URL = h.config.FrontendURL + "/people"
+	}
+
+	memories, err := store.Memories.ListByUser(ctx, user.ID)
+	if err != nil {
+		return data, err
+	}
+	// Memories are newest first; the digest reads in order
+	for i := len(memories) - 1; i >= 0; i-- {
+		if !memories[i].CreatedAt.Before(since) {
+			data.Memories = append(data.Memories, memories[i].Title)
+		}
+	}
+
+	people, err := store.People.ListByUser(ctx, user.ID)
+	if err != nil {
+		return data, err
+	}
+	for i := range people {
+		if !people[i].CreatedAt.Before(since) {
+			data.People = append(data.People, personName(&people[i]))
+		}
+	}
+	for _, event := range upcomingEvents(people, memories, localDay(now, loc), weeklyDigestDays) {
+		if event.Kind == EventBirthday {
+			data.Birthdays = append(data.Birthdays, mailer.DigestEvent{
+				Kind:       event.Kind,
+				PersonName: event.PersonName,
+				Years:      event.Years,
+				Deceased:   event.Deceased,
+				Date:       event.on,
+				DaysAway:   event.DaysAway,
+			})
+		}
+	}
+
+	messages, err := store.ChatMessages.ListSince(ctx, user.ID, since)
+	if err != nil {
+		return data, err
+	}
+	for _, message := range messages {
+		if message.Role != "user" {
+			continue
+		}
+		data.ChatMessages++
+		if message.Confused {
+			data.Confused = append(data.Confused, mailer.ChatMoment{
+				At:      message.CreatedAt.In(loc),
+				Message: excerpt(message.Content, confusedExcerptLength),
+			})
+		}
+	}
+
+	routines, err := store.Routines.ListByUser(ctx, user.ID)
+	if err != nil {
+		return data, err
+	}
+	titles := make(map[uuid.UUID]string, len(routines))
+	for _, routine := range routines {
+		titles[routine.ID] = routine.Title
+	}
+	checkIns, err := store.CheckIns.ListByUser(ctx, user.ID, since, now)
+	if err != nil {
+		return data, err
+	}
+	for _, checkIn := range checkIns {
+		if checkIn.Status == models.CheckInMissed {
+			data.Missed = append(data.Missed, mailer.MissedRoutine{
+				Routine: titles[checkIn.RoutineID],
+				Due:     checkIn.ScheduledAt.In(loc),
+			})
+		}
+	}
+	return data, nil
+}
+
+// excerpt shortens text to at most length characters, marking the cut
+func excerpt(text string, length int) string {
+	runes := []rune(strings.TrimSpace(text))
+	if len(runes) <= length {
+		return string(runes)
+	}
+	return strings.TrimSpace(string(runes[:length-1])) + "…"
+}
+
+// UnsubscribeWeeklyDigest stops the weekly digest for the caregiver or care
+// circle member named in the signed token of the link it carries, without
+// signing in
+func (h *Handler) UnsubscribeWeeklyDigest(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Abort(c, errTokenRequired)
+		return
+	}
+
+	// The token names a care circle member or, for the caregiver, the user
+	id, signature, _ := strings.Cut(req.Token, ".")
+	recipientID, err := uuid.Parse(id)
+	if err != nil {
+		apierror.Abort(c, errInvalidToken)
+		return
+	}
+	member, err := h.store.CareCircle.Get(c, recipientID)
+	switch {
+	case err == nil:
+		err = h.unsubscribeMember(c, member, signature)
+	case errors.Is(err, repository.ErrNotFound):
+		err = h.unsubscribeCaregiver(c, recipientID, signature)
+	default:
+		err = apierror.Internal("Failed to get care circle member", err)
+	}
+	if err != nil {
+		apierror.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "You will no longer receive the weekly digest."})
+}
+
+// unsubscribeMember stops the weekly digest to a care circle member
+func (h *Handler) unsubscribeMember(ctx context.Context, member *models.CareCircleMember, signature string) error {
+	if !utils.ValidSignature(h.config.JWTSecret, memberUnsubscribeMessage(member), signature) {
+		return errInvalidToken
+	}
+	if err := h.store.CareCircle.OptOut(ctx, member.ID); err != nil {
+		return apierror.Internal("Failed to unsubscribe", err)
+	}
+	return nil
+}
+
+// unsubscribeCaregiver stops the weekly digest to the user's caregiver
+func (h *Handler) unsubscribeCaregiver(ctx context.Context, userID uuid.UUID, signature string) error {
+	user, err := h.store.Users.Get(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return errInvalidToken
+	}
+	if err != nil {
+		return apierror.Internal("Failed to get user", err)
+	}
+	if user.CaregiverEmail == "" || !utils.ValidSignature(h.config.JWTSecret, unsubscribeMessage(user.ID, user.CaregiverEmail), signature) {
+		return errInvalidToken
+	}
+	if err := h.store.Users.OptOutOfWeeklyDigest(ctx, user.ID); err != nil {
+		return apierror.Internal("Failed to unsubscribe", err)
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/handlers"
+	"github.com/muneerlalji/Luma/mailer"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+var unsubscribeLink = regexp.MustCompile(`/unsubscribe\?token=(\S+)`)
+
+type WeeklyDigestTestSuite struct {
+	suite.Suite
+	env    *testutils.TestEnv
+	router *gin.Engine
+	user   models.User
+	now    time.Time
+}
+
+func (suite *WeeklyDigestTestSuite) SetupSuite() {
+	gin.SetMode(gin.TestMode)
+}
+
+func (suite *WeeklyDigestTestSuite) SetupTest() {
+	// A Monday
+	suite.now = time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC)
+	suite.env = testutils.NewTestEnv(func(deps *handlers.Deps) {
+		deps.Clock = func() time.Time { return suite.now }
+	})
+	h := suite.env.Handler
+
+	suite.user = models.User{
+		Email: "margaret@example.com", Password: "x", DisplayName: "Margaret", EmailConfirmed: true,
+		CaregiverEmail: "carer@example.com", Timezone: "Europe/Madrid",
+		LastDigestAt: &suite.now,
+	}
+	suite.env.Store.Users.Create(context.Background(), &suite.user)
+
+	suite.router = gin.New()
+	suite.router.Use(testutils.OpenAPIValidator(suite.T()), apierror.Middleware())
+	suite.router.POST("/weekly-digest/unsubscribe", h.UnsubscribeWeeklyDigest)
+	protected := suite.router.Group("/")
+	protected.Use(func(c *gin.Context) {
+		c.Set("user_id", suite.user.ID)
+		c.Next()
+	})
+	protected.GET("/me", h.GetCurrentUser)
+	protected.PUT("/profile", h.UpdateProfile)
+	protected.GET("/profile/care-circle", h.GetCareCircle)
+	protected.POST("/profile/care-circle", h.AddCareCircleMember)
+	protected.DELETE("/profile/care-circle/:id", h.DeleteCareCircleMember)
+}
+
+func (suite *WeeklyDigestTestSuite) request(method, path string, body any) *httptest.ResponseRecorder {
+	var reader bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&reader).Encode(body)
+	}
+	req, _ := http.NewRequest(method, path, &reader)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+// process runs the scheduler and sends what it queued, returning the weekly
+// digests sent
+func (suite *WeeklyDigestTestSuite) process() []testutils.EmailData {
+	suite.env.Email.ClearSentEmails()
+	_, err := suite.env.Handler.ProcessWeeklyDigests(context.Background())
+	suite.Require().NoError(err)
+	_, err = suite.env.SendQueuedEmails()
+	suite.Require().NoError(err)
+	return suite.env.Email.FindEmailByTemplate(mailer.WeeklyDigest)
+}
+
+func (suite *WeeklyDigestTestSuite) TestDigestSumsUpTheWeek() {
+	ctx := context.Background()
+	store := suite.env.Store
+	lastWeek, daysAgo := suite.now.AddDate(0, 0, -8), suite.now.AddDate(0, 0, -2)
+
+	for _, memory := range []models.Memory{
+		{UserID: suite.user.ID, Title: "Old picnic", Type: "event", CreatedAt: lastWeek},
+		{UserID: suite.user.ID, Title: "Trip to Brighton", Type: "event", CreatedAt: daysAgo},
+		{UserID: suite.user.ID, Title: "Tom's graduation", Type: "event", CreatedAt: daysAgo.Add(time.Hour)},
+	} {
+		suite.Require().NoError(store.Memories.Create(ctx, &memory))
+	}
+	birthday := time.Date(2014, time.March, 5, 0, 0, 0, 0, time.UTC)
+	for _, person := range []models.Person{
+		{UserID: suite.user.ID, FirstName: "Tom", LastName: "Hughes", Birthday: &birthday, CreatedAt: lastWeek},
+		{UserID: suite.user.ID, FirstName: "Ann", LastName: "Hughes", CreatedAt: daysAgo},
+	} {
+		suite.Require().NoError(store.People.Create(ctx, &person))
+	}
+	for _, message := range []models.ChatMessage{
+		{UserID: suite.user.ID, Role: "user", Content: "What day is it?", Confused: true, CreatedAt: lastWeek},
+		{UserID: suite.user.ID, Role: "user", Content: "Who is Tom?", CreatedAt: daysAgo},
+		{UserID: suite.user.ID, Role: "assistant", Content: "Tom is your grandson.", CreatedAt: daysAgo},
+		{UserID: suite.user.ID, Role: "user", Content: "Where am I?", Confused: true, CreatedAt: daysAgo.Add(time.Hour)},
+	} {
+		suite.Require().NoError(store.ChatMessages.Create(ctx, &message))
+	}
+	routine := models.Routine{UserID: suite.user.ID, Kind: "medication", Title: "Blood pressure tablets", Recurrence: "FREQ=DAILY", Times: []string{"09:00"}, StartsOn: lastWeek}
+	suite.Require().NoError(store.Routines.Create(ctx, &routine))
+	for _, checkIn := range []models.CheckIn{
+		{UserID: suite.user.ID, RoutineID: routine.ID, ScheduledAt: lastWeek, Status: models.CheckInMissed},
+		{UserID: suite.user.ID, RoutineID: routine.ID, ScheduledAt: daysAgo.AddDate(0, 0, -1), Status: models.CheckInDone},
+		{UserID: suite.user.ID, RoutineID: routine.ID, ScheduledAt: daysAgo, Status: models.CheckInMissed},
+	} {
+		suite.Require().NoError(store.CheckIns.Create(ctx, &checkIn))
+	}
+
+	emails := suite.process()
+	suite.Require().Len(emails, 1)
+	assert.Equal(suite.T(), "carer@example.com", emails[0].To)
+	match := unsubscribeLink.FindStringSubmatch(emails[0].Body)
+	suite.Require().NotNil(match)
+
+	madrid, _ := time.LoadLocation("Europe/Madrid")
+	suite.env.Email.AssertRendered(suite.T(), emails[0], mailer.WeeklyDigest, "en", mailer.WeeklyDigestData{
+		Name:         "Margaret",
+		From:         suite.now.AddDate(0, 0, -7).In(madrid),
+		To:           suite.now.In(madrid),
+		Memories:     []string{"Trip to Brighton", "Tom's graduation"},
+		People:       []string{"Ann Hughes"},
+		ChatMessages: 2,
+		Confused:     []mailer.ChatMoment{{At: daysAgo.Add(time.Hour).In(madrid), Message: "Where am I?"}},
+		Missed:       []mailer.MissedRoutine{{Routine: "Blood pressure tablets", Due: daysAgo.In(madrid)}},
+		Birthdays: []mailer.DigestEvent{{
+			Kind: handlers.EventBirthday, PersonName: "Tom Hughes", Years: 12,
+			Date: time.Date(2026, time.March, 5, 0, 0, 0, 0, time.UTC), DaysAway: 3,
+		}},
+		URL:            "http://localhost:3000/people",
+		UnsubscribeURL: "http://localhost:3000/unsubscribe?token=" + match[1],
+	})
+}
+
+func (suite *WeeklyDigestTestSuite) TestDigestIsWeekly() {
+	suite.Require().Len(suite.process(), 1, "a quiet week is still reported")
+
+	suite.now = suite.now.AddDate(0, 0, 6)
+	assert.Empty(suite.T(), suite.process())
+
+	suite.now = suite.now.AddDate(0, 0, 1).Add(time.Minute)
+	assert.Len(suite.T(), suite.process(), 1)
+}
+
+func (suite *WeeklyDigestTestSuite) TestUnsubscribe() {
+	emails := suite.process()
+	suite.Require().Len(emails, 1)
+	token := unsubscribeLink.FindStringSubmatch(emails[0].Body)[1]
+
+	assert.Equal(suite.T(), http.StatusBadRequest, suite.request("POST", "/weekly-digest/unsubscribe", map[string]string{"token": token + "x"}).Code)
+	assert.Equal(suite.T(), http.StatusBadRequest, suite.request("POST", "/weekly-digest/unsubscribe", map[string]string{"token": "nope"}).Code)
+
+	for range 2 {
+		w := suite.request("POST", "/weekly-digest/unsubscribe", map[string]string{"token": token})
+		suite.Require().Equal(http.StatusOK, w.Code, "unsubscribing twice is fine")
+	}
+	suite.now = suite.now.AddDate(0, 0, 8)
+	assert.Empty(suite.T(), suite.process())
+
+	var me models.UserResponse
+	suite.Require().NoError(json.Unmarshal(suite.request("GET", "/me", nil).Body.Bytes(), &me))
+	assert.False(suite.T(), me.WeeklyDigest)
+
+	// The daily digest is not affected
+	user, err := suite.env.Store.Users.Get(context.Background(), suite.user.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "carer@example.com", user.CaregiverEmail)
+}
+
+func (suite *WeeklyDigestTestSuite) TestNewCaregiverIsSubscribed() {
+	emails := suite.process()
+	suite.Require().Len(emails, 1)
+	token := unsubscribeLink.FindStringSubmatch(emails[0].Body)[1]
+	suite.Require().Equal(http.StatusOK, suite.request("POST", "/weekly-digest/unsubscribe", map[string]string{"token": token}).Code)
+
+	w := suite.request("PUT", "/profile", map[string]any{"displayName": "Margaret", "caregiverEmail": "son@example.com"})
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var response struct {
+		User models.UserResponse `json:"user"`
+	}
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	assert.True(suite.T(), response.User.WeeklyDigest)
+
+	// The old caregiver's link no longer works
+	assert.Equal(suite.T(), http.StatusBadRequest, suite.request("POST", "/weekly-digest/unsubscribe", map[string]string{"token": token}).Code)
+
+	suite.now = suite.now.AddDate(0, 0, 8)
+	emails = suite.process()
+	suite.Require().Len(emails, 1)
+	assert.Equal(suite.T(), "son@example.com", emails[0].To)
+
+	// The user can turn it off themselves
+	w = suite.request("PUT", "/profile", map[string]any{"displayName": "Margaret", "weeklyDigest": false})
+	suite.Require().Equal(http.StatusOK, w.Code)
+	suite.now = suite.now.AddDate(0, 0, 8)
+	assert.Empty(suite.T(), suite.process())
+}
+
+func (suite *WeeklyDigestTestSuite) TestCareCircle() {
+	var son handlers.CareCircleMemberResponse
+	w := suite.request("POST", "/profile/care-circle", map[string]string{"name": "Tom", "email": "son@example.com"})
+	suite.Require().Equal(http.StatusCreated, w.Code)
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &son))
+	assert.True(suite.T(), son.WeeklyDigest)
+	suite.Require().Equal(http.StatusCreated, suite.request("POST", "/profile/care-circle", map[string]string{"email": "daughter@example.com"}).Code)
+	assert.Equal(suite.T(), http.StatusConflict, suite.request("POST", "/profile/care-circle", map[string]string{"email": "Son@example.com"}).Code)
+	// The caregiver is sent one digest even when also in the circle
+	suite.Require().Equal(http.StatusCreated, suite.request("POST", "/profile/care-circle", map[string]string{"email": "carer@example.com"}).Code)
+
+	emails := suite.process()
+	suite.Require().Len(emails, 3)
+	tokens := make(map[string]string)
+	for _, email := range emails {
+		tokens[email.To] = unsubscribeLink.FindStringSubmatch(email.Body)[1]
+	}
+	assert.Len(suite.T(), tokens, 3)
+	assert.NotEqual(suite.T(), tokens["son@example.com"], tokens["daughter@example.com"])
+
+	// Each recipient unsubscribes only themselves
+	suite.Require().Equal(http.StatusOK, suite.request("POST", "/weekly-digest/unsubscribe", map[string]string{"token": tokens["son@example.com"]}).Code)
+	suite.now = suite.now.AddDate(0, 0, 8)
+	emails = suite.process()
+	suite.Require().Len(emails, 2)
+	for _, email := range emails {
+		assert.NotEqual(suite.T(), "son@example.com", email.To)
+	}
+
+	var circle []handlers.CareCircleMemberResponse
+	suite.Require().NoError(json.Unmarshal(suite.request("GET", "/profile/care-circle", nil).Body.Bytes(), &circle))
+	suite.Require().Len(circle, 3)
+	assert.False(suite.T(), circle[0].WeeklyDigest)
+
+	// Removed members are no longer sent it, and their links stop working
+	suite.Require().Equal(http.StatusOK, suite.request("DELETE", "/profile/care-circle/"+circle[1].ID.String(), nil).Code)
+	assert.Equal(suite.T(), http.StatusNotFound, suite.request("DELETE", "/profile/care-circle/"+circle[1].ID.String(), nil).Code)
+	assert.Equal(suite.T(), http.StatusBadRequest, suite.request("POST", "/weekly-digest/unsubscribe", map[string]string{"token": tokens["daughter@example.com"]}).Code)
+	suite.now = suite.now.AddDate(0, 0, 8)
+	emails = suite.process()
+	suite.Require().Len(emails, 1)
+	assert.Equal(suite.T(), "carer@example.com", emails[0].To)
+}
+
+func TestWeeklyDigestTestSuite(t *testing.T) {
+	suite.Run(t, new(WeeklyDigestTestSuite))
+}
@@ -0,0 +1,140 @@
+// Package health serves liveness and readiness probes
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Status values reported by the probes
+const (
+	StatusOK          = "ok"
+	StatusDegraded    = "degraded"
+	StatusUnavailable = "unavailable"
+	StatusError       = "error"
+)
+
+// DefaultTimeout bounds each readiness check
+const DefaultTimeout = 2 * time.Second
+
+// Check reports whether a dependency is usable
+type Check func(ctx context.Context) error
+
+// CheckResult is the outcome of one check
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// Optional checks do not make the service unready
+	Optional bool `json:"optional,omitempty"`
+}
+
+// Report is the body of a readiness response
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+type namedCheck struct {
+	name     string
+	check    Check
+	optional bool
+}
+
+// Checker runs the registered dependency checks
+type Checker struct {
+	// Timeout bounds each check; DefaultTimeout when zero
+	Timeout time.Duration
+
+	checks       []namedCheck
+	shuttingDown atomic.Bool
+}
+
+// NewChecker creates a checker with no checks
+func NewChecker() *Checker {
+	return &Checker{Timeout: DefaultTimeout}
+}
+
+// Add registers a check the service cannot work without
+func (checker *Checker) Add(name string, check Check) {
+	checker.checks = append(checker.checks, namedCheck{name: name, check: check})
+}
+
+// AddOptional registers a check whose failure degrades the service without
+// making it unready
+func (checker *Checker) AddOptional(name string, check Check) {
+	checker.checks = append(checker.checks, namedCheck{name: name, check: check, optional: true})
+}
+
+// ShuttingDown makes readiness fail so load balancers stop sending traffic
+// while in-flight requests drain
+func (checker *Checker) ShuttingDown() {
+	checker.shuttingDown.Store(true)
+}
+
+// Run executes every check concurrently and summarises the results
+func (checker *Checker) Run(ctx context.Context) Report {
+	timeout := checker.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	results := make([]CheckResult, len(checker.checks))
+	var wg sync.WaitGroup
+	for i, check := range checker.checks {
+		wg.Add(1)
+		go func(i int, check namedCheck) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			result := CheckResult{Status: StatusOK, Optional: check.optional}
+			if err := check.check(ctx); err != nil {
+				result.Status = StatusError
+				result.Error = err.Error()
+			}
+			results[i] = result
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(checker.checks))}
+	for i, check := range checker.checks {
+		result := results[i]
+		report.Checks[check.name] = result
+		if result.Status == StatusOK {
+			continue
+		}
+		if !check.optional {
+			report.Status = StatusUnavailable
+		} else if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+	if checker.shuttingDown.Load() {
+		report.Status = StatusUnavailable
+		report.Checks["shutdown"] = CheckResult{Status: StatusError, Error: "server is shutting down"}
+	}
+	return report
+}
+
+// Liveness answers 200 while the process is able to serve requests at all
+func (checker *Checker) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": StatusOK})
+}
+
+// Readiness answers 200 when every required dependency is usable and 503
+// otherwise, with the status of each dependency
+func (checker *Checker) Readiness(c *gin.Context) {
+	report := checker.Run(c.Request.Context())
+
+	code := http.StatusOK
+	if report.Status == StatusUnavailable {
+		code = http.StatusServiceUnavailable
+	}
+	c.JSON(code, report)
+}
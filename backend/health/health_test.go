@@ -0,0 +1,104 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muneerlalji/Luma/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ok(ctx context.Context) error { return nil }
+
+func failing(ctx context.Context) error { return errors.New("connection refused") }
+
+func probe(t *testing.T, checker *health.Checker, path string) (int, health.Report) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/healthz", checker.Liveness)
+	router.GET("/readyz", checker.Readiness)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", path, nil)
+	router.ServeHTTP(w, req)
+
+	var report health.Report
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	return w.Code, report
+}
+
+func TestReadiness_AllHealthy(t *testing.T) {
+	checker := health.NewChecker()
+	checker.Add("database", ok)
+	checker.Add("storage", ok)
+
+	code, report := probe(t, checker, "/readyz")
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.StatusOK, report.Status)
+	assert.Equal(t, health.StatusOK, report.Checks["database"].Status)
+	assert.Equal(t, health.StatusOK, report.Checks["storage"].Status)
+}
+
+func TestReadiness_RequiredCheckFails(t *testing.T) {
+	checker := health.NewChecker()
+	checker.Add("database", failing)
+	checker.Add("storage", ok)
+
+	code, report := probe(t, checker, "/readyz")
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, health.StatusUnavailable, report.Status)
+	assert.Equal(t, health.StatusError, report.Checks["database"].Status)
+	assert.Equal(t, "connection refused", report.Checks["database"].Error)
+	assert.Equal(t, health.StatusOK, report.Checks["storage"].Status)
+}
+
+func TestReadiness_OptionalCheckDegrades(t *testing.T) {
+	checker := health.NewChecker()
+	checker.Add("database", ok)
+	checker.AddOptional("llm", failing)
+
+	code, report := probe(t, checker, "/readyz")
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.StatusDegraded, report.Status)
+	assert.True(t, report.Checks["llm"].Optional)
+}
+
+func TestReadiness_CheckTimesOut(t *testing.T) {
+	checker := health.NewChecker()
+	checker.Timeout = 10 * time.Millisecond
+	checker.Add("storage", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	code, report := probe(t, checker, "/readyz")
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["storage"].Error)
+}
+
+func TestReadiness_ShuttingDown(t *testing.T) {
+	checker := health.NewChecker()
+	checker.Add("database", ok)
+	checker.ShuttingDown()
+
+	code, report := probe(t, checker, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, health.StatusUnavailable, report.Status)
+
+	// Liveness is unaffected so the process is not restarted mid-drain
+	code, report = probe(t, checker, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.StatusOK, report.Status)
+}
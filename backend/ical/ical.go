@@ -0,0 +1,442 @@
+// Package ical reads and writes iCalendar data (RFC 5545): components made of
+// content lines, escaped text, dates and date-times, and VTIMEZONE
+// components built from Go time zones.
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxLineOctets is the longest a content line may be before it is folded,
+// not counting the line break
+const maxLineOctets = 75
+
+// Params are the parameters of a property, keyed by upper-case name. Most
+// parameters have a single value.
+type Params map[string][]string
+
+// Get returns the first value of the named parameter
+func (p Params) Get(name string) string {
+	if values := p[strings.ToUpper(name)]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Property is a content line such as DTSTART;VALUE=DATE:20260302. Value is
+// kept as written; use Text for TEXT values.
+type Property struct {
+	Name   string
+	Params Params
+	Value  string
+}
+
+// Text is the value with TEXT escapes undone
+func (p *Property) Text() string {
+	return Unescape(p.Value)
+}
+
+// Component is a calendar object such as VCALENDAR, VEVENT or VALARM
+type Component struct {
+	Name       string
+	Properties []Property
+	Components []*Component
+}
+
+// NewComponent returns an empty component with the given name
+func NewComponent(name string) *Component {
+	return &Component{Name: strings.ToUpper(name)}
+}
+
+// NewCalendar returns a VCALENDAR with the required VERSION and PRODID
+func NewCalendar(productID string) *Component {
+	calendar := NewComponent("VCALENDAR")
+	calendar.Add("VERSION", "2.0")
+	calendar.Add("PRODID", productID)
+	calendar.Add("CALSCALE", "GREGORIAN")
+	return calendar
+}
+
+// Add appends a property with a value that is already encoded
+func (c *Component) Add(name, value string, params ...Params) {
+	property := Property{Name: strings.ToUpper(name), Value: value}
+	if len(params) > 0 {
+		property.Params = params[0]
+	}
+	c.Properties = append(c.Properties, property)
+}
+
+// AddText appends a TEXT property, escaping the value
+func (c *Component) AddText(name, text string) {
+	c.Add(name, Escape(text))
+}
+
+// AddComponent appends a child component
+func (c *Component) AddComponent(child *Component) {
+	c.Components = append(c.Components, child)
+}
+
+// Get returns the first property with the name, or nil
+func (c *Component) Get(name string) *Property {
+	name = strings.ToUpper(name)
+	for i := range c.Properties {
+		if c.Properties[i].Name == name {
+			return &c.Properties[i]
+		}
+	}
+	return nil
+}
+
+// Text returns the unescaped value of the first property with the name, or
+// "" when there is none
+func (c *Component) Text(name string) string {
+	if property := c.Get(name); property != nil {
+		return property.Text()
+	}
+	return ""
+}
+
+// Children returns the child components with the name
+func (c *Component) Children(name string) []*Component {
+	name = strings.ToUpper(name)
+	var children []*Component
+	for _, child := range c.Components {
+		if child.Name == name {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// ParseError reports malformed input and the line it starts on
+type ParseError struct {
+	Line int
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("ical: line %d: %s", e.Line, e.Msg)
+}
+
+// Parse reads a single VCALENDAR. Folded lines are joined, and names of
+// components, properties and parameters are upper-cased. Bare LF line
+// endings and blank lines are accepted.
+func Parse(r io.Reader) (*Component, error) {
+	lines := newLineReader(r)
+	var calendar *Component
+	var stack []*Component
+
+	for {
+		number, line, err := lines.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if number == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if line == "" {
+			continue
+		}
+
+		property, err := parseLine(line)
+		if err != nil {
+			return nil, &ParseError{Line: number, Msg: err.Error()}
+		}
+
+		switch property.Name {
+		case "BEGIN":
+			component := NewComponent(property.Value)
+			switch {
+			case len(stack) > 0:
+				parent := stack[len(stack)-1]
+				parent.Components = append(parent.Components, component)
+			case calendar != nil:
+				return nil, &ParseError{Line: number, Msg: "content after END:VCALENDAR"}
+			case component.Name != "VCALENDAR":
+				return nil, &ParseError{Line: number, Msg: fmt.Sprintf("expected BEGIN:VCALENDAR, got BEGIN:%s", component.Name)}
+			default:
+				calendar = component
+			}
+			stack = append(stack, component)
+		case "END":
+			name := strings.ToUpper(property.Value)
+			if len(stack) == 0 {
+				return nil, &ParseError{Line: number, Msg: "END:" + name + " without BEGIN"}
+			}
+			if open := stack[len(stack)-1]; open.Name != name {
+				return nil, &ParseError{Line: number, Msg: fmt.Sprintf("END:%s does not close %s", name, open.Name)}
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 0 {
+				return nil, &ParseError{Line: number, Msg: "property " + property.Name + " outside a component"}
+			}
+			open := stack[len(stack)-1]
+			open.Properties = append(open.Properties, property)
+		}
+	}
+
+	if calendar == nil {
+		return nil, &ParseError{Line: lines.number, Msg: "no VCALENDAR"}
+	}
+	if len(stack) > 0 {
+		return nil, &ParseError{Line: lines.number, Msg: stack[len(stack)-1].Name + " is not closed"}
+	}
+	return calendar, nil
+}
+
+// lineReader returns unfolded content lines with the number of the physical
+// line each one starts on
+type lineReader struct {
+	reader *bufio.Reader
+	// number is the last physical line read
+	number int
+	// pending is a physical line read ahead to look for continuations
+	pending    *string
+	pendingErr error
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{reader: bufio.NewReader(r)}
+}
+
+func (l *lineReader) physical() (string, error) {
+	if l.pending != nil {
+		line := *l.pending
+		l.pending = nil
+		return line, nil
+	}
+	if l.pendingErr != nil {
+		return "", l.pendingErr
+	}
+	line, err := l.reader.ReadString('\n')
+	if line == "" && err != nil {
+		return "", err
+	}
+	l.number++
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (l *lineReader) next() (int, string, error) {
+	first, err := l.physical()
+	if err != nil {
+		return l.number, "", err
+	}
+	start := l.number
+
+	var line strings.Builder
+	line.WriteString(first)
+	for {
+		continuation, err := l.physical()
+		if err != nil {
+			l.pendingErr = err
+			break
+		}
+		if continuation == "" || (continuation[0] != ' ' && continuation[0] != '\t') {
+			l.pending = &continuation
+			break
+		}
+		line.WriteString(continuation[1:])
+	}
+	return start, line.String(), nil
+}
+
+// parseLine splits a content line into its name, parameters and value
+func parseLine(line string) (Property, error) {
+	end := strings.IndexAny(line, ";:")
+	if end < 0 {
+		return Property{}, errors.New("missing ':'")
+	}
+	name := line[:end]
+	if !isName(name) {
+		return Property{}, fmt.Errorf("invalid name %q", name)
+	}
+	property := Property{Name: strings.ToUpper(name)}
+	rest := line[end:]
+
+	for rest[0] == ';' {
+		rest = rest[1:]
+		eq := strings.IndexByte(rest, '=')
+		if eq < 0 {
+			return Property{}, fmt.Errorf("parameter without value in %s", property.Name)
+		}
+		paramName := rest[:eq]
+		if !isName(paramName) {
+			return Property{}, fmt.Errorf("invalid parameter name %q", paramName)
+		}
+		rest = rest[eq+1:]
+
+		var values []string
+		for {
+			var value string
+			if strings.HasPrefix(rest, `"`) {
+				closing := strings.IndexByte(rest[1:], '"')
+				if closing < 0 {
+					return Property{}, fmt.Errorf("unterminated quote in %s", property.Name)
+				}
+				value, rest = rest[1:closing+1], rest[closing+2:]
+			} else {
+				stop := strings.IndexAny(rest, ",;:")
+				if stop < 0 {
+					return Property{}, errors.New("missing ':'")
+				}
+				value, rest = rest[:stop], rest[stop:]
+			}
+			values = append(values, decodeParam(value))
+			if rest == "" {
+				return Property{}, errors.New("missing ':'")
+			}
+			if rest[0] != ',' {
+				break
+			}
+			rest = rest[1:]
+		}
+
+		if property.Params == nil {
+			property.Params = Params{}
+		}
+		key := strings.ToUpper(paramName)
+		property.Params[key] = append(property.Params[key], values...)
+
+		if rest[0] != ';' && rest[0] != ':' {
+			return Property{}, fmt.Errorf("unexpected %q after parameter %s", rest[0], paramName)
+		}
+	}
+
+	property.Value = rest[1:]
+	if property.Name == "BEGIN" || property.Name == "END" {
+		property.Value = strings.ToUpper(property.Value)
+		if !isName(property.Value) {
+			return Property{}, fmt.Errorf("invalid component name %q", property.Value)
+		}
+	}
+	return property, nil
+}
+
+// isName reports whether s is an iana-token or x-name: letters, digits and
+// hyphens
+func isName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !(r == '-' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeParam undoes the caret escapes of RFC 6868
+func decodeParam(value string) string {
+	if !strings.Contains(value, "^") {
+		return value
+	}
+	return strings.NewReplacer("^n", "\n", "^N", "\n", "^'", `"`, "^^", "^").Replace(value)
+}
+
+// encodeParam applies the caret escapes of RFC 6868 and quotes values that
+// contain separators
+func encodeParam(value string) string {
+	value = strings.NewReplacer("^", "^^", "\n", "^n", `"`, "^'").Replace(value)
+	if strings.ContainsAny(value, ",;:") {
+		return `"` + value + `"`
+	}
+	return value
+}
+
+// Encode writes the component with CRLF line endings, folding lines longer
+// than 75 octets. Parameters are written in name order.
+func (c *Component) Encode(w io.Writer) error {
+	buffered := bufio.NewWriter(w)
+	c.encode(buffered)
+	return buffered.Flush()
+}
+
+func (c *Component) encode(w *bufio.Writer) {
+	writeLine(w, "BEGIN:"+c.Name)
+	for _, property := range c.Properties {
+		writeLine(w, property.line())
+	}
+	for _, child := range c.Components {
+		child.encode(w)
+	}
+	writeLine(w, "END:"+c.Name)
+}
+
+func (p *Property) line() string {
+	var line strings.Builder
+	line.WriteString(p.Name)
+	names := make([]string, 0, len(p.Params))
+	for name := range p.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		line.WriteString(";" + name + "=")
+		for i, value := range p.Params[name] {
+			if i > 0 {
+				line.WriteByte(',')
+			}
+			line.WriteString(encodeParam(value))
+		}
+	}
+	line.WriteString(":" + p.Value)
+	return line.String()
+}
+
+// writeLine writes a content line folded into chunks of at most 75 octets,
+// never splitting a UTF-8 sequence. Continuation lines start with a space.
+func writeLine(w *bufio.Writer, line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+		// The leading space counts towards the limit
+		limit = maxLineOctets - 1
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
+
+// Escape encodes text as a TEXT value: backslashes, semicolons, commas and
+// line breaks are escaped
+func Escape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(text)
+}
+
+// Unescape decodes a TEXT value. Unknown escapes keep the escaped character.
+func Unescape(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+	var text strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i == len(value)-1 {
+			text.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n', 'N':
+			text.WriteByte('\n')
+		default:
+			text.WriteByte(value[i])
+		}
+	}
+	return text.String()
+}
@@ -0,0 +1,315 @@
+package ical_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muneerlalji/Luma/ical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// crlf joins lines with CRLF line endings, as RFC 5545 requires
+func crlf(lines ...string) string {
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+func encode(t *testing.T, component *ical.Component) string {
+	t.Helper()
+	var out bytes.Buffer
+	require.NoError(t, component.Encode(&out))
+	return out.String()
+}
+
+func TestParse(t *testing.T) {
+	input := crlf(
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Example Corp.//CalDAV Client//EN",
+		"BEGIN:VEVENT",
+		"UID:19970901T130000Z-123401@example.com",
+		"DTSTART;VALUE=DATE:19970903",
+		"SUMMARY:Annual Employee Review\\, with \\\"notes\\\"\\; bring",
+		"  a pen",
+		"DESCRIPTION:Line one\\nLine two\\\\",
+		"ATTENDEE;ROLE=REQ-PARTICIPANT;DELEGATED-FROM=\"mailto:a@example.com\",\"m",
+		"\tailto:b@example.com\";CN=John Smith:mailto:jsmith@example.com",
+		"x-custom;x-param=^'quoted^'^nnext^^:value",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"TRIGGER:-PT15M",
+		"END:VALARM",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	)
+
+	calendar, err := ical.Parse(strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Equal(t, "VCALENDAR", calendar.Name)
+	assert.Equal(t, "2.0", calendar.Text("VERSION"))
+
+	events := calendar.Children("VEVENT")
+	require.Len(t, events, 1)
+	event := events[0]
+	assert.Equal(t, "19970901T130000Z-123401@example.com", event.Text("uid"))
+	// Folding removes the line break and one whitespace character only
+	assert.Equal(t, `Annual Employee Review, with "notes"; bring a pen`, event.Text("SUMMARY"))
+	assert.Equal(t, "Line one\nLine two\\", event.Text("DESCRIPTION"))
+
+	attendee := event.Get("ATTENDEE")
+	require.NotNil(t, attendee)
+	assert.Equal(t, "mailto:jsmith@example.com", attendee.Value)
+	assert.Equal(t, "REQ-PARTICIPANT", attendee.Params.Get("role"))
+	assert.Equal(t, []string{"mailto:a@example.com", "mailto:b@example.com"}, attendee.Params["DELEGATED-FROM"])
+	assert.Equal(t, "John Smith", attendee.Params.Get("CN"))
+
+	custom := event.Get("X-CUSTOM")
+	require.NotNil(t, custom)
+	assert.Equal(t, "\"quoted\"\nnext^", custom.Params.Get("X-PARAM"))
+
+	alarms := event.Children("VALARM")
+	require.Len(t, alarms, 1)
+	assert.Equal(t, "-PT15M", alarms[0].Text("TRIGGER"))
+	assert.Nil(t, event.Get("LOCATION"))
+	assert.Equal(t, "", event.Text("LOCATION"))
+}
+
+func TestParse_Lenient(t *testing.T) {
+	// A byte order mark, bare LF line endings, blank lines, lower-case names
+	// and no final line break are accepted
+	input := "\ufeffbegin:vcalendar\n\nBEGIN:VEVENT\nsummary:Tea\n with Tom\nEND:vevent\nEND:VCALENDAR"
+
+	calendar, err := ical.Parse(strings.NewReader(input))
+	require.NoError(t, err)
+	events := calendar.Children("VEVENT")
+	require.Len(t, events, 1)
+	assert.Equal(t, "Teawith Tom", events[0].Text("SUMMARY"))
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		line  int
+		msg   string
+	}{
+		{"empty", "", 0, "no VCALENDAR"},
+		{"missing colon", crlf("BEGIN:VCALENDAR", "VERSION 2.0", "END:VCALENDAR"), 2, "missing ':'"},
+		{"bad name", crlf("BEGIN:VCALENDAR", "X_BAD:1", "END:VCALENDAR"), 2, "invalid name"},
+		{"parameter without value", crlf("BEGIN:VCALENDAR", "DTSTART;VALUE:1", "END:VCALENDAR"), 2, "parameter without value"},
+		{"unterminated quote", crlf("BEGIN:VCALENDAR", `ATTENDEE;CN="Smith:mailto:a@example.com`, "END:VCALENDAR"), 2, "unterminated quote"},
+		{"text after quote", crlf("BEGIN:VCALENDAR", `ATTENDEE;CN="Smith"x:mailto:a@example.com`, "END:VCALENDAR"), 2, "after parameter"},
+		{"not a calendar", crlf("BEGIN:VEVENT", "END:VEVENT"), 1, "expected BEGIN:VCALENDAR"},
+		{"property outside", crlf("VERSION:2.0", "BEGIN:VCALENDAR", "END:VCALENDAR"), 1, "outside a component"},
+		{"mismatched end", crlf("BEGIN:VCALENDAR", "BEGIN:VEVENT", "END:VTODO", "END:VCALENDAR"), 3, "does not close VEVENT"},
+		{"end without begin", crlf("END:VCALENDAR"), 1, "without BEGIN"},
+		{"not closed", crlf("BEGIN:VCALENDAR", "BEGIN:VEVENT", "END:VEVENT"), 3, "VCALENDAR is not closed"},
+		{"second calendar", crlf("BEGIN:VCALENDAR", "END:VCALENDAR", "BEGIN:VCALENDAR", "END:VCALENDAR"), 3, "content after END:VCALENDAR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ical.Parse(strings.NewReader(tt.input))
+			var parseErr *ical.ParseError
+			require.ErrorAs(t, err, &parseErr)
+			assert.Equal(t, tt.line, parseErr.Line)
+			assert.Contains(t, parseErr.Msg, tt.msg)
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	calendar := ical.NewCalendar("-//Luma//Luma//EN")
+	event := ical.NewComponent("vevent")
+	event.Add("UID", "person-1-birthday@luma")
+	event.AddText("SUMMARY", "Tea, cake; and a \\ backslash\nnew line")
+	event.Add("ATTENDEE", "mailto:tom@example.com", ical.Params{
+		"CN":   {`Tom "Tommy" Hughes`},
+		"ROLE": {"REQ-PARTICIPANT"},
+		"X-A":  {"a:b", "c"},
+	})
+	calendar.AddComponent(event)
+
+	assert.Equal(t, crlf(
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Luma//Luma//EN",
+		"CALSCALE:GREGORIAN",
+		"BEGIN:VEVENT",
+		"UID:person-1-birthday@luma",
+		`SUMMARY:Tea\, cake\; and a \\ backslash\nnew line`,
+		`ATTENDEE;CN=Tom ^'Tommy^' Hughes;ROLE=REQ-PARTICIPANT;X-A="a:b",c:mailto:to`,
+		" m@example.com",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	), encode(t, calendar))
+}
+
+func TestEncode_Folding(t *testing.T) {
+	event := ical.NewComponent("VEVENT")
+	event.AddText("DESCRIPTION", strings.Repeat("a", 200))
+	// Each ü is two octets; the fold must not split one
+	event.AddText("SUMMARY", strings.Repeat("ü", 60))
+
+	out := encode(t, event)
+	lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+	for _, line := range lines {
+		assert.LessOrEqual(t, len(line), 75, line)
+		assert.True(t, strings.ToValidUTF8(line, "?") == line, "split UTF-8 sequence in %q", line)
+	}
+	assert.Equal(t, "DESCRIPTION:"+strings.Repeat("a", 63), lines[1])
+	assert.Equal(t, " "+strings.Repeat("a", 74), lines[2])
+
+	parsed, err := ical.Parse(strings.NewReader(crlf("BEGIN:VCALENDAR") + out + crlf("END:VCALENDAR")))
+	require.NoError(t, err)
+	events := parsed.Children("VEVENT")
+	require.Len(t, events, 1)
+	assert.Equal(t, strings.Repeat("a", 200), events[0].Text("DESCRIPTION"))
+	assert.Equal(t, strings.Repeat("ü", 60), events[0].Text("SUMMARY"))
+}
+
+func TestRoundTrip(t *testing.T) {
+	calendar := ical.NewCalendar("-//Luma//Luma//EN")
+	event := ical.NewComponent("VEVENT")
+	event.Add("UID", "a@example.com")
+	event.AddText("SUMMARY", "Lunch; with Tom, and Jane\n\\o/")
+	event.Add("X-PARAMS", "v", ical.Params{"X-ONE": {"^caret\nline", `say "hi"`}})
+	calendar.AddComponent(event)
+	first := encode(t, calendar)
+
+	parsed, err := ical.Parse(strings.NewReader(first))
+	require.NoError(t, err)
+	assert.Equal(t, calendar, parsed)
+	assert.Equal(t, first, encode(t, parsed))
+}
+
+func TestEscape(t *testing.T) {
+	assert.Equal(t, `a\\b\;c\,d\ne\nf\ng`, ical.Escape("a\\b;c,d\ne\r\nf\rg"))
+	assert.Equal(t, "a\\b;c,d\ne\nf:g", ical.Unescape(`a\\b\;c\,d\ne\Nf\:g`))
+	// A trailing backslash is kept
+	assert.Equal(t, `end\`, ical.Unescape(`end\`))
+}
+
+func TestPropertyTime(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	london, err := time.LoadLocation("Europe/London")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		line   string
+		want   time.Time
+		allDay bool
+	}{
+		{"date", "DTSTART;VALUE=DATE:20260302", time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC), true},
+		{"date without VALUE", "DTSTART:20260302", time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC), true},
+		{"UTC", "DTSTART:20260302T140000Z", time.Date(2026, time.March, 2, 14, 0, 0, 0, time.UTC), false},
+		{"TZID", "DTSTART;TZID=America/New_York:20260302T090000", time.Date(2026, time.March, 2, 9, 0, 0, 0, newYork), false},
+		{"global TZID", "DTSTART;TZID=/America/New_York:20260302T090000", time.Date(2026, time.March, 2, 9, 0, 0, 0, newYork), false},
+		{"unknown TZID", "DTSTART;TZID=Eastern Standard Time:20260302T090000", time.Date(2026, time.March, 2, 9, 0, 0, 0, london), false},
+		{"floating", "DTSTART:20260302T090000", time.Date(2026, time.March, 2, 9, 0, 0, 0, london), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calendar, err := ical.Parse(strings.NewReader(crlf("BEGIN:VCALENDAR", tt.line, "END:VCALENDAR")))
+			require.NoError(t, err)
+			got, allDay, err := calendar.Time("DTSTART", london)
+			require.NoError(t, err)
+			assert.True(t, tt.want.Equal(got), "got %s, want %s", got, tt.want)
+			assert.Equal(t, tt.allDay, allDay)
+		})
+	}
+
+	for _, line := range []string{"DTSTART;VALUE=DATE:2026-03-02", "DTSTART:20260302T9", "DTSTART:20261302T090000Z"} {
+		calendar, err := ical.Parse(strings.NewReader(crlf("BEGIN:VCALENDAR", line, "END:VCALENDAR")))
+		require.NoError(t, err)
+		_, _, err = calendar.Time("DTSTART", london)
+		assert.Error(t, err, line)
+	}
+	calendar := ical.NewCalendar("-//Luma//Luma//EN")
+	_, _, err = calendar.Time("DTSTART", london)
+	assert.ErrorIs(t, err, ical.ErrNoValue)
+}
+
+func TestAddTime(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	event := ical.NewComponent("VEVENT")
+	event.AddDate("DTSTART", time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC))
+	event.AddTime("DTSTAMP", time.Date(2026, time.March, 2, 14, 30, 5, 0, time.UTC))
+	event.AddTime("DUE", time.Date(2026, time.March, 2, 9, 0, 0, 0, newYork))
+	event.Add("DURATION", ical.FormatDuration(15*time.Minute))
+
+	assert.Equal(t, crlf(
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20260302",
+		"DTSTAMP:20260302T143005Z",
+		"DUE;TZID=America/New_York:20260302T090000",
+		"DURATION:PT15M",
+		"END:VEVENT",
+	), encode(t, event))
+}
+
+func TestFormatDuration(t *testing.T) {
+	assert.Equal(t, "PT0S", ical.FormatDuration(0))
+	assert.Equal(t, "PT15M", ical.FormatDuration(15*time.Minute))
+	assert.Equal(t, "PT1H30M", ical.FormatDuration(90*time.Minute))
+	assert.Equal(t, "P1D", ical.FormatDuration(24*time.Hour))
+	assert.Equal(t, "P2DT3H4M5S", ical.FormatDuration(51*time.Hour+4*time.Minute+5*time.Second))
+	assert.Equal(t, "-PT15M", ical.FormatDuration(-15*time.Minute))
+}
+
+func TestTimezone(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	require.NoError(t, err)
+	from := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	timezone := ical.Timezone(london, from, from.AddDate(1, 0, 0))
+	assert.Equal(t, crlf(
+		"BEGIN:VTIMEZONE",
+		"TZID:Europe/London",
+		"BEGIN:STANDARD",
+		"DTSTART:20260101T000000",
+		"TZOFFSETFROM:+0000",
+		"TZOFFSETTO:+0000",
+		"TZNAME:GMT",
+		"END:STANDARD",
+		"BEGIN:DAYLIGHT",
+		"DTSTART:20260329T010000",
+		"TZOFFSETFROM:+0000",
+		"TZOFFSETTO:+0100",
+		"TZNAME:BST",
+		"END:DAYLIGHT",
+		"BEGIN:STANDARD",
+		"DTSTART:20261025T020000",
+		"TZOFFSETFROM:+0100",
+		"TZOFFSETTO:+0000",
+		"TZNAME:GMT",
+		"END:STANDARD",
+		"END:VTIMEZONE",
+	), encode(t, timezone))
+
+	// Zones without changes have a single observance
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	require.NoError(t, err)
+	timezone = ical.Timezone(kolkata, from, from.AddDate(5, 0, 0))
+	require.Len(t, timezone.Components, 1)
+	assert.Equal(t, "+0530", timezone.Components[0].Text("TZOFFSETTO"))
+
+	stJohns, err := time.LoadLocation("America/St_Johns")
+	require.NoError(t, err)
+	timezone = ical.Timezone(stJohns, from, from.AddDate(0, 6, 0))
+	require.Len(t, timezone.Components, 2)
+	assert.Equal(t, "-0330", timezone.Components[1].Text("TZOFFSETFROM"))
+	assert.Equal(t, "-0230", timezone.Components[1].Text("TZOFFSETTO"))
+	assert.Equal(t, "20260308T020000", timezone.Components[1].Text("DTSTART"))
+
+	// Amsterdam was 19 minutes and 32 seconds ahead of UTC before 1937
+	amsterdam, err := time.LoadLocation("Europe/Amsterdam")
+	require.NoError(t, err)
+	timezone = ical.Timezone(amsterdam, time.Date(1935, time.January, 1, 0, 0, 0, 0, time.UTC), time.Date(1935, time.February, 1, 0, 0, 0, 0, time.UTC))
+	require.Len(t, timezone.Components, 1)
+	assert.Equal(t, "+001932", timezone.Components[0].Text("TZOFFSETTO"))
+}
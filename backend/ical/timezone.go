@@ -0,0 +1,97 @@
+package ical
+
+import (
+	"fmt"
+	"time"
+)
+
+// Timezone builds the VTIMEZONE that times written by AddTime in loc refer
+// to. It lists the observance in effect at from and each change of offset
+// until to; clients keep the last offset after that.
+func Timezone(loc *time.Location, from, to time.Time) *Component {
+	timezone := NewComponent("VTIMEZONE")
+	timezone.Add("TZID", loc.String())
+
+	at := from.In(loc).Truncate(time.Second)
+	name, offset := at.Zone()
+	timezone.AddComponent(observance(at, at.IsDST(), name, offset, offset))
+	for {
+		change, ok := nextTransition(at, to)
+		if !ok {
+			break
+		}
+		name, newOffset := change.Zone()
+		// DTSTART is the local time the change happens at, on the old offset
+		onset := change.In(time.FixedZone("", offset))
+		timezone.AddComponent(observance(onset, change.IsDST(), name, offset, newOffset))
+		at, offset = change, newOffset
+	}
+	return timezone
+}
+
+// observance is a STANDARD or DAYLIGHT component starting at the local time
+// of onset
+func observance(onset time.Time, dst bool, name string, from, to int) *Component {
+	kind := "STANDARD"
+	if dst {
+		kind = "DAYLIGHT"
+	}
+	component := NewComponent(kind)
+	component.Add("DTSTART", onset.Format(localTimeLayout))
+	component.Add("TZOFFSETFROM", formatOffset(from))
+	component.Add("TZOFFSETTO", formatOffset(to))
+	if name != "" {
+		component.AddText("TZNAME", name)
+	}
+	return component
+}
+
+// nextTransition finds the first second after t, up to end, at which the
+// offset or abbreviation of t's location changes
+func nextTransition(t, end time.Time) (time.Time, bool) {
+	name, offset := t.Zone()
+	changed := func(u time.Time) bool {
+		n, o := u.Zone()
+		return n != name || o != offset
+	}
+
+	// Zones change at most a few times a year, so a change is found by
+	// stepping a day at a time and then narrowed down to the second
+	before := t
+	for !before.After(end) {
+		after := before.Add(24 * time.Hour)
+		if changed(after) {
+			for after.Sub(before) > time.Second {
+				middle := before.Add(after.Sub(before) / 2).Truncate(time.Second)
+				if middle.Equal(before) {
+					break
+				}
+				if changed(middle) {
+					after = middle
+				} else {
+					before = middle
+				}
+			}
+			if after.After(end) {
+				return time.Time{}, false
+			}
+			return after, true
+		}
+		before = after
+	}
+	return time.Time{}, false
+}
+
+// formatOffset writes a UTC offset in seconds as a UTC-OFFSET value such as
+// +0100 or -0430
+func formatOffset(seconds int) string {
+	sign := '+'
+	if seconds < 0 {
+		sign, seconds = '-', -seconds
+	}
+	value := fmt.Sprintf("%c%02d%02d", sign, seconds/3600, seconds/60%60)
+	if seconds%60 != 0 {
+		value += fmt.Sprintf("%02d", seconds%60)
+	}
+	return value
+}
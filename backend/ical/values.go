@@ -0,0 +1,113 @@
+package ical
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	dateLayout      = "20060102"
+	localTimeLayout = "20060102T150405"
+	utcTimeLayout   = "20060102T150405Z"
+)
+
+// FormatDate writes the calendar date of t as a DATE value
+func FormatDate(t time.Time) string {
+	return t.Format(dateLayout)
+}
+
+// FormatUTC writes t as a DATE-TIME value in UTC
+func FormatUTC(t time.Time) string {
+	return t.UTC().Format(utcTimeLayout)
+}
+
+// AddDate appends a DATE property, such as an all-day DTSTART
+func (c *Component) AddDate(name string, date time.Time) {
+	c.Add(name, FormatDate(date), Params{"VALUE": {"DATE"}})
+}
+
+// AddTime appends a DATE-TIME property. Times in UTC are written with a Z
+// suffix; others as local time with a TZID naming their location, which
+// needs a matching VTIMEZONE in the calendar (see Timezone).
+func (c *Component) AddTime(name string, t time.Time) {
+	if t.Location() == time.UTC {
+		c.Add(name, FormatUTC(t))
+		return
+	}
+	c.Add(name, t.Format(localTimeLayout), Params{"TZID": {t.Location().String()}})
+}
+
+// Time reads a DATE or DATE-TIME property. Dates are returned at midnight
+// UTC with allDay set. Date-times in UTC or with a TZID Go knows are
+// returned in that location; floating times and unknown TZIDs are read in
+// loc.
+func (p *Property) Time(loc *time.Location) (t time.Time, allDay bool, err error) {
+	value := strings.TrimSpace(p.Value)
+	if strings.EqualFold(p.Params.Get("VALUE"), "DATE") || len(value) == len(dateLayout) {
+		t, err = time.Parse(dateLayout, value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid date %q in %s", value, p.Name)
+		}
+		return t, true, nil
+	}
+
+	if strings.HasSuffix(value, "Z") || strings.HasSuffix(value, "z") {
+		t, err = time.Parse(utcTimeLayout, strings.ToUpper(value))
+	} else {
+		if tzid := p.Params.Get("TZID"); tzid != "" {
+			// A leading slash marks a globally unique identifier
+			if named, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil {
+				loc = named
+			}
+		}
+		t, err = time.ParseInLocation(localTimeLayout, value, loc)
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid date-time %q in %s", value, p.Name)
+	}
+	return t, false, nil
+}
+
+// FormatDuration writes d as a DURATION value, such as PT1H30M
+func FormatDuration(d time.Duration) string {
+	var out strings.Builder
+	if d < 0 {
+		out.WriteByte('-')
+		d = -d
+	}
+	out.WriteByte('P')
+	days := d / (24 * time.Hour)
+	if days > 0 {
+		fmt.Fprintf(&out, "%dD", days)
+		d -= days * 24 * time.Hour
+	}
+	if d > 0 || days == 0 {
+		out.WriteByte('T')
+		hours, minutes, seconds := d/time.Hour, d%time.Hour/time.Minute, d%time.Minute/time.Second
+		if hours > 0 {
+			fmt.Fprintf(&out, "%dH", hours)
+		}
+		if minutes > 0 {
+			fmt.Fprintf(&out, "%dM", minutes)
+		}
+		if seconds > 0 || hours == 0 && minutes == 0 {
+			fmt.Fprintf(&out, "%dS", seconds)
+		}
+	}
+	return out.String()
+}
+
+// ErrNoValue is returned when a component lacks a required property
+var ErrNoValue = errors.New("ical: property is missing")
+
+// Time reads the named DATE or DATE-TIME property of the component; see
+// Property.Time
+func (c *Component) Time(name string, loc *time.Location) (time.Time, bool, error) {
+	property := c.Get(name)
+	if property == nil {
+		return time.Time{}, false, fmt.Errorf("%w: %s", ErrNoValue, strings.ToUpper(name))
+	}
+	return property.Time(loc)
+}
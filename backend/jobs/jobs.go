@@ -0,0 +1,436 @@
+// Package jobs runs background work queued in the jobs table. Workers claim
+// due jobs with row locks that skip rows other workers hold, so any number
+// of instances share the queue. A failed job is retried with exponential
+// backoff until it has been tried MaxAttempts times, then marked dead to
+// wait for an administrator.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/repository"
+	"github.com/muneerlalji/Luma/tracing"
+)
+
+const (
+	// claimBatchSize is how many jobs RunDue claims per transaction
+	claimBatchSize = 50
+	// maxRetryDelay caps the backoff between attempts
+	maxRetryDelay = 24 * time.Hour
+	// pruneJobs is the periodic job that deletes old succeeded jobs
+	pruneJobs = "prune_jobs"
+)
+
+// Config holds the worker settings
+type Config struct {
+	// Concurrency is how many jobs run at once
+	Concurrency int
+	// PollInterval is how often the queue is checked for due jobs
+	PollInterval time.Duration
+	// Lease is how long a job may run before another worker takes it over
+	// as abandoned
+	Lease time.Duration
+	// RetryDelay is the wait after the first failed attempt, doubling with
+	// each further one
+	RetryDelay time.Duration
+	// ShutdownTimeout is how long running jobs may finish on shutdown
+	ShutdownTimeout time.Duration
+	// Retention is how long succeeded jobs are kept; zero keeps them
+	Retention time.Duration
+	// Clock returns the current time; time.Now when nil
+	Clock func() time.Time
+}
+
+// DefaultConfig is used for the settings left zero
+var DefaultConfig = Config{
+	Concurrency:     4,
+	PollInterval:    time.Second,
+	Lease:           5 * time.Minute,
+	RetryDelay:      30 * time.Second,
+	ShutdownTimeout: 30 * time.Second,
+}
+
+// Kind names a type of job and the payload it carries
+type Kind[T any] struct {
+	Name string
+	// MaxAttempts is how many times a job is tried before it is marked
+	// dead; one when zero
+	MaxAttempts int
+}
+
+// Option changes a job as it is enqueued
+type Option func(*models.Job)
+
+// At delays the job until t
+func At(t time.Time) Option {
+	return func(job *models.Job) { job.RunAt = t }
+}
+
+// WithKey makes the job unique: enqueueing another with the same key fails
+// with repository.ErrDuplicate for as long as the job is kept
+func WithKey(key string) Option {
+	return func(job *models.Job) { job.Key = &key }
+}
+
+// Enqueue queues a job of kind k with payload, to run now unless an option
+// says otherwise. Enqueue with a transaction's store so the job only runs if
+// the changes it follows up on are committed.
+func (k Kind[T]) Enqueue(ctx context.Context, store *repository.Store, payload T, opts ...Option) (*models.Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s job: %w", k.Name, err)
+	}
+	job := &models.Job{
+		Kind:        k.Name,
+		Payload:     data,
+		Status:      models.JobPending,
+		MaxAttempts: max(k.MaxAttempts, 1),
+		RunAt:       time.Now(),
+	}
+	for _, opt := range opts {
+		opt(job)
+	}
+	if err := store.Jobs.Create(ctx, job); err != nil {
+		return nil, fmt.Errorf("enqueue %s job: %w", k.Name, err)
+	}
+	return job, nil
+}
+
+// handler runs a job given its encoded payload
+type handler func(ctx context.Context, payload json.RawMessage) error
+
+// periodic is a job enqueued on a schedule
+type periodic struct {
+	kind     Kind[struct{}]
+	schedule Schedule
+	// next is the next run not yet enqueued by this worker
+	next time.Time
+}
+
+// Worker runs the jobs it has handlers for
+type Worker struct {
+	store    *repository.Store
+	config   Config
+	handlers map[string]handler
+	periodic []*periodic
+	wake     chan struct{}
+
+	mu sync.Mutex
+}
+
+// New returns a worker for the queue in store. Register handlers before
+// calling Run.
+func New(store *repository.Store, config Config) *Worker {
+	defaults := DefaultConfig
+	if config.Concurrency <= 0 {
+		config.Concurrency = defaults.Concurrency
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaults.PollInterval
+	}
+	if config.Lease <= 0 {
+		config.Lease = defaults.Lease
+	}
+	if config.RetryDelay <= 0 {
+		config.RetryDelay = defaults.RetryDelay
+	}
+	if config.ShutdownTimeout <= 0 {
+		config.ShutdownTimeout = defaults.ShutdownTimeout
+	}
+	if config.Clock == nil {
+		config.Clock = time.Now
+	}
+	w := &Worker{
+		store:    store,
+		config:   config,
+		handlers: make(map[string]handler),
+		wake:     make(chan struct{}, 1),
+	}
+	if config.Retention > 0 {
+		w.Periodic(pruneJobs, MustParseCron("0 3 * * *"), w.prune)
+	}
+	return w
+}
+
+// Handle registers fn to run the jobs of kind, decoding their payload. It
+// panics if kind already has a handler.
+func Handle[T any](w *Worker, kind Kind[T], fn func(ctx context.Context, payload T) error) {
+	if _, ok := w.handlers[kind.Name]; ok {
+		panic("jobs: handler for " + kind.Name + " registered twice")
+	}
+	w.handlers[kind.Name] = func(ctx context.Context, data json.RawMessage) error {
+		var payload T
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return fmt.Errorf("decode payload: %w", err)
+		}
+		return fn(ctx, payload)
+	}
+}
+
+// Periodic registers fn to run on schedule under name. Each run is enqueued
+// once whichever instances share the queue, and is not retried; the next
+// run is the retry.
+func (w *Worker) Periodic(name string, schedule Schedule, fn func(ctx context.Context) error) {
+	kind := Kind[struct{}]{Name: name, MaxAttempts: 1}
+	Handle(w, kind, func(ctx context.Context, _ struct{}) error { return fn(ctx) })
+	w.periodic = append(w.periodic, &periodic{kind: kind, schedule: schedule})
+}
+
+// Trigger enqueues a run of the periodic job name now, without waiting for
+// its schedule. Runs triggered in the same second are enqueued once.
+func (w *Worker) Trigger(ctx context.Context, name string) error {
+	now := w.config.Clock().Truncate(time.Second)
+	_, err := Kind[struct{}]{Name: name, MaxAttempts: 1}.Enqueue(ctx, w.store, struct{}{}, At(now), WithKey(runKey(name, now)))
+	if err != nil && !errors.Is(err, repository.ErrDuplicate) {
+		return err
+	}
+	w.Wake()
+	return nil
+}
+
+// Wake tells the worker there may be jobs due without waiting for the next
+// poll
+func (w *Worker) Wake() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// runKey is the key of the run of periodic job name at t
+func runKey(name string, t time.Time) string {
+	return name + "@" + t.UTC().Format(time.RFC3339)
+}
+
+// Schedule enqueues the runs of periodic jobs that are due. Runs missed
+// while no worker was running are not caught up; the first run is the next
+// one on the schedule.
+func (w *Worker) Schedule(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.config.Clock()
+	var errs []error
+	for _, p := range w.periodic {
+		if p.next.IsZero() {
+			p.next = p.schedule.Next(now)
+		}
+		if p.next.IsZero() || now.Before(p.next) {
+			continue
+		}
+		_, err := p.kind.Enqueue(ctx, w.store, struct{}{}, At(p.next), WithKey(runKey(p.kind.Name, p.next)))
+		if err != nil && !errors.Is(err, repository.ErrDuplicate) {
+			errs = append(errs, err)
+			continue
+		}
+		p.next = p.schedule.Next(now)
+	}
+	return errors.Join(errs...)
+}
+
+// kinds lists the job kinds the worker has handlers for
+func (w *Worker) kinds() []string {
+	kinds := make([]string, 0, len(w.handlers))
+	for kind := range w.handlers {
+		kinds = append(kinds, kind)
+	}
+	slices.Sort(kinds)
+	return kinds
+}
+
+// claim marks up to limit due jobs running under a fresh lease and returns
+// them. Jobs whose lease ran out on their last attempt are marked dead
+// instead.
+func (w *Worker) claim(ctx context.Context, limit int) ([]models.Job, error) {
+	var claimed []models.Job
+	err := w.store.Transaction(ctx, func(tx *repository.Store) error {
+		now := w.config.Clock()
+		jobs, err := tx.Jobs.ClaimDue(ctx, now, w.kinds(), limit)
+		if err != nil {
+			return err
+		}
+		for i := range jobs {
+			job := &jobs[i]
+			if job.Status == models.JobRunning && job.Attempts >= job.MaxAttempts {
+				job.Status = models.JobDead
+				job.LastError = "lease expired before the job finished"
+				job.LockToken = ""
+				job.LockedUntil = nil
+				job.FinishedAt = &now
+				if err := tx.Jobs.Update(ctx, job); err != nil {
+					return err
+				}
+				continue
+			}
+			until := now.Add(w.config.Lease)
+			job.Status = models.JobRunning
+			job.Attempts++
+			job.LockToken = uuid.NewString()
+			job.LockedUntil = &until
+			if err := tx.Jobs.Update(ctx, job); err != nil {
+				return err
+			}
+			claimed = append(claimed, *job)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("claim jobs: %w", err)
+	}
+	return claimed, nil
+}
+
+// run runs a claimed job and records how it went. A job stopped by ctx
+// being cancelled is put back without counting the attempt.
+func (w *Worker) run(ctx context.Context, job models.Job) {
+	var err error
+	runCtx, span := tracing.Start(ctx, "job."+job.Kind)
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		err = w.handlers[job.Kind](runCtx, job.Payload)
+	}()
+	tracing.End(span, err)
+
+	now := w.config.Clock()
+	token := job.LockToken
+	job.LockToken = ""
+	job.LockedUntil = nil
+	switch {
+	case err == nil:
+		job.Status = models.JobSucceeded
+		job.LastError = ""
+		job.FinishedAt = &now
+	case ctx.Err() != nil:
+		job.Status = models.JobPending
+		job.Attempts--
+		job.RunAt = now
+	case job.Attempts >= job.MaxAttempts:
+		job.Status = models.JobDead
+		job.LastError = err.Error()
+		job.FinishedAt = &now
+		slog.Error("job failed", "job", job.ID, "kind", job.Kind, "attempts", job.Attempts, "error", err)
+	default:
+		delay := min(w.config.RetryDelay<<(job.Attempts-1), maxRetryDelay)
+		job.Status = models.JobPending
+		job.LastError = err.Error()
+		job.RunAt = now.Add(delay)
+		slog.Warn("job failed, will retry", "job", job.ID, "kind", job.Kind, "attempts", job.Attempts, "retry_at", job.RunAt, "error", err)
+	}
+
+	saved, saveErr := w.store.Jobs.UpdateIfLocked(context.WithoutCancel(ctx), &job, token)
+	if saveErr != nil {
+		slog.Error("failed to save job", "job", job.ID, "kind", job.Kind, "error", saveErr)
+	} else if !saved {
+		slog.Warn("job lease expired while it ran", "job", job.ID, "kind", job.Kind)
+	}
+}
+
+// RunDue enqueues the periodic jobs that are due, then runs every due job
+// one at a time until none is left, and returns how many ran. Failed jobs
+// are recorded rather than returned. It suits tests and one-off runs.
+func (w *Worker) RunDue(ctx context.Context) (int, error) {
+	if err := w.Schedule(ctx); err != nil {
+		return 0, err
+	}
+	var ran int
+	for {
+		jobs, err := w.claim(ctx, claimBatchSize)
+		if err != nil {
+			return ran, err
+		}
+		for _, job := range jobs {
+			w.run(ctx, job)
+			ran++
+		}
+		if len(jobs) == 0 {
+			return ran, nil
+		}
+	}
+}
+
+// Run runs due jobs, up to Concurrency at a time, until ctx is cancelled.
+// Running jobs then get up to ShutdownTimeout to finish before they are
+// cancelled and put back in the queue.
+func (w *Worker) Run(ctx context.Context) {
+	// Jobs run in a context of their own so a shutdown lets them finish
+	jobCtx, cancelJobs := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelJobs()
+
+	var running sync.WaitGroup
+	slots := make(chan struct{}, w.config.Concurrency)
+	ticker := time.NewTicker(w.config.PollInterval)
+	defer ticker.Stop()
+
+	slog.Info("job worker started", "concurrency", w.config.Concurrency, "kinds", w.kinds())
+	for ctx.Err() == nil {
+		if err := w.Schedule(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("failed to schedule periodic jobs", "error", err)
+		}
+
+		// Claim only as many jobs as there are free slots, so claimed jobs
+		// do not wait out their lease in this worker
+		if free := cap(slots) - len(slots); free > 0 {
+			jobs, err := w.claim(ctx, free)
+			if err != nil && ctx.Err() == nil {
+				slog.Error("failed to claim jobs", "error", err)
+			}
+			for _, job := range jobs {
+				slots <- struct{}{}
+				running.Add(1)
+				go func() {
+					defer running.Done()
+					w.run(jobCtx, job)
+					<-slots
+					// A slot is free and more jobs may be due
+					w.Wake()
+				}()
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		case <-w.wake:
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		running.Wait()
+		close(done)
+	}()
+	slog.Info("job worker stopping, waiting for running jobs", "timeout", w.config.ShutdownTimeout)
+	select {
+	case <-done:
+	case <-time.After(w.config.ShutdownTimeout):
+		slog.Warn("jobs still running at shutdown timeout, cancelling them")
+		cancelJobs()
+		<-done
+	}
+	slog.Info("job worker stopped")
+}
+
+// prune deletes succeeded jobs older than Retention
+func (w *Worker) prune(ctx context.Context) error {
+	deleted, err := w.store.Jobs.DeleteSucceededBefore(ctx, w.config.Clock().Add(-w.config.Retention))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		slog.Info("pruned succeeded jobs", "count", deleted)
+	}
+	return nil
+}
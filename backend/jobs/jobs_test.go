@@ -0,0 +1,281 @@
+package jobs_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/muneerlalji/Luma/jobs"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type greeting struct {
+	Name string `json:"name"`
+}
+
+var greet = jobs.Kind[greeting]{Name: "greet", MaxAttempts: 3}
+
+// clock is a settable time for workers
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time { return c.now }
+
+func newWorker(store *repository.Store, c *clock) *jobs.Worker {
+	return jobs.New(store, jobs.Config{RetryDelay: time.Minute, Clock: c.Now})
+}
+
+func TestTypedJobRuns(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	c := &clock{now: time.Now()}
+	w := newWorker(store, c)
+	var greeted []string
+	jobs.Handle(w, greet, func(ctx context.Context, payload greeting) error {
+		greeted = append(greeted, payload.Name)
+		return nil
+	})
+
+	job, err := greet.Enqueue(ctx, store, greeting{Name: "Margaret"}, jobs.At(c.now))
+	require.NoError(t, err)
+	later, err := greet.Enqueue(ctx, store, greeting{Name: "Tom"}, jobs.At(c.now.Add(time.Hour)))
+	require.NoError(t, err)
+
+	ran, err := w.RunDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, ran)
+	assert.Equal(t, []string{"Margaret"}, greeted)
+
+	stored, err := store.Jobs.Get(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobSucceeded, stored.Status)
+	assert.Equal(t, 1, stored.Attempts)
+	assert.Empty(t, stored.LockToken)
+	require.NotNil(t, stored.FinishedAt)
+
+	c.now = c.now.Add(time.Hour)
+	_, err = w.RunDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Margaret", "Tom"}, greeted)
+	stored, err = store.Jobs.Get(ctx, later.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobSucceeded, stored.Status)
+}
+
+func TestFailedJobBacksOffThenDies(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	c := &clock{now: time.Now()}
+	w := newWorker(store, c)
+	var attempts int
+	jobs.Handle(w, greet, func(ctx context.Context, payload greeting) error {
+		attempts++
+		if attempts == 2 {
+			panic("boom")
+		}
+		return errors.New("not today")
+	})
+	job, err := greet.Enqueue(ctx, store, greeting{Name: "Margaret"}, jobs.At(c.now))
+	require.NoError(t, err)
+
+	_, err = w.RunDue(ctx)
+	require.NoError(t, err, "job failures are recorded, not returned")
+	stored, err := store.Jobs.Get(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobPending, stored.Status)
+	assert.Equal(t, "not today", stored.LastError)
+	assert.Equal(t, c.now.Add(time.Minute), stored.RunAt)
+
+	// Not due again until a minute later, then two
+	c.now = c.now.Add(59 * time.Second)
+	ran, err := w.RunDue(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, ran)
+	c.now = c.now.Add(time.Second)
+	_, err = w.RunDue(ctx)
+	require.NoError(t, err)
+	stored, err = store.Jobs.Get(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "panic: boom", stored.LastError)
+	assert.Equal(t, c.now.Add(2*time.Minute), stored.RunAt)
+
+	c.now = c.now.Add(2 * time.Minute)
+	_, err = w.RunDue(ctx)
+	require.NoError(t, err)
+	stored, err = store.Jobs.Get(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobDead, stored.Status)
+	assert.Equal(t, 3, stored.Attempts)
+
+	c.now = c.now.Add(24 * time.Hour)
+	ran, err = w.RunDue(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, ran, "dead jobs wait for an administrator")
+}
+
+func TestAbandonedJobIsTakenOver(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	c := &clock{now: time.Now()}
+	w := newWorker(store, c)
+	var greeted int
+	jobs.Handle(w, greet, func(ctx context.Context, payload greeting) error {
+		greeted++
+		return nil
+	})
+
+	// A worker claimed the jobs and stopped before finishing them
+	expired := c.now.Add(-time.Second)
+	abandoned := func(attempts int) *models.Job {
+		job, err := greet.Enqueue(ctx, store, greeting{}, jobs.At(c.now.Add(-time.Hour)))
+		require.NoError(t, err)
+		job.Status, job.Attempts, job.LockToken, job.LockedUntil = models.JobRunning, attempts, "gone", &expired
+		require.NoError(t, store.Jobs.Update(ctx, job))
+		return job
+	}
+	retried, exhausted := abandoned(1), abandoned(3)
+
+	_, err := w.RunDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, greeted)
+	stored, err := store.Jobs.Get(ctx, retried.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobSucceeded, stored.Status)
+	assert.Equal(t, 2, stored.Attempts)
+	stored, err = store.Jobs.Get(ctx, exhausted.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobDead, stored.Status)
+	assert.NotEmpty(t, stored.LastError)
+
+	// The first worker can no longer record its outcome
+	retried.Status = models.JobDead
+	saved, err := store.Jobs.UpdateIfLocked(ctx, retried, "gone")
+	require.NoError(t, err)
+	assert.False(t, saved)
+}
+
+func TestPeriodicJobRunsOncePerSlot(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	c := &clock{now: time.Date(2026, time.March, 2, 9, 0, 30, 0, time.UTC)}
+	var runs int
+	tick := func(ctx context.Context) error {
+		runs++
+		return nil
+	}
+	// Two instances share the queue
+	first, second := newWorker(store, c), newWorker(store, c)
+	first.Periodic("tick", jobs.Every(time.Minute), tick)
+	second.Periodic("tick", jobs.Every(time.Minute), tick)
+
+	runBoth := func() {
+		for _, w := range []*jobs.Worker{first, second} {
+			_, err := w.RunDue(ctx)
+			require.NoError(t, err)
+		}
+	}
+	runBoth()
+	assert.Zero(t, runs, "the first run is the next slot")
+
+	c.now = c.now.Add(30 * time.Second)
+	runBoth()
+	assert.Equal(t, 1, runs)
+	runBoth()
+	assert.Equal(t, 1, runs)
+
+	// Missed slots are not caught up
+	c.now = c.now.Add(5 * time.Minute)
+	runBoth()
+	assert.Equal(t, 2, runs)
+
+	// A trigger runs it straight away, once
+	require.NoError(t, first.Trigger(ctx, "tick"))
+	require.NoError(t, second.Trigger(ctx, "tick"))
+	runBoth()
+	assert.Equal(t, 3, runs)
+}
+
+func TestPruneSucceededJobs(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	c := &clock{now: time.Date(2026, time.March, 2, 2, 0, 0, 0, time.UTC)}
+	w := jobs.New(store, jobs.Config{Retention: 24 * time.Hour, Clock: c.Now})
+	jobs.Handle(w, greet, func(ctx context.Context, payload greeting) error { return nil })
+
+	job, err := greet.Enqueue(ctx, store, greeting{}, jobs.At(c.now))
+	require.NoError(t, err)
+	_, err = w.RunDue(ctx)
+	require.NoError(t, err)
+
+	// Pruning runs daily at 03:00
+	c.now = c.now.Add(25 * time.Hour)
+	_, err = w.RunDue(ctx)
+	require.NoError(t, err)
+	_, err = store.Jobs.Get(ctx, job.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func TestRunFinishesJobsOnShutdown(t *testing.T) {
+	store := repository.NewMemoryStore()
+	w := jobs.New(store, jobs.Config{PollInterval: 10 * time.Millisecond})
+	started, release := make(chan struct{}), make(chan struct{})
+	jobs.Handle(w, greet, func(ctx context.Context, payload greeting) error {
+		close(started)
+		<-release
+		return nil
+	})
+	job, err := greet.Enqueue(context.Background(), store, greeting{})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(stopped)
+	}()
+	<-started
+	cancel()
+
+	select {
+	case <-stopped:
+		t.Fatal("worker stopped before its job finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	<-stopped
+
+	stored, err := store.Jobs.Get(context.Background(), job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobSucceeded, stored.Status)
+}
+
+func TestRunPutsBackJobsCancelledOnShutdown(t *testing.T) {
+	store := repository.NewMemoryStore()
+	w := jobs.New(store, jobs.Config{PollInterval: 10 * time.Millisecond, ShutdownTimeout: 10 * time.Millisecond})
+	started := make(chan struct{})
+	jobs.Handle(w, greet, func(ctx context.Context, payload greeting) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	job, err := greet.Enqueue(context.Background(), store, greeting{})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	w.Run(ctx)
+
+	stored, err := store.Jobs.Get(context.Background(), job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobPending, stored.Status)
+	assert.Zero(t, stored.Attempts, "the interrupted attempt does not count")
+	assert.Empty(t, stored.LastError)
+}
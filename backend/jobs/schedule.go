@@ -0,0 +1,167 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule says when a periodic job runs
+type Schedule interface {
+	// Next returns the first run after t, or the zero time if there is none
+	Next(t time.Time) time.Time
+}
+
+// every runs at multiples of an interval
+type every time.Duration
+
+// Every runs a job every d, at multiples of d since the zero time so that
+// instances agree on the runs
+func Every(d time.Duration) Schedule {
+	return every(d)
+}
+
+func (e every) Next(t time.Time) time.Time {
+	d := time.Duration(e)
+	return t.Truncate(d).Add(d)
+}
+
+// cron is a parsed cron expression; each field marks the values it matches
+type cron struct {
+	minute, hour, day, month, weekday []bool
+	// anyDay and anyWeekday are set when the field is *. As in cron, a day
+	// matches either restricted field when both are restricted.
+	anyDay, anyWeekday bool
+}
+
+// cronFields are the fields of a cron expression with their bounds
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// ParseCron reads a five-field cron expression such as "*/15 9-17 * * 1-5",
+// evaluated in UTC. Fields take *, numbers, ranges, lists and steps; day of
+// week 0 and 7 are Sunday.
+func ParseCron(spec string) (Schedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron %q: want %d fields, got %d", spec, len(cronFields), len(fields))
+	}
+	parsed := make([][]bool, len(fields))
+	for i, field := range fields {
+		values, err := parseCronField(field, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("cron %q: %s: %w", spec, cronFields[i].name, err)
+		}
+		parsed[i] = values
+	}
+	c := &cron{
+		minute:     parsed[0],
+		hour:       parsed[1],
+		day:        parsed[2],
+		month:      parsed[3],
+		weekday:    parsed[4],
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}
+	c.weekday[0] = c.weekday[0] || c.weekday[7]
+	if c.Next(time.Time{}).IsZero() {
+		return nil, fmt.Errorf("cron %q never runs", spec)
+	}
+	return c, nil
+}
+
+// MustParseCron is ParseCron for expressions known to be valid; it panics
+// on an error
+func MustParseCron(spec string) Schedule {
+	schedule, err := ParseCron(spec)
+	if err != nil {
+		panic(err)
+	}
+	return schedule
+}
+
+// parseCronField returns which values from 0 to max field matches
+func parseCronField(field string, min, max int) ([]bool, error) {
+	values := make([]bool, max+1)
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			parsed, err := strconv.Atoi(stepPart)
+			if err != nil || parsed < 1 {
+				return nil, fmt.Errorf("invalid step %q", stepPart)
+			}
+			step = parsed
+		}
+
+		low, high := min, max
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = strconv.Atoi(from); err != nil {
+				return nil, fmt.Errorf("invalid value %q", from)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(to); err != nil {
+					return nil, fmt.Errorf("invalid value %q", to)
+				}
+			} else if hasStep {
+				high = max
+			}
+		}
+		if low < min || high > max || low > high {
+			return nil, errors.New("value out of range " + strconv.Itoa(min) + "-" + strconv.Itoa(max))
+		}
+		for value := low; value <= high; value += step {
+			values[value] = true
+		}
+	}
+	return values, nil
+}
+
+// matchesDay reports whether the expression runs on the day of t
+func (c *cron) matchesDay(t time.Time) bool {
+	day, weekday := c.day[t.Day()], c.weekday[t.Weekday()]
+	switch {
+	case c.anyDay && c.anyWeekday:
+		return true
+	case c.anyDay:
+		return weekday
+	case c.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
+
+func (c *cron) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	// Any valid expression matches within a leap cycle
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case !c.month[t.Month()]:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !c.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case !c.hour[t.Hour()]:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case !c.minute[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,48 @@
+package jobs_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muneerlalji/Luma/jobs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvery(t *testing.T) {
+	at := time.Date(2026, time.March, 2, 9, 7, 12, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, time.March, 2, 9, 10, 0, 0, time.UTC), jobs.Every(5*time.Minute).Next(at))
+	assert.Equal(t, time.Date(2026, time.March, 2, 9, 15, 0, 0, time.UTC), jobs.Every(5*time.Minute).Next(at.Add(3*time.Minute)))
+}
+
+func TestParseCron(t *testing.T) {
+	// A Monday
+	at := time.Date(2026, time.March, 2, 9, 7, 12, 0, time.UTC)
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, time.March, 2, 9, 8, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2026, time.March, 2, 10, 0, 0, 0, time.UTC)},
+		{"*/15 9-17 * * 1-5", time.Date(2026, time.March, 2, 9, 15, 0, 0, time.UTC)},
+		{"30 3 * * *", time.Date(2026, time.March, 3, 3, 30, 0, 0, time.UTC)},
+		{"0 8 * * 0", time.Date(2026, time.March, 8, 8, 0, 0, 0, time.UTC)},
+		{"0 8 * * 7", time.Date(2026, time.March, 8, 8, 0, 0, 0, time.UTC)},
+		{"0 0 1,15 * *", time.Date(2026, time.March, 15, 0, 0, 0, 0, time.UTC)},
+		// Either the day of the month or of the week
+		{"0 0 20 * 3", time.Date(2026, time.March, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		schedule, err := jobs.ParseCron(tt.spec)
+		require.NoError(t, err, tt.spec)
+		assert.Equal(t, tt.want, schedule.Next(at), tt.spec)
+	}
+}
+
+func TestParseCron_Invalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "x * * * *", "0 0 30 2 *"} {
+		_, err := jobs.ParseCron(spec)
+		assert.Error(t, err, spec)
+	}
+}
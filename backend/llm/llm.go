@@ -0,0 +1,226 @@
+// Package llm talks to the language model that powers the chat assistant
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/muneerlalji/Luma/logging"
+)
+
+// ErrNotConfigured is returned when no API key or URL was provided
+var ErrNotConfigured = errors.New("language model is not configured")
+
+// Client generates replies to prompts
+type Client interface {
+	// Complete returns the full reply to prompt
+	Complete(ctx context.Context, prompt string, maxTokens int) (string, error)
+	// Stream calls onText with each piece of the reply as it arrives
+	Stream(ctx context.Context, prompt string, maxTokens int, onText func(string)) error
+}
+
+// Claude API request/response structures
+type ClaudeMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ClaudeRequest struct {
+	Model       string          `json:"model"`
+	MaxTokens   int             `json:"max_tokens"`
+	Messages    []ClaudeMessage `json:"messages"`
+	Temperature float64         `json:"temperature"`
+	Stream      bool            `json:"stream"`
+}
+
+type ClaudeResponse struct {
+	Content []struct {
+		Text string `json:"text"`
+	} `json:"content"`
+	Usage Usage `json:"usage"`
+}
+
+type ClaudeStreamingResponse struct {
+	Type  string `json:"type"`
+	Index int    `json:"index"`
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+	// Message is set on message_start and carries the input token count
+	Message struct {
+		Usage Usage `json:"usage"`
+	} `json:"message"`
+	// Usage is set on message_delta and carries the output token count
+	Usage Usage `json:"usage"`
+}
+
+// Usage counts the tokens of one request
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// DefaultClaudeModel is the model used when none is configured
+const DefaultClaudeModel = "claude-3-5-sonnet-20241022"
+
+// Claude is a Client for the Anthropic Messages API
+type Claude struct {
+	APIKey      string
+	APIURL      string
+	Model       string
+	Temperature float64
+	HTTPClient  *http.Client
+	// OnUsage, when set, is called with the token counts of each reply
+	OnUsage func(Usage)
+}
+
+// NewClaude creates a Claude client for the given key and endpoint
+func NewClaude(apiKey, apiURL string) *Claude {
+	return &Claude{
+		APIKey:      apiKey,
+		APIURL:      apiURL,
+		Model:       DefaultClaudeModel,
+		Temperature: 0.7,
+		HTTPClient:  &http.Client{},
+	}
+}
+
+// post sends a single-message request and returns the response once the
+// status has been checked
+func (c *Claude) post(ctx context.Context, prompt string, maxTokens int, stream bool) (*http.Response, error) {
+	if c.APIKey == "" || c.APIURL == "" {
+		return nil, ErrNotConfigured
+	}
+
+	jsonData, err := json.Marshal(ClaudeRequest{
+		Model:       c.Model,
+		MaxTokens:   maxTokens,
+		Temperature: c.Temperature,
+		Messages:    []ClaudeMessage{{Role: "user", Content: prompt}},
+		Stream:      stream,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.APIURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", c.APIKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, apiError(resp)
+	}
+	return resp, nil
+}
+
+// maxErrorBodyBytes bounds how much of an unrecognised error body is kept
+const maxErrorBodyBytes = 256
+
+// apiError describes a failed response by its error type and message only,
+// so prompts echoed back by the API do not end up in errors and logs
+func apiError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	var envelope struct {
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err == nil && envelope.Error.Type != "" {
+		return fmt.Errorf("API request failed with status %d: %s: %s", resp.StatusCode, envelope.Error.Type, envelope.Error.Message)
+	}
+	if len(body) > maxErrorBodyBytes {
+		body = append(body[:maxErrorBodyBytes], "..."...)
+	}
+	return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, body)
+}
+
+// Complete implements Client
+func (c *Claude) Complete(ctx context.Context, prompt string, maxTokens int) (string, error) {
+	resp, err := c.post(ctx, prompt, maxTokens, false)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var claudeResp ClaudeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&claudeResp); err != nil {
+		return "", err
+	}
+	c.recordUsage(claudeResp.Usage)
+	if len(claudeResp.Content) == 0 {
+		return "", errors.New("API response has no content")
+	}
+	return claudeResp.Content[0].Text, nil
+}
+
+func (c *Claude) recordUsage(usage Usage) {
+	if c.OnUsage != nil && (usage.InputTokens > 0 || usage.OutputTokens > 0) {
+		c.OnUsage(usage)
+	}
+}
+
+// Stream implements Client
+func (c *Claude) Stream(ctx context.Context, prompt string, maxTokens int, onText func(string)) error {
+	resp, err := c.post(ctx, prompt, maxTokens, true)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var usage Usage
+	defer func() { c.recordUsage(usage) }()
+
+	scanner := bufio.NewScanner(resp.Body)
+	buf := make([]byte, 0, 1024*1024)
+	scanner.Buffer(buf, 1024*1024)
+
+	for scanner.Scan() {
+		// Events normally arrive in Server-Sent Events format, but bare JSON
+		// lines are accepted too
+		line := scanner.Text()
+		data, isEvent := strings.CutPrefix(line, "data: ")
+		if data == "[DONE]" {
+			break
+		}
+
+		var event ClaudeStreamingResponse
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			if isEvent {
+				logging.FromContext(ctx).Warn("skipping unparseable stream event", "error", err)
+			}
+			continue
+		}
+
+		switch event.Type {
+		case "content_block_delta":
+			if event.Delta.Type == "text" || event.Delta.Type == "text_delta" {
+				onText(event.Delta.Text)
+			}
+		case "message_start":
+			usage.InputTokens = event.Message.Usage.InputTokens
+		case "message_delta":
+			usage.OutputTokens = event.Usage.OutputTokens
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,135 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces sensitive values in log records
+const Redacted = "[redacted]"
+
+// sensitiveKeys are attribute key words whose values are never logged
+// verbatim. Keys are split on "_", "-" and "." so "access_token" and
+// "user.email" match too.
+var sensitiveKeys = map[string]bool{
+	"authorization": true,
+	"cookie":        true,
+	"password":      true,
+	"secret":        true,
+	"token":         true,
+	"key":           true,
+	"email":         true,
+	"content":       true,
+	"prompt":        true,
+	"message":       true,
+	"reply":         true,
+	"body":          true,
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	// Query and form parameters such as ?token=... on confirmation links
+	paramPattern  = regexp.MustCompile(`(?i)\b((?:[a-z_]*token|password|secret|code|state|code_verifier)=)[^&\s"']+`)
+	jsonPattern   = regexp.MustCompile(`(?i)("(?:[a-z_]*token|password|secret|content|text|prompt)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	bearerPattern = regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`)
+	jwtPattern    = regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]*`)
+)
+
+// Redact scrubs email addresses, bearer tokens, JWTs, secret query
+// parameters and secret JSON fields from s
+func Redact(s string) string {
+	s = jsonPattern.ReplaceAllString(s, `$1"`+Redacted+`"`)
+	s = paramPattern.ReplaceAllString(s, "${1}"+Redacted)
+	s = bearerPattern.ReplaceAllString(s, "Bearer "+Redacted)
+	s = jwtPattern.ReplaceAllString(s, Redacted)
+	return emailPattern.ReplaceAllString(s, "[email]")
+}
+
+func isSensitiveKey(key string) bool {
+	for _, word := range strings.FieldsFunc(strings.ToLower(key), func(r rune) bool {
+		return r == '_' || r == '-' || r == '.'
+	}) {
+		if sensitiveKeys[word] {
+			return true
+		}
+	}
+	return false
+}
+
+// redactAttr hides the value of sensitive keys and scrubs everything else
+func redactAttr(attr slog.Attr) slog.Attr {
+	attr.Value = attr.Value.Resolve()
+	if attr.Value.Kind() == slog.KindGroup {
+		attrs := attr.Value.Group()
+		redacted := make([]slog.Attr, len(attrs))
+		for i, a := range attrs {
+			redacted[i] = redactAttr(a)
+		}
+		return slog.Attr{Key: attr.Key, Value: slog.GroupValue(redacted...)}
+	}
+	if isSensitiveKey(attr.Key) {
+		return slog.String(attr.Key, Redacted)
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, Redact(attr.Value.String()))
+	case slog.KindAny:
+		// Errors and other values are logged by their text, which may embed
+		// anything from a URL to an upstream response body
+		if err, ok := attr.Value.Any().(error); ok {
+			return slog.String(attr.Key, Redact(err.Error()))
+		}
+		if s, ok := attr.Value.Any().(interface{ String() string }); ok {
+			return slog.String(attr.Key, Redact(s.String()))
+		}
+	}
+	return attr
+}
+
+func redactAttrs(attrs []slog.Attr) []slog.Attr {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = redactAttr(attr)
+	}
+	return redacted
+}
+
+// RedactHandler wraps a slog.Handler and scrubs every record before it is
+// written
+type RedactHandler struct {
+	next slog.Handler
+}
+
+// NewRedactHandler returns a handler that redacts records and passes them on
+// to next
+func NewRedactHandler(next slog.Handler) *RedactHandler {
+	return &RedactHandler{next: next}
+}
+
+// Enabled implements slog.Handler
+func (h *RedactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler
+func (h *RedactHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, Redact(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(redactAttr(attr))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+// WithAttrs implements slog.Handler
+func (h *RedactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &RedactHandler{next: h.next.WithAttrs(redactAttrs(attrs))}
+}
+
+// WithGroup implements slog.Handler
+func (h *RedactHandler) WithGroup(name string) slog.Handler {
+	return &RedactHandler{next: h.next.WithGroup(name)}
+}
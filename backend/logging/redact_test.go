@@ -0,0 +1,82 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/muneerlalji/Luma/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedact(t *testing.T) {
+	tests := map[string]string{
+		"POST /auth/confirm?token=abc123&next=/home":               "POST /auth/confirm?token=[redacted]&next=/home",
+		"sent to jane.doe+luma@example.com":                        "sent to [email]",
+		"Authorization: Bearer abc.def-ghi":                        "Authorization: Bearer [redacted]",
+		"jwt eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.c2ln is invalid": "jwt [redacted] is invalid",
+		`{"content":"my daughter's name is Ann","role":"user"}`:    `{"content":"[redacted]","role":"user"}`,
+		"callback?code=xyz&state=s1":                               "callback?code=[redacted]&state=[redacted]",
+		"connection refused":                                       "connection refused",
+	}
+	for input, want := range tests {
+		assert.Equal(t, want, logging.Redact(input), input)
+	}
+}
+
+func logOne(t *testing.T, log func(logger *slog.Logger)) map[string]any {
+	t.Helper()
+	var out bytes.Buffer
+	log(logging.New(&out, logging.FormatJSON, slog.LevelDebug))
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(out.Bytes(), &record))
+	return record
+}
+
+func TestNew_RedactsAttributes(t *testing.T) {
+	record := logOne(t, func(logger *slog.Logger) {
+		logger.With("user_email", "jane@example.com").Info("login failed for jane@example.com",
+			"password", "hunter2",
+			"api_key", "sk-ant-123",
+			"error", errors.New("GET /reset?token=secret-token failed"),
+			slog.Group("chat", slog.String("content", "private thoughts"), slog.Int("length", 16)),
+			"status", 401,
+		)
+	})
+
+	assert.Equal(t, "login failed for [email]", record["msg"])
+	assert.Equal(t, logging.Redacted, record["user_email"])
+	assert.Equal(t, logging.Redacted, record["password"])
+	assert.Equal(t, logging.Redacted, record["api_key"])
+	assert.Equal(t, "GET /reset?token=[redacted] failed", record["error"])
+	assert.Equal(t, map[string]any{"content": logging.Redacted, "length": float64(16)}, record["chat"])
+	assert.Equal(t, float64(401), record["status"])
+}
+
+func TestNew_TextFormatAndLevel(t *testing.T) {
+	var out bytes.Buffer
+	logger := logging.New(&out, logging.FormatText, slog.LevelWarn)
+
+	logger.Info("hidden")
+	logger.Warn("shown", "token", "abc")
+
+	assert.NotContains(t, out.String(), "hidden")
+	assert.Contains(t, out.String(), "msg=shown")
+	assert.Contains(t, out.String(), "token=[redacted]")
+}
+
+func TestContextLogger(t *testing.T) {
+	assert.Same(t, slog.Default(), logging.FromContext(context.Background()))
+
+	record := logOne(t, func(logger *slog.Logger) {
+		ctx := logging.WithLogger(context.Background(), logger)
+		ctx = logging.With(ctx, "request_id", "req-1")
+		logging.FromContext(ctx).Info("hello")
+	})
+	assert.Equal(t, "req-1", record["request_id"])
+}
@@ -0,0 +1,186 @@
+package mailer
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// LinkData is the data of emails that carry a single link, such as the
+// confirmation and password reset emails
+type LinkData struct {
+	// Name is how the recipient is greeted
+	Name string
+	URL  string
+}
+
+// EmailChangeNoticeData tells the old address of a change to a new one
+type EmailChangeNoticeData struct {
+	Name     string
+	NewEmail string
+	// URL cancels the change
+	URL string
+}
+
+// ReminderData nudges the user to get in touch with someone
+type ReminderData struct {
+	FirstName  string
+	PersonName string
+	Phone      string
+	// URL is the People page, empty when unknown
+	URL string
+}
+
+// CheckInMissedData tells a caregiver that a routine was not checked in
+type CheckInMissedData struct {
+	// Name is the user the caregiver looks after
+	Name         string
+	Routine      string
+	Instructions string
+	// Due is in the user's timezone
+	Due time.Time
+	URL string
+}
+
+// DigestData lists the dates coming up for the user a caregiver looks after
+type DigestData struct {
+	Name   string
+	Events []DigestEvent
+	URL    string
+}
+
+// DigestEvent is an anniversary coming up. Kind is one of birthday,
+// anniversary, death_anniversary and memory_anniversary; memory
+// anniversaries carry the memory's Title instead of a PersonName.
+type DigestEvent struct {
+	Kind       string
+	PersonName string
+	Title      string
+	Years      int
+	Deceased   bool
+	Date       time.Time
+	DaysAway   int
+}
+
+// WeeklyDigestData tells a caregiver how the user they look after got on
+// over the past week. Times are in the user's timezone.
+type WeeklyDigestData struct {
+	Name string
+	// From and To bound the week
+	From time.Time
+	To   time.Time
+	// Memories and People are the titles and names of those added
+	Memories []string
+	People   []string
+	// ChatMessages counts the messages the user sent the assistant
+	ChatMessages int
+	Confused     []ChatMoment
+	Missed       []MissedRoutine
+	// Birthdays are those of the coming week
+	Birthdays []DigestEvent
+	// URL is the People page, empty when unknown
+	URL            string
+	UnsubscribeURL string
+}
+
+// ChatMoment is a message the user sent the assistant
+type ChatMoment struct {
+	At      time.Time
+	Message string
+}
+
+// MissedRoutine is an occurrence of a routine nobody checked in for
+type MissedRoutine struct {
+	Routine string
+	Due     time.Time
+}
+
+// Sample returns example data for the named template, for previews
+func Sample(name string) (any, error) {
+	due := time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC)
+	switch name {
+	case ConfirmEmail, ResetPassword, VerifyEmailChange, AccountLocked:
+		return LinkData{Name: "Margaret", URL: "https://luma.example.com/" + name + "?token=sample"}, nil
+	case EmailChangeNotice:
+		return EmailChangeNoticeData{Name: "Margaret", NewEmail: "margaret@example.org", URL: "https://luma.example.com/cancel-email-change?token=sample"}, nil
+	case Reminder:
+		return ReminderData{FirstName: "Tom", PersonName: "Tom Hughes", Phone: "555-0100", URL: "https://luma.example.com/people"}, nil
+	case CheckInMissed:
+		return CheckInMissedData{Name: "Margaret", Routine: "Blood pressure tablets", Instructions: "Two with water", Due: due, URL: "https://luma.example.com/today"}, nil
+	case CaregiverDigest:
+		return DigestData{Name: "Margaret", URL: "https://luma.example.com/people", Events: []DigestEvent{
+			{Kind: "birthday", PersonName: "Tom Hughes", Years: 12, Date: due, DaysAway: 0},
+			{Kind: "anniversary", PersonName: "Arthur Hughes", Years: 50, Deceased: true, Date: due.AddDate(0, 0, 1), DaysAway: 1},
+			{Kind: "memory_anniversary", Title: "Trip to Brighton", Years: 3, Date: due.AddDate(0, 0, 4), DaysAway: 4},
+		}}, nil
+	case WeeklyDigest:
+		return WeeklyDigestData{
+			Name:           "Margaret",
+			From:           due.AddDate(0, 0, -7),
+			To:             due,
+			Memories:       []string{"Trip to Brighton", "Tom's graduation"},
+			People:         []string{"Ann Hughes"},
+			ChatMessages:   23,
+			Confused:       []ChatMoment{{At: due.AddDate(0, 0, -3).Add(11 * time.Hour), Message: "What day is it today?"}},
+			Missed:         []MissedRoutine{{Routine: "Blood pressure tablets", Due: due.AddDate(0, 0, -2)}},
+			Birthdays:      []DigestEvent{{Kind: "birthday", PersonName: "Tom Hughes", Years: 12, Date: due.AddDate(0, 0, 3), DaysAway: 3}},
+			URL:            "https://luma.example.com/people",
+			UnsubscribeURL: "https://luma.example.com/unsubscribe?token=sample",
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+}
+
+// weekdays and months name days and months by locale, as time.Weekday and
+// time.Month index them
+var (
+	weekdays = map[string][7]string{
+		"en": {"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+		"es": {"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+	}
+	months = map[string][13]string{
+		"en": {"", "January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		"es": {"", "enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+	}
+	// dateFormats put a weekday, day of month and month together
+	dateFormats = map[string]string{
+		"en": "%[1]s, %[2]d %[3]s",
+		"es": "%[1]s, %[2]d de %[3]s",
+	}
+)
+
+// localeFuncs are the template functions, formatting for locale
+func localeFuncs(locale string) map[string]any {
+	days, ok := weekdays[locale]
+	if !ok {
+		days, locale = weekdays[DefaultLocale], DefaultLocale
+	}
+	return map[string]any{
+		// date formats a day such as "Monday, 2 March"
+		"date": func(t time.Time) string {
+			return fmt.Sprintf(dateFormats[locale], days[t.Weekday()], t.Day(), months[locale][t.Month()])
+		},
+		// clock formats a time of day such as "09:00"
+		"clock": func(t time.Time) string {
+			return t.Format("15:04")
+		},
+		// link is the argument of the "button" template
+		"link": func(url, label string) map[string]string {
+			return map[string]string{"URL": url, "Label": label}
+		},
+		// ordinal writes an English ordinal such as "21st"
+		"ordinal": func(n int) string {
+			suffix := "th"
+			switch {
+			case n%100 >= 11 && n%100 <= 13:
+			case n%10 == 1:
+				suffix = "st"
+			case n%10 == 2:
+				suffix = "nd"
+			case n%10 == 3:
+				suffix = "rd"
+			}
+			return strconv.Itoa(n) + suffix
+		},
+	}
+}
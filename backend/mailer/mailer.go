@@ -0,0 +1,200 @@
+// Package mailer renders the emails Luma sends from per-locale templates.
+// Each template has a subject, a plain text body and an HTML body, which is
+// wrapped in a shared layout.
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	texttemplate "text/template"
+
+	"golang.org/x/text/language"
+)
+
+// Template names
+const (
+	ConfirmEmail      = "confirm_email"
+	ResetPassword     = "reset_password"
+	VerifyEmailChange = "verify_email_change"
+	EmailChangeNotice = "email_change_notice"
+	AccountLocked     = "account_locked"
+	Reminder          = "reminder"
+	CheckInMissed     = "check_in_missed"
+	CaregiverDigest   = "caregiver_digest"
+	WeeklyDigest      = "weekly_digest"
+)
+
+// DefaultLocale is used when the recipient's locale has no templates
+const DefaultLocale = "en"
+
+// ErrUnknownTemplate is returned for a template name that does not exist
+var ErrUnknownTemplate = errors.New("unknown email template")
+
+//go:embed templates
+var files embed.FS
+
+// Message is a rendered email
+type Message struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// templateSet holds one template of one locale, parsed for text and HTML
+type templateSet struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+var (
+	// locales lists the locales with templates, default first
+	locales []string
+	// names lists the template names, sorted
+	names []string
+	// sets holds the templates by locale, then name
+	sets = map[string]map[string]*templateSet{}
+	// matcher picks the closest locale to a language preference
+	matcher language.Matcher
+)
+
+func init() {
+	entries, err := fs.ReadDir(files, "templates")
+	if err != nil {
+		panic(err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			locales = append(locales, entry.Name())
+		}
+	}
+	slices.SortFunc(locales, func(a, b string) int {
+		switch {
+		case a == DefaultLocale:
+			return -1
+		case b == DefaultLocale:
+			return 1
+		}
+		return strings.Compare(a, b)
+	})
+
+	tags := make([]language.Tag, len(locales))
+	for i, locale := range locales {
+		tags[i] = language.MustParse(locale)
+		sets[locale] = make(map[string]*templateSet)
+		templates, err := fs.Glob(files, path.Join("templates", locale, "*.tmpl"))
+		if err != nil {
+			panic(err)
+		}
+		for _, file := range templates {
+			name := strings.TrimSuffix(path.Base(file), ".tmpl")
+			if name == "layout" {
+				continue
+			}
+			sets[locale][name] = parse(locale, file)
+			if locale == DefaultLocale {
+				names = append(names, name)
+			}
+		}
+	}
+	matcher = language.NewMatcher(tags)
+	slices.Sort(names)
+
+	// Every locale must have every template
+	for _, locale := range locales {
+		for _, name := range names {
+			if sets[locale][name] == nil {
+				panic(fmt.Sprintf("mailer: %s has no %s template", locale, name))
+			}
+		}
+	}
+}
+
+// parse reads the shared layout, the locale's layout and file as both text
+// and HTML templates
+func parse(locale, file string) *templateSet {
+	patterns := []string{"templates/layout.tmpl", path.Join("templates", locale, "layout.tmpl"), file}
+	funcs := localeFuncs(locale)
+	return &templateSet{
+		text: texttemplate.Must(texttemplate.New(path.Base(file)).Funcs(funcs).Option("missingkey=error").ParseFS(files, patterns...)),
+		html: htmltemplate.Must(htmltemplate.New(path.Base(file)).Funcs(funcs).Option("missingkey=error").ParseFS(files, patterns...)),
+	}
+}
+
+// Locales returns the locales emails can be written in, default first
+func Locales() []string {
+	return slices.Clone(locales)
+}
+
+// Names returns the names of the templates, sorted
+func Names() []string {
+	return slices.Clone(names)
+}
+
+// Supported reports whether there are templates for locale
+func Supported(locale string) bool {
+	return slices.Contains(locales, locale)
+}
+
+// MatchLocale returns the supported locale closest to an Accept-Language
+// header or language tag, or DefaultLocale when none is close
+func MatchLocale(preference string) string {
+	tags, _, err := language.ParseAcceptLanguage(preference)
+	if err != nil || len(tags) == 0 {
+		return DefaultLocale
+	}
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return DefaultLocale
+	}
+	return locales[index]
+}
+
+// Render renders the named template in locale with data, falling back to
+// DefaultLocale when the locale has no templates
+func Render(name, locale string, data any) (*Message, error) {
+	if !Supported(locale) {
+		locale = DefaultLocale
+	}
+	set := sets[locale][name]
+	if set == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+	}
+
+	var subject, text, content, html bytes.Buffer
+	if err := set.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("render %s subject: %w", name, err)
+	}
+	if err := set.text.ExecuteTemplate(&text, "text", data); err != nil {
+		return nil, fmt.Errorf("render %s text: %w", name, err)
+	}
+	if err := set.html.ExecuteTemplate(&content, "html", data); err != nil {
+		return nil, fmt.Errorf("render %s html: %w", name, err)
+	}
+	page := layoutData{
+		Locale:  locale,
+		Subject: strings.TrimSpace(subject.String()),
+		Content: htmltemplate.HTML(content.String()),
+	}
+	if err := set.html.ExecuteTemplate(&html, "layout", page); err != nil {
+		return nil, fmt.Errorf("render %s layout: %w", name, err)
+	}
+	return &Message{
+		Subject: page.Subject,
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
+
+// layoutData is what the shared HTML layout is rendered with
+type layoutData struct {
+	Locale  string
+	Subject string
+	Content htmltemplate.HTML
+}
@@ -0,0 +1,112 @@
+package mailer_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muneerlalji/Luma/mailer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender_EveryTemplateAndLocale(t *testing.T) {
+	require.Contains(t, mailer.Locales(), "es")
+	assert.Equal(t, mailer.DefaultLocale, mailer.Locales()[0])
+	for _, name := range mailer.Names() {
+		data, err := mailer.Sample(name)
+		require.NoError(t, err)
+		for _, locale := range mailer.Locales() {
+			message, err := mailer.Render(name, locale, data)
+			require.NoError(t, err, "%s %s", name, locale)
+			assert.NotEmpty(t, message.Subject, "%s %s", name, locale)
+			assert.NotContains(t, message.Subject, "\n")
+			assert.NotEmpty(t, message.Text, "%s %s", name, locale)
+			assert.Contains(t, message.HTML, `<html lang="`+locale+`">`)
+			assert.NotContains(t, message.Text, "<p>")
+		}
+	}
+}
+
+func TestRender_Localized(t *testing.T) {
+	data := mailer.CheckInMissedData{
+		Name: "Margaret", Routine: "Tablets", Instructions: "With water",
+		Due: time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC), URL: "https://luma.example.com/today",
+	}
+	message, err := mailer.Render(mailer.CheckInMissed, "en", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Margaret has not checked in for Tablets", message.Subject)
+	assert.Equal(t, "Margaret has not confirmed Tablets, due at 09:00 on Monday, 2 March.\n\n"+
+		"Instructions: With water\n\nSee their schedule in Luma: https://luma.example.com/today\n", message.Text)
+
+	message, err = mailer.Render(mailer.CheckInMissed, "es", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Margaret no ha confirmado Tablets", message.Subject)
+	assert.Contains(t, message.Text, "a las 09:00 del lunes, 2 de marzo")
+
+	// Locales without templates fall back to the default
+	message, err = mailer.Render(mailer.CheckInMissed, "fr", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Margaret has not checked in for Tablets", message.Subject)
+}
+
+func TestRender_EscapesHTMLOnly(t *testing.T) {
+	data := mailer.ReminderData{FirstName: "Tom", PersonName: "Tom <Hughes> & co"}
+	message, err := mailer.Render(mailer.Reminder, "en", data)
+	require.NoError(t, err)
+	assert.Equal(t, "This is your reminder to get in touch with Tom <Hughes> & co.\n", message.Text)
+	assert.Contains(t, message.HTML, "Tom &lt;Hughes&gt; &amp; co")
+	assert.NotContains(t, message.HTML, "See your reminders")
+
+	_, err = mailer.Render("welcome", "en", data)
+	assert.ErrorIs(t, err, mailer.ErrUnknownTemplate)
+	// Data of the wrong shape is an error rather than a blank
+	_, err = mailer.Render(mailer.Reminder, "en", mailer.LinkData{})
+	assert.Error(t, err)
+}
+
+func TestRender_Digest(t *testing.T) {
+	on := time.Date(2026, time.March, 6, 0, 0, 0, 0, time.UTC)
+	data := mailer.DigestData{Name: "Margaret", Events: []mailer.DigestEvent{
+		{Kind: "birthday", PersonName: "Tom Hughes", Years: 12, Date: on, DaysAway: 4},
+		{Kind: "anniversary", PersonName: "Arthur Hughes", Years: 22, Date: on, DaysAway: 1},
+		{Kind: "memory_anniversary", Title: "Trip to Brighton", Years: 1, Date: on, DaysAway: 0},
+	}}
+	message, err := mailer.Render(mailer.CaregiverDigest, "en", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Here is what is coming up for Margaret this week:\n\n"+
+		"- Friday, 6 March (in 4 days): Tom Hughes turns 12\n"+
+		"- tomorrow, Friday, 6 March: 22nd anniversary with Arthur Hughes\n"+
+		"- today, Friday, 6 March: 1 year since Trip to Brighton\n", message.Text)
+}
+
+func TestMatchLocale(t *testing.T) {
+	assert.Equal(t, "es", mailer.MatchLocale("es-MX,es;q=0.9,en;q=0.8"))
+	assert.Equal(t, "en", mailer.MatchLocale("fr-FR,de;q=0.5"))
+	assert.Equal(t, "en", mailer.MatchLocale("en-GB"))
+	assert.Equal(t, "en", mailer.MatchLocale(""))
+	assert.True(t, mailer.Supported("es"))
+	assert.False(t, mailer.Supported("es-MX"))
+}
+
+func TestRender_WeeklyDigest(t *testing.T) {
+	data, err := mailer.Sample(mailer.WeeklyDigest)
+	require.NoError(t, err)
+	message, err := mailer.Render(mailer.WeeklyDigest, "en", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Margaret's week in Luma", message.Subject)
+	assert.Equal(t, "Here is how Margaret got on from Monday, 23 February to Monday, 2 March.\n\n"+
+		"New memories: 2\n- Trip to Brighton\n- Tom's graduation\n\n"+
+		"People added: 1\n- Ann Hughes\n\n"+
+		"Messages to the assistant: 23\n\n"+
+		"Moments that suggested confusion:\n- Friday, 27 February at 20:00: \"What day is it today?\"\n\n"+
+		"Missed routines:\n- Blood pressure tablets, due at 09:00 on Saturday, 28 February\n\n"+
+		"Birthdays in the coming week:\n- Thursday, 5 March: Tom Hughes turns 12\n\n"+
+		"See more in Luma: https://luma.example.com/people\n\n"+
+		"To stop these weekly emails, visit https://luma.example.com/unsubscribe?token=sample\n", message.Text)
+	assert.Contains(t, message.HTML, `href="https://luma.example.com/unsubscribe?token=sample"`)
+
+	// A quiet week says so rather than leaving sections out
+	message, err = mailer.Render(mailer.WeeklyDigest, "en", mailer.WeeklyDigestData{Name: "Margaret", UnsubscribeURL: "https://luma.example.com/unsubscribe"})
+	require.NoError(t, err)
+	assert.Contains(t, message.Text, "No moments of confusion were noticed.\n\nNo routines were missed.\n\nNo birthdays in the coming week.\n")
+}
@@ -1,74 +1,310 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	// Users' timezones must load on hosts without a zoneinfo database
+	_ "time/tzdata"
 
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/config"
 	"github.com/muneerlalji/Luma/db"
 	"github.com/muneerlalji/Luma/handlers"
+	"github.com/muneerlalji/Luma/health"
+	"github.com/muneerlalji/Luma/llm"
+	"github.com/muneerlalji/Luma/logging"
+	"github.com/muneerlalji/Luma/metrics"
 	"github.com/muneerlalji/Luma/middleware"
+	"github.com/muneerlalji/Luma/oidc"
+	"github.com/muneerlalji/Luma/repository"
+	"github.com/muneerlalji/Luma/storage"
+	"github.com/muneerlalji/Luma/tracing"
+	"github.com/muneerlalji/Luma/utils"
+	"github.com/muneerlalji/Luma/webpush"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
-func init() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			runMigrate(os.Args[2:])
+			return
+		case "config":
+			runConfig(os.Args[2:])
+			return
+		case "worker":
+			runWorker(os.Args[2:])
+			return
+		}
 	}
-}
 
-func main() {
-	db.Init()
+	cfg := loadConfig()
+	gin.SetMode(cfg.GinMode)
+	logger, m, flushTraces := setup(cfg)
+
+	// Cancelled on SIGINT/SIGTERM to start a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	h, photoStorage := newHandler(cfg, m)
+	worker := newWorker(cfg, h)
+	triggerOnQueuedEmail(ctx, cfg, h, worker)
+	// Without the embedded worker jobs are still queued here, for the
+	// worker command to run
+	var workerDone chan struct{}
+	if cfg.EmbeddedWorker {
+		workerDone = make(chan struct{})
+		go func() {
+			defer close(workerDone)
+			worker.Run(ctx)
+		}()
+	}
 
-	router := gin.Default()
-	router.Use(middleware.CORSMiddleware())
+	checker := newHealthChecker(cfg, photoStorage)
+
+	router := gin.New()
+	// Lets handlers pass the gin context to code that reads the request
+	// context, such as the request logger
+	router.ContextWithFallback = true
+	router.Use(
+		// Continues the trace started by the frontend, if any, in a span
+		// named after the route
+		otelgin.Middleware(tracing.ServiceName),
+		middleware.RequestID(logger),
+		// The calendar feed's file name is its secret token
+		middleware.RedactPathParams("file"),
+		m.Middleware(),
+		middleware.AccessLog(),
+		middleware.Recovery(),
+		middleware.CORSMiddleware(),
+		apierror.Middleware(),
+	)
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(func(c *gin.Context) { apierror.Abort(c, apierror.ErrNotFound) })
+	router.NoMethod(func(c *gin.Context) { apierror.Abort(c, apierror.ErrMethodNotAllowed) })
 
 	// Configure trusted proxies based on environment
-	env := os.Getenv("GIN_MODE")
-	if env == "release" {
+	if cfg.GinMode == gin.ReleaseMode {
 		router.SetTrustedProxies([]string{"127.0.0.1", "::1"})
 	} else {
 		router.SetTrustedProxies([]string{"127.0.0.1", "::1", "localhost"})
 	}
 
-	// Public routes
-	router.GET("/", func(c *gin.Context) {
-		c.JSON(200, gin.H{"message": "Luma backend running"})
+	// Metrics go on their own listener when one is configured, otherwise on
+	// the main router only behind a token
+	var metricsServer *http.Server
+	if cfg.Metrics.Addr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", m.Handler(cfg.Metrics.Token))
+		metricsServer = &http.Server{
+			Addr:              cfg.Metrics.Addr,
+			Handler:           mux,
+			ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		}
+	} else if cfg.Metrics.Token != "" {
+		router.GET("/metrics", gin.WrapH(m.Handler(cfg.Metrics.Token)))
+	}
+
+	// Rate limits, overridable with RATE_LIMIT_<NAME>_IP / RATE_LIMIT_<NAME>_EMAIL
+	var limiter middleware.RateLimitStore = middleware.NewMemoryRateLimitStore()
+	if cfg.RateLimitStore == "postgres" {
+		limiter = middleware.NewPostgresRateLimitStore(db.DB)
+	}
+	rateLimit := func(name string, defaults middleware.RateLimitConfig) gin.HandlerFunc {
+		return middleware.RateLimit(limiter, cfg.RateLimit(name, defaults))
+	}
+	limits := rateLimits{
+		auth: rateLimit("auth", middleware.RateLimitConfig{
+			PerIP: middleware.Limit{Requests: 60, Window: time.Minute},
+		}),
+		login: rateLimit("login", middleware.RateLimitConfig{
+			PerIP:    middleware.Limit{Requests: 20, Window: time.Minute},
+			PerEmail: middleware.Limit{Requests: 10, Window: 15 * time.Minute},
+		}),
+		// Token endpoints share one bucket so guesses cannot be spread across them
+		token: rateLimit("token", middleware.RateLimitConfig{
+			PerIP: middleware.Limit{Requests: 10, Window: 15 * time.Minute},
+		}),
+		forgotPassword: rateLimit("forgot_password", middleware.RateLimitConfig{
+			PerIP:    middleware.Limit{Requests: 5, Window: 15 * time.Minute},
+			PerEmail: middleware.Limit{Requests: 3, Window: time.Hour},
+		}),
+		resendConfirmation: rateLimit("resend_confirmation", middleware.RateLimitConfig{
+			PerIP:    middleware.Limit{Requests: 5, Window: 15 * time.Minute},
+			PerEmail: middleware.Limit{Requests: 3, Window: time.Hour},
+		}),
+		api: rateLimit("api", middleware.RateLimitConfig{
+			PerIP: middleware.Limit{Requests: 300, Window: time.Minute},
+		}),
+	}
+	registerRoutes(router, h, checker, limits, cfg.LegacyAPISunset)
+
+	server := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           router,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+	// Notification streams stay open until the client leaves, so they are
+	// ended rather than drained
+	server.RegisterOnShutdown(h.CloseStreams)
+	servers := []*http.Server{server}
+	if metricsServer != nil {
+		servers = append(servers, metricsServer)
+	}
+	for _, server := range servers {
+		go func() {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				fatal("server error", err)
+			}
+		}()
+		slog.Info("server listening", "addr", server.Addr)
+	}
+
+	<-ctx.Done()
+	stop()
+	shutdown(checker, cfg.Server.ShutdownTimeout, workerDone, servers...)
+	flushTraces()
+}
+
+// fatal logs err and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// setup configures logging, tracing and metrics and opens the database for
+// the server and the worker command. The returned function flushes traces.
+func setup(cfg *config.Config) (*slog.Logger, *metrics.Metrics, func()) {
+	logger := logging.New(os.Stdout, cfg.Log.Format, cfg.Log.Level)
+	slog.SetDefault(logger)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		fatal("tracing error", err)
+	}
+
+	if err := db.Init(cfg.DatabaseDSN, cfg.MigrateOnStart); err != nil {
+		fatal("database error", err)
+	}
+	m := metrics.New()
+	if err := errors.Join(m.InstrumentDB(db.DB), tracing.InstrumentDB(db.DB)); err != nil {
+		fatal("failed to instrument database", err)
+	}
+
+	flushTraces := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Warn("failed to flush traces", "error", err)
+		}
+	}
+	return logger, m, flushTraces
+}
+
+// newHandler connects the handlers to the database, email, chat, photo
+// storage and push services, returning the photo storage for health checks
+func newHandler(cfg *config.Config, m *metrics.Metrics) (*handlers.Handler, storage.Storage) {
+	s3Storage, err := storage.NewS3(context.Background(), storage.S3Config{
+		Region:          cfg.AWSRegion,
+		Bucket:          cfg.S3Bucket,
+		AccessKeyID:     cfg.AWSAccessKeyID,
+		SecretAccessKey: cfg.AWSSecretAccessKey,
+	})
+	if err != nil {
+		fatal("AWS config error", err)
+	}
+	photoStorage := tracing.Storage(m.Storage(s3Storage))
+
+	claude := llm.NewClaude(cfg.ClaudeAPIKey, cfg.AnthropicAPIURL)
+	claude.OnUsage = m.RecordLLMUsage
+	claude.HTTPClient.Transport = tracing.Transport(nil)
+
+	push, err := webpush.New(cfg.WebPush)
+	if err != nil {
+		fatal("web push config error", err)
+	}
+	push.HTTPClient.Transport = tracing.Transport(nil)
+
+	h := handlers.New(handlers.Deps{
+		Store: repository.NewGormStore(db.DB),
+		Email: tracing.Email(m.Email(&utils.DefaultEmailService{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			User:     cfg.SMTP.User,
+			Password: cfg.SMTP.Password,
+			From:     cfg.SMTP.From,
+		})),
+		LLM:     tracing.LLM(m.LLM(claude)),
+		Storage: photoStorage,
+		Push:    push,
+		OIDC:    oidc.NewRegistry(nil, cfg.OIDC...),
+		Config:  cfg.Handlers,
+	})
+	return h, photoStorage
+}
+
+// shutdown fails readiness, then waits up to timeout for in-flight requests
+// such as uploads and streaming chats to finish, and for the worker to stop
+// when workerDone is not nil, before closing the rest
+func shutdown(checker *health.Checker, timeout time.Duration, workerDone <-chan struct{}, servers ...*http.Server) {
+	slog.Info("shutting down, draining requests", "timeout", timeout)
+	checker.ShuttingDown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil {
+			slog.Warn("requests still running at shutdown timeout", "addr", server.Addr, "error", err)
+			server.Close()
+		}
+	}
+	// The worker bounds its own wait for running jobs
+	if workerDone != nil {
+		<-workerDone
+	}
+
+	if sqlDB, err := db.DB.DB(); err == nil {
+		sqlDB.Close()
+	}
+	slog.Info("shutdown complete")
+}
+
+// newHealthChecker registers the dependencies /readyz reports on. Chat is
+// optional, so a missing API key degrades the service without making it
+// unready.
+func newHealthChecker(cfg *config.Config, photoStorage storage.Storage) *health.Checker {
+	checker := health.NewChecker()
+
+	checker.Add("database", func(ctx context.Context) error {
+		sqlDB, err := db.DB.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	})
+	migrator, err := db.NewMigrator(db.DB)
+	if err != nil {
+		fatal("failed to load migrations", err)
+	}
+	checker.Add("migrations", migrator.RequireCurrent)
+	checker.Add("storage", photoStorage.Check)
+	checker.AddOptional("llm", func(ctx context.Context) error {
+		if cfg.ClaudeAPIKey == "" {
+			return llm.ErrNotConfigured
+		}
+		return nil
 	})
 
-	// Authentication routes
-	auth := router.Group("/auth")
-	{
-		auth.POST("/register", handlers.Register)
-		auth.POST("/login", handlers.Login)
-		auth.POST("/confirm", handlers.ConfirmEmail)
-		auth.POST("/forgot-password", handlers.ForgotPassword)
-		auth.POST("/reset-password", handlers.ResetPassword)
-	}
-
-	// Protected routes
-	protected := router.Group("/")
-	protected.Use(handlers.AuthMiddleware())
-	{
-		protected.GET("/me", handlers.GetCurrentUser)
-		protected.PUT("/profile", handlers.UpdateProfile)
-		protected.PUT("/change-password", handlers.ChangePassword)
-		protected.DELETE("/profile", handlers.DeleteAccount)
-		protected.POST("/upload-photo", handlers.UploadPhoto)
-		protected.POST("/memories", handlers.CreateMemory)
-		protected.GET("/memories", handlers.GetMemories)
-		protected.GET("/photos/:id", handlers.GetPhoto)
-		protected.POST("/people", handlers.CreatePerson)
-		protected.GET("/people", handlers.GetPeople)
-		protected.POST("/chat", handlers.Chat)
-		protected.GET("/chat/history", handlers.GetChatHistory)
-	}
-
-	port := os.Getenv("PORT")
-	if port == "" {
-		log.Fatal("PORT is not set")
-	}
-
-	router.Run(":" + port)
+	return checker
 }
@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/muneerlalji/Luma/llm"
+	"github.com/muneerlalji/Luma/storage"
+	"github.com/muneerlalji/Luma/utils"
+)
+
+// Storage wraps s so each operation is timed
+func (m *Metrics) Storage(s storage.Storage) storage.Storage {
+	return &instrumentedStorage{next: s, m: m}
+}
+
+type instrumentedStorage struct {
+	next storage.Storage
+	m    *Metrics
+}
+
+func (s *instrumentedStorage) observe(operation string, start time.Time, err error) {
+	s.m.storageDuration.WithLabelValues(operation, outcome(err)).Observe(time.Since(start).Seconds())
+}
+
+func (s *instrumentedStorage) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	start := time.Now()
+	err := s.next.Put(ctx, key, body, contentType)
+	s.observe("put", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	start := time.Now()
+	url, err := s.next.PresignGet(ctx, key, expires)
+	s.observe("presign_get", start, err)
+	return url, err
+}
+
+func (s *instrumentedStorage) Delete(ctx context.Context, key string) error {
+	start := time.Now()
+	err := s.next.Delete(ctx, key)
+	s.observe("delete", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) Check(ctx context.Context) error {
+	start := time.Now()
+	err := s.next.Check(ctx)
+	s.observe("check", start, err)
+	return err
+}
+
+// LLM wraps client so each call is timed, along with the time until the
+// first text of a streamed reply
+func (m *Metrics) LLM(client llm.Client) llm.Client {
+	return &instrumentedLLM{next: client, m: m}
+}
+
+// RecordLLMUsage counts the tokens of a reply; pass it as llm.Claude.OnUsage
+func (m *Metrics) RecordLLMUsage(usage llm.Usage) {
+	m.llmTokens.WithLabelValues("input").Add(float64(usage.InputTokens))
+	m.llmTokens.WithLabelValues("output").Add(float64(usage.OutputTokens))
+}
+
+type instrumentedLLM struct {
+	next llm.Client
+	m    *Metrics
+}
+
+func (c *instrumentedLLM) Complete(ctx context.Context, prompt string, maxTokens int) (string, error) {
+	start := time.Now()
+	reply, err := c.next.Complete(ctx, prompt, maxTokens)
+	c.m.llmDuration.WithLabelValues("complete", outcome(err)).Observe(time.Since(start).Seconds())
+	return reply, err
+}
+
+func (c *instrumentedLLM) Stream(ctx context.Context, prompt string, maxTokens int, onText func(string)) error {
+	start := time.Now()
+	first := true
+	err := c.next.Stream(ctx, prompt, maxTokens, func(text string) {
+		if first {
+			first = false
+			c.m.llmTimeToFirstToken.Observe(time.Since(start).Seconds())
+		}
+		onText(text)
+	})
+	c.m.llmDuration.WithLabelValues("stream", outcome(err)).Observe(time.Since(start).Seconds())
+	return err
+}
+
+// Email wraps service so sends are counted by outcome
+func (m *Metrics) Email(service utils.EmailService) utils.EmailService {
+	return &instrumentedEmail{next: service, m: m}
+}
+
+type instrumentedEmail struct {
+	next utils.EmailService
+	m    *Metrics
+}
+
+func (e *instrumentedEmail) SendEmail(ctx context.Context, email *utils.Email) error {
+	err := e.next.SendEmail(ctx, email)
+	e.m.emails.WithLabelValues(outcome(err)).Inc()
+	return err
+}
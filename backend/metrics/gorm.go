@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const startKey = "metrics:start"
+
+// InstrumentDB times every query made through db with GORM callbacks
+func (m *Metrics) InstrumentDB(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("*").Register("metrics:before_create", startQuery),
+		cb.Create().After("*").Register("metrics:after_create", m.observeQuery("create")),
+		cb.Query().Before("*").Register("metrics:before_query", startQuery),
+		cb.Query().After("*").Register("metrics:after_query", m.observeQuery("query")),
+		cb.Update().Before("*").Register("metrics:before_update", startQuery),
+		cb.Update().After("*").Register("metrics:after_update", m.observeQuery("update")),
+		cb.Delete().Before("*").Register("metrics:before_delete", startQuery),
+		cb.Delete().After("*").Register("metrics:after_delete", m.observeQuery("delete")),
+		cb.Row().Before("*").Register("metrics:before_row", startQuery),
+		cb.Row().After("*").Register("metrics:after_row", m.observeQuery("row")),
+		cb.Raw().Before("*").Register("metrics:before_raw", startQuery),
+		cb.Raw().After("*").Register("metrics:after_raw", m.observeQuery("raw")),
+	)
+}
+
+func startQuery(tx *gorm.DB) {
+	tx.InstanceSet(startKey, time.Now())
+}
+
+func (m *Metrics) observeQuery(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		value, _ := tx.InstanceGet(startKey)
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		table := tx.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		m.dbDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+		if err := tx.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			m.dbErrors.WithLabelValues(operation, table).Inc()
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muneerlalji/Luma/llm"
+	"github.com/muneerlalji/Luma/metrics"
+	"github.com/muneerlalji/Luma/storage"
+	"github.com/muneerlalji/Luma/utils"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware_LabelsByRoute(t *testing.T) {
+	m := metrics.New()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(m.Middleware())
+	router.GET("/photos/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, path := range []string{"/photos/1", "/photos/2", "/missing"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	expected := `
+# HELP luma_http_requests_total HTTP requests by method, route and status code.
+# TYPE luma_http_requests_total counter
+luma_http_requests_total{method="GET",route="/photos/:id",status="200"} 2
+luma_http_requests_total{method="GET",route="unmatched",status="404"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(m.Registry(), strings.NewReader(expected), "luma_http_requests_total"))
+}
+
+func TestHandler_RequiresToken(t *testing.T) {
+	handler := metrics.New().Handler("scrape-token")
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer scrape-token")
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "go_goroutines")
+}
+
+type failingEmail struct{}
+
+func (failingEmail) SendEmail(ctx context.Context, email *utils.Email) error {
+	return errors.New("smtp down")
+}
+
+func TestClients_RecordOutcomes(t *testing.T) {
+	m := metrics.New()
+
+	s := m.Storage(storage.NewMemory())
+	require.NoError(t, s.Put(context.Background(), "photo", strings.NewReader("jpeg"), "image/jpeg"))
+	_ = m.Email(failingEmail{}).SendEmail(context.Background(), &utils.Email{To: "someone@example.com", Subject: "subject", Text: "body"})
+
+	assert.Equal(t, 1, testutil.CollectAndCount(m.Registry(), "luma_storage_operation_duration_seconds"))
+	expected := `
+# HELP luma_emails_sent_total Emails sent by outcome.
+# TYPE luma_emails_sent_total counter
+luma_emails_sent_total{status="error"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(m.Registry(), strings.NewReader(expected), "luma_emails_sent_total"))
+}
+
+func TestLLM_StreamRecordsTokensAndFirstToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"type":"message_start","message":{"usage":{"input_tokens":42}}}`+"\n\n")
+		fmt.Fprint(w, `data: {"type":"content_block_delta","delta":{"type":"text_delta","text":"Hello"}}`+"\n\n")
+		fmt.Fprint(w, `data: {"type":"message_delta","usage":{"output_tokens":7}}`+"\n\n")
+	}))
+	defer server.Close()
+
+	m := metrics.New()
+	claude := llm.NewClaude("test-key", server.URL)
+	claude.OnUsage = m.RecordLLMUsage
+	client := m.LLM(claude)
+
+	var reply strings.Builder
+	require.NoError(t, client.Stream(context.Background(), "hi", 10, func(text string) { reply.WriteString(text) }))
+	assert.Equal(t, "Hello", reply.String())
+
+	expected := `
+# HELP luma_llm_tokens_total Language model tokens used, by type (input or output).
+# TYPE luma_llm_tokens_total counter
+luma_llm_tokens_total{type="input"} 42
+luma_llm_tokens_total{type="output"} 7
+`
+	assert.NoError(t, testutil.GatherAndCompare(m.Registry(), strings.NewReader(expected), "luma_llm_tokens_total"))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.Registry(), "luma_llm_time_to_first_token_seconds"))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.Registry(), "luma_llm_request_duration_seconds"))
+}
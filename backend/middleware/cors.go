@@ -10,7 +10,8 @@ func CORSMiddleware() gin.HandlerFunc {
 	config := cors.DefaultConfig()
 	config.AllowOrigins = []string{"http://localhost:3000", "http://localhost:5173", "http://127.0.0.1:3000", "http://127.0.0.1:5173"}
 	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", RequestIDHeader, "traceparent", "tracestate"}
+	config.ExposeHeaders = []string{RequestIDHeader, "Deprecation", "Sunset", "Link"}
 	config.AllowCredentials = true
 
 	return cors.New(config)
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecation describes routes that are kept as aliases of a newer version
+type Deprecation struct {
+	// Since is when the routes were deprecated
+	Since time.Time
+	// Sunset is when the routes will be removed
+	Sunset time.Time
+	// Successor is the path prefix of the replacement routes, e.g. "/api/v1"
+	Successor string
+}
+
+// Deprecated marks responses with the Deprecation (RFC 9745) and Sunset
+// (RFC 8594) headers and links to the same path under the successor
+func Deprecated(d Deprecation) gin.HandlerFunc {
+	deprecation := fmt.Sprintf("@%d", d.Since.Unix())
+	sunset := d.Sunset.UTC().Format(http.TimeFormat)
+	successor := strings.TrimRight(d.Successor, "/")
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		header.Set("Deprecation", deprecation)
+		header.Set("Sunset", sunset)
+		header.Set("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, successor, c.Request.URL.Path))
+		c.Next()
+	}
+}
@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/logging"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader carries the ID that ties a request to its log lines
+const RequestIDHeader = "X-Request-ID"
+
+// redactedPathKey holds the request path with secret parameters hidden
+const redactedPathKey = "redacted_path"
+
+// validRequestID limits IDs taken from clients to something safe to log
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._\-]{1,128}$`)
+
+// RequestID takes the request ID from the X-Request-ID header, or generates
+// one, echoes it in the response and adds it, and the trace ID when there is
+// one, to the request's logger
+func RequestID(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = uuid.NewString()
+		}
+		c.Set("request_id", id)
+		c.Header(RequestIDHeader, id)
+
+		requestLogger := logger.With("request_id", id)
+		if span := trace.SpanContextFromContext(c.Request.Context()); span.HasTraceID() {
+			requestLogger = requestLogger.With("trace_id", span.TraceID().String())
+		}
+		ctx := logging.WithLogger(c.Request.Context(), requestLogger)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// RedactPathParams hides the values of the named path parameters, such as
+// the token in a calendar feed URL, from the access log and the request's
+// span, which record "[redacted]" in their place. Handlers still read them
+// with c.Param. Use it after tracing starts.
+func RedactPathParams(names ...string) gin.HandlerFunc {
+	secret := make(map[string]bool, len(names))
+	for _, name := range names {
+		secret[name] = true
+	}
+	return func(c *gin.Context) {
+		if path, ok := redactPath(c, secret); ok {
+			c.Set(redactedPathKey, path)
+			// Replaces the path otelgin recorded when it started the span
+			trace.SpanFromContext(c.Request.Context()).SetAttributes(attribute.String("url.path", path))
+		}
+		c.Next()
+	}
+}
+
+// redactPath rebuilds the request path from its route with the secret
+// parameters hidden. It reports false when the route has none.
+func redactPath(c *gin.Context, secret map[string]bool) (string, bool) {
+	segments := strings.Split(c.FullPath(), "/")
+	redacted := false
+	for i, segment := range segments {
+		if segment == "" || segment[0] != ':' && segment[0] != '*' {
+			continue
+		}
+		name := segment[1:]
+		if secret[name] {
+			segments[i] = logging.Redacted
+			redacted = true
+		} else {
+			segments[i] = strings.TrimPrefix(c.Param(name), "/")
+		}
+	}
+	return strings.Join(segments, "/"), redacted
+}
+
+// requestPath is the path to record for the request
+func requestPath(c *gin.Context) string {
+	if path := c.GetString(redactedPathKey); path != "" {
+		return path
+	}
+	return c.Request.URL.Path
+}
+
+// AccessLog logs one line per request. Only the route and path are logged,
+// never the query string, which can hold tokens, nor path parameters hidden
+// by RedactPathParams.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		// c.Request carries the user ID once authentication has run
+		logging.FromContext(c.Request.Context()).Log(c.Request.Context(), level, "request",
+			"method", c.Request.Method,
+			"route", c.FullPath(),
+			"path", requestPath(c),
+			"status", status,
+			"duration", time.Since(start),
+			"bytes", c.Writer.Size(),
+			"client_ip", c.ClientIP(),
+		)
+	}
+}
+
+// Recovery turns panics into 500 responses and logs them with the request's
+// logger instead of dumping the raw request
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err any) {
+		logging.FromContext(c.Request.Context()).Error("panic while handling request",
+			"error", err, "stack", string(debug.Stack()))
+		apierror.Write(c, apierror.Internal("Internal server error", nil))
+	})
+}
@@ -0,0 +1,145 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muneerlalji/Luma/logging"
+	"github.com/muneerlalji/Luma/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newLoggedRouter(out *bytes.Buffer) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.ContextWithFallback = true
+	router.Use(
+		middleware.RequestID(logging.New(out, logging.FormatJSON, slog.LevelInfo)),
+		middleware.AccessLog(),
+		middleware.Recovery(),
+	)
+	router.POST("/auth/confirm", func(c *gin.Context) {
+		c.Request = c.Request.WithContext(logging.With(c.Request.Context(), "user_id", "user-1"))
+		logging.FromContext(c).Info("confirming")
+		c.Status(http.StatusNoContent)
+	})
+	router.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+	return router
+}
+
+func logLines(t *testing.T, out *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	decoder := json.NewDecoder(out)
+	for decoder.More() {
+		var line map[string]any
+		require.NoError(t, decoder.Decode(&line))
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestRequestID_PropagatesClientID(t *testing.T) {
+	var out bytes.Buffer
+	router := newLoggedRouter(&out)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/auth/confirm?token=abc123", nil)
+	req.Header.Set(middleware.RequestIDHeader, "client-req-42")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, "client-req-42", w.Header().Get(middleware.RequestIDHeader))
+	assert.NotContains(t, out.String(), "abc123")
+
+	lines := logLines(t, &out)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "confirming", lines[0]["msg"])
+	for _, line := range lines {
+		assert.Equal(t, "client-req-42", line["request_id"])
+		assert.Equal(t, "user-1", line["user_id"])
+	}
+
+	access := lines[1]
+	assert.Equal(t, "request", access["msg"])
+	assert.Equal(t, "/auth/confirm", access["path"])
+	assert.Equal(t, float64(http.StatusNoContent), access["status"])
+}
+
+func TestRequestID_GeneratesInvalidOrMissingID(t *testing.T) {
+	var out bytes.Buffer
+	router := newLoggedRouter(&out)
+
+	for _, header := range []string{"", "bad id\nwith newline"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/auth/confirm", nil)
+		if header != "" {
+			req.Header.Set(middleware.RequestIDHeader, header)
+		}
+		router.ServeHTTP(w, req)
+
+		id := w.Header().Get(middleware.RequestIDHeader)
+		assert.Len(t, id, 36, "expected a generated UUID")
+		assert.NotEqual(t, header, id)
+	}
+}
+
+func TestRedactPathParams(t *testing.T) {
+	var out bytes.Buffer
+	exporter := tracetest.NewInMemoryExporter()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(
+		otelgin.Middleware("luma", otelgin.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))),
+		middleware.RequestID(logging.New(&out, logging.FormatJSON, slog.LevelInfo)),
+		middleware.RedactPathParams("file"),
+		middleware.AccessLog(),
+	)
+	var file string
+	router.GET("/api/v1/calendar/feed/:file", func(c *gin.Context) {
+		file = c.Param("file")
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/calendar/feed/s3cr3t-feed-token.ics", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, "s3cr3t-feed-token.ics", file, "handlers still see the parameter")
+	assert.NotContains(t, out.String(), "s3cr3t")
+	lines := logLines(t, &out)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "/api/v1/calendar/feed/"+logging.Redacted, lines[0]["path"])
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	for _, attr := range spans[0].Attributes {
+		assert.NotContains(t, attr.Value.Emit(), "s3cr3t", string(attr.Key))
+	}
+	assert.NotContains(t, spans[0].Name, "s3cr3t")
+}
+
+func TestRecovery_LogsPanic(t *testing.T) {
+	var out bytes.Buffer
+	router := newLoggedRouter(&out)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/panic", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	lines := logLines(t, &out)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "boom", lines[0]["error"])
+	assert.Equal(t, "ERROR", lines[1]["level"])
+}
@@ -0,0 +1,278 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/logging"
+	"github.com/muneerlalji/Luma/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Limit describes a token bucket that holds up to Requests tokens and
+// refills completely over Window
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// Enabled reports whether the limit should be enforced
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Window > 0
+}
+
+// refillRate returns the number of tokens added per second
+func (l Limit) refillRate() float64 {
+	return float64(l.Requests) / l.Window.Seconds()
+}
+
+// ParseLimit parses limits written as "<requests>/<window>", e.g. "5/15m"
+func ParseLimit(value string) (Limit, error) {
+	parts := strings.SplitN(strings.TrimSpace(value), "/", 2)
+	if len(parts) != 2 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: expected <requests>/<window>", value)
+	}
+
+	requests, err := strconv.Atoi(parts[0])
+	if err != nil || requests < 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: bad request count", value)
+	}
+
+	window, err := time.ParseDuration(parts[1])
+	if err != nil || window <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: bad window", value)
+	}
+
+	return Limit{Requests: requests, Window: window}, nil
+}
+
+// RateLimitStore keeps token bucket state for rate limit keys
+type RateLimitStore interface {
+	// Take consumes one token from the bucket identified by key. When the
+	// bucket is empty it returns false and how long until a token is available.
+	Take(ctx context.Context, key string, limit Limit) (bool, time.Duration, error)
+}
+
+// takeToken applies the token bucket algorithm to a bucket last updated at
+// updatedAt and returns the remaining tokens, whether a token was taken, and
+// the wait until the next token when none was available
+func takeToken(tokens float64, updatedAt, now time.Time, limit Limit) (float64, bool, time.Duration) {
+	capacity := float64(limit.Requests)
+	elapsed := now.Sub(updatedAt).Seconds()
+	if elapsed > 0 {
+		tokens = math.Min(capacity, tokens+elapsed*limit.refillRate())
+	}
+
+	if tokens >= 1 {
+		return tokens - 1, true, 0
+	}
+
+	wait := time.Duration((1 - tokens) / limit.refillRate() * float64(time.Second))
+	return tokens, false, wait
+}
+
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	window    time.Duration
+}
+
+// MemoryRateLimitStore keeps buckets in process memory. It is only suitable
+// for single instance deployments.
+type MemoryRateLimitStore struct {
+	mutex     sync.Mutex
+	buckets   map[string]*memoryBucket
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+// NewMemoryRateLimitStore creates an empty in-memory store
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets: make(map[string]*memoryBucket),
+		now:     time.Now,
+	}
+}
+
+// SetClock overrides the store's time source (useful for testing)
+func (s *MemoryRateLimitStore) SetClock(now func() time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.now = now
+}
+
+// Take implements RateLimitStore
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	bucket, exists := s.buckets[key]
+	if !exists {
+		bucket = &memoryBucket{tokens: float64(limit.Requests), updatedAt: now}
+		s.buckets[key] = bucket
+	}
+	bucket.window = limit.Window
+
+	tokens, allowed, wait := takeToken(bucket.tokens, bucket.updatedAt, now, limit)
+	bucket.tokens = tokens
+	bucket.updatedAt = now
+
+	return allowed, wait, nil
+}
+
+// sweep drops buckets that have had time to refill completely, since they
+// are indistinguishable from new ones
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.updatedAt) > bucket.window {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// PostgresRateLimitStore keeps buckets in the rate_limit_buckets table so
+// limits are shared between backend instances
+type PostgresRateLimitStore struct {
+	db *gorm.DB
+}
+
+// NewPostgresRateLimitStore creates a store backed by the given database
+func NewPostgresRateLimitStore(db *gorm.DB) *PostgresRateLimitStore {
+	return &PostgresRateLimitStore{db: db}
+}
+
+// Take implements RateLimitStore
+func (s *PostgresRateLimitStore) Take(ctx context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	var allowed bool
+	var wait time.Duration
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		seed := models.RateLimitBucket{Key: key, Tokens: float64(limit.Requests), UpdatedAt: now}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&seed).Error; err != nil {
+			return err
+		}
+
+		var bucket models.RateLimitBucket
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).First(&bucket).Error; err != nil {
+			return err
+		}
+
+		bucket.Tokens, allowed, wait = takeToken(bucket.Tokens, bucket.UpdatedAt, now, limit)
+		bucket.UpdatedAt = now
+		return tx.Save(&bucket).Error
+	})
+
+	return allowed, wait, err
+}
+
+// RateLimitConfig configures the limits applied to a route or route group
+type RateLimitConfig struct {
+	// Name namespaces bucket keys so routes do not share buckets
+	Name string
+	// PerIP limits requests from a single client IP
+	PerIP Limit
+	// PerEmail limits requests naming the same "email" in their JSON body
+	PerEmail Limit
+}
+
+// maxRateLimitBodyBytes bounds how much of a request body is buffered to
+// look for an email address
+const maxRateLimitBodyBytes = 64 << 10
+
+// RateLimit rejects requests exceeding the configured limits with 429 Too
+// Many Requests and a Retry-After header. Store errors fail open so an
+// outage of the shared store does not take authentication down with it.
+func RateLimit(store RateLimitStore, config RateLimitConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keys := make(map[string]Limit)
+
+		if config.PerIP.Enabled() {
+			keys[config.Name+":ip:"+c.ClientIP()] = config.PerIP
+		}
+
+		if config.PerEmail.Enabled() {
+			if email := peekEmail(c); email != "" {
+				keys[config.Name+":email:"+email] = config.PerEmail
+			}
+		}
+
+		var retryAfter time.Duration
+		limited := false
+		for key, limit := range keys {
+			allowed, wait, err := store.Take(c.Request.Context(), key, limit)
+			if err != nil {
+				logging.FromContext(c.Request.Context()).Error("rate limit store error", "error", err)
+				continue
+			}
+			if !allowed {
+				limited = true
+				if wait > retryAfter {
+					retryAfter = wait
+				}
+			}
+		}
+
+		if limited {
+			AbortTooManyRequests(c, retryAfter, apierror.CodeRateLimited, "Too many requests. Please try again later.")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// AbortTooManyRequests responds with 429 and a Retry-After header rounded up
+// to whole seconds
+func AbortTooManyRequests(c *gin.Context, retryAfter time.Duration, code, message string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	apierror.Write(c, apierror.New(http.StatusTooManyRequests, code, message))
+}
+
+// peekEmail reads the "email" field from a JSON request body and restores the
+// body so handlers can still bind it
+func peekEmail(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxRateLimitBodyBytes))
+	if err != nil {
+		return ""
+	}
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+
+	var payload struct {
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+
+	return strings.ToLower(strings.TrimSpace(payload.Email))
+}
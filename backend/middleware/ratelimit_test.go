@@ -0,0 +1,114 @@
+package middleware_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muneerlalji/Luma/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	limit, err := middleware.ParseLimit("5/15m")
+	require.NoError(t, err)
+	assert.Equal(t, middleware.Limit{Requests: 5, Window: 15 * time.Minute}, limit)
+
+	for _, value := range []string{"", "5", "five/1m", "5/never", "5/0s", "-1/1m"} {
+		_, err := middleware.ParseLimit(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestMemoryRateLimitStore_RefillsOverWindow(t *testing.T) {
+	now := time.Now()
+	store := middleware.NewMemoryRateLimitStore()
+	store.SetClock(func() time.Time { return now })
+	limit := middleware.Limit{Requests: 2, Window: time.Minute}
+
+	for i := 0; i < 2; i++ {
+		allowed, _, err := store.Take(context.Background(), "key", limit)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+
+	allowed, wait, err := store.Take(context.Background(), "key", limit)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 30*time.Second, wait)
+
+	// Other keys have their own bucket
+	allowed, _, _ = store.Take(context.Background(), "other", limit)
+	assert.True(t, allowed)
+
+	// One token refills every 30 seconds
+	now = now.Add(30 * time.Second)
+	allowed, _, _ = store.Take(context.Background(), "key", limit)
+	assert.True(t, allowed)
+	allowed, _, _ = store.Take(context.Background(), "key", limit)
+	assert.False(t, allowed)
+}
+
+func newRateLimitedRouter(config middleware.RateLimitConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/login", middleware.RateLimit(middleware.NewMemoryRateLimitStore(), config), func(c *gin.Context) {
+		var req struct {
+			Email string `json:"email"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"email": req.Email})
+	})
+	return router
+}
+
+func postLogin(router *gin.Engine, ip, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = ip + ":1234"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit_PerIP(t *testing.T) {
+	router := newRateLimitedRouter(middleware.RateLimitConfig{
+		Name:  "login",
+		PerIP: middleware.Limit{Requests: 2, Window: time.Minute},
+	})
+
+	assert.Equal(t, http.StatusOK, postLogin(router, "10.0.0.1", `{"email":"a@example.com"}`).Code)
+	assert.Equal(t, http.StatusOK, postLogin(router, "10.0.0.1", `{"email":"b@example.com"}`).Code)
+
+	w := postLogin(router, "10.0.0.1", `{"email":"c@example.com"}`)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, postLogin(router, "10.0.0.2", `{"email":"c@example.com"}`).Code)
+}
+
+func TestRateLimit_PerEmailKeepsBodyIntact(t *testing.T) {
+	router := newRateLimitedRouter(middleware.RateLimitConfig{
+		Name:     "login",
+		PerEmail: middleware.Limit{Requests: 1, Window: time.Hour},
+	})
+
+	w := postLogin(router, "10.0.0.1", `{"email":"Victim@Example.com"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Victim@Example.com")
+
+	// Spreading attempts across IPs does not reset the per-email bucket
+	w = postLogin(router, "10.0.0.2", `{"email":"victim@example.com "}`)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "3600", w.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, postLogin(router, "10.0.0.2", `{"email":"other@example.com"}`).Code)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/muneerlalji/Luma/db"
+)
+
+const migrateUsage = `Usage: luma migrate <command>
+
+Commands:
+  up                apply all pending migrations
+  down [n]          revert the last n migrations (default 1)
+  status            list migrations and whether they are applied
+  create <name>     add empty up/down files to the migrations directory
+`
+
+// runMigrate implements the migrate subcommand
+func runMigrate(args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dir := flags.String("dir", "db/migrations", "migrations directory used by create")
+	flags.Usage = func() { fmt.Fprint(os.Stderr, migrateUsage) }
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	command, rest := flags.Arg(0), flags.Args()[1:]
+
+	if command == "create" {
+		if len(rest) != 1 {
+			log.Fatal("migrate create needs a name, e.g. migrate create add_reminders")
+		}
+		up, down, err := db.CreateMigration(*dir, rest[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Created %s\nCreated %s\n", up, down)
+		return
+	}
+
+	cfg := loadConfig("POSTGRES_DSN")
+	conn, err := db.Open(cfg.DatabaseDSN)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	migrator, err := db.NewMigrator(conn)
+	if err != nil {
+		log.Fatal("Failed to load migrations:", err)
+	}
+	ctx := context.Background()
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("Applied %d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("Schema is up to date")
+		}
+	case "down":
+		steps := 1
+		if len(rest) > 0 {
+			steps, err = strconv.Atoi(rest[0])
+			if err != nil || steps < 1 {
+				log.Fatal("migrate down needs a positive number of steps")
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, migration := range reverted {
+			fmt.Printf("Reverted %d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-40s %s\n", status.Version, status.Name, applied)
+		}
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CareCircleMember is a relative or friend who gets the weekly digest of how
+// the user is doing. Members have no account; each unsubscribes with the
+// signed link in their own digest.
+type CareCircleMember struct {
+	ID     uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID uuid.UUID `gorm:"type:uuid;not null"`
+	User   User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Name   string    `gorm:"not null;default:''"`
+	// Email is unique within a user's care circle, ignoring case
+	Email string `gorm:"not null"`
+	// WeeklyDigestOptOut is set when the member unsubscribes
+	WeeklyDigestOptOut bool      `gorm:"not null;default:false"`
+	CreatedAt          time.Time `gorm:"autoCreateTime"`
+}
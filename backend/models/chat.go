@@ -8,12 +8,15 @@ import (
 )
 
 type ChatMessage struct {
-	ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	UserID    uuid.UUID `gorm:"type:uuid;not null" json:"user_id"`
-	User      User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
-	Role      string    `gorm:"not null" json:"role"`
-	Content   string    `gorm:"type:text;not null" json:"content"`
-	CreatedAt time.Time `gorm:"autoCreateTime"`
+	ID      uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID  uuid.UUID `gorm:"type:uuid;not null" json:"user_id"`
+	User    User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	Role    string    `gorm:"not null" json:"role"`
+	Content string    `gorm:"type:text;not null" json:"content"`
+	// Confused flags a user message that suggests they were disoriented,
+	// for their caregiver's weekly digest
+	Confused  bool      `gorm:"not null;default:false" json:"-"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"createdAt"`
 }
 
 // MarshalJSON customizes the JSON serialization to format dates properly
@@ -31,6 +34,9 @@ func (cm ChatMessage) MarshalJSON() ([]byte, error) {
 // ChatRequest represents the chat request payload
 type ChatRequest struct {
 	Message string `json:"message" binding:"required"`
+	// PromptID is the memory prompt the user is answering, whose question
+	// opens the conversation
+	PromptID *uuid.UUID `json:"promptId,omitempty"`
 }
 
 // ChatResponse represents the chat response payload
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Outbox email statuses
+const (
+	// EmailPending emails are waiting to be sent or retried
+	EmailPending = "pending"
+	EmailSent    = "sent"
+	// EmailFailed emails ran out of attempts
+	EmailFailed = "failed"
+)
+
+// OutboxEmail is a rendered email waiting in the outbox, or the record of
+// one sent or given up on. Bodies are cleared once sent, as they may carry
+// one-time links.
+type OutboxEmail struct {
+	ID     uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID uuid.UUID `gorm:"type:uuid;not null"`
+	User   User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	// Template and Locale are what the email was rendered from
+	Template  string `gorm:"not null"`
+	Locale    string `gorm:"not null"`
+	Recipient string `gorm:"not null"`
+	Subject   string `gorm:"not null"`
+	TextBody  string `gorm:"type:text;not null"`
+	HTMLBody  string `gorm:"type:text;not null"`
+	Status    string `gorm:"not null"`
+	Attempts  int    `gorm:"not null;default:0"`
+	// NextAttemptAt is when a pending email is next tried
+	NextAttemptAt time.Time `gorm:"not null"`
+	LastError     string    `gorm:"type:text;not null;default:''"`
+	SentAt        *time.Time
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+	UpdatedAt     time.Time `gorm:"autoUpdateTime"`
+}
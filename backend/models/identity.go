@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserIdentity links a user to an account at an external OpenID Connect provider
+type UserIdentity struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	User      User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Provider  string    `gorm:"not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Subject   string    `gorm:"not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Email     string
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// UserIdentityResponse represents a linked identity sent to the client
+type UserIdentityResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Provider  string    `json:"provider"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// OIDCLoginState tracks an in-flight OpenID Connect sign-in between sending
+// the user to the provider and handling the callback
+type OIDCLoginState struct {
+	ID           uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	StateHash    string    `gorm:"size:64;uniqueIndex;not null"`
+	Provider     string    `gorm:"not null"`
+	CodeVerifier string    `gorm:"not null"`
+	Nonce        string    `gorm:"not null"`
+	// LinkUserID is set when a signed-in user is linking a new identity
+	LinkUserID *uuid.UUID `gorm:"type:uuid"`
+	ExpiresAt  time.Time  `gorm:"not null;index"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Interaction types
+const (
+	InteractionCall    = "call"
+	InteractionVisit   = "visit"
+	InteractionMessage = "message"
+)
+
+// Interaction records that the user was in touch with one of their people
+type Interaction struct {
+	ID         uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID     uuid.UUID `gorm:"type:uuid;not null"`
+	User       User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	PersonID   uuid.UUID `gorm:"type:uuid;not null"`
+	Person     Person    `gorm:"foreignKey:PersonID;constraint:OnDelete:CASCADE"`
+	Type       string    `gorm:"not null"`
+	OccurredAt time.Time `gorm:"not null"`
+	Notes      string    `gorm:"type:text;not null"`
+	// MemoryID optionally links a memory made during the interaction
+	MemoryID  *uuid.UUID `gorm:"type:uuid"`
+	Memory    *Memory    `gorm:"foreignKey:MemoryID;constraint:OnDelete:SET NULL"`
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Job statuses
+const (
+	// JobPending jobs are waiting to run or to be retried
+	JobPending = "pending"
+	// JobRunning jobs are leased by a worker until LockedUntil
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	// JobDead jobs ran out of attempts and wait for an administrator
+	JobDead = "dead"
+)
+
+// Job is a unit of background work run by package jobs
+type Job struct {
+	ID   uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	Kind string    `gorm:"not null"`
+	// Payload is the JSON the job's handler decodes
+	Payload     json.RawMessage `gorm:"type:jsonb;not null"`
+	Status      string          `gorm:"not null"`
+	Attempts    int             `gorm:"not null;default:0"`
+	MaxAttempts int             `gorm:"not null"`
+	// RunAt is when a pending job is next due
+	RunAt time.Time `gorm:"not null"`
+	// Key, when set, is unique across all jobs so the same work is only
+	// queued once, such as one run of a periodic job
+	Key *string
+	// LockToken identifies the worker's claim while the job is running
+	LockToken   string `gorm:"not null;default:''"`
+	LockedUntil *time.Time
+	LastError   string `gorm:"type:text;not null;default:''"`
+	FinishedAt  *time.Time
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime"`
+}
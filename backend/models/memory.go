@@ -7,12 +7,24 @@ import (
 )
 
 type Memory struct {
-	ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
-	UserID    uuid.UUID `gorm:"type:uuid;not null"`
-	User      User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
-	Title     string    `gorm:"not null"`
-	Type      string    `gorm:"not null"`
-	Content   string    `gorm:"type:text;not null"`
-	People    []Person  `gorm:"many2many:memory_people;"`
-	CreatedAt time.Time `gorm:"autoCreateTime"`
+	ID      uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID  uuid.UUID `gorm:"type:uuid;not null"`
+	User    User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Title   string    `gorm:"not null"`
+	Type    string    `gorm:"not null"`
+	Content string    `gorm:"type:text;not null"`
+	People  []Person  `gorm:"many2many:memory_people;"`
+	// OccurredOn is the calendar date the memory is from, if known
+	OccurredOn *time.Time `gorm:"type:date"`
+	// CalendarUID is the UID of the calendar event the memory was imported
+	// from
+	CalendarUID string `gorm:"not null;default:''"`
+	// Favorite memories come back more often in reminiscence prompts
+	Favorite bool `gorm:"not null;default:false"`
+	// ResurfaceInterval is how many days the memory was last put away for
+	// after a prompt; each prompt doubles it
+	ResurfaceInterval int `gorm:"not null;default:0"`
+	// NextResurfaceOn is the first day the memory may be prompted again
+	NextResurfaceOn *time.Time `gorm:"type:date"`
+	CreatedAt       time.Time  `gorm:"autoCreateTime"`
 }
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Reasons a memory is resurfaced
+const (
+	// PromptOnThisDay is a memory from this day in an earlier year
+	PromptOnThisDay = "on_this_day"
+	// PromptPerson is a memory with someone the user has not talked about
+	// lately
+	PromptPerson = "person"
+	// PromptFavorite is a memory the user marked as a favourite
+	PromptFavorite = "favorite"
+	// PromptRevisit is any other memory due to come back
+	PromptRevisit = "revisit"
+)
+
+// Prompt responses
+const (
+	PromptPending = "pending"
+	PromptEngaged = "engaged"
+	PromptSkipped = "skipped"
+)
+
+// MemoryPrompt is the memory the user is invited to reminisce about on a day
+type MemoryPrompt struct {
+	ID       uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID   uuid.UUID `gorm:"type:uuid;not null"`
+	User     User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	MemoryID uuid.UUID `gorm:"type:uuid;not null"`
+	Memory   Memory    `gorm:"foreignKey:MemoryID;constraint:OnDelete:CASCADE"`
+	// Day is the date in the user's timezone the prompt is for
+	Day    time.Time `gorm:"type:date;not null"`
+	Reason string    `gorm:"not null"`
+	// PersonID is the person a PromptPerson prompt is about
+	PersonID *uuid.UUID `gorm:"type:uuid"`
+	// Question is what the assistant opens the conversation with
+	Question    string `gorm:"type:text;not null"`
+	Response    string `gorm:"not null"`
+	RespondedAt *time.Time
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Notification types
+const (
+	// NotificationReminder is a reach-out reminder coming due
+	NotificationReminder = "reminder"
+	// NotificationRoutineDue is an occurrence of a routine coming due
+	NotificationRoutineDue = "routine_due"
+	// NotificationCheckInMissed is an occurrence nobody checked in for
+	NotificationCheckInMissed = "check_in_missed"
+	// NotificationReminiscence is the day's memory to reminisce about
+	NotificationReminiscence = "reminiscence"
+)
+
+// Notification channels
+const (
+	ChannelInApp = "in_app"
+	ChannelPush  = "push"
+	ChannelEmail = "email"
+)
+
+// Notification tells the user about something that needs their attention
+type Notification struct {
+	ID     uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID uuid.UUID `gorm:"type:uuid;not null"`
+	User   User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Type   string    `gorm:"not null"`
+	Title  string    `gorm:"not null"`
+	Body   string    `gorm:"type:text;not null"`
+	// URL is the frontend path the notification opens
+	URL string `gorm:"not null"`
+	// Data holds the IDs of what the notification is about, such as
+	// personId
+	Data map[string]string `gorm:"serializer:json;type:jsonb;not null"`
+	// InApp is false for notifications the user only wants pushed, which are
+	// not listed
+	InApp bool `gorm:"not null"`
+	// PushPending is set until the notification has been pushed to the
+	// user's devices
+	PushPending bool `gorm:"not null"`
+	ReadAt      *time.Time
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+}
+
+// PushSubscription is a browser the user allowed to receive Web Push
+// messages
+type PushSubscription struct {
+	ID     uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID uuid.UUID `gorm:"type:uuid;not null"`
+	User   User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	// Endpoint is the push service URL, unique to the browser
+	Endpoint string `gorm:"type:text;not null;uniqueIndex"`
+	// P256dh and Auth are the browser's keys in base64url
+	P256dh    string    `gorm:"not null"`
+	Auth      string    `gorm:"not null"`
+	UserAgent string    `gorm:"not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+// NotificationPreference turns one channel on or off for one type of
+// notification. Channels without a preference are on.
+type NotificationPreference struct {
+	UserID    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	User      User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Type      string    `gorm:"primaryKey"`
+	Channel   string    `gorm:"primaryKey"`
+	Enabled   bool      `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
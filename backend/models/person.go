@@ -18,6 +18,20 @@ type Person struct {
 	Notes        string     `gorm:"not null"`
 	PhotoID      *uuid.UUID `gorm:"type:uuid"`
 	Photo        *Photo     `gorm:"foreignKey:PhotoID"`
-	CreatedAt    time.Time  `gorm:"autoCreateTime"`
-	UpdatedAt    time.Time  `gorm:"autoUpdateTime"`
+	// ContactEveryDays is how often the user wants to be reminded to reach
+	// out; zero turns reminders off
+	ContactEveryDays int `gorm:"not null;default:0"`
+	// NextReminderAt is when the scheduler opens the next reminder
+	NextReminderAt  *time.Time
+	LastContactedAt *time.Time
+	// Calendar dates, stored at midnight UTC. Anniversary is the user's
+	// anniversary with the person, such as a wedding.
+	Birthday    *time.Time `gorm:"type:date"`
+	Anniversary *time.Time `gorm:"type:date"`
+	DiedOn      *time.Time `gorm:"type:date"`
+	// BirthdayUID is the UID of the calendar event the birthday was
+	// imported from
+	BirthdayUID string    `gorm:"not null;default:''"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime"`
 }
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// RateLimitBucket stores token bucket state for the Postgres rate limit store
+type RateLimitBucket struct {
+	Key       string    `gorm:"primaryKey;size:255"`
+	Tokens    float64   `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"not null"`
+}
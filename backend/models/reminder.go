@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Reminder statuses. A person has at most one open reminder at a time.
+const (
+	ReminderOpen      = "open"
+	ReminderCompleted = "completed"
+	ReminderDismissed = "dismissed"
+)
+
+// Reminder prompts the user to reach out to one of their people
+type Reminder struct {
+	ID       uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID   uuid.UUID `gorm:"type:uuid;not null"`
+	User     User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	PersonID uuid.UUID `gorm:"type:uuid;not null"`
+	Person   Person    `gorm:"foreignKey:PersonID;constraint:OnDelete:CASCADE"`
+	DueAt    time.Time `gorm:"not null"`
+	Status   string    `gorm:"not null"`
+	// SnoozedUntil hides an open reminder until then
+	SnoozedUntil *time.Time
+	// EmailedAt is set once the reminder has been emailed; snoozing clears it
+	EmailedAt  *time.Time
+	ResolvedAt *time.Time
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Routine kinds
+const (
+	RoutineMedication = "medication"
+	RoutineActivity   = "activity"
+)
+
+// Check-in statuses. The scheduler records missed check-ins for occurrences
+// nobody confirmed in time; the user can still confirm them later.
+const (
+	CheckInDone    = "done"
+	CheckInSkipped = "skipped"
+	CheckInMissed  = "missed"
+)
+
+// Routine is something the user does on a schedule, such as taking tablets
+type Routine struct {
+	ID           uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID       uuid.UUID `gorm:"type:uuid;not null"`
+	User         User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Kind         string    `gorm:"not null"`
+	Title        string    `gorm:"not null"`
+	Instructions string    `gorm:"type:text;not null"`
+	// Recurrence is an RRULE such as FREQ=DAILY (see package recurrence)
+	Recurrence string `gorm:"not null"`
+	// Times are the times of day, such as 09:00, in the user's timezone
+	Times    []string  `gorm:"serializer:json;type:jsonb;not null"`
+	StartsOn time.Time `gorm:"type:date;not null"`
+	// GraceMinutes is how late a check-in may be before it counts as missed
+	// and the caregiver is emailed
+	GraceMinutes int `gorm:"not null"`
+	// NextDueAt is the earliest occurrence the scheduler has not settled yet
+	NextDueAt *time.Time
+	// NotifyAt is the earliest occurrence the user has not been notified of
+	NotifyAt  *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+// CheckIn records whether the user did a routine at one of its occurrences
+type CheckIn struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null"`
+	User      User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	RoutineID uuid.UUID `gorm:"type:uuid;not null"`
+	Routine   Routine   `gorm:"foreignKey:RoutineID;constraint:OnDelete:CASCADE"`
+	// ScheduledAt is the occurrence checked in for
+	ScheduledAt time.Time `gorm:"not null"`
+	Status      string    `gorm:"not null"`
+	Note        string    `gorm:"type:text;not null"`
+	// CheckedInAt is when the user confirmed; missed check-ins have none
+	CheckedInAt *time.Time
+	// EscalatedAt is set once the caregiver has been emailed about a missed
+	// check-in
+	EscalatedAt *time.Time
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+}
@@ -7,24 +7,71 @@ import (
 )
 
 type User struct {
-	ID                uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
-	Email             string    `gorm:"uniqueIndex;not null"`
-	Password          string    `gorm:"not null"`
-	DisplayName       string
-	CreatedAt         time.Time `gorm:"autoCreateTime"`
-	UpdatedAt         time.Time `gorm:"autoUpdateTime"`
-	EmailConfirmed    bool      `gorm:"default:false"`
-	ConfirmationToken string    `gorm:"size:64"`
-	ResetToken        string    `gorm:"size:64"`
-	ResetTokenExpiry  *time.Time
+	ID             uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	Email          string    `gorm:"uniqueIndex;not null"`
+	Password       string    `gorm:"not null"`
+	DisplayName    string
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime"`
+	EmailConfirmed bool      `gorm:"default:false"`
+
+	// One-time tokens are stored as SHA-256 digests (see utils.HashToken)
+	ConfirmationToken       string `gorm:"size:64;index"`
+	ConfirmationTokenExpiry *time.Time
+	ResetToken              string `gorm:"size:64;index"`
+	ResetTokenExpiry        *time.Time
+
+	// Email change state. The old address keeps a cancel link that can also
+	// revert the change until EmailChangeCancelExpiry.
+	PendingEmail            string
+	EmailChangeToken        string `gorm:"size:64;index"`
+	EmailChangeExpiry       *time.Time
+	EmailChangeCancelToken  string `gorm:"size:64;index"`
+	EmailChangeCancelExpiry *time.Time
+	PreviousEmail           string
+
+	// Login lockout state
+	FailedLoginAttempts int `gorm:"not null;default:0"`
+	LockoutCount        int `gorm:"not null;default:0"`
+	LockedUntil         *time.Time
+	UnlockToken         string `gorm:"size:64;index"`
+
+	// CaregiverEmail receives a daily digest of upcoming dates and a weekly
+	// one of how the user is doing
+	CaregiverEmail string `gorm:"not null;default:''"`
+	LastDigestAt   *time.Time
+	// WeeklyDigestOptOut is set when the caregiver unsubscribes from the
+	// weekly digest, and cleared when the caregiver changes
+	WeeklyDigestOptOut bool `gorm:"not null;default:false"`
+	LastWeeklyDigestAt *time.Time
+
+	// Timezone is the IANA name routine times are in; empty means UTC
+	Timezone string `gorm:"not null;default:'UTC'"`
+	// Locale is the language emails are written in, such as "es"
+	Locale string `gorm:"not null;default:'en'"`
+
+	// CalendarToken is the digest of the secret in the calendar feed URL
+	CalendarToken string `gorm:"size:64;index"`
+
+	// NextPromptAt is when the user's next daily reminiscence prompt is due
+	NextPromptAt *time.Time
 }
 
 // UserResponse represents the user data sent to the client (without password)
 type UserResponse struct {
-	ID          uuid.UUID `json:"id"`
-	Email       string    `json:"email"`
-	DisplayName string    `json:"displayName"`
-	CreatedAt   time.Time `json:"createdAt"`
+	ID           uuid.UUID `json:"id"`
+	Email        string    `json:"email"`
+	DisplayName  string    `json:"displayName"`
+	PendingEmail string    `json:"pendingEmail,omitempty"`
+	// CaregiverEmail receives the daily digest of upcoming dates
+	CaregiverEmail string `json:"caregiverEmail,omitempty"`
+	// Timezone is the IANA name routine times are in
+	Timezone string `json:"timezone,omitempty"`
+	// Locale is the language emails are written in
+	Locale string `json:"locale,omitempty"`
+	// WeeklyDigest is whether the caregiver gets the weekly digest
+	WeeklyDigest bool      `json:"weeklyDigest,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
 }
 
 // LoginRequest represents the login request payload
@@ -33,9 +80,12 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
-// RegisterRequest represents the registration request payload
+// RegisterRequest represents the registration request payload. Passwords are
+// checked against utils.PasswordPolicy by the handler.
 type RegisterRequest struct {
 	Email       string `json:"email" binding:"required,email"`
-	Password    string `json:"password" binding:"required,min=6"`
+	Password    string `json:"password" binding:"required"`
 	DisplayName string `json:"displayName" binding:"required"`
+	// Locale defaults to the closest match to the Accept-Language header
+	Locale string `json:"locale,omitempty"`
 }
@@ -0,0 +1,199 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/muneerlalji/Luma/oidc"
+	"github.com/muneerlalji/Luma/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const redirectURL = "http://localhost:3000/oidc/callback"
+
+// signIn runs the browser half of the flow and returns the code the provider issued
+func signIn(t *testing.T, mock *testutils.OIDCMock, provider *oidc.Provider, nonce, challenge string) string {
+	authURL := provider.AuthCodeURL("state-123", nonce, challenge)
+	code, state, err := mock.Authorize(authURL)
+	require.NoError(t, err)
+	require.NotEmpty(t, code)
+	assert.Equal(t, "state-123", state)
+	return code
+}
+
+func TestDiscover(t *testing.T) {
+	mock := testutils.NewOIDCMock()
+	defer mock.Close()
+
+	provider, err := oidc.Discover(context.Background(), mock.Config("mock", redirectURL), nil)
+	require.NoError(t, err)
+
+	authURL, err := url.Parse(provider.AuthCodeURL("state", "nonce", "challenge"))
+	require.NoError(t, err)
+	assert.Equal(t, mock.Issuer()+"/authorize", authURL.Scheme+"://"+authURL.Host+authURL.Path)
+	assert.Equal(t, mock.ClientID, authURL.Query().Get("client_id"))
+	assert.Equal(t, redirectURL, authURL.Query().Get("redirect_uri"))
+	assert.Equal(t, "openid email profile", authURL.Query().Get("scope"))
+	assert.Equal(t, "S256", authURL.Query().Get("code_challenge_method"))
+}
+
+func TestDiscover_IssuerMismatch(t *testing.T) {
+	mock := testutils.NewOIDCMock()
+	defer mock.Close()
+
+	// Serve the mock's discovery document from a different origin
+	impostor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, err := http.Get(mock.Issuer() + r.URL.Path)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		io.Copy(w, resp.Body)
+	}))
+	defer impostor.Close()
+
+	config := mock.Config("mock", redirectURL)
+	config.Issuer = impostor.URL
+
+	_, err := oidc.Discover(context.Background(), config, nil)
+	assert.ErrorContains(t, err, "issuer mismatch")
+}
+
+func TestExchange(t *testing.T) {
+	mock := testutils.NewOIDCMock()
+	defer mock.Close()
+	mock.SetUser(testutils.OIDCUser{Subject: "subject-1", Email: "jane@example.com", EmailVerified: true, Name: "Jane"})
+
+	provider, err := oidc.Discover(context.Background(), mock.Config("mock", redirectURL), nil)
+	require.NoError(t, err)
+
+	verifier, challenge, err := oidc.NewPKCE()
+	require.NoError(t, err)
+	code := signIn(t, mock, provider, "nonce-1", challenge)
+
+	claims, err := provider.Exchange(context.Background(), code, verifier, "nonce-1")
+	require.NoError(t, err)
+	assert.Equal(t, "subject-1", claims.Subject)
+	assert.Equal(t, "jane@example.com", claims.Email)
+	assert.True(t, claims.EmailVerified)
+	assert.Equal(t, "Jane", claims.Name)
+
+	// Codes are single use
+	_, err = provider.Exchange(context.Background(), code, verifier, "nonce-1")
+	assert.Error(t, err)
+}
+
+func TestExchange_WrongVerifier(t *testing.T) {
+	mock := testutils.NewOIDCMock()
+	defer mock.Close()
+
+	provider, err := oidc.Discover(context.Background(), mock.Config("mock", redirectURL), nil)
+	require.NoError(t, err)
+
+	_, challenge, err := oidc.NewPKCE()
+	require.NoError(t, err)
+	otherVerifier, _, err := oidc.NewPKCE()
+	require.NoError(t, err)
+	code := signIn(t, mock, provider, "nonce", challenge)
+
+	_, err = provider.Exchange(context.Background(), code, otherVerifier, "nonce")
+	assert.Error(t, err)
+}
+
+func TestExchange_NonceMismatch(t *testing.T) {
+	mock := testutils.NewOIDCMock()
+	defer mock.Close()
+	mock.SetNonceOverride("replayed-nonce")
+
+	provider, err := oidc.Discover(context.Background(), mock.Config("mock", redirectURL), nil)
+	require.NoError(t, err)
+
+	verifier, challenge, err := oidc.NewPKCE()
+	require.NoError(t, err)
+	code := signIn(t, mock, provider, "nonce", challenge)
+
+	_, err = provider.Exchange(context.Background(), code, verifier, "nonce")
+	assert.ErrorContains(t, err, "nonce mismatch")
+}
+
+func TestVerifyIDToken_WrongAudience(t *testing.T) {
+	mock := testutils.NewOIDCMock()
+	defer mock.Close()
+
+	provider, err := oidc.Discover(context.Background(), mock.Config("mock", redirectURL), nil)
+	require.NoError(t, err)
+
+	// A token the provider issued to another client must not sign users in here
+	rawIDToken, err := mock.SignIDToken(jwt.MapClaims{
+		"iss":   mock.Issuer(),
+		"sub":   "subject",
+		"aud":   "another-client",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": "nonce",
+	})
+	require.NoError(t, err)
+
+	_, err = provider.VerifyIDToken(context.Background(), rawIDToken, "nonce")
+	assert.Error(t, err)
+}
+
+func TestVerifyIDToken_Expired(t *testing.T) {
+	mock := testutils.NewOIDCMock()
+	defer mock.Close()
+
+	provider, err := oidc.Discover(context.Background(), mock.Config("mock", redirectURL), nil)
+	require.NoError(t, err)
+
+	rawIDToken, err := mock.SignIDToken(jwt.MapClaims{
+		"iss":   mock.Issuer(),
+		"sub":   "subject",
+		"aud":   mock.ClientID,
+		"exp":   time.Now().Add(-time.Hour).Unix(),
+		"nonce": "nonce",
+	})
+	require.NoError(t, err)
+
+	_, err = provider.VerifyIDToken(context.Background(), rawIDToken, "nonce")
+	assert.Error(t, err)
+}
+
+func TestVerifyIDToken_UnknownKey(t *testing.T) {
+	mock := testutils.NewOIDCMock()
+	defer mock.Close()
+
+	provider, err := oidc.Discover(context.Background(), mock.Config("mock", redirectURL), nil)
+	require.NoError(t, err)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   mock.Issuer(),
+		"sub":   "forged",
+		"aud":   mock.ClientID,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": "nonce",
+	})
+	token.Header["kid"] = "attacker-key"
+	rawIDToken, err := token.SignedString(key)
+	require.NoError(t, err)
+
+	_, err = provider.VerifyIDToken(context.Background(), rawIDToken, "nonce")
+	assert.ErrorContains(t, err, "unknown signing key")
+}
+
+func TestRegistry_UnknownProvider(t *testing.T) {
+	registry := oidc.NewRegistry(nil)
+
+	_, err := registry.Provider(context.Background(), "missing")
+	assert.ErrorIs(t, err, oidc.ErrUnknownProvider)
+}
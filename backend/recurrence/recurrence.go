@@ -0,0 +1,201 @@
+// Package recurrence implements the subset of iCalendar RRULE recurrence
+// (RFC 5545) that routines use: daily, weekly and monthly rules with an
+// interval, weekdays, days of the month and an end date.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is how often a rule repeats
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+// Rule is a parsed RRULE. Rules match calendar days; the times of day are
+// kept separately by the caller.
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	// ByDay limits daily and weekly rules to these weekdays. Weekly rules
+	// default to the weekday of the start date.
+	ByDay []time.Weekday
+	// ByMonthDay limits monthly rules to these days, where -1 is the last
+	// day of the month. It defaults to the day of the start date.
+	ByMonthDay []int
+	// Until is the last day the rule may match
+	Until *time.Time
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// Parse reads a rule such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH". An
+// "RRULE:" prefix is allowed.
+func Parse(value string) (Rule, error) {
+	rule := Rule{Interval: 1}
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return Rule{}, errors.New("rule is empty")
+	}
+
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(value, ";") {
+		name, arg, ok := strings.Cut(part, "=")
+		if !ok || arg == "" {
+			return Rule{}, fmt.Errorf("malformed part %q", part)
+		}
+		name = strings.ToUpper(name)
+		if seen[name] {
+			return Rule{}, fmt.Errorf("%s is given twice", name)
+		}
+		seen[name] = true
+
+		switch name {
+		case "FREQ":
+			rule.Freq = Frequency(strings.ToUpper(arg))
+			if rule.Freq != Daily && rule.Freq != Weekly && rule.Freq != Monthly {
+				return Rule{}, fmt.Errorf("unsupported frequency %q", arg)
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(arg)
+			if err != nil || interval < 1 || interval > 365 {
+				return Rule{}, fmt.Errorf("invalid interval %q", arg)
+			}
+			rule.Interval = interval
+		case "BYDAY":
+			for _, day := range strings.Split(arg, ",") {
+				weekday, ok := weekdays[strings.ToUpper(day)]
+				if !ok {
+					return Rule{}, fmt.Errorf("invalid weekday %q", day)
+				}
+				if !slices.Contains(rule.ByDay, weekday) {
+					rule.ByDay = append(rule.ByDay, weekday)
+				}
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(arg, ",") {
+				monthDay, err := strconv.Atoi(day)
+				if err != nil || monthDay == 0 || monthDay < -31 || monthDay > 31 {
+					return Rule{}, fmt.Errorf("invalid day of the month %q", day)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, monthDay)
+			}
+		case "UNTIL":
+			// Only the date counts; a time of day such as T235959Z is ignored
+			date, _, _ := strings.Cut(arg, "T")
+			until, err := time.Parse("20060102", date)
+			if err != nil {
+				return Rule{}, fmt.Errorf("invalid end date %q", arg)
+			}
+			rule.Until = &until
+		case "WKST":
+			if strings.ToUpper(arg) != "MO" {
+				return Rule{}, errors.New("weeks must start on Monday")
+			}
+		default:
+			return Rule{}, fmt.Errorf("unsupported part %s", name)
+		}
+	}
+
+	switch {
+	case rule.Freq == "":
+		return Rule{}, errors.New("FREQ is required")
+	case len(rule.ByDay) > 0 && rule.Freq == Monthly:
+		return Rule{}, errors.New("BYDAY is not supported for monthly rules")
+	case len(rule.ByMonthDay) > 0 && rule.Freq != Monthly:
+		return Rule{}, errors.New("BYMONTHDAY is only supported for monthly rules")
+	}
+	return rule, nil
+}
+
+// String writes the rule in canonical form
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, weekday := range r.ByDay {
+			days[i] = strings.ToUpper(weekday.String()[:2])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, day := range r.ByMonthDay {
+			days[i] = strconv.Itoa(day)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Matches reports whether the rule, starting on start, falls on day. Only
+// the calendar dates of start and day are used.
+func (r Rule) Matches(start, day time.Time) bool {
+	start, day = civil(start), civil(day)
+	if day.Before(start) || (r.Until != nil && day.After(civil(*r.Until))) {
+		return false
+	}
+	interval := max(r.Interval, 1)
+
+	switch r.Freq {
+	case Daily:
+		if len(r.ByDay) > 0 && !slices.Contains(r.ByDay, day.Weekday()) {
+			return false
+		}
+		return daysBetween(start, day)%interval == 0
+	case Weekly:
+		byDay := r.ByDay
+		if len(byDay) == 0 {
+			byDay = []time.Weekday{start.Weekday()}
+		}
+		if !slices.Contains(byDay, day.Weekday()) {
+			return false
+		}
+		return daysBetween(monday(start), monday(day))/7%interval == 0
+	case Monthly:
+		byMonthDay := r.ByMonthDay
+		if len(byMonthDay) == 0 {
+			byMonthDay = []int{start.Day()}
+		}
+		last := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		matched := slices.ContainsFunc(byMonthDay, func(monthDay int) bool {
+			return monthDay == day.Day() || (monthDay < 0 && last+monthDay+1 == day.Day())
+		})
+		months := (day.Year()-start.Year())*12 + int(day.Month()-start.Month())
+		return matched && months%interval == 0
+	default:
+		return false
+	}
+}
+
+// civil keeps the calendar date of t, at midnight UTC
+func civil(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func monday(day time.Time) time.Time {
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+func daysBetween(from, to time.Time) int {
+	return int(to.Sub(from) / (24 * time.Hour))
+}
@@ -0,0 +1,77 @@
+package recurrence_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muneerlalji/Luma/recurrence"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func day(value string) time.Time {
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// matching lists the days from start through the following days that rule
+// falls on
+func matching(t *testing.T, rule string, start string, days int) []string {
+	parsed, err := recurrence.Parse(rule)
+	require.NoError(t, err)
+	var matched []string
+	for d := day(start); d.Before(day(start).AddDate(0, 0, days)); d = d.AddDate(0, 0, 1) {
+		if parsed.Matches(day(start), d) {
+			matched = append(matched, d.Format("2006-01-02"))
+		}
+	}
+	return matched
+}
+
+func TestParse(t *testing.T) {
+	rule, err := recurrence.Parse("RRULE:freq=weekly;INTERVAL=2;BYDAY=MO,th;UNTIL=20261231T235959Z")
+	require.NoError(t, err)
+	assert.Equal(t, recurrence.Weekly, rule.Freq)
+	assert.Equal(t, 2, rule.Interval)
+	assert.Equal(t, []time.Weekday{time.Monday, time.Thursday}, rule.ByDay)
+	assert.Equal(t, day("2026-12-31"), *rule.Until)
+	assert.Equal(t, "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;UNTIL=20261231", rule.String())
+
+	for _, invalid := range []string{
+		"", "INTERVAL=2", "FREQ=YEARLY", "FREQ=DAILY;INTERVAL=0", "FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=WEEKLY;BYDAY=XX", "FREQ=MONTHLY;BYDAY=MO", "FREQ=DAILY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=32", "FREQ=DAILY;COUNT=3", "FREQ=DAILY;UNTIL=tomorrow", "FREQ",
+	} {
+		_, err := recurrence.Parse(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestMatches_Daily(t *testing.T) {
+	assert.Equal(t, []string{"2026-03-02", "2026-03-03", "2026-03-04"}, matching(t, "FREQ=DAILY", "2026-03-02", 3))
+	assert.Equal(t, []string{"2026-03-02", "2026-03-05", "2026-03-08"}, matching(t, "FREQ=DAILY;INTERVAL=3", "2026-03-02", 9))
+	// Weekdays only
+	assert.Len(t, matching(t, "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR", "2026-03-02", 14), 10)
+	assert.Equal(t, []string{"2026-03-02", "2026-03-03"}, matching(t, "FREQ=DAILY;UNTIL=20260303", "2026-03-02", 7))
+}
+
+func TestMatches_Weekly(t *testing.T) {
+	// Defaults to the weekday of the start date, a Wednesday
+	assert.Equal(t, []string{"2026-03-04", "2026-03-11"}, matching(t, "FREQ=WEEKLY", "2026-03-04", 14))
+	// Every other week counts weeks from Monday, so the Monday before a
+	// Wednesday start is not in the first week
+	assert.Equal(t, []string{"2026-03-04", "2026-03-16", "2026-03-18"},
+		matching(t, "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", "2026-03-04", 15))
+}
+
+func TestMatches_Monthly(t *testing.T) {
+	assert.Equal(t, []string{"2026-01-15", "2026-02-15", "2026-03-15"}, matching(t, "FREQ=MONTHLY", "2026-01-15", 70))
+	// The last day of each month
+	assert.Equal(t, []string{"2026-01-31", "2026-02-28", "2026-03-31"}, matching(t, "FREQ=MONTHLY;BYMONTHDAY=-1", "2026-01-01", 90))
+	// Months without the day are skipped
+	assert.Equal(t, []string{"2026-01-30", "2026-03-30"}, matching(t, "FREQ=MONTHLY", "2026-01-30", 60))
+	assert.Equal(t, []string{"2026-01-01", "2026-03-01"}, matching(t, "FREQ=MONTHLY;INTERVAL=2;BYMONTHDAY=1", "2026-01-01", 90))
+}
@@ -0,0 +1,906 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/muneerlalji/Luma/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NewGormStore returns a store backed by the given database. The database
+// should be opened with TranslateError so duplicates map to ErrDuplicate.
+func NewGormStore(db *gorm.DB) *Store {
+	store := &Store{
+		Users:                   &gormUsers{db: db},
+		CareCircle:              &gormCareCircle{db: db},
+		Memories:                &gormMemories{db: db},
+		MemoryPrompts:           &gormMemoryPrompts{db: db},
+		People:                  &gormPeople{db: db},
+		Photos:                  &gormPhotos{db: db},
+		ChatMessages:            &gormChatMessages{db: db},
+		Identities:              &gormIdentities{db: db},
+		LoginStates:             &gormLoginStates{db: db},
+		Reminders:               &gormReminders{db: db},
+		Interactions:            &gormInteractions{db: db},
+		Routines:                &gormRoutines{db: db},
+		CheckIns:                &gormCheckIns{db: db},
+		Notifications:           &gormNotifications{db: db},
+		PushSubscriptions:       &gormPushSubscriptions{db: db},
+		NotificationPreferences: &gormNotificationPreferences{db: db},
+		OutboxEmails:            &gormOutboxEmails{db: db},
+		Jobs:                    &gormJobs{db: db},
+	}
+	store.transaction = func(ctx context.Context, fn func(tx *Store) error) error {
+		return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			txStore := NewGormStore(tx)
+			txStore.transaction = func(ctx context.Context, fn func(tx *Store) error) error {
+				return fn(txStore)
+			}
+			return fn(txStore)
+		})
+	}
+	return store
+}
+
+// translate maps GORM errors onto the package's sentinel errors
+func translate(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrDuplicate
+	default:
+		return err
+	}
+}
+
+type gormUsers struct {
+	db *gorm.DB
+}
+
+func (r *gormUsers) Create(ctx context.Context, user *models.User) error {
+	return translate(r.db.WithContext(ctx).Create(user).Error)
+}
+
+func (r *gormUsers) Get(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&user).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUsers) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUsers) GetByToken(ctx context.Context, field TokenField, digest string) (*models.User, error) {
+	if digest == "" {
+		return nil, ErrNotFound
+	}
+	var user models.User
+	if err := r.db.WithContext(ctx).Where(clause.Eq{Column: string(field), Value: digest}).First(&user).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUsers) Update(ctx context.Context, user *models.User) error {
+	return translate(r.db.WithContext(ctx).Save(user).Error)
+}
+
+func (r *gormUsers) UpdateIfToken(ctx context.Context, user *models.User, field TokenField, digest string) (bool, error) {
+	if digest == "" {
+		return false, nil
+	}
+	result := r.db.WithContext(ctx).Model(user).
+		Where(clause.Eq{Column: string(field), Value: digest}).
+		Select("*").Omit("id", "created_at").
+		Updates(user)
+	if result.Error != nil {
+		return false, translate(result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *gormUsers) SetConfirmationToken(ctx context.Context, id uuid.UUID, digest string, expiry time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND NOT email_confirmed", id).
+		Updates(map[string]interface{}{
+			"confirmation_token":        digest,
+			"confirmation_token_expiry": expiry,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *gormUsers) IncrementFailedLogins(ctx context.Context, id uuid.UUID) (int, error) {
+	var attempts []int
+	err := r.db.WithContext(ctx).Raw(`UPDATE users SET failed_login_attempts = failed_login_attempts + 1, updated_at = ?
+		WHERE id = ? RETURNING failed_login_attempts`, time.Now(), id).Scan(&attempts).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(attempts) == 0 {
+		return 0, ErrNotFound
+	}
+	return attempts[0], nil
+}
+
+func (r *gormUsers) LockOut(ctx context.Context, id uuid.UUID, threshold int, unlockToken string, until func(lockoutCount int) time.Time) (*time.Time, error) {
+	var lockedUntil *time.Time
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var counts []int
+		err := tx.Raw(`UPDATE users SET lockout_count = lockout_count + 1, failed_login_attempts = 0, unlock_token = ?, updated_at = ?
+			WHERE id = ? AND failed_login_attempts >= ? RETURNING lockout_count`, unlockToken, time.Now(), id, threshold).Scan(&counts).Error
+		if err != nil || len(counts) == 0 {
+			return err
+		}
+		end := until(counts[0])
+		lockedUntil = &end
+		return tx.Model(&models.User{}).Where("id = ?", id).Update("locked_until", end).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lockedUntil, nil
+}
+
+func (r *gormUsers) ClearFailedLogins(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"failed_login_attempts": 0,
+		"lockout_count":         0,
+		"locked_until":          nil,
+		"unlock_token":          "",
+	}).Error
+}
+
+func (r *gormUsers) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&models.User{}, "id = ?", id).Error
+}
+
+func (r *gormUsers) EmailInUse(ctx context.Context, email string, except uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.User{}).Where("email = ? AND id <> ?", email, except).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *gormUsers) DeleteUnconfirmedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("email_confirmed = ? AND created_at < ?", false, before).
+		Delete(&models.User{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *gormUsers) ClaimDigestDue(ctx context.Context, now time.Time, limit int) ([]models.User, error) {
+	var users []models.User
+	// The start of the user's day: local midnight, back in absolute time
+	const dayStart = `date_trunc('day', CAST(? AS timestamptz) AT TIME ZONE COALESCE(NULLIF(timezone, ''), 'UTC'))
+		AT TIME ZONE COALESCE(NULLIF(timezone, ''), 'UTC')`
+	err := r.db.WithContext(ctx).Clauses(skipLocked).
+		Where("caregiver_email <> '' AND (last_digest_at IS NULL OR last_digest_at < "+dayStart+")", now).
+		Order("last_digest_at NULLS FIRST").Limit(limit).Find(&users).Error
+	return users, err
+}
+
+func (r *gormUsers) MarkDigestSent(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("last_digest_at", at).Error
+}
+
+func (r *gormUsers) ClaimWeeklyDigestDue(ctx context.Context, since time.Time, limit int) ([]models.User, error) {
+	var users []models.User
+	err := r.db.WithContext(ctx).Clauses(skipLocked).
+		Where(`((caregiver_email <> '' AND NOT weekly_digest_opt_out) OR EXISTS (
+			SELECT 1 FROM care_circle_members m WHERE m.user_id = users.id AND NOT m.weekly_digest_opt_out
+		)) AND (last_weekly_digest_at IS NULL OR last_weekly_digest_at < ?)`, since).
+		Order("last_weekly_digest_at NULLS FIRST").Limit(limit).Find(&users).Error
+	return users, err
+}
+
+func (r *gormUsers) MarkWeeklyDigestSent(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("last_weekly_digest_at", at).Error
+}
+
+func (r *gormUsers) OptOutOfWeeklyDigest(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("weekly_digest_opt_out", true).Error
+}
+
+func (r *gormUsers) ClaimPromptDue(ctx context.Context, now time.Time, limit int) ([]models.User, error) {
+	var users []models.User
+	err := r.db.WithContext(ctx).Clauses(skipLocked).
+		Where("next_prompt_at IS NULL OR next_prompt_at <= ?", now).
+		Order("next_prompt_at NULLS FIRST").Limit(limit).Find(&users).Error
+	return users, err
+}
+
+func (r *gormUsers) SetNextPromptAt(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("next_prompt_at", at).Error
+}
+
+type gormCareCircle struct {
+	db *gorm.DB
+}
+
+func (r *gormCareCircle) Create(ctx context.Context, member *models.CareCircleMember) error {
+	return translate(r.db.WithContext(ctx).Omit(clause.Associations).Create(member).Error)
+}
+
+func (r *gormCareCircle) Get(ctx context.Context, id uuid.UUID) (*models.CareCircleMember, error) {
+	var member models.CareCircleMember
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&member).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &member, nil
+}
+
+func (r *gormCareCircle) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.CareCircleMember, error) {
+	var members []models.CareCircleMember
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at ASC").Find(&members).Error
+	return members, err
+}
+
+func (r *gormCareCircle) OptOut(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&models.CareCircleMember{}).Where("id = ?", id).Update("weekly_digest_opt_out", true).Error
+}
+
+func (r *gormCareCircle) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	result := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&models.CareCircleMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type gormMemories struct {
+	db *gorm.DB
+}
+
+func (r *gormMemories) Create(ctx context.Context, memory *models.Memory) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(memory).Error
+}
+
+func (r *gormMemories) AddPeople(ctx context.Context, memoryID uuid.UUID, people []models.Person) error {
+	return r.db.WithContext(ctx).Model(&models.Memory{ID: memoryID}).Association("People").Append(people)
+}
+
+func (r *gormMemories) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Memory, error) {
+	var memories []models.Memory
+	err := r.db.WithContext(ctx).Preload("People").Where("user_id = ?", userID).Order("created_at DESC").Find(&memories).Error
+	return memories, err
+}
+
+func (r *gormMemories) GetForUser(ctx context.Context, id, userID uuid.UUID) (*models.Memory, error) {
+	var memory models.Memory
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&memory).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &memory, nil
+}
+
+func (r *gormMemories) GetByCalendarUID(ctx context.Context, userID uuid.UUID, uid string) (*models.Memory, error) {
+	if uid == "" {
+		return nil, ErrNotFound
+	}
+	var memory models.Memory
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND calendar_uid = ?", userID, uid).First(&memory).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &memory, nil
+}
+
+func (r *gormMemories) Update(ctx context.Context, memory *models.Memory) error {
+	return translate(r.db.WithContext(ctx).Omit(clause.Associations).Save(memory).Error)
+}
+
+type gormMemoryPrompts struct {
+	db *gorm.DB
+}
+
+func (r *gormMemoryPrompts) Create(ctx context.Context, prompt *models.MemoryPrompt) error {
+	return translate(r.db.WithContext(ctx).Omit(clause.Associations).Create(prompt).Error)
+}
+
+func (r *gormMemoryPrompts) GetForUser(ctx context.Context, id, userID uuid.UUID) (*models.MemoryPrompt, error) {
+	var prompt models.MemoryPrompt
+	if err := r.db.WithContext(ctx).Preload("Memory").Where("id = ? AND user_id = ?", id, userID).First(&prompt).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &prompt, nil
+}
+
+func (r *gormMemoryPrompts) GetForDay(ctx context.Context, userID uuid.UUID, day time.Time) (*models.MemoryPrompt, error) {
+	var prompt models.MemoryPrompt
+	if err := r.db.WithContext(ctx).Preload("Memory").Where("user_id = ? AND day = ?", userID, day).First(&prompt).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &prompt, nil
+}
+
+func (r *gormMemoryPrompts) ListByUser(ctx context.Context, userID uuid.UUID, since time.Time) ([]models.MemoryPrompt, error) {
+	var prompts []models.MemoryPrompt
+	err := r.db.WithContext(ctx).Preload("Memory").Where("user_id = ? AND day >= ?", userID, since).
+		Order("day DESC").Find(&prompts).Error
+	return prompts, err
+}
+
+func (r *gormMemoryPrompts) Update(ctx context.Context, prompt *models.MemoryPrompt) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(prompt).Error
+}
+
+type gormPeople struct {
+	db *gorm.DB
+}
+
+func (r *gormPeople) Create(ctx context.Context, person *models.Person) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(person).Error
+}
+
+func (r *gormPeople) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Person, error) {
+	var people []models.Person
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at ASC").Find(&people).Error
+	return people, err
+}
+
+func (r *gormPeople) ListByIDs(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) ([]models.Person, error) {
+	var people []models.Person
+	err := r.db.WithContext(ctx).Where("id IN ? AND user_id = ?", ids, userID).Find(&people).Error
+	return people, err
+}
+
+func (r *gormPeople) GetForUser(ctx context.Context, id, userID uuid.UUID) (*models.Person, error) {
+	var person models.Person
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&person).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &person, nil
+}
+
+func (r *gormPeople) GetByBirthdayUID(ctx context.Context, userID uuid.UUID, uid string) (*models.Person, error) {
+	if uid == "" {
+		return nil, ErrNotFound
+	}
+	var person models.Person
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND birthday_uid = ?", userID, uid).First(&person).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &person, nil
+}
+
+func (r *gormPeople) Update(ctx context.Context, person *models.Person) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(person).Error
+}
+
+func (r *gormPeople) ClaimDueForReminder(ctx context.Context, now time.Time, limit int) ([]models.Person, error) {
+	var people []models.Person
+	err := r.db.WithContext(ctx).Clauses(skipLocked).
+		Where("contact_every_days > 0 AND next_reminder_at <= ?", now).
+		Order("next_reminder_at").Limit(limit).Find(&people).Error
+	return people, err
+}
+
+// skipLocked locks the selected rows and leaves out rows that another
+// transaction holds, so concurrent schedulers claim disjoint batches
+var skipLocked = clause.Locking{Strength: clause.LockingStrengthUpdate, Options: clause.LockingOptionsSkipLocked}
+
+type gormPhotos struct {
+	db *gorm.DB
+}
+
+func (r *gormPhotos) Create(ctx context.Context, photo *models.Photo) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(photo).Error
+}
+
+func (r *gormPhotos) GetForUser(ctx context.Context, id, userID uuid.UUID) (*models.Photo, error) {
+	var photo models.Photo
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&photo).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &photo, nil
+}
+
+func (r *gormPhotos) GetByMemory(ctx context.Context, memoryID uuid.UUID) (*models.Photo, error) {
+	var photo models.Photo
+	if err := r.db.WithContext(ctx).Where("memory_id = ?", memoryID).First(&photo).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &photo, nil
+}
+
+func (r *gormPhotos) Update(ctx context.Context, photo *models.Photo) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(photo).Error
+}
+
+func (r *gormPhotos) KeysByUser(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	var keys []string
+	err := r.db.WithContext(ctx).Model(&models.Photo{}).Where("user_id = ?", userID).Pluck("s3_key", &keys).Error
+	return keys, err
+}
+
+type gormChatMessages struct {
+	db *gorm.DB
+}
+
+func (r *gormChatMessages) Create(ctx context.Context, messages ...*models.ChatMessage) error {
+	for _, message := range messages {
+		if err := r.db.WithContext(ctx).Omit(clause.Associations).Create(message).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *gormChatMessages) ListByUser(ctx context.Context, userID uuid.UUID, limit int) ([]models.ChatMessage, error) {
+	var messages []models.ChatMessage
+	query := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at asc")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Find(&messages).Error
+	return messages, err
+}
+
+func (r *gormChatMessages) ListSince(ctx context.Context, userID uuid.UUID, since time.Time) ([]models.ChatMessage, error) {
+	var messages []models.ChatMessage
+	err := r.db.WithContext(ctx).Where("user_id = ? AND created_at >= ?", userID, since).Order("created_at asc").Find(&messages).Error
+	return messages, err
+}
+
+type gormIdentities struct {
+	db *gorm.DB
+}
+
+func (r *gormIdentities) Create(ctx context.Context, identity *models.UserIdentity) error {
+	return translate(r.db.WithContext(ctx).Omit(clause.Associations).Create(identity).Error)
+}
+
+func (r *gormIdentities) GetByProviderSubject(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := r.db.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &identity, nil
+}
+
+func (r *gormIdentities) GetForUser(ctx context.Context, id, userID uuid.UUID) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&identity).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &identity, nil
+}
+
+func (r *gormIdentities) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at ASC").Find(&identities).Error
+	return identities, err
+}
+
+func (r *gormIdentities) CountByUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.UserIdentity{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+func (r *gormIdentities) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&models.UserIdentity{}, "id = ?", id).Error
+}
+
+type gormLoginStates struct {
+	db *gorm.DB
+}
+
+func (r *gormLoginStates) Create(ctx context.Context, state *models.OIDCLoginState) error {
+	return r.db.WithContext(ctx).Create(state).Error
+}
+
+func (r *gormLoginStates) Consume(ctx context.Context, stateHash, provider string, now time.Time) (*models.OIDCLoginState, error) {
+	var state models.OIDCLoginState
+	result := r.db.WithContext(ctx).Clauses(clause.Returning{}).
+		Where("state_hash = ? AND provider = ? AND expires_at > ?", stateHash, provider, now).
+		Delete(&state)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+	return &state, nil
+}
+
+func (r *gormLoginStates) DeleteExpired(ctx context.Context, now time.Time) error {
+	return r.db.WithContext(ctx).Where("expires_at < ?", now).Delete(&models.OIDCLoginState{}).Error
+}
+
+type gormReminders struct {
+	db *gorm.DB
+}
+
+func (r *gormReminders) Create(ctx context.Context, reminder *models.Reminder) error {
+	return translate(r.db.WithContext(ctx).Omit(clause.Associations).Create(reminder).Error)
+}
+
+func (r *gormReminders) GetForUser(ctx context.Context, id, userID uuid.UUID) (*models.Reminder, error) {
+	var reminder models.Reminder
+	if err := r.db.WithContext(ctx).Preload("Person").Where("id = ? AND user_id = ?", id, userID).First(&reminder).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &reminder, nil
+}
+
+func (r *gormReminders) HasOpen(ctx context.Context, personID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Reminder{}).
+		Where("person_id = ? AND status = ?", personID, models.ReminderOpen).Count(&count).Error
+	return count > 0, err
+}
+
+// active selects open reminders that are due and not snoozed at now
+func (r *gormReminders) active(ctx context.Context, now time.Time) *gorm.DB {
+	return r.db.WithContext(ctx).
+		Where("status = ? AND due_at <= ?", models.ReminderOpen, now).
+		Where("snoozed_until IS NULL OR snoozed_until <= ?", now).
+		Order("due_at ASC")
+}
+
+func (r *gormReminders) ListActive(ctx context.Context, userID uuid.UUID, now time.Time) ([]models.Reminder, error) {
+	var reminders []models.Reminder
+	err := r.active(ctx, now).Preload("Person").Where("user_id = ?", userID).Find(&reminders).Error
+	return reminders, err
+}
+
+func (r *gormReminders) ResolveOpen(ctx context.Context, personID uuid.UUID, status string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Reminder{}).
+		Where("person_id = ? AND status = ?", personID, models.ReminderOpen).
+		Updates(map[string]any{"status": status, "resolved_at": at}).Error
+}
+
+func (r *gormReminders) ClaimUnsent(ctx context.Context, now time.Time, limit int) ([]models.Reminder, error) {
+	var reminders []models.Reminder
+	err := r.active(ctx, now).Clauses(skipLocked).Preload("Person").
+		Where("emailed_at IS NULL").Limit(limit).Find(&reminders).Error
+	return reminders, err
+}
+
+func (r *gormReminders) Update(ctx context.Context, reminder *models.Reminder) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(reminder).Error
+}
+
+type gormInteractions struct {
+	db *gorm.DB
+}
+
+func (r *gormInteractions) Create(ctx context.Context, interaction *models.Interaction) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(interaction).Error
+}
+
+func (r *gormInteractions) ListByPerson(ctx context.Context, userID, personID uuid.UUID) ([]models.Interaction, error) {
+	var interactions []models.Interaction
+	err := r.db.WithContext(ctx).Where("user_id = ? AND person_id = ?", userID, personID).
+		Order("occurred_at DESC").Find(&interactions).Error
+	return interactions, err
+}
+
+func (r *gormInteractions) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Interaction, error) {
+	var interactions []models.Interaction
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("occurred_at ASC").Find(&interactions).Error
+	return interactions, err
+}
+
+func (r *gormInteractions) LatestByPerson(ctx context.Context, userID uuid.UUID) ([]models.Interaction, error) {
+	var interactions []models.Interaction
+	err := r.db.WithContext(ctx).Select("DISTINCT ON (person_id) *").
+		Where("user_id = ?", userID).Order("person_id, occurred_at DESC").Find(&interactions).Error
+	return interactions, err
+}
+
+type gormRoutines struct {
+	db *gorm.DB
+}
+
+func (r *gormRoutines) Create(ctx context.Context, routine *models.Routine) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(routine).Error
+}
+
+func (r *gormRoutines) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Routine, error) {
+	var routines []models.Routine
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at ASC").Find(&routines).Error
+	return routines, err
+}
+
+func (r *gormRoutines) GetForUser(ctx context.Context, id, userID uuid.UUID) (*models.Routine, error) {
+	var routine models.Routine
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&routine).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &routine, nil
+}
+
+func (r *gormRoutines) Update(ctx context.Context, routine *models.Routine) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(routine).Error
+}
+
+func (r *gormRoutines) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&models.Routine{}, "id = ?", id).Error
+}
+
+func (r *gormRoutines) ClaimDue(ctx context.Context, now time.Time, limit int) ([]models.Routine, error) {
+	var routines []models.Routine
+	err := r.db.WithContext(ctx).Clauses(skipLocked).
+		Where("next_due_at + make_interval(mins => grace_minutes::int) <= ?", now).
+		Order("next_due_at").Limit(limit).Find(&routines).Error
+	return routines, err
+}
+
+func (r *gormRoutines) ClaimNotifyDue(ctx context.Context, now time.Time, limit int) ([]models.Routine, error) {
+	var routines []models.Routine
+	err := r.db.WithContext(ctx).Clauses(skipLocked).
+		Where("notify_at <= ?", now).Order("notify_at").Limit(limit).Find(&routines).Error
+	return routines, err
+}
+
+type gormCheckIns struct {
+	db *gorm.DB
+}
+
+func (r *gormCheckIns) Create(ctx context.Context, checkIn *models.CheckIn) error {
+	return translate(r.db.WithContext(ctx).Omit(clause.Associations).Create(checkIn).Error)
+}
+
+func (r *gormCheckIns) GetByOccurrence(ctx context.Context, routineID uuid.UUID, scheduledAt time.Time) (*models.CheckIn, error) {
+	var checkIn models.CheckIn
+	err := r.db.WithContext(ctx).Where("routine_id = ? AND scheduled_at = ?", routineID, scheduledAt).First(&checkIn).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &checkIn, nil
+}
+
+func (r *gormCheckIns) ListByUser(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]models.CheckIn, error) {
+	var checkIns []models.CheckIn
+	err := r.db.WithContext(ctx).Where("user_id = ? AND scheduled_at >= ? AND scheduled_at < ?", userID, from, to).
+		Order("scheduled_at ASC").Find(&checkIns).Error
+	return checkIns, err
+}
+
+func (r *gormCheckIns) Update(ctx context.Context, checkIn *models.CheckIn) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(checkIn).Error
+}
+
+func (r *gormCheckIns) ClaimUnescalated(ctx context.Context, since time.Time, limit int) ([]models.CheckIn, error) {
+	var checkIns []models.CheckIn
+	err := r.db.WithContext(ctx).Clauses(skipLocked).Preload("Routine").
+		Where("status = ? AND escalated_at IS NULL AND scheduled_at >= ?", models.CheckInMissed, since).
+		Where("user_id IN (SELECT id FROM users WHERE caregiver_email <> '')").
+		Order("scheduled_at").Limit(limit).Find(&checkIns).Error
+	return checkIns, err
+}
+
+type gormNotifications struct {
+	db *gorm.DB
+}
+
+func (r *gormNotifications) Create(ctx context.Context, notification *models.Notification) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(notification).Error
+}
+
+// inApp selects the user's listed notifications
+func (r *gormNotifications) inApp(ctx context.Context, userID uuid.UUID) *gorm.DB {
+	return r.db.WithContext(ctx).Where("user_id = ? AND in_app", userID)
+}
+
+func (r *gormNotifications) List(ctx context.Context, userID uuid.UUID, unreadOnly bool, limit int) ([]models.Notification, error) {
+	var notifications []models.Notification
+	query := r.inApp(ctx, userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	err := query.Order("created_at DESC").Limit(limit).Find(&notifications).Error
+	return notifications, err
+}
+
+func (r *gormNotifications) ListSince(ctx context.Context, userID uuid.UUID, since time.Time, limit int) ([]models.Notification, error) {
+	var notifications []models.Notification
+	err := r.inApp(ctx, userID).Where("created_at >= ?", since).
+		Order("created_at ASC").Limit(limit).Find(&notifications).Error
+	return notifications, err
+}
+
+func (r *gormNotifications) GetForUser(ctx context.Context, id, userID uuid.UUID) (*models.Notification, error) {
+	var notification models.Notification
+	if err := r.inApp(ctx, userID).Where("id = ?", id).First(&notification).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &notification, nil
+}
+
+func (r *gormNotifications) CountUnread(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.inApp(ctx, userID).Model(&models.Notification{}).Where("read_at IS NULL").Count(&count).Error
+	return count, err
+}
+
+func (r *gormNotifications) MarkRead(ctx context.Context, id, userID uuid.UUID, at time.Time) (*models.Notification, error) {
+	err := r.inApp(ctx, userID).Model(&models.Notification{}).
+		Where("id = ? AND read_at IS NULL", id).Update("read_at", at).Error
+	if err != nil {
+		return nil, err
+	}
+	return r.GetForUser(ctx, id, userID)
+}
+
+func (r *gormNotifications) MarkAllRead(ctx context.Context, userID uuid.UUID, at time.Time) (int64, error) {
+	result := r.inApp(ctx, userID).Model(&models.Notification{}).
+		Where("read_at IS NULL").Update("read_at", at)
+	return result.RowsAffected, result.Error
+}
+
+func (r *gormNotifications) ClaimPushPending(ctx context.Context, skip []uuid.UUID, limit int) ([]models.Notification, error) {
+	var notifications []models.Notification
+	query := r.db.WithContext(ctx).Clauses(skipLocked).Where("push_pending")
+	if len(skip) > 0 {
+		query = query.Where("id NOT IN ?", skip)
+	}
+	err := query.Order("created_at").Limit(limit).Find(&notifications).Error
+	return notifications, err
+}
+
+func (r *gormNotifications) Update(ctx context.Context, notification *models.Notification) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(notification).Error
+}
+
+type gormPushSubscriptions struct {
+	db *gorm.DB
+}
+
+func (r *gormPushSubscriptions) Save(ctx context.Context, subscription *models.PushSubscription) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "endpoint"}},
+		DoUpdates: clause.AssignmentColumns([]string{"user_id", "p256dh", "auth", "user_agent", "updated_at"}),
+	}).Create(subscription).Error
+}
+
+func (r *gormPushSubscriptions) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.PushSubscription, error) {
+	var subscriptions []models.PushSubscription
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at ASC").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+func (r *gormPushSubscriptions) DeleteByEndpoint(ctx context.Context, userID uuid.UUID, endpoint string) error {
+	result := r.db.WithContext(ctx).Where("user_id = ? AND endpoint = ?", userID, endpoint).
+		Delete(&models.PushSubscription{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormPushSubscriptions) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&models.PushSubscription{}, "id = ?", id).Error
+}
+
+type gormNotificationPreferences struct {
+	db *gorm.DB
+}
+
+func (r *gormNotificationPreferences) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.NotificationPreference, error) {
+	var preferences []models.NotificationPreference
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("type, channel").Find(&preferences).Error
+	return preferences, err
+}
+
+func (r *gormNotificationPreferences) Set(ctx context.Context, preference *models.NotificationPreference) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(preference).Error
+}
+
+type gormOutboxEmails struct {
+	db *gorm.DB
+}
+
+func (r *gormOutboxEmails) Create(ctx context.Context, email *models.OutboxEmail) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(email).Error
+}
+
+func (r *gormOutboxEmails) Get(ctx context.Context, id uuid.UUID) (*models.OutboxEmail, error) {
+	var email models.OutboxEmail
+	if err := r.db.WithContext(ctx).First(&email, "id = ?", id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &email, nil
+}
+
+func (r *gormOutboxEmails) ClaimDue(ctx context.Context, now time.Time, limit int) ([]models.OutboxEmail, error) {
+	var emails []models.OutboxEmail
+	err := r.db.WithContext(ctx).Clauses(skipLocked).
+		Where("status = ? AND next_attempt_at <= ?", models.EmailPending, now).
+		Order("next_attempt_at").Limit(limit).Find(&emails).Error
+	return emails, err
+}
+
+func (r *gormOutboxEmails) List(ctx context.Context, status string, limit int) ([]models.OutboxEmail, error) {
+	var emails []models.OutboxEmail
+	query := r.db.WithContext(ctx)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("created_at DESC").Limit(limit).Find(&emails).Error
+	return emails, err
+}
+
+func (r *gormOutboxEmails) Update(ctx context.Context, email *models.OutboxEmail) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(email).Error
+}
+
+type gormJobs struct {
+	db *gorm.DB
+}
+
+func (r *gormJobs) Create(ctx context.Context, job *models.Job) error {
+	return translate(r.db.WithContext(ctx).Create(job).Error)
+}
+
+func (r *gormJobs) Get(ctx context.Context, id uuid.UUID) (*models.Job, error) {
+	var job models.Job
+	if err := r.db.WithContext(ctx).First(&job, "id = ?", id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &job, nil
+}
+
+func (r *gormJobs) ClaimDue(ctx context.Context, now time.Time, kinds []string, limit int) ([]models.Job, error) {
+	var jobs []models.Job
+	err := r.db.WithContext(ctx).Clauses(skipLocked).
+		Where("kind IN ?", kinds).
+		Where("(status = ? AND run_at <= ?) OR (status = ? AND locked_until < ?)", models.JobPending, now, models.JobRunning, now).
+		Order("run_at").Limit(limit).Find(&jobs).Error
+	return jobs, err
+}
+
+func (r *gormJobs) List(ctx context.Context, status, kind string, limit int) ([]models.Job, error) {
+	var jobs []models.Job
+	query := r.db.WithContext(ctx)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	err := query.Order("created_at DESC").Limit(limit).Find(&jobs).Error
+	return jobs, err
+}
+
+func (r *gormJobs) Update(ctx context.Context, job *models.Job) error {
+	return translate(r.db.WithContext(ctx).Save(job).Error)
+}
+
+func (r *gormJobs) UpdateIfLocked(ctx context.Context, job *models.Job, token string) (bool, error) {
+	if token == "" {
+		return false, nil
+	}
+	result := r.db.WithContext(ctx).Model(job).
+		Where("lock_token = ?", token).
+		Select("*").Omit("id", "created_at").
+		Updates(job)
+	if result.Error != nil {
+		return false, translate(result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *gormJobs) DeleteSucceededBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("status = ? AND finished_at < ?", models.JobSucceeded, before).
+		Delete(&models.Job{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,2043 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/muneerlalji/Luma/models"
+)
+
+// NewMemoryStore returns a store that keeps everything in process memory.
+// It enforces the same uniqueness rules and cascades as the database schema
+// and is meant for tests and local experiments.
+func NewMemoryStore() *Store {
+	return newMemoryStore(&memoryDB{data: newMemoryData()})
+}
+
+func newMemoryStore(db *memoryDB) *Store {
+	store := &Store{
+		Users:                   &memoryUsers{db: db},
+		CareCircle:              &memoryCareCircle{db: db},
+		Memories:                &memoryMemories{db: db},
+		MemoryPrompts:           &memoryMemoryPrompts{db: db},
+		People:                  &memoryPeople{db: db},
+		Photos:                  &memoryPhotos{db: db},
+		ChatMessages:            &memoryChatMessages{db: db},
+		Identities:              &memoryIdentities{db: db},
+		LoginStates:             &memoryLoginStates{db: db},
+		Reminders:               &memoryReminders{db: db},
+		Interactions:            &memoryInteractions{db: db},
+		Routines:                &memoryRoutines{db: db},
+		CheckIns:                &memoryCheckIns{db: db},
+		Notifications:           &memoryNotifications{db: db},
+		PushSubscriptions:       &memoryPushSubscriptions{db: db},
+		NotificationPreferences: &memoryNotificationPreferences{db: db},
+		OutboxEmails:            &memoryOutboxEmails{db: db},
+		Jobs:                    &memoryJobs{db: db},
+	}
+	store.transaction = func(ctx context.Context, fn func(tx *Store) error) error {
+		db.txMu.Lock()
+		defer db.txMu.Unlock()
+
+		db.mu.Lock()
+		snapshot := db.data.clone()
+		db.mu.Unlock()
+
+		txStore := newMemoryStore(db)
+		txStore.transaction = func(ctx context.Context, fn func(tx *Store) error) error {
+			return fn(txStore)
+		}
+		if err := fn(txStore); err != nil {
+			db.mu.Lock()
+			db.data = snapshot
+			db.mu.Unlock()
+			return err
+		}
+		return nil
+	}
+	return store
+}
+
+// memoryDB is the state shared by the repositories of one memory store.
+// Transactions are serialized by txMu and rolled back by restoring a snapshot.
+type memoryDB struct {
+	txMu sync.Mutex
+	mu   sync.Mutex
+	data *memoryData
+}
+
+// memoryData holds records in insertion order, which breaks ties between
+// equal timestamps the way a serial primary key would
+type memoryData struct {
+	users                   []models.User
+	memories                []models.Memory
+	memoryPeople            map[uuid.UUID][]uuid.UUID
+	memoryPrompts           []models.MemoryPrompt
+	careCircle              []models.CareCircleMember
+	people                  []models.Person
+	photos                  []models.Photo
+	chatMessages            []models.ChatMessage
+	identities              []models.UserIdentity
+	loginStates             []models.OIDCLoginState
+	reminders               []models.Reminder
+	interactions            []models.Interaction
+	routines                []models.Routine
+	checkIns                []models.CheckIn
+	notifications           []models.Notification
+	pushSubscriptions       []models.PushSubscription
+	notificationPreferences []models.NotificationPreference
+	outboxEmails            []models.OutboxEmail
+	jobs                    []models.Job
+}
+
+func newMemoryData() *memoryData {
+	return &memoryData{memoryPeople: make(map[uuid.UUID][]uuid.UUID)}
+}
+
+func (d *memoryData) clone() *memoryData {
+	clone := &memoryData{
+		users:                   append([]models.User(nil), d.users...),
+		memories:                append([]models.Memory(nil), d.memories...),
+		memoryPeople:            make(map[uuid.UUID][]uuid.UUID, len(d.memoryPeople)),
+		memoryPrompts:           append([]models.MemoryPrompt(nil), d.memoryPrompts...),
+		careCircle:              append([]models.CareCircleMember(nil), d.careCircle...),
+		people:                  append([]models.Person(nil), d.people...),
+		photos:                  append([]models.Photo(nil), d.photos...),
+		chatMessages:            append([]models.ChatMessage(nil), d.chatMessages...),
+		identities:              append([]models.UserIdentity(nil), d.identities...),
+		loginStates:             append([]models.OIDCLoginState(nil), d.loginStates...),
+		reminders:               append([]models.Reminder(nil), d.reminders...),
+		interactions:            append([]models.Interaction(nil), d.interactions...),
+		routines:                append([]models.Routine(nil), d.routines...),
+		checkIns:                append([]models.CheckIn(nil), d.checkIns...),
+		notifications:           append([]models.Notification(nil), d.notifications...),
+		pushSubscriptions:       append([]models.PushSubscription(nil), d.pushSubscriptions...),
+		notificationPreferences: append([]models.NotificationPreference(nil), d.notificationPreferences...),
+		outboxEmails:            append([]models.OutboxEmail(nil), d.outboxEmails...),
+		jobs:                    append([]models.Job(nil), d.jobs...),
+	}
+	for memoryID, personIDs := range d.memoryPeople {
+		clone.memoryPeople[memoryID] = append([]uuid.UUID(nil), personIDs...)
+	}
+	return clone
+}
+
+// deleteWhere removes the records matching remove, keeping the order of the rest
+func deleteWhere[T any](records []T, remove func(*T) bool) ([]T, int64) {
+	kept := records[:0]
+	var removed int64
+	for i := range records {
+		if remove(&records[i]) {
+			removed++
+			continue
+		}
+		kept = append(kept, records[i])
+	}
+	return kept, removed
+}
+
+// deleteUser removes a user along with everything that cascades from it
+func (d *memoryData) deleteUser(id uuid.UUID) {
+	d.users, _ = deleteWhere(d.users, func(u *models.User) bool { return u.ID == id })
+	d.memories, _ = deleteWhere(d.memories, func(m *models.Memory) bool {
+		if m.UserID == id {
+			delete(d.memoryPeople, m.ID)
+			return true
+		}
+		return false
+	})
+	d.memoryPrompts, _ = deleteWhere(d.memoryPrompts, func(p *models.MemoryPrompt) bool { return p.UserID == id })
+	d.careCircle, _ = deleteWhere(d.careCircle, func(m *models.CareCircleMember) bool { return m.UserID == id })
+	d.people, _ = deleteWhere(d.people, func(p *models.Person) bool { return p.UserID == id })
+	d.photos, _ = deleteWhere(d.photos, func(p *models.Photo) bool { return p.UserID == id })
+	d.chatMessages, _ = deleteWhere(d.chatMessages, func(m *models.ChatMessage) bool { return m.UserID == id })
+	d.identities, _ = deleteWhere(d.identities, func(i *models.UserIdentity) bool { return i.UserID == id })
+	d.reminders, _ = deleteWhere(d.reminders, func(r *models.Reminder) bool { return r.UserID == id })
+	d.interactions, _ = deleteWhere(d.interactions, func(i *models.Interaction) bool { return i.UserID == id })
+	d.routines, _ = deleteWhere(d.routines, func(r *models.Routine) bool { return r.UserID == id })
+	d.checkIns, _ = deleteWhere(d.checkIns, func(c *models.CheckIn) bool { return c.UserID == id })
+	d.notifications, _ = deleteWhere(d.notifications, func(n *models.Notification) bool { return n.UserID == id })
+	d.pushSubscriptions, _ = deleteWhere(d.pushSubscriptions, func(s *models.PushSubscription) bool { return s.UserID == id })
+	d.notificationPreferences, _ = deleteWhere(d.notificationPreferences, func(p *models.NotificationPreference) bool { return p.UserID == id })
+	d.outboxEmails, _ = deleteWhere(d.outboxEmails, func(e *models.OutboxEmail) bool { return e.UserID == id })
+}
+
+// person returns a copy of the person with the given ID
+func (d *memoryData) person(id uuid.UUID) models.Person {
+	for _, person := range d.people {
+		if person.ID == id {
+			return person
+		}
+	}
+	return models.Person{}
+}
+
+// dayStart is local midnight on the day of now in timezone, as ClaimDigestDue
+// works it out in SQL; an empty or unknown timezone is UTC
+func dayStart(now time.Time, timezone string) time.Time {
+	loc, err := time.LoadLocation(timezone)
+	if timezone == "" || err != nil {
+		loc = time.UTC
+	}
+	year, month, day := now.In(loc).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
+
+// tokenValue returns the digest held in the user's token field
+func tokenValue(user *models.User, field TokenField) string {
+	switch field {
+	case ConfirmationToken:
+		return user.ConfirmationToken
+	case ResetToken:
+		return user.ResetToken
+	case EmailChangeToken:
+		return user.EmailChangeToken
+	case EmailChangeCancelToken:
+		return user.EmailChangeCancelToken
+	case UnlockToken:
+		return user.UnlockToken
+	case CalendarToken:
+		return user.CalendarToken
+	}
+	return ""
+}
+
+type memoryUsers struct {
+	db *memoryDB
+}
+
+func (r *memoryUsers) emailTaken(email string, except uuid.UUID) bool {
+	for _, user := range r.db.data.users {
+		if user.Email == email && user.ID != except {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryUsers) Create(ctx context.Context, user *models.User) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if user.ID == uuid.Nil {
+		user.ID = uuid.New()
+	}
+	if r.emailTaken(user.Email, user.ID) {
+		return ErrDuplicate
+	}
+	now := time.Now()
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = now
+	}
+	r.db.data.users = append(r.db.data.users, *user)
+	return nil
+}
+
+func (r *memoryUsers) find(match func(*models.User) bool) (*models.User, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for i := range r.db.data.users {
+		if match(&r.db.data.users[i]) {
+			user := r.db.data.users[i]
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryUsers) Get(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	return r.find(func(u *models.User) bool { return u.ID == id })
+}
+
+func (r *memoryUsers) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.find(func(u *models.User) bool { return u.Email == email })
+}
+
+func (r *memoryUsers) GetByToken(ctx context.Context, field TokenField, digest string) (*models.User, error) {
+	if digest == "" {
+		return nil, ErrNotFound
+	}
+	return r.find(func(u *models.User) bool { return tokenValue(u, field) == digest })
+}
+
+// save replaces the stored user with the same ID when match accepts it
+func (r *memoryUsers) save(user *models.User, match func(*models.User) bool) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for i := range r.db.data.users {
+		stored := &r.db.data.users[i]
+		if stored.ID != user.ID {
+			continue
+		}
+		if !match(stored) {
+			return false, nil
+		}
+		if r.emailTaken(user.Email, user.ID) {
+			return false, ErrDuplicate
+		}
+		user.CreatedAt = stored.CreatedAt
+		user.UpdatedAt = time.Now()
+		*stored = *user
+		return true, nil
+	}
+	return false, nil
+}
+
+func (r *memoryUsers) Update(ctx context.Context, user *models.User) error {
+	saved, err := r.save(user, func(*models.User) bool { return true })
+	if err != nil || saved {
+		return err
+	}
+	return r.Create(ctx, user)
+}
+
+func (r *memoryUsers) UpdateIfToken(ctx context.Context, user *models.User, field TokenField, digest string) (bool, error) {
+	if digest == "" {
+		return false, nil
+	}
+	return r.save(user, func(stored *models.User) bool { return tokenValue(stored, field) == digest })
+}
+
+// modify applies fn to the stored user with the given ID
+func (r *memoryUsers) modify(id uuid.UUID, fn func(*models.User)) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for i := range r.db.data.users {
+		if stored := &r.db.data.users[i]; stored.ID == id {
+			fn(stored)
+			stored.UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *memoryUsers) SetConfirmationToken(ctx context.Context, id uuid.UUID, digest string, expiry time.Time) (bool, error) {
+	var set bool
+	err := r.modify(id, func(user *models.User) {
+		if !user.EmailConfirmed {
+			user.ConfirmationToken = digest
+			user.ConfirmationTokenExpiry = &expiry
+			set = true
+		}
+	})
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return set, err
+}
+
+func (r *memoryUsers) IncrementFailedLogins(ctx context.Context, id uuid.UUID) (int, error) {
+	var attempts int
+	err := r.modify(id, func(user *models.User) {
+		user.FailedLoginAttempts++
+		attempts = user.FailedLoginAttempts
+	})
+	return attempts, err
+}
+
+func (r *memoryUsers) LockOut(ctx context.Context, id uuid.UUID, threshold int, unlockToken string, until func(lockoutCount int) time.Time) (*time.Time, error) {
+	var lockedUntil *time.Time
+	err := r.modify(id, func(user *models.User) {
+		if user.FailedLoginAttempts < threshold {
+			return
+		}
+		user.LockoutCount++
+		end := until(user.LockoutCount)
+		user.LockedUntil = &end
+		user.FailedLoginAttempts = 0
+		user.UnlockToken = unlockToken
+		lockedUntil = &end
+	})
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	return lockedUntil, err
+}
+
+func (r *memoryUsers) ClearFailedLogins(ctx context.Context, id uuid.UUID) error {
+	err := r.modify(id, func(user *models.User) {
+		user.FailedLoginAttempts = 0
+		user.LockoutCount = 0
+		user.LockedUntil = nil
+		user.UnlockToken = ""
+	})
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (r *memoryUsers) Delete(ctx context.Context, id uuid.UUID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.data.deleteUser(id)
+	return nil
+}
+
+func (r *memoryUsers) EmailInUse(ctx context.Context, email string, except uuid.UUID) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	return r.emailTaken(email, except), nil
+}
+
+func (r *memoryUsers) DeleteUnconfirmedBefore(ctx context.Context, before time.Time) (int64, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var ids []uuid.UUID
+	for _, user := range r.db.data.users {
+		if !user.EmailConfirmed && user.CreatedAt.Before(before) {
+			ids = append(ids, user.ID)
+		}
+	}
+	for _, id := range ids {
+		r.db.data.deleteUser(id)
+	}
+	return int64(len(ids)), nil
+}
+
+func (r *memoryUsers) ClaimDigestDue(ctx context.Context, now time.Time, limit int) ([]models.User, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	users := []models.User{}
+	for _, user := range r.db.data.users {
+		if user.CaregiverEmail != "" && (user.LastDigestAt == nil || user.LastDigestAt.Before(dayStart(now, user.Timezone))) {
+			users = append(users, user)
+		}
+	}
+	sort.SliceStable(users, func(i, j int) bool {
+		a, b := users[i].LastDigestAt, users[j].LastDigestAt
+		return a == nil && b != nil || a != nil && b != nil && a.Before(*b)
+	})
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (r *memoryUsers) MarkDigestSent(ctx context.Context, id uuid.UUID, at time.Time) error {
+	err := r.modify(id, func(user *models.User) { user.LastDigestAt = &at })
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (r *memoryUsers) ClaimWeeklyDigestDue(ctx context.Context, since time.Time, limit int) ([]models.User, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	subscribed := make(map[uuid.UUID]bool)
+	for _, member := range r.db.data.careCircle {
+		if !member.WeeklyDigestOptOut {
+			subscribed[member.UserID] = true
+		}
+	}
+	users := []models.User{}
+	for _, user := range r.db.data.users {
+		recipients := user.CaregiverEmail != "" && !user.WeeklyDigestOptOut || subscribed[user.ID]
+		if recipients && (user.LastWeeklyDigestAt == nil || user.LastWeeklyDigestAt.Before(since)) {
+			users = append(users, user)
+		}
+	}
+	sort.SliceStable(users, func(i, j int) bool {
+		a, b := users[i].LastWeeklyDigestAt, users[j].LastWeeklyDigestAt
+		return a == nil && b != nil || a != nil && b != nil && a.Before(*b)
+	})
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (r *memoryUsers) MarkWeeklyDigestSent(ctx context.Context, id uuid.UUID, at time.Time) error {
+	err := r.modify(id, func(user *models.User) { user.LastWeeklyDigestAt = &at })
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (r *memoryUsers) OptOutOfWeeklyDigest(ctx context.Context, id uuid.UUID) error {
+	err := r.modify(id, func(user *models.User) { user.WeeklyDigestOptOut = true })
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (r *memoryUsers) ClaimPromptDue(ctx context.Context, now time.Time, limit int) ([]models.User, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	users := []models.User{}
+	for _, user := range r.db.data.users {
+		if user.NextPromptAt == nil || !user.NextPromptAt.After(now) {
+			users = append(users, user)
+		}
+	}
+	sort.SliceStable(users, func(i, j int) bool {
+		a, b := users[i].NextPromptAt, users[j].NextPromptAt
+		return a == nil && b != nil || a != nil && b != nil && a.Before(*b)
+	})
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (r *memoryUsers) SetNextPromptAt(ctx context.Context, id uuid.UUID, at time.Time) error {
+	err := r.modify(id, func(user *models.User) { user.NextPromptAt = &at })
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+type memoryCareCircle struct {
+	db *memoryDB
+}
+
+func (r *memoryCareCircle) Create(ctx context.Context, member *models.CareCircleMember) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, existing := range r.db.data.careCircle {
+		if existing.UserID == member.UserID && strings.EqualFold(existing.Email, member.Email) {
+			return ErrDuplicate
+		}
+	}
+	if member.ID == uuid.Nil {
+		member.ID = uuid.New()
+	}
+	if member.CreatedAt.IsZero() {
+		member.CreatedAt = time.Now()
+	}
+	stored := *member
+	stored.User = models.User{}
+	r.db.data.careCircle = append(r.db.data.careCircle, stored)
+	return nil
+}
+
+func (r *memoryCareCircle) Get(ctx context.Context, id uuid.UUID) (*models.CareCircleMember, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, member := range r.db.data.careCircle {
+		if member.ID == id {
+			return &member, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryCareCircle) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.CareCircleMember, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	members := []models.CareCircleMember{}
+	for _, member := range r.db.data.careCircle {
+		if member.UserID == userID {
+			members = append(members, member)
+		}
+	}
+	return members, nil
+}
+
+func (r *memoryCareCircle) OptOut(ctx context.Context, id uuid.UUID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for i := range r.db.data.careCircle {
+		if r.db.data.careCircle[i].ID == id {
+			r.db.data.careCircle[i].WeeklyDigestOptOut = true
+		}
+	}
+	return nil
+}
+
+func (r *memoryCareCircle) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var removed int64
+	r.db.data.careCircle, removed = deleteWhere(r.db.data.careCircle, func(m *models.CareCircleMember) bool {
+		return m.ID == id && m.UserID == userID
+	})
+	if removed == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memoryMemories struct {
+	db *memoryDB
+}
+
+func (r *memoryMemories) Create(ctx context.Context, memory *models.Memory) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if memory.ID == uuid.Nil {
+		memory.ID = uuid.New()
+	}
+	if memory.CreatedAt.IsZero() {
+		memory.CreatedAt = time.Now()
+	}
+	stored := *memory
+	stored.People = nil
+	r.db.data.memories = append(r.db.data.memories, stored)
+	return nil
+}
+
+func (r *memoryMemories) AddPeople(ctx context.Context, memoryID uuid.UUID, people []models.Person) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	linked := r.db.data.memoryPeople[memoryID]
+	for _, person := range people {
+		duplicate := false
+		for _, id := range linked {
+			duplicate = duplicate || id == person.ID
+		}
+		if !duplicate {
+			linked = append(linked, person.ID)
+		}
+	}
+	r.db.data.memoryPeople[memoryID] = linked
+	return nil
+}
+
+func (r *memoryMemories) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Memory, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	memories := []models.Memory{}
+	for i := len(r.db.data.memories) - 1; i >= 0; i-- {
+		memory := r.db.data.memories[i]
+		if memory.UserID != userID {
+			continue
+		}
+		for _, personID := range r.db.data.memoryPeople[memory.ID] {
+			for _, person := range r.db.data.people {
+				if person.ID == personID {
+					memory.People = append(memory.People, person)
+				}
+			}
+		}
+		memories = append(memories, memory)
+	}
+	sort.SliceStable(memories, func(i, j int) bool {
+		return memories[i].CreatedAt.After(memories[j].CreatedAt)
+	})
+	return memories, nil
+}
+
+func (r *memoryMemories) GetForUser(ctx context.Context, id, userID uuid.UUID) (*models.Memory, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, memory := range r.db.data.memories {
+		if memory.ID == id && memory.UserID == userID {
+			return &memory, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryMemories) GetByCalendarUID(ctx context.Context, userID uuid.UUID, uid string) (*models.Memory, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, memory := range r.db.data.memories {
+		if uid != "" && memory.CalendarUID == uid && memory.UserID == userID {
+			return &memory, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryMemories) Update(ctx context.Context, memory *models.Memory) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for i := range r.db.data.memories {
+		if r.db.data.memories[i].ID == memory.ID {
+			stored := *memory
+			stored.People = nil
+			r.db.data.memories[i] = stored
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+type memoryMemoryPrompts struct {
+	db *memoryDB
+}
+
+func (r *memoryMemoryPrompts) Create(ctx context.Context, prompt *models.MemoryPrompt) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, existing := range r.db.data.memoryPrompts {
+		if existing.UserID == prompt.UserID && existing.Day.Equal(prompt.Day) {
+			return ErrDuplicate
+		}
+	}
+	if prompt.ID == uuid.Nil {
+		prompt.ID = uuid.New()
+	}
+	if prompt.CreatedAt.IsZero() {
+		prompt.CreatedAt = time.Now()
+	}
+	stored := *prompt
+	stored.User = models.User{}
+	stored.Memory = models.Memory{}
+	r.db.data.memoryPrompts = append(r.db.data.memoryPrompts, stored)
+	return nil
+}
+
+// list returns copies of the prompts accepted by match, newest day first,
+// with Memory loaded
+func (r *memoryMemoryPrompts) list(match func(*models.MemoryPrompt) bool) []models.MemoryPrompt {
+	prompts := []models.MemoryPrompt{}
+	for i := range r.db.data.memoryPrompts {
+		if match(&r.db.data.memoryPrompts[i]) {
+			prompts = append(prompts, r.db.data.memoryPrompts[i])
+		}
+	}
+	sort.SliceStable(prompts, func(i, j int) bool {
+		return prompts[i].Day.After(prompts[j].Day)
+	})
+	for i := range prompts {
+		for _, memory := range r.db.data.memories {
+			if memory.ID == prompts[i].MemoryID {
+				prompts[i].Memory = memory
+			}
+		}
+	}
+	return prompts
+}
+
+func (r *memoryMemoryPrompts) GetForUser(ctx context.Context, id, userID uuid.UUID) (*models.MemoryPrompt, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	prompts := r.list(func(p *models.MemoryPrompt) bool { return p.ID == id && p.UserID == userID })
+	if len(prompts) == 0 {
+		return nil, ErrNotFound
+	}
+	return &prompts[0], nil
+}
+
+func (r *memoryMemoryPrompts) GetForDay(ctx context.Context, userID uuid.UUID, day time.Time) (*models.MemoryPrompt, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	prompts := r.list(func(p *models.MemoryPrompt) bool { return p.UserID == userID && p.Day.Equal(day) })
+	if len(prompts) == 0 {
+		return nil, ErrNotFound
+	}
+	return &prompts[0], nil
+}
+
+func (r *memoryMemoryPrompts) ListByUser(ctx context.Context, userID uuid.UUID, since time.Time) ([]models.MemoryPrompt, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	return r.list(func(p *models.MemoryPrompt) bool { return p.UserID == userID && !p.Day.Before(since) }), nil
+}
+
+func (r *memoryMemoryPrompts) Update(ctx context.Context, prompt *models.MemoryPrompt) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for i := range r.db.data.memoryPrompts {
+		if r.db.data.memoryPrompts[i].ID == prompt.ID {
+			stored := *prompt
+			stored.User = models.User{}
+			stored.Memory = models.Memory{}
+			r.db.data.memoryPrompts[i] = stored
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+type memoryPeople struct {
+	db *memoryDB
+}
+
+func (r *memoryPeople) Create(ctx context.Context, person *models.Person) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if person.ID == uuid.Nil {
+		person.ID = uuid.New()
+	}
+	now := time.Now()
+	if person.CreatedAt.IsZero() {
+		person.CreatedAt = now
+	}
+	if person.UpdatedAt.IsZero() {
+		person.UpdatedAt = now
+	}
+	stored := *person
+	stored.Photo = nil
+	r.db.data.people = append(r.db.data.people, stored)
+	return nil
+}
+
+func (r *memoryPeople) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Person, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	people := []models.Person{}
+	for _, person := range r.db.data.people {
+		if person.UserID == userID {
+			people = append(people, person)
+		}
+	}
+	sort.SliceStable(people, func(i, j int) bool {
+		return people[i].CreatedAt.Before(people[j].CreatedAt)
+	})
+	return people, nil
+}
+
+func (r *memoryPeople) ListByIDs(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) ([]models.Person, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	people := []models.Person{}
+	for _, person := range r.db.data.people {
+		if person.UserID != userID {
+			continue
+		}
+		for _, id := range ids {
+			if person.ID == id {
+				people = append(people, person)
+				break
+			}
+		}
+	}
+	return people, nil
+}
+
+func (r *memoryPeople) GetForUser(ctx context.Context, id, userID uuid.UUID) (*models.Person, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, person := range r.db.data.people {
+		if person.ID == id && person.UserID == userID {
+			return &person, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryPeople) GetByBirthdayUID(ctx context.Context, userID uuid.UUID, uid string) (*models.Person, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, person := range r.db.data.people {
+		if uid != "" && person.BirthdayUID == uid && person.UserID == userID {
+			return &person, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryPeople) Update(ctx context.Context, person *models.Person) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for i := range r.db.data.people {
+		if r.db.data.people[i].ID == person.ID {
+			person.UpdatedAt = time.Now()
+			stored := *person
+			stored.Photo = nil
+			r.db.data.people[i] = stored
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *memoryPeople) ClaimDueForReminder(ctx context.Context, now time.Time, limit int) ([]models.Person, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	people := []models.Person{}
+	for _, person := range r.db.data.people {
+		if person.ContactEveryDays > 0 && person.NextReminderAt != nil && !person.NextReminderAt.After(now) {
+			people = append(people, person)
+		}
+	}
+	sort.SliceStable(people, func(i, j int) bool {
+		return people[i].NextReminderAt.Before(*people[j].NextReminderAt)
+	})
+	if len(people) > limit {
+		people = people[:limit]
+	}
+	return people, nil
+}
+
+type memoryPhotos struct {
+	db *memoryDB
+}
+
+func (r *memoryPhotos) Create(ctx context.Context, photo *models.Photo) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if photo.ID == uuid.Nil {
+		photo.ID = uuid.New()
+	}
+	if photo.UploadedAt.IsZero() {
+		photo.UploadedAt = time.Now()
+	}
+	stored := *photo
+	stored.Memory = nil
+	r.db.data.photos = append(r.db.data.photos, stored)
+	return nil
+}
+
+func (r *memoryPhotos) find(match func(*models.Photo) bool) (*models.Photo, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for i := range r.db.data.photos {
+		if match(&r.db.data.photos[i]) {
+			photo := r.db.data.photos[i]
+			return &photo, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryPhotos) GetForUser(ctx context.Context, id, userID uuid.UUID) (*models.Photo, error) {
+	return r.find(func(p *models.Photo) bool { return p.ID == id && p.UserID == userID })
+}
+
+func (r *memoryPhotos) GetByMemory(ctx context.Context, memoryID uuid.UUID) (*models.Photo, error) {
+	return r.find(func(p *models.Photo) bool { return p.MemoryID != nil && *p.MemoryID == memoryID })
+}
+
+func (r *memoryPhotos) Update(ctx context.Context, photo *models.Photo) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for i := range r.db.data.photos {
+		if r.db.data.photos[i].ID == photo.ID {
+			stored := *photo
+			stored.Memory = nil
+			r.db.data.photos[i] = stored
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *memoryPhotos) KeysByUser(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var keys []string
+	for _, photo := range r.db.data.photos {
+		if photo.UserID == userID {
+			keys = append(keys, photo.S3Key)
+		}
+	}
+	return keys, nil
+}
+
+type memoryChatMessages struct {
+	db *memoryDB
+}
+
+func (r *memoryChatMessages) Create(ctx context.Context, messages ...*models.ChatMessage) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, message := range messages {
+		if message.ID == uuid.Nil {
+			message.ID = uuid.New()
+		}
+		if message.CreatedAt.IsZero() {
+			message.CreatedAt = time.Now()
+		}
+		r.db.data.chatMessages = append(r.db.data.chatMessages, *message)
+	}
+	return nil
+}
+
+func (r *memoryChatMessages) ListByUser(ctx context.Context, userID uuid.UUID, limit int) ([]models.ChatMessage, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	messages := []models.ChatMessage{}
+	for _, message := range r.db.data.chatMessages {
+		if message.UserID == userID {
+			messages = append(messages, message)
+		}
+	}
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+	})
+	if limit > 0 && len(messages) > limit {
+		messages = messages[:limit]
+	}
+	return messages, nil
+}
+
+func (r *memoryChatMessages) ListSince(ctx context.Context, userID uuid.UUID, since time.Time) ([]models.ChatMessage, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	messages := []models.ChatMessage{}
+	for _, message := range r.db.data.chatMessages {
+		if message.UserID == userID && !message.CreatedAt.Before(since) {
+			messages = append(messages, message)
+		}
+	}
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+	})
+	return messages, nil
+}
+
+type memoryIdentities struct {
+	db *memoryDB
+}
+
+func (r *memoryIdentities) Create(ctx context.Context, identity *models.UserIdentity) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, existing := range r.db.data.identities {
+		if existing.Provider == identity.Provider && existing.Subject == identity.Subject {
+			return ErrDuplicate
+		}
+	}
+	if identity.ID == uuid.Nil {
+		identity.ID = uuid.New()
+	}
+	if identity.CreatedAt.IsZero() {
+		identity.CreatedAt = time.Now()
+	}
+	stored := *identity
+	stored.User = models.User{}
+	r.db.data.identities = append(r.db.data.identities, stored)
+	return nil
+}
+
+func (r *memoryIdentities) find(match func(*models.UserIdentity) bool) (*models.UserIdentity, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for i := range r.db.data.identities {
+		if match(&r.db.data.identities[i]) {
+			identity := r.db.data.identities[i]
+			return &identity, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryIdentities) GetByProviderSubject(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	return r.find(func(i *models.UserIdentity) bool { return i.Provider == provider && i.Subject == subject })
+}
+
+func (r *memoryIdentities) GetForUser(ctx context.Context, id, userID uuid.UUID) (*models.UserIdentity, error) {
+	return r.find(func(i *models.UserIdentity) bool { return i.ID == id && i.UserID == userID })
+}
+
+func (r *memoryIdentities) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	identities := []models.UserIdentity{}
+	for _, identity := range r.db.data.identities {
+		if identity.UserID == userID {
+			identities = append(identities, identity)
+		}
+	}
+	return identities, nil
+}
+
+func (r *memoryIdentities) CountByUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	identities, err := r.ListByUser(ctx, userID)
+	return int64(len(identities)), err
+}
+
+func (r *memoryIdentities) Delete(ctx context.Context, id uuid.UUID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.data.identities, _ = deleteWhere(r.db.data.identities, func(i *models.UserIdentity) bool { return i.ID == id })
+	return nil
+}
+
+type memoryLoginStates struct {
+	db *memoryDB
+}
+
+func (r *memoryLoginStates) Create(ctx context.Context, state *models.OIDCLoginState) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, existing := range r.db.data.loginStates {
+		if existing.StateHash == state.StateHash {
+			return ErrDuplicate
+		}
+	}
+	if state.ID == uuid.Nil {
+		state.ID = uuid.New()
+	}
+	if state.CreatedAt.IsZero() {
+		state.CreatedAt = time.Now()
+	}
+	r.db.data.loginStates = append(r.db.data.loginStates, *state)
+	return nil
+}
+
+func (r *memoryLoginStates) Consume(ctx context.Context, stateHash, provider string, now time.Time) (*models.OIDCLoginState, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var consumed *models.OIDCLoginState
+	r.db.data.loginStates, _ = deleteWhere(r.db.data.loginStates, func(s *models.OIDCLoginState) bool {
+		if consumed == nil && s.StateHash == stateHash && s.Provider == provider && s.ExpiresAt.After(now) {
+			state := *s
+			consumed = &state
+			return true
+		}
+		return false
+	})
+	if consumed == nil {
+		return nil, ErrNotFound
+	}
+	return consumed, nil
+}
+
+func (r *memoryLoginStates) DeleteExpired(ctx context.Context, now time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.data.loginStates, _ = deleteWhere(r.db.data.loginStates, func(s *models.OIDCLoginState) bool {
+		return s.ExpiresAt.Before(now)
+	})
+	return nil
+}
+
+type memoryReminders struct {
+	db *memoryDB
+}
+
+func (r *memoryReminders) Create(ctx context.Context, reminder *models.Reminder) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if reminder.Status == models.ReminderOpen {
+		for _, existing := range r.db.data.reminders {
+			if existing.PersonID == reminder.PersonID && existing.Status == models.ReminderOpen {
+				return ErrDuplicate
+			}
+		}
+	}
+	if reminder.ID == uuid.Nil {
+		reminder.ID = uuid.New()
+	}
+	if reminder.CreatedAt.IsZero() {
+		reminder.CreatedAt = time.Now()
+	}
+	stored := *reminder
+	stored.User = models.User{}
+	stored.Person = models.Person{}
+	r.db.data.reminders = append(r.db.data.reminders, stored)
+	return nil
+}
+
+// list returns copies of the reminders accepted by match, oldest due first,
+// with Person loaded
+func (r *memoryReminders) list(match func(*models.Reminder) bool) []models.Reminder {
+	reminders := []models.Reminder{}
+	for i := range r.db.data.reminders {
+		if match(&r.db.data.reminders[i]) {
+			reminder := r.db.data.reminders[i]
+			reminder.Person = r.db.data.person(reminder.PersonID)
+			reminders = append(reminders, reminder)
+		}
+	}
+	sort.SliceStable(reminders, func(i, j int) bool {
+		return reminders[i].DueAt.Before(reminders[j].DueAt)
+	})
+	return reminders
+}
+
+func (r *memoryReminders) GetForUser(ctx context.Context, id, userID uuid.UUID) (*models.Reminder, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	reminders := r.list(func(m *models.Reminder) bool { return m.ID == id && m.UserID == userID })
+	if len(reminders) == 0 {
+		return nil, ErrNotFound
+	}
+	return &reminders[0], nil
+}
+
+func (r *memoryReminders) HasOpen(ctx context.Context, personID uuid.UUID) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	open := r.list(func(m *models.Reminder) bool { return m.PersonID == personID && m.Status == models.ReminderOpen })
+	return len(open) > 0, nil
+}
+
+// isActive reports whether the reminder is open, due and not snoozed at now
+func isActive(reminder *models.Reminder, now time.Time) bool {
+	return reminder.Status == models.ReminderOpen && !reminder.DueAt.After(now) &&
+		(reminder.SnoozedUntil == nil || !reminder.SnoozedUntil.After(now))
+}
+
+func (r *memoryReminders) ListActive(ctx context.Context, userID uuid.UUID, now time.Time) ([]models.Reminder, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	return r.list(func(m *models.Reminder) bool { return m.UserID == userID && isActive(m, now) }), nil
+}
+
+func (r *memoryReminders) ResolveOpen(ctx context.Context, personID uuid.UUID, status string, at time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for i := range r.db.data.reminders {
+		reminder := &r.db.data.reminders[i]
+		if reminder.PersonID == personID && reminder.Status == models.ReminderOpen {
+			reminder.Status = status
+			reminder.ResolvedAt = &at
+		}
+	}
+	return nil
+}
+
+func (r *memoryReminders) ClaimUnsent(ctx context.Context, now time.Time, limit int) ([]models.Reminder, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	reminders := r.list(func(m *models.Reminder) bool { return m.EmailedAt == nil && isActive(m, now) })
+	if len(reminders) > limit {
+		reminders = reminders[:limit]
+	}
+	return reminders, nil
+}
+
+func (r *memoryReminders) Update(ctx context.Context, reminder *models.Reminder) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for i := range r.db.data.reminders {
+		if r.db.data.reminders[i].ID == reminder.ID {
+			stored := *reminder
+			stored.User = models.User{}
+			stored.Person = models.Person{}
+			r.db.data.reminders[i] = stored
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+type memoryInteractions struct {
+	db *memoryDB
+}
+
+func (r *memoryInteractions) Create(ctx context.Context, interaction *models.Interaction) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if interaction.ID == uuid.Nil {
+		interaction.ID = uuid.New()
+	}
+	if interaction.CreatedAt.IsZero() {
+		interaction.CreatedAt = time.Now()
+	}
+	stored := *interaction
+	stored.User = models.User{}
+	stored.Person = models.Person{}
+	stored.Memory = nil
+	r.db.data.interactions = append(r.db.data.interactions, stored)
+	return nil
+}
+
+// list returns the user's interactions accepted by match, oldest first
+func (r *memoryInteractions) list(userID uuid.UUID, match func(*models.Interaction) bool) []models.Interaction {
+	interactions := []models.Interaction{}
+	for i := range r.db.data.interactions {
+		if r.db.data.interactions[i].UserID == userID && match(&r.db.data.interactions[i]) {
+			interactions = append(interactions, r.db.data.interactions[i])
+		}
+	}
+	sort.SliceStable(interactions, func(i, j int) bool {
+		return interactions[i].OccurredAt.Before(interactions[j].OccurredAt)
+	})
+	return interactions
+}
+
+func (r *memoryInteractions) ListByPerson(ctx context.Context, userID, personID uuid.UUID) ([]models.Interaction, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	interactions := r.list(userID, func(i *models.Interaction) bool { return i.PersonID == personID })
+	for i, j := 0, len(interactions)-1; i < j; i, j = i+1, j-1 {
+		interactions[i], interactions[j] = interactions[j], interactions[i]
+	}
+	return interactions, nil
+}
+
+func (r *memoryInteractions) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Interaction, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	return r.list(userID, func(*models.Interaction) bool { return true }), nil
+}
+
+func (r *memoryInteractions) LatestByPerson(ctx context.Context, userID uuid.UUID) ([]models.Interaction, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	latest := make(map[uuid.UUID]int)
+	interactions := []models.Interaction{}
+	for _, interaction := range r.list(userID, func(*models.Interaction) bool { return true }) {
+		if i, ok := latest[interaction.PersonID]; ok {
+			interactions[i] = interaction
+			continue
+		}
+		latest[interaction.PersonID] = len(interactions)
+		interactions = append(interactions, interaction)
+	}
+	return interactions, nil
+}
+
+type memoryRoutines struct {
+	db *memoryDB
+}
+
+func (r *memoryRoutines) Create(ctx context.Context, routine *models.Routine) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if routine.ID == uuid.Nil {
+		routine.ID = uuid.New()
+	}
+	now := time.Now()
+	if routine.CreatedAt.IsZero() {
+		routine.CreatedAt = now
+	}
+	routine.UpdatedAt = now
+	stored := *routine
+	stored.User = models.User{}
+	stored.Times = append([]string(nil), routine.Times...)
+	r.db.data.routines = append(r.db.data.routines, stored)
+	return nil
+}
+
+// list returns copies of the routines accepted by match in creation order
+func (r *memoryRoutines) list(match func(*models.Routine) bool) []models.Routine {
+	routines := []models.Routine{}
+	for i := range r.db.data.routines {
+		if match(&r.db.data.routines[i]) {
+			routine := r.db.data.routines[i]
+			routine.Times = append([]string(nil), routine.Times...)
+			routines = append(routines, routine)
+		}
+	}
+	return routines
+}
+
+func (r *memoryRoutines) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Routine, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	return r.list(func(routine *models.Routine) bool { return routine.UserID == userID }), nil
+}
+
+func (r *memoryRoutines) GetForUser(ctx context.Context, id, userID uuid.UUID) (*models.Routine, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	routines := r.list(func(routine *models.Routine) bool { return routine.ID == id && routine.UserID == userID })
+	if len(routines) == 0 {
+		return nil, ErrNotFound
+	}
+	return &routines[0], nil
+}
+
+func (r *memoryRoutines) Update(ctx context.Context, routine *models.Routine) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for i := range r.db.data.routines {
+		if r.db.data.routines[i].ID == routine.ID {
+			routine.UpdatedAt = time.Now()
+			stored := *routine
+			stored.User = models.User{}
+			stored.Times = append([]string(nil), routine.Times...)
+			r.db.data.routines[i] = stored
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *memoryRoutines) Delete(ctx context.Context, id uuid.UUID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.data.routines, _ = deleteWhere(r.db.data.routines, func(routine *models.Routine) bool { return routine.ID == id })
+	r.db.data.checkIns, _ = deleteWhere(r.db.data.checkIns, func(c *models.CheckIn) bool { return c.RoutineID == id })
+	return nil
+}
+
+func (r *memoryRoutines) ClaimDue(ctx context.Context, now time.Time, limit int) ([]models.Routine, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	routines := r.list(func(routine *models.Routine) bool {
+		return routine.NextDueAt != nil &&
+			!routine.NextDueAt.Add(time.Duration(routine.GraceMinutes)*time.Minute).After(now)
+	})
+	sort.SliceStable(routines, func(i, j int) bool {
+		return routines[i].NextDueAt.Before(*routines[j].NextDueAt)
+	})
+	if len(routines) > limit {
+		routines = routines[:limit]
+	}
+	return routines, nil
+}
+
+func (r *memoryRoutines) ClaimNotifyDue(ctx context.Context, now time.Time, limit int) ([]models.Routine, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	routines := r.list(func(routine *models.Routine) bool {
+		return routine.NotifyAt != nil && !routine.NotifyAt.After(now)
+	})
+	sort.SliceStable(routines, func(i, j int) bool {
+		return routines[i].NotifyAt.Before(*routines[j].NotifyAt)
+	})
+	if len(routines) > limit {
+		routines = routines[:limit]
+	}
+	return routines, nil
+}
+
+type memoryCheckIns struct {
+	db *memoryDB
+}
+
+func (r *memoryCheckIns) Create(ctx context.Context, checkIn *models.CheckIn) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, existing := range r.db.data.checkIns {
+		if existing.RoutineID == checkIn.RoutineID && existing.ScheduledAt.Equal(checkIn.ScheduledAt) {
+			return ErrDuplicate
+		}
+	}
+	if checkIn.ID == uuid.Nil {
+		checkIn.ID = uuid.New()
+	}
+	if checkIn.CreatedAt.IsZero() {
+		checkIn.CreatedAt = time.Now()
+	}
+	stored := *checkIn
+	stored.User = models.User{}
+	stored.Routine = models.Routine{}
+	r.db.data.checkIns = append(r.db.data.checkIns, stored)
+	return nil
+}
+
+// list returns copies of the check-ins accepted by match, oldest scheduled
+// first
+func (r *memoryCheckIns) list(match func(*models.CheckIn) bool) []models.CheckIn {
+	checkIns := []models.CheckIn{}
+	for i := range r.db.data.checkIns {
+		if match(&r.db.data.checkIns[i]) {
+			checkIns = append(checkIns, r.db.data.checkIns[i])
+		}
+	}
+	sort.SliceStable(checkIns, func(i, j int) bool {
+		return checkIns[i].ScheduledAt.Before(checkIns[j].ScheduledAt)
+	})
+	return checkIns
+}
+
+func (r *memoryCheckIns) GetByOccurrence(ctx context.Context, routineID uuid.UUID, scheduledAt time.Time) (*models.CheckIn, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	checkIns := r.list(func(c *models.CheckIn) bool { return c.RoutineID == routineID && c.ScheduledAt.Equal(scheduledAt) })
+	if len(checkIns) == 0 {
+		return nil, ErrNotFound
+	}
+	return &checkIns[0], nil
+}
+
+func (r *memoryCheckIns) ListByUser(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]models.CheckIn, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	return r.list(func(c *models.CheckIn) bool {
+		return c.UserID == userID && !c.ScheduledAt.Before(from) && c.ScheduledAt.Before(to)
+	}), nil
+}
+
+func (r *memoryCheckIns) Update(ctx context.Context, checkIn *models.CheckIn) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for i := range r.db.data.checkIns {
+		if r.db.data.checkIns[i].ID == checkIn.ID {
+			stored := *checkIn
+			stored.User = models.User{}
+			stored.Routine = models.Routine{}
+			r.db.data.checkIns[i] = stored
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *memoryCheckIns) ClaimUnescalated(ctx context.Context, since time.Time, limit int) ([]models.CheckIn, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	caregivers := make(map[uuid.UUID]bool)
+	for _, user := range r.db.data.users {
+		caregivers[user.ID] = user.CaregiverEmail != ""
+	}
+	checkIns := r.list(func(c *models.CheckIn) bool {
+		return c.Status == models.CheckInMissed && c.EscalatedAt == nil && !c.ScheduledAt.Before(since) && caregivers[c.UserID]
+	})
+	if len(checkIns) > limit {
+		checkIns = checkIns[:limit]
+	}
+	for i := range checkIns {
+		for _, routine := range r.db.data.routines {
+			if routine.ID == checkIns[i].RoutineID {
+				checkIns[i].Routine = routine
+				checkIns[i].Routine.Times = append([]string(nil), routine.Times...)
+			}
+		}
+	}
+	return checkIns, nil
+}
+
+type memoryNotifications struct {
+	db *memoryDB
+}
+
+// storedNotification copies the notification without its associations
+func storedNotification(notification *models.Notification) models.Notification {
+	stored := *notification
+	stored.User = models.User{}
+	stored.Data = maps.Clone(notification.Data)
+	return stored
+}
+
+func (r *memoryNotifications) Create(ctx context.Context, notification *models.Notification) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if notification.ID == uuid.Nil {
+		notification.ID = uuid.New()
+	}
+	if notification.CreatedAt.IsZero() {
+		notification.CreatedAt = time.Now()
+	}
+	r.db.data.notifications = append(r.db.data.notifications, storedNotification(notification))
+	return nil
+}
+
+// list returns copies of the user's in-app notifications accepted by match,
+// oldest first
+func (r *memoryNotifications) list(userID uuid.UUID, match func(*models.Notification) bool) []models.Notification {
+	notifications := []models.Notification{}
+	for i := range r.db.data.notifications {
+		notification := &r.db.data.notifications[i]
+		if notification.UserID == userID && notification.InApp && match(notification) {
+			notifications = append(notifications, storedNotification(notification))
+		}
+	}
+	sort.SliceStable(notifications, func(i, j int) bool {
+		return notifications[i].CreatedAt.Before(notifications[j].CreatedAt)
+	})
+	return notifications
+}
+
+func (r *memoryNotifications) List(ctx context.Context, userID uuid.UUID, unreadOnly bool, limit int) ([]models.Notification, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	notifications := r.list(userID, func(n *models.Notification) bool { return !unreadOnly || n.ReadAt == nil })
+	slices.Reverse(notifications)
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+	}
+	return notifications, nil
+}
+
+func (r *memoryNotifications) ListSince(ctx context.Context, userID uuid.UUID, since time.Time, limit int) ([]models.Notification, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	notifications := r.list(userID, func(n *models.Notification) bool { return !n.CreatedAt.Before(since) })
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+	}
+	return notifications, nil
+}
+
+func (r *memoryNotifications) GetForUser(ctx context.Context, id, userID uuid.UUID) (*models.Notification, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	notifications := r.list(userID, func(n *models.Notification) bool { return n.ID == id })
+	if len(notifications) == 0 {
+		return nil, ErrNotFound
+	}
+	return &notifications[0], nil
+}
+
+func (r *memoryNotifications) CountUnread(ctx context.Context, userID uuid.UUID) (int64, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	return int64(len(r.list(userID, func(n *models.Notification) bool { return n.ReadAt == nil }))), nil
+}
+
+func (r *memoryNotifications) MarkRead(ctx context.Context, id, userID uuid.UUID, at time.Time) (*models.Notification, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for i := range r.db.data.notifications {
+		notification := &r.db.data.notifications[i]
+		if notification.ID == id && notification.UserID == userID && notification.InApp {
+			if notification.ReadAt == nil {
+				notification.ReadAt = &at
+			}
+			stored := storedNotification(notification)
+			return &stored, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryNotifications) MarkAllRead(ctx context.Context, userID uuid.UUID, at time.Time) (int64, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var marked int64
+	for i := range r.db.data.notifications {
+		notification := &r.db.data.notifications[i]
+		if notification.UserID == userID && notification.InApp && notification.ReadAt == nil {
+			notification.ReadAt = &at
+			marked++
+		}
+	}
+	return marked, nil
+}
+
+func (r *memoryNotifications) ClaimPushPending(ctx context.Context, skip []uuid.UUID, limit int) ([]models.Notification, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	notifications := []models.Notification{}
+	for i := range r.db.data.notifications {
+		if r.db.data.notifications[i].PushPending && !slices.Contains(skip, r.db.data.notifications[i].ID) {
+			notifications = append(notifications, storedNotification(&r.db.data.notifications[i]))
+		}
+	}
+	sort.SliceStable(notifications, func(i, j int) bool {
+		return notifications[i].CreatedAt.Before(notifications[j].CreatedAt)
+	})
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+	}
+	return notifications, nil
+}
+
+func (r *memoryNotifications) Update(ctx context.Context, notification *models.Notification) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for i := range r.db.data.notifications {
+		if r.db.data.notifications[i].ID == notification.ID {
+			r.db.data.notifications[i] = storedNotification(notification)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+type memoryPushSubscriptions struct {
+	db *memoryDB
+}
+
+func (r *memoryPushSubscriptions) Save(ctx context.Context, subscription *models.PushSubscription) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	now := time.Now()
+	subscription.UpdatedAt = now
+	for i := range r.db.data.pushSubscriptions {
+		existing := &r.db.data.pushSubscriptions[i]
+		if existing.Endpoint == subscription.Endpoint {
+			subscription.ID = existing.ID
+			subscription.CreatedAt = existing.CreatedAt
+			*existing = *subscription
+			existing.User = models.User{}
+			return nil
+		}
+	}
+	if subscription.ID == uuid.Nil {
+		subscription.ID = uuid.New()
+	}
+	if subscription.CreatedAt.IsZero() {
+		subscription.CreatedAt = now
+	}
+	stored := *subscription
+	stored.User = models.User{}
+	r.db.data.pushSubscriptions = append(r.db.data.pushSubscriptions, stored)
+	return nil
+}
+
+func (r *memoryPushSubscriptions) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.PushSubscription, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	subscriptions := []models.PushSubscription{}
+	for _, subscription := range r.db.data.pushSubscriptions {
+		if subscription.UserID == userID {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	return subscriptions, nil
+}
+
+func (r *memoryPushSubscriptions) DeleteByEndpoint(ctx context.Context, userID uuid.UUID, endpoint string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var removed int64
+	r.db.data.pushSubscriptions, removed = deleteWhere(r.db.data.pushSubscriptions, func(s *models.PushSubscription) bool {
+		return s.UserID == userID && s.Endpoint == endpoint
+	})
+	if removed == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *memoryPushSubscriptions) Delete(ctx context.Context, id uuid.UUID) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.data.pushSubscriptions, _ = deleteWhere(r.db.data.pushSubscriptions, func(s *models.PushSubscription) bool { return s.ID == id })
+	return nil
+}
+
+type memoryNotificationPreferences struct {
+	db *memoryDB
+}
+
+func (r *memoryNotificationPreferences) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.NotificationPreference, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	preferences := []models.NotificationPreference{}
+	for _, preference := range r.db.data.notificationPreferences {
+		if preference.UserID == userID {
+			preferences = append(preferences, preference)
+		}
+	}
+	sort.SliceStable(preferences, func(i, j int) bool {
+		if preferences[i].Type != preferences[j].Type {
+			return preferences[i].Type < preferences[j].Type
+		}
+		return preferences[i].Channel < preferences[j].Channel
+	})
+	return preferences, nil
+}
+
+func (r *memoryNotificationPreferences) Set(ctx context.Context, preference *models.NotificationPreference) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	preference.UpdatedAt = time.Now()
+	stored := *preference
+	stored.User = models.User{}
+	for i := range r.db.data.notificationPreferences {
+		existing := &r.db.data.notificationPreferences[i]
+		if existing.UserID == preference.UserID && existing.Type == preference.Type && existing.Channel == preference.Channel {
+			*existing = stored
+			return nil
+		}
+	}
+	r.db.data.notificationPreferences = append(r.db.data.notificationPreferences, stored)
+	return nil
+}
+
+type memoryOutboxEmails struct {
+	db *memoryDB
+}
+
+// storedOutboxEmail copies the email without its associations
+func storedOutboxEmail(email *models.OutboxEmail) models.OutboxEmail {
+	stored := *email
+	stored.User = models.User{}
+	return stored
+}
+
+func (r *memoryOutboxEmails) Create(ctx context.Context, email *models.OutboxEmail) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if email.ID == uuid.Nil {
+		email.ID = uuid.New()
+	}
+	now := time.Now()
+	if email.CreatedAt.IsZero() {
+		email.CreatedAt = now
+	}
+	email.UpdatedAt = now
+	r.db.data.outboxEmails = append(r.db.data.outboxEmails, storedOutboxEmail(email))
+	return nil
+}
+
+func (r *memoryOutboxEmails) Get(ctx context.Context, id uuid.UUID) (*models.OutboxEmail, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for i := range r.db.data.outboxEmails {
+		if r.db.data.outboxEmails[i].ID == id {
+			email := storedOutboxEmail(&r.db.data.outboxEmails[i])
+			return &email, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryOutboxEmails) ClaimDue(ctx context.Context, now time.Time, limit int) ([]models.OutboxEmail, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	emails := []models.OutboxEmail{}
+	for i := range r.db.data.outboxEmails {
+		email := &r.db.data.outboxEmails[i]
+		if email.Status == models.EmailPending && !email.NextAttemptAt.After(now) {
+			emails = append(emails, storedOutboxEmail(email))
+		}
+	}
+	sort.SliceStable(emails, func(i, j int) bool {
+		return emails[i].NextAttemptAt.Before(emails[j].NextAttemptAt)
+	})
+	if len(emails) > limit {
+		emails = emails[:limit]
+	}
+	return emails, nil
+}
+
+func (r *memoryOutboxEmails) List(ctx context.Context, status string, limit int) ([]models.OutboxEmail, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	emails := []models.OutboxEmail{}
+	for i := range r.db.data.outboxEmails {
+		email := &r.db.data.outboxEmails[i]
+		if status == "" || email.Status == status {
+			emails = append(emails, storedOutboxEmail(email))
+		}
+	}
+	sort.SliceStable(emails, func(i, j int) bool {
+		return emails[i].CreatedAt.After(emails[j].CreatedAt)
+	})
+	if len(emails) > limit {
+		emails = emails[:limit]
+	}
+	return emails, nil
+}
+
+func (r *memoryOutboxEmails) Update(ctx context.Context, email *models.OutboxEmail) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for i := range r.db.data.outboxEmails {
+		if r.db.data.outboxEmails[i].ID == email.ID {
+			email.UpdatedAt = time.Now()
+			r.db.data.outboxEmails[i] = storedOutboxEmail(email)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+type memoryJobs struct {
+	db *memoryDB
+}
+
+// storedJob copies the job so callers cannot change the stored payload
+func storedJob(job *models.Job) models.Job {
+	stored := *job
+	stored.Payload = append([]byte(nil), job.Payload...)
+	if job.Key != nil {
+		key := *job.Key
+		stored.Key = &key
+	}
+	return stored
+}
+
+func (r *memoryJobs) Create(ctx context.Context, job *models.Job) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if job.Key != nil {
+		for i := range r.db.data.jobs {
+			if key := r.db.data.jobs[i].Key; key != nil && *key == *job.Key {
+				return ErrDuplicate
+			}
+		}
+	}
+	if job.ID == uuid.Nil {
+		job.ID = uuid.New()
+	}
+	now := time.Now()
+	if job.CreatedAt.IsZero() {
+		job.CreatedAt = now
+	}
+	job.UpdatedAt = now
+	r.db.data.jobs = append(r.db.data.jobs, storedJob(job))
+	return nil
+}
+
+func (r *memoryJobs) Get(ctx context.Context, id uuid.UUID) (*models.Job, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for i := range r.db.data.jobs {
+		if r.db.data.jobs[i].ID == id {
+			job := storedJob(&r.db.data.jobs[i])
+			return &job, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryJobs) ClaimDue(ctx context.Context, now time.Time, kinds []string, limit int) ([]models.Job, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	jobs := []models.Job{}
+	for i := range r.db.data.jobs {
+		job := &r.db.data.jobs[i]
+		due := job.Status == models.JobPending && !job.RunAt.After(now)
+		lost := job.Status == models.JobRunning && job.LockedUntil != nil && job.LockedUntil.Before(now)
+		if (due || lost) && slices.Contains(kinds, job.Kind) {
+			jobs = append(jobs, storedJob(job))
+		}
+	}
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].RunAt.Before(jobs[j].RunAt)
+	})
+	if len(jobs) > limit {
+		jobs = jobs[:limit]
+	}
+	return jobs, nil
+}
+
+func (r *memoryJobs) List(ctx context.Context, status, kind string, limit int) ([]models.Job, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	jobs := []models.Job{}
+	for i := range r.db.data.jobs {
+		job := &r.db.data.jobs[i]
+		if (status == "" || job.Status == status) && (kind == "" || job.Kind == kind) {
+			jobs = append(jobs, storedJob(job))
+		}
+	}
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+	})
+	if len(jobs) > limit {
+		jobs = jobs[:limit]
+	}
+	return jobs, nil
+}
+
+func (r *memoryJobs) Update(ctx context.Context, job *models.Job) error {
+	_, err := r.save(job, func(*models.Job) bool { return true })
+	return err
+}
+
+func (r *memoryJobs) UpdateIfLocked(ctx context.Context, job *models.Job, token string) (bool, error) {
+	if token == "" {
+		return false, nil
+	}
+	saved, err := r.save(job, func(stored *models.Job) bool { return stored.LockToken == token })
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return saved, err
+}
+
+// save replaces the stored job when ok accepts it
+func (r *memoryJobs) save(job *models.Job, ok func(stored *models.Job) bool) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for i := range r.db.data.jobs {
+		stored := &r.db.data.jobs[i]
+		if stored.ID != job.ID {
+			continue
+		}
+		if !ok(stored) {
+			return false, nil
+		}
+		job.UpdatedAt = time.Now()
+		job.CreatedAt = stored.CreatedAt
+		*stored = storedJob(job)
+		return true, nil
+	}
+	return false, ErrNotFound
+}
+
+func (r *memoryJobs) DeleteSucceededBefore(ctx context.Context, before time.Time) (int64, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var deleted int64
+	r.db.data.jobs, deleted = deleteWhere(r.db.data.jobs, func(job *models.Job) bool {
+		return job.Status == models.JobSucceeded && job.FinishedAt != nil && job.FinishedAt.Before(before)
+	})
+	return deleted, nil
+}
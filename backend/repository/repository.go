@@ -0,0 +1,361 @@
+// Package repository defines the storage interfaces handlers depend on, with
+// a GORM implementation for Postgres and an in-memory one for tests.
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/muneerlalji/Luma/models"
+)
+
+var (
+	// ErrNotFound is returned when no record matches
+	ErrNotFound = errors.New("record not found")
+	// ErrDuplicate is returned when a write violates a uniqueness constraint
+	ErrDuplicate = errors.New("duplicate record")
+)
+
+// TokenField names a user column holding a one-time token digest
+type TokenField string
+
+const (
+	ConfirmationToken      TokenField = "confirmation_token"
+	ResetToken             TokenField = "reset_token"
+	EmailChangeToken       TokenField = "email_change_token"
+	EmailChangeCancelToken TokenField = "email_change_cancel_token"
+	UnlockToken            TokenField = "unlock_token"
+	CalendarToken          TokenField = "calendar_token"
+)
+
+// UserRepository stores user accounts
+type UserRepository interface {
+	Create(ctx context.Context, user *models.User) error
+	Get(ctx context.Context, id uuid.UUID) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	// GetByToken finds the user whose token field holds digest
+	GetByToken(ctx context.Context, field TokenField, digest string) (*models.User, error)
+	Update(ctx context.Context, user *models.User) error
+	// UpdateIfToken saves user only while field still holds digest and
+	// reports whether it did, so racing requests cannot reuse a token
+	UpdateIfToken(ctx context.Context, user *models.User, field TokenField, digest string) (bool, error)
+	// SetConfirmationToken replaces the confirmation token digest and its
+	// expiry while the user is unconfirmed, and reports whether they were
+	SetConfirmationToken(ctx context.Context, id uuid.UUID, digest string, expiry time.Time) (bool, error)
+	// IncrementFailedLogins counts a failed login and returns how many the
+	// user has had since their last lockout or successful login
+	IncrementFailedLogins(ctx context.Context, id uuid.UUID) (int, error)
+	// LockOut locks the user out while they have at least threshold failed
+	// logins, until the time until gives for their new lockout count. It
+	// resets the failed logins, stores the unlock token digest and returns
+	// when the lockout ends, or nil when the user was not locked out.
+	LockOut(ctx context.Context, id uuid.UUID, threshold int, unlockToken string, until func(lockoutCount int) time.Time) (*time.Time, error)
+	// ClearFailedLogins resets the user's failed logins and lockouts
+	ClearFailedLogins(ctx context.Context, id uuid.UUID) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	// EmailInUse reports whether a user other than except has the address
+	EmailInUse(ctx context.Context, email string, except uuid.UUID) (bool, error)
+	// DeleteUnconfirmedBefore removes unconfirmed accounts created before the
+	// given time and returns how many were removed
+	DeleteUnconfirmedBefore(ctx context.Context, before time.Time) (int64, error)
+	// ClaimDigestDue returns up to limit users with a caregiver who have not
+	// had a digest yet on the day of now in their timezone. In a transaction
+	// the rows stay locked until it ends and concurrent callers skip them.
+	ClaimDigestDue(ctx context.Context, now time.Time, limit int) ([]models.User, error)
+	// MarkDigestSent records when the user's daily digest went out
+	MarkDigestSent(ctx context.Context, id uuid.UUID, at time.Time) error
+	// ClaimWeeklyDigestDue returns up to limit users with a caregiver or
+	// care circle member who has not unsubscribed and whose last weekly
+	// digest went out before since. In a transaction the rows stay locked
+	// until it ends and concurrent callers skip them.
+	ClaimWeeklyDigestDue(ctx context.Context, since time.Time, limit int) ([]models.User, error)
+	// MarkWeeklyDigestSent records when the user's weekly digest went out
+	MarkWeeklyDigestSent(ctx context.Context, id uuid.UUID, at time.Time) error
+	// OptOutOfWeeklyDigest stops the weekly digest to the user's caregiver
+	OptOutOfWeeklyDigest(ctx context.Context, id uuid.UUID) error
+	// ClaimPromptDue returns up to limit users whose next reminiscence
+	// prompt is due at now, or who have not had one yet. In a transaction
+	// the rows stay locked until it ends and concurrent callers skip them.
+	ClaimPromptDue(ctx context.Context, now time.Time, limit int) ([]models.User, error)
+	// SetNextPromptAt records when the user's next reminiscence prompt is due
+	SetNextPromptAt(ctx context.Context, id uuid.UUID, at time.Time) error
+}
+
+// CareCircleRepository stores the people who get a user's weekly digest
+type CareCircleRepository interface {
+	// Create fails with ErrDuplicate when the user's care circle already
+	// has the email address, ignoring case
+	Create(ctx context.Context, member *models.CareCircleMember) error
+	Get(ctx context.Context, id uuid.UUID) (*models.CareCircleMember, error)
+	// ListByUser returns the user's care circle, oldest first
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.CareCircleMember, error)
+	// OptOut stops the member's weekly digest
+	OptOut(ctx context.Context, id uuid.UUID) error
+	// Delete removes a member of the user's care circle
+	Delete(ctx context.Context, id, userID uuid.UUID) error
+}
+
+// MemoryRepository stores memories and the people tagged in them
+type MemoryRepository interface {
+	Create(ctx context.Context, memory *models.Memory) error
+	AddPeople(ctx context.Context, memoryID uuid.UUID, people []models.Person) error
+	// ListByUser returns the user's memories newest first, with People loaded
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Memory, error)
+	GetForUser(ctx context.Context, id, userID uuid.UUID) (*models.Memory, error)
+	// GetByCalendarUID returns the memory imported from the calendar event
+	GetByCalendarUID(ctx context.Context, userID uuid.UUID, uid string) (*models.Memory, error)
+	Update(ctx context.Context, memory *models.Memory) error
+}
+
+// MemoryPromptRepository stores the daily reminiscence prompts
+type MemoryPromptRepository interface {
+	// Create fails with ErrDuplicate when the user already has a prompt for
+	// the day
+	Create(ctx context.Context, prompt *models.MemoryPrompt) error
+	// GetForUser returns the prompt with Memory loaded
+	GetForUser(ctx context.Context, id, userID uuid.UUID) (*models.MemoryPrompt, error)
+	// GetForDay returns the user's prompt for the day with Memory loaded
+	GetForDay(ctx context.Context, userID uuid.UUID, day time.Time) (*models.MemoryPrompt, error)
+	// ListByUser returns the user's prompts for days from since on, newest
+	// first, with Memory loaded
+	ListByUser(ctx context.Context, userID uuid.UUID, since time.Time) ([]models.MemoryPrompt, error)
+	Update(ctx context.Context, prompt *models.MemoryPrompt) error
+}
+
+// PersonRepository stores the people in a user's life
+type PersonRepository interface {
+	Create(ctx context.Context, person *models.Person) error
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Person, error)
+	// ListByIDs returns those of ids that belong to the user
+	ListByIDs(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) ([]models.Person, error)
+	GetForUser(ctx context.Context, id, userID uuid.UUID) (*models.Person, error)
+	// GetByBirthdayUID returns the person whose birthday was imported from
+	// the calendar event
+	GetByBirthdayUID(ctx context.Context, userID uuid.UUID, uid string) (*models.Person, error)
+	Update(ctx context.Context, person *models.Person) error
+	// ClaimDueForReminder returns up to limit people with a contact cadence
+	// whose next reminder is due at now. In a transaction the rows stay
+	// locked until it ends and concurrent callers skip them.
+	ClaimDueForReminder(ctx context.Context, now time.Time, limit int) ([]models.Person, error)
+}
+
+// ReminderRepository stores reach-out reminders
+type ReminderRepository interface {
+	// Create fails with ErrDuplicate when the person already has an open
+	// reminder
+	Create(ctx context.Context, reminder *models.Reminder) error
+	// GetForUser returns the reminder with Person loaded
+	GetForUser(ctx context.Context, id, userID uuid.UUID) (*models.Reminder, error)
+	HasOpen(ctx context.Context, personID uuid.UUID) (bool, error)
+	// ListActive returns the user's open reminders that are due and not
+	// snoozed at now, oldest first, with Person loaded
+	ListActive(ctx context.Context, userID uuid.UUID, now time.Time) ([]models.Reminder, error)
+	// ResolveOpen gives the person's open reminder, if any, the status and
+	// resolution time
+	ResolveOpen(ctx context.Context, personID uuid.UUID, status string, at time.Time) error
+	// ClaimUnsent returns up to limit active reminders that have not been
+	// emailed, with Person loaded. In a transaction the rows stay locked
+	// until it ends and concurrent callers skip them.
+	ClaimUnsent(ctx context.Context, now time.Time, limit int) ([]models.Reminder, error)
+	Update(ctx context.Context, reminder *models.Reminder) error
+}
+
+// PhotoRepository stores photo metadata; the files live in storage
+type PhotoRepository interface {
+	Create(ctx context.Context, photo *models.Photo) error
+	GetForUser(ctx context.Context, id, userID uuid.UUID) (*models.Photo, error)
+	GetByMemory(ctx context.Context, memoryID uuid.UUID) (*models.Photo, error)
+	Update(ctx context.Context, photo *models.Photo) error
+	// KeysByUser returns the storage keys of all the user's photos
+	KeysByUser(ctx context.Context, userID uuid.UUID) ([]string, error)
+}
+
+// InteractionRepository stores the log of contacts with people
+type InteractionRepository interface {
+	Create(ctx context.Context, interaction *models.Interaction) error
+	// ListByPerson returns the user's interactions with a person newest first
+	ListByPerson(ctx context.Context, userID, personID uuid.UUID) ([]models.Interaction, error)
+	// ListByUser returns all of the user's interactions oldest first
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Interaction, error)
+	// LatestByPerson returns the most recent interaction with each person
+	LatestByPerson(ctx context.Context, userID uuid.UUID) ([]models.Interaction, error)
+}
+
+// RoutineRepository stores routines such as medication schedules
+type RoutineRepository interface {
+	Create(ctx context.Context, routine *models.Routine) error
+	// ListByUser returns the user's routines oldest first
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Routine, error)
+	GetForUser(ctx context.Context, id, userID uuid.UUID) (*models.Routine, error)
+	Update(ctx context.Context, routine *models.Routine) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	// ClaimDue returns up to limit routines whose next occurrence is more
+	// than their grace period before now. In a transaction the rows stay
+	// locked until it ends and concurrent callers skip them.
+	ClaimDue(ctx context.Context, now time.Time, limit int) ([]models.Routine, error)
+	// ClaimNotifyDue returns up to limit routines whose next occurrence to
+	// notify the user of is at or before now. In a transaction the rows stay
+	// locked until it ends and concurrent callers skip them.
+	ClaimNotifyDue(ctx context.Context, now time.Time, limit int) ([]models.Routine, error)
+}
+
+// CheckInRepository stores check-ins for routine occurrences
+type CheckInRepository interface {
+	// Create fails with ErrDuplicate when the occurrence already has one
+	Create(ctx context.Context, checkIn *models.CheckIn) error
+	// GetByOccurrence returns the check-in for the routine at scheduledAt
+	GetByOccurrence(ctx context.Context, routineID uuid.UUID, scheduledAt time.Time) (*models.CheckIn, error)
+	// ListByUser returns the user's check-ins scheduled from from until to,
+	// oldest first
+	ListByUser(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]models.CheckIn, error)
+	Update(ctx context.Context, checkIn *models.CheckIn) error
+	// ClaimUnescalated returns up to limit missed check-ins scheduled since
+	// since whose user has a caregiver who has not been emailed, with
+	// Routine loaded. In a transaction the rows stay locked until it ends
+	// and concurrent callers skip them.
+	ClaimUnescalated(ctx context.Context, since time.Time, limit int) ([]models.CheckIn, error)
+}
+
+// NotificationRepository stores the notifications shown to users
+type NotificationRepository interface {
+	Create(ctx context.Context, notification *models.Notification) error
+	// List returns the user's in-app notifications newest first, only the
+	// unread ones when unreadOnly is set, at most limit of them
+	List(ctx context.Context, userID uuid.UUID, unreadOnly bool, limit int) ([]models.Notification, error)
+	// ListSince returns up to limit of the user's in-app notifications
+	// created at or after since, oldest first
+	ListSince(ctx context.Context, userID uuid.UUID, since time.Time, limit int) ([]models.Notification, error)
+	GetForUser(ctx context.Context, id, userID uuid.UUID) (*models.Notification, error)
+	CountUnread(ctx context.Context, userID uuid.UUID) (int64, error)
+	// MarkRead sets the read time of the user's unread in-app notification
+	// and returns it
+	MarkRead(ctx context.Context, id, userID uuid.UUID, at time.Time) (*models.Notification, error)
+	// MarkAllRead marks all of the user's in-app notifications read and
+	// returns how many were unread
+	MarkAllRead(ctx context.Context, userID uuid.UUID, at time.Time) (int64, error)
+	// ClaimPushPending returns up to limit notifications still to be pushed,
+	// oldest first, leaving out those in skip. In a transaction the rows stay
+	// locked until it ends and concurrent callers skip them.
+	ClaimPushPending(ctx context.Context, skip []uuid.UUID, limit int) ([]models.Notification, error)
+	Update(ctx context.Context, notification *models.Notification) error
+}
+
+// PushSubscriptionRepository stores the browsers users receive push
+// messages in
+type PushSubscriptionRepository interface {
+	// Save creates the subscription or, when its endpoint is already known,
+	// replaces the stored one, moving it to the subscription's user
+	Save(ctx context.Context, subscription *models.PushSubscription) error
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.PushSubscription, error)
+	// DeleteByEndpoint deletes the user's subscription for the endpoint
+	DeleteByEndpoint(ctx context.Context, userID uuid.UUID, endpoint string) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// NotificationPreferenceRepository stores which channels users want each
+// type of notification on
+type NotificationPreferenceRepository interface {
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.NotificationPreference, error)
+	// Set creates or replaces the preference for its type and channel
+	Set(ctx context.Context, preference *models.NotificationPreference) error
+}
+
+// OutboxEmailRepository stores rendered emails until they are sent
+type OutboxEmailRepository interface {
+	Create(ctx context.Context, email *models.OutboxEmail) error
+	Get(ctx context.Context, id uuid.UUID) (*models.OutboxEmail, error)
+	// ClaimDue returns up to limit pending emails whose next attempt is due
+	// by now, longest waiting first. In a transaction the rows stay locked
+	// until it ends and concurrent callers skip them.
+	ClaimDue(ctx context.Context, now time.Time, limit int) ([]models.OutboxEmail, error)
+	// List returns up to limit emails newest first, only those with status
+	// unless it is empty
+	List(ctx context.Context, status string, limit int) ([]models.OutboxEmail, error)
+	Update(ctx context.Context, email *models.OutboxEmail) error
+}
+
+// JobRepository stores background jobs
+type JobRepository interface {
+	// Create fails with ErrDuplicate when another job has the same Key
+	Create(ctx context.Context, job *models.Job) error
+	Get(ctx context.Context, id uuid.UUID) (*models.Job, error)
+	// ClaimDue returns up to limit jobs of the given kinds that are pending
+	// and due by now, or running on a lease that ran out before now, longest
+	// waiting first. In a transaction the rows stay locked until it ends and
+	// concurrent callers skip them.
+	ClaimDue(ctx context.Context, now time.Time, kinds []string, limit int) ([]models.Job, error)
+	// List returns up to limit jobs newest first, only those with status and
+	// kind unless they are empty
+	List(ctx context.Context, status, kind string, limit int) ([]models.Job, error)
+	Update(ctx context.Context, job *models.Job) error
+	// UpdateIfLocked saves job only while it still holds token and reports
+	// whether it did, so a worker whose lease ran out cannot overwrite the
+	// job after another worker claimed it
+	UpdateIfLocked(ctx context.Context, job *models.Job, token string) (bool, error)
+	// DeleteSucceededBefore removes jobs that succeeded before the given
+	// time and returns how many were removed
+	DeleteSucceededBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// ChatMessageRepository stores the chat history
+type ChatMessageRepository interface {
+	Create(ctx context.Context, messages ...*models.ChatMessage) error
+	// ListByUser returns the user's oldest messages first, at most limit of
+	// them when limit is positive
+	ListByUser(ctx context.Context, userID uuid.UUID, limit int) ([]models.ChatMessage, error)
+	// ListSince returns the user's messages created at or after since,
+	// oldest first
+	ListSince(ctx context.Context, userID uuid.UUID, since time.Time) ([]models.ChatMessage, error)
+}
+
+// IdentityRepository stores accounts linked at OpenID Connect providers
+type IdentityRepository interface {
+	Create(ctx context.Context, identity *models.UserIdentity) error
+	GetByProviderSubject(ctx context.Context, provider, subject string) (*models.UserIdentity, error)
+	GetForUser(ctx context.Context, id, userID uuid.UUID) (*models.UserIdentity, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error)
+	CountByUser(ctx context.Context, userID uuid.UUID) (int64, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// LoginStateRepository stores in-flight OpenID Connect sign-ins
+type LoginStateRepository interface {
+	Create(ctx context.Context, state *models.OIDCLoginState) error
+	// Consume deletes and returns the unexpired state with the given digest
+	// and provider, so each authorization response is used only once
+	Consume(ctx context.Context, stateHash, provider string, now time.Time) (*models.OIDCLoginState, error)
+	DeleteExpired(ctx context.Context, now time.Time) error
+}
+
+// Store groups the repositories and runs work across them atomically
+type Store struct {
+	Users                   UserRepository
+	CareCircle              CareCircleRepository
+	Memories                MemoryRepository
+	MemoryPrompts           MemoryPromptRepository
+	People                  PersonRepository
+	Photos                  PhotoRepository
+	ChatMessages            ChatMessageRepository
+	Identities              IdentityRepository
+	LoginStates             LoginStateRepository
+	Reminders               ReminderRepository
+	Interactions            InteractionRepository
+	Routines                RoutineRepository
+	CheckIns                CheckInRepository
+	Notifications           NotificationRepository
+	PushSubscriptions       PushSubscriptionRepository
+	NotificationPreferences NotificationPreferenceRepository
+	OutboxEmails            OutboxEmailRepository
+	Jobs                    JobRepository
+
+	transaction func(ctx context.Context, fn func(tx *Store) error) error
+}
+
+// Transaction runs fn with a store whose writes are committed together when
+// fn returns nil and rolled back otherwise. Nested calls join the outer
+// transaction.
+func (s *Store) Transaction(ctx context.Context, fn func(tx *Store) error) error {
+	return s.transaction(ctx, fn)
+}
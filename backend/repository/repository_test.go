@@ -0,0 +1,667 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/muneerlalji/Luma/models"
+	"github.com/muneerlalji/Luma/repository"
+	"github.com/muneerlalji/Luma/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// StoreTestSuite checks that every Store implementation behaves the same
+type StoreTestSuite struct {
+	suite.Suite
+	newStore func() *repository.Store
+	store    *repository.Store
+	ctx      context.Context
+}
+
+func (suite *StoreTestSuite) SetupTest() {
+	suite.store = suite.newStore()
+	suite.ctx = context.Background()
+}
+
+func (suite *StoreTestSuite) createUser(email string) models.User {
+	user := models.User{Email: email, DisplayName: "Test User", EmailConfirmed: true}
+	suite.Require().NoError(suite.store.Users.Create(suite.ctx, &user))
+	return user
+}
+
+func (suite *StoreTestSuite) TestUsers_NotFound() {
+	_, err := suite.store.Users.GetByEmail(suite.ctx, "missing@example.com")
+	assert.ErrorIs(suite.T(), err, repository.ErrNotFound)
+}
+
+func (suite *StoreTestSuite) TestUsers_DuplicateEmail() {
+	suite.createUser("test@example.com")
+
+	user := models.User{Email: "test@example.com", DisplayName: "Other"}
+	err := suite.store.Users.Create(suite.ctx, &user)
+	assert.ErrorIs(suite.T(), err, repository.ErrDuplicate)
+}
+
+func (suite *StoreTestSuite) TestUsers_UpdateIfToken() {
+	user := suite.createUser("test@example.com")
+	user.ResetToken = "digest"
+	suite.Require().NoError(suite.store.Users.Update(suite.ctx, &user))
+
+	found, err := suite.store.Users.GetByToken(suite.ctx, repository.ResetToken, "digest")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), user.ID, found.ID)
+
+	found.ResetToken = ""
+	ok, err := suite.store.Users.UpdateIfToken(suite.ctx, found, repository.ResetToken, "digest")
+	suite.Require().NoError(err)
+	assert.True(suite.T(), ok)
+
+	// The token has been used, so a second update is refused
+	ok, err = suite.store.Users.UpdateIfToken(suite.ctx, found, repository.ResetToken, "digest")
+	suite.Require().NoError(err)
+	assert.False(suite.T(), ok)
+}
+
+func (suite *StoreTestSuite) TestUsers_SetConfirmationToken() {
+	expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+	unconfirmed := models.User{Email: "new@example.com", DisplayName: "New", FailedLoginAttempts: 2}
+	suite.Require().NoError(suite.store.Users.Create(suite.ctx, &unconfirmed))
+
+	set, err := suite.store.Users.SetConfirmationToken(suite.ctx, unconfirmed.ID, "digest", expiry)
+	suite.Require().NoError(err)
+	assert.True(suite.T(), set)
+	found, err := suite.store.Users.GetByToken(suite.ctx, repository.ConfirmationToken, "digest")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), unconfirmed.ID, found.ID)
+	assert.True(suite.T(), expiry.Equal(*found.ConfirmationTokenExpiry))
+	assert.Equal(suite.T(), 2, found.FailedLoginAttempts, "other columns are left alone")
+
+	confirmed := suite.createUser("confirmed@example.com")
+	set, err = suite.store.Users.SetConfirmationToken(suite.ctx, confirmed.ID, "other", expiry)
+	suite.Require().NoError(err)
+	assert.False(suite.T(), set)
+	_, err = suite.store.Users.GetByToken(suite.ctx, repository.ConfirmationToken, "other")
+	assert.ErrorIs(suite.T(), err, repository.ErrNotFound)
+}
+
+func (suite *StoreTestSuite) TestUsers_FailedLogins() {
+	user := suite.createUser("test@example.com")
+	lockedUntil := time.Now().Add(time.Hour).Truncate(time.Second)
+	until := func(lockoutCount int) time.Time { return lockedUntil.Add(time.Duration(lockoutCount) * time.Minute) }
+
+	for want := 1; want <= 2; want++ {
+		attempts, err := suite.store.Users.IncrementFailedLogins(suite.ctx, user.ID)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), want, attempts)
+	}
+
+	// Below the threshold the account stays open
+	end, err := suite.store.Users.LockOut(suite.ctx, user.ID, 3, "digest", until)
+	suite.Require().NoError(err)
+	assert.Nil(suite.T(), end)
+
+	end, err = suite.store.Users.LockOut(suite.ctx, user.ID, 2, "digest", until)
+	suite.Require().NoError(err)
+	suite.Require().NotNil(end)
+	assert.True(suite.T(), lockedUntil.Add(time.Minute).Equal(*end))
+
+	// Lockouts touch only the lockout columns
+	stored, err := suite.store.Users.Get(suite.ctx, user.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 0, stored.FailedLoginAttempts)
+	assert.Equal(suite.T(), 1, stored.LockoutCount)
+	assert.Equal(suite.T(), "digest", stored.UnlockToken)
+	assert.Equal(suite.T(), user.DisplayName, stored.DisplayName)
+
+	suite.Require().NoError(suite.store.Users.ClearFailedLogins(suite.ctx, user.ID))
+	stored, err = suite.store.Users.Get(suite.ctx, user.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 0, stored.LockoutCount)
+	assert.Nil(suite.T(), stored.LockedUntil)
+	assert.Empty(suite.T(), stored.UnlockToken)
+}
+
+func (suite *StoreTestSuite) TestUsers_DeleteUnconfirmedBefore() {
+	stale := models.User{Email: "stale@example.com", DisplayName: "Stale", CreatedAt: time.Now().Add(-48 * time.Hour)}
+	suite.Require().NoError(suite.store.Users.Create(suite.ctx, &stale))
+	suite.createUser("confirmed@example.com")
+
+	removed, err := suite.store.Users.DeleteUnconfirmedBefore(suite.ctx, time.Now().Add(-24*time.Hour))
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), int64(1), removed)
+
+	_, err = suite.store.Users.Get(suite.ctx, stale.ID)
+	assert.ErrorIs(suite.T(), err, repository.ErrNotFound)
+}
+
+func (suite *StoreTestSuite) TestMemories_ListWithPeople() {
+	user := suite.createUser("test@example.com")
+	person := models.Person{FirstName: "Jane", LastName: "Doe", UserID: user.ID}
+	suite.Require().NoError(suite.store.People.Create(suite.ctx, &person))
+
+	memory := models.Memory{Title: "Picnic", Type: "story", UserID: user.ID}
+	suite.Require().NoError(suite.store.Memories.Create(suite.ctx, &memory))
+	suite.Require().NoError(suite.store.Memories.AddPeople(suite.ctx, memory.ID, []models.Person{person}))
+
+	memories, err := suite.store.Memories.ListByUser(suite.ctx, user.ID)
+	suite.Require().NoError(err)
+	suite.Require().Len(memories, 1)
+	suite.Require().Len(memories[0].People, 1)
+	assert.Equal(suite.T(), person.ID, memories[0].People[0].ID)
+}
+
+func (suite *StoreTestSuite) TestMemories_GetByCalendarUID() {
+	user := suite.createUser("test@example.com")
+	other := suite.createUser("other@example.com")
+	memory := models.Memory{Title: "Picnic", Type: "event", UserID: user.ID, CalendarUID: "picnic@example.com"}
+	suite.Require().NoError(suite.store.Memories.Create(suite.ctx, &memory))
+	untracked := models.Memory{Title: "Story", Type: "story", UserID: user.ID}
+	suite.Require().NoError(suite.store.Memories.Create(suite.ctx, &untracked))
+
+	found, err := suite.store.Memories.GetByCalendarUID(suite.ctx, user.ID, "picnic@example.com")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), memory.ID, found.ID)
+	_, err = suite.store.Memories.GetByCalendarUID(suite.ctx, other.ID, "picnic@example.com")
+	assert.ErrorIs(suite.T(), err, repository.ErrNotFound)
+	_, err = suite.store.Memories.GetByCalendarUID(suite.ctx, user.ID, "")
+	assert.ErrorIs(suite.T(), err, repository.ErrNotFound)
+
+	found.Title = "Picnic in the park"
+	suite.Require().NoError(suite.store.Memories.Update(suite.ctx, found))
+	updated, err := suite.store.Memories.GetForUser(suite.ctx, memory.ID, user.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "Picnic in the park", updated.Title)
+}
+
+func (suite *StoreTestSuite) TestChatMessages_ListLimit() {
+	user := suite.createUser("test@example.com")
+	for _, content := range []string{"first", "second", "third"} {
+		message := models.ChatMessage{Role: "user", Content: content, UserID: user.ID}
+		suite.Require().NoError(suite.store.ChatMessages.Create(suite.ctx, &message))
+	}
+
+	messages, err := suite.store.ChatMessages.ListByUser(suite.ctx, user.ID, 2)
+	suite.Require().NoError(err)
+	suite.Require().Len(messages, 2)
+	assert.Equal(suite.T(), "first", messages[0].Content)
+}
+
+func (suite *StoreTestSuite) TestChatMessages_ListSince() {
+	user := suite.createUser("test@example.com")
+	now := time.Now().Truncate(time.Second)
+	for i, content := range []string{"old", "recent", "latest"} {
+		message := models.ChatMessage{Role: "user", Content: content, UserID: user.ID, Confused: i == 1, CreatedAt: now.Add(time.Duration(i-2) * 24 * time.Hour)}
+		suite.Require().NoError(suite.store.ChatMessages.Create(suite.ctx, &message))
+	}
+
+	messages, err := suite.store.ChatMessages.ListSince(suite.ctx, user.ID, now.Add(-24*time.Hour))
+	suite.Require().NoError(err)
+	suite.Require().Len(messages, 2)
+	assert.Equal(suite.T(), "recent", messages[0].Content)
+	assert.True(suite.T(), messages[0].Confused)
+	assert.Equal(suite.T(), "latest", messages[1].Content)
+}
+
+func (suite *StoreTestSuite) TestLoginStates_ConsumeOnce() {
+	state := models.OIDCLoginState{StateHash: "hash", Provider: "mock", ExpiresAt: time.Now().Add(time.Minute)}
+	suite.Require().NoError(suite.store.LoginStates.Create(suite.ctx, &state))
+
+	consumed, err := suite.store.LoginStates.Consume(suite.ctx, "hash", "mock", time.Now())
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), state.ID, consumed.ID)
+
+	_, err = suite.store.LoginStates.Consume(suite.ctx, "hash", "mock", time.Now())
+	assert.ErrorIs(suite.T(), err, repository.ErrNotFound)
+}
+
+func (suite *StoreTestSuite) TestReminders_OneOpenPerPerson() {
+	user := suite.createUser("test@example.com")
+	person := models.Person{FirstName: "Jane", LastName: "Doe", UserID: user.ID}
+	suite.Require().NoError(suite.store.People.Create(suite.ctx, &person))
+
+	now := time.Now()
+	first := models.Reminder{UserID: user.ID, PersonID: person.ID, DueAt: now.Add(-time.Hour), Status: models.ReminderOpen}
+	suite.Require().NoError(suite.store.Reminders.Create(suite.ctx, &first))
+	second := models.Reminder{UserID: user.ID, PersonID: person.ID, DueAt: now, Status: models.ReminderOpen}
+	assert.ErrorIs(suite.T(), suite.store.Reminders.Create(suite.ctx, &second), repository.ErrDuplicate)
+
+	claimed, err := suite.store.Reminders.ClaimUnsent(suite.ctx, now, 10)
+	suite.Require().NoError(err)
+	suite.Require().Len(claimed, 1)
+	assert.Equal(suite.T(), "Jane", claimed[0].Person.FirstName)
+
+	// Snoozed or resolved reminders are neither listed nor claimed
+	later := now.Add(time.Hour)
+	first.SnoozedUntil = &later
+	suite.Require().NoError(suite.store.Reminders.Update(suite.ctx, &first))
+	active, err := suite.store.Reminders.ListActive(suite.ctx, user.ID, now)
+	suite.Require().NoError(err)
+	assert.Empty(suite.T(), active)
+
+	first.Status = models.ReminderCompleted
+	suite.Require().NoError(suite.store.Reminders.Update(suite.ctx, &first))
+	claimed, err = suite.store.Reminders.ClaimUnsent(suite.ctx, later, 10)
+	suite.Require().NoError(err)
+	assert.Empty(suite.T(), claimed)
+	suite.Require().NoError(suite.store.Reminders.Create(suite.ctx, &second))
+}
+
+func (suite *StoreTestSuite) TestPeople_ClaimDueForReminder() {
+	user := suite.createUser("test@example.com")
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	due := models.Person{FirstName: "Due", UserID: user.ID, ContactEveryDays: 7, NextReminderAt: &past}
+	later := models.Person{FirstName: "Later", UserID: user.ID, ContactEveryDays: 7, NextReminderAt: &future}
+	off := models.Person{FirstName: "Off", UserID: user.ID, NextReminderAt: &past}
+	for _, person := range []*models.Person{&due, &later, &off} {
+		suite.Require().NoError(suite.store.People.Create(suite.ctx, person))
+	}
+
+	people, err := suite.store.People.ClaimDueForReminder(suite.ctx, now, 10)
+	suite.Require().NoError(err)
+	suite.Require().Len(people, 1)
+	assert.Equal(suite.T(), due.ID, people[0].ID)
+}
+
+func (suite *StoreTestSuite) TestInteractions_Ordering() {
+	user := suite.createUser("test@example.com")
+	tom := models.Person{FirstName: "Tom", UserID: user.ID}
+	ann := models.Person{FirstName: "Ann", UserID: user.ID}
+	for _, person := range []*models.Person{&tom, &ann} {
+		suite.Require().NoError(suite.store.People.Create(suite.ctx, person))
+	}
+
+	now := time.Now().Truncate(time.Second)
+	for _, interaction := range []models.Interaction{
+		{UserID: user.ID, PersonID: tom.ID, Type: models.InteractionVisit, OccurredAt: now.Add(-2 * time.Hour)},
+		{UserID: user.ID, PersonID: tom.ID, Type: models.InteractionCall, OccurredAt: now},
+		{UserID: user.ID, PersonID: ann.ID, Type: models.InteractionMessage, OccurredAt: now.Add(-time.Hour)},
+	} {
+		suite.Require().NoError(suite.store.Interactions.Create(suite.ctx, &interaction))
+	}
+
+	withTom, err := suite.store.Interactions.ListByPerson(suite.ctx, user.ID, tom.ID)
+	suite.Require().NoError(err)
+	suite.Require().Len(withTom, 2)
+	assert.Equal(suite.T(), models.InteractionCall, withTom[0].Type)
+
+	all, err := suite.store.Interactions.ListByUser(suite.ctx, user.ID)
+	suite.Require().NoError(err)
+	suite.Require().Len(all, 3)
+	assert.Equal(suite.T(), models.InteractionVisit, all[0].Type)
+
+	latest, err := suite.store.Interactions.LatestByPerson(suite.ctx, user.ID)
+	suite.Require().NoError(err)
+	types := make(map[string]string)
+	for _, interaction := range latest {
+		types[interaction.PersonID.String()] = interaction.Type
+	}
+	assert.Equal(suite.T(), map[string]string{tom.ID.String(): models.InteractionCall, ann.ID.String(): models.InteractionMessage}, types)
+}
+
+func (suite *StoreTestSuite) TestUsers_ClaimDigestDue() {
+	today := time.Now().Truncate(24 * time.Hour)
+	yesterday, later := today.Add(-time.Hour), today.Add(time.Hour)
+	never := models.User{Email: "never@example.com", CaregiverEmail: "carer@example.com"}
+	due := models.User{Email: "due@example.com", CaregiverEmail: "carer@example.com", LastDigestAt: &yesterday}
+	sent := models.User{Email: "sent@example.com", CaregiverEmail: "carer@example.com", LastDigestAt: &later}
+	alone := models.User{Email: "alone@example.com"}
+	for _, user := range []*models.User{&due, &never, &sent, &alone} {
+		suite.Require().NoError(suite.store.Users.Create(suite.ctx, user))
+	}
+
+	now := today.Add(2 * time.Hour)
+	users, err := suite.store.Users.ClaimDigestDue(suite.ctx, now, 10)
+	suite.Require().NoError(err)
+	suite.Require().Len(users, 2)
+	assert.Equal(suite.T(), never.ID, users[0].ID)
+	assert.Equal(suite.T(), due.ID, users[1].ID)
+
+	suite.Require().NoError(suite.store.Users.MarkDigestSent(suite.ctx, never.ID, later))
+	users, err = suite.store.Users.ClaimDigestDue(suite.ctx, now, 10)
+	suite.Require().NoError(err)
+	suite.Require().Len(users, 1)
+	assert.Equal(suite.T(), due.ID, users[0].ID)
+}
+
+func (suite *StoreTestSuite) TestUsers_ClaimDigestDueByTimezone() {
+	// At 02:00 UTC it is still the evening before in New York, and already
+	// the morning in Tokyo, so a digest sent an hour before midnight UTC was
+	// sent on the same local day there
+	now := time.Date(2026, time.March, 10, 2, 0, 0, 0, time.UTC)
+	sentAt := time.Date(2026, time.March, 9, 23, 0, 0, 0, time.UTC)
+	utc := models.User{Email: "utc@example.com", CaregiverEmail: "carer@example.com", Timezone: "UTC", LastDigestAt: &sentAt}
+	newYork := models.User{Email: "ny@example.com", CaregiverEmail: "carer@example.com", Timezone: "America/New_York", LastDigestAt: &sentAt}
+	tokyo := models.User{Email: "tokyo@example.com", CaregiverEmail: "carer@example.com", Timezone: "Asia/Tokyo", LastDigestAt: &sentAt}
+	for _, user := range []*models.User{&utc, &newYork, &tokyo} {
+		suite.Require().NoError(suite.store.Users.Create(suite.ctx, user))
+	}
+
+	users, err := suite.store.Users.ClaimDigestDue(suite.ctx, now, 10)
+	suite.Require().NoError(err)
+	suite.Require().Len(users, 1)
+	assert.Equal(suite.T(), utc.ID, users[0].ID)
+}
+
+func (suite *StoreTestSuite) TestUsers_ClaimWeeklyDigestDue() {
+	since := time.Now().Truncate(time.Second).Add(-7 * 24 * time.Hour)
+	before, after := since.Add(-time.Hour), since.Add(time.Hour)
+	never := models.User{Email: "never@example.com", CaregiverEmail: "carer@example.com"}
+	due := models.User{Email: "due@example.com", CaregiverEmail: "carer@example.com", LastWeeklyDigestAt: &before}
+	sent := models.User{Email: "sent@example.com", CaregiverEmail: "carer@example.com", LastWeeklyDigestAt: &after}
+	unsubscribed := models.User{Email: "unsubscribed@example.com", CaregiverEmail: "carer@example.com", WeeklyDigestOptOut: true}
+	alone := models.User{Email: "alone@example.com"}
+	longAgo := before.Add(-time.Hour)
+	circle := models.User{Email: "circle@example.com", LastWeeklyDigestAt: &longAgo}
+	circleUnsubscribed := models.User{Email: "circle-unsubscribed@example.com"}
+	for _, user := range []*models.User{&due, &never, &sent, &unsubscribed, &alone, &circle, &circleUnsubscribed} {
+		suite.Require().NoError(suite.store.Users.Create(suite.ctx, user))
+	}
+	for _, member := range []models.CareCircleMember{
+		{UserID: circle.ID, Email: "son@example.com"},
+		{UserID: circleUnsubscribed.ID, Email: "son@example.com", WeeklyDigestOptOut: true},
+	} {
+		suite.Require().NoError(suite.store.CareCircle.Create(suite.ctx, &member))
+	}
+
+	users, err := suite.store.Users.ClaimWeeklyDigestDue(suite.ctx, since, 10)
+	suite.Require().NoError(err)
+	suite.Require().Len(users, 3)
+	assert.Equal(suite.T(), never.ID, users[0].ID)
+	assert.Equal(suite.T(), circle.ID, users[1].ID)
+	assert.Equal(suite.T(), due.ID, users[2].ID)
+
+	// Marking the digest sent leaves the rest of the user alone
+	suite.Require().NoError(suite.store.Users.MarkWeeklyDigestSent(suite.ctx, due.ID, after))
+	stored, err := suite.store.Users.Get(suite.ctx, due.ID)
+	suite.Require().NoError(err)
+	assert.True(suite.T(), after.Equal(*stored.LastWeeklyDigestAt))
+	assert.Equal(suite.T(), "carer@example.com", stored.CaregiverEmail)
+}
+
+func (suite *StoreTestSuite) TestCareCircle_UniqueEmail() {
+	user := suite.createUser("test@example.com")
+	other := suite.createUser("other@example.com")
+	member := models.CareCircleMember{UserID: user.ID, Name: "Tom", Email: "tom@example.com"}
+	suite.Require().NoError(suite.store.CareCircle.Create(suite.ctx, &member))
+
+	again := models.CareCircleMember{UserID: user.ID, Email: "Tom@Example.com"}
+	assert.ErrorIs(suite.T(), suite.store.CareCircle.Create(suite.ctx, &again), repository.ErrDuplicate)
+	elsewhere := models.CareCircleMember{UserID: other.ID, Email: "tom@example.com"}
+	suite.Require().NoError(suite.store.CareCircle.Create(suite.ctx, &elsewhere))
+
+	suite.Require().NoError(suite.store.CareCircle.OptOut(suite.ctx, member.ID))
+	members, err := suite.store.CareCircle.ListByUser(suite.ctx, user.ID)
+	suite.Require().NoError(err)
+	suite.Require().Len(members, 1)
+	assert.True(suite.T(), members[0].WeeklyDigestOptOut)
+
+	assert.ErrorIs(suite.T(), suite.store.CareCircle.Delete(suite.ctx, member.ID, other.ID), repository.ErrNotFound)
+	suite.Require().NoError(suite.store.CareCircle.Delete(suite.ctx, member.ID, user.ID))
+	_, err = suite.store.CareCircle.Get(suite.ctx, member.ID)
+	assert.ErrorIs(suite.T(), err, repository.ErrNotFound)
+}
+
+func (suite *StoreTestSuite) TestUsers_ClaimPromptDue() {
+	now := time.Now().Truncate(time.Second)
+	before, after := now.Add(-time.Hour), now.Add(time.Hour)
+	never := models.User{Email: "never@example.com"}
+	due := models.User{Email: "due@example.com", NextPromptAt: &before}
+	later := models.User{Email: "later@example.com", NextPromptAt: &after}
+	for _, user := range []*models.User{&due, &never, &later} {
+		suite.Require().NoError(suite.store.Users.Create(suite.ctx, user))
+	}
+
+	users, err := suite.store.Users.ClaimPromptDue(suite.ctx, now, 10)
+	suite.Require().NoError(err)
+	suite.Require().Len(users, 2)
+	assert.Equal(suite.T(), never.ID, users[0].ID)
+	assert.Equal(suite.T(), due.ID, users[1].ID)
+
+	suite.Require().NoError(suite.store.Users.SetNextPromptAt(suite.ctx, never.ID, after))
+	users, err = suite.store.Users.ClaimPromptDue(suite.ctx, now, 10)
+	suite.Require().NoError(err)
+	suite.Require().Len(users, 1)
+	assert.Equal(suite.T(), due.ID, users[0].ID)
+}
+
+func (suite *StoreTestSuite) TestMemoryPrompts_OnePerDay() {
+	user := suite.createUser("test@example.com")
+	memory := models.Memory{Title: "Picnic", Type: "story", UserID: user.ID}
+	suite.Require().NoError(suite.store.Memories.Create(suite.ctx, &memory))
+	yesterday := time.Date(2024, 6, 14, 0, 0, 0, 0, time.UTC)
+	today := yesterday.AddDate(0, 0, 1)
+	for _, day := range []time.Time{yesterday, today} {
+		prompt := models.MemoryPrompt{UserID: user.ID, MemoryID: memory.ID, Day: day, Reason: models.PromptRevisit, Response: models.PromptPending}
+		suite.Require().NoError(suite.store.MemoryPrompts.Create(suite.ctx, &prompt))
+	}
+
+	again := models.MemoryPrompt{UserID: user.ID, MemoryID: memory.ID, Day: today, Reason: models.PromptFavorite, Response: models.PromptPending}
+	assert.ErrorIs(suite.T(), suite.store.MemoryPrompts.Create(suite.ctx, &again), repository.ErrDuplicate)
+
+	found, err := suite.store.MemoryPrompts.GetForDay(suite.ctx, user.ID, today)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "Picnic", found.Memory.Title)
+	found.Response = models.PromptEngaged
+	suite.Require().NoError(suite.store.MemoryPrompts.Update(suite.ctx, found))
+
+	prompts, err := suite.store.MemoryPrompts.ListByUser(suite.ctx, user.ID, today)
+	suite.Require().NoError(err)
+	suite.Require().Len(prompts, 1)
+	assert.Equal(suite.T(), models.PromptEngaged, prompts[0].Response)
+	prompts, err = suite.store.MemoryPrompts.ListByUser(suite.ctx, user.ID, yesterday)
+	suite.Require().NoError(err)
+	suite.Require().Len(prompts, 2)
+	assert.True(suite.T(), prompts[0].Day.Equal(today))
+}
+
+func (suite *StoreTestSuite) TestNotifications_ReadAndPush() {
+	user := suite.createUser("test@example.com")
+	now := time.Now().Truncate(time.Second)
+	emailed := models.Notification{UserID: user.ID, Type: models.NotificationReminder, Title: "Emailed", CreatedAt: now.Add(-3 * time.Hour)}
+	older := models.Notification{UserID: user.ID, Type: models.NotificationReminder, Title: "Older", InApp: true, PushPending: true, CreatedAt: now.Add(-2 * time.Hour)}
+	newer := models.Notification{UserID: user.ID, Type: models.NotificationRoutineDue, Title: "Newer", InApp: true, CreatedAt: now.Add(-time.Hour)}
+	for _, notification := range []*models.Notification{&emailed, &older, &newer} {
+		suite.Require().NoError(suite.store.Notifications.Create(suite.ctx, notification))
+	}
+
+	// Notifications not shown in the app are neither listed nor counted
+	listed, err := suite.store.Notifications.List(suite.ctx, user.ID, false, 10)
+	suite.Require().NoError(err)
+	suite.Require().Len(listed, 2)
+	assert.Equal(suite.T(), "Newer", listed[0].Title)
+	since, err := suite.store.Notifications.ListSince(suite.ctx, user.ID, older.CreatedAt, 10)
+	suite.Require().NoError(err)
+	suite.Require().Len(since, 2)
+	assert.Equal(suite.T(), "Older", since[0].Title)
+	_, err = suite.store.Notifications.MarkRead(suite.ctx, emailed.ID, user.ID, now)
+	assert.ErrorIs(suite.T(), err, repository.ErrNotFound)
+
+	read, err := suite.store.Notifications.MarkRead(suite.ctx, older.ID, user.ID, now)
+	suite.Require().NoError(err)
+	suite.Require().NotNil(read.ReadAt)
+	unread, err := suite.store.Notifications.CountUnread(suite.ctx, user.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), int64(1), unread)
+	marked, err := suite.store.Notifications.MarkAllRead(suite.ctx, user.ID, now)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), int64(1), marked)
+
+	pending, err := suite.store.Notifications.ClaimPushPending(suite.ctx, nil, 10)
+	suite.Require().NoError(err)
+	suite.Require().Len(pending, 1)
+	assert.Equal(suite.T(), older.ID, pending[0].ID)
+	pending, err = suite.store.Notifications.ClaimPushPending(suite.ctx, []uuid.UUID{older.ID}, 10)
+	suite.Require().NoError(err)
+	assert.Empty(suite.T(), pending)
+}
+
+func (suite *StoreTestSuite) TestPushSubscriptions_SaveByEndpoint() {
+	user := suite.createUser("test@example.com")
+	other := suite.createUser("other@example.com")
+	first := models.PushSubscription{UserID: user.ID, Endpoint: "https://push.example.com/1", P256dh: "a", Auth: "a"}
+	suite.Require().NoError(suite.store.PushSubscriptions.Save(suite.ctx, &first))
+
+	// The browser was signed in to another account since
+	again := models.PushSubscription{UserID: other.ID, Endpoint: "https://push.example.com/1", P256dh: "b", Auth: "b"}
+	suite.Require().NoError(suite.store.PushSubscriptions.Save(suite.ctx, &again))
+	subscriptions, err := suite.store.PushSubscriptions.ListByUser(suite.ctx, user.ID)
+	suite.Require().NoError(err)
+	assert.Empty(suite.T(), subscriptions)
+	subscriptions, err = suite.store.PushSubscriptions.ListByUser(suite.ctx, other.ID)
+	suite.Require().NoError(err)
+	suite.Require().Len(subscriptions, 1)
+	assert.Equal(suite.T(), "b", subscriptions[0].P256dh)
+
+	assert.ErrorIs(suite.T(), suite.store.PushSubscriptions.DeleteByEndpoint(suite.ctx, user.ID, again.Endpoint), repository.ErrNotFound)
+	suite.Require().NoError(suite.store.PushSubscriptions.DeleteByEndpoint(suite.ctx, other.ID, again.Endpoint))
+}
+
+func (suite *StoreTestSuite) TestOutboxEmails_ClaimDueAndList() {
+	user := suite.createUser("test@example.com")
+	now := time.Now().Truncate(time.Second)
+	newEmail := func(subject, status string, next, created time.Time) models.OutboxEmail {
+		email := models.OutboxEmail{
+			UserID: user.ID, Template: "reminder", Locale: "en", Recipient: user.Email,
+			Subject: subject, TextBody: "text", HTMLBody: "<p>html</p>",
+			Status: status, NextAttemptAt: next, CreatedAt: created,
+		}
+		suite.Require().NoError(suite.store.OutboxEmails.Create(suite.ctx, &email))
+		return email
+	}
+	retry := newEmail("Retry", models.EmailPending, now.Add(-time.Minute), now.Add(-3*time.Hour))
+	fresh := newEmail("Fresh", models.EmailPending, now.Add(-time.Hour), now.Add(-2*time.Hour))
+	newEmail("Later", models.EmailPending, now.Add(time.Hour), now.Add(-time.Hour))
+	sent := newEmail("Sent", models.EmailSent, now.Add(-2*time.Hour), now)
+
+	due, err := suite.store.OutboxEmails.ClaimDue(suite.ctx, now, 10)
+	suite.Require().NoError(err)
+	suite.Require().Len(due, 2)
+	assert.Equal(suite.T(), fresh.ID, due[0].ID)
+	assert.Equal(suite.T(), retry.ID, due[1].ID)
+
+	all, err := suite.store.OutboxEmails.List(suite.ctx, "", 3)
+	suite.Require().NoError(err)
+	suite.Require().Len(all, 3)
+	assert.Equal(suite.T(), sent.ID, all[0].ID)
+	pending, err := suite.store.OutboxEmails.List(suite.ctx, models.EmailPending, 10)
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), pending, 3)
+
+	retry.Status = models.EmailFailed
+	retry.Attempts = 8
+	suite.Require().NoError(suite.store.OutboxEmails.Update(suite.ctx, &retry))
+	found, err := suite.store.OutboxEmails.Get(suite.ctx, retry.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), models.EmailFailed, found.Status)
+	assert.Equal(suite.T(), 8, found.Attempts)
+}
+
+func (suite *StoreTestSuite) TestJobs_ClaimDueAndLock() {
+	now := time.Now().Truncate(time.Second)
+	newJob := func(kind, status string, runAt time.Time, lockedUntil *time.Time) models.Job {
+		job := models.Job{
+			Kind: kind, Payload: []byte(`{}`), Status: status, MaxAttempts: 3,
+			RunAt: runAt, LockedUntil: lockedUntil,
+		}
+		suite.Require().NoError(suite.store.Jobs.Create(suite.ctx, &job))
+		return job
+	}
+	expired, held := now.Add(-time.Minute), now.Add(time.Minute)
+	late := newJob("late", models.JobPending, now.Add(-time.Hour), nil)
+	lost := newJob("lost", models.JobRunning, now.Add(-30*time.Minute), &expired)
+	newJob("held", models.JobRunning, now.Add(-2*time.Hour), &held)
+	newJob("later", models.JobPending, now.Add(time.Hour), nil)
+	newJob("dead", models.JobDead, now.Add(-3*time.Hour), nil)
+	newJob("unknown", models.JobPending, now.Add(-4*time.Hour), nil)
+
+	due, err := suite.store.Jobs.ClaimDue(suite.ctx, now, []string{"late", "lost", "held", "later", "dead"}, 10)
+	suite.Require().NoError(err)
+	suite.Require().Len(due, 2)
+	assert.Equal(suite.T(), late.ID, due[0].ID)
+	assert.Equal(suite.T(), lost.ID, due[1].ID)
+
+	late.Status = models.JobRunning
+	late.LockToken = "first"
+	suite.Require().NoError(suite.store.Jobs.Update(suite.ctx, &late))
+	late.Status = models.JobSucceeded
+	ok, err := suite.store.Jobs.UpdateIfLocked(suite.ctx, &late, "second")
+	suite.Require().NoError(err)
+	assert.False(suite.T(), ok, "another worker holds the job")
+	ok, err = suite.store.Jobs.UpdateIfLocked(suite.ctx, &late, "first")
+	suite.Require().NoError(err)
+	assert.True(suite.T(), ok)
+	found, err := suite.store.Jobs.Get(suite.ctx, late.ID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), models.JobSucceeded, found.Status)
+
+	running, err := suite.store.Jobs.List(suite.ctx, models.JobRunning, "", 10)
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), running, 2)
+	dead, err := suite.store.Jobs.List(suite.ctx, "", "dead", 10)
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), dead, 1)
+}
+
+func (suite *StoreTestSuite) TestJobs_KeyIsUnique() {
+	key := "digest@2026-03-02T09:00:00Z"
+	job := models.Job{Kind: "digest", Payload: []byte(`{}`), Status: models.JobPending, MaxAttempts: 1, RunAt: time.Now(), Key: &key}
+	suite.Require().NoError(suite.store.Jobs.Create(suite.ctx, &job))
+
+	again := models.Job{Kind: "digest", Payload: []byte(`{}`), Status: models.JobPending, MaxAttempts: 1, RunAt: time.Now(), Key: &key}
+	assert.ErrorIs(suite.T(), suite.store.Jobs.Create(suite.ctx, &again), repository.ErrDuplicate)
+}
+
+func (suite *StoreTestSuite) TestJobs_DeleteSucceededBefore() {
+	now := time.Now().Truncate(time.Second)
+	old, recent := now.Add(-48*time.Hour), now.Add(-time.Hour)
+	for _, job := range []models.Job{
+		{Kind: "old", Status: models.JobSucceeded, FinishedAt: &old},
+		{Kind: "recent", Status: models.JobSucceeded, FinishedAt: &recent},
+		{Kind: "dead", Status: models.JobDead, FinishedAt: &old},
+	} {
+		job.Payload, job.MaxAttempts, job.RunAt = []byte(`{}`), 1, old
+		suite.Require().NoError(suite.store.Jobs.Create(suite.ctx, &job))
+	}
+
+	deleted, err := suite.store.Jobs.DeleteSucceededBefore(suite.ctx, now.Add(-24*time.Hour))
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), int64(1), deleted)
+	jobs, err := suite.store.Jobs.List(suite.ctx, "", "", 10)
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), jobs, 2)
+}
+
+func (suite *StoreTestSuite) TestTransaction_RollsBack() {
+	failure := errors.New("failure")
+	err := suite.store.Transaction(suite.ctx, func(tx *repository.Store) error {
+		user := models.User{Email: "rollback@example.com", DisplayName: "Rollback"}
+		if err := tx.Users.Create(suite.ctx, &user); err != nil {
+			return err
+		}
+		return failure
+	})
+	assert.ErrorIs(suite.T(), err, failure)
+
+	_, err = suite.store.Users.GetByEmail(suite.ctx, "rollback@example.com")
+	assert.ErrorIs(suite.T(), err, repository.ErrNotFound)
+}
+
+func TestMemoryStore(t *testing.T) {
+	suite.Run(t, &StoreTestSuite{newStore: repository.NewMemoryStore})
+}
+
+func TestGormStore(t *testing.T) {
+	gormDB, err := testutils.OpenTestDB()
+	if err != nil {
+		t.Skipf("no test database: %v", err)
+	}
+	suite.Run(t, &StoreTestSuite{newStore: func() *repository.Store {
+		testutils.CleanupTestDB(gormDB)
+		return repository.NewGormStore(gormDB)
+	}})
+}
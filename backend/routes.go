@@ -0,0 +1,150 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muneerlalji/Luma/api"
+	"github.com/muneerlalji/Luma/handlers"
+	"github.com/muneerlalji/Luma/health"
+	"github.com/muneerlalji/Luma/middleware"
+)
+
+// legacyDeprecatedAt is when the unversioned routes became aliases of v1
+var legacyDeprecatedAt = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
+// rateLimits are the rate limiting middleware applied to groups of routes
+type rateLimits struct {
+	auth               gin.HandlerFunc
+	login              gin.HandlerFunc
+	token              gin.HandlerFunc
+	forgotPassword     gin.HandlerFunc
+	resendConfirmation gin.HandlerFunc
+	api                gin.HandlerFunc
+}
+
+// registerRoutes adds the service routes and each version of the API, which
+// api/openapi.yaml describes. Versions are mounted under /api; middleware
+// added to that group applies to all of them.
+func registerRoutes(router gin.IRouter, h *handlers.Handler, checker *health.Checker, limits rateLimits, legacySunset time.Time) {
+	// Service routes are not versioned
+	router.GET("/", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "Luma backend running"})
+	})
+	router.GET("/healthz", checker.Liveness)
+	router.GET("/readyz", checker.Readiness)
+
+	versions := router.Group("/api")
+	registerV1(versions.Group("/v1"), h, limits)
+
+	// The unversioned routes predate /api/v1 and stay as aliases until the
+	// sunset date
+	legacy := router.Group("/", middleware.Deprecated(middleware.Deprecation{
+		Since:     legacyDeprecatedAt,
+		Sunset:    legacySunset,
+		Successor: "/api/v1",
+	}))
+	registerV1(legacy, h, limits)
+}
+
+// registerV1 adds the routes of version 1 of the API to router
+func registerV1(router gin.IRouter, h *handlers.Handler, limits rateLimits) {
+	router.GET("/openapi.json", api.Handler())
+
+	// Authentication routes
+	auth := router.Group("/auth")
+	auth.Use(limits.auth)
+	{
+		auth.POST("/register", h.Register)
+		auth.POST("/login", limits.login, h.Login)
+		auth.POST("/confirm", limits.token, h.ConfirmEmail)
+		auth.POST("/forgot-password", limits.forgotPassword, h.ForgotPassword)
+		auth.POST("/resend-confirmation", limits.resendConfirmation, h.ResendConfirmation)
+		auth.POST("/reset-password", limits.token, h.ResetPassword)
+		auth.POST("/unlock", limits.token, h.UnlockAccount)
+		auth.POST("/confirm-email-change", limits.token, h.ConfirmEmailChange)
+		auth.POST("/cancel-email-change", limits.token, h.CancelEmailChange)
+		auth.GET("/oidc/providers", h.GetOIDCProviders)
+		auth.GET("/oidc/:provider/start", h.StartOIDCLogin)
+		auth.POST("/oidc/:provider/callback", limits.token, h.OIDCCallback)
+	}
+
+	// Calendar apps cannot sign in, so the feed is found by the secret in its
+	// URL
+	router.GET("/calendar/feed/:file", limits.api, h.GetCalendarFeed)
+
+	// Caregivers unsubscribe from the weekly digest with the signed token in
+	// its link
+	router.POST("/weekly-digest/unsubscribe", limits.token, h.UnsubscribeWeeklyDigest)
+
+	// Protected routes
+	protected := router.Group("/")
+	protected.Use(limits.api, h.AuthMiddleware())
+	{
+		protected.GET("/me", h.GetCurrentUser)
+		protected.PUT("/profile", h.UpdateProfile)
+		protected.POST("/profile/email", h.RequestEmailChange)
+		protected.PUT("/change-password", h.ChangePassword)
+		protected.GET("/profile/identities", h.GetIdentities)
+		protected.POST("/profile/identities/:provider", h.StartOIDCLink)
+		protected.DELETE("/profile/identities/:id", h.DeleteIdentity)
+		protected.GET("/profile/care-circle", h.GetCareCircle)
+		protected.POST("/profile/care-circle", h.AddCareCircleMember)
+		protected.DELETE("/profile/care-circle/:id", h.DeleteCareCircleMember)
+		protected.DELETE("/profile", h.DeleteAccount)
+		protected.POST("/upload-photo", h.UploadPhoto)
+		protected.POST("/memories", h.CreateMemory)
+		protected.GET("/memories", h.GetMemories)
+		protected.PUT("/memories/:id/favorite", h.FavoriteMemory)
+		protected.GET("/reminiscence", h.GetPrompts)
+		protected.GET("/reminiscence/today", h.GetTodayPrompt)
+		protected.POST("/reminiscence/:id/respond", h.RespondToPrompt)
+		protected.GET("/photos/:id", h.GetPhoto)
+		protected.POST("/people", h.CreatePerson)
+		protected.GET("/people", h.GetPeople)
+		protected.PUT("/people/:id/contact-cadence", h.SetContactCadence)
+		protected.PUT("/people/:id/dates", h.SetPersonDates)
+		protected.POST("/people/:id/interactions", h.CreateInteraction)
+		protected.GET("/people/:id/interactions", h.GetInteractions)
+		protected.GET("/insights", h.GetInsights)
+		protected.GET("/upcoming", h.GetUpcoming)
+		protected.GET("/reminders", h.GetReminders)
+		protected.POST("/reminders/:id/snooze", h.SnoozeReminder)
+		protected.POST("/reminders/:id/complete", h.CompleteReminder)
+		protected.POST("/reminders/:id/dismiss", h.DismissReminder)
+		protected.POST("/routines", h.CreateRoutine)
+		protected.GET("/routines", h.GetRoutines)
+		protected.PUT("/routines/:id", h.UpdateRoutine)
+		protected.DELETE("/routines/:id", h.DeleteRoutine)
+		protected.POST("/routines/:id/check-ins", h.CheckIn)
+		protected.GET("/schedule", h.GetSchedule)
+		protected.GET("/adherence", h.GetAdherence)
+		protected.POST("/calendar/feed", h.CreateCalendarFeed)
+		protected.DELETE("/calendar/feed", h.DeleteCalendarFeed)
+		protected.POST("/calendar/import", h.ImportCalendar)
+		protected.GET("/notifications", h.GetNotifications)
+		protected.GET("/notifications/stream", h.StreamNotifications)
+		protected.POST("/notifications/:id/read", h.MarkNotificationRead)
+		protected.POST("/notifications/read-all", h.MarkAllNotificationsRead)
+		protected.GET("/notifications/preferences", h.GetNotificationPreferences)
+		protected.PUT("/notifications/preferences", h.UpdateNotificationPreferences)
+		protected.GET("/notifications/push-key", h.GetPushKey)
+		protected.POST("/notifications/push-subscriptions", h.SubscribePush)
+		protected.DELETE("/notifications/push-subscriptions", h.UnsubscribePush)
+		protected.POST("/chat", h.Chat)
+		protected.GET("/chat/history", h.GetChatHistory)
+	}
+
+	admin := router.Group("/admin")
+	admin.Use(limits.api, h.AuthMiddleware(), h.RequireAdmin())
+	{
+		admin.GET("/emails", h.GetOutboxEmails)
+		admin.POST("/emails/:id/retry", h.RetryOutboxEmail)
+		admin.GET("/email-templates", h.GetEmailTemplates)
+		admin.GET("/email-templates/:name/preview", h.PreviewEmailTemplate)
+		admin.GET("/jobs", h.GetJobs)
+		admin.GET("/jobs/:id", h.GetJob)
+		admin.POST("/jobs/:id/retry", h.RetryJob)
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/muneerlalji/Luma/api"
+	"github.com/muneerlalji/Luma/apierror"
+	"github.com/muneerlalji/Luma/config"
+	"github.com/muneerlalji/Luma/handlers"
+	"github.com/muneerlalji/Luma/health"
+	"github.com/muneerlalji/Luma/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var pathParam = regexp.MustCompile(`:([^/]+)`)
+
+func newTestRouter(t *testing.T, env *testutils.TestEnv) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.ContextWithFallback = true
+	router.Use(testutils.OpenAPIValidator(t), apierror.Middleware())
+
+	next := func(c *gin.Context) { c.Next() }
+	registerRoutes(router, env.Handler, health.NewChecker(), rateLimits{
+		auth: next, login: next, token: next, forgotPassword: next, resendConfirmation: next, api: next,
+	}, config.DefaultLegacyAPISunset)
+	return router
+}
+
+// TestRoutesMatchOpenAPISpec fails when a route is added without documenting
+// it, or the spec documents a route that no longer exists. Each path is
+// expected under every server it lists.
+func TestRoutesMatchOpenAPISpec(t *testing.T) {
+	spec, err := api.Spec()
+	require.NoError(t, err)
+	router := newTestRouter(t, testutils.NewTestEnv())
+
+	// Path parameter names may differ, so compare templates without them
+	normalize := func(path string) string {
+		return regexp.MustCompile(`\{[^}]+\}`).ReplaceAllString(path, "{}")
+	}
+
+	var routes []string
+	for _, route := range router.Routes() {
+		routes = append(routes, route.Method+" "+normalize(pathParam.ReplaceAllString(route.Path, "{$1}")))
+	}
+	var documented []string
+	for path, item := range spec.Paths.Map() {
+		servers := spec.Servers
+		if len(item.Servers) > 0 {
+			servers = item.Servers
+		}
+		for _, server := range servers {
+			for method := range item.Operations() {
+				documented = append(documented, method+" "+normalize(strings.TrimRight(server.URL, "/")+path))
+			}
+		}
+	}
+	sort.Strings(routes)
+	sort.Strings(documented)
+	assert.Equal(t, documented, routes)
+}
+
+// TestAPIMatchesOpenAPISpec walks through the API as the frontend would. The
+// validator fails the test when a request or response drifts from the spec.
+func TestAPIMatchesOpenAPISpec(t *testing.T) {
+	env := testutils.NewTestEnv()
+	router := newTestRouter(t, env)
+
+	call := func(method, path string, body any, token string) *httptest.ResponseRecorder {
+		t.Helper()
+		var reader bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&reader).Encode(body))
+		}
+		req, _ := http.NewRequest(method, path, &reader)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder, v any) {
+		t.Helper()
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), v))
+	}
+
+	for _, path := range []string{"/", "/healthz", "/readyz", "/api/v1/openapi.json", "/api/v1/auth/oidc/providers"} {
+		assert.Equal(t, http.StatusOK, call("GET", path, nil, "").Code, path)
+	}
+
+	// Account
+	w := call("POST", "/api/v1/auth/register", map[string]string{
+		"email": "margaret@example.com", "password": "violet-Harbor-42", "displayName": "Margaret",
+	}, "")
+	require.Equal(t, http.StatusCreated, w.Code)
+	user, err := env.Store.Users.GetByEmail(context.Background(), "margaret@example.com")
+	require.NoError(t, err)
+	user.EmailConfirmed = true
+	require.NoError(t, env.Store.Users.Update(context.Background(), user))
+
+	w = call("POST", "/api/v1/auth/login", map[string]string{"email": "margaret@example.com", "password": "violet-Harbor-42"}, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var login struct {
+		Token string `json:"token"`
+	}
+	decode(w, &login)
+	token := login.Token
+
+	assert.Equal(t, http.StatusOK, call("GET", "/api/v1/me", nil, token).Code)
+	assert.Equal(t, http.StatusOK, call("PUT", "/api/v1/profile", map[string]string{"displayName": "Maggie", "caregiverEmail": "carer@example.com"}, token).Code)
+	assert.Equal(t, http.StatusOK, call("GET", "/api/v1/profile/identities", nil, token).Code)
+	assert.Equal(t, http.StatusOK, call("POST", "/api/v1/auth/forgot-password", map[string]string{"email": "margaret@example.com"}, "").Code)
+
+	// Photos, people and memories
+	var upload bytes.Buffer
+	form := multipart.NewWriter(&upload)
+	part, _ := form.CreateFormFile("file", "lake.jpg")
+	part.Write([]byte("jpeg"))
+	form.Close()
+	req, _ := http.NewRequest("POST", "/api/v1/upload-photo", &upload)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var photo struct {
+		ID string `json:"id"`
+	}
+	decode(w, &photo)
+	assert.Equal(t, http.StatusOK, call("GET", "/api/v1/photos/"+photo.ID, nil, token).Code)
+
+	assert.Equal(t, http.StatusOK, call("GET", "/api/v1/people", nil, token).Code)
+	w = call("POST", "/api/v1/people", handlers.CreatePersonRequest{
+		FirstName: "Tom", LastName: "Hughes", Email: "tom@example.com", Phone: "555-0100", Relationship: "Son",
+	}, token)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var person handlers.PersonResponse
+	decode(w, &person)
+
+	w = call("POST", "/api/v1/memories", map[string]any{
+		"title": "Lake trip", "type": "story", "content": "Swimming at dawn",
+		"photoId": photo.ID, "peopleIds": []string{person.ID.String()}, "occurredOn": "2019-08-04",
+	}, token)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created struct{ Memory handlers.MemoryResponse }
+	decode(w, &created)
+	assert.Equal(t, http.StatusOK, call("GET", "/api/v1/memories", nil, token).Code)
+	assert.Equal(t, http.StatusOK, call("PUT", "/api/v1/memories/"+created.Memory.ID.String()+"/favorite", map[string]bool{"favorite": true}, token).Code)
+	// Memories added today are not resurfaced until tomorrow
+	assert.Equal(t, http.StatusNotFound, call("GET", "/api/v1/reminiscence/today", nil, token).Code)
+	assert.Equal(t, http.StatusOK, call("GET", "/api/v1/reminiscence?days=7", nil, token).Code)
+	assert.Equal(t, http.StatusNotFound, call("POST", "/api/v1/reminiscence/"+created.Memory.ID.String()+"/respond", map[string]string{"response": "engaged"}, token).Code)
+	assert.Equal(t, http.StatusOK, call("GET", "/api/v1/people", nil, token).Code)
+	assert.Equal(t, http.StatusOK, call("GET", "/api/v1/chat/history?limit=10", nil, token).Code)
+	assert.Equal(t, http.StatusOK, call("PUT", "/api/v1/people/"+person.ID.String()+"/contact-cadence", map[string]int{"everyDays": 7}, token).Code)
+	assert.Equal(t, http.StatusOK, call("GET", "/api/v1/reminders", nil, token).Code)
+	assert.Equal(t, http.StatusCreated, call("POST", "/api/v1/people/"+person.ID.String()+"/interactions", map[string]string{
+		"type": "visit", "notes": "Tea in the garden",
+	}, token).Code)
+	assert.Equal(t, http.StatusOK, call("GET", "/api/v1/people/"+person.ID.String()+"/interactions", nil, token).Code)
+	assert.Equal(t, http.StatusOK, call("GET", "/api/v1/insights?days=7", nil, token).Code)
+	assert.Equal(t, http.StatusOK, call("PUT", "/api/v1/people/"+person.ID.String()+"/dates", map[string]string{
+		"birthday": "1990-04-12", "anniversary": "2015-06-20",
+	}, token).Code)
+	assert.Equal(t, http.StatusOK, call("GET", "/api/v1/upcoming?days=365", nil, token).Code)
+
+	// Routines and check-ins
+	yesterday := time.Now().UTC().AddDate(0, 0, -1)
+	w = call("POST", "/api/v1/routines", map[string]any{
+		"kind": "medication", "title": "Blood pressure tablets", "instructions": "With water",
+		"recurrence": "FREQ=DAILY", "times": []string{"08:00", "20:00"}, "startsOn": yesterday.Format("2006-01-02"),
+	}, token)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var routine handlers.RoutineResponse
+	decode(w, &routine)
+	assert.Equal(t, http.StatusOK, call("GET", "/api/v1/routines", nil, token).Code)
+	assert.Equal(t, http.StatusOK, call("POST", "/api/v1/routines/"+routine.ID.String()+"/check-ins", map[string]any{
+		"scheduledAt": time.Date(yesterday.Year(), yesterday.Month(), yesterday.Day(), 8, 0, 0, 0, time.UTC), "status": "done",
+	}, token).Code)
+	assert.Equal(t, http.StatusOK, call("GET", "/api/v1/schedule?date="+yesterday.Format("2006-01-02"), nil, token).Code)
+	assert.Equal(t, http.StatusOK, call("GET", "/api/v1/adherence?days=7", nil, token).Code)
+	assert.Equal(t, http.StatusOK, call("PUT", "/api/v1/routines/"+routine.ID.String(), map[string]any{
+		"kind": "medication", "title": "Blood pressure tablets", "recurrence": "FREQ=DAILY", "times": []string{"09:00"},
+		"graceMinutes": 30,
+	}, token).Code)
+	assert.Equal(t, http.StatusOK, call("PUT", "/api/v1/profile", map[string]string{"displayName": "Maggie", "timezone": "Europe/London"}, token).Code)
+	assert.Equal(t, http.StatusOK, call("GET", "/api/v1/schedule", nil, token).Code)
+
+	// Calendar
+	w = call("POST", "/api/v1/calendar/feed", nil, token)
+	require.Equal(t, http.StatusOK, w.Code)
+	var feed handlers.CalendarFeedResponse
+	decode(w, &feed)
+	feedURL, err := url.Parse(feed.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, call("GET", feedURL.Path, nil, "").Code)
+	assert.Equal(t, http.StatusNotFound, call("GET", "/api/v1/calendar/feed/unknown.ics", nil, "").Code)
+
+	upload.Reset()
+	form = multipart.NewWriter(&upload)
+	part, _ = form.CreateFormFile("file", "calendar.ics")
+	part.Write([]byte("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:1@example.com\r\nSUMMARY:Concert\r\nDTSTART;VALUE=DATE:20240301\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"))
+	form.Close()
+	req, _ = http.NewRequest("POST", "/api/v1/calendar/import", &upload)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusOK, call("DELETE", "/api/v1/calendar/feed", nil, token).Code)
+
+	// Notifications
+	w = call("GET", "/api/v1/notifications", nil, token)
+	require.Equal(t, http.StatusOK, w.Code)
+	var notifications handlers.NotificationListResponse
+	decode(w, &notifications)
+	assert.Empty(t, notifications.Notifications)
+	assert.Equal(t, http.StatusOK, call("POST", "/api/v1/notifications/read-all", nil, token).Code)
+	assert.Equal(t, http.StatusNotFound, call("POST", "/api/v1/notifications/"+person.ID.String()+"/read", nil, token).Code)
+	assert.Equal(t, http.StatusOK, call("GET", "/api/v1/notifications/preferences", nil, token).Code)
+	assert.Equal(t, http.StatusOK, call("PUT", "/api/v1/notifications/preferences", map[string]any{
+		"preferences": []map[string]any{{"type": "reminder", "channel": "push", "enabled": false}},
+	}, token).Code)
+	assert.Equal(t, http.StatusInternalServerError, call("GET", "/api/v1/notifications/push-key", nil, token).Code)
+	assert.Equal(t, http.StatusNotFound, call("DELETE", "/api/v1/notifications/push-subscriptions?endpoint=https://push.example.com/1", nil, token).Code)
+
+	assert.Equal(t, http.StatusOK, call("DELETE", "/api/v1/routines/"+routine.ID.String(), nil, token).Code)
+
+	// Unsubscribe links are signed for the caregiver
+	assert.Equal(t, http.StatusBadRequest, call("POST", "/api/v1/weekly-digest/unsubscribe", map[string]string{"token": "forged"}, "").Code)
+
+	// Administration is closed to users not listed in ADMIN_EMAILS
+	assert.Equal(t, http.StatusForbidden, call("GET", "/api/v1/admin/emails", nil, token).Code)
+	assert.Equal(t, http.StatusForbidden, call("GET", "/api/v1/admin/email-templates", nil, token).Code)
+	assert.Equal(t, http.StatusForbidden, call("GET", "/api/v1/admin/jobs", nil, token).Code)
+
+	// Errors use the documented envelope too
+	assert.Equal(t, http.StatusUnauthorized, call("GET", "/api/v1/memories", nil, "").Code)
+	assert.Equal(t, http.StatusBadRequest, call("POST", "/api/v1/memories", map[string]string{"title": "No content"}, token).Code)
+	assert.Equal(t, http.StatusNotFound, call("GET", "/api/v1/photos/"+person.ID.String(), nil, token).Code)
+
+	assert.Equal(t, http.StatusOK, call("DELETE", "/api/v1/profile", nil, token).Code)
+}
+
+// TestLegacyRoutesAreDeprecated checks that only the unversioned aliases
+// announce their removal
+func TestLegacyRoutesAreDeprecated(t *testing.T) {
+	router := newTestRouter(t, testutils.NewTestEnv())
+	get := func(path string) http.Header {
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Header()
+	}
+
+	legacy := get("/memories")
+	assert.Equal(t, "@1792281600", legacy.Get("Deprecation"))
+	assert.Equal(t, "Fri, 30 Apr 2027 00:00:00 GMT", legacy.Get("Sunset"))
+	assert.Equal(t, `</api/v1/memories>; rel="successor-version"`, legacy.Get("Link"))
+
+	for _, path := range []string{"/api/v1/memories", "/api/v1/openapi.json", "/healthz"} {
+		assert.Empty(t, get(path).Get("Deprecation"), path)
+	}
+}
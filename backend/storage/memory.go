@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned for keys that were never stored
+var ErrNotFound = errors.New("file not found")
+
+// Memory keeps files in process memory, for tests
+type Memory struct {
+	mutex sync.RWMutex
+	files map[string]MemoryFile
+}
+
+// MemoryFile is a file held by Memory
+type MemoryFile struct {
+	Body        []byte
+	ContentType string
+}
+
+// NewMemory creates an empty in-memory storage
+func NewMemory() *Memory {
+	return &Memory{files: make(map[string]MemoryFile)}
+}
+
+// Put implements Storage
+func (m *Memory) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.files[key] = MemoryFile{Body: data, ContentType: contentType}
+	return nil
+}
+
+// PresignGet implements Storage with a fake URL that names the key
+func (m *Memory) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	if _, ok := m.Get(key); !ok {
+		return "", ErrNotFound
+	}
+	return "memory://" + url.PathEscape(key) + "?expires=" + expires.String(), nil
+}
+
+// Delete implements Storage
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.files, key)
+	return nil
+}
+
+// Check implements Storage; memory is always reachable
+func (m *Memory) Check(ctx context.Context) error {
+	return nil
+}
+
+// Get returns a stored file
+func (m *Memory) Get(key string) (MemoryFile, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	file, ok := m.files[key]
+	return file, ok
+}
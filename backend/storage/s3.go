@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3 stores files in an S3 bucket
+type S3 struct {
+	client *s3.Client
+	bucket string
+}
+
+// S3Config names the bucket and, optionally, static credentials
+type S3Config struct {
+	Region string
+	Bucket string
+	// AccessKeyID and SecretAccessKey replace the default AWS credential
+	// chain when set
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// NewS3 creates an S3 storage for the configured bucket
+func NewS3(ctx context.Context, s3Config S3Config) (*S3, error) {
+	opts := []func(*config.LoadOptions) error{config.WithRegion(s3Config.Region)}
+	if s3Config.AccessKeyID != "" {
+		opts = append(opts, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(s3Config.AccessKeyID, s3Config.SecretAccessKey, ""),
+		))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &S3{client: s3.NewFromConfig(cfg), bucket: s3Config.Bucket}, nil
+}
+
+// Put implements Storage
+func (s *S3) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      &s.bucket,
+		Key:         &key,
+		Body:        body,
+		ContentType: &contentType,
+	})
+	return err
+}
+
+// PresignGet implements Storage
+func (s *S3) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	presignClient := s3.NewPresignClient(s.client)
+	presigned, err := presignClient.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: &s.bucket,
+		Key:    &key,
+	}, func(opts *s3.PresignOptions) {
+		opts.Expires = expires
+	})
+	if err != nil {
+		return "", err
+	}
+	return presigned.URL, nil
+}
+
+// Delete implements Storage
+func (s *S3) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: &s.bucket,
+		Key:    &key,
+	})
+	return err
+}
+
+// Check implements Storage by confirming the bucket exists and is accessible
+func (s *S3) Check(ctx context.Context) error {
+	_, err := s.client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: &s.bucket})
+	return err
+}
@@ -0,0 +1,20 @@
+// Package storage keeps uploaded files outside the database
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Storage stores files by key and hands out temporary links to them
+type Storage interface {
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+	// PresignGet returns a URL that serves the file until expires has passed
+	PresignGet(ctx context.Context, key string, expires time.Duration) (string, error)
+	// Delete removes a file; deleting a file that is not there is not an
+	// error
+	Delete(ctx context.Context, key string) error
+	// Check reports whether the storage is reachable
+	Check(ctx context.Context) error
+}
@@ -1,22 +1,33 @@
 package testutils
 
 import (
+	"context"
+	"fmt"
 	"sync"
+	"testing"
 
+	"github.com/muneerlalji/Luma/mailer"
 	"github.com/muneerlalji/Luma/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+var _ utils.EmailService = (*EmailMock)(nil)
+
 // EmailMock provides a mock implementation of email sending for tests
 type EmailMock struct {
 	sentEmails []EmailData
+	sendError  error
 	mutex      sync.RWMutex
 }
 
-// EmailData represents the data of a sent email
+// EmailData represents the data of a sent email. Body is the plain text part.
 type EmailData struct {
-	To      string
-	Subject string
-	Body    string
+	To       string
+	Subject  string
+	Body     string
+	HTML     string
+	Template string
 }
 
 // NewEmailMock creates a new email mock instance
@@ -26,24 +37,34 @@ func NewEmailMock() *EmailMock {
 	}
 }
 
-// SendEmail implements the EmailService interface
-func (em *EmailMock) SendEmail(to, subject, body string) error {
+// SendEmail implements the EmailService interface. Emails must be rendered
+// from a template with both parts, so tests catch emails built by hand.
+func (em *EmailMock) SendEmail(ctx context.Context, email *utils.Email) error {
 	em.mutex.Lock()
 	defer em.mutex.Unlock()
 
+	if em.sendError != nil {
+		return em.sendError
+	}
+	if email.Template == "" || email.Text == "" || email.HTML == "" {
+		return fmt.Errorf("email %q to %s was not rendered from a template", email.Subject, email.To)
+	}
+
 	em.sentEmails = append(em.sentEmails, EmailData{
-		To:      to,
-		Subject: subject,
-		Body:    body,
+		To:       email.To,
+		Subject:  email.Subject,
+		Body:     email.Text,
+		HTML:     email.HTML,
+		Template: email.Template,
 	})
 	return nil
 }
 
-// SetupEmailMock sets up the email mock as the global email service
-func SetupEmailMock() *EmailMock {
-	mock := NewEmailMock()
-	utils.SetEmailService(mock)
-	return mock
+// SetSendError makes subsequent sends fail with err (nil restores delivery)
+func (em *EmailMock) SetSendError(err error) {
+	em.mutex.Lock()
+	defer em.mutex.Unlock()
+	em.sendError = err
 }
 
 // GetSentEmails returns all sent emails
@@ -87,6 +108,32 @@ func (em *EmailMock) FindEmailBySubject(subject string) []EmailData {
 	return found
 }
 
+// AssertRendered checks that email is the named template rendered in locale
+// with data
+func (em *EmailMock) AssertRendered(t testing.TB, email EmailData, name, locale string, data any) {
+	t.Helper()
+	message, err := mailer.Render(name, locale, data)
+	require.NoError(t, err)
+	assert.Equal(t, name, email.Template)
+	assert.Equal(t, message.Subject, email.Subject)
+	assert.Equal(t, message.Text, email.Body)
+	assert.Equal(t, message.HTML, email.HTML)
+}
+
+// FindEmailByTemplate finds emails rendered from the named template
+func (em *EmailMock) FindEmailByTemplate(name string) []EmailData {
+	em.mutex.RLock()
+	defer em.mutex.RUnlock()
+
+	var found []EmailData
+	for _, email := range em.sentEmails {
+		if email.Template == name {
+			found = append(found, email)
+		}
+	}
+	return found
+}
+
 // FindEmailByRecipient finds emails by recipient
 func (em *EmailMock) FindEmailByRecipient(to string) []EmailData {
 	em.mutex.RLock()
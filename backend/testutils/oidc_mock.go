@@ -0,0 +1,263 @@
+package testutils
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/muneerlalji/Luma/oidc"
+)
+
+// OIDCUser is the account the mock identity provider signs in as
+type OIDCUser struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// OIDCMock is a local stand-in OpenID Connect provider for tests. Its
+// authorization endpoint approves immediately as the current user.
+type OIDCMock struct {
+	server        *httptest.Server
+	mutex         sync.Mutex
+	key           *rsa.PrivateKey
+	keyID         string
+	ClientID      string
+	ClientSecret  string
+	user          OIDCUser
+	codes         map[string]oidcMockCode
+	nonceOverride *string
+}
+
+type oidcMockCode struct {
+	user          OIDCUser
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+}
+
+// NewOIDCMock starts a mock identity provider
+func NewOIDCMock() *OIDCMock {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	mock := &OIDCMock{
+		key:          key,
+		keyID:        uuid.New().String(),
+		ClientID:     "luma-test-client",
+		ClientSecret: "luma-test-secret",
+		user: OIDCUser{
+			Subject:       "mock-subject",
+			Email:         "oidc@example.com",
+			EmailVerified: true,
+			Name:          "OIDC User",
+		},
+		codes: make(map[string]oidcMockCode),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", mock.handleDiscovery)
+	mux.HandleFunc("/jwks", mock.handleJWKS)
+	mux.HandleFunc("/authorize", mock.handleAuthorize)
+	mux.HandleFunc("/token", mock.handleToken)
+	mock.server = httptest.NewServer(mux)
+
+	return mock
+}
+
+// Issuer returns the mock's issuer URL
+func (m *OIDCMock) Issuer() string {
+	return m.server.URL
+}
+
+// Config returns an oidc.Config for this provider under the given name
+func (m *OIDCMock) Config(name, redirectURL string) oidc.Config {
+	return oidc.Config{
+		Name:         name,
+		DisplayName:  "Mock Provider",
+		Issuer:       m.Issuer(),
+		ClientID:     m.ClientID,
+		ClientSecret: m.ClientSecret,
+		RedirectURL:  redirectURL,
+	}
+}
+
+// SetUser sets the account subsequent sign-ins authenticate as
+func (m *OIDCMock) SetUser(user OIDCUser) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.user = user
+}
+
+// SetNonceOverride makes issued ID tokens carry the given nonce instead of
+// the one requested, to simulate replayed tokens
+func (m *OIDCMock) SetNonceOverride(nonce string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.nonceOverride = &nonce
+}
+
+// Authorize follows an authorization URL as a browser would and returns the
+// code and state the provider redirects back with
+func (m *OIDCMock) Authorize(authorizationURL string) (code, state string, err error) {
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Get(authorizationURL)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return "", "", err
+	}
+	return location.Query().Get("code"), location.Query().Get("state"), nil
+}
+
+// SignIDToken signs arbitrary claims with the provider's published key
+func (m *OIDCMock) SignIDToken(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = m.keyID
+	return token.SignedString(m.key)
+}
+
+// Close shuts the mock provider down
+func (m *OIDCMock) Close() {
+	m.server.Close()
+}
+
+func (m *OIDCMock) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                                m.Issuer(),
+		"authorization_endpoint":                m.Issuer() + "/authorize",
+		"token_endpoint":                        m.Issuer() + "/token",
+		"jwks_uri":                              m.Issuer() + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (m *OIDCMock) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": m.keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(m.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(m.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (m *OIDCMock) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != m.ClientID || query.Get("response_type") != "code" ||
+		query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	code := uuid.New().String()
+	m.mutex.Lock()
+	m.codes[code] = oidcMockCode{
+		user:          m.user,
+		redirectURI:   query.Get("redirect_uri"),
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+	}
+	m.mutex.Unlock()
+
+	redirect, _ := url.Parse(query.Get("redirect_uri"))
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", query.Get("state"))
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (m *OIDCMock) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		oidcMockError(w, "invalid_request")
+		return
+	}
+	if r.PostForm.Get("client_id") != m.ClientID || r.PostForm.Get("client_secret") != m.ClientSecret {
+		oidcMockError(w, "invalid_client")
+		return
+	}
+
+	m.mutex.Lock()
+	code, ok := m.codes[r.PostForm.Get("code")]
+	delete(m.codes, r.PostForm.Get("code"))
+	nonceOverride := m.nonceOverride
+	m.mutex.Unlock()
+
+	if !ok || code.redirectURI != r.PostForm.Get("redirect_uri") {
+		oidcMockError(w, "invalid_grant")
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != code.codeChallenge {
+		oidcMockError(w, "invalid_grant")
+		return
+	}
+
+	nonce := code.nonce
+	if nonceOverride != nil {
+		nonce = *nonceOverride
+	}
+
+	now := time.Now()
+	idToken, err := m.SignIDToken(jwt.MapClaims{
+		"iss":            m.Issuer(),
+		"sub":            code.user.Subject,
+		"aud":            m.ClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"nonce":          nonce,
+		"email":          code.user.Email,
+		"email_verified": code.user.EmailVerified,
+		"name":           code.user.Name,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": uuid.New().String(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func oidcMockError(w http.ResponseWriter, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
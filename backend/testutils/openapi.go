@@ -0,0 +1,125 @@
+package testutils
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gin-gonic/gin"
+	"github.com/muneerlalji/Luma/api"
+)
+
+func init() {
+	// Streamed chat replies and calendar feeds are checked as plain text
+	openapi3filter.RegisterBodyDecoder("text/event-stream", openapi3filter.PlainBodyDecoder)
+	openapi3filter.RegisterBodyDecoder("text/calendar", openapi3filter.PlainBodyDecoder)
+}
+
+var openAPIOptions = &openapi3filter.Options{
+	IncludeResponseStatus: true,
+	AuthenticationFunc:    openapi3filter.NoopAuthenticationFunc,
+}
+
+// OpenAPIRouter matches requests to operations of the OpenAPI spec
+func OpenAPIRouter(t testing.TB) routers.Router {
+	t.Helper()
+	spec, err := api.Spec()
+	if err != nil {
+		t.Fatalf("load OpenAPI spec: %v", err)
+	}
+	router, err := gorillamux.NewRouter(withPathServers(spec))
+	if err != nil {
+		t.Fatalf("route OpenAPI spec: %v", err)
+	}
+	return router
+}
+
+// withPathServers returns a copy of spec in which every path lists its
+// servers. gorillamux keeps using the servers of the last path that overrode
+// them, rather than returning to the document servers.
+func withPathServers(spec *openapi3.T) *openapi3.T {
+	paths := openapi3.NewPaths()
+	for path, item := range spec.Paths.Map() {
+		if len(item.Servers) == 0 {
+			copied := *item
+			copied.Servers = spec.Servers
+			item = &copied
+		}
+		paths.Set(path, item)
+	}
+	copied := *spec
+	copied.Paths = paths
+	return &copied
+}
+
+// OpenAPIValidator returns middleware that fails t when a route is missing
+// from the OpenAPI spec, a request the API accepted does not match it, or
+// any response does not match it. Rejected requests are not checked so tests
+// can still send invalid input.
+func OpenAPIValidator(t testing.TB) gin.HandlerFunc {
+	router := OpenAPIRouter(t)
+	return func(c *gin.Context) {
+		var body []byte
+		if c.Request.Body != nil {
+			body, _ = io.ReadAll(c.Request.Body)
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		c.Next()
+
+		req := c.Request.Clone(c.Request.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		route, pathParams, err := router.FindRoute(req)
+		if err != nil {
+			t.Errorf("%s %s is not in the OpenAPI spec: %v", req.Method, req.URL.Path, err)
+			return
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    req,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    openAPIOptions,
+		}
+		status := recorder.Status()
+		if status < http.StatusBadRequest {
+			if err := openapi3filter.ValidateRequest(req.Context(), input); err != nil {
+				t.Errorf("%s %s was accepted but does not match the OpenAPI spec: %v", req.Method, req.URL.Path, err)
+			}
+		}
+
+		responseInput := &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 status,
+			Header:                 recorder.Header(),
+			Options:                openAPIOptions,
+		}
+		responseInput.SetBodyBytes(recorder.body.Bytes())
+		if err := openapi3filter.ValidateResponse(req.Context(), responseInput); err != nil {
+			t.Errorf("%s %s answered %d not matching the OpenAPI spec: %v", req.Method, req.URL.Path, status, err)
+		}
+	}
+}
+
+// bodyRecorder keeps a copy of the response body
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
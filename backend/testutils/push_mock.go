@@ -0,0 +1,74 @@
+package testutils
+
+import (
+	"context"
+	"sync"
+
+	"github.com/muneerlalji/Luma/webpush"
+)
+
+var _ webpush.Sender = (*PushMock)(nil)
+
+// PushMockKey is the application server key the push mock reports
+const PushMockKey = "BPushMockPublicKey"
+
+// PushMock records push messages instead of sending them
+type PushMock struct {
+	sent       []PushData
+	sendErrors map[string]error
+	attempts   map[string]int
+	mutex      sync.RWMutex
+}
+
+// PushData is a message sent to one subscription
+type PushData struct {
+	Subscription webpush.Subscription
+	Message      webpush.Message
+}
+
+// NewPushMock creates a push mock that delivers every message
+func NewPushMock() *PushMock {
+	return &PushMock{sendErrors: make(map[string]error), attempts: make(map[string]int)}
+}
+
+// PublicKey implements webpush.Sender
+func (pm *PushMock) PublicKey() string {
+	return PushMockKey
+}
+
+// Send implements webpush.Sender
+func (pm *PushMock) Send(ctx context.Context, subscription webpush.Subscription, message webpush.Message) error {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	pm.attempts[subscription.Endpoint]++
+	if err := pm.sendErrors[subscription.Endpoint]; err != nil {
+		return err
+	}
+	pm.sent = append(pm.sent, PushData{Subscription: subscription, Message: message})
+	return nil
+}
+
+// SetSendError makes sends to endpoint fail with err (nil restores delivery)
+func (pm *PushMock) SetSendError(endpoint string, err error) {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+	pm.sendErrors[endpoint] = err
+}
+
+// Attempts returns how many sends to endpoint were tried, failed ones included
+func (pm *PushMock) Attempts(endpoint string) int {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+	return pm.attempts[endpoint]
+}
+
+// GetSent returns the messages sent so far
+func (pm *PushMock) GetSent() []PushData {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+
+	sent := make([]PushData, len(pm.sent))
+	copy(sent, pm.sent)
+	return sent
+}
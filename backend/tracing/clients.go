@@ -0,0 +1,105 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/muneerlalji/Luma/llm"
+	"github.com/muneerlalji/Luma/storage"
+	"github.com/muneerlalji/Luma/utils"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Transport wraps base, or http.DefaultTransport when nil, so outbound
+// requests get a client span and carry the trace context
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return otelhttp.NewTransport(base)
+}
+
+// Storage wraps s so each operation gets a span
+func Storage(s storage.Storage) storage.Storage {
+	return &tracedStorage{next: s}
+}
+
+type tracedStorage struct {
+	next storage.Storage
+}
+
+func (s *tracedStorage) Put(ctx context.Context, key string, body io.Reader, contentType string) (err error) {
+	ctx, span := Start(ctx, "storage.put", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("storage.content_type", contentType)))
+	defer func() { End(span, err) }()
+	return s.next.Put(ctx, key, body, contentType)
+}
+
+func (s *tracedStorage) PresignGet(ctx context.Context, key string, expires time.Duration) (url string, err error) {
+	ctx, span := Start(ctx, "storage.presign_get")
+	defer func() { End(span, err) }()
+	return s.next.PresignGet(ctx, key, expires)
+}
+
+func (s *tracedStorage) Delete(ctx context.Context, key string) (err error) {
+	ctx, span := Start(ctx, "storage.delete", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() { End(span, err) }()
+	return s.next.Delete(ctx, key)
+}
+
+func (s *tracedStorage) Check(ctx context.Context) (err error) {
+	ctx, span := Start(ctx, "storage.check", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() { End(span, err) }()
+	return s.next.Check(ctx)
+}
+
+// LLM wraps client so each call gets a span, with an event when the first
+// text of a streamed reply arrives. Prompts and replies are not recorded.
+func LLM(client llm.Client) llm.Client {
+	return &tracedLLM{next: client}
+}
+
+type tracedLLM struct {
+	next llm.Client
+}
+
+func (c *tracedLLM) Complete(ctx context.Context, prompt string, maxTokens int) (reply string, err error) {
+	ctx, span := Start(ctx, "llm.complete", trace.WithAttributes(attribute.Int("llm.max_tokens", maxTokens)))
+	defer func() { End(span, err) }()
+	return c.next.Complete(ctx, prompt, maxTokens)
+}
+
+func (c *tracedLLM) Stream(ctx context.Context, prompt string, maxTokens int, onText func(string)) (err error) {
+	ctx, span := Start(ctx, "llm.stream", trace.WithAttributes(attribute.Int("llm.max_tokens", maxTokens)))
+	defer func() { End(span, err) }()
+
+	first := true
+	return c.next.Stream(ctx, prompt, maxTokens, func(text string) {
+		if first {
+			first = false
+			span.AddEvent("first_token")
+		}
+		onText(text)
+	})
+}
+
+// Email wraps service so each send gets a span. Recipients and content are
+// not recorded.
+func Email(service utils.EmailService) utils.EmailService {
+	return &tracedEmail{next: service}
+}
+
+type tracedEmail struct {
+	next utils.EmailService
+}
+
+func (e *tracedEmail) SendEmail(ctx context.Context, email *utils.Email) (err error) {
+	ctx, span := Start(ctx, "email.send", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("email.template", email.Template)))
+	defer func() { End(span, err) }()
+	return e.next.SendEmail(ctx, email)
+}
@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const spanKey = "tracing:span"
+
+// InstrumentDB adds a span for every query made through db with GORM
+// callbacks. Statements are recorded with placeholders, never values.
+func InstrumentDB(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("*").Register("tracing:before_create", startQuery("create")),
+		cb.Create().After("*").Register("tracing:after_create", endQuery),
+		cb.Query().Before("*").Register("tracing:before_query", startQuery("query")),
+		cb.Query().After("*").Register("tracing:after_query", endQuery),
+		cb.Update().Before("*").Register("tracing:before_update", startQuery("update")),
+		cb.Update().After("*").Register("tracing:after_update", endQuery),
+		cb.Delete().Before("*").Register("tracing:before_delete", startQuery("delete")),
+		cb.Delete().After("*").Register("tracing:after_delete", endQuery),
+		cb.Row().Before("*").Register("tracing:before_row", startQuery("row")),
+		cb.Row().After("*").Register("tracing:after_row", endQuery),
+		cb.Raw().Before("*").Register("tracing:before_raw", startQuery("raw")),
+		cb.Raw().After("*").Register("tracing:after_raw", endQuery),
+	)
+}
+
+func startQuery(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		ctx, span := tracer.Start(tx.Statement.Context, "db."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system.name", "postgresql"),
+				attribute.String("db.operation.name", operation),
+			))
+		tx.Statement.Context = ctx
+		tx.InstanceSet(spanKey, span)
+	}
+}
+
+func endQuery(tx *gorm.DB) {
+	value, _ := tx.InstanceGet(spanKey)
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+
+	if tx.Statement.Table != "" {
+		span.SetAttributes(attribute.String("db.collection.name", tx.Statement.Table))
+	}
+	span.SetAttributes(
+		attribute.String("db.query.text", tx.Statement.SQL.String()),
+		attribute.Int64("db.response.returned_rows", tx.RowsAffected),
+	)
+	err := tx.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+	End(span, err)
+}
@@ -0,0 +1,105 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/muneerlalji/Luma/llm"
+	"github.com/muneerlalji/Luma/storage"
+	"github.com/muneerlalji/Luma/testutils"
+	"github.com/muneerlalji/Luma/tracing"
+	"github.com/muneerlalji/Luma/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// The global provider can only be delegated to once, so every test shares
+// one exporter and resets it
+var exporter = tracetest.NewInMemoryExporter()
+
+func TestMain(m *testing.M) {
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	os.Exit(m.Run())
+}
+
+func spanNames() []string {
+	var names []string
+	for _, span := range exporter.GetSpans() {
+		names = append(names, span.Name)
+	}
+	return names
+}
+
+func TestSetup(t *testing.T) {
+	shutdown, err := tracing.Setup(context.Background(), tracing.Config{Exporter: tracing.ExporterNone})
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	_, err = tracing.Setup(context.Background(), tracing.Config{Exporter: "jaeger"})
+	assert.Error(t, err)
+}
+
+func TestClients_ParentedToCaller(t *testing.T) {
+	exporter.Reset()
+	ctx, parent := tracing.Start(context.Background(), "request")
+
+	s := tracing.Storage(storage.NewMemory())
+	require.NoError(t, s.Put(ctx, "photo", strings.NewReader("jpeg"), "image/jpeg"))
+
+	email := testutils.NewEmailMock()
+	email.SetSendError(errors.New("smtp down"))
+	assert.Error(t, tracing.Email(email).SendEmail(ctx, &utils.Email{To: "someone@example.com", Subject: "subject", Text: "body"}))
+	parent.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 3)
+	assert.Equal(t, []string{"storage.put", "email.send", "request"}, spanNames())
+	for _, span := range spans[:2] {
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+	}
+	assert.Equal(t, codes.Error, spans[1].Status.Code)
+	for _, attr := range spans[1].Attributes {
+		assert.NotContains(t, attr.Value.Emit(), "someone@example.com")
+	}
+}
+
+func TestLLM_StreamPropagatesTraceContext(t *testing.T) {
+	exporter.Reset()
+	_, err := tracing.Setup(context.Background(), tracing.Config{})
+	require.NoError(t, err)
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		fmt.Fprint(w, `data: {"type":"content_block_delta","delta":{"type":"text_delta","text":"Hi"}}`+"\n\n")
+	}))
+	defer server.Close()
+
+	claude := llm.NewClaude("test-key", server.URL)
+	claude.HTTPClient.Transport = tracing.Transport(nil)
+	client := tracing.LLM(claude)
+
+	require.NoError(t, client.Stream(context.Background(), "hello", 10, func(string) {}))
+
+	assert.Contains(t, spanNames(), "llm.stream")
+	var stream sdktrace.ReadOnlySpan
+	for _, span := range exporter.GetSpans().Snapshots() {
+		if span.Name() == "llm.stream" {
+			stream = span
+		}
+	}
+	require.NotNil(t, stream)
+	require.Len(t, stream.Events(), 1)
+	assert.Equal(t, "first_token", stream.Events()[0].Name)
+	assert.Contains(t, traceparent, stream.SpanContext().TraceID().String())
+}
@@ -1,46 +1,55 @@
 package utils
 
 import (
+	"context"
 	"fmt"
-	"os"
-	"strconv"
 	"time"
 
 	gomail "gopkg.in/mail.v2"
 )
 
+// Email is a message ready to send, with a plain text body and optionally
+// an HTML alternative
+type Email struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	// Template names the template the email was rendered from, if any
+	Template string
+}
+
 // EmailService defines the interface for email operations
 type EmailService interface {
-	SendEmail(to, subject, body string) error
+	SendEmail(ctx context.Context, email *Email) error
 }
 
 // DefaultEmailService implements EmailService using SMTP
-type DefaultEmailService struct{}
-
-// SendEmail sends an email using SMTP configuration
-func (s *DefaultEmailService) SendEmail(to, subject, body string) error {
-	smtpHost := os.Getenv("SMTP_HOST")
-	smtpPort := os.Getenv("SMTP_PORT")
-	smtpUser := os.Getenv("SMTP_USER")
-	smtpPass := os.Getenv("SMTP_PASS")
-	smtpFrom := os.Getenv("SMTP_FROM")
-
-	if smtpHost == "" || smtpPort == "" || smtpUser == "" || smtpPass == "" || smtpFrom == "" {
-		return fmt.Errorf("SMTP configuration is incomplete")
-	}
+type DefaultEmailService struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	From     string
+}
 
-	port, err := strconv.Atoi(smtpPort)
-	if err != nil {
-		return fmt.Errorf("invalid SMTP_PORT: %v", err)
+// SendEmail sends an email using SMTP configuration, as multipart/alternative
+// when it has an HTML body
+func (s *DefaultEmailService) SendEmail(ctx context.Context, email *Email) error {
+	if s.Host == "" {
+		return fmt.Errorf("SMTP is not configured")
 	}
 
 	m := gomail.NewMessage()
-	m.SetHeader("From", smtpFrom)
-	m.SetHeader("To", to)
-	m.SetHeader("Subject", subject)
-	m.SetBody("text/plain", body)
+	m.SetHeader("From", s.From)
+	m.SetHeader("To", email.To)
+	m.SetHeader("Subject", email.Subject)
+	m.SetBody("text/plain", email.Text)
+	if email.HTML != "" {
+		m.AddAlternative("text/html", email.HTML)
+	}
 
-	d := gomail.NewDialer(smtpHost, port, smtpUser, smtpPass)
+	d := gomail.NewDialer(s.Host, s.Port, s.User, s.Password)
 	d.Timeout = 30 * time.Second
 	d.SSL = true
 
@@ -49,21 +58,3 @@ func (s *DefaultEmailService) SendEmail(to, subject, body string) error {
 	}
 	return nil
 }
-
-// Global email service instance
-var emailService EmailService = &DefaultEmailService{}
-
-// SetEmailService sets the global email service (useful for testing)
-func SetEmailService(service EmailService) {
-	emailService = service
-}
-
-// GetEmailService returns the current email service
-func GetEmailService() EmailService {
-	return emailService
-}
-
-// SendEmail is a convenience function that uses the global email service
-func SendEmail(to, subject, body string) error {
-	return emailService.SendEmail(to, subject, body)
-}
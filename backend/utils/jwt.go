@@ -2,8 +2,6 @@ package utils
 
 import (
 	"errors"
-	"log"
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -16,11 +14,13 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-// GenerateToken creates a new JWT token for a user
-func GenerateToken(userID uuid.UUID, email string) (string, error) {
-	secret := os.Getenv("JWT_SECRET")
+// errNoSecret is returned when tokens are used without a signing secret
+var errNoSecret = errors.New("JWT secret is not set")
+
+// GenerateToken creates a new JWT token for a user signed with secret
+func GenerateToken(secret string, userID uuid.UUID, email string) (string, error) {
 	if secret == "" {
-		log.Fatal("JWT_SECRET is not set")
+		return "", errNoSecret
 	}
 
 	claims := Claims{
@@ -37,11 +37,10 @@ func GenerateToken(userID uuid.UUID, email string) (string, error) {
 	return token.SignedString([]byte(secret))
 }
 
-// ValidateToken validates a JWT token and returns the claims
-func ValidateToken(tokenString string) (*Claims, error) {
-	secret := os.Getenv("JWT_SECRET")
+// ValidateToken validates a JWT token signed with secret and returns the claims
+func ValidateToken(secret, tokenString string) (*Claims, error) {
 	if secret == "" {
-		log.Fatal("JWT_SECRET is not set")
+		return nil, errNoSecret
 	}
 
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
@@ -0,0 +1,175 @@
+package utils
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+// commonPasswords is the bundled offline list of common and breached passwords
+var commonPasswords = parseCommonPasswords(commonPasswordsFile)
+
+func parseCommonPasswords(contents string) map[string]struct{} {
+	passwords := make(map[string]struct{})
+	for _, line := range strings.Split(contents, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		passwords[strings.ToLower(line)] = struct{}{}
+	}
+	return passwords
+}
+
+// Password violation codes, stable for clients to localize
+const (
+	PasswordTooShort         = "too_short"
+	PasswordTooLong          = "too_long"
+	PasswordMissingUpper     = "missing_uppercase"
+	PasswordMissingLower     = "missing_lowercase"
+	PasswordMissingDigit     = "missing_digit"
+	PasswordMissingSymbol    = "missing_symbol"
+	PasswordContainsPersonal = "contains_personal_info"
+	PasswordTooCommon        = "too_common"
+)
+
+// PasswordViolation describes one way a password fails the policy
+type PasswordViolation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicy describes the requirements passwords must meet
+type PasswordPolicy struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// RejectCommon rejects passwords from the bundled common password list
+	RejectCommon bool
+}
+
+// DefaultPasswordPolicy follows current NIST guidance: favour length and
+// reject known passwords rather than forcing character classes. MaxLength is
+// bcrypt's input limit.
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:    8,
+		MaxLength:    72,
+		RejectCommon: true,
+	}
+}
+
+// Validate checks password against the policy. personal holds values the
+// password must not contain, such as the user's email and display name.
+// It returns every violation so clients can show them all at once.
+func (p PasswordPolicy) Validate(password string, personal ...string) []PasswordViolation {
+	var violations []PasswordViolation
+	add := func(code, message string) {
+		violations = append(violations, PasswordViolation{Code: code, Message: message})
+	}
+
+	length := len([]rune(password))
+	if length < p.MinLength {
+		add(PasswordTooShort, fmt.Sprintf("Password must be at least %d characters long", p.MinLength))
+	}
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		add(PasswordTooLong, fmt.Sprintf("Password must be at most %d bytes long", p.MaxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireUpper && !hasUpper {
+		add(PasswordMissingUpper, "Password must contain an uppercase letter")
+	}
+	if p.RequireLower && !hasLower {
+		add(PasswordMissingLower, "Password must contain a lowercase letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		add(PasswordMissingDigit, "Password must contain a number")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		add(PasswordMissingSymbol, "Password must contain a symbol")
+	}
+
+	if containsPersonalInfo(password, personal) {
+		add(PasswordContainsPersonal, "Password must not contain your email or name")
+	}
+
+	if p.RejectCommon && IsCommonPassword(password) {
+		add(PasswordTooCommon, "Password is too common or has appeared in a data breach")
+	}
+
+	return violations
+}
+
+// IsCommonPassword reports whether password, or its core once leading and
+// trailing digits and symbols are stripped ("Password123!" -> "password"),
+// is on the bundled list
+func IsCommonPassword(password string) bool {
+	lower := strings.ToLower(password)
+	if _, found := commonPasswords[lower]; found {
+		return true
+	}
+
+	core := strings.TrimFunc(lower, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if core == "" {
+		return false
+	}
+	_, found := commonPasswords[core]
+	return found
+}
+
+// minPersonalInfoLength ignores fragments too short to be meaningful, so a
+// user named "Al" can still use passwords containing "al"
+const minPersonalInfoLength = 3
+
+// containsPersonalInfo reports whether password contains any of the values
+// or their individual words. Only the local part of email addresses is
+// considered, since domains like "gmail.com" say little about the user.
+func containsPersonalInfo(password string, values []string) bool {
+	lower := strings.ToLower(password)
+
+	var fragments []string
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if at := strings.LastIndex(value, "@"); at != -1 {
+			value = value[:at]
+		}
+		if value == "" {
+			continue
+		}
+
+		words := strings.FieldsFunc(value, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		fragments = append(fragments, value, strings.Join(words, ""))
+		fragments = append(fragments, words...)
+	}
+
+	for _, fragment := range fragments {
+		if len([]rune(fragment)) >= minPersonalInfoLength && strings.Contains(lower, fragment) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/muneerlalji/Luma/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func violationCodes(violations []utils.PasswordViolation) []string {
+	codes := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		codes = append(codes, violation.Code)
+	}
+	return codes
+}
+
+func TestPasswordPolicy_Default(t *testing.T) {
+	policy := utils.DefaultPasswordPolicy()
+
+	assert.Empty(t, policy.Validate("violet-Harbor-42", "mary@example.com", "Mary Smith"))
+	assert.Equal(t, []string{utils.PasswordTooShort}, violationCodes(policy.Validate("x7#kQ", "mary@example.com")))
+	assert.Contains(t, violationCodes(policy.Validate("", "mary@example.com")), utils.PasswordTooShort)
+	assert.Contains(t, violationCodes(policy.Validate(string(make([]byte, 73)))), utils.PasswordTooLong)
+}
+
+func TestPasswordPolicy_Complexity(t *testing.T) {
+	policy := utils.DefaultPasswordPolicy()
+	policy.RequireUpper = true
+	policy.RequireLower = true
+	policy.RequireDigit = true
+	policy.RequireSymbol = true
+
+	assert.Equal(t, []string{
+		utils.PasswordMissingUpper,
+		utils.PasswordMissingDigit,
+		utils.PasswordMissingSymbol,
+	}, violationCodes(policy.Validate("lanternharbour")))
+	assert.Empty(t, policy.Validate("Lantern-harbour9"))
+}
+
+func TestPasswordPolicy_PersonalInfo(t *testing.T) {
+	policy := utils.DefaultPasswordPolicy()
+
+	for _, password := range []string{
+		"margaret.h1954",     // email local part
+		"iloveGRANDMAMAGGIE", // display name words
+		"xx-maggiejones-xx",  // display name without spaces
+	} {
+		assert.Contains(t, violationCodes(policy.Validate(password, "margaret.h@example.com", "Maggie Jones")),
+			utils.PasswordContainsPersonal, password)
+	}
+
+	// The email domain and very short name fragments are ignored
+	assert.Empty(t, policy.Validate("example-garden-77", "al@example.com", "Al Ng"))
+}
+
+func TestIsCommonPassword(t *testing.T) {
+	assert.True(t, utils.IsCommonPassword("password"))
+	assert.True(t, utils.IsCommonPassword("Password123!"))
+	assert.True(t, utils.IsCommonPassword("123456789"))
+	assert.True(t, utils.IsCommonPassword("2024Sunshine!"))
+	assert.False(t, utils.IsCommonPassword("violet-Harbor-42"))
+
+	codes := violationCodes(utils.DefaultPasswordPolicy().Validate("qwerty123"))
+	assert.Equal(t, []string{utils.PasswordTooCommon}, codes)
+}
@@ -0,0 +1,109 @@
+package webpush
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	// recordSize is the size of the single record a message is encrypted
+	// into; push services accept at most 4096 octets of body
+	recordSize = 4096
+	saltSize   = 16
+	// headerSize is the salt, the record size, the key ID length and the
+	// uncompressed P-256 public key used as the key ID
+	headerSize = saltSize + 4 + 1 + 65
+	// MaxPayloadSize is the largest payload that fits in a message once the
+	// header, the padding delimiter and the GCM tag are added
+	MaxPayloadSize = recordSize - headerSize - 1 - 16
+)
+
+// Encrypt encrypts payload for the subscription with the aes128gcm content
+// coding of RFC 8188, keyed as RFC 8291 describes, using a new key pair and
+// salt for each message
+func Encrypt(payload []byte, subscription Subscription) ([]byte, error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return encrypt(payload, subscription, key, salt)
+}
+
+func encrypt(payload []byte, subscription Subscription, key *ecdh.PrivateKey, salt []byte) ([]byte, error) {
+	if len(payload) > MaxPayloadSize {
+		return nil, ErrPayloadTooLarge
+	}
+	uaPublicBytes, err := decodeKey(subscription.P256dh)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(uaPublicBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	authSecret, err := decodeKey(subscription.Auth)
+	if err != nil || len(authSecret) == 0 {
+		return nil, errors.New("invalid auth secret")
+	}
+
+	sharedSecret, err := key.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := key.PublicKey().Bytes()
+
+	// The input keying material mixes the shared secret with the
+	// subscription's auth secret and both public keys
+	keyInfo := append([]byte("WebPush: info\x00"), uaPublicBytes...)
+	keyInfo = append(keyInfo, asPublic...)
+	ikm, err := expand(authSecret, sharedSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	contentKey, err := expand(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := expand(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(contentKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	message := make([]byte, 0, headerSize+len(payload)+1+gcm.Overhead())
+	message = append(message, salt...)
+	message = binary.BigEndian.AppendUint32(message, recordSize)
+	message = append(message, byte(len(asPublic)))
+	message = append(message, asPublic...)
+	// 0x02 marks the last record; no further padding is added
+	plaintext := append(append([]byte{}, payload...), 0x02)
+	return gcm.Seal(message, nonce, plaintext, nil), nil
+}
+
+// expand derives length bytes from secret with HKDF-SHA-256
+func expand(salt, secret, info []byte, length int) ([]byte, error) {
+	prk, err := hkdf.Extract(sha256.New, secret, salt)
+	if err != nil {
+		return nil, err
+	}
+	return hkdf.Expand(sha256.New, prk, string(info), length)
+}
@@ -0,0 +1,225 @@
+// Package webpush sends Web Push messages (RFC 8030) to browsers, with the
+// payload encrypted for the subscription (RFC 8291) and the sender
+// identified by a VAPID key (RFC 8292).
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrNotConfigured is returned when no VAPID keys were provided
+var ErrNotConfigured = errors.New("web push is not configured")
+
+// ErrGone is returned when the push service no longer knows the
+// subscription, because it expired or the user revoked it. The subscription
+// should be deleted.
+var ErrGone = errors.New("push subscription is gone")
+
+// ErrPayloadTooLarge is returned for payloads that do not fit in a message
+var ErrPayloadTooLarge = errors.New("push payload is too large")
+
+// vapidTokenTTL is how long the signed VAPID token of a request is valid;
+// push services reject tokens valid for more than a day
+const vapidTokenTTL = 12 * time.Hour
+
+// Urgency tells the push service how soon to deliver a message to a device
+// saving battery (RFC 8030 section 5.3)
+const (
+	UrgencyLow    = "low"
+	UrgencyNormal = "normal"
+	UrgencyHigh   = "high"
+)
+
+// Subscription is where a browser receives push messages, with the keys
+// from PushSubscription.toJSON() written in base64url
+type Subscription struct {
+	Endpoint string
+	P256dh   string
+	Auth     string
+}
+
+// Message is a push message to deliver
+type Message struct {
+	Payload []byte
+	// TTL is how long the push service keeps the message for a device that
+	// is offline; zero means deliver now or never
+	TTL     time.Duration
+	Urgency string
+	// Topic, when set, replaces an undelivered message with the same topic
+	Topic string
+}
+
+// Sender delivers push messages
+type Sender interface {
+	// PublicKey is the application server key browsers subscribe with, in
+	// base64url, or "" when push is not configured
+	PublicKey() string
+	Send(ctx context.Context, subscription Subscription, message Message) error
+}
+
+// Config holds the VAPID key pair, in base64url as written by GenerateKeys,
+// and the contact URL push services may use to reach the sender
+type Config struct {
+	PublicKey  string
+	PrivateKey string
+	// Subject is a mailto: or https: URL
+	Subject string
+}
+
+// Client is a Sender that posts messages to push services over HTTP
+type Client struct {
+	HTTPClient *http.Client
+	publicKey  string
+	subject    string
+	key        *ecdsa.PrivateKey
+	now        func() time.Time
+}
+
+var _ Sender = (*Client)(nil)
+
+// New returns a client for the key pair in config. A client without keys
+// fails every send with ErrNotConfigured.
+func New(config Config) (*Client, error) {
+	client := &Client{HTTPClient: &http.Client{Timeout: 30 * time.Second}, now: time.Now}
+	if config.PublicKey == "" && config.PrivateKey == "" {
+		return client, nil
+	}
+
+	private, err := decodeKey(config.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("VAPID private key: %w", err)
+	}
+	key, err := ecdh.P256().NewPrivateKey(private)
+	if err != nil {
+		return nil, fmt.Errorf("VAPID private key: %w", err)
+	}
+	public, err := decodeKey(config.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("VAPID public key: %w", err)
+	}
+	if !bytes.Equal(public, key.PublicKey().Bytes()) {
+		return nil, errors.New("VAPID public key does not match the private key")
+	}
+	if !strings.HasPrefix(config.Subject, "mailto:") && !strings.HasPrefix(config.Subject, "https:") {
+		return nil, errors.New("VAPID subject must be a mailto: or https: URL")
+	}
+
+	client.publicKey = base64.RawURLEncoding.EncodeToString(public)
+	client.subject = config.Subject
+	client.key = signingKey(key)
+	return client, nil
+}
+
+// GenerateKeys returns a new VAPID key pair in base64url
+func GenerateKeys() (publicKey, privateKey string, err error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()),
+		base64.RawURLEncoding.EncodeToString(key.Bytes()), nil
+}
+
+// signingKey converts a P-256 key to the form ES256 signs with
+func signingKey(key *ecdh.PrivateKey) *ecdsa.PrivateKey {
+	// The public key is 0x04 followed by the X and Y coordinates
+	point := key.PublicKey().Bytes()
+	return &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(point[1:33]),
+			Y:     new(big.Int).SetBytes(point[33:]),
+		},
+		D: new(big.Int).SetBytes(key.Bytes()),
+	}
+}
+
+// PublicKey implements Sender
+func (c *Client) PublicKey() string {
+	return c.publicKey
+}
+
+// Send implements Sender
+func (c *Client) Send(ctx context.Context, subscription Subscription, message Message) error {
+	if c.key == nil {
+		return ErrNotConfigured
+	}
+
+	endpoint, err := url.Parse(subscription.Endpoint)
+	if err != nil || endpoint.Scheme != "https" || endpoint.Host == "" {
+		return fmt.Errorf("invalid push endpoint %q", subscription.Endpoint)
+	}
+	body, err := Encrypt(message.Payload, subscription)
+	if err != nil {
+		return err
+	}
+	token, err := c.vapidToken(endpoint.Scheme + "://" + endpoint.Host)
+	if err != nil {
+		return fmt.Errorf("sign VAPID token: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "vapid t="+token+", k="+c.publicKey)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(message.TTL/time.Second)))
+	if message.Urgency != "" {
+		req.Header.Set("Urgency", message.Urgency)
+	}
+	if message.Topic != "" {
+		req.Header.Set("Topic", message.Topic)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("push message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrGone
+	case resp.StatusCode == http.StatusRequestEntityTooLarge:
+		return ErrPayloadTooLarge
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("push service answered %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+}
+
+// vapidToken signs the JWT that identifies the sender to the push service
+// at audience
+func (c *Client) vapidToken(audience string) (string, error) {
+	claims := jwt.MapClaims{
+		"aud": audience,
+		"exp": c.now().Add(vapidTokenTTL).Unix(),
+		"sub": c.subject,
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodES256, claims).SignedString(c.key)
+}
+
+// decodeKey reads a base64url key, with or without padding
+func decodeKey(key string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(strings.TrimSpace(key), "="))
+}
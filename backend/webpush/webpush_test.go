@@ -0,0 +1,212 @@
+package webpush
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustDecode(t *testing.T, value string) []byte {
+	t.Helper()
+	decoded, err := decodeKey(value)
+	require.NoError(t, err)
+	return decoded
+}
+
+// browser is the receiving side of a subscription
+type browser struct {
+	key        *ecdh.PrivateKey
+	authSecret []byte
+}
+
+func newBrowser(t *testing.T) *browser {
+	t.Helper()
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	authSecret := make([]byte, 16)
+	rand.Read(authSecret)
+	return &browser{key: key, authSecret: authSecret}
+}
+
+func (b *browser) subscription(endpoint string) Subscription {
+	return Subscription{
+		Endpoint: endpoint,
+		P256dh:   base64.RawURLEncoding.EncodeToString(b.key.PublicKey().Bytes()),
+		Auth:     base64.RawURLEncoding.EncodeToString(b.authSecret),
+	}
+}
+
+// decrypt undoes Encrypt as a browser would
+func (b *browser) decrypt(t *testing.T, message []byte) []byte {
+	t.Helper()
+	require.Greater(t, len(message), headerSize)
+	salt := message[:saltSize]
+	assert.Equal(t, uint32(recordSize), binary.BigEndian.Uint32(message[saltSize:]))
+	require.Equal(t, byte(65), message[saltSize+4])
+	senderPublic, err := ecdh.P256().NewPublicKey(message[saltSize+5 : headerSize])
+	require.NoError(t, err)
+
+	sharedSecret, err := b.key.ECDH(senderPublic)
+	require.NoError(t, err)
+	keyInfo := append([]byte("WebPush: info\x00"), b.key.PublicKey().Bytes()...)
+	keyInfo = append(keyInfo, senderPublic.Bytes()...)
+	ikm, err := expand(b.authSecret, sharedSecret, keyInfo, 32)
+	require.NoError(t, err)
+	contentKey, err := expand(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	require.NoError(t, err)
+	nonce, err := expand(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12)
+	require.NoError(t, err)
+
+	block, err := aes.NewCipher(contentKey)
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	plaintext, err := gcm.Open(nil, nonce, message[headerSize:], nil)
+	require.NoError(t, err)
+	require.Equal(t, byte(0x02), plaintext[len(plaintext)-1])
+	return plaintext[:len(plaintext)-1]
+}
+
+// TestEncryptMatchesRFC8291 checks the example of RFC 8291 appendix A
+func TestEncryptMatchesRFC8291(t *testing.T) {
+	key, err := ecdh.P256().NewPrivateKey(mustDecode(t, "yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"))
+	require.NoError(t, err)
+	subscription := Subscription{
+		P256dh: "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4",
+		Auth:   "BTBZMqHH6r4Tts7J_aSIgg",
+	}
+
+	message, err := encrypt([]byte("When I grow up, I want to be a watermelon"), subscription, key, mustDecode(t, "DGv6ra1nlYgDCS1FRnbzlw"))
+	require.NoError(t, err)
+	assert.Equal(t, "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN",
+		base64.RawURLEncoding.EncodeToString(message))
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	browser := newBrowser(t)
+	payload := []byte(`{"title":"Time for your tablets"}`)
+
+	first, err := Encrypt(payload, browser.subscription(""))
+	require.NoError(t, err)
+	second, err := Encrypt(payload, browser.subscription(""))
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second, "each message has its own key and salt")
+	assert.Equal(t, payload, browser.decrypt(t, first))
+
+	_, err = Encrypt(make([]byte, MaxPayloadSize), browser.subscription(""))
+	require.NoError(t, err)
+	_, err = Encrypt(make([]byte, MaxPayloadSize+1), browser.subscription(""))
+	assert.ErrorIs(t, err, ErrPayloadTooLarge)
+
+	_, err = Encrypt(payload, Subscription{P256dh: "not-a-key", Auth: browser.subscription("").Auth})
+	assert.Error(t, err)
+}
+
+func newClient(t *testing.T) *Client {
+	t.Helper()
+	public, private, err := GenerateKeys()
+	require.NoError(t, err)
+	client, err := New(Config{PublicKey: public, PrivateKey: private, Subject: "mailto:admin@example.com"})
+	require.NoError(t, err)
+	return client
+}
+
+func TestSend(t *testing.T) {
+	browser := newBrowser(t)
+	var request *http.Request
+	var body []byte
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	client := newClient(t)
+	client.HTTPClient = server.Client()
+	client.now = func() time.Time { return time.Unix(1_800_000_000, 0) }
+	err := client.Send(context.Background(), browser.subscription(server.URL+"/push/abc"), Message{
+		Payload: []byte("hello"), TTL: time.Hour, Urgency: UrgencyHigh, Topic: "routine",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "/push/abc", request.URL.Path)
+	assert.Equal(t, "aes128gcm", request.Header.Get("Content-Encoding"))
+	assert.Equal(t, "3600", request.Header.Get("TTL"))
+	assert.Equal(t, "high", request.Header.Get("Urgency"))
+	assert.Equal(t, "routine", request.Header.Get("Topic"))
+	assert.Equal(t, []byte("hello"), browser.decrypt(t, body))
+
+	// The VAPID token is signed by the key the browser subscribed with
+	authorization := request.Header.Get("Authorization")
+	require.True(t, strings.HasPrefix(authorization, "vapid t="))
+	parts := strings.SplitN(strings.TrimPrefix(authorization, "vapid t="), ", k=", 2)
+	require.Len(t, parts, 2)
+	assert.Equal(t, client.PublicKey(), parts[1])
+	claims := jwt.MapClaims{}
+	_, err = jwt.NewParser(jwt.WithTimeFunc(client.now), jwt.WithValidMethods([]string{"ES256"})).
+		ParseWithClaims(parts[0], claims, func(*jwt.Token) (any, error) { return &client.key.PublicKey, nil })
+	require.NoError(t, err)
+	assert.Equal(t, server.URL, claims["aud"])
+	assert.Equal(t, "mailto:admin@example.com", claims["sub"])
+}
+
+func TestSendErrors(t *testing.T) {
+	status := http.StatusGone
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte("no such subscription"))
+	}))
+	defer server.Close()
+	client := newClient(t)
+	client.HTTPClient = server.Client()
+	subscription := newBrowser(t).subscription(server.URL)
+
+	assert.ErrorIs(t, client.Send(context.Background(), subscription, Message{}), ErrGone)
+	status = http.StatusNotFound
+	assert.ErrorIs(t, client.Send(context.Background(), subscription, Message{}), ErrGone)
+	status = http.StatusTooManyRequests
+	err := client.Send(context.Background(), subscription, Message{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "429: no such subscription")
+
+	subscription.Endpoint = "http://push.example.com/abc"
+	assert.Error(t, client.Send(context.Background(), subscription, Message{}))
+}
+
+func TestNew(t *testing.T) {
+	unconfigured, err := New(Config{})
+	require.NoError(t, err)
+	assert.Empty(t, unconfigured.PublicKey())
+	assert.True(t, errors.Is(unconfigured.Send(context.Background(), Subscription{}, Message{}), ErrNotConfigured))
+
+	public, private, err := GenerateKeys()
+	require.NoError(t, err)
+	otherPublic, _, err := GenerateKeys()
+	require.NoError(t, err)
+
+	_, err = New(Config{PublicKey: otherPublic, PrivateKey: private, Subject: "mailto:admin@example.com"})
+	assert.ErrorContains(t, err, "does not match")
+	_, err = New(Config{PublicKey: public, PrivateKey: private, Subject: "admin@example.com"})
+	assert.ErrorContains(t, err, "subject")
+	_, err = New(Config{PublicKey: public, PrivateKey: "short", Subject: "mailto:admin@example.com"})
+	assert.Error(t, err)
+	client, err := New(Config{PublicKey: public + "=", PrivateKey: private, Subject: "https://luma.example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, public, client.PublicKey())
+}
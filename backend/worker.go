@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/muneerlalji/Luma/config"
+	"github.com/muneerlalji/Luma/db"
+	"github.com/muneerlalji/Luma/handlers"
+	"github.com/muneerlalji/Luma/jobs"
+	"github.com/muneerlalji/Luma/repository"
+)
+
+const workerUsage = `Usage: luma worker
+
+Runs the background jobs, such as sending emails, digests and reminders,
+until SIGINT or SIGTERM. Run it when the servers set WORKER_EMBEDDED=false;
+any number of workers and servers may share the queue.
+`
+
+// Periodic jobs, named as they show in the admin jobs list
+const (
+	jobSendEmails           = "send_emails"
+	jobProcessReminders     = "process_reminders"
+	jobProcessDigests       = "process_digests"
+	jobProcessWeeklyDigests = "process_weekly_digests"
+	jobProcessCheckIns      = "process_check_ins"
+	jobProcessPush          = "process_push"
+	jobProcessPrompts       = "process_prompts"
+	jobPurgeUnconfirmed     = "purge_unconfirmed_users"
+)
+
+// runWorker implements the worker subcommand
+func runWorker(args []string) {
+	flags := flag.NewFlagSet("worker", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, workerUsage) }
+	flags.Parse(args)
+	if flags.NArg() != 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	cfg := loadConfig()
+	_, m, flushTraces := setup(cfg)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	h, _ := newHandler(cfg, m)
+	worker := newWorker(cfg, h)
+	triggerOnQueuedEmail(ctx, cfg, h, worker)
+	worker.Run(ctx)
+
+	if sqlDB, err := db.DB.DB(); err == nil {
+		sqlDB.Close()
+	}
+	flushTraces()
+}
+
+// newWorker registers the handlers' jobs and the periodic jobs on a worker
+// for the database. A zero interval or TTL leaves its periodic jobs out, so
+// this instance neither queues nor runs them.
+func newWorker(cfg *config.Config, h *handlers.Handler) *jobs.Worker {
+	worker := jobs.New(repository.NewGormStore(db.DB), cfg.Jobs)
+	jobs.Handle(worker, handlers.DeletePhotoFilesJob, h.DeletePhotoFiles)
+
+	if cfg.EmailInterval > 0 {
+		worker.Periodic(jobSendEmails, jobs.Every(cfg.EmailInterval), func(ctx context.Context) error {
+			sent, err := h.ProcessOutbox(ctx)
+			if sent > 0 {
+				slog.Info("sent emails", "count", sent)
+			}
+			// Undelivered emails are retried from the outbox, not by the job
+			if errors.Is(err, handlers.ErrNotDelivered) {
+				slog.Warn("failed to send emails", "error", err)
+				return nil
+			}
+			return err
+		})
+	}
+
+	if cfg.ReminderInterval > 0 {
+		every := jobs.Every(cfg.ReminderInterval)
+		worker.Periodic(jobProcessReminders, every, counted("queued reminder emails", h.ProcessReminders))
+		worker.Periodic(jobProcessDigests, every, counted("queued caregiver digests", h.ProcessDigests))
+		worker.Periodic(jobProcessWeeklyDigests, every, counted("queued weekly digests", h.ProcessWeeklyDigests))
+		worker.Periodic(jobProcessCheckIns, every, counted("queued missed check-in emails", h.ProcessCheckIns))
+		worker.Periodic(jobProcessPush, every, counted("pushed notifications", h.ProcessPush))
+		worker.Periodic(jobProcessPrompts, every, counted("created memory prompts", h.ProcessPrompts))
+	}
+
+	if ttl := cfg.UnconfirmedAccountTTL; ttl > 0 {
+		worker.Periodic(jobPurgeUnconfirmed, jobs.MustParseCron("0 * * * *"), func(ctx context.Context) error {
+			purged, err := h.PurgeUnconfirmedUsers(ctx, ttl)
+			if purged > 0 {
+				slog.Info("purged unconfirmed users", "count", purged)
+			}
+			return err
+		})
+	}
+
+	return worker
+}
+
+// counted turns a function that processes what is due into a job that logs
+// how much it did
+func counted(msg string, process func(ctx context.Context) (int, error)) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		count, err := process(ctx)
+		if count > 0 {
+			slog.Info(msg, "count", count)
+		}
+		return err
+	}
+}
+
+// triggerOnQueuedEmail runs the email job as soon as emails are queued,
+// rather than at its next interval, until ctx is cancelled. An email queued
+// in a transaction that had not committed yet when the job ran is sent on
+// the next run.
+func triggerOnQueuedEmail(ctx context.Context, cfg *config.Config, h *handlers.Handler, worker *jobs.Worker) {
+	if cfg.EmailInterval <= 0 {
+		return
+	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-h.OutboxWake():
+				if err := worker.Trigger(ctx, jobSendEmails); err != nil && ctx.Err() == nil {
+					slog.Error("failed to trigger email job", "error", err)
+				}
+			}
+		}
+	}()
+}
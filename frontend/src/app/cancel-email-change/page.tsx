@@ -0,0 +1,41 @@
+'use client';
+import "./page.css"
+import { useEffect, useState } from 'react';
+import { useSearchParams, useRouter } from 'next/navigation';
+import axios from 'axios';
+import Page from "../components/page/Page";
+import { apiErrorMessage } from '../../services/apiError';
+
+export default function CancelEmailChangePage() {
+  const searchParams = useSearchParams();
+  const router = useRouter();
+  const [message, setMessage] = useState('');
+  const [error, setError] = useState('');
+  const token = searchParams.get('token');
+
+  useEffect(() => {
+    if (!token) {
+      setError('Missing token');
+      return;
+    }
+    axios.post(`${process.env.NEXT_PUBLIC_API_URL}/api/v1/auth/cancel-email-change`, { token })
+      .then(() => {
+        setMessage('Email change cancelled. Your account keeps its previous email.');
+        setTimeout(() => router.push('/login'), 2000);
+      })
+      .catch((err) => setError(apiErrorMessage(err, err.message)));
+  }, [token, router]);
+
+  return (
+    <Page>
+      <main className="confirm-main">
+        <div className="confirm-container">
+          <h1 className="confirm-title">Cancel Email Change</h1>
+          {error && <div className="confirm-error">{error}</div>}
+          {message && <div className="confirm-message">{message}</div>}
+          {!error && !message && <div>Cancelling...</div>}
+        </div>
+      </main>
+    </Page>
+  );
+} 
\ No newline at end of file
@@ -6,12 +6,22 @@ import Button from '../button/Button';
 import { useRouter } from 'next/navigation';
 import { useState, useRef, useEffect } from 'react';
 import ProfileIcon from '../../../assets/Profile.svg'
+import { streamNotifications } from '../../../services/notificationService';
 
 export default function Header() {
-  const { user, logout } = useAuth();
+  const { user, token, logout } = useAuth();
   const router = useRouter();
   const [dropdownOpen, setDropdownOpen] = useState(false);
   const dropdownRef = useRef<HTMLDivElement>(null);
+  const [unread, setUnread] = useState(0);
+
+  // Keep the unread count live while signed in
+  useEffect(() => {
+    if (!user || !token) return;
+    const controller = new AbortController();
+    streamNotifications(token, { onUnread: setUnread, onNotification: () => {} }, controller.signal);
+    return () => controller.abort();
+  }, [user, token]);
 
   useEffect(() => {
     function handleClickOutside(event: MouseEvent) {
@@ -53,12 +63,25 @@ export default function Header() {
               >
                 People
               </Button>
+              <Button 
+                style={{ background: 'rgba(255, 255, 255, 0.2)', color: '#fff', minWidth: 150, padding: '0.875rem 1.75rem', fontWeight: '600', border: '1px solid rgba(255, 255, 255, 0.3)', whiteSpace: 'nowrap', textAlign: 'center', display: 'flex', alignItems: 'center', justifyContent: 'center' }} 
+                onClick={() => router.push('/today')}
+              >
+                Today
+              </Button>
               <Button 
                 style={{ background: 'rgba(255, 255, 255, 0.2)', color: '#fff', minWidth: 150, padding: '0.875rem 1.75rem', fontWeight: '600', border: '1px solid rgba(255, 255, 255, 0.3)', whiteSpace: 'nowrap', textAlign: 'center', display: 'flex', alignItems: 'center', justifyContent: 'center' }} 
                 onClick={() => router.push('/chat')}
               >
                 AI Assistant
               </Button>
+              <Button 
+                style={{ background: 'rgba(255, 255, 255, 0.2)', color: '#fff', minWidth: 150, padding: '0.875rem 1.75rem', fontWeight: '600', border: '1px solid rgba(255, 255, 255, 0.3)', whiteSpace: 'nowrap', textAlign: 'center', display: 'flex', alignItems: 'center', justifyContent: 'center', gap: '0.5rem' }} 
+                onClick={() => router.push('/notifications')}
+              >
+                Notifications
+                {unread > 0 && <span className="header-badge">{unread}</span>}
+              </Button>
               <div style={{ position: 'relative' }} ref={dropdownRef}>
                 <div style={{ cursor: 'pointer' }} onClick={() => setDropdownOpen(v => !v)} title="Profile">
                   <ProfileIcon className="header-profile-icon" />
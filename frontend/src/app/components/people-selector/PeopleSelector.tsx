@@ -3,6 +3,7 @@ import { useState, useEffect } from 'react';
 import { useAuth } from '../../../context/AuthContext';
 import { Person, getPeople } from '../../../services/personService';
 import './PeopleSelector.css';
+import { apiErrorMessage } from '../../../services/apiError';
 
 interface PeopleSelectorProps {
   selectedPeople: string[];
@@ -29,7 +30,7 @@ export default function PeopleSelector({ selectedPeople, onPeopleChange }: Peopl
       const peopleData = await getPeople(token);
       setPeople(peopleData || []);
     } catch (err: any) {
-      setError(err.response?.data?.error || 'Failed to fetch people');
+      setError(apiErrorMessage(err, 'Failed to fetch people'));
       setPeople([]);
     } finally {
       setLoading(false);
@@ -0,0 +1,41 @@
+'use client';
+import "./page.css"
+import { useEffect, useState } from 'react';
+import { useSearchParams, useRouter } from 'next/navigation';
+import axios from 'axios';
+import Page from "../components/page/Page";
+import { apiErrorMessage } from '../../services/apiError';
+
+export default function ConfirmEmailChangePage() {
+  const searchParams = useSearchParams();
+  const router = useRouter();
+  const [message, setMessage] = useState('');
+  const [error, setError] = useState('');
+  const token = searchParams.get('token');
+
+  useEffect(() => {
+    if (!token) {
+      setError('Missing token');
+      return;
+    }
+    axios.post(`${process.env.NEXT_PUBLIC_API_URL}/api/v1/auth/confirm-email-change`, { token })
+      .then(() => {
+        setMessage('Email changed! Log in with your new email.');
+        setTimeout(() => router.push('/login'), 2000);
+      })
+      .catch((err) => setError(apiErrorMessage(err, err.message)));
+  }, [token, router]);
+
+  return (
+    <Page>
+      <main className="confirm-main">
+        <div className="confirm-container">
+          <h1 className="confirm-title">Confirm New Email</h1>
+          {error && <div className="confirm-error">{error}</div>}
+          {message && <div className="confirm-message">{message}</div>}
+          {!error && !message && <div>Confirming...</div>}
+        </div>
+      </main>
+    </Page>
+  );
+} 
\ No newline at end of file
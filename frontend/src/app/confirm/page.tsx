@@ -4,6 +4,7 @@ import { useEffect, useState } from 'react';
 import { useSearchParams, useRouter } from 'next/navigation';
 import axios from 'axios';
 import Page from "../components/page/Page";
+import { apiErrorMessage } from '../../services/apiError';
 
 export default function ConfirmEmailPage() {
   const searchParams = useSearchParams();
@@ -17,12 +18,12 @@ export default function ConfirmEmailPage() {
       setError('Missing token');
       return;
     }
-    axios.post(`${process.env.NEXT_PUBLIC_API_URL}/auth/confirm?token=${encodeURIComponent(token)}`)
+    axios.post(`${process.env.NEXT_PUBLIC_API_URL}/api/v1/auth/confirm?token=${encodeURIComponent(token)}`)
       .then(() => {
         setMessage('Email confirmed! You can now log in.');
         setTimeout(() => router.push('/login'), 2000);
       })
-      .catch((err) => setError(err.message));
+      .catch((err) => setError(apiErrorMessage(err, err.message)));
   }, [token, router]);
 
   return (
@@ -8,6 +8,7 @@ import PeopleSelector from '../components/people-selector/PeopleSelector';
 import AuthGuard from '../components/auth-guard/AuthGuard';
 import axios from 'axios';
 import './page.css';
+import { apiErrorMessage } from '../../services/apiError';
 
 export default function CreateMemory() {
   const { token } = useAuth();
@@ -66,7 +67,7 @@ export default function CreateMemory() {
         formData.append('file', selectedFile);
 
         const uploadResponse = await axios.post(
-          `${process.env.NEXT_PUBLIC_API_URL}/upload-photo`,
+          `${process.env.NEXT_PUBLIC_API_URL}/api/v1/upload-photo`,
           formData,
           {
             headers: {
@@ -88,7 +89,7 @@ export default function CreateMemory() {
       };
 
       await axios.post(
-        `${process.env.NEXT_PUBLIC_API_URL}/memories`,
+        `${process.env.NEXT_PUBLIC_API_URL}/api/v1/memories`,
         memoryData,
         {
           headers: {
@@ -100,7 +101,7 @@ export default function CreateMemory() {
 
       router.push('/memories');
     } catch (err: any) {
-      setError(err.response?.data?.error || 'Failed to create memory');
+      setError(apiErrorMessage(err, 'Failed to create memory'));
     } finally {
       setIsSubmitting(false);
     }
@@ -4,6 +4,7 @@ import { useState } from 'react';
 import axios from 'axios';
 import Link from 'next/link';
 import Page from "../components/page/Page";
+import { apiErrorMessage } from '../../services/apiError';
 
 export default function ForgotPasswordPage() {
   const [email, setEmail] = useState('');
@@ -17,10 +18,10 @@ export default function ForgotPasswordPage() {
     setMessage('');
     setLoading(true);
     try {
-      await axios.post(`${process.env.NEXT_PUBLIC_API_URL}/auth/forgot-password`, { email });
+      await axios.post(`${process.env.NEXT_PUBLIC_API_URL}/api/v1/auth/forgot-password`, { email });
       setMessage('If the email exists, a reset link has been sent.');
     } catch (err: unknown) {
-      setError(err instanceof Error ? err.message : 'An error occurred');
+      setError(apiErrorMessage(err, err instanceof Error ? err.message : 'An error occurred'));
     } finally {
       setLoading(false);
     }
@@ -1,6 +1,8 @@
 import { useAuth } from "@/context/AuthContext";
 import axios from "axios";
+import { useSearchParams } from "next/navigation";
 import { useEffect, useRef, useState } from "react";
+import { MemoryPrompt, getTodayPrompt } from "@/services/reminiscenceService";
 
 export interface ChatMessage {
   id: string;
@@ -11,6 +13,10 @@ export interface ChatMessage {
 
 export const useChatPage = () => {
   const { token, loading: authLoading } = useAuth();
+  const searchParams = useSearchParams();
+  // prompt is the memory prompt the conversation was opened with, until the
+  // user answers it
+  const [prompt, setPrompt] = useState<MemoryPrompt | null>(null);
   const [messages, setMessages] = useState<ChatMessage[]>([]);
   const [inputMessage, setInputMessage] = useState('');
   const [isLoading, setIsLoading] = useState(false);
@@ -22,11 +28,20 @@ export const useChatPage = () => {
   const loadChatHistory = async (token: string) => {
     try {
       setLoadingHistory(true);
-      const response = await axios.get(`${process.env.NEXT_PUBLIC_API_URL}/chat/history`, {
+      const response = await axios.get(`${process.env.NEXT_PUBLIC_API_URL}/api/v1/chat/history`, {
         headers: { Authorization: `Bearer ${token}` }
       });
 
-      setMessages(response.data.messages || []);
+      const history: ChatMessage[] = response.data.messages || [];
+
+      // Opened from today's memory prompt: the assistant asks its question
+      const promptId = searchParams.get('prompt');
+      const today = promptId ? await getTodayPrompt(token) : null;
+      if (today && today.id === promptId && today.response === 'pending') {
+        setPrompt(today);
+        history.push({ id: `prompt-${today.id}`, role: 'assistant', content: today.question, createdAt: new Date().toISOString() });
+      }
+      setMessages(history);
     } catch (error) {
       setError('Failed to load chat history. Please try again.');
     } finally {
@@ -85,14 +100,15 @@ export const useChatPage = () => {
 
     try {
       // Use fetch for streaming support
-      const response = await fetch(`${process.env.NEXT_PUBLIC_API_URL}/chat?stream=true`, {
+      const response = await fetch(`${process.env.NEXT_PUBLIC_API_URL}/api/v1/chat?stream=true`, {
         method: 'POST',
         headers: {
           'Content-Type': 'application/json',
           'Authorization': `Bearer ${token}`
         },
-        body: JSON.stringify({ message: userMessage })
+        body: JSON.stringify({ message: userMessage, promptId: prompt?.id })
       });
+      setPrompt(null);
 
       if (!response.ok) {
         throw new Error(`HTTP error! status: ${response.status}`);
@@ -1,12 +1,14 @@
 'use client';
 import "./page.css"
-import { useState } from 'react';
+import { useEffect, useState } from 'react';
+import axios from 'axios';
 import { useAuth } from '../../context/AuthContext';
 import { useRouter } from 'next/navigation';
 import Link from 'next/link';
 import Page from "../components/page/Page";
 import Button from "../components/button/Button";
 import TextBox from "../components/textbox/TextBox";
+import { apiErrorMessage } from '../../services/apiError';
 
 export default function LoginPage() {
   const { login, loading } = useAuth();
@@ -14,6 +16,24 @@ export default function LoginPage() {
   const [email, setEmail] = useState('');
   const [password, setPassword] = useState('');
   const [error, setError] = useState('');
+  const [providers, setProviders] = useState<{ name: string; displayName: string }[]>([]);
+
+  useEffect(() => {
+    axios.get(`${process.env.NEXT_PUBLIC_API_URL}/api/v1/auth/oidc/providers`)
+      .then(res => setProviders(res.data.providers))
+      .catch(() => setProviders([]));
+  }, []);
+
+  async function handleProviderLogin(provider: string) {
+    setError('');
+    try {
+      const res = await axios.get(`${process.env.NEXT_PUBLIC_API_URL}/api/v1/auth/oidc/${provider}/start`);
+      sessionStorage.setItem('oidcProvider', provider);
+      window.location.href = res.data.authorizationUrl;
+    } catch (err: unknown) {
+      setError(apiErrorMessage(err, err instanceof Error ? err.message : 'An error occurred'));
+    }
+  }
 
   async function handleSubmit(e: React.FormEvent) {
     e.preventDefault();
@@ -22,7 +42,7 @@ export default function LoginPage() {
       await login(email, password);
       router.push('/');
     } catch (err: unknown) {
-      setError(err instanceof Error ? err.message : 'An error occurred');
+      setError(apiErrorMessage(err, err instanceof Error ? err.message : 'An error occurred'));
     }
   }
 
@@ -51,6 +71,15 @@ export default function LoginPage() {
               {loading ? 'Logging in...' : 'Login'}
             </Button>
           </form>
+          {providers.length > 0 && (
+            <div className="login-providers">
+              {providers.map(provider => (
+                <Button key={provider.name} type="button" onClick={() => handleProviderLogin(provider.name)}>
+                  Continue with {provider.displayName}
+                </Button>
+              ))}
+            </div>
+          )}
           <div className="login-links">
             <Link href="/forgot-password" className="login-forgot-link">Forgot password?</Link>
             <div className="login-register-link">Don&apos;t have an account? <Link href="/register" className="login-register-anchor">Register</Link></div>
@@ -8,6 +8,7 @@ import Photo from '../components/photo/Photo';
 import AuthGuard from '../components/auth-guard/AuthGuard';
 import axios from 'axios';
 import './page.css';
+import { apiErrorMessage } from '../../services/apiError';
 
 interface Person {
   id: string;
@@ -40,7 +41,7 @@ export default function Memories() {
     try {
       setLoadingMemories(true);
       const response = await axios.get(
-        `${process.env.NEXT_PUBLIC_API_URL}/memories`,
+        `${process.env.NEXT_PUBLIC_API_URL}/api/v1/memories`,
         {
           headers: {
             'Authorization': `Bearer ${token}`,
@@ -50,7 +51,7 @@ export default function Memories() {
       
       setMemories(response.data.memories || []);
     } catch (err: any) {
-      setError(err.response?.data?.error || 'Failed to fetch memories');
+      setError(apiErrorMessage(err, 'Failed to fetch memories'));
     } finally {
       setLoadingMemories(false);
     }
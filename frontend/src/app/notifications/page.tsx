@@ -0,0 +1,192 @@
+'use client';
+import { useState, useEffect } from 'react';
+import { useRouter } from 'next/navigation';
+import { useAuth } from '../../context/AuthContext';
+import Page from '../components/page/Page';
+import Button from '../components/button/Button';
+import AuthGuard from '../components/auth-guard/AuthGuard';
+import {
+  Notification,
+  NotificationPreference,
+  disablePush,
+  enablePush,
+  getNotificationPreferences,
+  getNotifications,
+  markAllNotificationsRead,
+  markNotificationRead,
+  pushEnabled,
+  pushSupported,
+  setNotificationPreference,
+  streamNotifications,
+} from '../../services/notificationService';
+import './page.css';
+import { apiErrorMessage } from '../../services/apiError';
+
+const typeText: Record<NotificationPreference['type'], string> = {
+  reminder: 'Reach-out reminders',
+  routine_due: 'Medication and activities due',
+  check_in_missed: 'Missed check-ins',
+};
+
+const channelText: Record<NotificationPreference['channel'], string> = {
+  in_app: 'In the app',
+  push: 'On this device',
+  email: 'By email',
+};
+
+export default function Notifications() {
+  const { token, loading: authLoading } = useAuth();
+  const router = useRouter();
+  const [notifications, setNotifications] = useState<Notification[]>([]);
+  const [unread, setUnread] = useState(0);
+  const [preferences, setPreferences] = useState<NotificationPreference[]>([]);
+  const [push, setPush] = useState(false);
+  const [loading, setLoading] = useState(true);
+  const [error, setError] = useState('');
+
+  const fetchNotifications = async (token: string) => {
+    try {
+      setLoading(true);
+      const [list, preferenceData, pushOn] = await Promise.all([
+        getNotifications(token),
+        getNotificationPreferences(token),
+        pushEnabled(),
+      ]);
+      setNotifications(list.notifications || []);
+      setUnread(list.unread);
+      setPreferences(preferenceData.preferences || []);
+      setPush(pushOn);
+    } catch (err: any) {
+      setError(apiErrorMessage(err, 'Failed to fetch notifications'));
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const open = async (notification: Notification) => {
+    if (!token) return;
+    try {
+      if (!notification.readAt) {
+        const read = await markNotificationRead(notification.id, token);
+        setNotifications((prev) => prev.map((n) => (n.id === read.id ? read : n)));
+      }
+      router.push(notification.url);
+    } catch (err: any) {
+      setError(apiErrorMessage(err, 'Failed to open notification'));
+    }
+  };
+
+  const readAll = async () => {
+    if (!token) return;
+    try {
+      const readAt = new Date().toISOString();
+      await markAllNotificationsRead(token);
+      setNotifications((prev) => prev.map((n) => (n.readAt ? n : { ...n, readAt })));
+      setUnread(0);
+    } catch (err: any) {
+      setError(apiErrorMessage(err, 'Failed to mark notifications read'));
+    }
+  };
+
+  const toggle = async (preference: NotificationPreference) => {
+    if (!token) return;
+    try {
+      const updated = await setNotificationPreference({ ...preference, enabled: !preference.enabled }, token);
+      setPreferences(updated.preferences);
+    } catch (err: any) {
+      setError(apiErrorMessage(err, 'Failed to save preference'));
+    }
+  };
+
+  const togglePush = async () => {
+    if (!token) return;
+    try {
+      if (push) {
+        await disablePush(token);
+      } else {
+        await enablePush(token);
+      }
+      setPush(!push);
+    } catch (err: any) {
+      setError(apiErrorMessage(err, 'Failed to change push notifications'));
+    }
+  };
+
+  // Only fetch data when auth is done loading and we have a token
+  useEffect(() => {
+    if (!authLoading && token && loading) {
+      fetchNotifications(token);
+    }
+  }, [authLoading, token, loading]);
+
+  // Show new notifications as they arrive
+  useEffect(() => {
+    if (authLoading || !token) return;
+    const controller = new AbortController();
+    streamNotifications(token, {
+      onUnread: setUnread,
+      onNotification: (notification) =>
+        setNotifications((prev) => (prev.some((n) => n.id === notification.id) ? prev : [notification, ...prev])),
+    }, controller.signal);
+    return () => controller.abort();
+  }, [authLoading, token]);
+
+  return (
+    <AuthGuard>
+      <Page>
+        <div className="notifications-container">
+          <h1>Notifications</h1>
+          <p className="notifications-subtitle">
+            {unread === 0 ? 'You are all caught up.' : `${unread} unread`}
+          </p>
+
+          {error && <p className="error-message">{error}</p>}
+
+          {loading ? (
+            <div className="loading">Loading...</div>
+          ) : (
+            <>
+              {unread > 0 && (
+                <Button onClick={readAll} style={{ background: '#2563eb' }}>
+                  Mark all read
+                </Button>
+              )}
+              <ul className="notifications">
+                {notifications.map((notification) => (
+                  <li
+                    key={notification.id}
+                    className={`notification ${notification.readAt ? 'read' : 'unread'}`}
+                    onClick={() => open(notification)}
+                  >
+                    <strong>{notification.title}</strong>
+                    {notification.body && <p>{notification.body}</p>}
+                    <span className="notification-time">{new Date(notification.createdAt).toLocaleString()}</span>
+                  </li>
+                ))}
+              </ul>
+
+              <h2>Settings</h2>
+              {pushSupported() && (
+                <label className="preference">
+                  <input type="checkbox" checked={push} onChange={togglePush} />
+                  Show notifications on this device
+                </label>
+              )}
+              {Object.entries(typeText).map(([type, text]) => (
+                <div key={type} className="preference-group">
+                  <h3>{text}</h3>
+                  {preferences.filter((preference) => preference.type === type).map((preference) => (
+                    <label key={preference.channel} className="preference">
+                      <input type="checkbox" checked={preference.enabled} onChange={() => toggle(preference)} />
+                      {channelText[preference.channel]}
+                    </label>
+                  ))}
+                </div>
+              ))}
+            </>
+          )}
+        </div>
+      </Page>
+    </AuthGuard>
+  );
+}
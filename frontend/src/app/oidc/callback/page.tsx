@@ -0,0 +1,53 @@
+'use client';
+import "./page.css"
+import { useEffect, useState } from 'react';
+import { useSearchParams } from 'next/navigation';
+import axios from 'axios';
+import Page from "../../components/page/Page";
+import { apiErrorMessage } from '../../../services/apiError';
+
+export default function OIDCCallbackPage() {
+  const searchParams = useSearchParams();
+  const [message, setMessage] = useState('');
+  const [error, setError] = useState('');
+  const code = searchParams.get('code');
+  const state = searchParams.get('state');
+
+  useEffect(() => {
+    const provider = sessionStorage.getItem('oidcProvider');
+    if (searchParams.get('error')) {
+      setError(searchParams.get('error_description') || 'Sign-in was cancelled');
+      return;
+    }
+    if (!code || !state || !provider) {
+      setError('Missing sign-in response');
+      return;
+    }
+    sessionStorage.removeItem('oidcProvider');
+    axios.post(`${process.env.NEXT_PUBLIC_API_URL}/api/v1/auth/oidc/${provider}/callback`, { code, state })
+      .then((res) => {
+        if (res.data.token) {
+          localStorage.setItem('token', res.data.token);
+          setMessage('Signed in! Redirecting...');
+          window.location.href = '/';
+        } else {
+          setMessage('Account linked! Redirecting...');
+          window.location.href = '/profile';
+        }
+      })
+      .catch((err) => setError(apiErrorMessage(err, err.message)));
+  }, [code, state, searchParams]);
+
+  return (
+    <Page>
+      <main className="oidc-callback-main">
+        <div className="oidc-callback-container">
+          <h1 className="oidc-callback-title">Signing In</h1>
+          {error && <div className="oidc-callback-error">{error}</div>}
+          {message && <div className="oidc-callback-message">{message}</div>}
+          {!error && !message && <div>Completing sign-in...</div>}
+        </div>
+      </main>
+    </Page>
+  );
+}
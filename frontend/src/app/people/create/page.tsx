@@ -8,6 +8,7 @@ import { createPerson, CreatePersonRequest } from '../../../services/personServi
 import AuthGuard from '../../components/auth-guard/AuthGuard';
 import axios from 'axios';
 import './page.css';
+import { apiErrorMessage } from '../../../services/apiError';
 
 export default function CreatePerson() {
   const { token } = useAuth();
@@ -18,6 +19,9 @@ export default function CreatePerson() {
   const [phone, setPhone] = useState('');
   const [relationship, setRelationship] = useState('');
   const [notes, setNotes] = useState('');
+  const [contactEveryDays, setContactEveryDays] = useState(0);
+  const [birthday, setBirthday] = useState('');
+  const [anniversary, setAnniversary] = useState('');
   const [selectedFile, setSelectedFile] = useState<File | null>(null);
   const [previewUrl, setPreviewUrl] = useState<string | null>(null);
   const [isSubmitting, setIsSubmitting] = useState(false);
@@ -68,7 +72,7 @@ export default function CreatePerson() {
         formData.append('file', selectedFile);
 
         const uploadResponse = await axios.post(
-          `${process.env.NEXT_PUBLIC_API_URL}/upload-photo`,
+          `${process.env.NEXT_PUBLIC_API_URL}/api/v1/upload-photo`,
           formData,
           {
             headers: {
@@ -89,12 +93,15 @@ export default function CreatePerson() {
         relationship,
         notes,
         photoId: photoId || undefined,
+        contactEveryDays,
+        birthday: birthday || undefined,
+        anniversary: anniversary || undefined,
       };
 
       await createPerson(personData, token);
       router.push('/people');
     } catch (err: any) {
-      setError(err.response?.data?.error || 'Failed to create person');
+      setError(apiErrorMessage(err, 'Failed to create person'));
     } finally {
       setIsSubmitting(false);
     }
@@ -197,6 +204,45 @@ export default function CreatePerson() {
               />
             </div>
 
+            <div className="form-row">
+              <div className="form-group">
+                <label htmlFor="birthday">Birthday</label>
+                <input
+                  type="date"
+                  id="birthday"
+                  value={birthday}
+                  onChange={(e) => setBirthday(e.target.value)}
+                  className="form-input"
+                />
+              </div>
+
+              <div className="form-group">
+                <label htmlFor="anniversary">Anniversary</label>
+                <input
+                  type="date"
+                  id="anniversary"
+                  value={anniversary}
+                  onChange={(e) => setAnniversary(e.target.value)}
+                  className="form-input"
+                />
+              </div>
+            </div>
+
+            <div className="form-group">
+              <label htmlFor="contactEveryDays">Remind me to reach out</label>
+              <select
+                id="contactEveryDays"
+                value={contactEveryDays}
+                onChange={(e) => setContactEveryDays(Number(e.target.value))}
+                className="form-select"
+              >
+                <option value={0}>Never</option>
+                <option value={7}>Every week</option>
+                <option value={14}>Every two weeks</option>
+                <option value={30}>Every month</option>
+              </select>
+            </div>
+
             <div className="form-group">
               <label htmlFor="photo">Photo (Optional)</label>
               <div className="photo-upload-section">
@@ -4,30 +4,80 @@ import { useRouter } from 'next/navigation';
 import { useAuth } from '../../context/AuthContext';
 import Page from '../components/page/Page';
 import Button from '../components/button/Button';
-import { Person, getPeople } from '../../services/personService';
+import { Person, UpcomingEvent, getPeople, getUpcoming } from '../../services/personService';
+import { Reminder, getReminders, completeReminder, snoozeReminder, dismissReminder } from '../../services/reminderService';
+import { InteractionType, PersonInsight, getInsights, logInteraction } from '../../services/interactionService';
 import AuthGuard from '../components/auth-guard/AuthGuard';
 import './page.css';
+import { apiErrorMessage } from '../../services/apiError';
 
 export default function People() {
   const { token, loading: authLoading } = useAuth();
   const router = useRouter();
   const [people, setPeople] = useState<Person[]>([]);
+  const [reminders, setReminders] = useState<Reminder[]>([]);
+  const [insights, setInsights] = useState<Record<string, PersonInsight>>({});
+  const [upcoming, setUpcoming] = useState<UpcomingEvent[]>([]);
   const [loadingPeople, setLoadingPeople] = useState(true);
   const [error, setError] = useState('');
 
   const fetchPeople = async (token: string) => {
     try {
       setLoadingPeople(true);
-      const peopleData = await getPeople(token);
+      const [peopleData, reminderData, insightData, upcomingData] = await Promise.all([
+        getPeople(token),
+        getReminders(token),
+        getInsights(token),
+        getUpcoming(token, 14),
+      ]);
       setPeople(peopleData || []);
+      setReminders(reminderData || []);
+      setInsights(Object.fromEntries(insightData.people.map((insight) => [insight.personId, insight])));
+      setUpcoming(upcomingData || []);
     } catch (err: any) {
-      setError(err.response?.data?.error || 'Failed to fetch people');
+      setError(apiErrorMessage(err, 'Failed to fetch people'));
       setPeople([]);
     } finally {
       setLoadingPeople(false);
     }
   };
 
+  // Acting on a reminder takes it off the list; completing it also moves the
+  // person's next reminder
+  const resolveReminder = async (action: (id: string, token: string) => Promise<Reminder>, id: string) => {
+    if (!token) return;
+    try {
+      await action(id, token);
+      setReminders((current) => current.filter((reminder) => reminder.id !== id));
+      if (action === completeReminder) {
+        setPeople(await getPeople(token));
+      }
+    } catch (err: any) {
+      setError(apiErrorMessage(err, 'Failed to update reminder'));
+    }
+  };
+
+  // Logging contact completes the person's reminder, so refresh everything
+  const recordContact = async (personId: string, type: InteractionType) => {
+    if (!token) return;
+    try {
+      await logInteraction(personId, type, token);
+      await fetchPeople(token);
+    } catch (err: any) {
+      setError(apiErrorMessage(err, 'Failed to log contact'));
+    }
+  };
+
+  const lastContact = (insight?: PersonInsight) => {
+    if (!insight || insight.daysSinceContact === undefined) return 'No contact logged yet';
+    if (insight.daysSinceContact === 0) return 'Last in touch today';
+    if (insight.daysSinceContact === 1) return 'Last in touch yesterday';
+    return `Last in touch ${insight.daysSinceContact} days ago`;
+  };
+
+  const snoozeUntilTomorrow = (id: string, token: string) =>
+    snoozeReminder(id, new Date(Date.now() + 24 * 60 * 60 * 1000), token);
+
   // Only fetch data when auth is done loading and we have a token
   useEffect(() => {
     if (!authLoading && token && loadingPeople) {
@@ -54,6 +104,52 @@ export default function People() {
 
           {error && <p className="error-message">{error}</p>}
 
+          {reminders.length > 0 && (
+            <div className="reminders">
+              <h2>Time to reach out</h2>
+              {reminders.map((reminder) => (
+                <div key={reminder.id} className="reminder">
+                  <p>
+                    Get in touch with <strong>{reminder.personName}</strong>
+                    {reminder.phone && <> on {reminder.phone}</>}
+                  </p>
+                  <div className="reminder-actions">
+                    <Button onClick={() => resolveReminder(completeReminder, reminder.id)} style={{ background: '#16a34a' }}>
+                      Done
+                    </Button>
+                    <Button onClick={() => resolveReminder(snoozeUntilTomorrow, reminder.id)} style={{ background: '#6b7280' }}>
+                      Tomorrow
+                    </Button>
+                    <Button onClick={() => resolveReminder(dismissReminder, reminder.id)} style={{ background: '#6b7280' }}>
+                      Dismiss
+                    </Button>
+                  </div>
+                </div>
+              ))}
+            </div>
+          )}
+
+          {upcoming.length > 0 && (
+            <div className="upcoming">
+              <h2>Coming up</h2>
+              <ul>
+                {upcoming.map((event) => (
+                  <li key={`${event.kind}-${event.personId ?? event.memoryId}`}>
+                    <strong>
+                      {event.daysAway === 0
+                        ? 'Today'
+                        : event.daysAway === 1
+                          ? 'Tomorrow'
+                          : new Date(`${event.date}T00:00:00`).toLocaleDateString(undefined, { weekday: 'long', day: 'numeric', month: 'long' })}
+                    </strong>
+                    {': '}
+                    {event.title}
+                  </li>
+                ))}
+              </ul>
+            </div>
+          )}
+
           {loadingPeople ? (
             <div className="loading">Loading people...</div>
           ) : !people || people.length === 0 ? (
@@ -87,6 +183,18 @@ export default function People() {
                     {person.notes && (
                       <p className="notes">{person.notes}</p>
                     )}
+                    {person.diedOn && (
+                      <p className="died-on">Passed away {new Date(`${person.diedOn}T00:00:00`).toLocaleDateString()}</p>
+                    )}
+                    {person.contactEveryDays > 0 && (
+                      <p className="cadence">Reminder every {person.contactEveryDays} days</p>
+                    )}
+                    <p className="last-contact">{lastContact(insights[person.id])}</p>
+                    <div className="contact-actions">
+                      <button onClick={() => recordContact(person.id, 'call')}>Called</button>
+                      <button onClick={() => recordContact(person.id, 'visit')}>Visited</button>
+                      <button onClick={() => recordContact(person.id, 'message')}>Messaged</button>
+                    </div>
                   </div>
                 </div>
               ))}
@@ -7,18 +7,31 @@ import Button from '../components/button/Button';
 import AuthGuard from '../components/auth-guard/AuthGuard';
 import axios from 'axios';
 import './page.css';
+import { apiErrorMessage } from '../../services/apiError';
+import { createCalendarFeed, deleteCalendarFeed, importCalendar, importSummary } from '../../services/calendarService';
+import { CareCircleMember, addCareCircleMember, getCareCircle, removeCareCircleMember } from '../../services/careCircleService';
 
 interface UserProfile {
   id: string;
   email: string;
   displayName: string;
+  caregiverEmail?: string;
+  timezone?: string;
+  locale?: string;
+  weeklyDigest?: boolean;
 }
 
+const emailLanguages: Record<string, string> = { en: 'English', es: 'Español' };
+
 export default function Profile() {
   const { token, loading: authLoading, logout } = useAuth();
   const router = useRouter();
   const [profile, setProfile] = useState<UserProfile | null>(null);
   const [displayName, setDisplayName] = useState('');
+  const [caregiverEmail, setCaregiverEmail] = useState('');
+  const [timezone, setTimezone] = useState('');
+  const [locale, setLocale] = useState('en');
+  const [weeklyDigest, setWeeklyDigest] = useState(true);
   const [currentPassword, setCurrentPassword] = useState('');
   const [newPassword, setNewPassword] = useState('');
   const [confirmPassword, setConfirmPassword] = useState('');
@@ -28,12 +41,17 @@ export default function Profile() {
   const [isSubmitting, setIsSubmitting] = useState(false);
   const [error, setError] = useState('');
   const [success, setSuccess] = useState('');
+  const [feedUrl, setFeedUrl] = useState('');
+  const [calendarFile, setCalendarFile] = useState<File | null>(null);
+  const [careCircle, setCareCircle] = useState<CareCircleMember[]>([]);
+  const [memberName, setMemberName] = useState('');
+  const [memberEmail, setMemberEmail] = useState('');
 
   const fetchProfile = async (token: string) => {
     try {
       setLoadingProfile(true);
       const response = await axios.get(
-        `${process.env.NEXT_PUBLIC_API_URL}/me`,
+        `${process.env.NEXT_PUBLIC_API_URL}/api/v1/me`,
         {
           headers: {
             'Authorization': `Bearer ${token}`,
@@ -44,8 +62,13 @@ export default function Profile() {
       const userData = response.data;
       setProfile(userData);
       setDisplayName(userData.displayName || '');
+      setCaregiverEmail(userData.caregiverEmail || '');
+      setTimezone(userData.timezone || Intl.DateTimeFormat().resolvedOptions().timeZone);
+      setLocale(userData.locale || 'en');
+      setWeeklyDigest(!userData.caregiverEmail || !!userData.weeklyDigest);
+      setCareCircle(await getCareCircle(token));
     } catch (err: any) {
-      setError(err.response?.data?.error || 'Failed to fetch profile');
+      setError(apiErrorMessage(err, 'Failed to fetch profile'));
     } finally {
       setLoadingProfile(false);
     }
@@ -68,9 +91,13 @@ export default function Profile() {
 
     try {
       await axios.put(
-        `${process.env.NEXT_PUBLIC_API_URL}/profile`,
+        `${process.env.NEXT_PUBLIC_API_URL}/api/v1/profile`,
         {
           displayName,
+          caregiverEmail,
+          timezone: timezone || undefined,
+          locale,
+          weeklyDigest: caregiverEmail ? weeklyDigest : undefined,
         },
         {
           headers: {
@@ -84,7 +111,7 @@ export default function Profile() {
       setIsEditing(false);
       fetchProfile(token); // Refresh profile data
     } catch (err: any) {
-      setError(err.response?.data?.error || 'Failed to update profile');
+      setError(apiErrorMessage(err, 'Failed to update profile'));
     } finally {
       setIsSubmitting(false);
     }
@@ -99,8 +126,8 @@ export default function Profile() {
       return;
     }
 
-    if (newPassword.length < 6) {
-      setError('New password must be at least 6 characters long');
+    if (newPassword.length < 8) {
+      setError('New password must be at least 8 characters long');
       return;
     }
     
@@ -110,7 +137,7 @@ export default function Profile() {
 
     try {
       await axios.put(
-        `${process.env.NEXT_PUBLIC_API_URL}/change-password`,
+        `${process.env.NEXT_PUBLIC_API_URL}/api/v1/change-password`,
         {
           currentPassword,
           newPassword,
@@ -129,12 +156,90 @@ export default function Profile() {
       setNewPassword('');
       setConfirmPassword('');
     } catch (err: any) {
-      setError(err.response?.data?.error || 'Failed to change password');
+      setError(apiErrorMessage(err, 'Failed to change password'));
+    } finally {
+      setIsSubmitting(false);
+    }
+  };
+
+  const handleCreateFeed = async () => {
+    if (!token) return;
+    if (feedUrl && !confirm('Calendars subscribed to the current link will stop updating. Continue?')) {
+      return;
+    }
+    setError('');
+    setSuccess('');
+    try {
+      setFeedUrl(await createCalendarFeed(token));
+    } catch (err: any) {
+      setError(apiErrorMessage(err, 'Failed to create calendar link'));
+    }
+  };
+
+  const handleDeleteFeed = async () => {
+    if (!token) return;
+    setError('');
+    try {
+      await deleteCalendarFeed(token);
+      setFeedUrl('');
+      setSuccess('Calendar link turned off');
+    } catch (err: any) {
+      setError(apiErrorMessage(err, 'Failed to turn off calendar link'));
+    }
+  };
+
+  const handleImportCalendar = async (e: React.FormEvent) => {
+    e.preventDefault();
+    if (!token || !calendarFile) return;
+
+    setIsSubmitting(true);
+    setError('');
+    setSuccess('');
+    try {
+      const result = await importCalendar(calendarFile, token);
+      const skipped = result.skipped.length ? ` (${result.skipped.length} events skipped)` : '';
+      setSuccess(importSummary(result) + skipped);
+      setCalendarFile(null);
+    } catch (err: any) {
+      setError(apiErrorMessage(err, 'Failed to import calendar'));
+    } finally {
+      setIsSubmitting(false);
+    }
+  };
+
+  const handleAddMember = async (e: React.FormEvent) => {
+    e.preventDefault();
+    if (!token) return;
+
+    setIsSubmitting(true);
+    setError('');
+    setSuccess('');
+    try {
+      const member = await addCareCircleMember(memberName, memberEmail, token);
+      setCareCircle([...careCircle, member]);
+      setMemberName('');
+      setMemberEmail('');
+      setSuccess(`${member.name} will get the weekly digest`);
+    } catch (err: any) {
+      setError(apiErrorMessage(err, 'Failed to add to care circle'));
     } finally {
       setIsSubmitting(false);
     }
   };
 
+  const handleRemoveMember = async (member: CareCircleMember) => {
+    if (!token) return;
+    setError('');
+    setSuccess('');
+    try {
+      await removeCareCircleMember(member.id, token);
+      setCareCircle(careCircle.filter((m) => m.id !== member.id));
+      setSuccess(`Removed ${member.name} from your care circle`);
+    } catch (err: any) {
+      setError(apiErrorMessage(err, 'Failed to remove from care circle'));
+    }
+  };
+
   const handleDeleteAccount = async () => {
     if (!confirm('Are you sure you want to delete your account? This action cannot be undone.')) {
       return;
@@ -146,7 +251,7 @@ export default function Profile() {
 
     try {
       await axios.delete(
-        `${process.env.NEXT_PUBLIC_API_URL}/profile`,
+        `${process.env.NEXT_PUBLIC_API_URL}/api/v1/profile`,
         {
           headers: {
             'Authorization': `Bearer ${token}`,
@@ -157,7 +262,7 @@ export default function Profile() {
       logout();
       router.push('/');
     } catch (err: any) {
-      setError(err.response?.data?.error || 'Failed to delete account');
+      setError(apiErrorMessage(err, 'Failed to delete account'));
       setIsSubmitting(false);
     }
   };
@@ -206,6 +311,24 @@ export default function Profile() {
                       <label>Display Name:</label>
                       <span>{profile?.displayName || 'Not set'}</span>
                     </div>
+                    <div className="info-row">
+                      <label>Caregiver Email:</label>
+                      <span>{profile?.caregiverEmail || 'Not set'}</span>
+                    </div>
+                    <div className="info-row">
+                      <label>Timezone:</label>
+                      <span>{profile?.timezone || 'UTC'}</span>
+                    </div>
+                    <div className="info-row">
+                      <label>Email Language:</label>
+                      <span>{emailLanguages[profile?.locale || 'en']}</span>
+                    </div>
+                    {profile?.caregiverEmail && (
+                      <div className="info-row">
+                        <label>Weekly Digest:</label>
+                        <span>{profile.weeklyDigest ? 'On' : 'Off'}</span>
+                      </div>
+                    )}
                   </div>
                 ) : (
                   <form onSubmit={handleUpdateProfile} className="profile-form">
@@ -219,12 +342,64 @@ export default function Profile() {
                         className="form-input"
                       />
                     </div>
+                    <div className="form-group">
+                      <label htmlFor="caregiverEmail">Caregiver Email</label>
+                      <input
+                        type="email"
+                        id="caregiverEmail"
+                        value={caregiverEmail}
+                        onChange={(e) => setCaregiverEmail(e.target.value)}
+                        placeholder="Gets a daily email of upcoming dates and is told about missed check-ins"
+                        className="form-input"
+                      />
+                    </div>
+                    {caregiverEmail && (
+                      <div className="form-group">
+                        <label htmlFor="weeklyDigest">
+                          <input
+                            type="checkbox"
+                            id="weeklyDigest"
+                            checked={weeklyDigest}
+                            onChange={(e) => setWeeklyDigest(e.target.checked)}
+                          />{' '}
+                          Email my caregiver a weekly digest of how I am doing
+                        </label>
+                      </div>
+                    )}
+                    <div className="form-group">
+                      <label htmlFor="timezone">Timezone</label>
+                      <input
+                        type="text"
+                        id="timezone"
+                        value={timezone}
+                        onChange={(e) => setTimezone(e.target.value)}
+                        placeholder="Such as Europe/London; routine times are in this timezone"
+                        className="form-input"
+                      />
+                    </div>
+                    <div className="form-group">
+                      <label htmlFor="locale">Email Language</label>
+                      <select
+                        id="locale"
+                        value={locale}
+                        onChange={(e) => setLocale(e.target.value)}
+                        className="form-input"
+                      >
+                        {Object.entries(emailLanguages).map(([value, label]) => (
+                          <option key={value} value={value}>{label}</option>
+                        ))}
+                      </select>
+                    </div>
                     <div className="form-actions">
                       <Button
                         type="button"
                         onClick={() => {
                           setIsEditing(false);
                           setDisplayName(profile?.displayName || '');
+                          setCaregiverEmail(profile?.caregiverEmail || '');
+                          setTimezone(profile?.timezone || '');
+                          setLocale(profile?.locale || 'en');
+                          setWeeklyDigest(!profile?.caregiverEmail || !!profile?.weeklyDigest);
                         }}
                         style={{ background: '#6b7280' }}
                         disabled={isSubmitting}
@@ -318,6 +493,125 @@ export default function Profile() {
                 )}
               </div>
 
+              {/* Calendar Section */}
+              <div className="profile-section">
+                <div className="section-header">
+                  <h2>Calendar</h2>
+                </div>
+                <div className="calendar-content">
+                  <p>
+                    Subscribe to birthdays, anniversaries, reminders and routines from
+                    Google Calendar, Apple Calendar or Outlook. Anyone with the link can
+                    see these events, so keep it private.
+                  </p>
+                  {feedUrl && (
+                    <input
+                      type="text"
+                      readOnly
+                      value={feedUrl}
+                      onFocus={(e) => e.target.select()}
+                      className="form-input"
+                      aria-label="Calendar link"
+                    />
+                  )}
+                  <div className="form-actions">
+                    {feedUrl && (
+                      <Button onClick={handleDeleteFeed} style={{ background: '#6b7280' }}>
+                        Turn Off Link
+                      </Button>
+                    )}
+                    <Button onClick={handleCreateFeed} style={{ background: '#2563eb' }}>
+                      {feedUrl ? 'Create New Link' : 'Create Calendar Link'}
+                    </Button>
+                  </div>
+
+                  <form onSubmit={handleImportCalendar} className="profile-form">
+                    <div className="form-group">
+                      <label htmlFor="calendarFile">Import an .ics file</label>
+                      <input
+                        type="file"
+                        id="calendarFile"
+                        accept=".ics,text/calendar"
+                        onChange={(e) => setCalendarFile(e.target.files?.[0] || null)}
+                        className="form-input"
+                      />
+                      <p className="calendar-hint">
+                        Past events become memories and birthday events set birthdays.
+                        Importing the same file again updates them.
+                      </p>
+                    </div>
+                    <div className="form-actions">
+                      <Button
+                        type="submit"
+                        style={{ background: '#2563eb' }}
+                        disabled={isSubmitting || !calendarFile}
+                      >
+                        {isSubmitting ? 'Importing...' : 'Import'}
+                      </Button>
+                    </div>
+                  </form>
+                </div>
+              </div>
+
+              {/* Care Circle Section */}
+              <div className="profile-section">
+                <div className="section-header">
+                  <h2>Care Circle</h2>
+                </div>
+                <div className="calendar-content">
+                  <p>
+                    Family and friends in your care circle each get the weekly digest of
+                    how you are doing, and can unsubscribe from it themselves.
+                  </p>
+                  {careCircle.map((member) => (
+                    <div className="info-row" key={member.id}>
+                      <label>{member.name}:</label>
+                      <span>
+                        {member.email}
+                        {!member.weeklyDigest && ' (unsubscribed)'}
+                      </span>
+                      <Button onClick={() => handleRemoveMember(member)} style={{ background: '#6b7280' }}>
+                        Remove
+                      </Button>
+                    </div>
+                  ))}
+
+                  <form onSubmit={handleAddMember} className="profile-form">
+                    <div className="form-group">
+                      <label htmlFor="memberName">Name</label>
+                      <input
+                        type="text"
+                        id="memberName"
+                        value={memberName}
+                        onChange={(e) => setMemberName(e.target.value)}
+                        required
+                        className="form-input"
+                      />
+                    </div>
+                    <div className="form-group">
+                      <label htmlFor="memberEmail">Email</label>
+                      <input
+                        type="email"
+                        id="memberEmail"
+                        value={memberEmail}
+                        onChange={(e) => setMemberEmail(e.target.value)}
+                        required
+                        className="form-input"
+                      />
+                    </div>
+                    <div className="form-actions">
+                      <Button
+                        type="submit"
+                        style={{ background: '#2563eb' }}
+                        disabled={isSubmitting}
+                      >
+                        Add to Care Circle
+                      </Button>
+                    </div>
+                  </form>
+                </div>
+              </div>
+
               {/* Danger Zone Section */}
               <div className="profile-section danger-zone">
                 <div className="section-header">
@@ -7,6 +7,7 @@ import Link from 'next/link';
 import Page from "../components/page/Page";
 import Button from "../components/button/Button";
 import TextBox from "../components/textbox/TextBox";
+import { apiErrorMessage } from '../../services/apiError';
 
 export default function RegisterPage() {
   const { register, loading } = useAuth();
@@ -26,7 +27,7 @@ export default function RegisterPage() {
       setSuccess('Registration successful! Please check your email to confirm your account.');
       setTimeout(() => router.push('/login'), 2000);
     } catch (err: unknown) {
-      setError(err instanceof Error ? err.message : 'An error occurred');
+      setError(apiErrorMessage(err, err instanceof Error ? err.message : 'An error occurred'));
     }
   }
 
@@ -5,6 +5,7 @@ import { useSearchParams, useRouter } from 'next/navigation';
 import axios from 'axios';
 import Link from 'next/link';
 import Page from "../components/page/Page";
+import { apiErrorMessage } from '../../services/apiError';
 
 export default function ResetPasswordPage() {
   const searchParams = useSearchParams();
@@ -21,11 +22,11 @@ export default function ResetPasswordPage() {
     setMessage('');
     setLoading(true);
     try {
-      await axios.post(`${process.env.NEXT_PUBLIC_API_URL}/auth/reset-password`, { token, password });
+      await axios.post(`${process.env.NEXT_PUBLIC_API_URL}/api/v1/auth/reset-password`, { token, password });
       setMessage('Password reset successful! Redirecting to login...');
       setTimeout(() => router.push('/login'), 2000);
     } catch (err: unknown) {
-      setError(err instanceof Error ? err.message : 'An error occurred');
+      setError(apiErrorMessage(err, err instanceof Error ? err.message : 'An error occurred'));
     } finally {
       setLoading(false);
     }
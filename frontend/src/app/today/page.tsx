@@ -0,0 +1,232 @@
+'use client';
+import { useState, useEffect } from 'react';
+import Link from 'next/link';
+import { useAuth } from '../../context/AuthContext';
+import Page from '../components/page/Page';
+import Button from '../components/button/Button';
+import AuthGuard from '../components/auth-guard/AuthGuard';
+import {
+  Adherence,
+  Routine,
+  ScheduledCheckIn,
+  checkIn,
+  createRoutine,
+  deleteRoutine,
+  getAdherence,
+  getRoutines,
+  getSchedule,
+} from '../../services/routineService';
+import { MemoryPrompt, favoriteMemory, getTodayPrompt, respondToPrompt } from '../../services/reminiscenceService';
+import './page.css';
+import { apiErrorMessage } from '../../services/apiError';
+
+const statusText: Record<ScheduledCheckIn['status'], string> = {
+  pending: 'To do',
+  done: 'Done',
+  skipped: 'Skipped',
+  missed: 'Missed',
+};
+
+export default function Today() {
+  const { token, loading: authLoading } = useAuth();
+  const [schedule, setSchedule] = useState<ScheduledCheckIn[]>([]);
+  const [routines, setRoutines] = useState<Routine[]>([]);
+  const [adherence, setAdherence] = useState<Adherence | null>(null);
+  const [prompt, setPrompt] = useState<MemoryPrompt | null>(null);
+  const [loading, setLoading] = useState(true);
+  const [error, setError] = useState('');
+  const [kind, setKind] = useState<Routine['kind']>('medication');
+  const [title, setTitle] = useState('');
+  const [instructions, setInstructions] = useState('');
+  const [times, setTimes] = useState('09:00');
+  const [recurrence, setRecurrence] = useState('FREQ=DAILY');
+
+  const fetchToday = async (token: string) => {
+    try {
+      setLoading(true);
+      const [scheduleData, routineData, adherenceData, promptData] = await Promise.all([
+        getSchedule(token),
+        getRoutines(token),
+        getAdherence(token, 7),
+        getTodayPrompt(token),
+      ]);
+      setSchedule(scheduleData || []);
+      setRoutines(routineData || []);
+      setAdherence(adherenceData);
+      setPrompt(promptData);
+    } catch (err: any) {
+      setError(apiErrorMessage(err, "Failed to fetch today's schedule"));
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const answer = async (item: ScheduledCheckIn, status: 'done' | 'skipped') => {
+    if (!token) return;
+    try {
+      await checkIn(item, status, token);
+      await fetchToday(token);
+    } catch (err: any) {
+      setError(apiErrorMessage(err, 'Failed to check in'));
+    }
+  };
+
+  const skipPrompt = async (prompt: MemoryPrompt) => {
+    if (!token) return;
+    try {
+      setPrompt(await respondToPrompt(prompt.id, 'skipped', token));
+    } catch (err: any) {
+      setError(apiErrorMessage(err, 'Failed to skip the memory'));
+    }
+  };
+
+  const toggleFavorite = async (prompt: MemoryPrompt) => {
+    if (!token) return;
+    try {
+      const { memory } = await favoriteMemory(prompt.memory.id, !prompt.memory.favorite, token);
+      setPrompt({ ...prompt, memory });
+    } catch (err: any) {
+      setError(apiErrorMessage(err, 'Failed to update the memory'));
+    }
+  };
+
+  const addRoutine = async (e: React.FormEvent) => {
+    e.preventDefault();
+    if (!token) return;
+    try {
+      await createRoutine({
+        kind,
+        title,
+        instructions,
+        recurrence,
+        times: times.split(',').map((time) => time.trim()).filter(Boolean),
+      }, token);
+      setTitle('');
+      setInstructions('');
+      setError('');
+      await fetchToday(token);
+    } catch (err: any) {
+      setError(apiErrorMessage(err, 'Failed to add routine'));
+    }
+  };
+
+  const removeRoutine = async (id: string) => {
+    if (!token) return;
+    try {
+      await deleteRoutine(id, token);
+      await fetchToday(token);
+    } catch (err: any) {
+      setError(apiErrorMessage(err, 'Failed to remove routine'));
+    }
+  };
+
+  // Only fetch data when auth is done loading and we have a token
+  useEffect(() => {
+    if (!authLoading && token && loading) {
+      fetchToday(token);
+    }
+  }, [authLoading, token, loading]);
+
+  return (
+    <AuthGuard>
+      <Page>
+        <div className="today-container">
+          <h1>Today</h1>
+          <p className="today-subtitle">Your medication and activities for today</p>
+
+          {error && <p className="error-message">{error}</p>}
+
+          {prompt && prompt.response !== 'skipped' && (
+            <div className="memory-prompt">
+              <h2>A memory for today</h2>
+              <p className="memory-prompt-title">{prompt.memory.title}</p>
+              <p>{prompt.question}</p>
+              <div className="schedule-actions">
+                <Link href={`/chat?prompt=${prompt.id}`} className="memory-prompt-link">
+                  Talk about it
+                </Link>
+                {prompt.response === 'pending' && (
+                  <Button onClick={() => skipPrompt(prompt)} style={{ background: '#6b7280' }}>
+                    Not today
+                  </Button>
+                )}
+                <Button onClick={() => toggleFavorite(prompt)} style={{ background: '#d97706' }}>
+                  {prompt.memory.favorite ? 'Unfavourite' : 'Favourite'}
+                </Button>
+              </div>
+            </div>
+          )}
+
+          {loading ? (
+            <div className="loading">Loading...</div>
+          ) : schedule.length === 0 ? (
+            <p className="today-empty">Nothing is planned for today.</p>
+          ) : (
+            <ul className="schedule">
+              {schedule.map((item) => (
+                <li key={`${item.routineId}-${item.scheduledAt}`} className={`schedule-item ${item.status}`}>
+                  <div>
+                    <strong>{item.time}</strong> {item.title}
+                    {item.instructions && <p>{item.instructions}</p>}
+                  </div>
+                  {item.status === 'pending' || item.status === 'missed' ? (
+                    <div className="schedule-actions">
+                      <Button onClick={() => answer(item, 'done')} style={{ background: '#16a34a' }}>
+                        Done
+                      </Button>
+                      <Button onClick={() => answer(item, 'skipped')} style={{ background: '#6b7280' }}>
+                        Skip
+                      </Button>
+                    </div>
+                  ) : (
+                    <span className="schedule-status">{statusText[item.status]}</span>
+                  )}
+                </li>
+              ))}
+            </ul>
+          )}
+
+          {adherence?.rate !== undefined && (
+            <p className="adherence">
+              Over the last {adherence.days} days, {adherence.done} of {adherence.scheduled} were done
+              ({Math.round(adherence.rate * 100)}%).
+            </p>
+          )}
+
+          <h2>Routines</h2>
+          <ul className="routines">
+            {routines.map((routine) => (
+              <li key={routine.id}>
+                <span>
+                  <strong>{routine.title}</strong> at {routine.times.join(', ')} ({routine.recurrence})
+                </span>
+                <Button onClick={() => removeRoutine(routine.id)} style={{ background: '#dc2626' }}>
+                  Remove
+                </Button>
+              </li>
+            ))}
+          </ul>
+
+          <form className="routine-form" onSubmit={addRoutine}>
+            <select value={kind} onChange={(e) => setKind(e.target.value as Routine['kind'])}>
+              <option value="medication">Medication</option>
+              <option value="activity">Activity</option>
+            </select>
+            <input value={title} onChange={(e) => setTitle(e.target.value)} placeholder="What, such as Blood pressure tablets" required />
+            <input value={instructions} onChange={(e) => setInstructions(e.target.value)} placeholder="Instructions" />
+            <input value={times} onChange={(e) => setTimes(e.target.value)} placeholder="Times, such as 09:00, 21:00" required />
+            <select value={recurrence} onChange={(e) => setRecurrence(e.target.value)}>
+              <option value="FREQ=DAILY">Every day</option>
+              <option value="FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR">Weekdays</option>
+              <option value="FREQ=WEEKLY">Every week</option>
+              <option value="FREQ=MONTHLY">Every month</option>
+            </select>
+            <Button type="submit" style={{ background: '#2563eb' }}>
+              Add Routine
+            </Button>
+          </form>
+        </div>
+      </Page>
+    </AuthGuard>
+  );
+}
@@ -0,0 +1,40 @@
+'use client';
+import "./page.css"
+import { useEffect, useState } from 'react';
+import { useSearchParams, useRouter } from 'next/navigation';
+import axios from 'axios';
+import Page from "../components/page/Page";
+
+export default function UnlockAccountPage() {
+  const searchParams = useSearchParams();
+  const router = useRouter();
+  const [message, setMessage] = useState('');
+  const [error, setError] = useState('');
+  const token = searchParams.get('token');
+
+  useEffect(() => {
+    if (!token) {
+      setError('Missing token');
+      return;
+    }
+    axios.post(`${process.env.NEXT_PUBLIC_API_URL}/api/v1/auth/unlock`, { token })
+      .then(() => {
+        setMessage('Account unlocked! You can now log in.');
+        setTimeout(() => router.push('/login'), 2000);
+      })
+      .catch((err) => setError(err.message));
+  }, [token, router]);
+
+  return (
+    <Page>
+      <main className="unlock-main">
+        <div className="unlock-container">
+          <h1 className="unlock-title">Unlock Account</h1>
+          {error && <div className="unlock-error">{error}</div>}
+          {message && <div className="unlock-message">{message}</div>}
+          {!error && !message && <div>Unlocking...</div>}
+        </div>
+      </main>
+    </Page>
+  );
+} 
\ No newline at end of file
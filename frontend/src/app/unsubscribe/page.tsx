@@ -0,0 +1,45 @@
+'use client';
+import "./page.css"
+import { useState } from 'react';
+import { useSearchParams } from 'next/navigation';
+import axios from 'axios';
+import Page from "../components/page/Page";
+import Button from '../components/button/Button';
+import { apiErrorMessage } from '../../services/apiError';
+
+// Mail scanners open links, so unsubscribing waits for the button
+export default function UnsubscribePage() {
+  const searchParams = useSearchParams();
+  const [message, setMessage] = useState('');
+  const [error, setError] = useState(searchParams.get('token') ? '' : 'Missing token');
+  const [isSubmitting, setIsSubmitting] = useState(false);
+  const token = searchParams.get('token');
+
+  const unsubscribe = () => {
+    setIsSubmitting(true);
+    axios.post(`${process.env.NEXT_PUBLIC_API_URL}/api/v1/weekly-digest/unsubscribe`, { token })
+      .then(() => setMessage('You will no longer receive the weekly digest.'))
+      .catch((err) => setError(apiErrorMessage(err, err.message)))
+      .finally(() => setIsSubmitting(false));
+  };
+
+  return (
+    <Page>
+      <main className="unsubscribe-main">
+        <div className="unsubscribe-container">
+          <h1 className="unsubscribe-title">Weekly Digest</h1>
+          {error && <div className="unsubscribe-error">{error}</div>}
+          {message && <div className="unsubscribe-message">{message}</div>}
+          {!error && !message && (
+            <>
+              <div className="unsubscribe-text">Stop receiving the weekly email about how your loved one is doing?</div>
+              <Button onClick={unsubscribe} disabled={isSubmitting}>
+                {isSubmitting ? 'Unsubscribing...' : 'Unsubscribe'}
+              </Button>
+            </>
+          )}
+        </div>
+      </main>
+    </Page>
+  );
+}
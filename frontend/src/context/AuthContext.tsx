@@ -31,7 +31,7 @@ export function AuthProvider({ children }: { children: ReactNode }) {
       const t = existingToken || token;
       if (!t) return;
       
-      const res = await axios.get(`${process.env.NEXT_PUBLIC_API_URL}/me`, {
+      const res = await axios.get(`${process.env.NEXT_PUBLIC_API_URL}/api/v1/me`, {
         headers: { Authorization: `Bearer ${t}` },
       });
       
@@ -57,7 +57,7 @@ export function AuthProvider({ children }: { children: ReactNode }) {
   async function login(email: string, password: string) {
     setLoading(true);
     try {
-      const res = await axios.post(`${process.env.NEXT_PUBLIC_API_URL}/auth/login`, { email, password });
+      const res = await axios.post(`${process.env.NEXT_PUBLIC_API_URL}/api/v1/auth/login`, { email, password });
       
       console.log('Login response:', res.data);
       
@@ -81,7 +81,7 @@ export function AuthProvider({ children }: { children: ReactNode }) {
   async function register(email: string, password: string, displayName: string) {
     setLoading(true);
     try {
-      await axios.post(`${process.env.NEXT_PUBLIC_API_URL}/auth/register`, { email, password, displayName });
+      await axios.post(`${process.env.NEXT_PUBLIC_API_URL}/api/v1/auth/register`, { email, password, displayName });
     } catch (error) {
       console.error('Registration failed:', error);
       throw error;
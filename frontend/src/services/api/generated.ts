@@ -0,0 +1,949 @@
+// Code generated by backend/api/cmd/tsclient from backend/api/openapi.yaml. DO NOT EDIT.
+
+import axios, { AxiosRequestConfig } from 'axios';
+
+export interface RequestOptions extends AxiosRequestConfig {
+  /** Session token, sent as a bearer token */
+  token?: string;
+}
+
+const request = async <T>(config: AxiosRequestConfig, options: RequestOptions = {}): Promise<T> => {
+  const { token, headers, ...rest } = options;
+  const response = await axios.request<T>({
+    ...rest,
+    ...config,
+    baseURL: process.env.NEXT_PUBLIC_API_URL,
+    headers: token ? { ...headers, Authorization: `Bearer ${token}` } : headers,
+  });
+  return response.data;
+};
+
+export interface AddCareCircleMemberRequest {
+  email: string;
+  name?: string;
+}
+
+export interface AdherenceResponse {
+  /** One entry per day, oldest first */
+  daily: DailyAdherence[];
+  days: number;
+  done: number;
+  missed: number;
+  /** Share of scheduled check-ins done */
+  rate?: number;
+  routines: RoutineAdherence[];
+  /** Occurrences due so far; pending ones are left out */
+  scheduled: number;
+  skipped: number;
+}
+
+export interface ApiError {
+  /** Stable machine-readable code, such as `memory_not_found` */
+  code: string;
+  /** Problems with individual request fields, keyed by field name */
+  fields?: Record<string, ApiFieldError[]>;
+  /** English fallback message */
+  message: string;
+  /** ID to quote when reporting the problem */
+  request_id?: string;
+}
+
+export interface ApiFieldError {
+  code: string;
+  message: string;
+}
+
+export interface AuthResponse {
+  /** Session token to send as `Authorization: Bearer <token>` */
+  token: string;
+  user: User;
+}
+
+export interface AuthorizationResponse {
+  authorizationUrl: string;
+}
+
+export interface CalendarFeedResponse {
+  /** Secret URL calendar apps subscribe to */
+  url: string;
+}
+
+export interface CalendarImportResponse {
+  birthdaysSet: number;
+  memoriesCreated: number;
+  memoriesUpdated: number;
+  peopleCreated: number;
+  skipped: SkippedEvent[];
+  /** Events imported before that have not changed */
+  unchanged: number;
+}
+
+export interface CareCircleMemberResponse {
+  createdAt: string;
+  email: string;
+  id: string;
+  name: string;
+  /** False once the member unsubscribes */
+  weeklyDigest: boolean;
+}
+
+export interface ChangePasswordRequest {
+  currentPassword: string;
+  newPassword: string;
+}
+
+export interface ChatHistoryResponse {
+  messages: ChatMessage[];
+}
+
+export interface ChatMessage {
+  content: string;
+  createdAt: string;
+  id: string;
+  role: 'user' | 'assistant';
+  user_id: string;
+}
+
+export interface ChatRequest {
+  message: string;
+  /** The memory prompt the user is answering. Its question opens the conversation and the prompt is recorded as engaged with. */
+  promptId?: string;
+}
+
+export interface ChatResponse {
+  message: string;
+}
+
+export interface CheckInRequest {
+  note?: string;
+  /** The occurrence checked in for */
+  scheduledAt: string;
+  status: 'done' | 'skipped';
+}
+
+export interface CheckInResponse {
+  checkedInAt?: string;
+  id: string;
+  note: string;
+  routineId: string;
+  scheduledAt: string;
+  status: CheckInStatus;
+}
+
+/** Pending occurrences are neither checked in nor late yet */
+export type CheckInStatus = 'pending' | 'done' | 'skipped' | 'missed';
+
+export interface CheckResult {
+  error?: string;
+  optional?: boolean;
+  status: string;
+}
+
+export interface ContactCadenceRequest {
+  /** Days between reminders; 0 turns reminders off */
+  everyDays: number;
+}
+
+export interface CreateInteractionRequest {
+  /** A memory made during the interaction */
+  memoryId?: string;
+  notes?: string;
+  /** When it happened; now by default and never in the future */
+  occurredAt?: string;
+  type: 'call' | 'visit' | 'message';
+}
+
+export interface CreateMemoryRequest {
+  content: string;
+  /** Favourite memories come back more often in prompts */
+  favorite?: boolean;
+  /** The date the memory is from */
+  occurredOn?: string;
+  peopleIds?: string[];
+  photoId?: string;
+  title: string;
+  type: string;
+}
+
+export interface CreatePersonRequest {
+  /** The user's anniversary with the person, such as a wedding */
+  anniversary?: string;
+  birthday?: string;
+  /** Remind the user to reach out every so many days; 0 turns reminders off */
+  contactEveryDays?: number;
+  /** Date of death; there are no reminders to reach out after it */
+  diedOn?: string;
+  email: string;
+  firstName: string;
+  lastName: string;
+  notes?: string;
+  phone: string;
+  photoId?: string;
+  relationship: string;
+}
+
+export interface DailyAdherence {
+  date: string;
+  done: number;
+  missed: number;
+  rate?: number;
+  scheduled: number;
+  skipped: number;
+}
+
+export interface EmailChangeRequest {
+  currentPassword: string;
+  newEmail: string;
+}
+
+export interface EmailChangeResponse {
+  message: string;
+  pendingEmail: string;
+}
+
+export interface EmailPreview {
+  html: string;
+  locale: Locale;
+  subject: string;
+  template: string;
+  text: string;
+}
+
+export interface EmailRequest {
+  email: string;
+}
+
+/** Pending emails are waiting to be sent or retried; failed ones ran out of attempts */
+export type EmailStatus = 'pending' | 'sent' | 'failed';
+
+export interface EmailTemplatesResponse {
+  locales: Locale[];
+  templates: string[];
+}
+
+export interface ErrorResponse {
+  error: ApiError;
+}
+
+export interface FavoriteMemoryRequest {
+  favorite: boolean;
+}
+
+export interface IdentitiesResponse {
+  identities: Identity[];
+}
+
+export interface Identity {
+  createdAt: string;
+  email: string;
+  id: string;
+  provider: string;
+}
+
+export interface InsightsResponse {
+  days: number;
+  /** People not in touch within the window */
+  notContacted: string[];
+  /** Never contacted first, then the longest out of touch */
+  people: PersonInsight[];
+  previousCount: number;
+  recentCount: number;
+  trend: Trend;
+}
+
+export interface InteractionResponse {
+  createdAt: string;
+  id: string;
+  memoryId?: string;
+  notes: string;
+  occurredAt: string;
+  personId: string;
+  type: 'call' | 'visit' | 'message';
+}
+
+export interface Job {
+  attempts: number;
+  createdAt: string;
+  finishedAt?: string;
+  id: string;
+  /** Makes the job unique, such as the scheduled run of a periodic job */
+  key?: string;
+  kind: string;
+  lastError?: string;
+  /** When a running job may be taken over if its worker has not finished it */
+  lockedUntil?: string;
+  maxAttempts: number;
+  /** The JSON the job was queued with */
+  payload: unknown;
+  /** When a pending job is next run */
+  runAt: string;
+  status: JobStatus;
+}
+
+/** Pending jobs are waiting to run or be retried; dead ones ran out of attempts */
+export type JobStatus = 'pending' | 'running' | 'succeeded' | 'dead';
+
+export interface LivenessResponse {
+  status: string;
+}
+
+/** Language emails are written in. At registration it defaults to the closest match to the Accept-Language header. */
+export type Locale = 'en' | 'es';
+
+export interface LoginRequest {
+  email: string;
+  password: string;
+}
+
+export interface MemoriesResponse {
+  memories: MemoryResponse[];
+}
+
+export interface MemoryEnvelope {
+  memory: MemoryResponse;
+}
+
+export interface MemoryPromptListResponse {
+  engaged: number;
+  prompts: MemoryPromptResponse[];
+  skipped: number;
+  unanswered: number;
+}
+
+export interface MemoryPromptResponse {
+  /** The date in the user's timezone the prompt is for */
+  day: string;
+  id: string;
+  memory: MemoryResponse;
+  /** The person a prompt for the person reason is about */
+  personId?: string;
+  /** What the assistant opens the conversation with */
+  question: string;
+  /** Why the memory came up: it is from this day in an earlier year, it is with someone the user has not talked about lately, it is a favourite, or its turn came round again */
+  reason: 'on_this_day' | 'person' | 'favorite' | 'revisit';
+  respondedAt?: string;
+  response: 'pending' | 'engaged' | 'skipped';
+}
+
+export interface MemoryResponse {
+  content: string;
+  createdAt: string;
+  favorite: boolean;
+  id: string;
+  occurredOn?: string;
+  people?: PersonResponse[];
+  photoId?: string;
+  photoUrl?: string;
+  title: string;
+  type: string;
+}
+
+export interface MessageResponse {
+  message: string;
+}
+
+export interface Notification {
+  body: string;
+  createdAt: string;
+  /** IDs of what the notification is about, such as `routineId` */
+  data: Record<string, string>;
+  id: string;
+  readAt?: string;
+  title: string;
+  type: NotificationType;
+  /** App path the notification opens */
+  url: string;
+}
+
+/** Emails about missed check-ins go to the caregiver */
+export type NotificationChannel = 'in_app' | 'push' | 'email';
+
+export interface NotificationListResponse {
+  notifications: Notification[];
+  unread: number;
+}
+
+export interface NotificationPreference {
+  channel: NotificationChannel;
+  enabled: boolean;
+  type: NotificationType;
+}
+
+export interface NotificationPreferenceRequest {
+  channel: NotificationChannel;
+  enabled: boolean;
+  type: NotificationType;
+}
+
+export interface NotificationPreferencesRequest {
+  preferences: NotificationPreferenceRequest[];
+}
+
+export interface NotificationPreferencesResponse {
+  /** Routines coming due are not emailed, so that pair is left out */
+  preferences: NotificationPreference[];
+}
+
+export type NotificationType = 'reminder' | 'routine_due' | 'check_in_missed' | 'reminiscence';
+
+export interface OIDCCallbackRequest {
+  code: string;
+  state: string;
+}
+
+export interface OutboxEmail {
+  attempts: number;
+  createdAt: string;
+  id: string;
+  lastError?: string;
+  locale: Locale;
+  /** When a pending email is next tried */
+  nextAttemptAt?: string;
+  recipient: string;
+  sentAt?: string;
+  status: EmailStatus;
+  subject: string;
+  template: string;
+  userId: string;
+}
+
+/** Replaces all three dates; dates left out are cleared */
+export interface PersonDatesRequest {
+  anniversary?: string;
+  birthday?: string;
+  diedOn?: string;
+}
+
+export interface PersonInsight {
+  /** Mean days between logged interactions */
+  averageGapDays?: number;
+  daysSinceContact?: number;
+  interactionCount: number;
+  lastContactAt?: string;
+  personId: string;
+  personName: string;
+  /** Interactions in the window before it */
+  previousCount: number;
+  /** Interactions in the window */
+  recentCount: number;
+  trend: Trend;
+}
+
+export interface PersonResponse {
+  anniversary?: string;
+  birthday?: string;
+  contactEveryDays: number;
+  diedOn?: string;
+  email: string;
+  firstName: string;
+  id: string;
+  lastContactedAt?: string;
+  lastName: string;
+  nextReminderAt?: string;
+  notes: string;
+  phone: string;
+  photoId?: string;
+  photoUrl?: string;
+  relationship: string;
+}
+
+export interface Photo {
+  filename: string;
+  filetype: string;
+  uploadedAt: string;
+  url: string;
+}
+
+export interface Provider {
+  displayName: string;
+  name: string;
+}
+
+export interface ProvidersResponse {
+  providers: Provider[];
+}
+
+export interface PushKeyResponse {
+  /** VAPID public key in base64url, the `applicationServerKey` to subscribe with */
+  publicKey: string;
+}
+
+/** A browser's `PushSubscription.toJSON()` */
+export interface PushSubscriptionRequest {
+  /** HTTPS URL of the push service */
+  endpoint: string;
+  keys: {
+    auth: string;
+    p256dh: string;
+  };
+}
+
+export interface ReadinessReport {
+  checks: Record<string, CheckResult>;
+  status: 'ok' | 'degraded' | 'unavailable';
+}
+
+export interface RegisterRequest {
+  displayName: string;
+  email: string;
+  locale?: Locale;
+  /** Checked against the password policy */
+  password: string;
+}
+
+export interface ReminderResponse {
+  dueAt: string;
+  email: string;
+  id: string;
+  personId: string;
+  personName: string;
+  phone: string;
+  resolvedAt?: string;
+  snoozedUntil?: string;
+  status: 'open' | 'completed' | 'dismissed';
+}
+
+export interface ResetPasswordRequest {
+  password: string;
+  token: string;
+}
+
+export interface RespondToPromptRequest {
+  response: 'engaged' | 'skipped';
+}
+
+export interface RoutineAdherence {
+  done: number;
+  kind: 'medication' | 'activity';
+  missed: number;
+  /** Share of scheduled check-ins done */
+  rate?: number;
+  routineId: string;
+  scheduled: number;
+  skipped: number;
+  title: string;
+}
+
+export interface RoutineRequest {
+  /** How late a check-in may be before it counts as missed and the caregiver is emailed, 60 by default */
+  graceMinutes?: number;
+  instructions?: string;
+  kind: 'medication' | 'activity';
+  /** RRULE with FREQ of DAILY, WEEKLY or MONTHLY and optionally INTERVAL, BYDAY, BYMONTHDAY and UNTIL, such as FREQ=WEEKLY;BYDAY=MO,TH */
+  recurrence: string;
+  /** Today by default when creating; kept when replacing */
+  startsOn?: string;
+  /** Times of day in the user's timezone */
+  times: string[];
+  title: string;
+}
+
+export interface RoutineResponse {
+  createdAt: string;
+  graceMinutes: number;
+  id: string;
+  instructions: string;
+  kind: 'medication' | 'activity';
+  /** Next occurrence not yet settled; missing once the routine has ended */
+  nextDueAt?: string;
+  recurrence: string;
+  startsOn: string;
+  times: string[];
+  title: string;
+}
+
+export interface ScheduledCheckIn {
+  checkedInAt?: string;
+  instructions: string;
+  kind: 'medication' | 'activity';
+  note?: string;
+  routineId: string;
+  scheduledAt: string;
+  status: CheckInStatus;
+  /** Time of day in the user's timezone */
+  time: string;
+  title: string;
+}
+
+export interface SkippedEvent {
+  reason: 'cancelled' | 'no_uid' | 'no_date' | 'from_luma' | 'recurring' | 'future' | 'birthday_set';
+  summary: string;
+  uid?: string;
+}
+
+export interface SnoozeReminderRequest {
+  until: string;
+}
+
+export interface TokenRequest {
+  token: string;
+}
+
+/** Whether the window had more interactions than the one before it */
+export type Trend = 'up' | 'down' | 'steady';
+
+export interface UnreadCountResponse {
+  unread: number;
+}
+
+export interface UpcomingEvent {
+  date: string;
+  /** 0 for today */
+  daysAway: number;
+  /** The person has passed away */
+  deceased: boolean;
+  kind: 'birthday' | 'anniversary' | 'death_anniversary' | 'memory_anniversary';
+  memoryId?: string;
+  personId?: string;
+  personName?: string;
+  /** Describes the event, such as "Tom Hughes turns 12" */
+  title: string;
+  /** Age turned or years since the original date */
+  years: number;
+}
+
+export interface UpdateProfileRequest {
+  /** Address that receives the daily digest of upcoming dates. Left alone when missing; an empty string removes it. */
+  caregiverEmail?: string;
+  displayName?: string;
+  locale?: Locale;
+  /** IANA timezone that routine times are in. Left alone when missing. */
+  timezone?: string;
+  /** Whether the caregiver gets the weekly digest. Left alone when missing; a new caregiver gets it until they unsubscribe. */
+  weeklyDigest?: boolean;
+}
+
+export interface UploadPhotoResponse {
+  id: string;
+  key: string;
+  message: string;
+}
+
+export interface User {
+  /** Receives the daily digest of upcoming dates and the weekly digest */
+  caregiverEmail?: string;
+  createdAt: string;
+  displayName: string;
+  email: string;
+  id: string;
+  locale?: Locale;
+  /** New email awaiting verification */
+  pendingEmail?: string;
+  /** IANA timezone that routine times are in, such as Europe/London */
+  timezone?: string;
+  /** Whether the caregiver gets the weekly digest; missing when there is no caregiver or they unsubscribed */
+  weeklyDigest?: boolean;
+}
+
+export interface UserMessageResponse {
+  message: string;
+  user: User;
+}
+
+/** Reports that the backend is running */
+export const getServiceInfo = (options?: RequestOptions) =>
+  request<MessageResponse>({ method: 'GET', url: `/` }, options);
+
+/** Reports how many scheduled check-ins were done, skipped or missed */
+export const getAdherence = (query?: { days?: number }, options?: RequestOptions) =>
+  request<AdherenceResponse>({ method: 'GET', url: `/api/v1/adherence`, params: query }, options);
+
+/** Lists the email templates and the languages they are written in */
+export const getEmailTemplates = (options?: RequestOptions) =>
+  request<EmailTemplatesResponse>({ method: 'GET', url: `/api/v1/admin/email-templates` }, options);
+
+/** Renders an email template with sample data */
+export const previewEmailTemplate = (name: string, query?: { locale?: Locale }, options?: RequestOptions) =>
+  request<EmailPreview>({ method: 'GET', url: `/api/v1/admin/email-templates/${encodeURIComponent(name)}/preview`, params: query }, options);
+
+/** Lists emails in the outbox and their delivery status, newest first */
+export const getOutboxEmails = (query?: { status?: EmailStatus; limit?: number }, options?: RequestOptions) =>
+  request<OutboxEmail[]>({ method: 'GET', url: `/api/v1/admin/emails`, params: query }, options);
+
+/** Puts a failed email back in the outbox with fresh attempts */
+export const retryOutboxEmail = (id: string, options?: RequestOptions) =>
+  request<OutboxEmail>({ method: 'POST', url: `/api/v1/admin/emails/${encodeURIComponent(id)}/retry` }, options);
+
+/** Lists background jobs, newest first */
+export const getJobs = (query?: { status?: JobStatus; kind?: string; limit?: number }, options?: RequestOptions) =>
+  request<Job[]>({ method: 'GET', url: `/api/v1/admin/jobs`, params: query }, options);
+
+/** Returns a background job with its payload and last error */
+export const getJob = (id: string, options?: RequestOptions) =>
+  request<Job>({ method: 'GET', url: `/api/v1/admin/jobs/${encodeURIComponent(id)}` }, options);
+
+/** Puts a dead job back in the queue with fresh attempts */
+export const retryJob = (id: string, options?: RequestOptions) =>
+  request<Job>({ method: 'POST', url: `/api/v1/admin/jobs/${encodeURIComponent(id)}/retry` }, options);
+
+/** Cancels or reverts an email change with the token sent to the old address */
+export const cancelEmailChange = (body: TokenRequest, options?: RequestOptions) =>
+  request<MessageResponse>({ method: 'POST', url: `/api/v1/auth/cancel-email-change`, data: body }, options);
+
+/** Confirms an email address with the token from the confirmation email */
+export const confirmEmail = (body?: TokenRequest, query?: { token?: string }, options?: RequestOptions) =>
+  request<MessageResponse>({ method: 'POST', url: `/api/v1/auth/confirm`, data: body, params: query }, options);
+
+/** Confirms a new email address with the token sent to it */
+export const confirmEmailChange = (body: TokenRequest, options?: RequestOptions) =>
+  request<MessageResponse>({ method: 'POST', url: `/api/v1/auth/confirm-email-change`, data: body }, options);
+
+/** Sends a password reset link */
+export const forgotPassword = (body: EmailRequest, options?: RequestOptions) =>
+  request<MessageResponse>({ method: 'POST', url: `/api/v1/auth/forgot-password`, data: body }, options);
+
+/** Exchanges an email and password for a session token */
+export const login = (body: LoginRequest, options?: RequestOptions) =>
+  request<AuthResponse>({ method: 'POST', url: `/api/v1/auth/login`, data: body }, options);
+
+/** Lists the identity providers users can sign in with */
+export const getOIDCProviders = (options?: RequestOptions) =>
+  request<ProvidersResponse>({ method: 'GET', url: `/api/v1/auth/oidc/providers` }, options);
+
+/** Completes a sign-in or account link after the provider redirects back */
+export const oidcCallback = (provider: string, body: OIDCCallbackRequest, options?: RequestOptions) =>
+  request<AuthResponse | MessageResponse>({ method: 'POST', url: `/api/v1/auth/oidc/${encodeURIComponent(provider)}/callback`, data: body }, options);
+
+/** Begins signing in with an identity provider */
+export const startOIDCLogin = (provider: string, options?: RequestOptions) =>
+  request<AuthorizationResponse>({ method: 'GET', url: `/api/v1/auth/oidc/${encodeURIComponent(provider)}/start` }, options);
+
+/** Creates an account and sends a confirmation email */
+export const register = (body: RegisterRequest, options?: RequestOptions) =>
+  request<UserMessageResponse>({ method: 'POST', url: `/api/v1/auth/register`, data: body }, options);
+
+/** Sends a new confirmation link to an unconfirmed account */
+export const resendConfirmation = (body: EmailRequest, options?: RequestOptions) =>
+  request<MessageResponse>({ method: 'POST', url: `/api/v1/auth/resend-confirmation`, data: body }, options);
+
+/** Sets a new password with the token from the reset email */
+export const resetPassword = (body: ResetPasswordRequest, options?: RequestOptions) =>
+  request<MessageResponse>({ method: 'POST', url: `/api/v1/auth/reset-password`, data: body }, options);
+
+/** Lifts a login lockout with the token from the unlock email */
+export const unlockAccount = (body: TokenRequest, options?: RequestOptions) =>
+  request<MessageResponse>({ method: 'POST', url: `/api/v1/auth/unlock`, data: body }, options);
+
+/** Issues a new secret iCalendar feed URL, turning off any earlier one */
+export const createCalendarFeed = (options?: RequestOptions) =>
+  request<CalendarFeedResponse>({ method: 'POST', url: `/api/v1/calendar/feed` }, options);
+
+/** Turns off the calendar feed */
+export const deleteCalendarFeed = (options?: RequestOptions) =>
+  request<MessageResponse>({ method: 'DELETE', url: `/api/v1/calendar/feed` }, options);
+
+/** Returns birthdays, anniversaries, reach-out reminders and routines as iCalendar */
+export const getCalendarFeed = (file: string, options?: RequestOptions) =>
+  request<void>({ method: 'GET', url: `/api/v1/calendar/feed/${encodeURIComponent(file)}` }, options);
+
+/** Imports past events of an .ics file as memories and birthday events as birthdays */
+export const importCalendar = (body: FormData, options?: RequestOptions) =>
+  request<CalendarImportResponse>({ method: 'POST', url: `/api/v1/calendar/import`, data: body }, options);
+
+/** Changes the signed-in user's password */
+export const changePassword = (body: ChangePasswordRequest, options?: RequestOptions) =>
+  request<MessageResponse>({ method: 'PUT', url: `/api/v1/change-password`, data: body }, options);
+
+/** Asks the assistant a question answered from the user's memories and people */
+export const chat = (body: ChatRequest, query?: { stream?: boolean }, options?: RequestOptions) =>
+  request<ChatResponse>({ method: 'POST', url: `/api/v1/chat`, data: body, params: query }, options);
+
+/** Lists past chat messages */
+export const getChatHistory = (query?: { limit?: number }, options?: RequestOptions) =>
+  request<ChatHistoryResponse>({ method: 'GET', url: `/api/v1/chat/history`, params: query }, options);
+
+/** Liveness probe */
+export const getLiveness = (options?: RequestOptions) =>
+  request<LivenessResponse>({ method: 'GET', url: `/healthz` }, options);
+
+/** Summarizes how often the user is in touch with each of their people */
+export const getInsights = (query?: { days?: number }, options?: RequestOptions) =>
+  request<InsightsResponse>({ method: 'GET', url: `/api/v1/insights`, params: query }, options);
+
+/** Returns the signed-in user */
+export const getCurrentUser = (options?: RequestOptions) =>
+  request<User>({ method: 'GET', url: `/api/v1/me` }, options);
+
+/** Lists the signed-in user's memories, newest first */
+export const getMemories = (options?: RequestOptions) =>
+  request<MemoriesResponse>({ method: 'GET', url: `/api/v1/memories` }, options);
+
+/** Records a memory, optionally with a photo and the people in it */
+export const createMemory = (body: CreateMemoryRequest, options?: RequestOptions) =>
+  request<MemoryEnvelope>({ method: 'POST', url: `/api/v1/memories`, data: body }, options);
+
+/** Marks a memory as a favourite, which comes back more often in prompts, or unmarks it */
+export const favoriteMemory = (id: string, body: FavoriteMemoryRequest, options?: RequestOptions) =>
+  request<MemoryEnvelope>({ method: 'PUT', url: `/api/v1/memories/${encodeURIComponent(id)}/favorite`, data: body }, options);
+
+/** Lists in-app notifications, newest first, with the number unread */
+export const getNotifications = (query?: { unread?: boolean; limit?: number }, options?: RequestOptions) =>
+  request<NotificationListResponse>({ method: 'GET', url: `/api/v1/notifications`, params: query }, options);
+
+/** Lists which channels each type of notification is sent on */
+export const getNotificationPreferences = (options?: RequestOptions) =>
+  request<NotificationPreferencesResponse>({ method: 'GET', url: `/api/v1/notifications/preferences` }, options);
+
+/** Turns channels on or off, keeping the preferences not named */
+export const updateNotificationPreferences = (body: NotificationPreferencesRequest, options?: RequestOptions) =>
+  request<NotificationPreferencesResponse>({ method: 'PUT', url: `/api/v1/notifications/preferences`, data: body }, options);
+
+/** Returns the VAPID public key browsers subscribe to push with */
+export const getPushKey = (options?: RequestOptions) =>
+  request<PushKeyResponse>({ method: 'GET', url: `/api/v1/notifications/push-key` }, options);
+
+/** Saves a browser's push subscription, replacing any with the same endpoint */
+export const subscribePush = (body: PushSubscriptionRequest, options?: RequestOptions) =>
+  request<MessageResponse>({ method: 'POST', url: `/api/v1/notifications/push-subscriptions`, data: body }, options);
+
+/** Deletes a browser's push subscription */
+export const unsubscribePush = (query?: { endpoint?: string }, options?: RequestOptions) =>
+  request<MessageResponse>({ method: 'DELETE', url: `/api/v1/notifications/push-subscriptions`, params: query }, options);
+
+/** Marks every notification read */
+export const markAllNotificationsRead = (options?: RequestOptions) =>
+  request<UnreadCountResponse>({ method: 'POST', url: `/api/v1/notifications/read-all` }, options);
+
+/** Streams new notifications and the unread count as server-sent events */
+export const streamNotifications = (options?: RequestOptions) =>
+  request<void>({ method: 'GET', url: `/api/v1/notifications/stream` }, options);
+
+/** Marks a notification read */
+export const markNotificationRead = (id: string, options?: RequestOptions) =>
+  request<Notification>({ method: 'POST', url: `/api/v1/notifications/${encodeURIComponent(id)}/read` }, options);
+
+/** This specification */
+export const getOpenAPISpec = (options?: RequestOptions) =>
+  request<Record<string, unknown>>({ method: 'GET', url: `/api/v1/openapi.json` }, options);
+
+/** Lists the people the signed-in user has added */
+export const getPeople = (options?: RequestOptions) =>
+  request<PersonResponse[]>({ method: 'GET', url: `/api/v1/people` }, options);
+
+/** Adds a person */
+export const createPerson = (body: CreatePersonRequest, options?: RequestOptions) =>
+  request<PersonResponse>({ method: 'POST', url: `/api/v1/people`, data: body }, options);
+
+/** Sets how often to be reminded to reach out to a person */
+export const setContactCadence = (id: string, body: ContactCadenceRequest, options?: RequestOptions) =>
+  request<PersonResponse>({ method: 'PUT', url: `/api/v1/people/${encodeURIComponent(id)}/contact-cadence`, data: body }, options);
+
+/** Sets a person's birthday, anniversary and date of death */
+export const setPersonDates = (id: string, body: PersonDatesRequest, options?: RequestOptions) =>
+  request<PersonResponse>({ method: 'PUT', url: `/api/v1/people/${encodeURIComponent(id)}/dates`, data: body }, options);
+
+/** Lists the calls, visits and messages logged with a person, newest first */
+export const getInteractions = (id: string, options?: RequestOptions) =>
+  request<InteractionResponse[]>({ method: 'GET', url: `/api/v1/people/${encodeURIComponent(id)}/interactions` }, options);
+
+/** Logs a call, visit or message with a person */
+export const createInteraction = (id: string, body: CreateInteractionRequest, options?: RequestOptions) =>
+  request<InteractionResponse>({ method: 'POST', url: `/api/v1/people/${encodeURIComponent(id)}/interactions`, data: body }, options);
+
+/** Returns a short-lived download URL for a photo */
+export const getPhoto = (id: string, options?: RequestOptions) =>
+  request<Photo>({ method: 'GET', url: `/api/v1/photos/${encodeURIComponent(id)}` }, options);
+
+/** Updates the signed-in user's profile */
+export const updateProfile = (body: UpdateProfileRequest, options?: RequestOptions) =>
+  request<UserMessageResponse>({ method: 'PUT', url: `/api/v1/profile`, data: body }, options);
+
+/** Deletes the signed-in user's account and everything in it */
+export const deleteAccount = (options?: RequestOptions) =>
+  request<MessageResponse>({ method: 'DELETE', url: `/api/v1/profile` }, options);
+
+/** Lists the people who get the weekly digest besides the caregiver, oldest first */
+export const getCareCircle = (options?: RequestOptions) =>
+  request<CareCircleMemberResponse[]>({ method: 'GET', url: `/api/v1/profile/care-circle` }, options);
+
+/** Adds someone to the weekly digest, from the next one on */
+export const addCareCircleMember = (body: AddCareCircleMemberRequest, options?: RequestOptions) =>
+  request<CareCircleMemberResponse>({ method: 'POST', url: `/api/v1/profile/care-circle`, data: body }, options);
+
+/** Removes someone from the weekly digest */
+export const deleteCareCircleMember = (id: string, options?: RequestOptions) =>
+  request<MessageResponse>({ method: 'DELETE', url: `/api/v1/profile/care-circle/${encodeURIComponent(id)}` }, options);
+
+/** Starts changing the login email by verifying the new address */
+export const requestEmailChange = (body: EmailChangeRequest, options?: RequestOptions) =>
+  request<EmailChangeResponse>({ method: 'POST', url: `/api/v1/profile/email`, data: body }, options);
+
+/** Lists the identity provider accounts linked to the signed-in user */
+export const getIdentities = (options?: RequestOptions) =>
+  request<IdentitiesResponse>({ method: 'GET', url: `/api/v1/profile/identities` }, options);
+
+/** Begins linking an identity provider account to the signed-in user */
+export const startOIDCLink = (id: string, options?: RequestOptions) =>
+  request<AuthorizationResponse>({ method: 'POST', url: `/api/v1/profile/identities/${encodeURIComponent(id)}` }, options);
+
+/** Unlinks an identity provider account */
+export const deleteIdentity = (id: string, options?: RequestOptions) =>
+  request<MessageResponse>({ method: 'DELETE', url: `/api/v1/profile/identities/${encodeURIComponent(id)}` }, options);
+
+/** Readiness probe with the status of each dependency */
+export const getReadiness = (options?: RequestOptions) =>
+  request<ReadinessReport>({ method: 'GET', url: `/readyz` }, options);
+
+/** Lists the reminders to reach out that are due and not snoozed */
+export const getReminders = (options?: RequestOptions) =>
+  request<ReminderResponse[]>({ method: 'GET', url: `/api/v1/reminders` }, options);
+
+/** Records that the user got in touch and schedules the next reminder */
+export const completeReminder = (id: string, options?: RequestOptions) =>
+  request<ReminderResponse>({ method: 'POST', url: `/api/v1/reminders/${encodeURIComponent(id)}/complete` }, options);
+
+/** Closes a reminder without recording a contact */
+export const dismissReminder = (id: string, options?: RequestOptions) =>
+  request<ReminderResponse>({ method: 'POST', url: `/api/v1/reminders/${encodeURIComponent(id)}/dismiss` }, options);
+
+/** Hides a reminder until a later time, when it is shown and emailed again */
+export const snoozeReminder = (id: string, body: SnoozeReminderRequest, options?: RequestOptions) =>
+  request<ReminderResponse>({ method: 'POST', url: `/api/v1/reminders/${encodeURIComponent(id)}/snooze`, data: body }, options);
+
+/** Lists recent memory prompts, newest first, with how many were engaged with or skipped */
+export const getMemoryPrompts = (query?: { days?: number }, options?: RequestOptions) =>
+  request<MemoryPromptListResponse>({ method: 'GET', url: `/api/v1/reminiscence`, params: query }, options);
+
+/** Returns the memory to reminisce about today, choosing it if the daily job has not yet */
+export const getTodayMemoryPrompt = (options?: RequestOptions) =>
+  request<MemoryPromptResponse>({ method: 'GET', url: `/api/v1/reminiscence/today` }, options);
+
+/** Records whether the user engaged with or skipped a memory prompt */
+export const respondToMemoryPrompt = (id: string, body: RespondToPromptRequest, options?: RequestOptions) =>
+  request<MemoryPromptResponse>({ method: 'POST', url: `/api/v1/reminiscence/${encodeURIComponent(id)}/respond`, data: body }, options);
+
+/** Lists the user's routines, oldest first */
+export const getRoutines = (options?: RequestOptions) =>
+  request<RoutineResponse[]>({ method: 'GET', url: `/api/v1/routines` }, options);
+
+/** Adds a routine, such as tablets at 09:00 every day */
+export const createRoutine = (body: RoutineRequest, options?: RequestOptions) =>
+  request<RoutineResponse>({ method: 'POST', url: `/api/v1/routines`, data: body }, options);
+
+/** Replaces a routine, keeping its check-ins */
+export const updateRoutine = (id: string, body: RoutineRequest, options?: RequestOptions) =>
+  request<RoutineResponse>({ method: 'PUT', url: `/api/v1/routines/${encodeURIComponent(id)}`, data: body }, options);
+
+/** Deletes a routine and its check-ins */
+export const deleteRoutine = (id: string, options?: RequestOptions) =>
+  request<MessageResponse>({ method: 'DELETE', url: `/api/v1/routines/${encodeURIComponent(id)}` }, options);
+
+/** Confirms or skips one occurrence of a routine */
+export const checkIn = (id: string, body: CheckInRequest, options?: RequestOptions) =>
+  request<CheckInResponse>({ method: 'POST', url: `/api/v1/routines/${encodeURIComponent(id)}/check-ins`, data: body }, options);
+
+/** Lists the routines scheduled on a day, in order, with their check-ins */
+export const getSchedule = (query?: { date?: string }, options?: RequestOptions) =>
+  request<ScheduledCheckIn[]>({ method: 'GET', url: `/api/v1/schedule`, params: query }, options);
+
+/** Lists birthdays, anniversaries and memory anniversaries coming up, soonest first */
+export const getUpcoming = (query?: { days?: number }, options?: RequestOptions) =>
+  request<UpcomingEvent[]>({ method: 'GET', url: `/api/v1/upcoming`, params: query }, options);
+
+/** Uploads a photo to attach to a memory or person */
+export const uploadPhoto = (body: FormData, options?: RequestOptions) =>
+  request<UploadPhotoResponse>({ method: 'POST', url: `/api/v1/upload-photo`, data: body }, options);
+
+/** Stops the weekly digest with the token from its unsubscribe link */
+export const unsubscribeWeeklyDigest = (body: TokenRequest, options?: RequestOptions) =>
+  request<MessageResponse>({ method: 'POST', url: `/api/v1/weekly-digest/unsubscribe`, data: body }, options);
@@ -0,0 +1,19 @@
+import type { ApiError } from './api/generated';
+
+// apiError returns the error envelope of a failed API request, if any
+export const apiError = (err: any): ApiError | undefined => {
+  const body = err?.response?.data?.error;
+  return body && typeof body === 'object' ? body : undefined;
+};
+
+// apiErrorMessage returns a message to show for a failed API request
+export const apiErrorMessage = (err: any, fallback: string): string => {
+  const body = apiError(err);
+  if (!body) {
+    return fallback;
+  }
+  const fieldMessages = Object.entries(body.fields ?? {}).flatMap(([field, problems]) =>
+    problems.map((problem) => `${field} ${problem.message}`)
+  );
+  return fieldMessages.length > 0 ? `${body.message}: ${fieldMessages.join(', ')}` : body.message;
+};
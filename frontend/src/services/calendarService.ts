@@ -0,0 +1,37 @@
+import * as api from './api/generated';
+
+export type CalendarImport = api.CalendarImportResponse;
+
+export const createCalendarFeed = async (token: string): Promise<string> => {
+  const feed = await api.createCalendarFeed({ token });
+  return feed.url;
+};
+
+export const deleteCalendarFeed = (token: string) =>
+  api.deleteCalendarFeed({ token });
+
+export const importCalendar = async (file: File, token: string): Promise<CalendarImport> => {
+  const form = new FormData();
+  form.append('file', file);
+  try {
+    return await api.importCalendar(form, { token });
+  } catch (error) {
+    console.error('Failed to import calendar:', error);
+    throw error;
+  }
+};
+
+const count = (n: number, one: string, many: string) => `${n} ${n === 1 ? one : many}`;
+
+// importSummary describes an import in a sentence, such as "Added 3
+// memories, set 1 birthday"
+export const importSummary = (result: CalendarImport): string => {
+  const parts = [];
+  if (result.memoriesCreated) parts.push(`added ${count(result.memoriesCreated, 'memory', 'memories')}`);
+  if (result.memoriesUpdated) parts.push(`updated ${count(result.memoriesUpdated, 'memory', 'memories')}`);
+  if (result.birthdaysSet) parts.push(`set ${count(result.birthdaysSet, 'birthday', 'birthdays')}`);
+  if (result.peopleCreated) parts.push(`added ${count(result.peopleCreated, 'person', 'people')}`);
+  if (parts.length === 0) return 'Nothing new to import';
+  const sentence = parts.join(', ');
+  return sentence.charAt(0).toUpperCase() + sentence.slice(1);
+};
@@ -0,0 +1,12 @@
+import * as api from './api/generated';
+
+export type CareCircleMember = api.CareCircleMemberResponse;
+
+export const getCareCircle = (token: string): Promise<CareCircleMember[]> =>
+  api.getCareCircle({ token });
+
+export const addCareCircleMember = (name: string, email: string, token: string): Promise<CareCircleMember> =>
+  api.addCareCircleMember({ name, email }, { token });
+
+export const removeCareCircleMember = (id: string, token: string) =>
+  api.deleteCareCircleMember(id, { token });
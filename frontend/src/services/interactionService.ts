@@ -0,0 +1,21 @@
+import * as api from './api/generated';
+
+export type Interaction = api.InteractionResponse;
+export type InteractionType = api.CreateInteractionRequest['type'];
+export type Insights = api.InsightsResponse;
+export type PersonInsight = api.PersonInsight;
+
+export const logInteraction = (personId: string, type: InteractionType, token: string, notes = ''): Promise<Interaction> =>
+  api.createInteraction(personId, { type, notes }, { token });
+
+export const getInteractions = (personId: string, token: string): Promise<Interaction[]> =>
+  api.getInteractions(personId, { token });
+
+export const getInsights = async (token: string, days?: number): Promise<Insights> => {
+  try {
+    return await api.getInsights({ days }, { token });
+  } catch (error) {
+    console.error('Failed to get insights:', error);
+    throw error;
+  }
+};
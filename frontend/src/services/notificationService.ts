@@ -0,0 +1,126 @@
+import * as api from './api/generated';
+
+export type Notification = api.Notification;
+export type NotificationList = api.NotificationListResponse;
+export type NotificationPreference = api.NotificationPreference;
+
+export const getNotifications = async (token: string, unread?: boolean): Promise<NotificationList> => {
+  try {
+    return await api.getNotifications({ unread }, { token });
+  } catch (error) {
+    console.error('Failed to get notifications:', error);
+    throw error;
+  }
+};
+
+export const markNotificationRead = (id: string, token: string): Promise<Notification> =>
+  api.markNotificationRead(id, { token });
+
+export const markAllNotificationsRead = (token: string) =>
+  api.markAllNotificationsRead({ token });
+
+export const getNotificationPreferences = (token: string) =>
+  api.getNotificationPreferences({ token });
+
+export const setNotificationPreference = (preference: NotificationPreference, token: string) =>
+  api.updateNotificationPreferences({ preferences: [preference] }, { token });
+
+export interface StreamHandlers {
+  onUnread: (unread: number) => void;
+  onNotification: (notification: Notification) => void;
+}
+
+/**
+ * Follows the notification stream until signal aborts, reconnecting after
+ * errors. EventSource cannot send the bearer token, so the stream is read
+ * with fetch.
+ */
+export const streamNotifications = async (token: string, handlers: StreamHandlers, signal: AbortSignal) => {
+  let lastEventId = '';
+  while (!signal.aborted) {
+    try {
+      const headers: Record<string, string> = { Authorization: `Bearer ${token}` };
+      if (lastEventId) {
+        headers['Last-Event-ID'] = lastEventId;
+      }
+      const response = await fetch(`${process.env.NEXT_PUBLIC_API_URL}/api/v1/notifications/stream`, { headers, signal });
+      if (!response.ok || !response.body) {
+        throw new Error(`HTTP error! status: ${response.status}`);
+      }
+
+      const reader = response.body.getReader();
+      const decoder = new TextDecoder();
+      let buffer = '';
+      while (true) {
+        const { done, value } = await reader.read();
+        if (done) break;
+        buffer += decoder.decode(value, { stream: true });
+        const events = buffer.split('\n\n');
+        buffer = events.pop() || '';
+        for (const event of events) {
+          let id = '';
+          let name = '';
+          let data = '';
+          for (const line of event.split('\n')) {
+            if (line.startsWith('id: ')) id = line.slice(4);
+            else if (line.startsWith('event: ')) name = line.slice(7);
+            else if (line.startsWith('data: ')) data = line.slice(6);
+          }
+          if (name === 'unread') {
+            handlers.onUnread(JSON.parse(data).unread);
+          } else if (name === 'notification') {
+            lastEventId = id;
+            handlers.onNotification(JSON.parse(data));
+          }
+        }
+      }
+    } catch (error) {
+      if (signal.aborted) return;
+      console.error('Notification stream failed:', error);
+    }
+    // Wait before reconnecting
+    await new Promise((resolve) => setTimeout(resolve, 5000));
+  }
+};
+
+const base64UrlToBytes = (value: string) => {
+  const base64 = (value + '='.repeat((4 - (value.length % 4)) % 4)).replace(/-/g, '+').replace(/_/g, '/');
+  return Uint8Array.from(atob(base64), (char) => char.charCodeAt(0));
+};
+
+export const pushSupported = () =>
+  typeof window !== 'undefined' && 'serviceWorker' in navigator && 'PushManager' in window;
+
+/** Asks permission to notify and subscribes this browser to push messages */
+export const enablePush = async (token: string) => {
+  const permission = await window.Notification.requestPermission();
+  if (permission !== 'granted') {
+    throw new Error('Notifications are blocked in this browser');
+  }
+  const { publicKey } = await api.getPushKey({ token });
+  const registration = await navigator.serviceWorker.register('/sw.js');
+  const subscription = await registration.pushManager.subscribe({
+    userVisibleOnly: true,
+    applicationServerKey: base64UrlToBytes(publicKey),
+  });
+  const json = subscription.toJSON();
+  await api.subscribePush({
+    endpoint: subscription.endpoint,
+    keys: { p256dh: json.keys?.p256dh ?? '', auth: json.keys?.auth ?? '' },
+  }, { token });
+};
+
+/** Unsubscribes this browser from push messages */
+export const disablePush = async (token: string) => {
+  const registration = await navigator.serviceWorker.getRegistration('/sw.js');
+  const subscription = await registration?.pushManager.getSubscription();
+  if (!subscription) return;
+  await api.unsubscribePush({ endpoint: subscription.endpoint }, { token });
+  await subscription.unsubscribe();
+};
+
+export const pushEnabled = async () => {
+  if (!pushSupported()) return false;
+  const registration = await navigator.serviceWorker.getRegistration('/sw.js');
+  return !!(await registration?.pushManager.getSubscription());
+};
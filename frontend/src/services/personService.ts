@@ -1,40 +1,11 @@
-import axios from 'axios';
+import * as api from './api/generated';
 
-export interface Person {
-  id: string;
-  firstName: string;
-  lastName: string;
-  email: string;
-  phone: string;
-  relationship: string;
-  notes: string;
-  photoId?: string;
-  photoUrl?: string;
-}
-
-export interface CreatePersonRequest {
-  firstName: string;
-  lastName: string;
-  email: string;
-  phone: string;
-  relationship: string;
-  notes: string;
-  photoId?: string;
-}
+export type Person = api.PersonResponse;
+export type CreatePersonRequest = api.CreatePersonRequest;
 
 export const createPerson = async (personData: CreatePersonRequest, token: string): Promise<Person> => {
   try {
-    const response = await axios.post(
-      `${process.env.NEXT_PUBLIC_API_URL}/people`,
-      personData,
-      {
-        headers: {
-          'Authorization': `Bearer ${token}`,
-          'Content-Type': 'application/json',
-        },
-      }
-    );
-    return response.data;
+    return await api.createPerson(personData, { token });
   } catch (error) {
     console.error('Failed to create person:', error);
     throw error;
@@ -43,17 +14,23 @@ export const createPerson = async (personData: CreatePersonRequest, token: strin
 
 export const getPeople = async (token: string): Promise<Person[]> => {
   try {
-    const response = await axios.get(
-      `${process.env.NEXT_PUBLIC_API_URL}/people`,
-      {
-        headers: {
-          'Authorization': `Bearer ${token}`,
-        },
-      }
-    );
-    return response.data;
+    return await api.getPeople({ token });
   } catch (error) {
     console.error('Failed to get people:', error);
     throw error;
   }
-}; 
\ No newline at end of file
+};
+
+export type UpcomingEvent = api.UpcomingEvent;
+
+export const getUpcoming = async (token: string, days?: number): Promise<UpcomingEvent[]> => {
+  try {
+    return await api.getUpcoming({ days }, { token });
+  } catch (error) {
+    console.error('Failed to get upcoming dates:', error);
+    throw error;
+  }
+};
+
+export const setPersonDates = (id: string, dates: api.PersonDatesRequest, token: string): Promise<Person> =>
+  api.setPersonDates(id, dates, { token });
@@ -1,25 +1,13 @@
-import axios from 'axios';
+import * as api from './api/generated';
 
-export interface PhotoResponse {
-  url: string;
-  filename: string;
-  filetype: string;
-  uploadedAt: string;
-}
+export type PhotoResponse = api.Photo;
 
 export const getPhotoUrl = async (photoId: string, token: string): Promise<string> => {
   try {
-    const response = await axios.get(
-      `${process.env.NEXT_PUBLIC_API_URL}/photos/${photoId}`,
-      {
-        headers: {
-          'Authorization': `Bearer ${token}`,
-        },
-      }
-    );
-    return response.data.url;
+    const photo = await api.getPhoto(photoId, { token });
+    return photo.url;
   } catch (error) {
     console.error('Failed to get photo URL:', error);
     throw error;
   }
-}; 
\ No newline at end of file
+};
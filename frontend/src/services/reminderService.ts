@@ -0,0 +1,21 @@
+import * as api from './api/generated';
+
+export type Reminder = api.ReminderResponse;
+
+export const getReminders = async (token: string): Promise<Reminder[]> => {
+  try {
+    return await api.getReminders({ token });
+  } catch (error) {
+    console.error('Failed to get reminders:', error);
+    throw error;
+  }
+};
+
+export const snoozeReminder = (id: string, until: Date, token: string): Promise<Reminder> =>
+  api.snoozeReminder(id, { until: until.toISOString() }, { token });
+
+export const completeReminder = (id: string, token: string): Promise<Reminder> =>
+  api.completeReminder(id, { token });
+
+export const dismissReminder = (id: string, token: string): Promise<Reminder> =>
+  api.dismissReminder(id, { token });
@@ -0,0 +1,26 @@
+import * as api from './api/generated';
+
+export type MemoryPrompt = api.MemoryPromptResponse;
+export type MemoryPromptList = api.MemoryPromptListResponse;
+
+// getTodayPrompt returns today's memory prompt, or null when no memory is due
+export const getTodayPrompt = async (token: string): Promise<MemoryPrompt | null> => {
+  try {
+    return await api.getTodayMemoryPrompt({ token });
+  } catch (error: any) {
+    if (error?.response?.status === 404) {
+      return null;
+    }
+    console.error("Failed to get today's memory prompt:", error);
+    throw error;
+  }
+};
+
+export const getPrompts = (token: string, days?: number): Promise<MemoryPromptList> =>
+  api.getMemoryPrompts({ days }, { token });
+
+export const respondToPrompt = (id: string, response: 'engaged' | 'skipped', token: string): Promise<MemoryPrompt> =>
+  api.respondToMemoryPrompt(id, { response }, { token });
+
+export const favoriteMemory = (id: string, favorite: boolean, token: string) =>
+  api.favoriteMemory(id, { favorite }, { token });
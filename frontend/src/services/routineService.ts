@@ -0,0 +1,30 @@
+import * as api from './api/generated';
+
+export type Routine = api.RoutineResponse;
+export type RoutineRequest = api.RoutineRequest;
+export type ScheduledCheckIn = api.ScheduledCheckIn;
+export type Adherence = api.AdherenceResponse;
+
+export const getSchedule = async (token: string, date?: string): Promise<ScheduledCheckIn[]> => {
+  try {
+    return await api.getSchedule({ date }, { token });
+  } catch (error) {
+    console.error('Failed to get schedule:', error);
+    throw error;
+  }
+};
+
+export const getRoutines = (token: string): Promise<Routine[]> =>
+  api.getRoutines({ token });
+
+export const createRoutine = (routine: RoutineRequest, token: string): Promise<Routine> =>
+  api.createRoutine(routine, { token });
+
+export const deleteRoutine = (id: string, token: string) =>
+  api.deleteRoutine(id, { token });
+
+export const checkIn = (item: ScheduledCheckIn, status: 'done' | 'skipped', token: string) =>
+  api.checkIn(item.routineId, { scheduledAt: item.scheduledAt, status }, { token });
+
+export const getAdherence = (token: string, days?: number): Promise<Adherence> =>
+  api.getAdherence({ days }, { token });